@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+)
+
+// config is the bot's strategy: hold TargetSolBps of the portfolio value in SOL and the rest
+// in QuoteMint, trading back to the target when the SOL share drifts by more than ThresholdBps
+type config struct {
+	QuoteMint    string   `json:"quoteMint"`
+	TargetSolBps uint64   `json:"targetSolBps"`
+	ThresholdBps uint64   `json:"thresholdBps"`
+	SlippageBps  uint64   `json:"slippageBps"`
+	Interval     duration `json:"interval"`
+	// ReserveLamports stay untouched for fees and rent and are left out of the portfolio
+	ReserveLamports uint64 `json:"reserveLamports"`
+	// MinTradeLamports skips rebalances worth less, so fees don't eat small corrections
+	MinTradeLamports uint64 `json:"minTradeLamports"`
+	// ProbeLamports is the SOL amount quoted each tick to price the portfolio
+	ProbeLamports uint64 `json:"probeLamports"`
+	// Simulate sends every rebalance as a simulation only
+	Simulate bool `json:"simulate"`
+}
+
+// defaultConfig holds half the portfolio in USDC, rebalancing on 5% drift, simulating only
+func defaultConfig() config {
+	return config{
+		QuoteMint:        "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v",
+		TargetSolBps:     5000,
+		ThresholdBps:     500,
+		SlippageBps:      100,
+		Interval:         duration(30 * time.Second),
+		ReserveLamports:  50_000_000,
+		MinTradeLamports: 10_000_000,
+		ProbeLamports:    100_000_000,
+		Simulate:         true,
+	}
+}
+
+// loadConfig reads a JSON config over the defaults; an empty path keeps the defaults
+func loadConfig(path string) (config, error) {
+	cfg := defaultConfig()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return config{}, fmt.Errorf("failed to read config: %w", err)
+		}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return config{}, fmt.Errorf("failed to parse config: %w", err)
+		}
+	}
+	if cfg.QuoteMint == sol.WSOL.String() {
+		return config{}, fmt.Errorf("quote mint must not be WSOL")
+	}
+	if cfg.TargetSolBps > 10000 || cfg.ThresholdBps > 10000 || cfg.SlippageBps >= 10000 {
+		return config{}, fmt.Errorf("bps values must not exceed 10000")
+	}
+	if cfg.Interval <= 0 || cfg.ProbeLamports == 0 {
+		return config{}, fmt.Errorf("interval and probe amount must be positive")
+	}
+	return cfg, nil
+}
+
+// duration reads JSON strings like "30s"
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"30s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
@@ -0,0 +1,170 @@
+// Command solroute-bot is a reference daemon built on the router. It keeps a wallet's value
+// split between SOL and one token at a target ratio: each interval it prices the portfolio
+// with a probe quote and, when the split has drifted past a threshold, swaps back to the
+// target through the best pool. Pools are discovered once per pair and kept fresh by a
+// background cache refresh.
+//
+// Usage: solroute-bot [-config strategy.json]
+//
+// The key, endpoints, protocols and RPC settings are read with config.Load, from
+// $SOLROUTE_CONFIG and the environment as by the main example. Rebalances are only simulated unless the config sets
+// "simulate": false.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	routeconfig "github.com/gtdvccc/SolRouteTmp/pkg/config"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gtdvccc/SolRouteTmp/utils"
+)
+
+func main() {
+	configPath := flag.String("config", "", "JSON strategy config; defaults to a simulated 50/50 SOL/USDC split")
+	flag.Parse()
+	utils.LoadEnv()
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+	settings, err := routeconfig.Load("")
+	if err != nil {
+		log.Fatalf("Invalid settings: %v", err)
+	}
+	if settings.PrivateKey == "" {
+		log.Fatalf("%s is required", routeconfig.EnvPrivateKey)
+	}
+	privateKey := solana.MustPrivateKeyFromBase58(settings.PrivateKey)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	endpoints, err := settings.NewEndpoints(ctx)
+	if err != nil {
+		log.Fatalf("Failed to create solana client: %v", err)
+	}
+	defer endpoints.Close()
+
+	quoteMint := solana.MustPublicKeyFromBase58(cfg.QuoteMint)
+	if _, err := endpoints.Quote.SelectOrCreateSPLTokenAccount(ctx, privateKey, quoteMint); err != nil {
+		log.Fatalf("Failed to prepare %s token account: %v", quoteMint, err)
+	}
+
+	protocols, err := settings.NewProtocols(endpoints.Discovery)
+	if err != nil {
+		log.Fatalf("Failed to create protocols: %v", err)
+	}
+	cache := router.NewPoolCache(router.DefaultPoolCacheTTL, protocols...)
+	cache.StartRefresh(ctx, router.DefaultPoolCacheTTL)
+	r := router.NewSimpleRouter()
+	r.SetPoolCache(cache)
+	r.SetQuoteClient(endpoints.Quote.RpcClient)
+
+	b := &bot{cfg: cfg, key: privateKey, client: endpoints.Quote, router: r}
+	log.Printf("Rebalancing %s between SOL and %s every %s", privateKey.PublicKey(), quoteMint, time.Duration(cfg.Interval))
+	ticker := time.NewTicker(time.Duration(cfg.Interval))
+	defer ticker.Stop()
+	for {
+		if err := b.tick(ctx); err != nil {
+			log.Printf("Rebalance failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			log.Printf("Shutting down")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// bot ties the router, the wallet and the strategy together
+type bot struct {
+	cfg    config
+	key    solana.PrivateKey
+	client *sol.Client
+	router *router.SimpleRouter
+}
+
+// tick prices the portfolio and rebalances it if it has drifted too far
+func (b *bot) tick(ctx context.Context) error {
+	wsol := sol.WSOL.String()
+	if _, err := b.router.QueryAllPools(ctx, wsol, b.cfg.QuoteMint); err != nil {
+		return fmt.Errorf("failed to discover pools: %w", err)
+	}
+	probeLamports := math.NewIntFromUint64(b.cfg.ProbeLamports)
+	_, probeOut, err := b.router.GetBestPool(ctx, nil, wsol, b.cfg.QuoteMint, probeLamports)
+	if err != nil {
+		return fmt.Errorf("failed to price SOL: %w", err)
+	}
+
+	user := b.key.PublicKey()
+	balance, err := b.client.RpcClient.GetBalance(ctx, user, rpc.CommitmentConfirmed)
+	if err != nil {
+		return fmt.Errorf("failed to get SOL balance: %w", err)
+	}
+	quoteBalance, err := b.client.GetUserTokenBalance(ctx, user, solana.MustPublicKeyFromBase58(b.cfg.QuoteMint))
+	if err != nil {
+		return fmt.Errorf("failed to get token balance: %w", err)
+	}
+
+	plan := planRebalance(b.cfg, balance.Value, quoteBalance, probeLamports, probeOut)
+	if plan == nil {
+		log.Printf("Holding: %d lamports, %d %s at %s per %d lamports", balance.Value, quoteBalance, b.cfg.QuoteMint, probeOut, b.cfg.ProbeLamports)
+		return nil
+	}
+	return b.execute(ctx, plan)
+}
+
+// execute swaps through the best pool with the configured slippage
+func (b *bot) execute(ctx context.Context, plan *rebalance) error {
+	tokenIn, tokenOut := b.cfg.QuoteMint, sol.WSOL.String()
+	if plan.SellSol {
+		tokenIn, tokenOut = tokenOut, tokenIn
+	}
+	if !plan.AmountIn.IsUint64() {
+		return fmt.Errorf("trade amount %s overflows u64", plan.AmountIn)
+	}
+	pool, amountOut, err := b.router.GetBestPool(ctx, nil, tokenIn, tokenOut, plan.AmountIn)
+	if err != nil {
+		return fmt.Errorf("failed to quote rebalance: %w", err)
+	}
+	if err := sol.VerifyPoolVaults(ctx, b.client.RpcClient, pool); err != nil {
+		return fmt.Errorf("pool vault verification failed: %w", err)
+	}
+	minOut, _, err := pkg.SlippageBps(b.cfg.SlippageBps).MinAmountOut(amountOut, 0, false)
+	if err != nil {
+		return err
+	}
+
+	user := b.key.PublicKey()
+	unlock := pkg.LockPool(pool)
+	instructions, err := pool.BuildSwapInstructions(ctx, b.client.RpcClient, user, tokenIn, plan.AmountIn, minOut)
+	unlock()
+	if err != nil {
+		return fmt.Errorf("failed to build swap: %w", err)
+	}
+	instructions, err = b.client.WrapWsolSwap(ctx, user, tokenIn, plan.AmountIn.Uint64(), instructions, sol.WsolOptions{Unwind: true, OutputMint: tokenOut})
+	if err != nil {
+		return fmt.Errorf("failed to fund WSOL: %w", err)
+	}
+	sig, err := b.client.SendTx(ctx, solana.Hash{}, []solana.PrivateKey{b.key}, instructions, b.cfg.Simulate)
+	if err != nil {
+		return fmt.Errorf("failed to send rebalance: %w", err)
+	}
+	log.Printf("Rebalanced %s %s -> %s through %s (%s), worth %s lamports, simulated=%v: %s",
+		plan.AmountIn, tokenIn, tokenOut, router.VenueName(pool.ProtocolName()), pool.GetID(), plan.Lamports, b.cfg.Simulate, sig)
+	return nil
+}
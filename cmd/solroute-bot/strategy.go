@@ -0,0 +1,40 @@
+package main
+
+import (
+	"cosmossdk.io/math"
+)
+
+// rebalance is a trade back to the target allocation
+type rebalance struct {
+	SellSol  bool     // SOL to the quote token, or the other way around
+	AmountIn math.Int // lamports when selling SOL, quote units otherwise
+	Lamports math.Int // value of the trade in lamports
+}
+
+// planRebalance values the portfolio at probeOut quote units per probeLamports and returns
+// the trade back to the target, or nil while the SOL share is within the threshold
+func planRebalance(cfg config, lamports, quoteBalance uint64, probeLamports, probeOut math.Int) *rebalance {
+	if !probeOut.IsPositive() {
+		return nil
+	}
+	solValue := math.NewIntFromUint64(lamports - min(lamports, cfg.ReserveLamports))
+	quoteValue := math.NewIntFromUint64(quoteBalance).Mul(probeLamports).Quo(probeOut)
+	total := solValue.Add(quoteValue)
+	if !total.IsPositive() {
+		return nil
+	}
+
+	target := total.MulRaw(int64(cfg.TargetSolBps)).QuoRaw(10000)
+	drift := solValue.Sub(target)
+	if drift.Abs().MulRaw(10000).LTE(total.MulRaw(int64(cfg.ThresholdBps))) {
+		return nil
+	}
+	if drift.Abs().LT(math.NewIntFromUint64(cfg.MinTradeLamports)) {
+		return nil
+	}
+	if drift.IsPositive() {
+		return &rebalance{SellSol: true, AmountIn: drift, Lamports: drift}
+	}
+	lamportsWanted := drift.Neg()
+	return &rebalance{AmountIn: lamportsWanted.Mul(probeOut).Quo(probeLamports), Lamports: lamportsWanted}
+}
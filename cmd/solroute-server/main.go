@@ -0,0 +1,105 @@
+// Command solroute-server serves the router over HTTP, so clients in any language can quote
+// swaps and fetch them as unsigned transactions from a self-hosted routing engine. See
+// package server for the endpoints.
+//
+// Usage: solroute-server [-addr :8080] [-config solroute.yaml] [-protocols raydium_clmm,orca_whirlpool] [-tenants tenants.json]
+//
+// Endpoints, protocols, commitment, rate limits and the default slippage are read with
+// config.Load, from the file and the environment as by the main example; -protocols overrides
+// the configured ones. With -tenants, requests need an API key from the file, see
+// server.LoadTenants. The server holds no keys: swaps are returned for the caller's wallet,
+// and the tenant's fee payer, to sign and send.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/config"
+	"github.com/gtdvccc/SolRouteTmp/pkg/protocolregistry"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/server"
+	"github.com/gtdvccc/SolRouteTmp/utils"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	requestTimeout := flag.Duration("timeout", 15*time.Second, "time allowed to answer a request")
+	configPath := flag.String("config", "", "YAML or JSON settings file; $SOLROUTE_CONFIG when empty")
+	protocolNames := flag.String("protocols", "", "comma-separated protocols to route through, overriding the config")
+	tenantsPath := flag.String("tenants", "", "JSON file of tenants and their API keys; requests need no key when empty")
+	flag.Parse()
+	utils.LoadEnv()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+	if *protocolNames != "" {
+		cfg.Protocols = cfg.Protocols[:0]
+		for _, name := range protocolregistry.ParseNames(*protocolNames) {
+			cfg.Protocols = append(cfg.Protocols, string(name))
+		}
+		if err := cfg.Validate(); err != nil {
+			log.Fatalf("Invalid protocols: %v", err)
+		}
+	}
+	// Quotes are served without subscriptions
+	cfg.RPC.WSURL = ""
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	endpoints, err := cfg.NewEndpoints(ctx)
+	if err != nil {
+		log.Fatalf("Failed to create solana client: %v", err)
+	}
+	defer endpoints.Close()
+	endpoints.Quote.Blockhashes.StartPolling(ctx, 10*time.Second)
+
+	protocols, err := cfg.NewProtocols(endpoints.Discovery)
+	if err != nil {
+		log.Fatalf("Failed to create protocols: %v", err)
+	}
+	cache := router.NewPoolCache(router.DefaultPoolCacheTTL, protocols...)
+	cache.StartRefresh(ctx, router.DefaultPoolCacheTTL)
+	r := router.NewSimpleRouter()
+	r.SetPoolCache(cache)
+	r.SetQuoteClient(endpoints.Quote.RpcClient)
+	r.SetDiscoveryBudget(router.InteractiveDiscoveryBudget)
+
+	handler := server.New(r, endpoints.Quote)
+	handler.SetDefaultSlippageBps(cfg.SlippageBps)
+	if *tenantsPath != "" {
+		tenants, err := server.LoadTenants(*tenantsPath)
+		if err != nil {
+			log.Fatalf("Invalid tenants: %v", err)
+		}
+		handler.SetTenants(tenants)
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           http.TimeoutHandler(handler, *requestTimeout, `{"error":"request timed out"}`),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Serving quotes on %s through %s", *addr, cfg.RPC.URL)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Server failed: %v", err)
+	}
+	log.Printf("Shut down")
+}
@@ -0,0 +1,274 @@
+// Package exampledex is a reference venue for pkg.Venue: a constant-product DEX with a
+// made-up program and account layout. It only uses the module's public API, so an
+// out-of-tree module can copy it as the starting point for a proprietary venue and register
+// it with protocol.NewPluginProtocol.
+package exampledex
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Name is the protocol name the example pools report
+const Name pkg.ProtocolName = "example_dex"
+
+// ProgramID of the example DEX; it isn't deployed anywhere
+var ProgramID = solana.MustPublicKeyFromBase58("ExampLeDex1111111111111111111111111111111111")
+
+// Pool account layout:
+//
+//	discriminator [8] | mint_a [32] | mint_b [32] | vault_a [32] | vault_b [32] |
+//	reserve_a u64 | reserve_b u64 | fee_bps u16
+const (
+	PoolAccountSize = 8 + 4*32 + 8 + 8 + 2
+
+	mintAOffset = 8
+	mintBOffset = mintAOffset + 32
+)
+
+// PoolDiscriminator prefixes every pool account
+var PoolDiscriminator = [8]byte{'e', 'x', 'd', 'x', 'p', 'o', 'o', 'l'}
+
+// Swap instruction discriminators, followed by two little-endian u64 arguments
+const (
+	swapExactInInstruction  uint8 = 1 // amount_in, min_amount_out
+	swapExactOutInstruction uint8 = 2 // amount_out, max_amount_in
+)
+
+var (
+	_ pkg.Venue = Venue{}
+	_ pkg.Pool  = (*Pool)(nil)
+)
+
+// Venue implements pkg.Venue for the example DEX
+type Venue struct{}
+
+func (Venue) Name() pkg.ProtocolName {
+	return Name
+}
+
+func (Venue) ProgramID() solana.PublicKey {
+	return ProgramID
+}
+
+// PairFilters scans for pools in both mint orders
+func (Venue) PairFilters(baseMint, quoteMint solana.PublicKey) [][]rpc.RPCFilter {
+	pairFilter := func(mintA, mintB solana.PublicKey) []rpc.RPCFilter {
+		return []rpc.RPCFilter{
+			{DataSize: PoolAccountSize},
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: 0, Bytes: PoolDiscriminator[:]}},
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: mintAOffset, Bytes: mintA.Bytes()}},
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: mintBOffset, Bytes: mintB.Bytes()}},
+		}
+	}
+	return [][]rpc.RPCFilter{pairFilter(baseMint, quoteMint), pairFilter(quoteMint, baseMint)}
+}
+
+func (Venue) DecodePool(id solana.PublicKey, data []byte) (pkg.Pool, error) {
+	pool := &Pool{ID: id}
+	if err := pool.Decode(data); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// Pool is an example DEX pool; its reserves live in the pool account, so quoting needs no
+// further RPC calls
+type Pool struct {
+	ID       solana.PublicKey
+	MintA    solana.PublicKey
+	MintB    solana.PublicKey
+	VaultA   solana.PublicKey
+	VaultB   solana.PublicKey
+	ReserveA uint64
+	ReserveB uint64
+	FeeBps   uint16
+}
+
+// Decode reads a pool account
+func (p *Pool) Decode(data []byte) error {
+	if len(data) != PoolAccountSize {
+		return fmt.Errorf("invalid pool account size %d, expected %d", len(data), PoolAccountSize)
+	}
+	if [8]byte(data[:8]) != PoolDiscriminator {
+		return errors.New("invalid pool account discriminator")
+	}
+	offset := 8
+	for _, key := range []*solana.PublicKey{&p.MintA, &p.MintB, &p.VaultA, &p.VaultB} {
+		*key = solana.PublicKeyFromBytes(data[offset : offset+32])
+		offset += 32
+	}
+	p.ReserveA = binary.LittleEndian.Uint64(data[offset:])
+	p.ReserveB = binary.LittleEndian.Uint64(data[offset+8:])
+	p.FeeBps = binary.LittleEndian.Uint16(data[offset+16:])
+	if p.FeeBps >= 10000 {
+		return fmt.Errorf("invalid fee %d bps", p.FeeBps)
+	}
+	return nil
+}
+
+// Encode writes the pool account; the inverse of Decode
+func (p *Pool) Encode() []byte {
+	data := make([]byte, 0, PoolAccountSize)
+	data = append(data, PoolDiscriminator[:]...)
+	for _, key := range []solana.PublicKey{p.MintA, p.MintB, p.VaultA, p.VaultB} {
+		data = append(data, key.Bytes()...)
+	}
+	data = binary.LittleEndian.AppendUint64(data, p.ReserveA)
+	data = binary.LittleEndian.AppendUint64(data, p.ReserveB)
+	return binary.LittleEndian.AppendUint16(data, p.FeeBps)
+}
+
+func (p *Pool) ProtocolName() pkg.ProtocolName {
+	return Name
+}
+
+func (p *Pool) ProtocolType() pkg.ProtocolType {
+	return pkg.ProtocolTypeExternal
+}
+
+func (p *Pool) GetProgramID() solana.PublicKey {
+	return ProgramID
+}
+
+func (p *Pool) GetID() string {
+	return p.ID.String()
+}
+
+// FeeRateBps returns the swap fee in basis points
+func (p *Pool) FeeRateBps() uint64 {
+	return uint64(p.FeeBps)
+}
+
+func (p *Pool) GetTokens() (baseMint, quoteMint string) {
+	return p.MintA.String(), p.MintB.String()
+}
+
+// reserves returns the reserves ordered by swap direction
+func (p *Pool) reserves(inputMint string) (reserveIn, reserveOut math.Int, aToB bool, err error) {
+	switch inputMint {
+	case p.MintA.String():
+		return math.NewIntFromUint64(p.ReserveA), math.NewIntFromUint64(p.ReserveB), true, nil
+	case p.MintB.String():
+		return math.NewIntFromUint64(p.ReserveB), math.NewIntFromUint64(p.ReserveA), false, nil
+	default:
+		return math.Int{}, math.Int{}, false, fmt.Errorf("mint %s not in pool %s", inputMint, p.ID)
+	}
+}
+
+// otherMint returns the mint on the other side of the pool
+func (p *Pool) otherMint(mint string) (string, error) {
+	switch mint {
+	case p.MintA.String():
+		return p.MintB.String(), nil
+	case p.MintB.String():
+		return p.MintA.String(), nil
+	default:
+		return "", fmt.Errorf("mint %s not in pool %s", mint, p.ID)
+	}
+}
+
+// Quote charges the fee on the input and prices the rest on x*y=k, rounding down
+func (p *Pool) Quote(ctx context.Context, solClient pkg.RPC, inputMint string, inputAmount math.Int) (math.Int, error) {
+	if !inputAmount.IsPositive() {
+		return math.Int{}, fmt.Errorf("input amount must be positive")
+	}
+	reserveIn, reserveOut, _, err := p.reserves(inputMint)
+	if err != nil {
+		return math.Int{}, err
+	}
+	amountInAfterFee := inputAmount.MulRaw(int64(10000 - p.FeeBps)).QuoRaw(10000)
+	return amountInAfterFee.Mul(reserveOut).Quo(reserveIn.Add(amountInAfterFee)), nil
+}
+
+// QuoteExactOut inverts Quote, rounding the input up
+func (p *Pool) QuoteExactOut(ctx context.Context, solClient pkg.RPC, outputMint string, desiredOut math.Int) (math.Int, error) {
+	if !desiredOut.IsPositive() {
+		return math.Int{}, fmt.Errorf("output amount must be positive")
+	}
+	inputMint, err := p.otherMint(outputMint)
+	if err != nil {
+		return math.Int{}, err
+	}
+	reserveIn, reserveOut, _, err := p.reserves(inputMint)
+	if err != nil {
+		return math.Int{}, err
+	}
+	if desiredOut.GTE(reserveOut) {
+		return math.Int{}, fmt.Errorf("output %s exceeds pool reserve %s", desiredOut, reserveOut)
+	}
+	amountInAfterFee := ceilDiv(reserveIn.Mul(desiredOut), reserveOut.Sub(desiredOut))
+	return ceilDiv(amountInAfterFee.MulRaw(10000), math.NewInt(int64(10000-p.FeeBps))), nil
+}
+
+func ceilDiv(a, b math.Int) math.Int {
+	return a.Add(b).SubRaw(1).Quo(b)
+}
+
+func (p *Pool) BuildSwapInstructions(ctx context.Context, solClient pkg.RPC, user solana.PublicKey, inputMint string, inputAmount math.Int, minOut math.Int) ([]solana.Instruction, error) {
+	_, _, aToB, err := p.reserves(inputMint)
+	if err != nil {
+		return nil, err
+	}
+	inst, err := p.swapInstruction(user, aToB, swapExactInInstruction, inputAmount, minOut)
+	if err != nil {
+		return nil, err
+	}
+	return []solana.Instruction{inst}, nil
+}
+
+func (p *Pool) BuildSwapInstructionsExactOut(ctx context.Context, solClient pkg.RPC, user solana.PublicKey, outputMint string, amountOut math.Int, maxIn math.Int) ([]solana.Instruction, error) {
+	inputMint, err := p.otherMint(outputMint)
+	if err != nil {
+		return nil, err
+	}
+	_, _, aToB, err := p.reserves(inputMint)
+	if err != nil {
+		return nil, err
+	}
+	inst, err := p.swapInstruction(user, aToB, swapExactOutInstruction, amountOut, maxIn)
+	if err != nil {
+		return nil, err
+	}
+	return []solana.Instruction{inst}, nil
+}
+
+// swapInstruction swaps between the user's associated token accounts, which must exist
+func (p *Pool) swapInstruction(user solana.PublicKey, aToB bool, kind uint8, amount, threshold math.Int) (solana.Instruction, error) {
+	if !amount.IsUint64() || !threshold.IsUint64() {
+		return nil, fmt.Errorf("swap amounts must fit in u64")
+	}
+	userA, _, err := solana.FindAssociatedTokenAddress(user, p.MintA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive token A account: %w", err)
+	}
+	userB, _, err := solana.FindAssociatedTokenAddress(user, p.MintB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive token B account: %w", err)
+	}
+	source, destination := userA, userB
+	if !aToB {
+		source, destination = userB, userA
+	}
+
+	data := []byte{kind}
+	data = binary.LittleEndian.AppendUint64(data, amount.Uint64())
+	data = binary.LittleEndian.AppendUint64(data, threshold.Uint64())
+	accounts := solana.AccountMetaSlice{
+		solana.NewAccountMeta(p.ID, true, false),
+		solana.NewAccountMeta(user, false, true),
+		solana.NewAccountMeta(source, true, false),
+		solana.NewAccountMeta(destination, true, false),
+		solana.NewAccountMeta(p.VaultA, true, false),
+		solana.NewAccountMeta(p.VaultB, true, false),
+		solana.NewAccountMeta(solana.TokenProgramID, false, false),
+	}
+	return solana.NewInstruction(ProgramID, accounts, data), nil
+}
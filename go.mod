@@ -7,6 +7,7 @@ require (
 	github.com/gagliardetto/binary v0.8.0
 	github.com/gagliardetto/solana-go v1.12.0
 	github.com/stretchr/testify v1.10.0
+	gopkg.in/yaml.v3 v3.0.1
 	lukechampine.com/uint128 v1.3.0
 )
 
@@ -43,7 +44,6 @@ require (
 	golang.org/x/sys v0.34.0 // indirect
 	golang.org/x/term v0.33.0 // indirect
 	golang.org/x/time v0.6.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 replace  github.com/gtdvccc/SolRouteTmp => ./
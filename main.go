@@ -7,8 +7,7 @@ import (
 
 	"cosmossdk.io/math"
 	"github.com/gagliardetto/solana-go"
-	"github.com/gagliardetto/solana-go/rpc"
-	"github.com/gtdvccc/SolRouteTmp/pkg/protocol"
+	"github.com/gtdvccc/SolRouteTmp/pkg/config"
 	"github.com/gtdvccc/SolRouteTmp/pkg/router"
 	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
 	"github.com/gtdvccc/SolRouteTmp/utils"
@@ -20,50 +19,36 @@ const (
 
 	// Swap parameters
 	defaultAmountIn = 1000000000 // 1 sol (9 decimals)
-	slippageBps     = 100        // 1% slippage
 )
 
 func main() {
 	// Load .env if present
 	utils.LoadEnv()
 
-	// Initialize private key from environment
-	privateKeyStr := os.Getenv("SOLANA_PRIVATE_KEY")
-	if privateKeyStr == "" {
-		log.Fatalf("SOLANA_PRIVATE_KEY is required")
+	if len(os.Args) > 1 && os.Args[1] == "validate-pools" {
+		os.Exit(runValidatePools(context.Background(), os.Args[2:]))
 	}
-	privateKey := solana.MustPrivateKeyFromBase58(privateKeyStr)
-	log.Printf("PublicKey: %v", privateKey.PublicKey())
 
-	ctx := context.Background()
-	// RPC endpoints from env (with defaults)
-	mainnetRPC := os.Getenv("SOLANA_RPC_URL")
-	if mainnetRPC == "" {
-		mainnetRPC = "https://api.mainnet-beta.solana.com"
-	}
-	mainnetWSRPC := os.Getenv("SOLANA_WS_RPC_URL")
-	if mainnetWSRPC == "" {
-		mainnetWSRPC = "wss://api.mainnet-beta.solana.com"
+	// Settings come from $SOLROUTE_CONFIG, if set, and the environment
+	cfg, err := config.Load("")
+	if err != nil {
+		log.Fatalf("Invalid config: %v", err)
 	}
 
-	solClient, err := sol.NewClient(ctx, mainnetRPC, mainnetWSRPC)
-	if err != nil {
-		log.Fatalf("Failed to create solana client: %v", err)
+	// Initialize private key from environment
+	if cfg.PrivateKey == "" {
+		log.Fatalf("%s is required", config.EnvPrivateKey)
 	}
-	defer solClient.Close()
+	privateKey := solana.MustPrivateKeyFromBase58(cfg.PrivateKey)
+	log.Printf("PublicKey: %v", privateKey.PublicKey())
 
-	// check balance first
-	balance, err := solClient.GetUserTokenBalance(ctx, privateKey.PublicKey(), sol.WSOL)
+	ctx := context.Background()
+	endpoints, err := cfg.NewEndpoints(ctx)
 	if err != nil {
-		log.Fatalf("Failed to get user token balance: %v", err)
-	}
-	log.Printf("User token balance: %v", balance)
-	if balance < 10000000 {
-		err = solClient.CoverWsol(ctx, privateKey, 10000000)
-		if err != nil {
-			log.Fatalf("Failed to cover wsol: %v", err)
-		}
+		log.Fatalf("Failed to create solana client: %v", err)
 	}
+	defer endpoints.Close()
+	solClient := endpoints.Quote
 
 	tokenAccount, err := solClient.SelectOrCreateSPLTokenAccount(ctx, privateKey, solana.MustPublicKeyFromBase58(usdcTokenAddr))
 	if err != nil {
@@ -71,13 +56,12 @@ func main() {
 	}
 	log.Printf("USDC token account: %v", tokenAccount.String())
 
-	router := router.NewSimpleRouter(
-		protocol.NewPumpAmm(solClient),
-		protocol.NewRaydiumAmm(solClient),
-		protocol.NewRaydiumClmm(solClient),
-		protocol.NewRaydiumCpmm(solClient),
-		protocol.NewMeteoraDlmm(solClient),
-	)
+	// Discovery runs getProgramAccounts, quotes and sends use the low-latency endpoint
+	protocols, err := cfg.NewProtocols(endpoints.Discovery)
+	if err != nil {
+		log.Fatalf("Failed to create protocols: %v", err)
+	}
+	router := router.NewSimpleRouter(protocols...)
 
 	// Query available pools
 	pools, err := router.QueryAllPools(ctx, usdcTokenAddr, sol.WSOL.String())
@@ -97,26 +81,30 @@ func main() {
 	log.Printf("Selected best pool: %v", bestPool.GetID())
 	log.Printf("Expected output amount: %v", amountOut)
 
-	// Calculate minimum output amount with slippage
-	minAmountOut := amountOut.Mul(math.NewInt(10000 - slippageBps)).Quo(math.NewInt(10000))
+	// Verify the pool vaults on-chain before trusting the pool data
+	if err := sol.VerifyPoolVaults(ctx, solClient.RpcClient, bestPool); err != nil {
+		log.Fatalf("Pool vault verification failed: %v", err)
+	}
 
-	// Build swap instructions
-	instructions, err := bestPool.BuildSwapInstructions(ctx, solClient.RpcClient,
-		privateKey.PublicKey(), usdcTokenAddr, amountIn, minAmountOut)
+	// Build swap instructions with the minimum output at the slippage tolerance, wrapping only
+	// the SOL the swap is short of and unwrapping what's left afterwards
+	instructions, err := solClient.BuildSwapInstructionsWithSlippage(ctx, bestPool, privateKey.PublicKey(), sol.WSOL.String(), amountIn, amountOut, cfg.Slippage(), sol.SwapOptions{WrapSol: true, CreateOutputAccount: true})
 	if err != nil {
 		log.Fatalf("Failed to build swap instructions: %v", err)
 	}
-	log.Printf("Generated swap instructions: %v", instructions)
 
-	// Prepare transaction
-	signers := []solana.PrivateKey{privateKey}
-	res, err := solClient.RpcClient.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	// Size the compute unit limit by simulation and price it with the configured priority fee
+	budget := sol.NewComputeBudgetManager(solClient)
+	budget.SetPriorityFeeStrategy(cfg.PriorityFeeStrategy(solClient))
+	instructions, err = budget.Apply(ctx, privateKey.PublicKey(), instructions)
 	if err != nil {
-		log.Fatalf("Failed to get blockhash: %v", err)
+		log.Fatalf("Failed to set compute budget: %v", err)
 	}
+	log.Printf("Generated swap instructions: %v", instructions)
 
-	// Send transaction
-	sig, err := solClient.SendTx(ctx, res.Value.Blockhash, signers, instructions, true)
+	// Send transaction with the client's cached blockhash
+	signers := []solana.PrivateKey{privateKey}
+	sig, err := solClient.SendTx(ctx, solana.Hash{}, signers, instructions, true)
 	if err != nil {
 		log.Fatalf("Failed to send transaction: %v", err)
 	}
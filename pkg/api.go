@@ -1,23 +1,35 @@
+// Package pkg defines the interfaces shared by every venue: Pool, Protocol and the optional
+// NamedProtocol, VaultPool, FeeQuoter, DeprecatablePool, PrefetchPool, FeeRatePool,
+// OpenTimePool, DecodablePool, TransferFeePool and SpotPricePool, the v2 request-struct interfaces, and Venue
+// for protocols plugged in from other modules. It is the package to depend on when implementing a venue.
 package pkg
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"cosmossdk.io/math"
 	"github.com/gagliardetto/solana-go"
-	"github.com/gagliardetto/solana-go/rpc"
 )
 
 // ProtocolName represents the string name of AMM protocol
 type ProtocolName string
 
 const (
-	ProtocolNameRaydiumAmm    ProtocolName = "raydium_amm"
-	ProtocolNameRaydiumClmm   ProtocolName = "raydium_clmm"
-	ProtocolNameRaydiumCpmm   ProtocolName = "raydium_cpmm"
-	ProtocolNameMeteoraDlmm   ProtocolName = "meteora_dlmm"
-	ProtocolNamePumpAmm       ProtocolName = "pump_amm"
-	ProtocolNameOrcaWhirlpool ProtocolName = "orca_whirlpool"
+	ProtocolNameRaydiumAmm       ProtocolName = "raydium_amm"
+	ProtocolNameRaydiumClmm      ProtocolName = "raydium_clmm"
+	ProtocolNameRaydiumCpmm      ProtocolName = "raydium_cpmm"
+	ProtocolNameRaydiumLaunchLab ProtocolName = "raydium_launchlab"
+	ProtocolNameMeteoraDlmm      ProtocolName = "meteora_dlmm"
+	ProtocolNameMeteoraDamm      ProtocolName = "meteora_damm"
+	ProtocolNameMeteoraDammV2    ProtocolName = "meteora_damm_v2"
+	ProtocolNamePumpAmm          ProtocolName = "pump_amm"
+	ProtocolNameOrcaWhirlpool    ProtocolName = "orca_whirlpool"
+	ProtocolNamePhoenix          ProtocolName = "phoenix"
+	ProtocolNameMoonshot         ProtocolName = "moonshot"
+	ProtocolNameStakePool        ProtocolName = "stake_pool"
+	ProtocolNameSaber            ProtocolName = "saber"
 )
 
 // ProtocolType represents the numeric type of AMM protocol (matches contract enum)
@@ -30,6 +42,16 @@ const (
 	ProtocolTypeMeteoraDlmm
 	ProtocolTypePumpAmm
 	ProtocolTypeOrcaWhirlpool
+	ProtocolTypePhoenix
+	ProtocolTypeMeteoraDamm
+	ProtocolTypeMeteoraDammV2
+	ProtocolTypeRaydiumLaunchLab
+	ProtocolTypeMoonshot
+	ProtocolTypeStakePool
+	ProtocolTypeSaber
+
+	// ProtocolTypeExternal is reported by venues plugged in from outside this module
+	ProtocolTypeExternal ProtocolType = 255
 )
 
 type Pool interface {
@@ -38,18 +60,152 @@ type Pool interface {
 	GetProgramID() solana.PublicKey
 	GetID() string
 	GetTokens() (baseMint, quoteMint string)
-	Quote(ctx context.Context, solClient *rpc.Client, inputMint string, inputAmount math.Int) (math.Int, error)
+	Quoter
+	InstructionBuilder
+}
+
+// Quoter prices swaps against a pool's current state
+type Quoter interface {
+	Quote(ctx context.Context, solClient RPC, inputMint string, inputAmount math.Int) (math.Int, error)
+	// QuoteExactOut returns the input amount required to receive exactly desiredOut of outputMint
+	QuoteExactOut(ctx context.Context, solClient RPC, outputMint string, desiredOut math.Int) (math.Int, error)
+}
+
+// InstructionBuilder builds the instructions that execute a swap through a pool
+type InstructionBuilder interface {
 	BuildSwapInstructions(
 		ctx context.Context,
-		solClient *rpc.Client,
+		solClient RPC,
 		user solana.PublicKey,
 		inputMint string,
 		inputAmount math.Int,
 		minOut math.Int,
 	) ([]solana.Instruction, error)
+	// BuildSwapInstructionsExactOut builds a swap that delivers amountOut of outputMint,
+	// spending at most maxIn of the other token
+	BuildSwapInstructionsExactOut(
+		ctx context.Context,
+		solClient RPC,
+		user solana.PublicKey,
+		outputMint string,
+		amountOut math.Int,
+		maxIn math.Int,
+	) ([]solana.Instruction, error)
 }
 
 type Protocol interface {
 	FetchPoolsByPair(ctx context.Context, baseMint, quoteMint string) ([]Pool, error)
 	FetchPoolByID(ctx context.Context, poolID string) (Pool, error)
 }
+
+// NamedProtocol is implemented by protocols that report the ProtocolName of the pools they
+// discover, so pools recorded by name can be fetched again through them
+type NamedProtocol interface {
+	Protocol
+	Name() ProtocolName
+}
+
+// TokenVault is a pool-owned token account that must hold Mint and be controlled by Authority
+type TokenVault struct {
+	Address   solana.PublicKey
+	Mint      solana.PublicKey
+	Authority solana.PublicKey // zero skips the authority check
+}
+
+// VaultPool is implemented by pools that expose their token vaults for on-chain verification
+type VaultPool interface {
+	Pool
+	TokenVaults() []TokenVault
+}
+
+// FeeBreakdown splits the swap fee charged by a quote between liquidity providers and the protocol
+type FeeBreakdown struct {
+	Mint        string // token the fee is charged in
+	LpFee       math.Int
+	ProtocolFee math.Int
+}
+
+// FeeQuoter is implemented by pools that can report the fee split of a quote
+type FeeQuoter interface {
+	QuoteWithFees(ctx context.Context, solClient RPC, inputMint string, inputAmount math.Int) (math.Int, FeeBreakdown, error)
+}
+
+// Deprecation explains why a pool should no longer be routed through
+type Deprecation struct {
+	Reason    string
+	Successor string // ID of the pool the liquidity migrated to, empty if unknown
+}
+
+// DeprecatablePool is implemented by pools that can detect they were migrated, disabled or drained
+type DeprecatablePool interface {
+	Pool
+	// Deprecation returns nil while the pool is routable
+	Deprecation() *Deprecation
+	SetSuccessor(poolID string)
+}
+
+// PrefetchPool is implemented by pools that can list the accounts a quote reads, so a router
+// can fetch them for many pools in a few batched requests before quoting
+type PrefetchPool interface {
+	Pool
+	// QuoteAccounts returns the accounts Quote reads for inputMint, given the pool's current state
+	QuoteAccounts(inputMint string) []solana.PublicKey
+}
+
+// FeeRatePool is implemented by pools with a fixed swap fee rate
+type FeeRatePool interface {
+	Pool
+	// FeeRateBps returns the fee charged on a swap's input in basis points, rounded up
+	FeeRateBps() uint64
+}
+
+// OpenTimePool is implemented by pools that record when they opened for trading
+type OpenTimePool interface {
+	Pool
+	OpenedAt() time.Time
+}
+
+// DecodablePool is implemented by pools that can update themselves from their state account's
+// data, so a streaming source can keep them current without an RPC round trip
+type DecodablePool interface {
+	Pool
+	// Decode replaces the pool's on-chain state with data, keeping its ID
+	Decode(data []byte) error
+}
+
+// TransferFeePool is implemented by pools that read their mints' Token-2022 transfer fees
+// themselves, so their quotes must not be adjusted for them again
+type TransferFeePool interface {
+	Pool
+	// HandlesTransferFees reports whether the pool's quotes are net of transfer fees
+	HandlesTransferFees() bool
+}
+
+// SpotPrice is a pool's marginal price, what an infinitesimal swap would pay before fees
+type SpotPrice struct {
+	// Price is in raw units of the pool's second token per raw unit of its first, in the
+	// order of GetTokens
+	Price float64
+	// Depth is the liquidity at the price valued in raw units of the second token: both
+	// reserves of a constant product pool, the in-range liquidity of a concentrated liquidity
+	// pool, the active bin of a DLMM pool
+	Depth float64
+}
+
+// ReserveSpotPrice returns the SpotPrice of a constant product pool holding the reserves
+func ReserveSpotPrice(baseReserve, quoteReserve math.Int) (SpotPrice, error) {
+	if baseReserve.IsNil() || quoteReserve.IsNil() || !baseReserve.IsPositive() || !quoteReserve.IsPositive() {
+		return SpotPrice{}, fmt.Errorf("pool has no reserves")
+	}
+	base, _ := math.LegacyNewDecFromInt(baseReserve).Float64()
+	quote, _ := math.LegacyNewDecFromInt(quoteReserve).Float64()
+	return SpotPrice{Price: quote / base, Depth: 2 * quote}, nil
+}
+
+// SpotPricePool is implemented by pools that can report their spot price from their state
+type SpotPricePool interface {
+	Pool
+	// SpotPrice reads the pool's price, refreshing the reserves of pools that quote from
+	// them as Quote would
+	SpotPrice(ctx context.Context, solClient RPC) (SpotPrice, error)
+}
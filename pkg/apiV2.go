@@ -0,0 +1,312 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+)
+
+// The v2 interfaces take and return structs, so new options can be added as fields without
+// breaking implementations or callers. Zero values of optional fields keep the v1 behaviour.
+// AdaptPool and AdaptProtocol wrap v1 implementations; FromPoolV2 and FromProtocolV2 let v2
+// implementations be routed by code that still takes v1 interfaces.
+
+// PoolInfo identifies a pool and its tokens
+type PoolInfo struct {
+	ID           string
+	ProtocolName ProtocolName
+	ProtocolType ProtocolType
+	ProgramID    solana.PublicKey
+	BaseMint     string
+	QuoteMint    string
+}
+
+// QuoteRequest asks a pool for a price
+type QuoteRequest struct {
+	Client RPC
+	// InputMint and OutputMint are the swap's tokens; either may be left empty and is then
+	// taken as the pool's other token
+	InputMint  string
+	OutputMint string
+	// Amount is the input, or the desired output when ExactOut is set
+	Amount   math.Int
+	ExactOut bool
+	// WithFees asks for the LP/protocol fee split, if the pool can report it
+	WithFees bool
+}
+
+// QuoteResponse is a priced swap
+type QuoteResponse struct {
+	InputMint  string
+	OutputMint string
+	AmountIn   math.Int
+	AmountOut  math.Int
+	Fees       *FeeBreakdown // set when requested and reported
+}
+
+// SwapBuildRequest asks a pool for the instructions of a swap
+type SwapBuildRequest struct {
+	Client     RPC
+	User       solana.PublicKey
+	InputMint  string // either mint may be empty, as in QuoteRequest
+	OutputMint string
+	// Amount is the input, or the output when ExactOut is set
+	Amount   math.Int
+	ExactOut bool
+	// Limit is the minimum output of an exact-input swap or the maximum input of an
+	// exact-output swap
+	Limit math.Int
+}
+
+// SwapBuildResponse holds the instructions that execute a swap
+type SwapBuildResponse struct {
+	Instructions []solana.Instruction
+}
+
+// PoolV2 is Pool with request and response structs
+type PoolV2 interface {
+	Info() PoolInfo
+	Quote(ctx context.Context, req QuoteRequest) (QuoteResponse, error)
+	BuildSwap(ctx context.Context, req SwapBuildRequest) (SwapBuildResponse, error)
+}
+
+// FetchPoolsRequest selects pools by pair, or a single pool when PoolID is set
+type FetchPoolsRequest struct {
+	BaseMint  string
+	QuoteMint string
+	PoolID    string
+}
+
+// ProtocolV2 is Protocol with request structs
+type ProtocolV2 interface {
+	FetchPools(ctx context.Context, req FetchPoolsRequest) ([]PoolV2, error)
+}
+
+// ResolveMints fills in the missing side of a pair from the pool's tokens and checks both
+// mints belong to the pool
+func (info PoolInfo) ResolveMints(inputMint, outputMint string) (string, string, error) {
+	other := func(mint string) (string, error) {
+		switch mint {
+		case info.BaseMint:
+			return info.QuoteMint, nil
+		case info.QuoteMint:
+			return info.BaseMint, nil
+		default:
+			return "", fmt.Errorf("mint %s not in pool %s", mint, info.ID)
+		}
+	}
+	switch {
+	case inputMint == "" && outputMint == "":
+		return "", "", fmt.Errorf("input or output mint is required")
+	case inputMint == "":
+		in, err := other(outputMint)
+		return in, outputMint, err
+	case outputMint == "":
+		out, err := other(inputMint)
+		return inputMint, out, err
+	}
+	if out, err := other(inputMint); err != nil || out != outputMint {
+		return "", "", fmt.Errorf("pool %s does not swap %s for %s", info.ID, inputMint, outputMint)
+	}
+	return inputMint, outputMint, nil
+}
+
+// AdaptPool exposes a v1 pool through PoolV2
+func AdaptPool(pool Pool) PoolV2 {
+	if v1, ok := pool.(poolV1); ok {
+		return v1.PoolV2
+	}
+	return poolV2{pool}
+}
+
+// poolV2 adapts a v1 Pool to PoolV2
+type poolV2 struct {
+	pool Pool
+}
+
+func (p poolV2) Info() PoolInfo {
+	baseMint, quoteMint := p.pool.GetTokens()
+	return PoolInfo{
+		ID:           p.pool.GetID(),
+		ProtocolName: p.pool.ProtocolName(),
+		ProtocolType: p.pool.ProtocolType(),
+		ProgramID:    p.pool.GetProgramID(),
+		BaseMint:     baseMint,
+		QuoteMint:    quoteMint,
+	}
+}
+
+func (p poolV2) Quote(ctx context.Context, req QuoteRequest) (QuoteResponse, error) {
+	inputMint, outputMint, err := p.Info().ResolveMints(req.InputMint, req.OutputMint)
+	if err != nil {
+		return QuoteResponse{}, err
+	}
+	resp := QuoteResponse{InputMint: inputMint, OutputMint: outputMint}
+	if req.ExactOut {
+		resp.AmountOut = req.Amount
+		resp.AmountIn, err = p.pool.QuoteExactOut(ctx, req.Client, outputMint, req.Amount)
+	} else {
+		resp.AmountIn = req.Amount
+		resp.AmountOut, err = p.pool.Quote(ctx, req.Client, inputMint, req.Amount)
+	}
+	if err != nil {
+		return QuoteResponse{}, err
+	}
+	if feeQuoter, ok := p.pool.(FeeQuoter); ok && req.WithFees {
+		if _, fees, err := feeQuoter.QuoteWithFees(ctx, req.Client, inputMint, resp.AmountIn); err == nil {
+			resp.Fees = &fees
+		}
+	}
+	return resp, nil
+}
+
+func (p poolV2) BuildSwap(ctx context.Context, req SwapBuildRequest) (SwapBuildResponse, error) {
+	inputMint, outputMint, err := p.Info().ResolveMints(req.InputMint, req.OutputMint)
+	if err != nil {
+		return SwapBuildResponse{}, err
+	}
+	if req.Limit.IsNil() {
+		return SwapBuildResponse{}, fmt.Errorf("limit is required")
+	}
+	var instructions []solana.Instruction
+	if req.ExactOut {
+		instructions, err = p.pool.BuildSwapInstructionsExactOut(ctx, req.Client, req.User, outputMint, req.Amount, req.Limit)
+	} else {
+		instructions, err = p.pool.BuildSwapInstructions(ctx, req.Client, req.User, inputMint, req.Amount, req.Limit)
+	}
+	if err != nil {
+		return SwapBuildResponse{}, err
+	}
+	return SwapBuildResponse{Instructions: instructions}, nil
+}
+
+// FromPoolV2 exposes a v2 pool through the v1 Pool interface
+func FromPoolV2(pool PoolV2) Pool {
+	if v2, ok := pool.(poolV2); ok {
+		return v2.pool
+	}
+	return poolV1{pool}
+}
+
+// poolV1 adapts a PoolV2 to the v1 Pool
+type poolV1 struct {
+	PoolV2
+}
+
+func (p poolV1) ProtocolName() ProtocolName {
+	return p.Info().ProtocolName
+}
+
+func (p poolV1) ProtocolType() ProtocolType {
+	return p.Info().ProtocolType
+}
+
+func (p poolV1) GetProgramID() solana.PublicKey {
+	return p.Info().ProgramID
+}
+
+func (p poolV1) GetID() string {
+	return p.Info().ID
+}
+
+func (p poolV1) GetTokens() (string, string) {
+	info := p.Info()
+	return info.BaseMint, info.QuoteMint
+}
+
+func (p poolV1) Quote(ctx context.Context, solClient RPC, inputMint string, inputAmount math.Int) (math.Int, error) {
+	resp, err := p.PoolV2.Quote(ctx, QuoteRequest{Client: solClient, InputMint: inputMint, Amount: inputAmount})
+	if err != nil {
+		return math.Int{}, err
+	}
+	return resp.AmountOut, nil
+}
+
+func (p poolV1) QuoteExactOut(ctx context.Context, solClient RPC, outputMint string, desiredOut math.Int) (math.Int, error) {
+	resp, err := p.PoolV2.Quote(ctx, QuoteRequest{Client: solClient, OutputMint: outputMint, Amount: desiredOut, ExactOut: true})
+	if err != nil {
+		return math.Int{}, err
+	}
+	return resp.AmountIn, nil
+}
+
+func (p poolV1) BuildSwapInstructions(ctx context.Context, solClient RPC, user solana.PublicKey, inputMint string, inputAmount math.Int, minOut math.Int) ([]solana.Instruction, error) {
+	resp, err := p.BuildSwap(ctx, SwapBuildRequest{Client: solClient, User: user, InputMint: inputMint, Amount: inputAmount, Limit: minOut})
+	return resp.Instructions, err
+}
+
+func (p poolV1) BuildSwapInstructionsExactOut(ctx context.Context, solClient RPC, user solana.PublicKey, outputMint string, amountOut math.Int, maxIn math.Int) ([]solana.Instruction, error) {
+	resp, err := p.BuildSwap(ctx, SwapBuildRequest{Client: solClient, User: user, OutputMint: outputMint, Amount: amountOut, ExactOut: true, Limit: maxIn})
+	return resp.Instructions, err
+}
+
+// AdaptProtocol exposes a v1 protocol through ProtocolV2
+func AdaptProtocol(protocol Protocol) ProtocolV2 {
+	if v1, ok := protocol.(protocolV1); ok {
+		return v1.ProtocolV2
+	}
+	return protocolV2{protocol}
+}
+
+// protocolV2 adapts a v1 Protocol to ProtocolV2
+type protocolV2 struct {
+	protocol Protocol
+}
+
+func (p protocolV2) FetchPools(ctx context.Context, req FetchPoolsRequest) ([]PoolV2, error) {
+	if req.PoolID != "" {
+		pool, err := p.protocol.FetchPoolByID(ctx, req.PoolID)
+		if err != nil {
+			return nil, err
+		}
+		return []PoolV2{AdaptPool(pool)}, nil
+	}
+	pools, err := p.protocol.FetchPoolsByPair(ctx, req.BaseMint, req.QuoteMint)
+	if err != nil {
+		return nil, err
+	}
+	adapted := make([]PoolV2, len(pools))
+	for i, pool := range pools {
+		adapted[i] = AdaptPool(pool)
+	}
+	return adapted, nil
+}
+
+// FromProtocolV2 exposes a v2 protocol through the v1 Protocol interface, e.g. to pass it
+// to router.NewSimpleRouter
+func FromProtocolV2(protocol ProtocolV2) Protocol {
+	if v2, ok := protocol.(protocolV2); ok {
+		return v2.protocol
+	}
+	return protocolV1{protocol}
+}
+
+// protocolV1 adapts a ProtocolV2 to the v1 Protocol
+type protocolV1 struct {
+	ProtocolV2
+}
+
+func (p protocolV1) FetchPoolsByPair(ctx context.Context, baseMint, quoteMint string) ([]Pool, error) {
+	pools, err := p.FetchPools(ctx, FetchPoolsRequest{BaseMint: baseMint, QuoteMint: quoteMint})
+	if err != nil {
+		return nil, err
+	}
+	adapted := make([]Pool, len(pools))
+	for i, pool := range pools {
+		adapted[i] = FromPoolV2(pool)
+	}
+	return adapted, nil
+}
+
+func (p protocolV1) FetchPoolByID(ctx context.Context, poolID string) (Pool, error) {
+	pools, err := p.FetchPools(ctx, FetchPoolsRequest{PoolID: poolID})
+	if err != nil {
+		return nil, err
+	}
+	if len(pools) != 1 {
+		return nil, fmt.Errorf("expected pool %s, got %d pools", poolID, len(pools))
+	}
+	return FromPoolV2(pools[0]), nil
+}
@@ -0,0 +1,61 @@
+// Package clmmmath holds the Q64.64 fixed point math shared by the concentrated
+// liquidity pools (Raydium CLMM and Orca Whirlpool). Results match the on-chain
+// programs bit for bit, including the rounding direction of every division.
+package clmmmath
+
+import (
+	"math/big"
+
+	"cosmossdk.io/math"
+)
+
+// Resolution is the number of fractional bits of a Q64.64 value
+const Resolution = 64
+
+// Q64 is 1.0 in Q64.64
+var Q64 = new(big.Int).Lsh(big.NewInt(1), Resolution)
+
+// MaxUint128 is the largest u128, used for reciprocals of Q64.64 values
+var MaxUint128 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+
+// MulDivFloor returns a*b/denominator rounded down. It panics if denominator is zero.
+func MulDivFloor(a, b, denominator math.Int) math.Int {
+	if denominator.IsZero() {
+		panic("division by zero")
+	}
+	return a.Mul(b).Quo(denominator)
+}
+
+// MulDivCeil returns a*b/denominator rounded up. It panics if denominator is zero.
+func MulDivCeil(a, b, denominator math.Int) math.Int {
+	if denominator.IsZero() {
+		panic("division by zero")
+	}
+	return math.NewIntFromBigInt(mulDivCeil(a.BigInt(), b.BigInt(), denominator.BigInt()))
+}
+
+// bigOne is added to round quotients up
+var bigOne = big.NewInt(1)
+
+// mulDivCeil is MulDivCeil on non-negative big.Ints
+func mulDivCeil(a, b, denominator *big.Int) *big.Int {
+	return divCeil(new(big.Int).Mul(a, b), denominator)
+}
+
+// divCeil divides the non-negative numerator by denominator in place, rounding up
+func divCeil(numerator, denominator *big.Int) *big.Int {
+	remainder := new(big.Int)
+	numerator.QuoRem(numerator, denominator, remainder)
+	if remainder.Sign() != 0 {
+		numerator.Add(numerator, bigOne)
+	}
+	return numerator
+}
+
+// SpotPrice returns the price of token A in token B at a Q64.64 sqrt price, and the liquidity
+// in range there valued in token B: twice the virtual token B reserve liquidity * sqrtPrice
+func SpotPrice(sqrtPriceX64, liquidity *big.Int) (price, depth float64) {
+	sqrtPrice, _ := new(big.Float).Quo(new(big.Float).SetInt(sqrtPriceX64), new(big.Float).SetInt(Q64)).Float64()
+	l, _ := new(big.Float).SetInt(liquidity).Float64()
+	return sqrtPrice * sqrtPrice, 2 * l * sqrtPrice
+}
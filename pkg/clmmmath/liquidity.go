@@ -0,0 +1,116 @@
+package clmmmath
+
+import (
+	"math/big"
+)
+
+// orderedPrices returns the two sqrt prices low first, panicking on a non-positive price
+func orderedPrices(sqrtPriceX64A, sqrtPriceX64B *big.Int) (*big.Int, *big.Int) {
+	if sqrtPriceX64A.Cmp(sqrtPriceX64B) > 0 {
+		sqrtPriceX64A, sqrtPriceX64B = sqrtPriceX64B, sqrtPriceX64A
+	}
+	if sqrtPriceX64A.Sign() <= 0 {
+		panic("sqrtPriceX64A must be greater than 0")
+	}
+	return sqrtPriceX64A, sqrtPriceX64B
+}
+
+// GetTokenAmountAFromLiquidity returns the token A amount between two sqrt prices:
+// liquidity * (sqrtB - sqrtA) / (sqrtA * sqrtB), in either price order
+func GetTokenAmountAFromLiquidity(sqrtPriceX64A, sqrtPriceX64B, liquidity *big.Int, roundUp bool) *big.Int {
+	priceA, priceB := orderedPrices(sqrtPriceX64A, sqrtPriceX64B)
+
+	numerator1 := new(big.Int).Lsh(liquidity, Resolution)
+	numerator2 := new(big.Int).Sub(priceB, priceA)
+
+	if roundUp {
+		return divCeil(mulDivCeil(numerator1, numerator2, priceB), priceA)
+	}
+	numerator1.Mul(numerator1, numerator2)
+	numerator1.Quo(numerator1, priceB)
+	return numerator1.Quo(numerator1, priceA)
+}
+
+// GetTokenAmountBFromLiquidity returns the token B amount between two sqrt prices:
+// liquidity * (sqrtB - sqrtA), in either price order
+func GetTokenAmountBFromLiquidity(sqrtPriceX64A, sqrtPriceX64B, liquidity *big.Int, roundUp bool) *big.Int {
+	priceA, priceB := orderedPrices(sqrtPriceX64A, sqrtPriceX64B)
+
+	amount := new(big.Int).Sub(priceB, priceA)
+	amount.Mul(amount, liquidity)
+	if roundUp {
+		return divCeil(amount, Q64)
+	}
+	return amount.Rsh(amount, Resolution)
+}
+
+// GetNextSqrtPriceX64FromInput returns the sqrt price after adding amount of the input
+// token, rounding so the pool never gives away more than it receives
+func GetNextSqrtPriceX64FromInput(sqrtPriceX64Current, liquidity, amount *big.Int, zeroForOne bool) *big.Int {
+	if sqrtPriceX64Current.Sign() <= 0 {
+		panic("sqrtPriceX64Current must be greater than 0")
+	}
+	if liquidity.Sign() <= 0 {
+		panic("liquidity must be greater than 0")
+	}
+	if amount.Sign() == 0 {
+		return sqrtPriceX64Current
+	}
+
+	if zeroForOne {
+		return getNextSqrtPriceFromTokenAmountARoundingUp(sqrtPriceX64Current, liquidity, amount, true)
+	}
+	return getNextSqrtPriceFromTokenAmountBRoundingDown(sqrtPriceX64Current, liquidity, amount, true)
+}
+
+// GetNextSqrtPriceX64FromOutput returns the sqrt price after removing amount of the
+// output token
+func GetNextSqrtPriceX64FromOutput(sqrtPriceX64Current, liquidity, amount *big.Int, zeroForOne bool) *big.Int {
+	if sqrtPriceX64Current.Sign() <= 0 {
+		panic("sqrtPriceX64Current must be greater than 0")
+	}
+	if liquidity.Sign() <= 0 {
+		panic("liquidity must be greater than 0")
+	}
+
+	if zeroForOne {
+		return getNextSqrtPriceFromTokenAmountBRoundingDown(sqrtPriceX64Current, liquidity, amount, false)
+	}
+	return getNextSqrtPriceFromTokenAmountARoundingUp(sqrtPriceX64Current, liquidity, amount, false)
+}
+
+func getNextSqrtPriceFromTokenAmountARoundingUp(sqrtPriceX64, liquidity, amount *big.Int, add bool) *big.Int {
+	if amount.Sign() == 0 {
+		return sqrtPriceX64
+	}
+
+	liquidityLeftShift := new(big.Int).Lsh(liquidity, Resolution)
+	amountMulSqrtPrice := new(big.Int).Mul(amount, sqrtPriceX64)
+
+	// amountMulSqrtPrice becomes the denominator
+	if add {
+		amountMulSqrtPrice.Add(liquidityLeftShift, amountMulSqrtPrice)
+		return mulDivCeil(liquidityLeftShift, sqrtPriceX64, amountMulSqrtPrice)
+	}
+
+	if liquidityLeftShift.Cmp(amountMulSqrtPrice) <= 0 {
+		panic("liquidity must be greater than amount * sqrtPrice")
+	}
+	amountMulSqrtPrice.Sub(liquidityLeftShift, amountMulSqrtPrice)
+	return mulDivCeil(liquidityLeftShift, sqrtPriceX64, amountMulSqrtPrice)
+}
+
+func getNextSqrtPriceFromTokenAmountBRoundingDown(sqrtPriceX64, liquidity, amount *big.Int, add bool) *big.Int {
+	deltaY := new(big.Int).Lsh(amount, Resolution)
+
+	if add {
+		deltaY.Quo(deltaY, liquidity)
+		return deltaY.Add(sqrtPriceX64, deltaY)
+	}
+
+	amountDivLiquidity := divCeil(deltaY, liquidity)
+	if sqrtPriceX64.Cmp(amountDivLiquidity) <= 0 {
+		panic("sqrtPriceX64 must be greater than amountDivLiquidity")
+	}
+	return amountDivLiquidity.Sub(sqrtPriceX64, amountDivLiquidity)
+}
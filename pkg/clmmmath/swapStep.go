@@ -0,0 +1,274 @@
+package clmmmath
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/uint256"
+	"cosmossdk.io/math"
+)
+
+// FeeRateDenominator is the unit of the pools' fee rates, parts per million
+const FeeRateDenominator = 1_000_000
+
+// errOverflow is returned when a swap step's math leaves 256 bits, where the programs fail too
+var errOverflow = errors.New("swap step overflows 256 bits")
+
+// SwapStep is a swap within one price range: the price it moves to, the input it takes net
+// of fees, the output it gives and the fee it charges on top of the input
+type SwapStep struct {
+	SqrtPriceNextX64 uint256.Int
+	AmountIn         uint256.Int
+	AmountOut        uint256.Int
+	FeeAmount        uint256.Int
+}
+
+// ComputeSwapStep swaps amountRemaining from sqrtPriceCurrentX64 toward sqrtPriceTargetX64
+// with liquidity in range, following the programs' compute_swap_step. amountRemaining is the
+// input, fees included, when exactIn, and the output wanted otherwise. feeRate is in parts
+// per million. The math runs on 256-bit integers with 512-bit products, without allocating.
+func ComputeSwapStep(sqrtPriceCurrentX64, sqrtPriceTargetX64, liquidity, amountRemaining *uint256.Int, exactIn bool, feeRate uint32, zeroForOne bool) (SwapStep, error) {
+	if feeRate >= FeeRateDenominator {
+		return SwapStep{}, errors.New("fee rate must be below 100%")
+	}
+	denominator := uint256.NewInt(FeeRateDenominator)
+	fee := uint256.NewInt(uint64(feeRate))
+	afterFee := uint256.NewInt(FeeRateDenominator - uint64(feeRate))
+
+	var step SwapStep
+	var err error
+	if exactIn {
+		var remainingAfterFee uint256.Int
+		if _, overflow := remainingAfterFee.MulDivOverflow(amountRemaining, afterFee, denominator); overflow {
+			return SwapStep{}, errOverflow
+		}
+		if zeroForOne {
+			err = tokenAmountA(&step.AmountIn, sqrtPriceTargetX64, sqrtPriceCurrentX64, liquidity, true)
+		} else {
+			err = tokenAmountB(&step.AmountIn, sqrtPriceCurrentX64, sqrtPriceTargetX64, liquidity, true)
+		}
+		if err != nil {
+			return SwapStep{}, err
+		}
+		if remainingAfterFee.Cmp(&step.AmountIn) >= 0 {
+			step.SqrtPriceNextX64 = *sqrtPriceTargetX64
+		} else if err := nextSqrtPriceFromInput(&step.SqrtPriceNextX64, sqrtPriceCurrentX64, liquidity, &remainingAfterFee, zeroForOne); err != nil {
+			return SwapStep{}, err
+		}
+	} else {
+		if zeroForOne {
+			err = tokenAmountB(&step.AmountOut, sqrtPriceTargetX64, sqrtPriceCurrentX64, liquidity, false)
+		} else {
+			err = tokenAmountA(&step.AmountOut, sqrtPriceCurrentX64, sqrtPriceTargetX64, liquidity, false)
+		}
+		if err != nil {
+			return SwapStep{}, err
+		}
+		if amountRemaining.Cmp(&step.AmountOut) >= 0 {
+			step.SqrtPriceNextX64 = *sqrtPriceTargetX64
+		} else if err := nextSqrtPriceFromOutput(&step.SqrtPriceNextX64, sqrtPriceCurrentX64, liquidity, amountRemaining, zeroForOne); err != nil {
+			return SwapStep{}, err
+		}
+	}
+
+	// Recompute the amounts between the current and next price, except the one the target
+	// price was reached with
+	reachedTarget := step.SqrtPriceNextX64.Eq(sqrtPriceTargetX64)
+	if !(reachedTarget && exactIn) {
+		if zeroForOne {
+			err = tokenAmountA(&step.AmountIn, &step.SqrtPriceNextX64, sqrtPriceCurrentX64, liquidity, true)
+		} else {
+			err = tokenAmountB(&step.AmountIn, sqrtPriceCurrentX64, &step.SqrtPriceNextX64, liquidity, true)
+		}
+		if err != nil {
+			return SwapStep{}, err
+		}
+	}
+	if !(reachedTarget && !exactIn) {
+		if zeroForOne {
+			err = tokenAmountB(&step.AmountOut, &step.SqrtPriceNextX64, sqrtPriceCurrentX64, liquidity, false)
+		} else {
+			err = tokenAmountA(&step.AmountOut, sqrtPriceCurrentX64, &step.SqrtPriceNextX64, liquidity, false)
+		}
+		if err != nil {
+			return SwapStep{}, err
+		}
+	}
+
+	// The output can't exceed what's left of an exact output swap
+	if !exactIn && step.AmountOut.Gt(amountRemaining) {
+		step.AmountOut = *amountRemaining
+	}
+
+	if exactIn && !reachedTarget {
+		// A step that stops short of the target keeps the rest of the input as its fee
+		step.FeeAmount.Sub(amountRemaining, &step.AmountIn)
+	} else if _, overflow := step.FeeAmount.MulDivCeilOverflow(&step.AmountIn, fee, afterFee); overflow {
+		return SwapStep{}, errOverflow
+	}
+	return step, nil
+}
+
+// orderedSqrtPrices returns the two sqrt prices low first, failing on a zero price
+func orderedSqrtPrices(sqrtPriceX64A, sqrtPriceX64B *uint256.Int) (*uint256.Int, *uint256.Int, error) {
+	if sqrtPriceX64A.Gt(sqrtPriceX64B) {
+		sqrtPriceX64A, sqrtPriceX64B = sqrtPriceX64B, sqrtPriceX64A
+	}
+	if sqrtPriceX64A.IsZero() {
+		return nil, nil, errors.New("sqrt price must be greater than 0")
+	}
+	return sqrtPriceX64A, sqrtPriceX64B, nil
+}
+
+// tokenAmountA is GetTokenAmountAFromLiquidity on 256-bit integers, setting z
+func tokenAmountA(z, sqrtPriceX64A, sqrtPriceX64B, liquidity *uint256.Int, roundUp bool) error {
+	priceA, priceB, err := orderedSqrtPrices(sqrtPriceX64A, sqrtPriceX64B)
+	if err != nil {
+		return err
+	}
+	if liquidity.BitLen() > 256-Resolution {
+		return errOverflow
+	}
+	var numerator1, numerator2 uint256.Int
+	numerator1.Lsh(liquidity, Resolution)
+	numerator2.Sub(priceB, priceA)
+	if roundUp {
+		if _, overflow := z.MulDivCeilOverflow(&numerator1, &numerator2, priceB); overflow {
+			return errOverflow
+		}
+		if _, overflow := z.MulDivCeilOverflow(z, uint256.NewInt(1), priceA); overflow {
+			return errOverflow
+		}
+		return nil
+	}
+	if _, overflow := z.MulDivOverflow(&numerator1, &numerator2, priceB); overflow {
+		return errOverflow
+	}
+	z.Div(z, priceA)
+	return nil
+}
+
+// tokenAmountB is GetTokenAmountBFromLiquidity on 256-bit integers, setting z
+func tokenAmountB(z, sqrtPriceX64A, sqrtPriceX64B, liquidity *uint256.Int, roundUp bool) error {
+	priceA, priceB, err := orderedSqrtPrices(sqrtPriceX64A, sqrtPriceX64B)
+	if err != nil {
+		return err
+	}
+	var priceDiff uint256.Int
+	priceDiff.Sub(priceB, priceA)
+	q64 := new(uint256.Int).Lsh(uint256.NewInt(1), Resolution)
+	var overflow bool
+	if roundUp {
+		_, overflow = z.MulDivCeilOverflow(liquidity, &priceDiff, q64)
+	} else {
+		_, overflow = z.MulDivOverflow(liquidity, &priceDiff, q64)
+	}
+	if overflow {
+		return errOverflow
+	}
+	return nil
+}
+
+// nextSqrtPriceFromInput is GetNextSqrtPriceX64FromInput on 256-bit integers, setting z
+func nextSqrtPriceFromInput(z, sqrtPriceX64, liquidity, amount *uint256.Int, zeroForOne bool) error {
+	if sqrtPriceX64.IsZero() || liquidity.IsZero() {
+		return errors.New("sqrt price and liquidity must be greater than 0")
+	}
+	if amount.IsZero() {
+		z.Set(sqrtPriceX64)
+		return nil
+	}
+	if zeroForOne {
+		return nextSqrtPriceFromAmountA(z, sqrtPriceX64, liquidity, amount, true)
+	}
+	return nextSqrtPriceFromAmountB(z, sqrtPriceX64, liquidity, amount, true)
+}
+
+// nextSqrtPriceFromOutput is GetNextSqrtPriceX64FromOutput on 256-bit integers, setting z
+func nextSqrtPriceFromOutput(z, sqrtPriceX64, liquidity, amount *uint256.Int, zeroForOne bool) error {
+	if sqrtPriceX64.IsZero() || liquidity.IsZero() {
+		return errors.New("sqrt price and liquidity must be greater than 0")
+	}
+	if zeroForOne {
+		return nextSqrtPriceFromAmountB(z, sqrtPriceX64, liquidity, amount, false)
+	}
+	return nextSqrtPriceFromAmountA(z, sqrtPriceX64, liquidity, amount, false)
+}
+
+// nextSqrtPriceFromAmountA moves the price by adding or removing amount of token A, rounding
+// up: liquidity * sqrtPrice / (liquidity ± amount * sqrtPrice)
+func nextSqrtPriceFromAmountA(z, sqrtPriceX64, liquidity, amount *uint256.Int, add bool) error {
+	if amount.IsZero() {
+		z.Set(sqrtPriceX64)
+		return nil
+	}
+	if liquidity.BitLen() > 256-Resolution {
+		return errOverflow
+	}
+	var liquidityShifted, denominator uint256.Int
+	liquidityShifted.Lsh(liquidity, Resolution)
+	if _, overflow := denominator.MulOverflow(amount, sqrtPriceX64); overflow {
+		return errOverflow
+	}
+	if add {
+		if _, overflow := denominator.AddOverflow(&liquidityShifted, &denominator); overflow {
+			return errOverflow
+		}
+	} else {
+		if liquidityShifted.Cmp(&denominator) <= 0 {
+			return errors.New("liquidity must be greater than amount * sqrtPrice")
+		}
+		denominator.Sub(&liquidityShifted, &denominator)
+	}
+	if _, overflow := z.MulDivCeilOverflow(&liquidityShifted, sqrtPriceX64, &denominator); overflow {
+		return errOverflow
+	}
+	return nil
+}
+
+// nextSqrtPriceFromAmountB moves the price by adding or removing amount of token B, rounding
+// down: sqrtPrice ± amount / liquidity
+func nextSqrtPriceFromAmountB(z, sqrtPriceX64, liquidity, amount *uint256.Int, add bool) error {
+	if amount.BitLen() > 256-Resolution {
+		return errOverflow
+	}
+	var delta uint256.Int
+	delta.Lsh(amount, Resolution)
+	if add {
+		delta.Div(&delta, liquidity)
+		if _, overflow := z.AddOverflow(sqrtPriceX64, &delta); overflow {
+			return errOverflow
+		}
+		return nil
+	}
+	if _, overflow := delta.MulDivCeilOverflow(&delta, uint256.NewInt(1), liquidity); overflow {
+		return errOverflow
+	}
+	if sqrtPriceX64.Cmp(&delta) <= 0 {
+		return errors.New("sqrt price must be greater than amount / liquidity")
+	}
+	z.Sub(sqrtPriceX64, &delta)
+	return nil
+}
+
+// ComputeSwapStepInt is ComputeSwapStep for the pools' math.Int swap loops, where a positive
+// amountRemaining is an exact input and a negative one an exact output
+func ComputeSwapStepInt(sqrtPriceCurrentX64, sqrtPriceTargetX64, liquidity, amountRemaining math.Int, feeRate uint32, zeroForOne bool) (sqrtPriceNextX64, amountIn, amountOut, feeAmount math.Int, err error) {
+	exactIn := !amountRemaining.IsNegative()
+	if !exactIn {
+		amountRemaining = amountRemaining.Neg()
+	}
+	var args [4]*uint256.Int
+	for i, value := range []math.Int{sqrtPriceCurrentX64, sqrtPriceTargetX64, liquidity, amountRemaining} {
+		var overflow bool
+		if args[i], overflow = uint256.FromBig(value.BigIntMut()); overflow {
+			return math.Int{}, math.Int{}, math.Int{}, math.Int{}, fmt.Errorf("swap step argument %s out of range", value)
+		}
+	}
+	step, err := ComputeSwapStep(args[0], args[1], args[2], args[3], exactIn, feeRate, zeroForOne)
+	if err != nil {
+		return math.Int{}, math.Int{}, math.Int{}, math.Int{}, err
+	}
+	return math.NewIntFromBigIntMut(step.SqrtPriceNextX64.ToBig()), math.NewIntFromBigIntMut(step.AmountIn.ToBig()),
+		math.NewIntFromBigIntMut(step.AmountOut.ToBig()), math.NewIntFromBigIntMut(step.FeeAmount.ToBig()), nil
+}
@@ -0,0 +1,151 @@
+package clmmmath
+
+import (
+	"errors"
+	"math/big"
+
+	"cosmossdk.io/math"
+)
+
+// Tick range and the sqrt prices at its ends, as defined by the Raydium CLMM program
+const (
+	MinTick = -443636
+	MaxTick = 443636
+)
+
+var (
+	MinSqrtPriceX64    = math.NewInt(4295048016)
+	MaxSqrtPriceX64, _ = math.NewIntFromString("79226673521066979257578248091")
+)
+
+// tickRatios[i] is sqrt(1.0001)^-(2^(i+1)) in Q64.64; bit 0 is the starting ratio
+var tickRatios = [...]*big.Int{
+	mustBig("18444899583751176192"),
+	mustBig("18443055278223355904"),
+	mustBig("18439367220385607680"),
+	mustBig("18431993317065453568"),
+	mustBig("18417254355718170624"),
+	mustBig("18387811781193609216"),
+	mustBig("18329067761203558400"),
+	mustBig("18212142134806163456"),
+	mustBig("17980523815641700352"),
+	mustBig("17526086738831433728"),
+	mustBig("16651378430235570176"),
+	mustBig("15030750278694412288"),
+	mustBig("12247334978884435968"),
+	mustBig("8131365268886854656"),
+	mustBig("3584323654725218816"),
+	mustBig("696457651848324352"),
+	mustBig("26294789957507116"),
+	mustBig("37481735321082"),
+}
+
+var tickRatioOdd = mustBig("18445821805675395072")
+
+// Constants for the log approximation in TickFromSqrtPriceX64
+var (
+	bitPrecision           = 14
+	logB2X32               = mustBig("59543866431248")
+	logBPErrMarginLowerX64 = mustBig("184467440737095516")
+	logBPErrMarginUpperX64 = mustBig("15793534762490258745")
+)
+
+func mustBig(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic("invalid integer constant: " + s)
+	}
+	return n
+}
+
+// SqrtPriceX64FromTick returns sqrt(1.0001^tick) in Q64.64, following the Raydium
+// CLMM program's get_sqrt_price_at_tick
+func SqrtPriceX64FromTick(tick int64) (math.Int, error) {
+	if tick < MinTick || tick > MaxTick {
+		return math.Int{}, errors.New("tick must be in MIN_TICK and MAX_TICK")
+	}
+
+	tickAbs := tick
+	if tick < 0 {
+		tickAbs = -tick
+	}
+
+	ratio := new(big.Int).Set(Q64)
+	if tickAbs&0x1 != 0 {
+		ratio.Set(tickRatioOdd)
+	}
+	for i, mulBy := range tickRatios {
+		if tickAbs&(0x2<<i) != 0 {
+			ratio.Mul(ratio, mulBy)
+			ratio.Rsh(ratio, Resolution)
+		}
+	}
+
+	if tick > 0 {
+		ratio.Quo(MaxUint128, ratio)
+	}
+	return math.NewIntFromBigInt(ratio), nil
+}
+
+// TickFromSqrtPriceX64 returns the greatest tick whose sqrt price is at most sqrtPriceX64,
+// following the Raydium CLMM program's get_tick_at_sqrt_price
+func TickFromSqrtPriceX64(sqrtPriceX64 math.Int) (int64, error) {
+	if sqrtPriceX64.GT(MaxSqrtPriceX64) || sqrtPriceX64.LT(MinSqrtPriceX64) {
+		return 0, errors.New("provided sqrtPrice is not within the supported sqrtPrice range")
+	}
+	return tickFromSqrtPrice(sqrtPriceX64, SqrtPriceX64FromTick)
+}
+
+// tickFromSqrtPrice approximates log_sqrt(1.0001)(price) and settles the two candidate
+// ticks with the program's own tick to price conversion
+func tickFromSqrtPrice(sqrtPriceX64 math.Int, sqrtPriceFromTick func(int64) (math.Int, error)) (int64, error) {
+	price := sqrtPriceX64.BigInt()
+
+	// Integer part of log2(price) as Q32.32
+	msb := price.BitLen() - 1
+	log2pIntegerX32 := big.NewInt(int64(msb - 64))
+	log2pIntegerX32.Lsh(log2pIntegerX32, 32)
+
+	// Normalise price into [1, 2) as Q1.63 and square repeatedly to extract fractional bits
+	var r *big.Int
+	if msb >= 64 {
+		r = new(big.Int).Rsh(price, uint(msb-63))
+	} else {
+		r = new(big.Int).Lsh(price, uint(63-msb))
+	}
+
+	bit := new(big.Int).Lsh(big.NewInt(1), 63)
+	log2pFractionX64 := new(big.Int)
+	for precision := 0; bit.Sign() > 0 && precision < bitPrecision; precision++ {
+		r.Mul(r, r)
+		rMoreThanTwo := r.Bit(127)
+		r.Rsh(r, uint(63+rMoreThanTwo))
+		if rMoreThanTwo == 1 {
+			log2pFractionX64.Add(log2pFractionX64, bit)
+		}
+		bit.Rsh(bit, 1)
+	}
+
+	log2pX32 := new(big.Int).Rsh(log2pFractionX64, 32)
+	log2pX32.Add(log2pX32, log2pIntegerX32)
+	logbpX64 := new(big.Int).Mul(log2pX32, logB2X32)
+
+	// Rsh floors toward negative infinity, matching the program's arithmetic shift
+	tickLow := new(big.Int).Sub(logbpX64, logBPErrMarginLowerX64)
+	tickLow.Rsh(tickLow, 64)
+	tickHigh := new(big.Int).Add(logbpX64, logBPErrMarginUpperX64)
+	tickHigh.Rsh(tickHigh, 64)
+
+	if tickLow.Cmp(tickHigh) == 0 {
+		return tickLow.Int64(), nil
+	}
+
+	derivedTickHighSqrtPriceX64, err := sqrtPriceFromTick(tickHigh.Int64())
+	if err != nil {
+		return 0, err
+	}
+	if derivedTickHighSqrtPriceX64.LTE(sqrtPriceX64) {
+		return tickHigh.Int64(), nil
+	}
+	return tickLow.Int64(), nil
+}
@@ -0,0 +1,110 @@
+package clmmmath
+
+import (
+	"errors"
+	"math/big"
+
+	"cosmossdk.io/math"
+)
+
+// Orca Whirlpool shares the tick range and log approximation with Raydium CLMM but computes
+// tick prices with more precise constants, so its sqrt prices differ in the low bits and its
+// maximum sqrt price is lower.
+var (
+	WhirlpoolMinSqrtPriceX64    = math.NewInt(4295048016)
+	WhirlpoolMaxSqrtPriceX64, _ = math.NewIntFromString("79226673515401279992447579055")
+)
+
+// whirlpoolPositiveRatios[i] is sqrt(1.0001)^(2^(i+1)) in Q32.96
+var whirlpoolPositiveRatios = [...]*big.Int{
+	mustBig("79236085330515764027303304731"),
+	mustBig("79244008939048815603706035061"),
+	mustBig("79259858533276714757314932305"),
+	mustBig("79291567232598584799939703904"),
+	mustBig("79355022692464371645785046466"),
+	mustBig("79482085999252804386437311141"),
+	mustBig("79736823300114093921829183326"),
+	mustBig("80248749790819932309965073892"),
+	mustBig("81282483887344747381513967011"),
+	mustBig("83390072131320151908154831281"),
+	mustBig("87770609709833776024991924138"),
+	mustBig("97234110755111693312479820773"),
+	mustBig("119332217159966728226237229890"),
+	mustBig("179736315981702064433883588727"),
+	mustBig("407748233172238350107850275304"),
+	mustBig("2098478828474011932436660412517"),
+	mustBig("55581415166113811149459800483533"),
+	mustBig("38992368544603139932233054999993551"),
+}
+
+// whirlpoolNegativeRatios[i] is sqrt(1.0001)^-(2^(i+1)) in Q64.64
+var whirlpoolNegativeRatios = [...]*big.Int{
+	mustBig("18444899583751176498"),
+	mustBig("18443055278223354162"),
+	mustBig("18439367220385604838"),
+	mustBig("18431993317065449817"),
+	mustBig("18417254355718160513"),
+	mustBig("18387811781193591352"),
+	mustBig("18329067761203520168"),
+	mustBig("18212142134806087854"),
+	mustBig("17980523815641551639"),
+	mustBig("17526086738831147013"),
+	mustBig("16651378430235024244"),
+	mustBig("15030750278693429944"),
+	mustBig("12247334978882834399"),
+	mustBig("8131365268884726200"),
+	mustBig("3584323654723342297"),
+	mustBig("696457651847595233"),
+	mustBig("26294789957452057"),
+	mustBig("37481735321082"),
+}
+
+var (
+	whirlpoolPositiveRatioOdd = mustBig("79232123823359799118286999567")
+	whirlpoolNegativeRatioOdd = mustBig("18445821805675392311")
+	q96                       = new(big.Int).Lsh(big.NewInt(1), 96)
+)
+
+// WhirlpoolSqrtPriceX64FromTick returns sqrt(1.0001^tick) in Q64.64, following the Whirlpool
+// program's sqrt_price_from_tick_index
+func WhirlpoolSqrtPriceX64FromTick(tick int64) (math.Int, error) {
+	if tick < MinTick || tick > MaxTick {
+		return math.Int{}, errors.New("tick must be in MIN_TICK and MAX_TICK")
+	}
+
+	if tick >= 0 {
+		ratio := new(big.Int).Set(q96)
+		if tick&0x1 != 0 {
+			ratio.Set(whirlpoolPositiveRatioOdd)
+		}
+		for i, mulBy := range whirlpoolPositiveRatios {
+			if tick&(0x2<<i) != 0 {
+				ratio.Mul(ratio, mulBy)
+				ratio.Rsh(ratio, 96)
+			}
+		}
+		return math.NewIntFromBigInt(ratio.Rsh(ratio, 32)), nil
+	}
+
+	tickAbs := -tick
+	ratio := new(big.Int).Set(Q64)
+	if tickAbs&0x1 != 0 {
+		ratio.Set(whirlpoolNegativeRatioOdd)
+	}
+	for i, mulBy := range whirlpoolNegativeRatios {
+		if tickAbs&(0x2<<i) != 0 {
+			ratio.Mul(ratio, mulBy)
+			ratio.Rsh(ratio, Resolution)
+		}
+	}
+	return math.NewIntFromBigInt(ratio), nil
+}
+
+// WhirlpoolTickFromSqrtPriceX64 returns the greatest tick whose Whirlpool sqrt price is at most
+// sqrtPriceX64, following the Whirlpool program's tick_index_from_sqrt_price
+func WhirlpoolTickFromSqrtPriceX64(sqrtPriceX64 math.Int) (int64, error) {
+	if sqrtPriceX64.GT(WhirlpoolMaxSqrtPriceX64) || sqrtPriceX64.LT(WhirlpoolMinSqrtPriceX64) {
+		return 0, errors.New("provided sqrtPrice is not within the supported sqrtPrice range")
+	}
+	return tickFromSqrtPrice(sqrtPriceX64, WhirlpoolSqrtPriceX64FromTick)
+}
@@ -0,0 +1,157 @@
+// Package clock abstracts wall-clock time, sleeping and randomness so that retry,
+// backoff and expiry logic can be driven deterministically in tests
+package clock
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Clock reports the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// Sleeper waits for a duration, returning early with ctx.Err() if ctx is done
+type Sleeper interface {
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// Ticker delivers the time on the returned channel every d until stop is called. Like
+// time.Ticker, ticks a slow receiver misses are dropped.
+type Ticker interface {
+	NewTicker(d time.Duration) (ticks <-chan time.Time, stop func())
+}
+
+// NewTicker ticks every d through clk when it implements Ticker and the wall clock otherwise
+func NewTicker(clk Clock, d time.Duration) (<-chan time.Time, func()) {
+	if ticker, ok := clk.(Ticker); ok {
+		return ticker.NewTicker(d)
+	}
+	return System{}.NewTicker(d)
+}
+
+// Rand is a source of randomness for jitter; *math/rand.Rand satisfies it
+type Rand interface {
+	Float64() float64
+}
+
+// System uses the wall clock, real timers and the global random source
+type System struct{}
+
+func (System) Now() time.Time {
+	return time.Now()
+}
+
+func (System) Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (System) NewTicker(d time.Duration) (<-chan time.Time, func()) {
+	ticker := time.NewTicker(d)
+	return ticker.C, ticker.Stop
+}
+
+func (System) Float64() float64 {
+	return rand.Float64()
+}
+
+// Fake is a manually driven Clock, Sleeper and Ticker. Sleep returns immediately and
+// advances the fake time, recording the requested duration; tickers fire as the fake time
+// passes their next tick.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	sleeps  []time.Duration
+	tickers []*fakeTicker
+}
+
+type fakeTicker struct {
+	next   time.Time
+	period time.Duration
+	ticks  chan time.Time
+}
+
+// NewFake creates a fake clock starting at start
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sleeps = append(f.sleeps, d)
+	if d > 0 {
+		f.advance(d)
+	}
+	return nil
+}
+
+func (f *Fake) NewTicker(d time.Duration) (<-chan time.Time, func()) {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ticker := &fakeTicker{next: f.now.Add(d), period: d, ticks: make(chan time.Time, 1)}
+	f.tickers = append(f.tickers, ticker)
+	return ticker.ticks, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for i, t := range f.tickers {
+			if t == ticker {
+				f.tickers = append(f.tickers[:i], f.tickers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Advance moves the fake time forward by d
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.advance(d)
+}
+
+// advance moves the time forward and fires the tickers it passes; f.mu must be held
+func (f *Fake) advance(d time.Duration) {
+	f.now = f.now.Add(d)
+	for _, ticker := range f.tickers {
+		for !ticker.next.After(f.now) {
+			select {
+			case ticker.ticks <- ticker.next:
+			default:
+			}
+			ticker.next = ticker.next.Add(ticker.period)
+		}
+	}
+}
+
+// Sleeps returns the durations passed to Sleep so far
+func (f *Fake) Sleeps() []time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]time.Duration(nil), f.sleeps...)
+}
@@ -0,0 +1,324 @@
+// Package config loads the settings shared by the router and the RPC clients, RPC endpoints,
+// enabled protocols, slippage, commitment, rate limits and priority fees, from a YAML or JSON
+// file and the environment, and validates them before anything connects.
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/protocolregistry"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gagliardetto/solana-go/rpc"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultRPCURL and DefaultWSURL are Solana's public mainnet endpoints
+	DefaultRPCURL = "https://api.mainnet-beta.solana.com"
+	DefaultWSURL  = "wss://api.mainnet-beta.solana.com"
+	// DefaultSlippageBps is the slippage tolerance of swaps that don't set one
+	DefaultSlippageBps = 100
+
+	// EnvConfigPath names the config file Load reads when given no path
+	EnvConfigPath = "SOLROUTE_CONFIG"
+)
+
+// Environment variables override the file, so deployments can keep one file and vary secrets
+// and endpoints per environment
+const (
+	EnvPrivateKey        = "SOLANA_PRIVATE_KEY"
+	EnvRPCURL            = "SOLANA_RPC_URL"
+	EnvWSURL             = "SOLANA_WS_RPC_URL"
+	EnvDiscoveryURL      = "SOLANA_DISCOVERY_RPC_URL"
+	EnvCluster           = "SOLROUTE_CLUSTER"
+	EnvRateLimit         = "SOLANA_RPC_RATE_LIMIT"
+	EnvProtocols         = "SOLROUTE_PROTOCOLS"
+	EnvSlippageBps       = "SOLROUTE_SLIPPAGE_BPS"
+	EnvReadCommitment    = "SOLROUTE_READ_COMMITMENT"
+	EnvExecuteCommitment = "SOLROUTE_EXECUTE_COMMITMENT"
+	EnvPriorityFeeTier   = "SOLROUTE_PRIORITY_FEE_TIER"
+	EnvPriorityFeePrice  = "SOLROUTE_PRIORITY_FEE_MICROLAMPORTS"
+)
+
+// Config holds the router and client settings
+type Config struct {
+	RPC RPCConfig `json:"rpc" yaml:"rpc"`
+	// Cluster is the network the endpoints serve, picking the program IDs protocols use; empty
+	// detects it from rpc.url
+	Cluster sol.Cluster `json:"cluster" yaml:"cluster"`
+	// Protocols are the protocolregistry names to route through; empty routes through every
+	// registered one
+	Protocols   []string          `json:"protocols" yaml:"protocols"`
+	SlippageBps uint64            `json:"slippageBps" yaml:"slippageBps"`
+	Commitment  CommitmentConfig  `json:"commitment" yaml:"commitment"`
+	RateLimit   RateLimitConfig   `json:"rateLimit" yaml:"rateLimit"`
+	PriorityFee PriorityFeeConfig `json:"priorityFee" yaml:"priorityFee"`
+
+	// PrivateKey is read from the environment only, so keys stay out of config files
+	PrivateKey string `json:"-" yaml:"-"`
+}
+
+// RPCConfig are the endpoints the clients connect to
+type RPCConfig struct {
+	URL   string `json:"url" yaml:"url"`
+	WSURL string `json:"wsUrl" yaml:"wsUrl"`
+	// DiscoveryURL, when set, takes the getProgramAccounts scans of pool discovery
+	DiscoveryURL string `json:"discoveryUrl" yaml:"discoveryUrl"`
+}
+
+// CommitmentConfig are the sol.Config commitment levels; empty keeps each call's own
+type CommitmentConfig struct {
+	Read    string `json:"read" yaml:"read"`
+	Execute string `json:"execute" yaml:"execute"`
+}
+
+// RateLimitConfig caps the requests each endpoint is sent; zero is unlimited
+type RateLimitConfig struct {
+	RequestsPerSecond float64 `json:"requestsPerSecond" yaml:"requestsPerSecond"`
+	// Burst defaults to one second of requests
+	Burst int `json:"burst" yaml:"burst"`
+}
+
+// PriorityFeeConfig picks the compute unit price: a tier of recent fees, a fixed price, or
+// none when both are unset
+type PriorityFeeConfig struct {
+	Tier          sol.FeeTier `json:"tier" yaml:"tier"`
+	MicroLamports uint64      `json:"microLamports" yaml:"microLamports"`
+}
+
+// Default returns mainnet's public endpoints, every protocol and 1% slippage
+func Default() Config {
+	return Config{
+		RPC:         RPCConfig{URL: DefaultRPCURL, WSURL: DefaultWSURL},
+		SlippageBps: DefaultSlippageBps,
+	}
+}
+
+// Load reads the file at path, or at $SOLROUTE_CONFIG when path is empty, over the defaults,
+// applies the environment and validates the result. Files ending in .json are read as JSON,
+// others as YAML. Without a file only the defaults and the environment apply.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	if path == "" {
+		path = os.Getenv(EnvConfigPath)
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to read config: %w", err)
+		}
+		if err := cfg.decode(data, strings.EqualFold(filepath.Ext(path), ".json")); err != nil {
+			return Config{}, fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+	}
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// decode reads data over cfg, rejecting unknown keys so misspelled settings don't silently
+// keep their defaults
+func (c *Config) decode(data []byte, isJSON bool) error {
+	if isJSON {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		return decoder.Decode(c)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	// An empty file keeps the defaults
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// applyEnv overrides settings with the environment variables that are set
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	fields := map[string]*string{
+		EnvPrivateKey:        &c.PrivateKey,
+		EnvRPCURL:            &c.RPC.URL,
+		EnvWSURL:             &c.RPC.WSURL,
+		EnvDiscoveryURL:      &c.RPC.DiscoveryURL,
+		EnvCluster:           (*string)(&c.Cluster),
+		EnvReadCommitment:    &c.Commitment.Read,
+		EnvExecuteCommitment: &c.Commitment.Execute,
+	}
+	for key, field := range fields {
+		if value, ok := lookup(key); ok && value != "" {
+			*field = value
+		}
+	}
+	if value, ok := lookup(EnvProtocols); ok && value != "" {
+		c.Protocols = c.Protocols[:0]
+		for _, name := range protocolregistry.ParseNames(value) {
+			c.Protocols = append(c.Protocols, string(name))
+		}
+	}
+	if value, ok := lookup(EnvSlippageBps); ok && value != "" {
+		bps, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", EnvSlippageBps, value, err)
+		}
+		c.SlippageBps = bps
+	}
+	if value, ok := lookup(EnvRateLimit); ok && value != "" {
+		limit, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", EnvRateLimit, value, err)
+		}
+		c.RateLimit.RequestsPerSecond = limit
+	}
+	if value, ok := lookup(EnvPriorityFeeTier); ok && value != "" {
+		c.PriorityFee = PriorityFeeConfig{Tier: sol.FeeTier(value)}
+	}
+	if value, ok := lookup(EnvPriorityFeePrice); ok && value != "" {
+		price, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", EnvPriorityFeePrice, value, err)
+		}
+		c.PriorityFee = PriorityFeeConfig{MicroLamports: price}
+	}
+	return nil
+}
+
+// Validate checks the settings without connecting anywhere
+func (c *Config) Validate() error {
+	if err := validateURL("rpc.url", c.RPC.URL, "http", "https"); err != nil {
+		return err
+	}
+	if c.RPC.WSURL != "" {
+		if err := validateURL("rpc.wsUrl", c.RPC.WSURL, "ws", "wss"); err != nil {
+			return err
+		}
+	}
+	if c.RPC.DiscoveryURL != "" {
+		if err := validateURL("rpc.discoveryUrl", c.RPC.DiscoveryURL, "http", "https"); err != nil {
+			return err
+		}
+	}
+	if c.Cluster != "" {
+		if _, err := sol.ParseCluster(string(c.Cluster)); err != nil {
+			return fmt.Errorf("cluster: %w", err)
+		}
+	}
+	known := make(map[pkg.ProtocolName]bool)
+	for _, name := range protocolregistry.Names() {
+		known[name] = true
+	}
+	for _, name := range c.Protocols {
+		if !known[pkg.ProtocolName(name)] {
+			return fmt.Errorf("protocols: unknown protocol %q", name)
+		}
+	}
+	if c.SlippageBps >= 10000 {
+		return fmt.Errorf("slippageBps must be below 10000, got %d", c.SlippageBps)
+	}
+	for field, commitment := range map[string]string{"commitment.read": c.Commitment.Read, "commitment.execute": c.Commitment.Execute} {
+		switch rpc.CommitmentType(commitment) {
+		case "", rpc.CommitmentProcessed, rpc.CommitmentConfirmed, rpc.CommitmentFinalized:
+		default:
+			return fmt.Errorf("%s must be processed, confirmed or finalized, got %q", field, commitment)
+		}
+	}
+	if c.RateLimit.RequestsPerSecond < 0 || c.RateLimit.Burst < 0 {
+		return fmt.Errorf("rateLimit must not be negative")
+	}
+	switch c.PriorityFee.Tier {
+	case "", sol.FeeTierEconomy, sol.FeeTierNormal, sol.FeeTierFast:
+	default:
+		return fmt.Errorf("priorityFee.tier must be economy, normal or fast, got %q", c.PriorityFee.Tier)
+	}
+	if c.PriorityFee.Tier != "" && c.PriorityFee.MicroLamports > 0 {
+		return fmt.Errorf("priorityFee sets both a tier and a fixed price")
+	}
+	return nil
+}
+
+// validateURL checks that value is an absolute URL with one of schemes
+func validateURL(field, value string, schemes ...string) error {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", field, err)
+	}
+	for _, scheme := range schemes {
+		if parsed.Scheme == scheme && parsed.Host != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s must be a %s URL, got %q", field, strings.Join(schemes, " or "), value)
+}
+
+// NewEndpoints connects to the configured endpoints with the configured cluster, rate limit
+// and commitment
+func (c *Config) NewEndpoints(ctx context.Context) (*sol.Endpoints, error) {
+	endpoints, err := sol.NewEndpoints(ctx, c.RPC.URL, c.RPC.WSURL, c.RPC.DiscoveryURL)
+	if err != nil {
+		return nil, err
+	}
+	if c.Cluster != "" {
+		cluster, err := sol.ParseCluster(string(c.Cluster))
+		if err != nil {
+			endpoints.Close()
+			return nil, err
+		}
+		endpoints.SetCluster(cluster)
+	}
+	if limit := c.RateLimit.RequestsPerSecond; limit > 0 {
+		burst := c.RateLimit.Burst
+		if burst == 0 {
+			burst = max(int(limit), 1)
+		}
+		endpoints.SetRateLimit(limit, burst)
+	}
+	if c.Commitment.Read != "" || c.Commitment.Execute != "" {
+		endpoints.SetConfig(sol.Config{
+			ReadCommitment:    rpc.CommitmentType(c.Commitment.Read),
+			ExecuteCommitment: rpc.CommitmentType(c.Commitment.Execute),
+		})
+	}
+	return endpoints, nil
+}
+
+// NewProtocols builds the configured protocols from protocolregistry, discovering pools
+// through solClient
+func (c *Config) NewProtocols(solClient *sol.Client) ([]pkg.Protocol, error) {
+	names := make([]pkg.ProtocolName, len(c.Protocols))
+	for i, name := range c.Protocols {
+		names[i] = pkg.ProtocolName(name)
+	}
+	return protocolregistry.Build(solClient, names...)
+}
+
+// Slippage returns the configured slippage tolerance
+func (c *Config) Slippage() pkg.SlippageConfig {
+	return pkg.SlippageBps(c.SlippageBps)
+}
+
+// PriorityFeeStrategy returns the configured compute unit pricing, tiers priced by the
+// client's priority fee oracle
+func (c *Config) PriorityFeeStrategy(client *sol.Client) sol.PriorityFeeStrategy {
+	if c.PriorityFee.Tier == "" {
+		return sol.FixedPriorityFee(c.PriorityFee.MicroLamports)
+	}
+	oracle := client.PriorityFees
+	if oracle == nil {
+		oracle = sol.NewPriorityFeeOracle(client)
+	}
+	return oracle.Strategy(c.PriorityFee.Tier)
+}
@@ -0,0 +1,95 @@
+// Package errors defines the errors routing and execution fail with, so callers can branch
+// with errors.Is instead of matching messages. Import it under another name, e.g.
+// solerrors, to keep the standard library's errors package in scope.
+package errors
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+var (
+	// ErrNoRoute is returned when no pool can quote a swap; it wraps each pool's failure
+	ErrNoRoute = errors.New("no route found")
+	// ErrInsufficientLiquidity is returned when a pool can't fill the requested amount
+	ErrInsufficientLiquidity = errors.New("insufficient liquidity")
+	// ErrPoolDisabled is returned for pools that can't be routed through: paused, disabled
+	// on chain or deprecated
+	ErrPoolDisabled = errors.New("pool disabled")
+	// ErrRateLimited is returned when an RPC node or the router's own limits reject a request
+	ErrRateLimited = errors.New("rate limited")
+	// ErrTickArrayMissing is returned when a concentrated liquidity pool lacks the tick
+	// arrays a swap crosses
+	ErrTickArrayMissing = errors.New("tick array missing")
+	// ErrAccountNotFound is returned when an account a swap needs doesn't exist
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrPriceImpactTooHigh is returned when a quote moves the price further than the
+	// slippage policy allows
+	ErrPriceImpactTooHigh = errors.New("price impact too high")
+	// ErrSlippageExceeded is returned when a swap would receive less than its minimum output
+	ErrSlippageExceeded = errors.New("slippage exceeded")
+	// ErrInsufficientFunds is returned when the user can't pay for a swap or its fees
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrDeadlineExceeded is returned when a swap's deadline passes before it is sent
+	ErrDeadlineExceeded = errors.New("swap deadline exceeded")
+)
+
+// rpcInvalidParams is the JSON-RPC code nodes answer account lookups of unknown keys with
+const rpcInvalidParams = -32602
+
+// Mark returns an error with err's message that also matches sentinel, so a package can
+// keep its own sentinel while belonging to one of the categories above
+func Mark(err, sentinel error) error {
+	return &marked{err: err, sentinel: sentinel}
+}
+
+type marked struct {
+	err      error
+	sentinel error
+}
+
+func (m *marked) Error() string {
+	return m.err.Error()
+}
+
+func (m *marked) Unwrap() []error {
+	return []error{m.err, m.sentinel}
+}
+
+// IsRateLimited reports whether err is ErrRateLimited or an RPC node's HTTP 429
+func IsRateLimited(err error) bool {
+	if errors.Is(err, ErrRateLimited) {
+		return true
+	}
+	var httpErr *jsonrpc.HTTPError
+	return errors.As(err, &httpErr) && httpErr.Code == http.StatusTooManyRequests
+}
+
+// IsRetryable reports whether repeating the request that failed with err may succeed: rate
+// limits, server errors, network timeouts and dropped connections
+func IsRetryable(err error) bool {
+	if IsRateLimited(err) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) && httpErr.Code >= http.StatusInternalServerError {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// IsAccountNotFound reports whether err is ErrAccountNotFound or an RPC node reporting an
+// unknown account
+func IsAccountNotFound(err error) bool {
+	if errors.Is(err, ErrAccountNotFound) || errors.Is(err, rpc.ErrNotFound) {
+		return true
+	}
+	var rpcErr *jsonrpc.RPCError
+	return errors.As(err, &rpcErr) && rpcErr.Code == rpcInvalidParams
+}
@@ -0,0 +1,160 @@
+// Package layout measures fixed size Borsh account layouts. The structs pool accounts decode
+// into also give the account's size and each field's offset, so the RPC filters that find
+// pools and the decoders that read them can't disagree about where a field is.
+package layout
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	bin "github.com/gagliardetto/binary"
+)
+
+// DiscriminatorSize is the length of the discriminator Anchor puts before an account's fields
+const DiscriminatorSize = 8
+
+// Layout is the shape of an Anchor account whose fields are all fixed size. Sizes and offsets
+// count the discriminator.
+type Layout struct {
+	typ     reflect.Type
+	size    uint64
+	offsets map[string]uint64
+}
+
+var layouts sync.Map // reflect.Type -> *Layout
+
+// Of returns the layout of the account struct v, or a pointer to it. Fields are laid out as
+// the Borsh decoder reads them: in order, packed, skipping unexported fields and those tagged
+// `bin:"-"` or `bin:"skip"`. Nested struct fields are named by dotted paths.
+func Of(v any) (*Layout, error) {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("account layout must be a struct, got %v", t)
+	}
+	if l, ok := layouts.Load(t); ok {
+		return l.(*Layout), nil
+	}
+	l := &Layout{typ: t, offsets: make(map[string]uint64)}
+	size, err := l.measure(t, "", DiscriminatorSize)
+	if err != nil {
+		return nil, fmt.Errorf("layout of %s: %w", t, err)
+	}
+	l.size = size
+	actual, _ := layouts.LoadOrStore(t, l)
+	return actual.(*Layout), nil
+}
+
+// MustOf is Of for layouts known when the program is written, panicking on error
+func MustOf(v any) *Layout {
+	l, err := Of(v)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// Size returns the account's size in bytes
+func (l *Layout) Size() uint64 {
+	return l.size
+}
+
+// Offset returns the offset of a field, e.g. "TokenMintA" or "Parameters.BaseFactor", and
+// whether the layout has it
+func (l *Layout) Offset(field string) (uint64, bool) {
+	offset, ok := l.offsets[field]
+	return offset, ok
+}
+
+// Decode reads account data into v, which must point to the layout's struct. The
+// discriminator isn't checked and bytes past the layout are ignored.
+func (l *Layout) Decode(data []byte, v any) error {
+	if t := reflect.TypeOf(v); t.Kind() != reflect.Pointer || t.Elem() != l.typ {
+		return fmt.Errorf("cannot decode %s account into %T", l.typ, v)
+	}
+	if uint64(len(data)) < l.size {
+		return fmt.Errorf("%s account too short: %d bytes, expected %d", l.typ.Name(), len(data), l.size)
+	}
+	return bin.NewBorshDecoder(data[DiscriminatorSize:l.size]).Decode(v)
+}
+
+// measure records the offsets of t's fields starting at offset and returns where they end
+func (l *Layout) measure(t reflect.Type, prefix string, offset uint64) (uint64, error) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("bin")
+		if !field.IsExported() || hasTag(tag, "-") || hasTag(tag, "skip") {
+			continue
+		}
+		name := prefix + field.Name
+		if hasTag(tag, "optional") || hasTag(tag, "option") || hasTag(tag, "coption") {
+			return 0, fmt.Errorf("field %s is optional, so not fixed size", name)
+		}
+		l.offsets[name] = offset
+		if field.Type.Kind() == reflect.Struct && !isScalar(field.Type) {
+			end, err := l.measure(field.Type, name+".", offset)
+			if err != nil {
+				return 0, err
+			}
+			offset = end
+			continue
+		}
+		size, err := sizeOf(field.Type)
+		if err != nil {
+			return 0, fmt.Errorf("field %s: %w", name, err)
+		}
+		offset += size
+	}
+	return offset, nil
+}
+
+// Integers wider than 64 bits that are encoded as 16 bytes whatever their Go fields
+var scalars = map[reflect.Type]uint64{
+	reflect.TypeOf(bin.Uint128{}): 16,
+	reflect.TypeOf(bin.Int128{}):  16,
+}
+
+func isScalar(t reflect.Type) bool {
+	_, ok := scalars[t]
+	return ok
+}
+
+// sizeOf returns the encoded size of a fixed size type
+func sizeOf(t reflect.Type) (uint64, error) {
+	if size, ok := scalars[t]; ok {
+		return size, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int8, reflect.Uint8:
+		return 1, nil
+	case reflect.Int16, reflect.Uint16:
+		return 2, nil
+	case reflect.Int32, reflect.Uint32, reflect.Float32:
+		return 4, nil
+	case reflect.Int64, reflect.Uint64, reflect.Float64:
+		return 8, nil
+	case reflect.Array:
+		elem, err := sizeOf(t.Elem())
+		if err != nil {
+			return 0, err
+		}
+		return uint64(t.Len()) * elem, nil
+	case reflect.Struct:
+		nested := &Layout{offsets: make(map[string]uint64)}
+		return nested.measure(t, "", 0)
+	}
+	return 0, fmt.Errorf("%s is not fixed size", t)
+}
+
+func hasTag(tag, option string) bool {
+	for _, s := range strings.Fields(tag) {
+		if s == option {
+			return true
+		}
+	}
+	return false
+}
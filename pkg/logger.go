@@ -0,0 +1,37 @@
+package pkg
+
+import (
+	"log/slog"
+)
+
+// Logger receives the client's, router's and venues' diagnostics as a message followed by
+// alternating keys and values, as log/slog does. *slog.Logger satisfies it.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// Keys of the fields log lines carry, so they can be filtered on across packages
+const (
+	LogKeyPool       = "pool"
+	LogKeyProtocol   = "protocol"
+	LogKeyInputMint  = "input_mint"
+	LogKeyOutputMint = "output_mint"
+	LogKeyBaseMint   = "base_mint"
+	LogKeyQuoteMint  = "quote_mint"
+	LogKeyLatency    = "latency"
+	LogKeyError      = "error"
+)
+
+// DiscardLogger drops everything logged to it
+var DiscardLogger Logger = slog.New(slog.DiscardHandler)
+
+// LoggerOrDefault returns logger, or slog's default logger when it is nil
+func LoggerOrDefault(logger Logger) Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
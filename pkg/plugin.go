@@ -0,0 +1,29 @@
+package pkg
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Decoder turns a venue's pool accounts into routable pools
+type Decoder interface {
+	// ProgramID is the program owning the venue's pool accounts
+	ProgramID() solana.PublicKey
+	// DecodePool decodes the account data of pool id. The returned pool quotes and builds
+	// swaps through its Quoter and InstructionBuilder methods.
+	DecodePool(id solana.PublicKey, data []byte) (Pool, error)
+}
+
+// DiscoveryFilter narrows the getProgramAccounts scans that find a venue's pools for a pair.
+// Each filter set is one scan, usually one per mint order.
+type DiscoveryFilter interface {
+	PairFilters(baseMint, quoteMint solana.PublicKey) [][]rpc.RPCFilter
+}
+
+// Venue is what an out-of-tree module implements to route through a DEX this module doesn't
+// support; protocol.NewPluginProtocol turns it into a Protocol for the router
+type Venue interface {
+	Name() ProtocolName
+	Decoder
+	DiscoveryFilter
+}
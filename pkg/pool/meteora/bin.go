@@ -59,6 +59,28 @@ func (bin *Bin) GetAmountOut(amountIn uint64, price uint128.Uint128, swapForY bo
 	)
 }
 
+// GetAmountIn calculates the input amount needed to take amountOut from the bin at the given price
+// Uses rounding up for both swap directions so the bin is never undercharged
+func (bin *Bin) GetAmountIn(amountOut uint64, price uint128.Uint128, swapForY bool) (*big.Int, error) {
+	if swapForY {
+		// Calculate: (amountOut << SCALE_OFFSET) / price (rounding up)
+		return SafeShlDivCast(
+			new(big.Int).SetUint64(amountOut),
+			price.Big(),
+			ScaleOffset,
+			RoundingUp,
+		)
+	}
+
+	// Calculate: price * amountOut >> SCALE_OFFSET (rounding up)
+	return SafeMulShrCast(
+		price.Big(),
+		new(big.Int).SetUint64(amountOut),
+		ScaleOffset,
+		RoundingUp,
+	)
+}
+
 // GetMaxAmountIn calculates the maximum input amount that can be swapped for the given price
 // Uses rounding up for both swap directions
 func (bin *Bin) GetMaxAmountIn(price uint128.Uint128, swapForY bool) (*big.Int, error) {
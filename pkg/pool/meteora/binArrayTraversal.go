@@ -0,0 +1,163 @@
+package meteora
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// binArrayFetchCount is how many bin arrays with liquidity are read ahead in a swap direction
+const binArrayFetchCount = 4
+
+// loadBitmapExtension reads the pair's bitmap extension, which tracks bin arrays beyond the
+// pair's own bitmap. Pairs without one are remembered, so it is read at most once.
+func (pool *MeteoraDlmmPool) loadBitmapExtension(ctx context.Context, solClient pkg.RPC) error {
+	if pool.bitmapExtension != nil || pool.bitmapExtensionChecked {
+		return nil
+	}
+	if pool.BitmapExtensionKey.IsZero() {
+		pool.BitmapExtensionKey, _ = DeriveBinArrayBitmapExtension(pool.PoolId)
+	}
+	results, err := solClient.GetMultipleAccountsWithOpts(ctx, []solana.PublicKey{pool.BitmapExtensionKey}, sol.MultipleAccountsOpts(ctx, rpc.CommitmentConfirmed))
+	if err != nil {
+		return fmt.Errorf("failed to get bitmap extension of pair %s: %w", pool.PoolId, err)
+	}
+	if len(results.Value) == 1 && results.Value[0] != nil {
+		extension, err := ParseBinArrayBitmapExtension(results.Value[0].Data.GetBinary())
+		if err != nil {
+			return fmt.Errorf("failed to parse bitmap extension of pair %s: %w", pool.PoolId, err)
+		}
+		pool.bitmapExtension = extension
+	}
+	pool.bitmapExtensionChecked = true
+	return nil
+}
+
+// nextBinArrayIndex is NextBinArrayIndexWithLiquidity, reading the bitmap extension when the
+// search leaves the pair's bitmap
+func (pool *MeteoraDlmmPool) nextBinArrayIndex(ctx context.Context, solClient pkg.RPC, swapForY bool, start int32) (int32, bool, error) {
+	index, found, err := pool.NextBinArrayIndexWithLiquidity(swapForY, start)
+	if err != nil || found || pool.bitmapExtension != nil || pool.bitmapExtensionChecked || solClient == nil {
+		return index, found, err
+	}
+	if err := pool.loadBitmapExtension(ctx, solClient); err != nil {
+		return 0, false, err
+	}
+	return pool.NextBinArrayIndexWithLiquidity(swapForY, start)
+}
+
+// binArrayIndexesForSwap returns up to count indexes of bin arrays with liquidity from start
+// inclusive, in the order a swap crosses them
+func (pool *MeteoraDlmmPool) binArrayIndexesForSwap(ctx context.Context, solClient pkg.RPC, swapForY bool, start int32, count int) ([]int32, error) {
+	indexes := make([]int32, 0, count)
+	for len(indexes) < count {
+		index, found, err := pool.nextBinArrayIndex(ctx, solClient, swapForY, start)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			break
+		}
+		indexes = append(indexes, index)
+		if swapForY {
+			start = index - 1
+		} else {
+			start = index + 1
+		}
+	}
+	return indexes, nil
+}
+
+// fetchBinArrays reads the bin arrays at indexes not loaded yet in one request
+func (pool *MeteoraDlmmPool) fetchBinArrays(ctx context.Context, solClient pkg.RPC, indexes []int32) error {
+	if pool.BinArrays == nil {
+		pool.BinArrays = make(map[string]BinArray)
+	}
+	keys := make([]solana.PublicKey, 0, len(indexes))
+	for _, index := range indexes {
+		key, _ := DeriveBinArrayPDA(pool.PoolId, int64(index))
+		if _, ok := pool.BinArrays[key.String()]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	results, err := solClient.GetMultipleAccountsWithOpts(ctx, keys, sol.MultipleAccountsOpts(ctx, rpc.CommitmentConfirmed))
+	if err != nil {
+		return fmt.Errorf("failed to get bin arrays of pair %s: %w", pool.PoolId, err)
+	}
+	for i, result := range results.Value {
+		if result == nil {
+			continue
+		}
+		binArray, err := ParseBinArray(result.Data.GetBinary())
+		if err != nil {
+			return fmt.Errorf("failed to parse bin array %s: %w", keys[i], err)
+		}
+		pool.BinArrays[keys[i].String()] = binArray
+	}
+	return nil
+}
+
+// binArrayForSwap returns the next bin array with liquidity from the active bin in the swap
+// direction. Arrays beyond those loaded are read through solClient, a few at a time, so large
+// swaps can be quoted past the arrays read at discovery.
+func (pool *MeteoraDlmmPool) binArrayForSwap(ctx context.Context, solClient pkg.RPC, swapForY bool) (solana.PublicKey, BinArray, error) {
+	start := int32(BinIDToBinArrayIndex(pool.activeId))
+	index, found, err := pool.nextBinArrayIndex(ctx, solClient, swapForY, start)
+	if err != nil {
+		return solana.PublicKey{}, BinArray{}, err
+	}
+	if !found {
+		return solana.PublicKey{}, BinArray{}, fmt.Errorf("%w: no bin array with liquidity past bin %d", solerrors.ErrInsufficientLiquidity, pool.activeId)
+	}
+	key, _ := DeriveBinArrayPDA(pool.PoolId, int64(index))
+	if binArray, ok := pool.BinArrays[key.String()]; ok {
+		return key, binArray, nil
+	}
+	if solClient == nil {
+		return solana.PublicKey{}, BinArray{}, fmt.Errorf("bin array %d not loaded", index)
+	}
+	indexes, err := pool.binArrayIndexesForSwap(ctx, solClient, swapForY, index, binArrayFetchCount)
+	if err != nil {
+		return solana.PublicKey{}, BinArray{}, err
+	}
+	if err := pool.fetchBinArrays(ctx, solClient, indexes); err != nil {
+		return solana.PublicKey{}, BinArray{}, err
+	}
+	binArray, ok := pool.BinArrays[key.String()]
+	if !ok {
+		return solana.PublicKey{}, BinArray{}, fmt.Errorf("bin array %s not found", key)
+	}
+	return key, binArray, nil
+}
+
+// swapBinArrays returns the bin arrays a swap passes as remaining accounts: those the quote
+// crossed, in order, and the next one with liquidity, so the swap lands when the price moves
+// before it does
+func (pool *MeteoraDlmmPool) swapBinArrays(ctx context.Context, solClient pkg.RPC, swapForY bool, crossed []solana.PublicKey) ([]solana.PublicKey, error) {
+	if len(crossed) == 0 {
+		return crossed, nil
+	}
+	last := pool.BinArrays[crossed[len(crossed)-1].String()]
+	start := int32(last.index) + 1
+	if swapForY {
+		start = int32(last.index) - 1
+	}
+	indexes, err := pool.binArrayIndexesForSwap(ctx, solClient, swapForY, start, 1)
+	if err != nil {
+		return nil, err
+	}
+	binArrays := append([]solana.PublicKey(nil), crossed...)
+	for _, index := range indexes {
+		key, _ := DeriveBinArrayPDA(pool.PoolId, int64(index))
+		binArrays = append(binArrays, key)
+	}
+	return binArrays, nil
+}
@@ -0,0 +1,91 @@
+package meteora
+
+import (
+	"iter"
+	"sort"
+
+	"lukechampine.com/uint128"
+)
+
+// BinLiquidity is the liquidity held by a bin
+type BinLiquidity struct {
+	AmountX uint64
+	AmountY uint64
+	Price   uint128.Uint128 // Q64.64 price of X in Y
+}
+
+// ActiveID returns the ID of the bin the pool is currently trading in
+func (pool *MeteoraDlmmPool) ActiveID() int32 {
+	return pool.activeId
+}
+
+// NextBinArrayIndexWithLiquidity returns the index of the next bin array with liquidity from
+// startArrayIndex inclusive, searching down for swapForY swaps and up otherwise. It moves
+// between the pool's bitmap and its bitmap extension as the search leaves either; found is
+// false when neither has liquidity in the direction.
+func (pool *MeteoraDlmmPool) NextBinArrayIndexWithLiquidity(swapForY bool, startArrayIndex int32) (index int32, found bool, err error) {
+	index = startArrayIndex
+	// A search crosses from the extension to the bitmap and back out at most once each way
+	for range 3 {
+		if IsOverflowDefaultBinArrayBitmap(index) {
+			if pool.bitmapExtension == nil {
+				return index, false, nil
+			}
+			if minIndex, maxIndex := pool.bitmapExtension.BitmapRange(); index < minIndex || index > maxIndex {
+				return index, false, nil
+			}
+			index, found, err = pool.bitmapExtension.NextBinArrayIndexWithLiquidity(swapForY, index)
+		} else {
+			index, found, err = pool.NextBinArrayIndexWithLiquidityInternal(swapForY, index)
+		}
+		if err != nil || found {
+			return index, found, err
+		}
+		if !IsOverflowDefaultBinArrayBitmap(index) && pool.bitmapExtension == nil {
+			return index, false, nil
+		}
+	}
+	return index, false, nil
+}
+
+// BinsWithLiquidity yields the non-empty bins from the active bin in the swap direction, down
+// for swapForY swaps and up otherwise, keyed by bin ID. A swap only consumes the side it pays
+// out: Y when swapping for Y and X otherwise. Only arrays in BinArrays are walked, so load them
+// first with a quote or GetBinArrayForSwap.
+func (pool *MeteoraDlmmPool) BinsWithLiquidity(swapForY bool) iter.Seq2[int32, BinLiquidity] {
+	return func(yield func(int32, BinLiquidity) bool) {
+		binArrays := make([]BinArray, 0, len(pool.BinArrays))
+		for _, binArray := range pool.BinArrays {
+			binArrays = append(binArrays, binArray)
+		}
+		sort.Slice(binArrays, func(i, j int) bool {
+			if swapForY {
+				return binArrays[i].index > binArrays[j].index
+			}
+			return binArrays[i].index < binArrays[j].index
+		})
+
+		for _, binArray := range binArrays {
+			lowerBinID, _, err := GetBinArrayLowerUpperBinID(int32(binArray.index))
+			if err != nil {
+				continue
+			}
+			for i := range binArray.bins {
+				if swapForY {
+					i = len(binArray.bins) - 1 - i
+				}
+				bin := binArray.bins[i]
+				id := lowerBinID + int32(i)
+				if (swapForY && id > pool.activeId) || (!swapForY && id < pool.activeId) {
+					continue
+				}
+				if bin.amountX == 0 && bin.amountY == 0 {
+					continue
+				}
+				if !yield(id, BinLiquidity{AmountX: bin.amountX, AmountY: bin.amountY, Price: bin.price}) {
+					return
+				}
+			}
+		}
+	}
+}
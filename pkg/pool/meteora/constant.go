@@ -44,6 +44,12 @@ var (
 	// MeteoraProgramID is the main Meteora DLMM program ID
 	MeteoraProgramID = solana.MustPublicKeyFromBase58("LBUZKhRxPF3XUpBCjp4YzTKgLccjZhTSDM9YuVaPwxo")
 
+	// MeteoraDammProgramID is the Meteora Dynamic AMM (DAMM v1) program ID
+	MeteoraDammProgramID = solana.MustPublicKeyFromBase58("Eo7WjKq67rjJQSZxS6z3YkapzY3eMj6Xy8X5EQVn5UaB")
+
+	// MeteoraVaultProgramID is the Meteora dynamic vault program that holds DAMM liquidity
+	MeteoraVaultProgramID = solana.MustPublicKeyFromBase58("24Uqj9JCLxUeoC3hGfh5W3s9FM9uCHDS2SG3LYwBpyTi")
+
 	// MemoProgramID is the Solana memo program ID
 	MemoProgramID = solana.MustPublicKeyFromBase58("MemoSq4gqABAXKb96qnH8TysNcWxMyWCqXgDLGmfcHr")
 
@@ -55,6 +61,18 @@ var (
 
 	// Swap2IxDiscm is the instruction discriminator for swap2 instruction
 	Swap2IxDiscm = [8]byte{65, 75, 63, 76, 235, 91, 91, 136}
+
+	// SwapExactOut2IxDiscm is the instruction discriminator for swap_exact_out2 instruction
+	SwapExactOut2IxDiscm = [8]byte{43, 215, 247, 132, 137, 60, 243, 81}
+
+	// DammSwapIxDiscm is the instruction discriminator for the Dynamic AMM swap instruction
+	DammSwapIxDiscm = [8]byte{248, 198, 158, 145, 225, 117, 135, 200}
+
+	// DammPoolDiscriminator prefixes Dynamic AMM pool accounts
+	DammPoolDiscriminator = [8]byte{241, 154, 109, 4, 17, 177, 109, 188}
+
+	// DynamicVaultDiscriminator prefixes dynamic vault accounts
+	DynamicVaultDiscriminator = [8]byte{211, 8, 232, 43, 2, 152, 117, 119}
 )
 
 // PairStatus represents the status of a trading pair
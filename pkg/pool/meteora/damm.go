@@ -0,0 +1,490 @@
+package meteora
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	cosmosmath "cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	// DammCurveConstantProduct pools price on x*y=k; stable-swap pools aren't supported
+	DammCurveConstantProduct uint8 = 0
+
+	dammActivationTypeTimestamp uint8 = 1
+)
+
+// Dynamic vault account layout
+const (
+	vaultEnabledOffset      = 8
+	vaultTotalAmountOffset  = 11
+	vaultTokenVaultOffset   = 19
+	vaultFeeVaultOffset     = 51
+	vaultTokenMintOffset    = 83
+	vaultLpMintOffset       = 115
+	vaultLockedProfitOffset = 1203
+	vaultAccountMinSize     = vaultLockedProfitOffset + 24
+
+	// lockedProfitDegradationDenominator scales LockedProfitDegradation, the share of
+	// locked profit released per second
+	lockedProfitDegradationDenominator = 1_000_000_000_000
+)
+
+// DynamicVault is a Meteora dynamic vault; a Dynamic AMM pool keeps each side of its liquidity
+// as LP tokens of one vault, which lends the tokens out for yield
+type DynamicVault struct {
+	Enabled                 bool
+	TotalAmount             uint64
+	TokenVault              solana.PublicKey
+	FeeVault                solana.PublicKey
+	TokenMint               solana.PublicKey
+	LpMint                  solana.PublicKey
+	LastUpdatedLockedProfit uint64
+	LastReport              uint64
+	LockedProfitDegradation uint64
+}
+
+// Decode reads a dynamic vault account
+func (v *DynamicVault) Decode(data []byte) error {
+	if len(data) < vaultAccountMinSize {
+		return fmt.Errorf("vault account too short: %d bytes", len(data))
+	}
+	if [8]byte(data[:8]) != DynamicVaultDiscriminator {
+		return fmt.Errorf("invalid vault account discriminator")
+	}
+	key := func(offset int) solana.PublicKey { return solana.PublicKeyFromBytes(data[offset : offset+32]) }
+	v.Enabled = data[vaultEnabledOffset] != 0
+	v.TotalAmount = binary.LittleEndian.Uint64(data[vaultTotalAmountOffset:])
+	v.TokenVault = key(vaultTokenVaultOffset)
+	v.FeeVault = key(vaultFeeVaultOffset)
+	v.TokenMint = key(vaultTokenMintOffset)
+	v.LpMint = key(vaultLpMintOffset)
+	v.LastUpdatedLockedProfit = binary.LittleEndian.Uint64(data[vaultLockedProfitOffset:])
+	v.LastReport = binary.LittleEndian.Uint64(data[vaultLockedProfitOffset+8:])
+	v.LockedProfitDegradation = binary.LittleEndian.Uint64(data[vaultLockedProfitOffset+16:])
+	return nil
+}
+
+// UnlockedAmount is the vault balance less the strategy profit still being released
+func (v *DynamicVault) UnlockedAmount(now int64) cosmosmath.Int {
+	locked := cosmosmath.ZeroInt()
+	duration := uint64(0)
+	if now > int64(v.LastReport) {
+		duration = uint64(now) - v.LastReport
+	}
+	ratio := cosmosmath.NewIntFromUint64(duration).Mul(cosmosmath.NewIntFromUint64(v.LockedProfitDegradation))
+	denominator := cosmosmath.NewInt(lockedProfitDegradationDenominator)
+	if ratio.LTE(denominator) {
+		locked = cosmosmath.NewIntFromUint64(v.LastUpdatedLockedProfit).Mul(denominator.Sub(ratio)).Quo(denominator)
+	}
+	return cosmosmath.NewIntFromUint64(v.TotalAmount).Sub(locked)
+}
+
+// amountByShare converts vault LP tokens to the tokens they redeem for
+func (v *DynamicVault) amountByShare(now int64, share, totalSupply cosmosmath.Int) cosmosmath.Int {
+	if totalSupply.IsZero() {
+		return cosmosmath.ZeroInt()
+	}
+	return v.UnlockedAmount(now).Mul(share).Quo(totalSupply)
+}
+
+// unmintAmount converts tokens to the vault LP tokens they are worth
+func (v *DynamicVault) unmintAmount(now int64, amount, totalSupply cosmosmath.Int) cosmosmath.Int {
+	unlocked := v.UnlockedAmount(now)
+	if unlocked.IsZero() {
+		return cosmosmath.ZeroInt()
+	}
+	return amount.Mul(totalSupply).Quo(unlocked)
+}
+
+// MeteoraDammPool is a Meteora Dynamic AMM (DAMM v1) constant-product pool. Its reserves are
+// the vault tokens its vault LP balances redeem for, so quotes follow the vaults' exchange
+// rates as they accrue yield.
+type MeteoraDammPool struct {
+	PoolId            solana.PublicKey
+	LpMint            solana.PublicKey
+	TokenAMint        solana.PublicKey
+	TokenBMint        solana.PublicKey
+	AVault            solana.PublicKey
+	BVault            solana.PublicKey
+	AVaultLp          solana.PublicKey // the pool's LP token account of vault A
+	BVaultLp          solana.PublicKey
+	Enabled           bool
+	ProtocolTokenAFee solana.PublicKey
+	ProtocolTokenBFee solana.PublicKey
+
+	TradeFeeNumerator           uint64
+	TradeFeeDenominator         uint64
+	ProtocolTradeFeeNumerator   uint64
+	ProtocolTradeFeeDenominator uint64
+
+	ActivationPoint uint64
+	ActivationType  uint8
+	CurveType       uint8
+
+	// Vault state loaded by Quote
+	VaultA         DynamicVault
+	VaultB         DynamicVault
+	PoolVaultALp   uint64 // vault A LP tokens held by the pool
+	PoolVaultBLp   uint64
+	VaultALpSupply uint64
+	VaultBLpSupply uint64
+
+	TimeSource clock.Clock // wall clock for locked profit and activation; defaults to the system clock
+}
+
+var _ pkg.Pool = (*MeteoraDammPool)(nil)
+
+// Decode reads a Dynamic AMM pool account through DammPool
+func (pool *MeteoraDammPool) Decode(data []byte) error {
+	var account DammPool
+	if err := dammPoolLayout.Decode(data, &account); err != nil {
+		return err
+	}
+	if [8]byte(data[:8]) != DammPoolDiscriminator {
+		return fmt.Errorf("invalid pool account discriminator")
+	}
+	pool.LpMint = account.LpMint
+	pool.TokenAMint = account.TokenAMint
+	pool.TokenBMint = account.TokenBMint
+	pool.AVault = account.AVault
+	pool.BVault = account.BVault
+	pool.AVaultLp = account.AVaultLp
+	pool.BVaultLp = account.BVaultLp
+	pool.Enabled = account.Enabled
+	pool.ProtocolTokenAFee = account.ProtocolTokenAFee
+	pool.ProtocolTokenBFee = account.ProtocolTokenBFee
+	pool.TradeFeeNumerator = account.Fees.TradeFeeNumerator
+	pool.TradeFeeDenominator = account.Fees.TradeFeeDenominator
+	pool.ProtocolTradeFeeNumerator = account.Fees.ProtocolTradeFeeNumerator
+	pool.ProtocolTradeFeeDenominator = account.Fees.ProtocolTradeFeeDenominator
+	pool.ActivationPoint = account.Bootstrapping.ActivationPoint
+	pool.ActivationType = account.Bootstrapping.ActivationType
+	pool.CurveType = account.CurveType
+
+	if pool.TradeFeeDenominator == 0 || pool.ProtocolTradeFeeDenominator == 0 {
+		return fmt.Errorf("pool has a zero fee denominator")
+	}
+	if pool.CurveType != DammCurveConstantProduct {
+		return fmt.Errorf("unsupported curve type %d", pool.CurveType)
+	}
+	return nil
+}
+
+// Offset returns the offset of a DammPool field, used in discovery filters, or 0 for unknown
+// fields
+func (pool *MeteoraDammPool) Offset(field string) uint64 {
+	offset, _ := dammPoolLayout.Offset(field)
+	return offset
+}
+
+func (pool *MeteoraDammPool) ProtocolName() pkg.ProtocolName {
+	return pkg.ProtocolNameMeteoraDamm
+}
+
+func (pool *MeteoraDammPool) ProtocolType() pkg.ProtocolType {
+	return pkg.ProtocolTypeMeteoraDamm
+}
+
+func (pool *MeteoraDammPool) GetProgramID() solana.PublicKey {
+	return MeteoraDammProgramID
+}
+
+func (pool *MeteoraDammPool) GetID() string {
+	return pool.PoolId.String()
+}
+
+// FeeRateBps returns the trade fee in basis points, rounded up
+func (pool *MeteoraDammPool) FeeRateBps() uint64 {
+	if pool.TradeFeeDenominator == 0 {
+		return 0
+	}
+	return (pool.TradeFeeNumerator*10000 + pool.TradeFeeDenominator - 1) / pool.TradeFeeDenominator
+}
+
+// GetTokens returns token A and token B
+func (pool *MeteoraDammPool) GetTokens() (string, string) {
+	return pool.TokenAMint.String(), pool.TokenBMint.String()
+}
+
+func (pool *MeteoraDammPool) now() time.Time {
+	if pool.TimeSource == nil {
+		return time.Now()
+	}
+	return pool.TimeSource.Now()
+}
+
+// UpdateVaults loads the vaults, the pool's vault LP balances and the vault LP supplies. The
+// LP mints are only known once the vaults have been read, so the first load takes two requests.
+func (pool *MeteoraDammPool) UpdateVaults(ctx context.Context, solClient pkg.RPC) error {
+	accounts := []solana.PublicKey{pool.AVault, pool.BVault, pool.AVaultLp, pool.BVaultLp}
+	lpMintsKnown := !pool.VaultA.LpMint.IsZero() && !pool.VaultB.LpMint.IsZero()
+	if lpMintsKnown {
+		accounts = append(accounts, pool.VaultA.LpMint, pool.VaultB.LpMint)
+	}
+	results, err := solClient.GetMultipleAccountsWithOpts(ctx, accounts, sol.MultipleAccountsOpts(ctx, rpc.CommitmentProcessed))
+	if err != nil {
+		return fmt.Errorf("failed to get vault accounts: %w", err)
+	}
+	data := make([][]byte, len(accounts))
+	for i, account := range results.Value {
+		if account == nil {
+			return fmt.Errorf("account %s not found", accounts[i])
+		}
+		data[i] = account.Data.GetBinary()
+	}
+
+	var vaultA, vaultB DynamicVault
+	if err := vaultA.Decode(data[0]); err != nil {
+		return fmt.Errorf("failed to decode vault %s: %w", pool.AVault, err)
+	}
+	if err := vaultB.Decode(data[1]); err != nil {
+		return fmt.Errorf("failed to decode vault %s: %w", pool.BVault, err)
+	}
+	if !lpMintsKnown || !vaultA.LpMint.Equals(pool.VaultA.LpMint) || !vaultB.LpMint.Equals(pool.VaultB.LpMint) {
+		mints, err := solClient.GetMultipleAccountsWithOpts(ctx, []solana.PublicKey{vaultA.LpMint, vaultB.LpMint}, sol.MultipleAccountsOpts(ctx, rpc.CommitmentProcessed))
+		if err != nil {
+			return fmt.Errorf("failed to get vault LP mints: %w", err)
+		}
+		if len(mints.Value) != 2 || mints.Value[0] == nil || mints.Value[1] == nil {
+			return fmt.Errorf("vault LP mint not found")
+		}
+		data = append(data[:4], mints.Value[0].Data.GetBinary(), mints.Value[1].Data.GetBinary())
+	}
+
+	amounts := make([]uint64, 0, 4)
+	for i, offset := range []int{64, 64, 36, 36} { // token account amount, then mint supply
+		if len(data[2+i]) < offset+8 {
+			return fmt.Errorf("token account data too short")
+		}
+		amounts = append(amounts, binary.LittleEndian.Uint64(data[2+i][offset:]))
+	}
+	pool.VaultA, pool.VaultB = vaultA, vaultB
+	pool.PoolVaultALp, pool.PoolVaultBLp = amounts[0], amounts[1]
+	pool.VaultALpSupply, pool.VaultBLpSupply = amounts[2], amounts[3]
+	return nil
+}
+
+// Quote loads the vaults and prices the swap as the program does
+func (pool *MeteoraDammPool) Quote(ctx context.Context, solClient pkg.RPC, inputMint string, inputAmount cosmosmath.Int) (cosmosmath.Int, error) {
+	if err := pool.UpdateVaults(ctx, solClient); err != nil {
+		return cosmosmath.Int{}, err
+	}
+	return pool.QuoteOffline(inputMint, inputAmount)
+}
+
+// QuoteOffline is Quote against the vault state as last loaded
+func (pool *MeteoraDammPool) QuoteOffline(inputMint string, inputAmount cosmosmath.Int) (cosmosmath.Int, error) {
+	if !inputAmount.IsPositive() {
+		return cosmosmath.Int{}, fmt.Errorf("input amount must be positive")
+	}
+	if err := pool.checkTradable(); err != nil {
+		return cosmosmath.Int{}, err
+	}
+	aToB, err := pool.isAToB(inputMint)
+	if err != nil {
+		return cosmosmath.Int{}, err
+	}
+	return pool.swapOut(aToB, inputAmount), nil
+}
+
+// QuoteExactOut returns the smallest input whose quote delivers desiredOut. The program only
+// swaps exact inputs, so the input is found by searching over QuoteOffline.
+func (pool *MeteoraDammPool) QuoteExactOut(ctx context.Context, solClient pkg.RPC, outputMint string, desiredOut cosmosmath.Int) (cosmosmath.Int, error) {
+	if err := pool.UpdateVaults(ctx, solClient); err != nil {
+		return cosmosmath.Int{}, err
+	}
+	return pool.QuoteExactOutOffline(outputMint, desiredOut)
+}
+
+// QuoteExactOutOffline is QuoteExactOut against the vault state as last loaded
+func (pool *MeteoraDammPool) QuoteExactOutOffline(outputMint string, desiredOut cosmosmath.Int) (cosmosmath.Int, error) {
+	if !desiredOut.IsPositive() {
+		return cosmosmath.Int{}, fmt.Errorf("output amount must be positive")
+	}
+	if err := pool.checkTradable(); err != nil {
+		return cosmosmath.Int{}, err
+	}
+	aToB, err := pool.isAToB(outputMint)
+	if err != nil {
+		return cosmosmath.Int{}, err
+	}
+	aToB = !aToB
+
+	// Double the bound until it buys enough, then bisect; u64 inputs only
+	low, high := cosmosmath.ZeroInt(), desiredOut
+	maxInput := cosmosmath.NewIntFromUint64(^uint64(0))
+	for pool.swapOut(aToB, high).LT(desiredOut) {
+		if high.GTE(maxInput) {
+			return cosmosmath.Int{}, fmt.Errorf("%w: pool can't pay out %s", solerrors.ErrInsufficientLiquidity, desiredOut)
+		}
+		low = high
+		high = cosmosmath.MinInt(high.MulRaw(2), maxInput)
+	}
+	for high.Sub(low).GT(cosmosmath.OneInt()) {
+		mid := low.Add(high).QuoRaw(2)
+		if pool.swapOut(aToB, mid).GTE(desiredOut) {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+	return high, nil
+}
+
+// checkTradable rejects disabled pools and pools still waiting for a timestamp activation;
+// slot activations are left to the program
+func (pool *MeteoraDammPool) checkTradable() error {
+	if !pool.Enabled {
+		return fmt.Errorf("pool %s is disabled", pool.PoolId)
+	}
+	if pool.ActivationType == dammActivationTypeTimestamp && uint64(pool.now().Unix()) < pool.ActivationPoint {
+		return fmt.Errorf("pool %s activates at %d", pool.PoolId, pool.ActivationPoint)
+	}
+	return nil
+}
+
+func (pool *MeteoraDammPool) isAToB(inputMint string) (bool, error) {
+	switch inputMint {
+	case pool.TokenAMint.String():
+		return true, nil
+	case pool.TokenBMint.String():
+		return false, nil
+	default:
+		return false, fmt.Errorf("mint %s not in pool %s", inputMint, pool.PoolId)
+	}
+}
+
+// swapOut follows the program's swap: the protocol fee is taken from the input, the rest is
+// deposited into the input vault, the trade fee comes out of what that deposit is worth to the
+// pool, and the constant-product output is withdrawn from the output vault in whole LP tokens
+func (pool *MeteoraDammPool) swapOut(aToB bool, inAmount cosmosmath.Int) cosmosmath.Int {
+	now := pool.now().Unix()
+	vaultIn, vaultOut := pool.VaultA, pool.VaultB
+	poolLpIn, poolLpOut := cosmosmath.NewIntFromUint64(pool.PoolVaultALp), cosmosmath.NewIntFromUint64(pool.PoolVaultBLp)
+	supplyIn, supplyOut := cosmosmath.NewIntFromUint64(pool.VaultALpSupply), cosmosmath.NewIntFromUint64(pool.VaultBLpSupply)
+	if !aToB {
+		vaultIn, vaultOut = vaultOut, vaultIn
+		poolLpIn, poolLpOut = poolLpOut, poolLpIn
+		supplyIn, supplyOut = supplyOut, supplyIn
+	}
+
+	if supplyIn.IsZero() || supplyOut.IsZero() {
+		return cosmosmath.ZeroInt()
+	}
+
+	tradeFee := dammFee(inAmount, pool.TradeFeeNumerator, pool.TradeFeeDenominator)
+	protocolFee := dammFee(tradeFee, pool.ProtocolTradeFeeNumerator, pool.ProtocolTradeFeeDenominator)
+	tradeFee = tradeFee.Sub(protocolFee)
+	inAfterProtocolFee := inAmount.Sub(protocolFee)
+
+	beforeIn := vaultIn.amountByShare(now, poolLpIn, supplyIn)
+	inLp := vaultIn.unmintAmount(now, inAfterProtocolFee, supplyIn)
+	// The deposit lands in the vault's unlocked balance and mints inLp to the pool
+	afterIn := vaultIn.UnlockedAmount(now).Add(inAfterProtocolFee).Mul(poolLpIn.Add(inLp)).Quo(supplyIn.Add(inLp))
+	actualIn := afterIn.Sub(beforeIn).Sub(tradeFee)
+	if !actualIn.IsPositive() {
+		return cosmosmath.ZeroInt()
+	}
+
+	reserveOut := vaultOut.amountByShare(now, poolLpOut, supplyOut)
+	out := reserveOut.Mul(actualIn).Quo(beforeIn.Add(actualIn))
+	outLp := vaultOut.unmintAmount(now, out, supplyOut)
+	return vaultOut.amountByShare(now, outLp, supplyOut)
+}
+
+// dammFee charges numerator/denominator of amount, rounded down but at least 1 when the rate
+// isn't zero
+func dammFee(amount cosmosmath.Int, numerator, denominator uint64) cosmosmath.Int {
+	if numerator == 0 || amount.IsZero() {
+		return cosmosmath.ZeroInt()
+	}
+	fee := amount.Mul(cosmosmath.NewIntFromUint64(numerator)).Quo(cosmosmath.NewIntFromUint64(denominator))
+	if fee.IsZero() {
+		return cosmosmath.OneInt()
+	}
+	return fee
+}
+
+// BuildSwapInstructions swaps between the user's associated token accounts, which must exist
+func (pool *MeteoraDammPool) BuildSwapInstructions(ctx context.Context, solClient pkg.RPC, user solana.PublicKey, inputMint string, inputAmount cosmosmath.Int, minOut cosmosmath.Int) ([]solana.Instruction, error) {
+	aToB, err := pool.isAToB(inputMint)
+	if err != nil {
+		return nil, err
+	}
+	if pool.VaultA.TokenVault.IsZero() || pool.VaultB.TokenVault.IsZero() {
+		if err := pool.UpdateVaults(ctx, solClient); err != nil {
+			return nil, err
+		}
+	}
+	inst, err := pool.swapInstruction(user, aToB, inputAmount, minOut)
+	if err != nil {
+		return nil, err
+	}
+	return []solana.Instruction{inst}, nil
+}
+
+// BuildSwapInstructionsExactOut swaps the input QuoteExactOut finds for amountOut, requiring
+// at least amountOut back; it fails if that input exceeds maxIn
+func (pool *MeteoraDammPool) BuildSwapInstructionsExactOut(ctx context.Context, solClient pkg.RPC, user solana.PublicKey, outputMint string, amountOut cosmosmath.Int, maxIn cosmosmath.Int) ([]solana.Instruction, error) {
+	amountIn, err := pool.QuoteExactOut(ctx, solClient, outputMint, amountOut)
+	if err != nil {
+		return nil, err
+	}
+	if amountIn.GT(maxIn) {
+		return nil, fmt.Errorf("input %s for output %s exceeds maximum %s", amountIn, amountOut, maxIn)
+	}
+	inputMint := pool.TokenAMint.String()
+	if outputMint == pool.TokenAMint.String() {
+		inputMint = pool.TokenBMint.String()
+	}
+	return pool.BuildSwapInstructions(ctx, solClient, user, inputMint, amountIn, amountOut)
+}
+
+func (pool *MeteoraDammPool) swapInstruction(user solana.PublicKey, aToB bool, inAmount, minOut cosmosmath.Int) (solana.Instruction, error) {
+	if !inAmount.IsUint64() || !minOut.IsUint64() {
+		return nil, fmt.Errorf("swap amounts must fit in u64")
+	}
+	userA, _, err := solana.FindAssociatedTokenAddress(user, pool.TokenAMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive token A account: %w", err)
+	}
+	userB, _, err := solana.FindAssociatedTokenAddress(user, pool.TokenBMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive token B account: %w", err)
+	}
+	source, destination, protocolFee := userA, userB, pool.ProtocolTokenAFee
+	if !aToB {
+		source, destination, protocolFee = userB, userA, pool.ProtocolTokenBFee
+	}
+
+	data := append([]byte{}, DammSwapIxDiscm[:]...)
+	data = binary.LittleEndian.AppendUint64(data, inAmount.Uint64())
+	data = binary.LittleEndian.AppendUint64(data, minOut.Uint64())
+	accounts := solana.AccountMetaSlice{
+		solana.NewAccountMeta(pool.PoolId, true, false),
+		solana.NewAccountMeta(source, true, false),
+		solana.NewAccountMeta(destination, true, false),
+		solana.NewAccountMeta(pool.AVault, true, false),
+		solana.NewAccountMeta(pool.BVault, true, false),
+		solana.NewAccountMeta(pool.VaultA.TokenVault, true, false),
+		solana.NewAccountMeta(pool.VaultB.TokenVault, true, false),
+		solana.NewAccountMeta(pool.VaultA.LpMint, true, false),
+		solana.NewAccountMeta(pool.VaultB.LpMint, true, false),
+		solana.NewAccountMeta(pool.AVaultLp, true, false),
+		solana.NewAccountMeta(pool.BVaultLp, true, false),
+		solana.NewAccountMeta(protocolFee, true, false),
+		solana.NewAccountMeta(user, false, true),
+		solana.NewAccountMeta(MeteoraVaultProgramID, false, false),
+		solana.NewAccountMeta(solana.TokenProgramID, false, false),
+	}
+	return solana.NewInstruction(MeteoraDammProgramID, accounts, data), nil
+}
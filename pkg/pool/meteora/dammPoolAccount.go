@@ -0,0 +1,66 @@
+package meteora
+
+import (
+	"github.com/gtdvccc/SolRouteTmp/pkg/layout"
+	"github.com/gagliardetto/solana-go"
+)
+
+// DammPool is the Pool account of the Dynamic AMM program IDL, after the discriminator,
+// through the tag of its curve type. Stable curves carry their parameters after the tag, which
+// MeteoraDammPool doesn't read since it only prices constant product pools.
+type DammPool struct {
+	LpMint            solana.PublicKey
+	TokenAMint        solana.PublicKey
+	TokenBMint        solana.PublicKey
+	AVault            solana.PublicKey
+	BVault            solana.PublicKey
+	AVaultLp          solana.PublicKey
+	BVaultLp          solana.PublicKey
+	AVaultLpBump      uint8
+	Enabled           bool
+	ProtocolTokenAFee solana.PublicKey
+	ProtocolTokenBFee solana.PublicKey
+	FeeLastUpdatedAt  uint64
+	Padding0          [24]uint8
+	Fees              DammPoolFees
+	PoolType          uint8
+	Stake             solana.PublicKey
+	TotalLockedLp     uint64
+	Bootstrapping     DammBootstrapping
+	PartnerInfo       DammPartnerInfo
+	Padding           DammPadding
+	CurveType         uint8
+}
+
+// DammPoolFees are a Dynamic AMM pool's trade fees as fractions
+type DammPoolFees struct {
+	TradeFeeNumerator           uint64
+	TradeFeeDenominator         uint64
+	ProtocolTradeFeeNumerator   uint64
+	ProtocolTradeFeeDenominator uint64
+}
+
+// DammBootstrapping is when and for whom a Dynamic AMM pool opens
+type DammBootstrapping struct {
+	ActivationPoint  uint64
+	WhitelistedVault solana.PublicKey
+	PoolCreator      solana.PublicKey
+	ActivationType   uint8
+}
+
+// DammPartnerInfo is the fee share of the partner that created a Dynamic AMM pool
+type DammPartnerInfo struct {
+	FeeNumerator     uint64
+	PartnerAuthority solana.PublicKey
+	PendingFeeA      uint64
+	PendingFeeB      uint64
+}
+
+// DammPadding is space reserved in the Dynamic AMM pool account
+type DammPadding struct {
+	Padding0 [6]uint8
+	Padding1 [21]uint64
+	Padding2 [21]uint64
+}
+
+var dammPoolLayout = layout.MustOf(DammPool{})
@@ -0,0 +1,429 @@
+package dammv2
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clmmmath"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	cosmath "cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+var (
+	_ pkg.Pool          = (*Pool)(nil)
+	_ pkg.VaultPool     = (*Pool)(nil)
+	_ pkg.SpotPricePool = (*Pool)(nil)
+)
+
+// BaseFee is the scheduled part of the trade fee; after activation the cliff fee falls by
+// ReductionFactor every PeriodFrequency slots or seconds, NumberOfPeriod times
+type BaseFee struct {
+	CliffFeeNumerator uint64
+	FeeSchedulerMode  uint8
+	NumberOfPeriod    uint16
+	PeriodFrequency   uint64
+	ReductionFactor   uint64
+}
+
+// DynamicFee adds a fee that grows with recent price volatility
+type DynamicFee struct {
+	Initialized           bool
+	MaxVolatilityAccum    uint32
+	VariableFeeControl    uint32
+	BinStep               uint16
+	VolatilityAccumulator *big.Int
+}
+
+// Pool is a Meteora DAMM v2 pool. Its whole state, including the price, lives in the pool
+// account, so quotes only need that account and the clock.
+type Pool struct {
+	PoolId      solana.PublicKey
+	TokenAMint  solana.PublicKey
+	TokenBMint  solana.PublicKey
+	TokenAVault solana.PublicKey
+	TokenBVault solana.PublicKey
+
+	BaseFee            BaseFee
+	DynamicFee         DynamicFee
+	ProtocolFeePercent uint8
+
+	Liquidity    *big.Int // Q64.64
+	SqrtMinPrice *big.Int // Q64.64
+	SqrtMaxPrice *big.Int
+	SqrtPrice    *big.Int
+
+	ActivationPoint uint64
+	ActivationType  uint8
+	PoolStatus      uint8
+	TokenAFlag      uint8 // 1 when token A is a Token-2022 mint
+	TokenBFlag      uint8
+	CollectFeeMode  uint8
+
+	// Slot and UnixTimestamp of the last refresh, used for activation and the fee schedule
+	Slot          uint64
+	UnixTimestamp int64
+}
+
+// NewPool decodes a pool account
+func NewPool(id solana.PublicKey, data []byte) (*Pool, error) {
+	pool := &Pool{PoolId: id}
+	if err := pool.Decode(data); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// Decode reads a pool account
+func (pool *Pool) Decode(data []byte) error {
+	if len(data) <= collectFeeModeOffset {
+		return fmt.Errorf("pool account too short: %d bytes", len(data))
+	}
+	if [8]byte(data[:8]) != PoolDiscriminator {
+		return fmt.Errorf("invalid pool account discriminator")
+	}
+	key := func(offset int) solana.PublicKey { return solana.PublicKeyFromBytes(data[offset : offset+32]) }
+	u64 := func(offset int) uint64 { return binary.LittleEndian.Uint64(data[offset:]) }
+
+	pool.BaseFee = BaseFee{
+		CliffFeeNumerator: u64(baseFeeOffset),
+		FeeSchedulerMode:  data[baseFeeOffset+8],
+		NumberOfPeriod:    binary.LittleEndian.Uint16(data[baseFeeOffset+14:]),
+		PeriodFrequency:   u64(baseFeeOffset + 16),
+		ReductionFactor:   u64(baseFeeOffset + 24),
+	}
+	pool.ProtocolFeePercent = data[protocolFeePctOffset]
+	pool.DynamicFee = DynamicFee{
+		Initialized:           data[dynamicFeeOffset] != 0,
+		MaxVolatilityAccum:    binary.LittleEndian.Uint32(data[dynamicFeeOffset+8:]),
+		VariableFeeControl:    binary.LittleEndian.Uint32(data[dynamicFeeOffset+12:]),
+		BinStep:               binary.LittleEndian.Uint16(data[dynamicFeeOffset+16:]),
+		VolatilityAccumulator: readU128(data, dynamicFeeOffset+64),
+	}
+	pool.TokenAMint = key(TokenAMintOffset)
+	pool.TokenBMint = key(TokenBMintOffset)
+	pool.TokenAVault = key(tokenAVaultOffset)
+	pool.TokenBVault = key(tokenBVaultOffset)
+	pool.Liquidity = readU128(data, liquidityOffset)
+	pool.SqrtMinPrice = readU128(data, sqrtMinPriceOffset)
+	pool.SqrtMaxPrice = readU128(data, sqrtMaxPriceOffset)
+	pool.SqrtPrice = readU128(data, sqrtPriceOffset)
+	pool.ActivationPoint = u64(activationPointOffset)
+	pool.ActivationType = data[activationTypeOffset]
+	pool.PoolStatus = data[poolStatusOffset]
+	pool.TokenAFlag = data[tokenAFlagOffset]
+	pool.TokenBFlag = data[tokenBFlagOffset]
+	pool.CollectFeeMode = data[collectFeeModeOffset]
+	return nil
+}
+
+// readU128 reads a little-endian u128
+func readU128(data []byte, offset int) *big.Int {
+	be := make([]byte, 16)
+	for i := range 16 {
+		be[15-i] = data[offset+i]
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+func (pool *Pool) ProtocolName() pkg.ProtocolName {
+	return pkg.ProtocolNameMeteoraDammV2
+}
+
+func (pool *Pool) ProtocolType() pkg.ProtocolType {
+	return pkg.ProtocolTypeMeteoraDammV2
+}
+
+func (pool *Pool) GetProgramID() solana.PublicKey {
+	return ProgramID
+}
+
+func (pool *Pool) GetID() string {
+	return pool.PoolId.String()
+}
+
+// GetTokens returns token A and token B
+func (pool *Pool) GetTokens() (string, string) {
+	return pool.TokenAMint.String(), pool.TokenBMint.String()
+}
+
+// SpotPrice returns the pool's price and liquidity from its state
+func (pool *Pool) SpotPrice(ctx context.Context, solClient pkg.RPC) (pkg.SpotPrice, error) {
+	if pool.SqrtPrice == nil || pool.Liquidity == nil {
+		return pkg.SpotPrice{}, fmt.Errorf("pool %s has no price", pool.PoolId)
+	}
+	// Unlike the concentrated liquidity programs, liquidity is itself Q64.64
+	price, depth := clmmmath.SpotPrice(pool.SqrtPrice, new(big.Int).Rsh(pool.Liquidity, clmmmath.Resolution))
+	return pkg.SpotPrice{Price: price, Depth: depth}, nil
+}
+
+// TokenVaults returns the pool's vaults, owned by the program-wide pool authority
+func (pool *Pool) TokenVaults() []pkg.TokenVault {
+	return []pkg.TokenVault{
+		{Address: pool.TokenAVault, Mint: pool.TokenAMint, Authority: PoolAuthority},
+		{Address: pool.TokenBVault, Mint: pool.TokenBMint, Authority: PoolAuthority},
+	}
+}
+
+// refresh reloads the pool together with the clock
+func (pool *Pool) refresh(ctx context.Context, solClient pkg.RPC) error {
+	accounts := []solana.PublicKey{pool.PoolId, solana.SysVarClockPubkey}
+	results, err := solClient.GetMultipleAccountsWithOpts(ctx, accounts, sol.MultipleAccountsOpts(ctx, rpc.CommitmentProcessed))
+	if err != nil {
+		return fmt.Errorf("failed to get pool %s: %w", pool.PoolId, err)
+	}
+	if len(results.Value) != len(accounts) || results.Value[0] == nil || results.Value[1] == nil {
+		return fmt.Errorf("pool %s or the clock sysvar not found", pool.PoolId)
+	}
+	if err := pool.Decode(results.Value[0].Data.GetBinary()); err != nil {
+		return fmt.Errorf("failed to decode pool %s: %w", pool.PoolId, err)
+	}
+	clock := results.Value[1].Data.GetBinary()
+	if len(clock) < 40 {
+		return fmt.Errorf("clock sysvar too short: %d bytes", len(clock))
+	}
+	pool.Slot = binary.LittleEndian.Uint64(clock[0:])
+	pool.UnixTimestamp = int64(binary.LittleEndian.Uint64(clock[32:]))
+	return nil
+}
+
+// Quote refreshes the pool and prices the swap as the program does
+func (pool *Pool) Quote(ctx context.Context, solClient pkg.RPC, inputMint string, inputAmount cosmath.Int) (cosmath.Int, error) {
+	if err := pool.refresh(ctx, solClient); err != nil {
+		return cosmath.Int{}, err
+	}
+	return pool.QuoteOffline(inputMint, inputAmount)
+}
+
+// QuoteOffline is Quote against the pool state and clock as last loaded
+func (pool *Pool) QuoteOffline(inputMint string, inputAmount cosmath.Int) (cosmath.Int, error) {
+	if !inputAmount.IsPositive() {
+		return cosmath.Int{}, fmt.Errorf("input amount must be positive")
+	}
+	aToB, err := pool.isAToB(inputMint)
+	if err != nil {
+		return cosmath.Int{}, err
+	}
+	feeNumerator, err := pool.checkTradable()
+	if err != nil {
+		return cosmath.Int{}, err
+	}
+	return pool.swapOut(aToB, inputAmount, feeNumerator)
+}
+
+// QuoteExactOut returns the smallest input whose quote delivers desiredOut. The program only
+// swaps exact inputs, so the input is found by searching over QuoteOffline.
+func (pool *Pool) QuoteExactOut(ctx context.Context, solClient pkg.RPC, outputMint string, desiredOut cosmath.Int) (cosmath.Int, error) {
+	if err := pool.refresh(ctx, solClient); err != nil {
+		return cosmath.Int{}, err
+	}
+	return pool.QuoteExactOutOffline(outputMint, desiredOut)
+}
+
+// QuoteExactOutOffline is QuoteExactOut against the pool state and clock as last loaded
+func (pool *Pool) QuoteExactOutOffline(outputMint string, desiredOut cosmath.Int) (cosmath.Int, error) {
+	if !desiredOut.IsPositive() {
+		return cosmath.Int{}, fmt.Errorf("output amount must be positive")
+	}
+	aToB, err := pool.isAToB(outputMint)
+	if err != nil {
+		return cosmath.Int{}, err
+	}
+	aToB = !aToB
+	feeNumerator, err := pool.checkTradable()
+	if err != nil {
+		return cosmath.Int{}, err
+	}
+	// An input that pushes the price out of range fills nothing
+	out := func(amountIn cosmath.Int) cosmath.Int {
+		amountOut, err := pool.swapOut(aToB, amountIn, feeNumerator)
+		if err != nil {
+			return cosmath.ZeroInt()
+		}
+		return amountOut
+	}
+
+	// Double the bound until it buys enough, then bisect; u64 inputs only
+	low, high := cosmath.ZeroInt(), desiredOut
+	maxInput := cosmath.NewIntFromUint64(^uint64(0))
+	for out(high).LT(desiredOut) {
+		if high.GTE(maxInput) {
+			return cosmath.Int{}, fmt.Errorf("%w: pool can't pay out %s", solerrors.ErrInsufficientLiquidity, desiredOut)
+		}
+		low = high
+		high = cosmath.MinInt(high.MulRaw(2), maxInput)
+	}
+	for high.Sub(low).GT(cosmath.OneInt()) {
+		mid := low.Add(high).QuoRaw(2)
+		if out(mid).GTE(desiredOut) {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+	return high, nil
+}
+
+func (pool *Pool) isAToB(inputMint string) (bool, error) {
+	switch inputMint {
+	case pool.TokenAMint.String():
+		return true, nil
+	case pool.TokenBMint.String():
+		return false, nil
+	default:
+		return false, fmt.Errorf("mint %s not in pool %s", inputMint, pool.PoolId)
+	}
+}
+
+// currentPoint is the slot or timestamp the pool's activation and fee schedule count in
+func (pool *Pool) currentPoint() uint64 {
+	if pool.ActivationType == ActivationTypeTimestamp {
+		return uint64(max(pool.UnixTimestamp, 0))
+	}
+	return pool.Slot
+}
+
+// checkTradable rejects disabled and not yet activated pools and returns the current trade
+// fee numerator
+func (pool *Pool) checkTradable() (uint64, error) {
+	if pool.PoolStatus != PoolStatusEnabled {
+		return 0, fmt.Errorf("pool %s is disabled", pool.PoolId)
+	}
+	if pool.currentPoint() < pool.ActivationPoint {
+		return 0, fmt.Errorf("pool %s activates at %d", pool.PoolId, pool.ActivationPoint)
+	}
+	return pool.TradeFeeNumerator()
+}
+
+// TradeFeeNumerator returns the fee, over FeeDenominator, charged at the last loaded point:
+// the scheduled base fee plus the dynamic fee, capped at MaxFeeNumerator
+func (pool *Pool) TradeFeeNumerator() (uint64, error) {
+	fee := new(big.Int).SetUint64(pool.BaseFee.CliffFeeNumerator)
+	if pool.BaseFee.PeriodFrequency > 0 && pool.currentPoint() >= pool.ActivationPoint {
+		periods := min((pool.currentPoint()-pool.ActivationPoint)/pool.BaseFee.PeriodFrequency, uint64(pool.BaseFee.NumberOfPeriod))
+		reduction := new(big.Int).SetUint64(pool.BaseFee.ReductionFactor)
+		switch pool.BaseFee.FeeSchedulerMode {
+		case FeeSchedulerLinear:
+			fee.Sub(fee, reduction.Mul(reduction, new(big.Int).SetUint64(periods)))
+			if fee.Sign() < 0 {
+				fee.SetInt64(0)
+			}
+		case FeeSchedulerExponential:
+			// cliff * (1 - reduction/10000)^periods
+			keep := new(big.Int).Sub(big.NewInt(basisPointMax), reduction)
+			if keep.Sign() < 0 {
+				keep.SetInt64(0)
+			}
+			scale := new(big.Int).Exp(big.NewInt(basisPointMax), new(big.Int).SetUint64(periods), nil)
+			fee.Mul(fee, keep.Exp(keep, new(big.Int).SetUint64(periods), nil))
+			fee.Quo(fee, scale)
+		default:
+			return 0, fmt.Errorf("unsupported fee scheduler mode %d", pool.BaseFee.FeeSchedulerMode)
+		}
+	}
+
+	if dyn := pool.DynamicFee; dyn.Initialized && dyn.VolatilityAccumulator != nil {
+		// ((volatility * bin_step)^2 * variable_fee_control) / 1e11, rounded up
+		v := new(big.Int).Mul(dyn.VolatilityAccumulator, big.NewInt(int64(dyn.BinStep)))
+		v.Mul(v, v)
+		v.Mul(v, big.NewInt(int64(dyn.VariableFeeControl)))
+		v.Add(v, big.NewInt(dynamicFeeScale-1))
+		v.Quo(v, big.NewInt(dynamicFeeScale))
+		fee.Add(fee, v)
+	}
+
+	if fee.Cmp(big.NewInt(MaxFeeNumerator)) > 0 {
+		return MaxFeeNumerator, nil
+	}
+	return fee.Uint64(), nil
+}
+
+// feesOnInput reports whether the fee is charged on the input of the swap; otherwise it comes
+// out of the output
+func (pool *Pool) feesOnInput(aToB bool) bool {
+	return pool.CollectFeeMode == CollectFeeOnlyB && !aToB
+}
+
+// swapOut follows the program's swap: the fee is charged on the input or the output depending
+// on the collect fee mode, rounded up, and the price may not leave [SqrtMinPrice, SqrtMaxPrice]
+func (pool *Pool) swapOut(aToB bool, inAmount cosmath.Int, feeNumerator uint64) (cosmath.Int, error) {
+	if pool.Liquidity == nil || pool.Liquidity.Sign() == 0 {
+		return cosmath.Int{}, fmt.Errorf("pool %s has no liquidity", pool.PoolId)
+	}
+	amount := inAmount.BigInt()
+	if pool.feesOnInput(aToB) {
+		amount.Sub(amount, tradeFee(amount, feeNumerator))
+	}
+
+	var out *big.Int
+	if aToB {
+		next := nextSqrtPriceFromAmountA(pool.SqrtPrice, pool.Liquidity, amount)
+		if next.Cmp(pool.SqrtMinPrice) < 0 {
+			return cosmath.Int{}, fmt.Errorf("%w: swap moves the price below the pool's range", solerrors.ErrInsufficientLiquidity)
+		}
+		out = deltaAmountB(next, pool.SqrtPrice, pool.Liquidity)
+	} else {
+		next := nextSqrtPriceFromAmountB(pool.SqrtPrice, pool.Liquidity, amount)
+		if next.Cmp(pool.SqrtMaxPrice) > 0 {
+			return cosmath.Int{}, fmt.Errorf("%w: swap moves the price above the pool's range", solerrors.ErrInsufficientLiquidity)
+		}
+		out = deltaAmountA(pool.SqrtPrice, next, pool.Liquidity)
+	}
+
+	if !pool.feesOnInput(aToB) {
+		out.Sub(out, tradeFee(out, feeNumerator))
+	}
+	if out.Sign() < 0 {
+		out.SetInt64(0)
+	}
+	return cosmath.NewIntFromBigInt(out), nil
+}
+
+// tradeFee charges feeNumerator/FeeDenominator of amount, rounded up
+func tradeFee(amount *big.Int, feeNumerator uint64) *big.Int {
+	fee := new(big.Int).Mul(amount, new(big.Int).SetUint64(feeNumerator))
+	fee.Add(fee, big.NewInt(FeeDenominator-1))
+	return fee.Quo(fee, big.NewInt(FeeDenominator))
+}
+
+// nextSqrtPriceFromAmountA is ceil(L * √P / (L + amount * √P))
+func nextSqrtPriceFromAmountA(sqrtPrice, liquidity, amount *big.Int) *big.Int {
+	numerator := new(big.Int).Mul(liquidity, sqrtPrice)
+	denominator := new(big.Int).Mul(amount, sqrtPrice)
+	denominator.Add(denominator, liquidity)
+	return ceilQuo(numerator, denominator)
+}
+
+// nextSqrtPriceFromAmountB is √P + (amount << 128) / L, rounded down
+func nextSqrtPriceFromAmountB(sqrtPrice, liquidity, amount *big.Int) *big.Int {
+	quotient := new(big.Int).Lsh(amount, 128)
+	quotient.Quo(quotient, liquidity)
+	return quotient.Add(quotient, sqrtPrice)
+}
+
+// deltaAmountA is L * (upper - lower) / (lower * upper), rounded down
+func deltaAmountA(lower, upper, liquidity *big.Int) *big.Int {
+	numerator := new(big.Int).Sub(upper, lower)
+	numerator.Mul(numerator, liquidity)
+	return numerator.Quo(numerator, new(big.Int).Mul(lower, upper))
+}
+
+// deltaAmountB is L * (upper - lower) >> 128, rounded down
+func deltaAmountB(lower, upper, liquidity *big.Int) *big.Int {
+	delta := new(big.Int).Sub(upper, lower)
+	delta.Mul(delta, liquidity)
+	return delta.Rsh(delta, 128)
+}
+
+func ceilQuo(numerator, denominator *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+	if r.Sign() > 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
@@ -0,0 +1,92 @@
+package dammv2
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/txbuilder"
+	cosmath "cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+)
+
+// BuildSwapInstructions swaps between the user's associated token accounts, which must exist.
+// Token-2022 accounts are derived under the Token-2022 program; transfer fees are applied by
+// sol.MintInspector.WrapPools.
+func (pool *Pool) BuildSwapInstructions(ctx context.Context, solClient pkg.RPC, user solana.PublicKey, inputMint string, inputAmount cosmath.Int, minOut cosmath.Int) ([]solana.Instruction, error) {
+	aToB, err := pool.isAToB(inputMint)
+	if err != nil {
+		return nil, err
+	}
+	inst, err := pool.swapInstruction(user, aToB, inputAmount, minOut)
+	if err != nil {
+		return nil, err
+	}
+	return []solana.Instruction{inst}, nil
+}
+
+// BuildSwapInstructionsExactOut swaps the input QuoteExactOut finds for amountOut, requiring
+// at least amountOut back; it fails if that input exceeds maxIn
+func (pool *Pool) BuildSwapInstructionsExactOut(ctx context.Context, solClient pkg.RPC, user solana.PublicKey, outputMint string, amountOut cosmath.Int, maxIn cosmath.Int) ([]solana.Instruction, error) {
+	amountIn, err := pool.QuoteExactOut(ctx, solClient, outputMint, amountOut)
+	if err != nil {
+		return nil, err
+	}
+	if amountIn.GT(maxIn) {
+		return nil, fmt.Errorf("input %s for output %s exceeds maximum %s", amountIn, amountOut, maxIn)
+	}
+	inputMint := pool.TokenAMint.String()
+	if outputMint == pool.TokenAMint.String() {
+		inputMint = pool.TokenBMint.String()
+	}
+	return pool.BuildSwapInstructions(ctx, solClient, user, inputMint, amountIn, amountOut)
+}
+
+// tokenProgram returns the token program of a side from its token flag
+func tokenProgram(flag uint8) solana.PublicKey {
+	if flag == tokenFlagToken2022 {
+		return solana.Token2022ProgramID
+	}
+	return solana.TokenProgramID
+}
+
+func (pool *Pool) swapInstruction(user solana.PublicKey, aToB bool, inAmount, minOut cosmath.Int) (solana.Instruction, error) {
+	if !inAmount.IsUint64() || !minOut.IsUint64() {
+		return nil, fmt.Errorf("swap amounts must fit in u64")
+	}
+	programA, programB := tokenProgram(pool.TokenAFlag), tokenProgram(pool.TokenBFlag)
+	userA, err := txbuilder.AssociatedTokenAddress(user, pool.TokenAMint, programA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive token A account: %w", err)
+	}
+	userB, err := txbuilder.AssociatedTokenAddress(user, pool.TokenBMint, programB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive token B account: %w", err)
+	}
+	source, destination := userA, userB
+	if !aToB {
+		source, destination = userB, userA
+	}
+
+	data := append([]byte{}, SwapIxDiscm[:]...)
+	data = binary.LittleEndian.AppendUint64(data, inAmount.Uint64())
+	data = binary.LittleEndian.AppendUint64(data, minOut.Uint64())
+	accounts := solana.AccountMetaSlice{
+		solana.NewAccountMeta(PoolAuthority, false, false),
+		solana.NewAccountMeta(pool.PoolId, true, false),
+		solana.NewAccountMeta(source, true, false),
+		solana.NewAccountMeta(destination, true, false),
+		solana.NewAccountMeta(pool.TokenAVault, true, false),
+		solana.NewAccountMeta(pool.TokenBVault, true, false),
+		solana.NewAccountMeta(pool.TokenAMint, false, false),
+		solana.NewAccountMeta(pool.TokenBMint, false, false),
+		solana.NewAccountMeta(user, false, true),
+		solana.NewAccountMeta(programA, false, false),
+		solana.NewAccountMeta(programB, false, false),
+		solana.NewAccountMeta(ProgramID, false, false), // referral token account: None
+		solana.NewAccountMeta(EventAuthority, false, false),
+		solana.NewAccountMeta(ProgramID, false, false),
+	}
+	return solana.NewInstruction(ProgramID, accounts, data), nil
+}
@@ -0,0 +1,60 @@
+package dammv2
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/utils"
+	"cosmossdk.io/math"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+)
+
+// swapEventDiscriminator identifies the EvtSwap event the program emits for swaps
+var swapEventDiscriminator = utils.GetDiscriminator("event", "EvtSwap")
+
+// tradeDirectionAToB is the EvtSwap trade direction of swaps selling token A
+const tradeDirectionAToB = 0
+
+type swapEvent struct {
+	Pool             solana.PublicKey
+	TradeDirection   uint8
+	HasReferral      bool
+	AmountIn         uint64
+	MinimumAmountOut uint64
+	OutputAmount     uint64
+	NextSqrtPrice    bin.Uint128
+	LpFee            uint64
+	ProtocolFee      uint64
+	PartnerFee       uint64
+	ReferralFee      uint64
+	ActualAmountIn   uint64
+	CurrentTimestamp uint64
+}
+
+// DecodeSwapEvent decodes the EvtSwap event the program emits for each swap. The trader is
+// the swap instruction's payer.
+func DecodeSwapEvent(event pkg.ProgramEvent) (pkg.SwapEvent, bool, error) {
+	if !bytes.HasPrefix(event.Data, swapEventDiscriminator) {
+		return pkg.SwapEvent{}, false, nil
+	}
+	var ev swapEvent
+	if err := bin.NewBinDecoder(event.Data[8:]).Decode(&ev); err != nil {
+		return pkg.SwapEvent{}, false, fmt.Errorf("failed to decode DAMM v2 swap event: %w", err)
+	}
+	swap := pkg.SwapEvent{
+		Protocol:     pkg.ProtocolNameMeteoraDammV2,
+		Pool:         ev.Pool,
+		ZeroForOne:   ev.TradeDirection == tradeDirectionAToB,
+		AmountIn:     ev.ActualAmountIn,
+		AmountOut:    ev.OutputAmount,
+		Fee:          ev.LpFee + ev.ProtocolFee + ev.PartnerFee + ev.ReferralFee,
+		SqrtPriceX64: math.NewIntFromBigInt(ev.NextSqrtPrice.BigInt()),
+	}
+	// pool authority, pool, user accounts, vaults, mints, then the payer
+	if len(event.Accounts) > 8 {
+		swap.User = event.Accounts[8]
+	}
+	return swap, true, nil
+}
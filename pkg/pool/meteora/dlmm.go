@@ -1,12 +1,15 @@
+// Package meteora implements Meteora DLMM and Dynamic AMM pools; DAMM v2 lives in
+// meteora/dammv2
 package meteora
 
 import (
 	"context"
 	"fmt"
 	"math/big"
-	"unsafe"
+	"time"
 
 	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
 	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
 	"github.com/gagliardetto/solana-go"
 )
@@ -14,82 +17,48 @@ import (
 // MeteoraDlmmPool represents a Meteora DLMM (Dynamic Liquidity Market Maker) pool
 // This struct contains all the pool parameters, state, and runtime data
 type MeteoraDlmmPool struct {
-	Discriminator [8]uint8 `bin:"borsh"`
-	parameters    struct {
-		baseFactor               uint16   `bin:"borsh"`
-		filterPeriod             uint16   `bin:"borsh"`
-		decayPeriod              uint16   `bin:"borsh"`
-		reductionFactor          uint16   `bin:"borsh"`
-		variableFeeControl       uint32   `bin:"borsh"`
-		maxVolatilityAccumulator uint32   `bin:"borsh"`
-		minBinId                 int32    `bin:"borsh"`
-		maxBinId                 int32    `bin:"borsh"`
-		protocolShare            uint16   `bin:"borsh"`
-		baseFeePowerFactor       uint8    `bin:"borsh"`
-		padding                  [5]uint8 `bin:"borsh"`
-	} `bin:"borsh"`
-	vParameters struct {
-		volatilityAccumulator uint32   `bin:"borsh"`
-		volatilityReference   uint32   `bin:"borsh"`
-		indexReference        int32    `bin:"borsh"`
-		padding               [4]uint8 `bin:"borsh"`
-		lastUpdateTimestamp   int64    `bin:"borsh"`
-		padding1              [8]uint8 `bin:"borsh"`
-	} `bin:"borsh"`
-	bumpSeed                [1]uint8         `bin:"borsh"`
-	binStepSeed             [2]uint8         `bin:"borsh"`
-	pairType                uint8            `bin:"borsh"`
-	activeId                int32            `bin:"borsh"`
-	binStep                 uint16           `bin:"borsh"`
-	status                  uint8            `bin:"borsh"`
-	requireBaseFactorSeed   uint8            `bin:"borsh"`
-	baseFactorSeed          [2]uint8         `bin:"borsh"`
-	activationType          uint8            `bin:"borsh"`
-	creatorPoolOnOffControl uint8            `bin:"borsh"`
-	TokenXMint              solana.PublicKey `bin:"borsh"`
-	TokenYMint              solana.PublicKey `bin:"borsh"`
-	reserveX                solana.PublicKey `bin:"borsh"`
-	reserveY                solana.PublicKey `bin:"borsh"`
-	protocolFee             struct {
-		amountX uint64 `bin:"borsh"`
-		amountY uint64 `bin:"borsh"`
-	} `bin:"borsh"`
-	padding1    [32]uint8 `bin:"borsh"`
-	rewardInfos [2]struct {
-		mint                                      solana.PublicKey `bin:"borsh"`
-		vault                                     solana.PublicKey `bin:"borsh"`
-		funder                                    solana.PublicKey `bin:"borsh"`
-		rewardDuration                            int64            `bin:"borsh"`
-		rewardDurationEnd                         int64            `bin:"borsh"`
-		rewardRate                                int64            `bin:"borsh"`
-		lastUpdateTime                            int64            `bin:"borsh"`
-		cumulativeSecondsWithEmptyLiquidityReward int64            `bin:"borsh"`
-	} `bin:"borsh"`
-	oracle                   solana.PublicKey `bin:"borsh"`
-	binArrayBitmap           [16]uint64       `bin:"borsh"`
-	lastUpdatedAt            int64            `bin:"borsh"`
-	padding2                 [32]uint8        `bin:"borsh"`
-	preActivationSwapAddress solana.PublicKey `bin:"borsh"`
-	baseKey                  solana.PublicKey `bin:"borsh"`
-	activationPoint          uint64           `bin:"borsh"`
-	preActivationDuration    uint64           `bin:"borsh"`
-	padding3                 [8]uint8         `bin:"borsh"`
-	padding4                 uint64           `bin:"borsh"`
-	creator                  solana.PublicKey `bin:"borsh"`
-	tokenMintXProgramFlag    uint8            `bin:"borsh"`
-	tokenMintYProgramFlag    uint8            `bin:"borsh"`
-	reserved                 [22]uint8        `bin:"borsh"`
-	_                        [16]uint8        `bin:"borsh"` // padding to ensure 904 bytes total size
+	// On-chain state, decoded from LbPair
+	parameters               StaticParameters
+	vParameters              VariableParameters
+	bumpSeed                 [1]uint8
+	binStepSeed              [2]uint8
+	pairType                 uint8
+	activeId                 int32
+	binStep                  uint16
+	status                   uint8
+	requireBaseFactorSeed    uint8
+	baseFactorSeed           [2]uint8
+	activationType           uint8
+	creatorPoolOnOffControl  uint8
+	TokenXMint               solana.PublicKey
+	TokenYMint               solana.PublicKey
+	reserveX                 solana.PublicKey
+	reserveY                 solana.PublicKey
+	protocolFee              ProtocolFee
+	rewardInfos              [2]RewardInfo
+	oracle                   solana.PublicKey
+	binArrayBitmap           [16]uint64
+	lastUpdatedAt            int64
+	preActivationSwapAddress solana.PublicKey
+	baseKey                  solana.PublicKey
+	activationPoint          uint64
+	preActivationDuration    uint64
+	creator                  solana.PublicKey
+	tokenMintXProgramFlag    uint8
+	tokenMintYProgramFlag    uint8
 
 	// Runtime fields (not part of on-chain data)
 	PoolId             solana.PublicKey
 	BinArrays          map[string]BinArray // key: binArrayPubkey
 	BitmapExtensionKey solana.PublicKey
 	bitmapExtension    *BinArrayBitmapExtension
-	Clock              sol.Clock
-	orgActiveId        int32
-	UserBaseAccount    solana.PublicKey
-	UserQuoteAccount   solana.PublicKey
+	// bitmapExtensionChecked is set once the extension was looked up, found or not
+	bitmapExtensionChecked bool
+	Clock                  sol.Clock
+	orgActiveId            int32
+	UserBaseAccount        solana.PublicKey
+	UserQuoteAccount       solana.PublicKey
+	TimeSource             clock.Clock // wall clock for timestamp activation; defaults to the system clock
 }
 
 func (pool *MeteoraDlmmPool) ProtocolName() pkg.ProtocolName {
@@ -114,230 +83,87 @@ func (pool *MeteoraDlmmPool) GetTokens() (string, string) {
 	return pool.TokenXMint.String(), pool.TokenYMint.String()
 }
 
-// Span returns the size of the pool struct in bytes
-func (pool *MeteoraDlmmPool) Span() uint64 {
-	return uint64(unsafe.Sizeof(*pool))
+// SpotPrice returns the price of the active bin. Its depth is the active bin's liquidity when
+// its bin array is loaded, zero otherwise.
+func (pool *MeteoraDlmmPool) SpotPrice(ctx context.Context, solClient pkg.RPC) (pkg.SpotPrice, error) {
+	priceX64, err := GetPriceFromID(pool.activeId, pool.binStep)
+	if err != nil {
+		return pkg.SpotPrice{}, fmt.Errorf("failed to get active bin price: %w", err)
+	}
+	price, _ := new(big.Float).Quo(new(big.Float).SetInt(priceX64.Big()), new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), uint(ScaleOffset)))).Float64()
+	spot := pkg.SpotPrice{Price: price}
+	for _, binArray := range pool.BinArrays {
+		if within, err := binArray.IsBinIDWithinRange(pool.activeId); err != nil || !within {
+			continue
+		}
+		if bin, err := binArray.GetBinMut(pool.activeId); err == nil {
+			spot.Depth = float64(bin.amountX)*price + float64(bin.amountY)
+		}
+		break
+	}
+	return spot, nil
 }
 
-// Offset returns the byte offset of a specific field in the pool data
-func (pool *MeteoraDlmmPool) Offset(field string) uint64 {
-	switch field {
-	case "TokenYMint":
-		return 120
-	case "TokenXMint":
-		return 88
-	default:
-		return 0
+// TokenVaults returns the pair reserves, which are controlled by the lb pair account
+func (pool *MeteoraDlmmPool) TokenVaults() []pkg.TokenVault {
+	return []pkg.TokenVault{
+		{Address: pool.reserveX, Mint: pool.TokenXMint, Authority: pool.PoolId},
+		{Address: pool.reserveY, Mint: pool.TokenYMint, Authority: pool.PoolId},
 	}
 }
 
-// Decode deserializes binary data into the pool structure
-func (pool *MeteoraDlmmPool) Decode(data []byte) error {
-	// Manual parsing for first few fields
-	offset := 8 // Skip discriminator
-	pool.parameters.baseFactor = uint16(data[offset]) | uint16(data[offset+1])<<8
-	offset += 2
-
-	pool.parameters.filterPeriod = uint16(data[offset]) | uint16(data[offset+1])<<8
-	offset += 2
-
-	pool.parameters.decayPeriod = uint16(data[offset]) | uint16(data[offset+1])<<8
-	offset += 2
-
-	pool.parameters.reductionFactor = uint16(data[offset]) | uint16(data[offset+1])<<8
-	offset += 2
-
-	pool.parameters.variableFeeControl = uint32(data[offset]) | uint32(data[offset+1])<<8 | uint32(data[offset+2])<<16 | uint32(data[offset+3])<<24
-	offset += 4
-
-	pool.parameters.maxVolatilityAccumulator = uint32(data[offset]) | uint32(data[offset+1])<<8 | uint32(data[offset+2])<<16 | uint32(data[offset+3])<<24
-	offset += 4
-
-	pool.parameters.minBinId = int32(uint32(data[offset]) | uint32(data[offset+1])<<8 | uint32(data[offset+2])<<16 | uint32(data[offset+3])<<24)
-	offset += 4
-
-	pool.parameters.maxBinId = int32(uint32(data[offset]) | uint32(data[offset+1])<<8 | uint32(data[offset+2])<<16 | uint32(data[offset+3])<<24)
-	offset += 4
-
-	pool.parameters.protocolShare = uint16(data[offset]) | uint16(data[offset+1])<<8
-	offset += 2
-
-	pool.parameters.baseFeePowerFactor = data[offset]
-	offset += 1
-
-	// Skip padding
-	offset += 5
-
-	// Parse vParameters
-	pool.vParameters.volatilityAccumulator = uint32(data[offset]) | uint32(data[offset+1])<<8 | uint32(data[offset+2])<<16 | uint32(data[offset+3])<<24
-	offset += 4
-
-	pool.vParameters.volatilityReference = uint32(data[offset]) | uint32(data[offset+1])<<8 | uint32(data[offset+2])<<16 | uint32(data[offset+3])<<24
-	offset += 4
-
-	pool.vParameters.indexReference = int32(uint32(data[offset]) | uint32(data[offset+1])<<8 | uint32(data[offset+2])<<16 | uint32(data[offset+3])<<24)
-	offset += 4
-
-	// Skip padding
-	offset += 4
-
-	pool.vParameters.lastUpdateTimestamp = int64(uint64(data[offset]) | uint64(data[offset+1])<<8 | uint64(data[offset+2])<<16 | uint64(data[offset+3])<<24 | uint64(data[offset+4])<<32 | uint64(data[offset+5])<<40 | uint64(data[offset+6])<<48 | uint64(data[offset+7])<<56)
-	offset += 8
-
-	// Skip padding
-	offset += 8
-
-	// Parse bumpSeed and binStepSeed
-	pool.bumpSeed[0] = data[offset]
-	offset += 1
-
-	pool.binStepSeed[0] = data[offset]
-	pool.binStepSeed[1] = data[offset+1]
-	offset += 2
-
-	// Parse pairType and activeId
-	pool.pairType = data[offset]
-	offset += 1
-
-	pool.activeId = int32(uint32(data[offset]) | uint32(data[offset+1])<<8 | uint32(data[offset+2])<<16 | uint32(data[offset+3])<<24)
-	offset += 4
-
-	// Parse binStep and status
-	pool.binStep = uint16(data[offset]) | uint16(data[offset+1])<<8
-	offset += 2
-
-	pool.status = data[offset]
-	offset += 1
-
-	// Parse requireBaseFactorSeed and baseFactorSeed
-	pool.requireBaseFactorSeed = data[offset]
-	offset += 1
-
-	pool.baseFactorSeed[0] = data[offset]
-	pool.baseFactorSeed[1] = data[offset+1]
-	offset += 2
-
-	// Parse activationType and creatorPoolOnOffControl
-	pool.activationType = data[offset]
-	offset += 1
-
-	pool.creatorPoolOnOffControl = data[offset]
-	offset += 1
-
-	// Parse TokenXMint
-	copy(pool.TokenXMint[:], data[offset:offset+32])
-	offset += 32
-
-	// Parse TokenYMint
-	copy(pool.TokenYMint[:], data[offset:offset+32])
-	offset += 32
-
-	// Parse reserveX
-	copy(pool.reserveX[:], data[offset:offset+32])
-	offset += 32
-
-	// Parse reserveY
-	copy(pool.reserveY[:], data[offset:offset+32])
-	offset += 32
-
-	// Parse protocolFee
-	pool.protocolFee.amountX = uint64(data[offset]) | uint64(data[offset+1])<<8 | uint64(data[offset+2])<<16 | uint64(data[offset+3])<<24 | uint64(data[offset+4])<<32 | uint64(data[offset+5])<<40 | uint64(data[offset+6])<<48 | uint64(data[offset+7])<<56
-	offset += 8
-
-	pool.protocolFee.amountY = uint64(data[offset]) | uint64(data[offset+1])<<8 | uint64(data[offset+2])<<16 | uint64(data[offset+3])<<24 | uint64(data[offset+4])<<32 | uint64(data[offset+5])<<40 | uint64(data[offset+6])<<48 | uint64(data[offset+7])<<56
-	offset += 8
-
-	// Skip padding1
-	offset += 32
-
-	// Parse rewardInfos
-	for i := 0; i < 2; i++ {
-		copy(pool.rewardInfos[i].mint[:], data[offset:offset+32])
-		offset += 32
-
-		copy(pool.rewardInfos[i].vault[:], data[offset:offset+32])
-		offset += 32
-
-		copy(pool.rewardInfos[i].funder[:], data[offset:offset+32])
-		offset += 32
-
-		pool.rewardInfos[i].rewardDuration = int64(uint64(data[offset]) | uint64(data[offset+1])<<8 | uint64(data[offset+2])<<16 | uint64(data[offset+3])<<24 | uint64(data[offset+4])<<32 | uint64(data[offset+5])<<40 | uint64(data[offset+6])<<48 | uint64(data[offset+7])<<56)
-		offset += 8
-
-		pool.rewardInfos[i].rewardDurationEnd = int64(uint64(data[offset]) | uint64(data[offset+1])<<8 | uint64(data[offset+2])<<16 | uint64(data[offset+3])<<24 | uint64(data[offset+4])<<32 | uint64(data[offset+5])<<40 | uint64(data[offset+6])<<48 | uint64(data[offset+7])<<56)
-		offset += 8
-
-		pool.rewardInfos[i].rewardRate = int64(uint64(data[offset]) | uint64(data[offset+1])<<8 | uint64(data[offset+2])<<16 | uint64(data[offset+3])<<24 | uint64(data[offset+4])<<32 | uint64(data[offset+5])<<40 | uint64(data[offset+6])<<48 | uint64(data[offset+7])<<56)
-		offset += 8
-
-		pool.rewardInfos[i].lastUpdateTime = int64(uint64(data[offset]) | uint64(data[offset+1])<<8 | uint64(data[offset+2])<<16 | uint64(data[offset+3])<<24 | uint64(data[offset+4])<<32 | uint64(data[offset+5])<<40 | uint64(data[offset+6])<<48 | uint64(data[offset+7])<<56)
-		offset += 8
-
-		pool.rewardInfos[i].cumulativeSecondsWithEmptyLiquidityReward = int64(uint64(data[offset]) | uint64(data[offset+1])<<8 | uint64(data[offset+2])<<16 | uint64(data[offset+3])<<24 | uint64(data[offset+4])<<32 | uint64(data[offset+5])<<40 | uint64(data[offset+6])<<48 | uint64(data[offset+7])<<56)
-		offset += 8
+func (pool *MeteoraDlmmPool) now() time.Time {
+	if pool.TimeSource == nil {
+		return time.Now()
 	}
+	return pool.TimeSource.Now()
+}
 
-	// Adjust offset to match the correct oracle position
-	offset = 552
+// Span returns the account size, 904 bytes including the discriminator
+func (pool *MeteoraDlmmPool) Span() uint64 {
+	return lbPairLayout.Size()
+}
 
-	// Parse oracle
-	copy(pool.oracle[:], data[offset:offset+32])
-	offset += 32
+// Offset returns the offset of an LbPair field, or 0 for unknown fields
+func (pool *MeteoraDlmmPool) Offset(field string) uint64 {
+	offset, _ := lbPairLayout.Offset(field)
+	return offset
+}
 
-	// Parse binArrayBitmap
-	for i := 0; i < 16; i++ {
-		pool.binArrayBitmap[i] = uint64(data[offset]) | uint64(data[offset+1])<<8 | uint64(data[offset+2])<<16 | uint64(data[offset+3])<<24 | uint64(data[offset+4])<<32 | uint64(data[offset+5])<<40 | uint64(data[offset+6])<<48 | uint64(data[offset+7])<<56
-		offset += 8
+// Decode parses a pool account through LbPair
+func (pool *MeteoraDlmmPool) Decode(data []byte) error {
+	var pair LbPair
+	if err := lbPairLayout.Decode(data, &pair); err != nil {
+		return err
 	}
-
-	// Parse lastUpdatedAt
-	pool.lastUpdatedAt = int64(uint64(data[offset]) | uint64(data[offset+1])<<8 | uint64(data[offset+2])<<16 | uint64(data[offset+3])<<24 | uint64(data[offset+4])<<32 | uint64(data[offset+5])<<40 | uint64(data[offset+6])<<48 | uint64(data[offset+7])<<56)
-	offset += 8
-
-	// Skip padding2
-	offset += 32
-
-	// Parse preActivationSwapAddress
-	copy(pool.preActivationSwapAddress[:], data[offset:offset+32])
-	offset += 32
-
-	// Parse baseKey
-	copy(pool.baseKey[:], data[offset:offset+32])
-	offset += 32
-
-	// Parse activationPoint
-	pool.activationPoint = uint64(data[offset]) | uint64(data[offset+1])<<8 | uint64(data[offset+2])<<16 | uint64(data[offset+3])<<24 | uint64(data[offset+4])<<32 | uint64(data[offset+5])<<40 | uint64(data[offset+6])<<48 | uint64(data[offset+7])<<56
-	offset += 8
-
-	// Parse preActivationDuration
-	pool.preActivationDuration = uint64(data[offset]) | uint64(data[offset+1])<<8 | uint64(data[offset+2])<<16 | uint64(data[offset+3])<<24 | uint64(data[offset+4])<<32 | uint64(data[offset+5])<<40 | uint64(data[offset+6])<<48 | uint64(data[offset+7])<<56
-	offset += 8
-
-	// Skip padding3
-	offset += 8
-
-	// Parse padding4
-	pool.padding4 = uint64(data[offset]) | uint64(data[offset+1])<<8 | uint64(data[offset+2])<<16 | uint64(data[offset+3])<<24 | uint64(data[offset+4])<<32 | uint64(data[offset+5])<<40 | uint64(data[offset+6])<<48 | uint64(data[offset+7])<<56
-	offset += 8
-
-	// Parse creator
-	copy(pool.creator[:], data[offset:offset+32])
-	offset += 32
-
-	// Parse tokenMintXProgramFlag and tokenMintYProgramFlag
-	pool.tokenMintXProgramFlag = data[offset]
-	offset += 1
-
-	pool.tokenMintYProgramFlag = data[offset]
-	offset += 1
-
-	// Parse reserved
-	copy(pool.reserved[:], data[offset:offset+22])
-	offset += 22
-
-	// Skip final padding
-	offset += 16
-
+	pool.parameters = pair.Parameters
+	pool.vParameters = pair.VParameters
+	pool.bumpSeed = pair.BumpSeed
+	pool.binStepSeed = pair.BinStepSeed
+	pool.pairType = pair.PairType
+	pool.activeId = pair.ActiveId
+	pool.binStep = pair.BinStep
+	pool.status = pair.Status
+	pool.requireBaseFactorSeed = pair.RequireBaseFactorSeed
+	pool.baseFactorSeed = pair.BaseFactorSeed
+	pool.activationType = pair.ActivationType
+	pool.creatorPoolOnOffControl = pair.CreatorPoolOnOffControl
+	pool.TokenXMint = pair.TokenXMint
+	pool.TokenYMint = pair.TokenYMint
+	pool.reserveX = pair.ReserveX
+	pool.reserveY = pair.ReserveY
+	pool.protocolFee = pair.ProtocolFee
+	pool.rewardInfos = pair.RewardInfos
+	pool.oracle = pair.Oracle
+	pool.binArrayBitmap = pair.BinArrayBitmap
+	pool.lastUpdatedAt = pair.LastUpdatedAt
+	pool.preActivationSwapAddress = pair.PreActivationSwapAddress
+	pool.baseKey = pair.BaseKey
+	pool.activationPoint = pair.ActivationPoint
+	pool.preActivationDuration = pair.PreActivationDuration
+	pool.creator = pair.Creator
+	pool.tokenMintXProgramFlag = pair.TokenMintXProgramFlag
+	pool.tokenMintYProgramFlag = pair.TokenMintYProgramFlag
 	return nil
 }
 
@@ -390,45 +216,18 @@ func (pool *MeteoraDlmmPool) UpdateClock(ctx context.Context, client *sol.Client
 	return nil
 }
 
-// GetBinArrayForSwap retrieves bin arrays needed for swap operations
+// GetBinArrayForSwap reads the bin arrays with liquidity nearest the active bin in both
+// directions, and the bitmap extension when they lie beyond the pair's bitmap. Quotes read
+// further arrays as swaps reach them.
 func (pool *MeteoraDlmmPool) GetBinArrayForSwap(ctx context.Context, client *sol.Client) error {
-	if pool.BinArrays == nil {
-		pool.BinArrays = make(map[string]BinArray) // Initialize bin array map
-	}
-
-	// Get active bin array public keys for both positive and negative orders
-	var activeBinArrayPubkeys []solana.PublicKey
-
-	positiveOrderActiveBinArrayPubkeys, err := pool.GetBinArrayPubkeysForSwap(true, 4)
-	if err != nil {
-		return fmt.Errorf("failed to get positive order bin array pubkeys: %w", err)
-	}
-	activeBinArrayPubkeys = append(activeBinArrayPubkeys, positiveOrderActiveBinArrayPubkeys...)
-
-	negativeOrderActiveBinArrayPubkeys, err := pool.GetBinArrayPubkeysForSwap(false, 4)
-	if err != nil {
-		return fmt.Errorf("failed to get negative order bin array pubkeys: %w", err)
-	}
-	activeBinArrayPubkeys = append(activeBinArrayPubkeys, negativeOrderActiveBinArrayPubkeys...)
-
-	// Fetch all bin array accounts in batch
-	results, err := client.RpcClient.GetMultipleAccounts(ctx, activeBinArrayPubkeys...)
-	if err != nil {
-		return fmt.Errorf("batch request failed: %w", err)
-	}
-
-	// Parse and store bin arrays
-	for i, result := range results.Value {
-		if result == nil {
-			// Skip nil results (account doesn't exist)
-			continue
-		}
-		accountKey := activeBinArrayPubkeys[i].String()
-		binArray, err := ParseBinArray(result.Data.GetBinary())
+	start := int32(BinIDToBinArrayIndex(pool.activeId))
+	var indexes []int32
+	for _, swapForY := range []bool{true, false} {
+		directionIndexes, err := pool.binArrayIndexesForSwap(ctx, client.RpcClient, swapForY, start, binArrayFetchCount)
 		if err != nil {
-			return fmt.Errorf("failed to parse bin array for account %s: %w", accountKey, err)
+			return fmt.Errorf("failed to find bin arrays for swap: %w", err)
 		}
-		pool.BinArrays[accountKey] = binArray
+		indexes = append(indexes, directionIndexes...)
 	}
-	return nil
+	return pool.fetchBinArrays(ctx, client.RpcClient, indexes)
 }
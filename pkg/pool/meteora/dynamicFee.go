@@ -0,0 +1,83 @@
+package meteora
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// feeRatePerBps converts FeePrecision fee rates to basis points
+const feeRatePerBps = FeePrecision / 10_000
+
+// QuoteAccounts returns the accounts Quote reads the pair's volatility state and the chain time
+// from
+func (pool *MeteoraDlmmPool) QuoteAccounts(inputMint string) []solana.PublicKey {
+	return []solana.PublicKey{pool.PoolId, solana.SysVarClockPubkey}
+}
+
+// refreshFeeState reads the pair's volatility state, which every swap updates, and the chain
+// time it decays against, so the variable fee matches the one the next swap pays. Without the
+// clock sysvar the wall clock stands in for chain time.
+func (pool *MeteoraDlmmPool) refreshFeeState(ctx context.Context, solClient pkg.RPC) error {
+	accounts := pool.QuoteAccounts("")
+	results, err := solClient.GetMultipleAccountsWithOpts(ctx, accounts, sol.MultipleAccountsOpts(ctx, rpc.CommitmentProcessed))
+	if err != nil {
+		return fmt.Errorf("failed to get pair %s: %w", pool.PoolId, err)
+	}
+	if len(results.Value) != len(accounts) || results.Value[0] == nil {
+		return fmt.Errorf("pair %s not found", pool.PoolId)
+	}
+	var pair LbPair
+	if err := lbPairLayout.Decode(results.Value[0].Data.GetBinary(), &pair); err != nil {
+		return fmt.Errorf("failed to decode pair %s: %w", pool.PoolId, err)
+	}
+	pool.vParameters = pair.VParameters
+	if results.Value[1] != nil {
+		clock, err := sol.ParseClock(results.Value[1].Data.GetBinary())
+		if err != nil {
+			return err
+		}
+		pool.Clock = *clock
+	}
+	return nil
+}
+
+// currentTimestamp returns the chain time volatility decays against: the clock last read, or
+// the wall clock before one is
+func (pool *MeteoraDlmmPool) currentTimestamp() int64 {
+	if pool.Clock.UnixTimestamp > 0 {
+		return int64(pool.Clock.UnixTimestamp)
+	}
+	return pool.now().Unix()
+}
+
+// FeeRate returns the fee rate, in FeePrecision units, a swap entering the active bin now pays:
+// the base fee plus the variable fee of the volatility decayed since the last swap. Swaps
+// crossing bins pay more in each further bin.
+func (pool *MeteoraDlmmPool) FeeRate() (*big.Int, error) {
+	vParameters := pool.vParameters
+	defer func() { pool.vParameters = vParameters }()
+	pool.UpdateReferences()
+	if err := pool.UpdateVolatilityAccumulator(); err != nil {
+		return nil, err
+	}
+	return pool.GetTotalFee()
+}
+
+// FeeRateBps returns FeeRate in basis points, rounded up, as of the pair state last read
+func (pool *MeteoraDlmmPool) FeeRateBps() uint64 {
+	rate, err := pool.FeeRate()
+	if err != nil {
+		rate, _ = pool.GetBaseFee()
+	}
+	bps, rem := new(big.Int).QuoRem(rate, big.NewInt(feeRatePerBps), new(big.Int))
+	if rem.Sign() > 0 {
+		bps.Add(bps, big.NewInt(1))
+	}
+	return bps.Uint64()
+}
@@ -1,8 +1,8 @@
 package meteora
 
 import (
+	"encoding/binary"
 	"fmt"
-	"math/big"
 )
 
 // BinArrayBitmapExtension represents an extension of the bin array bitmap
@@ -19,7 +19,8 @@ func (extension *BinArrayBitmapExtension) BitmapRange() (int32, int32) {
 }
 
 // NextBinArrayIndexWithLiquidity finds the next bin array index with liquidity
-// based on the swap direction and starting index
+// based on the swap direction and starting index. When none is found, the index returned is
+// where the search continues: the edge of the default bitmap, or past the extension's range.
 func (extension *BinArrayBitmapExtension) NextBinArrayIndexWithLiquidity(swapForY bool, startIndex int32) (int32, bool, error) {
 	minBitmapID, maxBitmapID := extension.BitmapRange()
 
@@ -41,7 +42,7 @@ func (extension *BinArrayBitmapExtension) NextBinArrayIndexWithLiquidity(swapFor
 			if value != nil {
 				return *value, true, nil
 			}
-			return 0, false, fmt.Errorf("cannot find non zero liquidity bin array id")
+			return maxBitmapID + 1, false, nil
 		}
 	} else {
 		if swapForY {
@@ -52,7 +53,7 @@ func (extension *BinArrayBitmapExtension) NextBinArrayIndexWithLiquidity(swapFor
 			if value != nil {
 				return *value, true, nil
 			}
-			return 0, false, fmt.Errorf("cannot find non zero liquidity bin array id")
+			return minBitmapID - 1, false, nil
 		} else {
 			value, err := extension.IterBitmap(startIndex, -BinArrayBitmapSize-1)
 			if err != nil {
@@ -66,150 +67,25 @@ func (extension *BinArrayBitmapExtension) NextBinArrayIndexWithLiquidity(swapFor
 	}
 }
 
-// IterBitmap iterates through the bitmap from startIndex to endIndex
-// and returns the first bin array index with liquidity
+// IterBitmap iterates through the bitmap from startIndex to endIndex, both inclusive and in
+// either direction, and returns the first bin array index with liquidity
 func (extension *BinArrayBitmapExtension) IterBitmap(startIndex, endIndex int32) (*int32, error) {
-	// If start index equals end index, check that specific bit
-	if startIndex == endIndex {
-		hasBit, err := extension.Bit(startIndex)
+	step := int32(1)
+	if endIndex < startIndex {
+		step = -1
+	}
+	for index := startIndex; ; index += step {
+		hasBit, err := extension.Bit(index)
 		if err != nil {
 			return nil, err
 		}
 		if hasBit {
-			return &startIndex, nil
+			return &index, nil
 		}
-		return nil, nil
-	}
-
-	offset, err := GetBitmapOffset(startIndex)
-	if err != nil {
-		return nil, err
-	}
-
-	binArrayOffset, err := BinArrayOffsetInBitmap(startIndex)
-	if err != nil {
-		return nil, err
-	}
-
-	if startIndex < 0 {
-		// Handle negative range
-		if startIndex < endIndex {
-			// Forward iteration
-			for i := offset; i >= 0; i-- {
-				// Convert [8]uint64 to big.Int
-				binArrayBitmap := ArrayToBigInt(extension.NegativeBinArrayBitmap[i])
-
-				if i == offset {
-					// Left shift operation
-					shift := big.NewInt(int64(BinArrayBitmapSize - binArrayOffset - 1))
-					binArrayBitmap.Lsh(binArrayBitmap, uint(shift.Int64()))
-
-					if binArrayBitmap.Sign() == 0 {
-						continue
-					}
-
-					// Calculate leading zeros count
-					leadingZeros := CountLeadingZeros(binArrayBitmap)
-					binArrayOffsetInBitmap := binArrayOffset - leadingZeros
-
-					return ToBinArrayIndex(i, binArrayOffsetInBitmap, false)
-				}
-
-				if binArrayBitmap.Sign() == 0 {
-					continue
-				}
-
-				leadingZeros := CountLeadingZeros(binArrayBitmap)
-				binArrayOffsetInBitmap := BinArrayBitmapSize - leadingZeros - 1
-
-				return ToBinArrayIndex(i, binArrayOffsetInBitmap, false)
-			}
-		} else {
-			// Backward iteration
-			for i := offset; i < ExtensionBinArrayBitmapSize; i++ {
-				binArrayBitmap := ArrayToBigInt(extension.NegativeBinArrayBitmap[i])
-
-				if i == offset {
-					// Right shift operation
-					binArrayBitmap.Rsh(binArrayBitmap, uint(binArrayOffset))
-
-					if binArrayBitmap.Sign() == 0 {
-						continue
-					}
-
-					trailingZeros := CountTrailingZeros(binArrayBitmap)
-					binArrayOffsetInBitmap := binArrayOffset + trailingZeros
-
-					return ToBinArrayIndex(i, binArrayOffsetInBitmap, false)
-				}
-
-				if binArrayBitmap.Sign() == 0 {
-					continue
-				}
-
-				binArrayOffsetInBitmap := CountTrailingZeros(binArrayBitmap)
-				return ToBinArrayIndex(i, binArrayOffsetInBitmap, false)
-			}
-		}
-	} else {
-		// Handle positive range
-		if startIndex < endIndex {
-			// Forward iteration
-			for i := offset; i < ExtensionBinArrayBitmapSize; i++ {
-				binArrayBitmap := ArrayToBigInt(extension.PositiveBinArrayBitmap[i])
-
-				if i == offset {
-					binArrayBitmap.Rsh(binArrayBitmap, uint(binArrayOffset))
-
-					if binArrayBitmap.Sign() == 0 {
-						continue
-					}
-
-					trailingZeros := CountTrailingZeros(binArrayBitmap)
-					binArrayOffsetInBitmap := binArrayOffset + trailingZeros
-
-					return ToBinArrayIndex(i, binArrayOffsetInBitmap, true)
-				}
-
-				if binArrayBitmap.Sign() == 0 {
-					continue
-				}
-
-				binArrayOffsetInBitmap := CountTrailingZeros(binArrayBitmap)
-				return ToBinArrayIndex(i, binArrayOffsetInBitmap, true)
-			}
-		} else {
-			// Backward iteration
-			for i := offset; i >= 0; i-- {
-				binArrayBitmap := ArrayToBigInt(extension.PositiveBinArrayBitmap[i])
-
-				if i == offset {
-					shift := big.NewInt(int64(BinArrayBitmapSize - binArrayOffset - 1))
-					binArrayBitmap.Lsh(binArrayBitmap, uint(shift.Int64()))
-
-					if binArrayBitmap.Sign() == 0 {
-						continue
-					}
-
-					leadingZeros := CountLeadingZeros(binArrayBitmap)
-					binArrayOffsetInBitmap := binArrayOffset - leadingZeros
-
-					return ToBinArrayIndex(i, binArrayOffsetInBitmap, true)
-				}
-
-				if binArrayBitmap.Sign() == 0 {
-					continue
-				}
-
-				leadingZeros := CountLeadingZeros(binArrayBitmap)
-				binArrayOffsetInBitmap := BinArrayBitmapSize - leadingZeros - 1
-
-				return ToBinArrayIndex(i, binArrayOffsetInBitmap, true)
-			}
+		if index == endIndex {
+			return nil, nil
 		}
 	}
-
-	return nil, nil
 }
 
 // Bit checks if a specific bit is set in the bitmap at the given bin array index
@@ -226,11 +102,8 @@ func (extension *BinArrayBitmapExtension) Bit(binArrayIndex int32) (bool, error)
 		return false, err
 	}
 
-	// Convert [8]uint64 to big.Int
-	bigInt := ArrayToBigInt(bitmap)
-
-	// Check the bit value at the specified position
-	return bigInt.Bit(binArrayOffset) == 1, nil
+	// The bitmap is a little-endian U512: limb 0 holds the lowest bits
+	return bitmap[binArrayOffset/64]>>(binArrayOffset%64)&1 == 1, nil
 }
 
 // GetBitmap retrieves the bitmap data for a given bin array index
@@ -239,9 +112,41 @@ func (extension *BinArrayBitmapExtension) GetBitmap(binArrayIndex int32) ([8]uin
 	if err != nil {
 		return [8]uint64{}, 0, err
 	}
-
+	if offset < 0 || offset >= ExtensionBinArrayBitmapSize {
+		return [8]uint64{}, 0, fmt.Errorf("bin array index %d outside the bitmap extension", binArrayIndex)
+	}
+	bitmaps := extension.PositiveBinArrayBitmap
 	if binArrayIndex < 0 {
-		return extension.NegativeBinArrayBitmap[offset], offset, nil
+		bitmaps = extension.NegativeBinArrayBitmap
+	}
+	if offset >= len(bitmaps) {
+		return [8]uint64{}, offset, nil
+	}
+
+	return bitmaps[offset], offset, nil
+}
+
+// bitmapExtensionSize is the account size: discriminator, pair, and the positive and negative
+// bitmaps
+const bitmapExtensionSize = 8 + 32 + 2*ExtensionBinArrayBitmapSize*64
+
+// ParseBinArrayBitmapExtension decodes a pair's bin array bitmap extension account
+func ParseBinArrayBitmapExtension(data []byte) (*BinArrayBitmapExtension, error) {
+	if len(data) < bitmapExtensionSize {
+		return nil, fmt.Errorf("bitmap extension too short: expected %d bytes, got %d", bitmapExtensionSize, len(data))
+	}
+	extension := &BinArrayBitmapExtension{
+		PositiveBinArrayBitmap: make([][8]uint64, ExtensionBinArrayBitmapSize),
+		NegativeBinArrayBitmap: make([][8]uint64, ExtensionBinArrayBitmapSize),
+	}
+	offset := 8 + 32
+	for _, bitmaps := range [][][8]uint64{extension.PositiveBinArrayBitmap, extension.NegativeBinArrayBitmap} {
+		for i := range bitmaps {
+			for j := range bitmaps[i] {
+				bitmaps[i][j] = binary.LittleEndian.Uint64(data[offset:])
+				offset += 8
+			}
+		}
 	}
-	return extension.PositiveBinArrayBitmap[offset], offset, nil
+	return extension, nil
 }
@@ -0,0 +1,90 @@
+package meteora
+
+import (
+	"github.com/gtdvccc/SolRouteTmp/pkg/layout"
+	"github.com/gagliardetto/solana-go"
+	"lukechampine.com/uint128"
+)
+
+// LbPair is the LbPair account of the DLMM program IDL, after the discriminator.
+// MeteoraDlmmPool decodes through it, and its layout gives the pool's size and field offsets.
+type LbPair struct {
+	Parameters               StaticParameters
+	VParameters              VariableParameters
+	BumpSeed                 [1]uint8
+	BinStepSeed              [2]uint8
+	PairType                 uint8
+	ActiveId                 int32
+	BinStep                  uint16
+	Status                   uint8
+	RequireBaseFactorSeed    uint8
+	BaseFactorSeed           [2]uint8
+	ActivationType           uint8
+	CreatorPoolOnOffControl  uint8
+	TokenXMint               solana.PublicKey
+	TokenYMint               solana.PublicKey
+	ReserveX                 solana.PublicKey
+	ReserveY                 solana.PublicKey
+	ProtocolFee              ProtocolFee
+	Padding1                 [32]uint8
+	RewardInfos              [2]RewardInfo
+	Oracle                   solana.PublicKey
+	BinArrayBitmap           [16]uint64
+	LastUpdatedAt            int64
+	Padding2                 [32]uint8
+	PreActivationSwapAddress solana.PublicKey
+	BaseKey                  solana.PublicKey
+	ActivationPoint          uint64
+	PreActivationDuration    uint64
+	Padding3                 [8]uint8
+	Padding4                 uint64
+	Creator                  solana.PublicKey
+	TokenMintXProgramFlag    uint8
+	TokenMintYProgramFlag    uint8
+	Reserved                 [22]uint8
+}
+
+// StaticParameters are an LbPair's fee parameters, set by its preset
+type StaticParameters struct {
+	BaseFactor               uint16
+	FilterPeriod             uint16
+	DecayPeriod              uint16
+	ReductionFactor          uint16
+	VariableFeeControl       uint32
+	MaxVolatilityAccumulator uint32
+	MinBinId                 int32
+	MaxBinId                 int32
+	ProtocolShare            uint16
+	BaseFeePowerFactor       uint8
+	Padding                  [5]uint8
+}
+
+// VariableParameters are an LbPair's volatility state, updated by swaps
+type VariableParameters struct {
+	VolatilityAccumulator uint32
+	VolatilityReference   uint32
+	IndexReference        int32
+	Padding               [4]uint8
+	LastUpdateTimestamp   int64
+	Padding1              [8]uint8
+}
+
+// ProtocolFee is the protocol's share of the fees, not yet claimed
+type ProtocolFee struct {
+	AmountX uint64
+	AmountY uint64
+}
+
+// RewardInfo is a farming reward of an LbPair
+type RewardInfo struct {
+	Mint                                      solana.PublicKey
+	Vault                                     solana.PublicKey
+	Funder                                    solana.PublicKey
+	RewardDuration                            uint64
+	RewardDurationEnd                         uint64
+	RewardRate                                uint128.Uint128
+	LastUpdateTime                            uint64
+	CumulativeSecondsWithEmptyLiquidityReward uint64
+}
+
+var lbPairLayout = layout.MustOf(LbPair{})
@@ -6,83 +6,200 @@ import (
 	"fmt"
 	"math"
 	"math/big"
-	"time"
 
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
 	cosmosmath "cosmossdk.io/math"
 	"github.com/gagliardetto/solana-go"
-	"github.com/gagliardetto/solana-go/rpc"
 	"lukechampine.com/uint128"
 )
 
 // Quote calculates the output amount for a given input amount and token
-func (pool *MeteoraDlmmPool) Quote(ctx context.Context, solClient *rpc.Client, inputMint string, inputAmount cosmosmath.Int) (cosmosmath.Int, error) {
-	pool.orgActiveId = pool.activeId
+func (pool *MeteoraDlmmPool) Quote(ctx context.Context, solClient pkg.RPC, inputMint string, inputAmount cosmosmath.Int) (cosmosmath.Int, error) {
+	amountOut, _, err := pool.QuoteWithFees(ctx, solClient, inputMint, inputAmount)
+	return amountOut, err
+}
+
+// QuoteWithFees calculates the output amount like Quote and reports how the swap fee,
+// charged in the input token, is split between LPs and the protocol. The fee includes the
+// variable fee of the pair's volatility, read fresh, as it grows with each bin crossed.
+func (pool *MeteoraDlmmPool) QuoteWithFees(ctx context.Context, solClient pkg.RPC, inputMint string, inputAmount cosmosmath.Int) (cosmosmath.Int, pkg.FeeBreakdown, error) {
+	amountOut, fees, _, err := pool.quoteExactIn(ctx, solClient, inputMint, inputAmount)
+	return amountOut, fees, err
+}
+
+// quoteExactIn simulates an exact-input swap, also returning the bin arrays it crosses in order
+func (pool *MeteoraDlmmPool) quoteExactIn(ctx context.Context, solClient pkg.RPC, inputMint string, inputAmount cosmosmath.Int) (cosmosmath.Int, pkg.FeeBreakdown, []solana.PublicKey, error) {
 	totalAmountOut := cosmosmath.ZeroInt()
+	fees := pkg.FeeBreakdown{
+		Mint:        inputMint,
+		LpFee:       cosmosmath.ZeroInt(),
+		ProtocolFee: cosmosmath.ZeroInt(),
+	}
+	if err := pool.refreshFeeState(ctx, solClient); err != nil {
+		return cosmosmath.ZeroInt(), fees, nil, err
+	}
+	// Quotes simulate the swap's bin and volatility updates without keeping them
+	pool.orgActiveId = pool.activeId
+	vParameters := pool.vParameters
+	defer func() { pool.activeId, pool.vParameters = pool.orgActiveId, vParameters }()
 
 	if err := pool.validateSwapActivation(); err != nil {
-		return cosmosmath.ZeroInt(), fmt.Errorf("swap activation validation failed: %w", err)
+		return cosmosmath.ZeroInt(), fees, nil, fmt.Errorf("swap activation validation failed: %w", err)
 	}
 	pool.UpdateReferences()
 
 	amountLeft := inputAmount
 	swapForY := inputMint == pool.TokenXMint.String()
+	var binArrays []solana.PublicKey
 
-	// Process active bin arrays
 	for amountLeft.IsPositive() {
-		// Get the current active bin array
-		activeBinArray, err := pool.getCurrentActiveBinArray(swapForY)
+		binArrayKey, activeBinArray, err := pool.enterBinArrayForSwap(ctx, solClient, swapForY)
 		if err != nil {
-			return cosmosmath.ZeroInt(), err
+			return cosmosmath.ZeroInt(), fees, nil, err
 		}
+		binArrays = append(binArrays, binArrayKey)
 
-		// Process active bins
-		for {
+		for amountLeft.IsPositive() {
 			withinRange, err := activeBinArray.IsBinIDWithinRange(pool.activeId)
 			if err != nil {
-				return cosmosmath.ZeroInt(), fmt.Errorf("failed to check bin ID range: %w", err)
+				return cosmosmath.ZeroInt(), fees, nil, fmt.Errorf("failed to check bin ID range: %w", err)
 			}
-			if !withinRange || inputAmount.IsZero() {
-				if err := pool.AdvanceActiveBin(swapForY); err != nil {
-					return cosmosmath.ZeroInt(), fmt.Errorf("failed to advance active bin: %w", err)
-				}
+			if !withinRange {
 				break
-			} else {
-				// Update volatility accumulator
-				if err := pool.UpdateVolatilityAccumulator(); err != nil {
-					return cosmosmath.ZeroInt(), fmt.Errorf("failed to update volatility accumulator: %w", err)
-				}
+			}
+
+			if err := pool.UpdateVolatilityAccumulator(); err != nil {
+				return cosmosmath.ZeroInt(), fees, nil, fmt.Errorf("failed to update volatility accumulator: %w", err)
+			}
+
+			activeBin, err := activeBinArray.GetBinMut(pool.activeId)
+			if err != nil {
+				return cosmosmath.ZeroInt(), fees, nil, fmt.Errorf("failed to get active bin: %w", err)
+			}
 
-				activeBin, err := activeBinArray.GetBinMut(pool.activeId)
+			if !activeBin.IsEmpty(!swapForY) {
+				swapResult, err := pool.Swap(activeBin, amountLeft.Uint64(), swapForY)
 				if err != nil {
-					return cosmosmath.ZeroInt(), fmt.Errorf("failed to get active bin: %w", err)
+					return cosmosmath.ZeroInt(), fees, nil, fmt.Errorf("swap failed: %w", err)
 				}
+				amountLeft = amountLeft.Sub(cosmosmath.NewIntFromUint64(swapResult.amountInWithFees))
+				totalAmountOut = totalAmountOut.Add(cosmosmath.NewIntFromUint64(swapResult.amountOut))
+				fees.ProtocolFee = fees.ProtocolFee.Add(cosmosmath.NewIntFromUint64(swapResult.protocolFee))
+				fees.LpFee = fees.LpFee.Add(cosmosmath.NewIntFromUint64(swapResult.fee - swapResult.protocolFee))
+			}
+			if amountLeft.IsPositive() {
+				if err := pool.AdvanceActiveBin(swapForY); err != nil {
+					return cosmosmath.ZeroInt(), fees, nil, fmt.Errorf("failed to advance active bin: %w", err)
+				}
+			}
+		}
+	}
+
+	return totalAmountOut, fees, binArrays, nil
+}
+
+// enterBinArrayForSwap returns the next bin array with liquidity in the swap direction, moving
+// the active bin to its edge when it lies past empty arrays
+func (pool *MeteoraDlmmPool) enterBinArrayForSwap(ctx context.Context, solClient pkg.RPC, swapForY bool) (solana.PublicKey, BinArray, error) {
+	key, binArray, err := pool.binArrayForSwap(ctx, solClient, swapForY)
+	if err != nil {
+		return solana.PublicKey{}, BinArray{}, err
+	}
+	withinRange, err := binArray.IsBinIDWithinRange(pool.activeId)
+	if err != nil {
+		return solana.PublicKey{}, BinArray{}, fmt.Errorf("failed to check bin ID range: %w", err)
+	}
+	if !withinRange {
+		lowerBinID, upperBinID, err := GetBinArrayLowerUpperBinID(int32(binArray.index))
+		if err != nil {
+			return solana.PublicKey{}, BinArray{}, fmt.Errorf("failed to get bin array bounds: %w", err)
+		}
+		if swapForY {
+			pool.activeId = upperBinID
+		} else {
+			pool.activeId = lowerBinID
+		}
+	}
+	return key, binArray, nil
+}
+
+// QuoteExactOut calculates the input amount required to receive desiredOut of outputMint
+func (pool *MeteoraDlmmPool) QuoteExactOut(ctx context.Context, solClient pkg.RPC, outputMint string, desiredOut cosmosmath.Int) (cosmosmath.Int, error) {
+	amountIn, _, err := pool.quoteExactOut(ctx, solClient, outputMint, desiredOut)
+	return amountIn, err
+}
+
+// quoteExactOut simulates an exact-output swap, also returning the bin arrays it crosses in
+// order
+func (pool *MeteoraDlmmPool) quoteExactOut(ctx context.Context, solClient pkg.RPC, outputMint string, desiredOut cosmosmath.Int) (cosmosmath.Int, []solana.PublicKey, error) {
+	if !desiredOut.IsPositive() {
+		return cosmosmath.ZeroInt(), nil, errors.New("output amount must be positive")
+	}
+	if err := pool.validateSwapActivation(); err != nil {
+		return cosmosmath.ZeroInt(), nil, fmt.Errorf("swap activation validation failed: %w", err)
+	}
+	if err := pool.refreshFeeState(ctx, solClient); err != nil {
+		return cosmosmath.ZeroInt(), nil, err
+	}
+
+	pool.orgActiveId = pool.activeId
+	vParameters := pool.vParameters
+	defer func() { pool.activeId, pool.vParameters = pool.orgActiveId, vParameters }()
+	pool.UpdateReferences()
+
+	totalAmountIn := cosmosmath.ZeroInt()
+	amountOutLeft := desiredOut
+	swapForY := outputMint == pool.TokenYMint.String()
+	var binArrays []solana.PublicKey
+
+	for amountOutLeft.IsPositive() {
+		binArrayKey, activeBinArray, err := pool.enterBinArrayForSwap(ctx, solClient, swapForY)
+		if err != nil {
+			return cosmosmath.ZeroInt(), nil, err
+		}
+		binArrays = append(binArrays, binArrayKey)
+
+		for amountOutLeft.IsPositive() {
+			withinRange, err := activeBinArray.IsBinIDWithinRange(pool.activeId)
+			if err != nil {
+				return cosmosmath.ZeroInt(), nil, fmt.Errorf("failed to check bin ID range: %w", err)
+			}
+			if !withinRange {
+				break
+			}
 
-				if !activeBin.IsEmpty(!swapForY) {
-					swapResult, err := pool.Swap(
-						activeBin,
-						amountLeft.Uint64(),
-						swapForY,
-					)
-					if err != nil {
-						return cosmosmath.ZeroInt(), fmt.Errorf("swap failed: %w", err)
-					}
-					amountLeft = amountLeft.Sub(cosmosmath.NewInt(int64(swapResult.amountInWithFees)))
-					totalAmountOut = totalAmountOut.Add(cosmosmath.NewInt(int64(swapResult.amountOut)))
+			if err := pool.UpdateVolatilityAccumulator(); err != nil {
+				return cosmosmath.ZeroInt(), nil, fmt.Errorf("failed to update volatility accumulator: %w", err)
+			}
+
+			activeBin, err := activeBinArray.GetBinMut(pool.activeId)
+			if err != nil {
+				return cosmosmath.ZeroInt(), nil, fmt.Errorf("failed to get active bin: %w", err)
+			}
+
+			if !activeBin.IsEmpty(!swapForY) {
+				swapResult, err := pool.SwapExactOut(activeBin, amountOutLeft.Uint64(), swapForY)
+				if err != nil {
+					return cosmosmath.ZeroInt(), nil, fmt.Errorf("swap failed: %w", err)
 				}
+				amountOutLeft = amountOutLeft.Sub(cosmosmath.NewIntFromUint64(swapResult.amountOut))
+				totalAmountIn = totalAmountIn.Add(cosmosmath.NewIntFromUint64(swapResult.amountInWithFees))
+			}
+			if amountOutLeft.IsPositive() {
 				if err := pool.AdvanceActiveBin(swapForY); err != nil {
-					return cosmosmath.ZeroInt(), fmt.Errorf("failed to advance active bin: %w", err)
+					return cosmosmath.ZeroInt(), nil, fmt.Errorf("failed to advance active bin: %w", err)
 				}
 			}
 		}
 	}
 
-	pool.activeId = pool.orgActiveId
-	return totalAmountOut, nil
+	return totalAmountIn, binArrays, nil
 }
 
 // validateSwapActivation checks if the swap is allowed based on pair status and activation conditions
 func (pool *MeteoraDlmmPool) validateSwapActivation() error {
-	currentTimestamp := uint64(time.Now().Unix())
+	currentTimestamp := uint64(pool.now().Unix())
 	currentSlot := uint64(pool.Clock.Slot)
 
 	// Check pair status
@@ -109,20 +226,21 @@ func (pool *MeteoraDlmmPool) validateSwapActivation() error {
 	return nil
 }
 
-// UpdateReferences updates the volatility reference parameters based on elapsed time
+// UpdateReferences updates the volatility reference parameters based on the time elapsed since
+// the pair's last swap
 func (pool *MeteoraDlmmPool) UpdateReferences() {
-	elapsed := int64(pool.Clock.UnixTimestamp) - pool.vParameters.lastUpdateTimestamp
-	if elapsed >= int64(pool.parameters.filterPeriod) {
-		pool.vParameters.indexReference = pool.activeId
-		if elapsed < int64(pool.parameters.decayPeriod) {
+	elapsed := pool.currentTimestamp() - pool.vParameters.LastUpdateTimestamp
+	if elapsed >= int64(pool.parameters.FilterPeriod) {
+		pool.vParameters.IndexReference = pool.activeId
+		if elapsed < int64(pool.parameters.DecayPeriod) {
 			// Note: JS SDK and Rust SDK have different implementations
 			// JS uses multiplication, Rust uses subtraction
-			volatilityAccumulator := pool.vParameters.volatilityAccumulator * uint32(pool.parameters.reductionFactor)
+			volatilityAccumulator := pool.vParameters.VolatilityAccumulator * uint32(pool.parameters.ReductionFactor)
 			volatilityReference := volatilityAccumulator / BasisPointMax
 
-			pool.vParameters.volatilityReference = volatilityReference
+			pool.vParameters.VolatilityReference = volatilityReference
 		} else {
-			pool.vParameters.volatilityReference = 0
+			pool.vParameters.VolatilityReference = 0
 		}
 	}
 }
@@ -221,6 +339,64 @@ func (pool *MeteoraDlmmPool) Swap(bin *Bin, amountIn uint64, swapForY bool) (*Sw
 	}, nil
 }
 
+// SwapExactOut performs an exact-output swap on a specific bin, taking at most amountOut from it
+func (pool *MeteoraDlmmPool) SwapExactOut(bin *Bin, amountOut uint64, swapForY bool) (*SwapResult, error) {
+	price, err := bin.GetOrStoreBinPrice(pool.activeId, pool.binStep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bin price: %w", err)
+	}
+
+	maxAmountOut := bin.GetMaxAmountOut(swapForY)
+
+	var (
+		amountIn         *big.Int
+		isExactOutAmount bool
+	)
+	if amountOut >= maxAmountOut {
+		// Drain the bin
+		amountOut = maxAmountOut
+		amountIn, err = bin.GetMaxAmountIn(price, swapForY)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get max amount in: %w", err)
+		}
+	} else {
+		amountIn, err = bin.GetAmountIn(amountOut, price, swapForY)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get amount in: %w", err)
+		}
+		isExactOutAmount = true
+	}
+	if !amountIn.IsUint64() {
+		return nil, fmt.Errorf("amount in exceeds uint64 range")
+	}
+
+	// Fees are charged on top of the amount that goes into the bin
+	fee, err := pool.ComputeFee(amountIn.Uint64())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute fee: %w", err)
+	}
+	protocolFee, err := pool.ComputeProtocolFee(fee)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute protocol fee: %w", err)
+	}
+
+	if swapForY {
+		bin.amountX += amountIn.Uint64()
+		bin.amountY -= amountOut
+	} else {
+		bin.amountY += amountIn.Uint64()
+		bin.amountX -= amountOut
+	}
+
+	return &SwapResult{
+		amountInWithFees: amountIn.Uint64() + fee,
+		amountOut:        amountOut,
+		fee:              fee,
+		protocolFee:      protocolFee,
+		isExactOutAmount: isExactOutAmount,
+	}, nil
+}
+
 // NextBinArrayIndexWithLiquidityInternal finds the next bin array index with liquidity using internal bitmap
 func (pool *MeteoraDlmmPool) NextBinArrayIndexWithLiquidityInternal(swapForY bool, startArrayIndex int32) (int32, bool, error) {
 	// Convert binArrayBitmap to big integer type (using math/big package)
@@ -253,7 +429,7 @@ func (pool *MeteoraDlmmPool) NextBinArrayIndexWithLiquidityInternal(swapForY boo
 // UpdateVolatilityAccumulator updates the volatility accumulator based on index changes
 func (pool *MeteoraDlmmPool) UpdateVolatilityAccumulator() error {
 	// Calculate delta_id (absolute difference of indices)
-	deltaID := int64(pool.vParameters.indexReference) - int64(pool.activeId)
+	deltaID := int64(pool.vParameters.IndexReference) - int64(pool.activeId)
 
 	// Take absolute value
 	if deltaID < 0 {
@@ -264,16 +440,16 @@ func (pool *MeteoraDlmmPool) UpdateVolatilityAccumulator() error {
 	deltaIdWithBasisPoint := deltaID * int64(BasisPointMax)
 
 	// Calculate volatility_accumulator
-	volatilityAccumulator := uint64(pool.vParameters.volatilityReference) + uint64(deltaIdWithBasisPoint)
+	volatilityAccumulator := uint64(pool.vParameters.VolatilityReference) + uint64(deltaIdWithBasisPoint)
 
 	// Take the smaller value
 	minValue := uint64(math.Min(
 		float64(volatilityAccumulator),
-		float64(pool.parameters.maxVolatilityAccumulator),
+		float64(pool.parameters.MaxVolatilityAccumulator),
 	))
 
 	// Update accumulator value
-	pool.vParameters.volatilityAccumulator = uint32(minValue)
+	pool.vParameters.VolatilityAccumulator = uint32(minValue)
 
 	return nil
 }
@@ -284,7 +460,7 @@ func (pool *MeteoraDlmmPool) ComputeProtocolFee(feeAmount uint64) (uint64, error
 	feeAmountBig := uint128.From64(feeAmount)
 
 	// Convert protocol_share to uint128
-	protocolShare := uint128.From64(uint64(pool.parameters.protocolShare))
+	protocolShare := uint128.From64(uint64(pool.parameters.ProtocolShare))
 
 	// Calculate feeAmount * protocol_share
 	protocolFee := feeAmountBig.Mul(protocolShare)
@@ -356,7 +532,7 @@ func (pool *MeteoraDlmmPool) GetTotalFee() (*big.Int, error) {
 // GetBaseFee calculates the base fee based on pool parameters
 func (pool *MeteoraDlmmPool) GetBaseFee() (*big.Int, error) {
 	// Create big.Int for calculation
-	result := new(big.Int).SetUint64(uint64(pool.parameters.baseFactor))
+	result := new(big.Int).SetUint64(uint64(pool.parameters.BaseFactor))
 
 	// Multiply by bin_step
 	result.Mul(result, new(big.Int).SetUint64(uint64(pool.binStep)))
@@ -367,7 +543,7 @@ func (pool *MeteoraDlmmPool) GetBaseFee() (*big.Int, error) {
 	// Calculate 10^base_fee_power_factor
 	powerOf10 := new(big.Int).Exp(
 		big.NewInt(10),
-		new(big.Int).SetUint64(uint64(pool.parameters.baseFeePowerFactor)),
+		new(big.Int).SetUint64(uint64(pool.parameters.BaseFeePowerFactor)),
 		nil,
 	)
 
@@ -383,20 +559,20 @@ func (pool *MeteoraDlmmPool) GetBaseFee() (*big.Int, error) {
 
 // GetVariableFee gets the variable fee based on current volatility accumulator
 func (pool *MeteoraDlmmPool) GetVariableFee() (*big.Int, error) {
-	return pool.ComputeVariableFee(pool.vParameters.volatilityAccumulator)
+	return pool.ComputeVariableFee(pool.vParameters.VolatilityAccumulator)
 }
 
 // ComputeVariableFee calculates the variable fee based on volatility accumulator
 func (pool *MeteoraDlmmPool) ComputeVariableFee(volatilityAccumulator uint32) (*big.Int, error) {
 	// If variable fee control is 0, return 0 directly
-	if pool.parameters.variableFeeControl == 0 {
+	if pool.parameters.VariableFeeControl == 0 {
 		return big.NewInt(0), nil
 	}
 
 	// Convert to uint128
 	volatilityAccumulatorBig := cosmosmath.NewInt(int64(volatilityAccumulator))
 	binStep := cosmosmath.NewInt(int64(pool.binStep))
-	variableFeeControl := cosmosmath.NewInt(int64(pool.parameters.variableFeeControl))
+	variableFeeControl := cosmosmath.NewInt(int64(pool.parameters.VariableFeeControl))
 
 	// Calculate (volatility_accumulator * bin_step)^2
 	squareVfaBin := volatilityAccumulatorBig.Mul(binStep)
@@ -434,7 +610,7 @@ func (pool *MeteoraDlmmPool) AdvanceActiveBin(swapForY bool) error {
 
 	// Check if new bin ID is within valid range
 	if nextActiveBinID < MinBinID || nextActiveBinID > MaxBinID {
-		return fmt.Errorf("insufficient liquidity: bin id %d out of range [%d, %d]",
+		return fmt.Errorf("%w: bin id %d out of range [%d, %d]", solerrors.ErrInsufficientLiquidity,
 			nextActiveBinID, MinBinID, MaxBinID)
 	}
 
@@ -444,100 +620,17 @@ func (pool *MeteoraDlmmPool) AdvanceActiveBin(swapForY bool) error {
 	return nil
 }
 
-// GetBinArrayPubkeysForSwap retrieves bin array public keys needed for swap operations
+// GetBinArrayPubkeysForSwap returns the addresses of up to takeCount bin arrays with liquidity
+// from the active bin, in the order a swap crosses them. Only the bitmaps already read are
+// searched.
 func (pool *MeteoraDlmmPool) GetBinArrayPubkeysForSwap(swapForY bool, takeCount uint8) ([]solana.PublicKey, error) {
-	binArrayPubkeys := make([]solana.PublicKey, 0)
-
-	startBinArrayIdx := BinIDToBinArrayIndex(pool.activeId)
-
-	var binArrayIdx []int64
-	increment := int64(1)
-	if swapForY {
-		increment = -1
+	indexes, err := pool.binArrayIndexesForSwap(context.Background(), nil, swapForY, int32(BinIDToBinArrayIndex(pool.activeId)), int(takeCount))
+	if err != nil {
+		return nil, err
 	}
-	for i := 0; i < int(takeCount); i++ {
-		if IsOverflowDefaultBinArrayBitmap(int32(startBinArrayIdx)) {
-			if pool.bitmapExtension == nil {
-				break
-			}
-			nextBinArrayIdx, hasLiquidity, err := pool.bitmapExtension.NextBinArrayIndexWithLiquidity(swapForY, int32(startBinArrayIdx))
-			if err != nil {
-				break
-			}
-			if hasLiquidity {
-				binArrayIdx = append(binArrayIdx, int64(nextBinArrayIdx))
-				pda, _ := DeriveBinArrayPDA(pool.PoolId, int64(nextBinArrayIdx))
-				binArrayPubkeys = append(binArrayPubkeys, pda)
-				startBinArrayIdx = int64(nextBinArrayIdx) + increment
-			} else {
-				// Switch to internal bitmap
-				startBinArrayIdx = int64(nextBinArrayIdx)
-			}
-		} else {
-			nextBinArrayIdx, hasLiquidity, err := pool.NextBinArrayIndexWithLiquidityInternal(swapForY, int32(startBinArrayIdx))
-			if err != nil {
-				break
-			}
-			if hasLiquidity {
-				binArrayIdx = append(binArrayIdx, int64(nextBinArrayIdx))
-				pda, _ := DeriveBinArrayPDA(pool.PoolId, int64(nextBinArrayIdx))
-				binArrayPubkeys = append(binArrayPubkeys, pda)
-				startBinArrayIdx = int64(nextBinArrayIdx) + increment
-			} else {
-				// Switch to external bitmap
-				startBinArrayIdx = int64(nextBinArrayIdx)
-			}
-		}
+	binArrayPubkeys := make([]solana.PublicKey, len(indexes))
+	for i, index := range indexes {
+		binArrayPubkeys[i], _ = DeriveBinArrayPDA(pool.PoolId, int64(index))
 	}
-
 	return binArrayPubkeys, nil
 }
-
-// getCurrentActiveBinArray retrieves the current active bin array for swap operations
-func (pool *MeteoraDlmmPool) getCurrentActiveBinArray(swapForY bool) (BinArray, error) {
-	startBinArrayIdx := BinIDToBinArrayIndex(pool.activeId)
-
-	var binArrayIdx int64
-	increment := int64(1)
-	if swapForY {
-		increment = -1
-	}
-
-	if IsOverflowDefaultBinArrayBitmap(int32(startBinArrayIdx)) {
-		if pool.bitmapExtension == nil {
-			return BinArray{}, errors.New("bitmapExtension is nil")
-		}
-		nextBinArrayIdx, hasLiquidity, err := pool.bitmapExtension.NextBinArrayIndexWithLiquidity(swapForY, int32(startBinArrayIdx))
-		if err != nil {
-			return BinArray{}, err
-		}
-		if hasLiquidity {
-			binArrayIdx = int64(nextBinArrayIdx)
-			startBinArrayIdx = int64(nextBinArrayIdx) + increment
-		} else {
-			// Switch to internal bitmap
-			startBinArrayIdx = int64(nextBinArrayIdx)
-		}
-	} else {
-		nextBinArrayIdx, hasLiquidity, err := pool.NextBinArrayIndexWithLiquidityInternal(swapForY, int32(startBinArrayIdx))
-		if err != nil {
-			return BinArray{}, err
-		}
-		if hasLiquidity {
-			binArrayIdx = int64(nextBinArrayIdx)
-			startBinArrayIdx = int64(nextBinArrayIdx) + increment
-		} else {
-			// Switch to external bitmap
-			startBinArrayIdx = int64(nextBinArrayIdx)
-		}
-	}
-
-	// Generate PDA address for bin array
-	pda, _ := DeriveBinArrayPDA(pool.PoolId, binArrayIdx)
-
-	binArray, exists := pool.BinArrays[pda.String()]
-	if !exists {
-		return BinArray{}, errors.New("active bin array not found")
-	}
-	return binArray, nil
-}
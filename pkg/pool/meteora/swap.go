@@ -6,16 +6,16 @@ import (
 	"encoding/binary"
 	"fmt"
 
+	"github.com/gtdvccc/SolRouteTmp/pkg"
 	"cosmossdk.io/math"
 	bin "github.com/gagliardetto/binary"
 	"github.com/gagliardetto/solana-go"
-	"github.com/gagliardetto/solana-go/rpc"
 )
 
 // BuildSwapInstructions creates Solana instructions for performing a swap operation
 func (pool *MeteoraDlmmPool) BuildSwapInstructions(
 	ctx context.Context,
-	solClient *rpc.Client,
+	solClient pkg.RPC,
 	user solana.PublicKey,
 	inputMint string,
 	inputAmount math.Int,
@@ -23,6 +23,69 @@ func (pool *MeteoraDlmmPool) BuildSwapInstructions(
 ) ([]solana.Instruction, error) {
 	instructions := []solana.Instruction{}
 
+	_, _, crossed, err := pool.quoteExactIn(ctx, solClient, inputMint, inputAmount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find bin arrays for swap: %w", err)
+	}
+	binArrays, err := pool.swapBinArrays(ctx, solClient, inputMint == pool.TokenXMint.String(), crossed)
+	if err != nil {
+		return nil, err
+	}
+
+	instruction := SwapInstruction{
+		AmountIn:              inputAmount.Uint64(),
+		MinAmountOut:          minOut.Uint64(),
+		AccountMetaSlice:      pool.swapAccountMetas(user, inputMint, binArrays),
+		RemainingAccountsInfo: defaultRemainingAccountsInfo(),
+	}
+	instruction.BaseVariant = bin.BaseVariant{
+		Impl: instruction,
+	}
+
+	instructions = append(instructions, &instruction)
+
+	return instructions, nil
+}
+
+// BuildSwapInstructionsExactOut creates a swap_exact_out2 instruction that receives exactly
+// amountOut of outputMint while spending at most maxIn
+func (pool *MeteoraDlmmPool) BuildSwapInstructionsExactOut(
+	ctx context.Context,
+	solClient pkg.RPC,
+	user solana.PublicKey,
+	outputMint string,
+	amountOut math.Int,
+	maxIn math.Int,
+) ([]solana.Instruction, error) {
+	inputMint := pool.TokenXMint.String()
+	if outputMint == pool.TokenXMint.String() {
+		inputMint = pool.TokenYMint.String()
+	}
+
+	_, crossed, err := pool.quoteExactOut(ctx, solClient, outputMint, amountOut)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find bin arrays for swap: %w", err)
+	}
+	binArrays, err := pool.swapBinArrays(ctx, solClient, outputMint == pool.TokenYMint.String(), crossed)
+	if err != nil {
+		return nil, err
+	}
+
+	instruction := SwapExactOutInstruction{
+		MaxInAmount:           maxIn.Uint64(),
+		OutAmount:             amountOut.Uint64(),
+		AccountMetaSlice:      pool.swapAccountMetas(user, inputMint, binArrays),
+		RemainingAccountsInfo: defaultRemainingAccountsInfo(),
+	}
+	instruction.BaseVariant = bin.BaseVariant{
+		Impl: instruction,
+	}
+	return []solana.Instruction{&instruction}, nil
+}
+
+// swapAccountMetas returns the account list shared by swap2 and swap_exact_out2, binArrays in
+// the order the swap crosses them
+func (pool *MeteoraDlmmPool) swapAccountMetas(user solana.PublicKey, inputMint string, binArrays []solana.PublicKey) solana.AccountMetaSlice {
 	var userQuoteAccount solana.PublicKey
 	var userBaseAccount solana.PublicKey
 	if inputMint == pool.TokenXMint.String() {
@@ -33,59 +96,51 @@ func (pool *MeteoraDlmmPool) BuildSwapInstructions(
 		userQuoteAccount = pool.UserBaseAccount
 	}
 
-	instruction := SwapInstruction{
-		AmountIn:         inputAmount.Uint64(),
-		MinAmountOut:     minOut.Uint64(),
-		AccountMetaSlice: make(solana.AccountMetaSlice, 16+len(pool.BinArrays)),
-		RemainingAccountsInfo: RemainingAccountsInfo{
-			Slices: []RemainingAccountsSlice{
-				{
-					AccountsType: AccountsTypeTransferHookX,
-					Length:       0, // Set as needed
-				},
-				{
-					AccountsType: AccountsTypeTransferHookY,
-					Length:       0, // Set as needed
-				},
-			},
-		},
-	}
-	instruction.BaseVariant = bin.BaseVariant{
-		Impl: instruction,
-	}
+	accounts := make(solana.AccountMetaSlice, 16+len(binArrays))
 
 	// Ensure correct Token Program address is used
-	instruction.AccountMetaSlice[0] = solana.NewAccountMeta(pool.PoolId, true, false)
+	accounts[0] = solana.NewAccountMeta(pool.PoolId, true, false)
 	if pool.bitmapExtension != nil {
-		instruction.AccountMetaSlice[1] = solana.NewAccountMeta(pool.BitmapExtensionKey, false, false)
+		accounts[1] = solana.NewAccountMeta(pool.BitmapExtensionKey, false, false)
 	} else {
-		instruction.AccountMetaSlice[1] = solana.NewAccountMeta(MeteoraProgramID, false, false)
-	}
-	instruction.AccountMetaSlice[2] = solana.NewAccountMeta(pool.reserveX, true, false)
-	instruction.AccountMetaSlice[3] = solana.NewAccountMeta(pool.reserveY, true, false)
-	instruction.AccountMetaSlice[4] = solana.NewAccountMeta(userBaseAccount, true, false)
-	instruction.AccountMetaSlice[5] = solana.NewAccountMeta(userQuoteAccount, true, false)
-	instruction.AccountMetaSlice[6] = solana.NewAccountMeta(pool.TokenXMint, false, false)
-	instruction.AccountMetaSlice[7] = solana.NewAccountMeta(pool.TokenYMint, false, false)
-	instruction.AccountMetaSlice[8] = solana.NewAccountMeta(pool.oracle, true, false)
-	instruction.AccountMetaSlice[9] = solana.NewAccountMeta(MeteoraProgramID, false, false) // Host fee account - set to null in JS SDK but not in Rust SDK
-	instruction.AccountMetaSlice[10] = solana.NewAccountMeta(user, true, true)
+		accounts[1] = solana.NewAccountMeta(MeteoraProgramID, false, false)
+	}
+	accounts[2] = solana.NewAccountMeta(pool.reserveX, true, false)
+	accounts[3] = solana.NewAccountMeta(pool.reserveY, true, false)
+	accounts[4] = solana.NewAccountMeta(userBaseAccount, true, false)
+	accounts[5] = solana.NewAccountMeta(userQuoteAccount, true, false)
+	accounts[6] = solana.NewAccountMeta(pool.TokenXMint, false, false)
+	accounts[7] = solana.NewAccountMeta(pool.TokenYMint, false, false)
+	accounts[8] = solana.NewAccountMeta(pool.oracle, true, false)
+	accounts[9] = solana.NewAccountMeta(MeteoraProgramID, false, false) // Host fee account - set to null in JS SDK but not in Rust SDK
+	accounts[10] = solana.NewAccountMeta(user, true, true)
 	tokenProgramID := solana.MustPublicKeyFromBase58("TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA")
-	instruction.AccountMetaSlice[11] = solana.NewAccountMeta(tokenProgramID, false, false)
-	instruction.AccountMetaSlice[12] = solana.NewAccountMeta(tokenProgramID, false, false)
-	instruction.AccountMetaSlice[13] = solana.NewAccountMeta(MemoProgramID, false, false)
-	instruction.AccountMetaSlice[14] = solana.NewAccountMeta(DeriveEventAuthorityPDA(), false, false)
-	instruction.AccountMetaSlice[15] = solana.NewAccountMeta(MeteoraProgramID, true, false)
-
-	index := 16
-	for binArrayKey := range pool.BinArrays {
-		instruction.AccountMetaSlice[index] = solana.NewAccountMeta(solana.MustPublicKeyFromBase58(binArrayKey), true, false)
-		index++
+	accounts[11] = solana.NewAccountMeta(tokenProgramID, false, false)
+	accounts[12] = solana.NewAccountMeta(tokenProgramID, false, false)
+	accounts[13] = solana.NewAccountMeta(MemoProgramID, false, false)
+	accounts[14] = solana.NewAccountMeta(DeriveEventAuthorityPDA(), false, false)
+	accounts[15] = solana.NewAccountMeta(MeteoraProgramID, true, false)
+
+	for i, binArrayKey := range binArrays {
+		accounts[16+i] = solana.NewAccountMeta(binArrayKey, true, false)
 	}
+	return accounts
+}
 
-	instructions = append(instructions, &instruction)
-
-	return instructions, nil
+// defaultRemainingAccountsInfo describes empty transfer hook slices for both tokens
+func defaultRemainingAccountsInfo() RemainingAccountsInfo {
+	return RemainingAccountsInfo{
+		Slices: []RemainingAccountsSlice{
+			{
+				AccountsType: AccountsTypeTransferHookX,
+				Length:       0, // Set as needed
+			},
+			{
+				AccountsType: AccountsTypeTransferHookY,
+				Length:       0, // Set as needed
+			},
+		},
+	}
 }
 
 // AccountsType represents the type of accounts in the remaining accounts slice
@@ -153,3 +208,44 @@ func (instruction *SwapInstruction) Data() ([]byte, error) {
 
 	return buffer.Bytes(), nil
 }
+
+// SwapExactOutInstruction represents a Meteora swap_exact_out2 instruction
+type SwapExactOutInstruction struct {
+	bin.BaseVariant
+	MaxInAmount             uint64                `bin:"max_in_amount"`
+	OutAmount               uint64                `bin:"out_amount"`
+	RemainingAccountsInfo   RemainingAccountsInfo `bin:"remaining_accounts_info"`
+	solana.AccountMetaSlice `bin:"-" borsh_skip:"true"`
+}
+
+// ProgramID returns the Meteora program ID
+func (instruction *SwapExactOutInstruction) ProgramID() solana.PublicKey {
+	return MeteoraProgramID
+}
+
+// Accounts returns the account metadata for the instruction
+func (instruction *SwapExactOutInstruction) Accounts() (out []*solana.AccountMeta) {
+	return instruction.Impl.(solana.AccountsGettable).GetAccounts()
+}
+
+// Data serializes the instruction data for on-chain execution
+func (instruction *SwapExactOutInstruction) Data() ([]byte, error) {
+	buffer := new(bytes.Buffer)
+	if _, err := buffer.Write(SwapExactOut2IxDiscm[:]); err != nil {
+		return nil, fmt.Errorf("failed to write discriminator: %w", err)
+	}
+
+	if err := bin.NewBorshEncoder(buffer).WriteUint64(instruction.MaxInAmount, binary.LittleEndian); err != nil {
+		return nil, fmt.Errorf("failed to encode max in amount: %w", err)
+	}
+
+	if err := bin.NewBorshEncoder(buffer).WriteUint64(instruction.OutAmount, binary.LittleEndian); err != nil {
+		return nil, fmt.Errorf("failed to encode out amount: %w", err)
+	}
+
+	if err := bin.NewBorshEncoder(buffer).Encode(instruction.RemainingAccountsInfo); err != nil {
+		return nil, fmt.Errorf("failed to encode remaining accounts info: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
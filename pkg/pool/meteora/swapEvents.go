@@ -0,0 +1,49 @@
+package meteora
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/utils"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+)
+
+// dlmmSwapEventDiscriminator identifies the Swap event the DLMM program emits for swaps
+var dlmmSwapEventDiscriminator = utils.GetDiscriminator("event", "Swap")
+
+type dlmmSwapEvent struct {
+	LbPair      solana.PublicKey
+	From        solana.PublicKey
+	StartBinID  int32
+	EndBinID    int32
+	AmountIn    uint64
+	AmountOut   uint64
+	SwapForY    bool
+	Fee         uint64
+	ProtocolFee uint64
+	FeeBps      bin.Uint128
+	HostFee     uint64
+}
+
+// DecodeDLMMSwapEvent decodes the Swap event the DLMM program emits for each swap. Its fee
+// includes the protocol's share.
+func DecodeDLMMSwapEvent(event pkg.ProgramEvent) (pkg.SwapEvent, bool, error) {
+	if !bytes.HasPrefix(event.Data, dlmmSwapEventDiscriminator) {
+		return pkg.SwapEvent{}, false, nil
+	}
+	var ev dlmmSwapEvent
+	if err := bin.NewBinDecoder(event.Data[8:]).Decode(&ev); err != nil {
+		return pkg.SwapEvent{}, false, fmt.Errorf("failed to decode DLMM swap event: %w", err)
+	}
+	return pkg.SwapEvent{
+		Protocol:   pkg.ProtocolNameMeteoraDlmm,
+		Pool:       ev.LbPair,
+		User:       ev.From,
+		ZeroForOne: ev.SwapForY,
+		AmountIn:   ev.AmountIn,
+		AmountOut:  ev.AmountOut,
+		Fee:        ev.Fee,
+	}, true, nil
+}
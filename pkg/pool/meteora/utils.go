@@ -1,10 +1,10 @@
 package meteora
 
 import (
-	"encoding/binary"
 	"fmt"
 	"math/big"
 
+	"github.com/gtdvccc/SolRouteTmp/pkg/txbuilder"
 	"github.com/gagliardetto/solana-go"
 	"lukechampine.com/uint128"
 )
@@ -90,26 +90,13 @@ func BinIDToBinArrayIndex(binID int32) int64 {
 
 // DeriveEventAuthorityPDA derives the event authority PDA
 func DeriveEventAuthorityPDA() solana.PublicKey {
-	seeds := [][]byte{[]byte("__event_authority")}
-	pda, _, _ := solana.FindProgramAddress(seeds, MeteoraProgramID)
+	pda, _ := txbuilder.EventAuthority(MeteoraProgramID)
 	return pda
 }
 
 // DeriveBinArrayPDA derives a bin array PDA for the given LB pair and bin array index
 func DeriveBinArrayPDA(lbPair solana.PublicKey, binArrayIndex int64) (solana.PublicKey, uint8) {
-	// Convert bin_array_index to little endian bytes
-	binArrayIndexBytes := make([]byte, 8)
-	binary.LittleEndian.PutUint64(binArrayIndexBytes, uint64(binArrayIndex))
-
-	// Create the seeds slice
-	seeds := [][]byte{
-		[]byte(BinArraySeed),
-		lbPair.Bytes(),
-		binArrayIndexBytes,
-	}
-
-	// Find the PDA
-	pda, bump, err := solana.FindProgramAddress(seeds, MeteoraProgramID)
+	pda, bump, err := txbuilder.DlmmBinArray(MeteoraProgramID, lbPair, binArrayIndex)
 	if err != nil {
 		return solana.PublicKey{}, 0
 	}
@@ -119,13 +106,7 @@ func DeriveBinArrayPDA(lbPair solana.PublicKey, binArrayIndex int64) (solana.Pub
 
 // DeriveBinArrayBitmapExtension derives the bin array bitmap extension PDA
 func DeriveBinArrayBitmapExtension(lbPair solana.PublicKey) (solana.PublicKey, uint8) {
-	pda, bump, err := solana.FindProgramAddress(
-		[][]byte{
-			[]byte(BinArrayBitmapSeed),
-			lbPair.Bytes(),
-		},
-		MeteoraProgramID, // Replace with actual program ID
-	)
+	pda, bump, err := txbuilder.DlmmBitmapExtension(MeteoraProgramID, lbPair)
 	if err != nil {
 		return solana.PublicKey{}, 0
 	}
@@ -207,43 +188,39 @@ const (
 	RoundingDown
 )
 
+// bigOne is added to round quotients up
+var bigOne = big.NewInt(1)
+
 // MulShr calculates (x * y) >> offset
 func MulShr(x, y *big.Int, offset uint8, rounding Rounding) (*big.Int, error) {
-	one := big.NewInt(1)
-	scale := new(big.Int).Lsh(one, uint(offset))
-	denominator := scale
-	if denominator.Cmp(big.NewInt(0)) == 0 {
-		return nil, fmt.Errorf("shift overflow")
+	prod := new(big.Int).Mul(x, y)
+	// The shifted out bits are the remainder of dividing by 1 << offset
+	inexact := prod.Sign() != 0 && prod.TrailingZeroBits() < uint(offset)
+	prod.Rsh(prod, uint(offset))
+	if rounding == RoundingUp && inexact {
+		prod.Add(prod, bigOne)
 	}
-	return MulDiv(x, y, denominator, rounding), nil
+	return prod, nil
 }
 
 // ShlDiv calculates (x << offset) / y
 func ShlDiv(x, y *big.Int, offset uint8, rounding Rounding) (*big.Int, error) {
-	one := big.NewInt(1)
-	scale := new(big.Int).Lsh(one, uint(offset))
-	if scale.Cmp(big.NewInt(0)) == 0 {
-		return nil, fmt.Errorf("shift overflow")
-	}
-	return MulDiv(x, scale, y, rounding), nil
+	return divRound(new(big.Int).Lsh(x, uint(offset)), y, rounding), nil
 }
 
 // MulDiv performs multiplication and division with rounding
 func MulDiv(x, y, denominator *big.Int, rounding Rounding) *big.Int {
-	// Convert to big.Int for calculation (equivalent to U256 in Rust)
-	xBig := x
-	yBig := y
-
-	// Calculate product
-	prod := new(big.Int).Mul(xBig, yBig)
-
-	div, mod := new(big.Int).DivMod(prod, denominator, new(big.Int))
+	return divRound(new(big.Int).Mul(x, y), denominator, rounding)
+}
 
+// divRound divides numerator by denominator in place, as U256 division does on chain
+func divRound(numerator, denominator *big.Int, rounding Rounding) *big.Int {
+	mod := new(big.Int)
+	numerator.DivMod(numerator, denominator, mod)
 	if rounding == RoundingUp && mod.Sign() != 0 {
-		return div.Add(div, big.NewInt(1))
+		numerator.Add(numerator, bigOne)
 	}
-
-	return div
+	return numerator
 }
 
 // GetPriceFromID calculates the price from active ID and bin step
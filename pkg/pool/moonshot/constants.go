@@ -0,0 +1,59 @@
+// Package moonshot implements Moonshot launchpad bonding curves, which sell a token for
+// native SOL until the curve reaches its market cap threshold and migrates
+package moonshot
+
+import (
+	"github.com/gagliardetto/solana-go"
+)
+
+var (
+	// ProgramID is the Moonshot launchpad program
+	ProgramID = solana.MustPublicKeyFromBase58("MoonCVVNZFSYkqNXP6bxHLPL6QQJiMagDL3qcqUQTrG")
+
+	// ConfigAccount holds the launchpad's fee settings
+	ConfigAccount = solana.MustPublicKeyFromBase58("36Eru7v11oU5Pfrojyn5oY3nETA1a1iqsw2WUu6afkM9")
+	// DexFeeAccount and HelioFeeAccount receive the trade fee
+	DexFeeAccount   = solana.MustPublicKeyFromBase58("3udvfL24waJcLhskRAsStNMoNUvtyXdxrWQz4hgi953N")
+	HelioFeeAccount = solana.MustPublicKeyFromBase58("5K5RtTWzzLp4P8Npi84ocf7F1vBsAu29N1irG4iiUnzt")
+
+	CurveAccountDiscriminator = [8]byte{8, 91, 83, 28, 132, 216, 248, 22}
+	BuyIxDiscm                = [8]byte{102, 6, 61, 18, 1, 218, 235, 234}
+	SellIxDiscm               = [8]byte{51, 230, 133, 164, 1, 127, 131, 173}
+)
+
+// Curve account layout
+const (
+	totalSupplyOffset        = 8
+	curveAmountOffset        = 16
+	mintOffset               = 24
+	decimalsOffset           = 56
+	collateralCurrencyOffset = 57
+	curveTypeOffset          = 58
+	curveAccountMinSize      = 82
+
+	// configFeeBpsOffset is fee_bps in the config account, after five authority keys
+	configFeeBpsOffset = 8 + 5*32
+)
+
+// Curve types
+const (
+	CurveLinearV1          uint8 = 0
+	CurveConstantProductV1 uint8 = 1
+)
+
+// CollateralSol is the only collateral currency
+const CollateralSol uint8 = 0
+
+// Constant-product curve parameters, in token base units and lamports
+const (
+	initialVirtualTokenReserves      = 1_073_000_000_000_000_000
+	initialVirtualCollateralReserves = 30_000_000_000
+)
+
+// Trade sides: which of the token and collateral amounts is fixed
+const (
+	fixedSideExactIn  uint8 = 0
+	fixedSideExactOut uint8 = 1
+)
+
+const basisPointMax = 10_000
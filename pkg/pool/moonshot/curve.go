@@ -0,0 +1,331 @@
+package moonshot
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gtdvccc/SolRouteTmp/pkg/txbuilder"
+	cosmath "cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+var _ pkg.Pool = (*CurvePool)(nil)
+
+// CurvePool is the bonding curve of one Moonshot token. It is quoted against WSOL, but trades
+// move native SOL: buys spend the user's lamports and sells pay lamports back.
+type CurvePool struct {
+	PoolId             solana.PublicKey
+	Mint               solana.PublicKey
+	TotalSupply        uint64
+	CurveAmount        uint64 // tokens still held by the curve
+	Decimals           uint8
+	CollateralCurrency uint8
+	CurveType          uint8
+
+	// Loaded by Quote
+	FeeBps       uint16
+	TokenProgram solana.PublicKey
+}
+
+// CurveAddress derives the curve account of a mint
+func CurveAddress(mint solana.PublicKey) (solana.PublicKey, error) {
+	address, err := txbuilder.MoonshotCurve(ProgramID, mint)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to derive curve account: %w", err)
+	}
+	return address, nil
+}
+
+// NewCurvePool decodes a curve account
+func NewCurvePool(id solana.PublicKey, data []byte) (*CurvePool, error) {
+	pool := &CurvePool{PoolId: id}
+	if err := pool.Decode(data); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// Decode reads a curve account
+func (pool *CurvePool) Decode(data []byte) error {
+	if len(data) < curveAccountMinSize {
+		return fmt.Errorf("curve account too short: %d bytes", len(data))
+	}
+	if [8]byte(data[:8]) != CurveAccountDiscriminator {
+		return fmt.Errorf("invalid curve account discriminator")
+	}
+	pool.TotalSupply = binary.LittleEndian.Uint64(data[totalSupplyOffset:])
+	pool.CurveAmount = binary.LittleEndian.Uint64(data[curveAmountOffset:])
+	pool.Mint = solana.PublicKeyFromBytes(data[mintOffset : mintOffset+32])
+	pool.Decimals = data[decimalsOffset]
+	pool.CollateralCurrency = data[collateralCurrencyOffset]
+	pool.CurveType = data[curveTypeOffset]
+	return nil
+}
+
+func (pool *CurvePool) ProtocolName() pkg.ProtocolName {
+	return pkg.ProtocolNameMoonshot
+}
+
+func (pool *CurvePool) ProtocolType() pkg.ProtocolType {
+	return pkg.ProtocolTypeMoonshot
+}
+
+func (pool *CurvePool) GetProgramID() solana.PublicKey {
+	return ProgramID
+}
+
+func (pool *CurvePool) GetID() string {
+	return pool.PoolId.String()
+}
+
+// FeeRateBps returns the curve's trade fee in basis points
+func (pool *CurvePool) FeeRateBps() uint64 {
+	return uint64(pool.FeeBps)
+}
+
+// GetTokens returns the token and WSOL, which stands in for the native SOL collateral
+func (pool *CurvePool) GetTokens() (string, string) {
+	return pool.Mint.String(), solana.WrappedSol.String()
+}
+
+// refresh reloads the curve, the fee from the config account and the mint's token program
+func (pool *CurvePool) refresh(ctx context.Context, solClient pkg.RPC) error {
+	accounts := []solana.PublicKey{pool.PoolId, ConfigAccount, pool.Mint}
+	results, err := solClient.GetMultipleAccountsWithOpts(ctx, accounts, sol.MultipleAccountsOpts(ctx, rpc.CommitmentProcessed))
+	if err != nil {
+		return fmt.Errorf("failed to get curve %s: %w", pool.PoolId, err)
+	}
+	if len(results.Value) != len(accounts) {
+		return fmt.Errorf("expected %d accounts, got %d", len(accounts), len(results.Value))
+	}
+	for i, account := range results.Value {
+		if account == nil {
+			return fmt.Errorf("account %s not found", accounts[i])
+		}
+	}
+	if err := pool.Decode(results.Value[0].Data.GetBinary()); err != nil {
+		return fmt.Errorf("failed to decode curve %s: %w", pool.PoolId, err)
+	}
+	config := results.Value[1].Data.GetBinary()
+	if len(config) < configFeeBpsOffset+2 {
+		return fmt.Errorf("config account too short: %d bytes", len(config))
+	}
+	pool.FeeBps = binary.LittleEndian.Uint16(config[configFeeBpsOffset:])
+	pool.TokenProgram = results.Value[2].Owner
+	return nil
+}
+
+// Quote refreshes the curve and prices a buy (WSOL in) or sell (token in)
+func (pool *CurvePool) Quote(ctx context.Context, solClient pkg.RPC, inputMint string, inputAmount cosmath.Int) (cosmath.Int, error) {
+	if err := pool.refresh(ctx, solClient); err != nil {
+		return cosmath.Int{}, err
+	}
+	return pool.QuoteOffline(inputMint, inputAmount)
+}
+
+// QuoteOffline prices the trade against the curve as last loaded. The fee is taken from the
+// SOL side: out of the input of buys and the output of sells.
+func (pool *CurvePool) QuoteOffline(inputMint string, inputAmount cosmath.Int) (cosmath.Int, error) {
+	if !inputAmount.IsPositive() {
+		return cosmath.Int{}, fmt.Errorf("input amount must be positive")
+	}
+	buy, err := pool.isBuy(inputMint, true)
+	if err != nil {
+		return cosmath.Int{}, err
+	}
+	if err := pool.checkTradable(); err != nil {
+		return cosmath.Int{}, err
+	}
+	tokenReserve, collateralReserve := pool.virtualReserves()
+	k := tokenReserve.Mul(collateralReserve)
+
+	if buy {
+		collateral := inputAmount.Sub(pool.fee(inputAmount))
+		// Tokens leave the curve as the virtual collateral grows; rounding keeps k
+		tokensOut := tokenReserve.Sub(ceilDiv(k, collateralReserve.Add(collateral)))
+		if tokensOut.GT(cosmath.NewIntFromUint64(pool.CurveAmount)) {
+			return cosmath.Int{}, fmt.Errorf("%w: curve holds %d tokens", solerrors.ErrInsufficientLiquidity, pool.CurveAmount)
+		}
+		return cosmath.MaxInt(tokensOut, cosmath.ZeroInt()), nil
+	}
+
+	collateralOut := collateralReserve.Sub(ceilDiv(k, tokenReserve.Add(inputAmount)))
+	return cosmath.MaxInt(collateralOut.Sub(pool.fee(collateralOut)), cosmath.ZeroInt()), nil
+}
+
+// QuoteExactOut refreshes the curve and returns the input needed for desiredOut
+func (pool *CurvePool) QuoteExactOut(ctx context.Context, solClient pkg.RPC, outputMint string, desiredOut cosmath.Int) (cosmath.Int, error) {
+	if err := pool.refresh(ctx, solClient); err != nil {
+		return cosmath.Int{}, err
+	}
+	return pool.QuoteExactOutOffline(outputMint, desiredOut)
+}
+
+// QuoteExactOutOffline is QuoteExactOut against the curve as last loaded
+func (pool *CurvePool) QuoteExactOutOffline(outputMint string, desiredOut cosmath.Int) (cosmath.Int, error) {
+	if !desiredOut.IsPositive() {
+		return cosmath.Int{}, fmt.Errorf("output amount must be positive")
+	}
+	buy, err := pool.isBuy(outputMint, false)
+	if err != nil {
+		return cosmath.Int{}, err
+	}
+	if err := pool.checkTradable(); err != nil {
+		return cosmath.Int{}, err
+	}
+	tokenReserve, collateralReserve := pool.virtualReserves()
+	k := tokenReserve.Mul(collateralReserve)
+
+	if buy {
+		if desiredOut.GT(cosmath.NewIntFromUint64(pool.CurveAmount)) || desiredOut.GTE(tokenReserve) {
+			return cosmath.Int{}, fmt.Errorf("%w: curve holds %d tokens", solerrors.ErrInsufficientLiquidity, pool.CurveAmount)
+		}
+		collateral := ceilDiv(k, tokenReserve.Sub(desiredOut)).Sub(collateralReserve)
+		return pool.preFee(collateral), nil
+	}
+
+	grossOut := pool.preFee(desiredOut)
+	if grossOut.GTE(collateralReserve) {
+		return cosmath.Int{}, fmt.Errorf("%w: output exceeds the curve's collateral", solerrors.ErrInsufficientLiquidity)
+	}
+	return ceilDiv(k, collateralReserve.Sub(grossOut)).Sub(tokenReserve), nil
+}
+
+// isBuy reports whether the trade buys the token; mint is the input when isInput
+func (pool *CurvePool) isBuy(mint string, isInput bool) (bool, error) {
+	switch mint {
+	case solana.WrappedSol.String():
+		return isInput, nil
+	case pool.Mint.String():
+		return !isInput, nil
+	default:
+		return false, fmt.Errorf("mint %s not in curve %s", mint, pool.PoolId)
+	}
+}
+
+func (pool *CurvePool) checkTradable() error {
+	if pool.CollateralCurrency != CollateralSol {
+		return fmt.Errorf("unsupported collateral currency %d", pool.CollateralCurrency)
+	}
+	if pool.CurveType != CurveConstantProductV1 {
+		return fmt.Errorf("unsupported curve type %d", pool.CurveType)
+	}
+	if pool.CurveAmount > pool.TotalSupply {
+		return fmt.Errorf("curve holds more than the total supply")
+	}
+	return nil
+}
+
+// virtualReserves returns the curve's virtual token and collateral reserves after the tokens
+// sold so far
+func (pool *CurvePool) virtualReserves() (cosmath.Int, cosmath.Int) {
+	sold := cosmath.NewIntFromUint64(pool.TotalSupply - pool.CurveAmount)
+	initialTokens := cosmath.NewIntFromUint64(initialVirtualTokenReserves)
+	tokenReserve := initialTokens.Sub(sold)
+	collateralReserve := initialTokens.MulRaw(initialVirtualCollateralReserves).Quo(tokenReserve)
+	return tokenReserve, collateralReserve
+}
+
+// fee is FeeBps of a collateral amount, rounded up
+func (pool *CurvePool) fee(amount cosmath.Int) cosmath.Int {
+	return ceilDiv(amount.MulRaw(int64(pool.FeeBps)), cosmath.NewInt(basisPointMax))
+}
+
+// preFee is the smallest collateral amount that is at least postFee once the fee is taken
+func (pool *CurvePool) preFee(postFee cosmath.Int) cosmath.Int {
+	amount := ceilDiv(postFee.MulRaw(basisPointMax), cosmath.NewInt(basisPointMax-int64(pool.FeeBps)))
+	for amount.Sub(pool.fee(amount)).LT(postFee) {
+		amount = amount.AddRaw(1)
+	}
+	return amount
+}
+
+func ceilDiv(numerator, denominator cosmath.Int) cosmath.Int {
+	return numerator.Add(denominator).SubRaw(1).Quo(denominator)
+}
+
+// BuildSwapInstructions buys or sells for an exact input. The output floor is passed as the
+// trade's expected amount with zero slippage. The user's token account must exist.
+func (pool *CurvePool) BuildSwapInstructions(ctx context.Context, solClient pkg.RPC, user solana.PublicKey, inputMint string, inputAmount cosmath.Int, minOut cosmath.Int) ([]solana.Instruction, error) {
+	buy, err := pool.isBuy(inputMint, true)
+	if err != nil {
+		return nil, err
+	}
+	tokenAmount, collateralAmount := inputAmount, minOut
+	if buy {
+		tokenAmount, collateralAmount = minOut, inputAmount
+	}
+	inst, err := pool.tradeInstruction(ctx, solClient, user, buy, tokenAmount, collateralAmount, fixedSideExactIn)
+	if err != nil {
+		return nil, err
+	}
+	return []solana.Instruction{inst}, nil
+}
+
+// BuildSwapInstructionsExactOut buys or sells for an exact output, spending at most maxIn
+func (pool *CurvePool) BuildSwapInstructionsExactOut(ctx context.Context, solClient pkg.RPC, user solana.PublicKey, outputMint string, amountOut cosmath.Int, maxIn cosmath.Int) ([]solana.Instruction, error) {
+	buy, err := pool.isBuy(outputMint, false)
+	if err != nil {
+		return nil, err
+	}
+	tokenAmount, collateralAmount := maxIn, amountOut
+	if buy {
+		tokenAmount, collateralAmount = amountOut, maxIn
+	}
+	inst, err := pool.tradeInstruction(ctx, solClient, user, buy, tokenAmount, collateralAmount, fixedSideExactOut)
+	if err != nil {
+		return nil, err
+	}
+	return []solana.Instruction{inst}, nil
+}
+
+// tradeInstruction encodes TradeParams: token amount, collateral amount, fixed side and a
+// zero slippage, so the unfixed amount is a hard limit
+func (pool *CurvePool) tradeInstruction(ctx context.Context, solClient pkg.RPC, user solana.PublicKey, buy bool, tokenAmount, collateralAmount cosmath.Int, fixedSide uint8) (solana.Instruction, error) {
+	if !tokenAmount.IsUint64() || !collateralAmount.IsUint64() {
+		return nil, fmt.Errorf("trade amounts must fit in u64")
+	}
+	if pool.TokenProgram.IsZero() {
+		if err := pool.refresh(ctx, solClient); err != nil {
+			return nil, err
+		}
+	}
+	userToken, err := txbuilder.AssociatedTokenAddress(user, pool.Mint, pool.TokenProgram)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive user token account: %w", err)
+	}
+	curveToken, err := txbuilder.AssociatedTokenAddress(pool.PoolId, pool.Mint, pool.TokenProgram)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive curve token account: %w", err)
+	}
+
+	discriminator := SellIxDiscm
+	if buy {
+		discriminator = BuyIxDiscm
+	}
+	data := append([]byte{}, discriminator[:]...)
+	data = binary.LittleEndian.AppendUint64(data, tokenAmount.Uint64())
+	data = binary.LittleEndian.AppendUint64(data, collateralAmount.Uint64())
+	data = append(data, fixedSide)
+	data = binary.LittleEndian.AppendUint64(data, 0) // slippage_bps
+
+	accounts := solana.AccountMetaSlice{
+		solana.NewAccountMeta(user, true, true),
+		solana.NewAccountMeta(userToken, true, false),
+		solana.NewAccountMeta(pool.PoolId, true, false),
+		solana.NewAccountMeta(curveToken, true, false),
+		solana.NewAccountMeta(DexFeeAccount, true, false),
+		solana.NewAccountMeta(HelioFeeAccount, true, false),
+		solana.NewAccountMeta(pool.Mint, false, false),
+		solana.NewAccountMeta(ConfigAccount, false, false),
+		solana.NewAccountMeta(pool.TokenProgram, false, false),
+		solana.NewAccountMeta(solana.SPLAssociatedTokenAccountProgramID, false, false),
+		solana.NewAccountMeta(solana.SystemProgramID, false, false),
+	}
+	return solana.NewInstruction(ProgramID, accounts, data), nil
+}
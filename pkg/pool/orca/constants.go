@@ -1,24 +1,54 @@
+// Package orca implements Orca Whirlpool concentrated liquidity pools
 package orca
 
 import (
+	"fmt"
 	"math/big"
 
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
 	"cosmossdk.io/math"
 	"github.com/gagliardetto/solana-go"
 )
 
-// Program IDs
+// Whirlpool program IDs and the WhirlpoolsConfig accounts owning Orca's pools, by cluster.
+// They are unexported so no caller can repoint every pool in the process; pools and
+// protocols carry the ones they use.
 var (
-	// Orca Whirlpool Program ID
-	ORCA_WHIRLPOOL_PROGRAM_ID        = solana.MustPublicKeyFromBase58("whirLbMiicVdio4qvUfM5KAg6Ct8VwpYzGff3uctyCc")
-	ORCA_WHIRLPOOL_DEVNET_PROGRAM_ID = solana.MustPublicKeyFromBase58("whirLbMiicVdio4qvUfM5KAg6Ct8VwpYzGff3uctyCc")
+	whirlpoolProgramID = solana.MustPublicKeyFromBase58("whirLbMiicVdio4qvUfM5KAg6Ct8VwpYzGff3uctyCc")
+	// Orca deploys the program at the same address on devnet; the clusters differ in the
+	// WhirlpoolsConfig that Orca's pools are created under
+	devnetWhirlpoolProgramID = solana.MustPublicKeyFromBase58("whirLbMiicVdio4qvUfM5KAg6Ct8VwpYzGff3uctyCc")
+
+	mainnetWhirlpoolsConfig = solana.MustPublicKeyFromBase58("2LecshUwdy9xi7meFgHtFJQNSKk4KdTrcpvaB56dP2NQ")
+	devnetWhirlpoolsConfig  = solana.MustPublicKeyFromBase58("FcrweFY1G9HJAHG5inkGB6pKg1HZ6x9UC2WioAfWrGkR")
+)
 
+// Program IDs
+var (
 	// Standard Solana Program IDs
 	TOKEN_PROGRAM_ID      = solana.MustPublicKeyFromBase58("TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA")
 	TOKEN_2022_PROGRAM_ID = solana.MustPublicKeyFromBase58("TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb")
 	MEMO_PROGRAM_ID       = solana.MustPublicKeyFromBase58("MemoSq4gqABAXKb96qnH8TysNcWxMyWCqXgDLGmfcHr")
 )
 
+// WhirlpoolProgramID returns the Whirlpool program ID on mainnet
+func WhirlpoolProgramID() solana.PublicKey {
+	return whirlpoolProgramID
+}
+
+// ProgramIDsForCluster returns the Whirlpool program ID and the WhirlpoolsConfig of Orca's
+// pools on the cluster; custom clusters get mainnet's
+func ProgramIDsForCluster(cluster sol.Cluster) (programID, whirlpoolsConfig solana.PublicKey, err error) {
+	switch cluster {
+	case sol.ClusterMainnet, sol.ClusterCustom:
+		return whirlpoolProgramID, mainnetWhirlpoolsConfig, nil
+	case sol.ClusterDevnet:
+		return devnetWhirlpoolProgramID, devnetWhirlpoolsConfig, nil
+	default:
+		return solana.PublicKey{}, solana.PublicKey{}, fmt.Errorf("orca whirlpool is not deployed on %s", cluster)
+	}
+}
+
 // Tick Array Configuration - Based on Orca Whirlpool specification
 const (
 	TICK_ARRAY_SIZE                 = 88  // Whirlpool uses 88 instead of CLMM's 60
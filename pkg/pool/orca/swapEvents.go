@@ -0,0 +1,57 @@
+package orca
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/utils"
+	"cosmossdk.io/math"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+)
+
+// tradedEventDiscriminator identifies the Traded event the Whirlpool program logs for swaps
+var tradedEventDiscriminator = utils.GetDiscriminator("event", "Traded")
+
+type tradedEvent struct {
+	Whirlpool         solana.PublicKey
+	AToB              bool
+	PreSqrtPrice      bin.Uint128
+	PostSqrtPrice     bin.Uint128
+	InputAmount       uint64
+	OutputAmount      uint64
+	InputTransferFee  uint64
+	OutputTransferFee uint64
+	LpFee             uint64
+	ProtocolFee       uint64
+}
+
+// DecodeWhirlpoolSwapEvent decodes the Traded event a Whirlpool swap logs. The trader is the
+// swap instruction's token authority, the account before the whirlpool in both swap and
+// swap v2.
+func DecodeWhirlpoolSwapEvent(event pkg.ProgramEvent) (pkg.SwapEvent, bool, error) {
+	if !bytes.HasPrefix(event.Data, tradedEventDiscriminator) {
+		return pkg.SwapEvent{}, false, nil
+	}
+	var ev tradedEvent
+	if err := bin.NewBinDecoder(event.Data[8:]).Decode(&ev); err != nil {
+		return pkg.SwapEvent{}, false, fmt.Errorf("failed to decode whirlpool traded event: %w", err)
+	}
+	swap := pkg.SwapEvent{
+		Protocol:     pkg.ProtocolNameOrcaWhirlpool,
+		Pool:         ev.Whirlpool,
+		ZeroForOne:   ev.AToB,
+		AmountIn:     ev.InputAmount,
+		AmountOut:    ev.OutputAmount,
+		Fee:          ev.LpFee + ev.ProtocolFee,
+		SqrtPriceX64: math.NewIntFromBigInt(ev.PostSqrtPrice.BigInt()),
+	}
+	for i := 1; i < len(event.Accounts); i++ {
+		if event.Accounts[i].Equals(ev.Whirlpool) {
+			swap.User = event.Accounts[i-1]
+			break
+		}
+	}
+	return swap, true, nil
+}
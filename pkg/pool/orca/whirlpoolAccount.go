@@ -0,0 +1,34 @@
+package orca
+
+import (
+	"github.com/gtdvccc/SolRouteTmp/pkg/layout"
+	"github.com/gagliardetto/solana-go"
+	"lukechampine.com/uint128"
+)
+
+// WhirlpoolAccount is the Whirlpool account of the Whirlpool program IDL, after the
+// discriminator. WhirlpoolPool decodes through it, and its layout gives the pool's size and
+// field offsets.
+type WhirlpoolAccount struct {
+	WhirlpoolsConfig           solana.PublicKey
+	WhirlpoolBump              [1]uint8
+	TickSpacing                uint16
+	FeeTierIndexSeed           [2]uint8
+	FeeRate                    uint16
+	ProtocolFeeRate            uint16
+	Liquidity                  uint128.Uint128
+	SqrtPrice                  uint128.Uint128
+	TickCurrentIndex           int32
+	ProtocolFeeOwedA           uint64
+	ProtocolFeeOwedB           uint64
+	TokenMintA                 solana.PublicKey
+	TokenVaultA                solana.PublicKey
+	FeeGrowthGlobalA           uint128.Uint128
+	TokenMintB                 solana.PublicKey
+	TokenVaultB                solana.PublicKey
+	FeeGrowthGlobalB           uint128.Uint128
+	RewardLastUpdatedTimestamp uint64
+	RewardInfos                [3]WhirlpoolRewardInfo
+}
+
+var whirlpoolLayout = layout.MustOf(WhirlpoolAccount{})
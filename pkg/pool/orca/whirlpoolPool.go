@@ -3,13 +3,17 @@ package orca
 import (
 	"bytes"
 	"context"
-	"encoding/binary"
+	"errors"
 	"fmt"
-	"math/big"
 	"strings"
 	"time"
 
 	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clmmmath"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/retry"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
 	cosmath "cosmossdk.io/math"
 	bin "github.com/gagliardetto/binary"
 	"github.com/gagliardetto/solana-go"
@@ -63,11 +67,20 @@ type WhirlpoolPool struct {
 
 	// Internal use fields
 	PoolId           solana.PublicKey // Pool ID (internal calculation)
+	ProgramID        solana.PublicKey // Program owning the pool, WhirlpoolProgramID when zero
 	UserBaseAccount  solana.PublicKey // User base token account
 	UserQuoteAccount solana.PublicKey // User quote token account
 
 	// Tick array cache for real-time data (similar to CLMM)
 	TickArrayCache map[string]WhirlpoolTickArray // Cache for real-time tick arrays
+
+	// Sleeper drives retry backoff; defaults to the system clock when nil
+	Sleeper clock.Sleeper
+	// Logger receives quote warnings; defaults to slog's default logger when nil
+	Logger pkg.Logger
+	// InitializeTickArrays quotes tick arrays not created yet as holding no initialized tick,
+	// and has swaps create them first, with the user paying their rent, rather than fail
+	InitializeTickArrays bool
 }
 
 // WhirlpoolRewardInfo reward information structure - Reference external/orca/whirlpool/generated/types.go
@@ -79,6 +92,17 @@ type WhirlpoolRewardInfo struct {
 	GrowthGlobalX64       uint128.Uint128  // growthGlobalX64
 }
 
+func (pool *WhirlpoolPool) sleeper() clock.Sleeper {
+	if pool.Sleeper == nil {
+		return clock.System{}
+	}
+	return pool.Sleeper
+}
+
+func (pool *WhirlpoolPool) logger() pkg.Logger {
+	return pkg.LoggerOrDefault(pool.Logger)
+}
+
 // Implement basic methods of Pool interface
 func (pool *WhirlpoolPool) ProtocolName() pkg.ProtocolName {
 	return pkg.ProtocolNameOrcaWhirlpool
@@ -89,258 +113,116 @@ func (pool *WhirlpoolPool) ProtocolType() pkg.ProtocolType {
 }
 
 func (pool *WhirlpoolPool) GetProgramID() solana.PublicKey {
-	return ORCA_WHIRLPOOL_PROGRAM_ID
+	if pool.ProgramID.IsZero() {
+		return whirlpoolProgramID
+	}
+	return pool.ProgramID
 }
 
 func (pool *WhirlpoolPool) GetID() string {
 	return pool.PoolId.String()
 }
 
+// FeeRateBps returns the swap fee in basis points, rounded up from hundredths of a basis point
+func (pool *WhirlpoolPool) FeeRateBps() uint64 {
+	return (uint64(pool.FeeRate) + 99) / 100
+}
+
 // GetTokens returns token pair - Note field name mapping
 func (pool *WhirlpoolPool) GetTokens() (baseMint, quoteMint string) {
 	return pool.TokenMintA.String(), pool.TokenMintB.String()
 }
 
-// Decode parses Whirlpool account data - Reference CLMM Decode implementation
-func (pool *WhirlpoolPool) Decode(data []byte) error {
-	// Skip 8 bytes discriminator if present
-	if len(data) > 8 {
-		data = data[8:]
-	}
-
-	offset := 0
-
-	// Parse whirlpools config (32 bytes)
-	pool.WhirlpoolsConfig = solana.PublicKeyFromBytes(data[offset : offset+32])
-	offset += 32
-
-	// Parse whirlpool bump (1 byte)
-	copy(pool.WhirlpoolBump[:], data[offset:offset+1])
-	offset += 1
-
-	// Parse tick spacing (2 bytes)
-	pool.TickSpacing = binary.LittleEndian.Uint16(data[offset : offset+2])
-	offset += 2
-
-	// Parse fee tier index seed (2 bytes)
-	copy(pool.FeeTierIndexSeed[:], data[offset:offset+2])
-	offset += 2
-
-	// Parse fee rate (2 bytes)
-	pool.FeeRate = binary.LittleEndian.Uint16(data[offset : offset+2])
-	offset += 2
-
-	// Parse protocol fee rate (2 bytes)
-	pool.ProtocolFeeRate = binary.LittleEndian.Uint16(data[offset : offset+2])
-	offset += 2
-
-	// Parse liquidity (16 bytes)
-	pool.Liquidity = uint128.FromBytes(data[offset : offset+16])
-	offset += 16
-
-	// Parse sqrt price (16 bytes) - Note: CLMM calls it SqrtPriceX64
-	pool.SqrtPrice = uint128.FromBytes(data[offset : offset+16])
-	offset += 16
-
-	// Parse tick current index (4 bytes) - Note: CLMM calls it TickCurrent
-	pool.TickCurrentIndex = int32(binary.LittleEndian.Uint32(data[offset : offset+4]))
-	offset += 4
-
-	// Parse protocol fee owed A (8 bytes)
-	pool.ProtocolFeeOwedA = binary.LittleEndian.Uint64(data[offset : offset+8])
-	offset += 8
-
-	// Parse protocol fee owed B (8 bytes)
-	pool.ProtocolFeeOwedB = binary.LittleEndian.Uint64(data[offset : offset+8])
-	offset += 8
-
-	// Parse token mint A (32 bytes) - Note: CLMM calls it TokenMint0
-	pool.TokenMintA = solana.PublicKeyFromBytes(data[offset : offset+32])
-	offset += 32
-
-	// Parse token vault A (32 bytes) - Note: CLMM calls it TokenVault0
-	pool.TokenVaultA = solana.PublicKeyFromBytes(data[offset : offset+32])
-	offset += 32
-
-	// Parse fee growth global A (16 bytes)
-	pool.FeeGrowthGlobalA = uint128.FromBytes(data[offset : offset+16])
-	offset += 16
-
-	// Parse token mint B (32 bytes) - Note: CLMM calls it TokenMint1
-	pool.TokenMintB = solana.PublicKeyFromBytes(data[offset : offset+32])
-	offset += 32
-
-	// Parse token vault B (32 bytes) - Note: CLMM calls it TokenVault1
-	pool.TokenVaultB = solana.PublicKeyFromBytes(data[offset : offset+32])
-	offset += 32
-
-	// Parse fee growth global B (16 bytes)
-	pool.FeeGrowthGlobalB = uint128.FromBytes(data[offset : offset+16])
-	offset += 16
-
-	// Parse reward last updated timestamp (8 bytes)
-	pool.RewardLastUpdatedTimestamp = binary.LittleEndian.Uint64(data[offset : offset+8])
-	offset += 8
-
-	// Parse reward infos (3 reward infos, each containing multiple fields)
-	for i := 0; i < 3; i++ {
-		// mint (32 bytes)
-		pool.RewardInfos[i].Mint = solana.PublicKeyFromBytes(data[offset : offset+32])
-		offset += 32
-
-		// vault (32 bytes)
-		pool.RewardInfos[i].Vault = solana.PublicKeyFromBytes(data[offset : offset+32])
-		offset += 32
-
-		// authority (32 bytes)
-		pool.RewardInfos[i].Authority = solana.PublicKeyFromBytes(data[offset : offset+32])
-		offset += 32
+// SpotPrice returns the pool's price and in-range liquidity from its state
+func (pool *WhirlpoolPool) SpotPrice(ctx context.Context, solClient pkg.RPC) (pkg.SpotPrice, error) {
+	price, depth := clmmmath.SpotPrice(pool.SqrtPrice.Big(), pool.Liquidity.Big())
+	return pkg.SpotPrice{Price: price, Depth: depth}, nil
+}
 
-		// emissions per second x64 (16 bytes)
-		pool.RewardInfos[i].EmissionsPerSecondX64 = uint128.FromBytes(data[offset : offset+16])
-		offset += 16
+// TokenVaults returns the pool vaults, which are controlled by the whirlpool account
+func (pool *WhirlpoolPool) TokenVaults() []pkg.TokenVault {
+	return []pkg.TokenVault{
+		{Address: pool.TokenVaultA, Mint: pool.TokenMintA, Authority: pool.PoolId},
+		{Address: pool.TokenVaultB, Mint: pool.TokenMintB, Authority: pool.PoolId},
+	}
+}
 
-		// growth global x64 (16 bytes)
-		pool.RewardInfos[i].GrowthGlobalX64 = uint128.FromBytes(data[offset : offset+16])
-		offset += 16
+// QuoteAccounts returns the tick arrays around the current tick in both directions, which
+// Quote refreshes whichever way the swap goes
+func (pool *WhirlpoolPool) QuoteAccounts(inputMint string) []solana.PublicKey {
+	var accounts []solana.PublicKey
+	for _, aToB := range []bool{true, false} {
+		ta0, ta1, ta2, err := DeriveMultipleWhirlpoolTickArrayPDAs(pool.GetProgramID(), pool.PoolId, int64(pool.TickCurrentIndex), int64(pool.TickSpacing), aToB)
+		if err != nil {
+			continue
+		}
+		accounts = append(accounts, ta0, ta1, ta2)
 	}
+	return accounts
+}
 
+// Decode parses Whirlpool account data through WhirlpoolAccount
+func (pool *WhirlpoolPool) Decode(data []byte) error {
+	var account WhirlpoolAccount
+	if err := whirlpoolLayout.Decode(data, &account); err != nil {
+		return err
+	}
+	pool.WhirlpoolsConfig = account.WhirlpoolsConfig
+	pool.WhirlpoolBump = account.WhirlpoolBump
+	pool.TickSpacing = account.TickSpacing
+	pool.FeeTierIndexSeed = account.FeeTierIndexSeed
+	pool.FeeRate = account.FeeRate
+	pool.ProtocolFeeRate = account.ProtocolFeeRate
+	pool.Liquidity = account.Liquidity
+	pool.SqrtPrice = account.SqrtPrice
+	pool.TickCurrentIndex = account.TickCurrentIndex
+	pool.ProtocolFeeOwedA = account.ProtocolFeeOwedA
+	pool.ProtocolFeeOwedB = account.ProtocolFeeOwedB
+	pool.TokenMintA = account.TokenMintA
+	pool.TokenVaultA = account.TokenVaultA
+	pool.FeeGrowthGlobalA = account.FeeGrowthGlobalA
+	pool.TokenMintB = account.TokenMintB
+	pool.TokenVaultB = account.TokenVaultB
+	pool.FeeGrowthGlobalB = account.FeeGrowthGlobalB
+	pool.RewardLastUpdatedTimestamp = account.RewardLastUpdatedTimestamp
+	pool.RewardInfos = account.RewardInfos
 	return nil
 }
 
-// Span returns account data size - Precise calculation based on complete Whirlpool structure
+// Span returns the account size, 653 bytes including the discriminator
 func (pool *WhirlpoolPool) Span() uint64 {
-	// Based on Whirlpool structure calculation from external/orca/whirlpool/generated/types.go:
-	//
-	// 8 bytes discriminator
-	// 32 bytes whirlpoolsConfig (PublicKey)
-	// 1 byte whirlpoolBump
-	// 2 bytes tickSpacing (uint16)
-	// 2 bytes feeTierIndexSeed
-	// 2 bytes feeRate (uint16)
-	// 2 bytes protocolFeeRate (uint16)
-	// 16 bytes liquidity (Uint128)
-	// 16 bytes sqrtPrice (Uint128)
-	// 4 bytes tickCurrentIndex (int32)
-	// 8 bytes protocolFeeOwedA (uint64)
-	// 8 bytes protocolFeeOwedB (uint64)
-	// 32 bytes tokenMintA (PublicKey)
-	// 32 bytes tokenVaultA (PublicKey)
-	// 16 bytes feeGrowthGlobalA (Uint128)
-	// 32 bytes tokenMintB (PublicKey)
-	// 32 bytes tokenVaultB (PublicKey)
-	// 16 bytes feeGrowthGlobalB (Uint128)
-	// 8 bytes rewardLastUpdatedTimestamp (uint64)
-	// 3 * (32+32+32+16+16) bytes rewardInfos (3 WhirlpoolRewardInfo)
-	//   Each WhirlpoolRewardInfo: mint(32) + vault(32) + authority(32) + emissionsPerSecondX64(16) + growthGlobalX64(16) = 128 bytes
-
-	return uint64(8 + 32 + 1 + 2 + 2 + 2 + 2 + 16 + 16 + 4 + 8 + 8 + 32 + 32 + 16 + 32 + 32 + 16 + 8 + 3*128)
-	// = 8 + 261 + 384 = 653 bytes (including discriminator)
+	return whirlpoolLayout.Size()
 }
 
-// Offset returns field offset - Used for RPC query filters
+// Offset returns the offset of a WhirlpoolAccount field, used for RPC query filters, or 0 for
+// unknown fields
 func (pool *WhirlpoolPool) Offset(field string) uint64 {
-	// Add 8 bytes for discriminator
-	baseOffset := uint64(8)
-
-	switch field {
-	case "TokenMintA":
-		// Precise offset calculation based on Whirlpool structure:
-		// whirlpoolsConfig(32) + whirlpoolBump(1) + tickSpacing(2) + feeTierIndexSeed(2) +
-		// feeRate(2) + protocolFeeRate(2) + liquidity(16) + sqrtPrice(16) +
-		// tickCurrentIndex(4) + protocolFeeOwedA(8) + protocolFeeOwedB(8)
-		return baseOffset + 32 + 1 + 2 + 2 + 2 + 2 + 16 + 16 + 4 + 8 + 8 // = 101
-	case "TokenMintB":
-		// After TokenMintA: tokenMintA(32) + tokenVaultA(32) + feeGrowthGlobalA(16)
-		// Note: Actual verification found TokenMintB at offset 181, not 189
-		return baseOffset + 101 + 32 + 32 + 16 - 8 // = 181 (corrected 8-byte difference)
-	case "TickSpacing":
-		// After whirlpoolsConfig(32) + whirlpoolBump(1)
-		return baseOffset + 32 + 1 // = 41
-	case "FeeRate":
-		// After whirlpoolsConfig(32) + whirlpoolBump(1) + tickSpacing(2) + feeTierIndexSeed(2)
-		return baseOffset + 32 + 1 + 2 + 2 // = 45
-	case "SqrtPrice":
-		// After liquidity
-		return baseOffset + 32 + 1 + 2 + 2 + 2 + 2 + 16 // = 65
-	case "TickCurrentIndex":
-		// After sqrtPrice
-		return baseOffset + 32 + 1 + 2 + 2 + 2 + 2 + 16 + 16 // = 81
-	}
-	return 0
+	offset, _ := whirlpoolLayout.Offset(field)
+	return offset
 }
 
 // Quote method - Get swap quote (with boundary validation and error handling)
-func (pool *WhirlpoolPool) Quote(ctx context.Context, solClient *rpc.Client, inputMint string, inputAmount cosmath.Int) (cosmath.Int, error) {
-	// 1. Input validation
-	if err := pool.validateQuoteInputs(inputMint, inputAmount); err != nil {
-		return cosmath.Int{}, fmt.Errorf("quote input validation failed: %w", err)
-	}
-
-	// 2. Pool state validation
-	if err := pool.validatePoolState(); err != nil {
-		return cosmath.Int{}, fmt.Errorf("pool state validation failed: %w", err)
-	}
-
-	// 3. Pool health check (based on CLMM's quality assessment approach)
-	if healthy, err := pool.IsHealthy(); !healthy {
-		return cosmath.Int{}, fmt.Errorf("pool health check failed: %w", err)
-	}
-
-	// 4. Real-time data update (similar to CLMM's approach)
-	if err := pool.UpdateTickArrays(ctx, solClient); err != nil {
-		// Log warning but continue - we can fall back to static data
-		// This follows the same pattern as CLMM's error handling
-		fmt.Printf("Warning: failed to update tick arrays (using static data): %v\n", err)
+func (pool *WhirlpoolPool) Quote(ctx context.Context, solClient pkg.RPC, inputMint string, inputAmount cosmath.Int) (cosmath.Int, error) {
+	if _, err := pool.prepareQuote(ctx, solClient, inputMint, inputAmount); err != nil {
+		return cosmath.Int{}, err
 	}
 
-	// 4.1 Validate tick array sequence for this direction to avoid 6038
-	var aToB bool
-	if inputMint == pool.TokenMintA.String() {
-		aToB = true
-	} else if inputMint == pool.TokenMintB.String() {
-		aToB = false
-	} else {
+	if inputMint != pool.TokenMintA.String() && inputMint != pool.TokenMintB.String() {
 		return cosmath.Int{}, fmt.Errorf("input mint %s not found in pool %s", inputMint, pool.PoolId.String())
 	}
-	// Validate tick array sequence but allow some flexibility
-	if err := pool.validateTickArraySequence(ctx, solClient, aToB); err != nil {
-		// Log warning but don't completely fail - let the swap calculation attempt proceed
-		// Some pools may have minor tick array issues but still be usable
-		fmt.Printf("Warning: tick array validation failed for pool %s: %v\n", pool.PoolId.String(), err)
-		// Still return the error for very critical issues like missing primary arrays
-		if isCriticalTickArrayError(err) {
-			return cosmath.Int{}, fmt.Errorf("critical tick array issue: %w", err)
-		}
-	}
 
 	// 5. Calculate quote (with retry mechanism)
-	maxRetries := 2
-	var lastErr error
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		var priceResult cosmath.Int
-		var err error
-		if inputMint == pool.TokenMintA.String() {
-			priceResult, err = pool.ComputeWhirlpoolAmountOutFormat(pool.TokenMintA.String(), inputAmount)
-		} else if inputMint == pool.TokenMintB.String() {
-			priceResult, err = pool.ComputeWhirlpoolAmountOutFormat(pool.TokenMintB.String(), inputAmount)
-		} else {
-			return cosmath.Int{}, fmt.Errorf("input mint %s not found in pool %s", inputMint, pool.PoolId.String())
-		}
-		if err != nil {
-			lastErr = err
-			if attempt < maxRetries && isTemporaryError(err) {
-				time.Sleep(time.Duration(50*(attempt+1)) * time.Millisecond)
-				continue
-			}
-			return cosmath.Int{}, fmt.Errorf("amount calculation failed after %d attempts: %w", attempt+1, err)
-		}
-		if err := pool.validateQuoteOutput(priceResult); err != nil {
-			return cosmath.Int{}, fmt.Errorf("quote output validation failed: %w", err)
-		}
-		return priceResult.Neg(), nil
+	policy := retry.Policy{MaxAttempts: 3, BaseDelay: 50 * time.Millisecond, Retryable: isTemporaryError, Sleeper: pool.sleeper()}
+	priceResult, err := retry.Do(ctx, policy, func(context.Context) (cosmath.Int, error) {
+		return pool.ComputeWhirlpoolAmountOutFormat(inputMint, inputAmount)
+	})
+	if err != nil {
+		return cosmath.Int{}, fmt.Errorf("amount calculation failed: %w", err)
+	}
+	if err := pool.validateQuoteOutput(priceResult); err != nil {
+		return cosmath.Int{}, fmt.Errorf("quote output validation failed: %w", err)
 	}
-	return cosmath.Int{}, fmt.Errorf("quote calculation failed after retries: %w", lastErr)
+	return priceResult.Neg(), nil
 }
 
 // validateQuoteInputs validates quote input parameters
@@ -482,21 +364,95 @@ func isTemporaryError(err error) bool {
 		strings.Contains(errorMsg, "timeout")
 }
 
-// isCriticalTickArrayError determines if a tick array error is critical enough to skip the pool
+// errTickArrayDecode marks tick arrays whose account data can't be decoded
+var errTickArrayDecode = errors.New("failed to decode tick array")
+
+// isCriticalTickArrayError determines if a tick array error is critical enough to skip the
+// pool. Arrays out of sequence may still work in some cases.
 func isCriticalTickArrayError(err error) bool {
-	errorMsg := strings.ToLower(err.Error())
-	// Critical errors that definitely prevent swapping
-	return strings.Contains(errorMsg, "primary tick array missing") ||
-		strings.Contains(errorMsg, "failed to decode tick array") ||
-		strings.Contains(errorMsg, "corrupted") ||
-		strings.Contains(errorMsg, "abnormal liquidity_net")
-	// Non-critical: "not consecutive" - may still work in some cases
+	return errors.Is(err, solerrors.ErrTickArrayMissing) || errors.Is(err, errTickArrayDecode)
+}
+
+// prepareQuote runs the validations and tick array refresh shared by Quote and QuoteExactOut,
+// returning the swap direction for inputMint
+func (pool *WhirlpoolPool) prepareQuote(ctx context.Context, solClient pkg.RPC, inputMint string, amount cosmath.Int) (bool, error) {
+	// 1. Input validation
+	if err := pool.validateQuoteInputs(inputMint, amount); err != nil {
+		return false, fmt.Errorf("quote input validation failed: %w", err)
+	}
+
+	// 2. Pool state validation
+	if err := pool.validatePoolState(); err != nil {
+		return false, fmt.Errorf("pool state validation failed: %w", err)
+	}
+
+	// 3. Pool health check (based on CLMM's quality assessment approach)
+	if healthy, err := pool.IsHealthy(); !healthy {
+		return false, fmt.Errorf("pool health check failed: %w", err)
+	}
+
+	// 4. Real-time data update (similar to CLMM's approach)
+	if err := pool.UpdateTickArrays(ctx, solClient); err != nil {
+		// Log warning but continue - we can fall back to static data
+		// This follows the same pattern as CLMM's error handling
+		pool.logger().Warn("failed to update tick arrays, using static data", pkg.LogKeyPool, pool.PoolId.String(), pkg.LogKeyError, err)
+	}
+
+	// 4.1 Validate tick array sequence for this direction to avoid 6038
+	var aToB bool
+	if inputMint == pool.TokenMintA.String() {
+		aToB = true
+	} else if inputMint == pool.TokenMintB.String() {
+		aToB = false
+	} else {
+		return false, fmt.Errorf("input mint %s not found in pool %s", inputMint, pool.PoolId.String())
+	}
+	// Validate tick array sequence but allow some flexibility
+	// Missing arrays are no issue when the swap creates them
+	if err := pool.validateTickArraySequence(ctx, solClient, aToB); err != nil && !(pool.InitializeTickArrays && errors.Is(err, solerrors.ErrTickArrayMissing)) {
+		// Log warning but don't completely fail - let the swap calculation attempt proceed
+		// Some pools may have minor tick array issues but still be usable
+		pool.logger().Warn("tick array validation failed", pkg.LogKeyPool, pool.PoolId.String(), pkg.LogKeyInputMint, inputMint, pkg.LogKeyError, err)
+		// Still return the error for very critical issues like missing primary arrays
+		if isCriticalTickArrayError(err) {
+			return false, fmt.Errorf("critical tick array issue: %w", err)
+		}
+	}
+
+	return aToB, nil
+}
+
+// QuoteExactOut calculates the input amount required to receive desiredOut of outputMint
+func (pool *WhirlpoolPool) QuoteExactOut(ctx context.Context, solClient pkg.RPC, outputMint string, desiredOut cosmath.Int) (cosmath.Int, error) {
+	var inputMint string
+	if outputMint == pool.TokenMintB.String() {
+		inputMint = pool.TokenMintA.String()
+	} else if outputMint == pool.TokenMintA.String() {
+		inputMint = pool.TokenMintB.String()
+	} else {
+		return cosmath.Int{}, fmt.Errorf("output mint %s not found in pool %s", outputMint, pool.PoolId.String())
+	}
+
+	aToB, err := pool.prepareQuote(ctx, solClient, inputMint, desiredOut)
+	if err != nil {
+		return cosmath.Int{}, err
+	}
+
+	// A negative amountSpecified switches the swap computation into exact-output mode
+	amountIn, err := pool.whirlpoolSwapCompute(aToB, desiredOut.Neg(), cosmath.NewIntFromUint64(uint64(pool.FeeRate)))
+	if err != nil {
+		return cosmath.Int{}, fmt.Errorf("failed to compute Whirlpool exact-out amount: %w", err)
+	}
+	if err := pool.validateQuoteOutput(amountIn); err != nil {
+		return cosmath.Int{}, fmt.Errorf("quote output validation failed: %w", err)
+	}
+	return amountIn, nil
 }
 
 // UpdateTickArrays fetches and caches real-time tick array data
 // Based on CLMM's real-time data fetching approach
 // Note: This method only fetches data, doesn't perform validation that could block pool selection
-func (pool *WhirlpoolPool) UpdateTickArrays(ctx context.Context, solClient *rpc.Client) error {
+func (pool *WhirlpoolPool) UpdateTickArrays(ctx context.Context, solClient pkg.RPC) error {
 	// Try both directions to get comprehensive tick array data
 	directions := []bool{true, false} // A->B and B->A
 
@@ -507,33 +463,35 @@ func (pool *WhirlpoolPool) UpdateTickArrays(ctx context.Context, solClient *rpc.
 
 	for _, aToB := range directions {
 		// Get required tick array addresses based on current tick and swap direction
-		tickArray0, tickArray1, tickArray2, err := DeriveMultipleWhirlpoolTickArrayPDAs(
-			pool.PoolId,
-			int64(pool.TickCurrentIndex),
-			int64(pool.TickSpacing),
-			aToB,
-		)
-		if err != nil {
+		startIndexes := pool.swapTickArrayStartIndexes(aToB)
+		tickArrayAddrs := make([]solana.PublicKey, 0, len(startIndexes))
+		for _, startIndex := range startIndexes {
+			tickArrayAddr, err := DeriveWhirlpoolTickArrayPDA(pool.GetProgramID(), pool.PoolId, startIndex)
+			if err != nil {
+				break
+			}
+			tickArrayAddrs = append(tickArrayAddrs, tickArrayAddr)
+		}
+		if len(tickArrayAddrs) == 0 {
 			// Log warning and try next direction
 			continue
 		}
 
-		// Collect all tick array addresses
-		tickArrayAddrs := []solana.PublicKey{tickArray0, tickArray1, tickArray2}
-
 		// Batch fetch all tick arrays (similar to CLMM approach)
-		results, err := solClient.GetMultipleAccountsWithOpts(ctx, tickArrayAddrs, &rpc.GetMultipleAccountsOpts{
-			Commitment: rpc.CommitmentProcessed,
-		})
+		results, err := solClient.GetMultipleAccountsWithOpts(ctx, tickArrayAddrs, sol.MultipleAccountsOpts(ctx, rpc.CommitmentProcessed))
 		if err != nil {
 			// Log warning and try next direction
 			continue
 		}
 
 		// Parse and cache tick array data
-		for _, result := range results.Value {
+		for i, result := range results.Value {
 			if result == nil {
-				continue // Skip uninitialized tick arrays
+				// Uninitialized tick arrays are skipped, or quoted as empty when swaps create them
+				if pool.InitializeTickArrays && i < len(startIndexes) {
+					pool.TickArrayCache[fmt.Sprintf("%d", startIndexes[i])] = emptyWhirlpoolTickArray(pool.PoolId, startIndexes[i])
+				}
+				continue
 			}
 
 			tickArray := &WhirlpoolTickArray{}
@@ -557,18 +515,7 @@ func (pool *WhirlpoolPool) ComputeWhirlpoolAmountOutFormat(inputTokenMint string
 	// Determine swap direction: A -> B is true, B -> A is false
 	zeroForOne := inputTokenMint == pool.TokenMintA.String()
 
-	// Use current pool state for basic calculation
-	firstTickArrayStartIndex := getWhirlpoolTickArrayStartIndexByTick(int64(pool.TickCurrentIndex), int64(pool.TickSpacing))
-
-	// Call core swap calculation logic
-	expectedAmountOut, err := pool.whirlpoolSwapCompute(
-		int64(pool.TickCurrentIndex),
-		zeroForOne,
-		inputAmount,
-		cosmath.NewIntFromUint64(uint64(pool.FeeRate)), // Use pool's fee rate
-		firstTickArrayStartIndex,
-		nil, // Temporarily not using external bitmap
-	)
+	expectedAmountOut, err := pool.whirlpoolSwapCompute(zeroForOne, inputAmount, cosmath.NewIntFromUint64(uint64(pool.FeeRate)))
 	if err != nil {
 		return cosmath.Int{}, fmt.Errorf("failed to compute Whirlpool swap amount: %w", err)
 	}
@@ -587,11 +534,47 @@ func (pool *WhirlpoolPool) ComputeWhirlpoolAmountOutFormat(inputTokenMint string
 // Returned instruction can be directly used for Solana transaction execution.
 func (pool *WhirlpoolPool) BuildSwapInstructions(
 	ctx context.Context,
-	solClient *rpc.Client,
+	solClient pkg.RPC,
 	userAddr solana.PublicKey,
 	inputMint string,
 	amountIn cosmath.Int,
 	minOutAmountWithDecimals cosmath.Int,
+) ([]solana.Instruction, error) {
+	return pool.buildSwapInstructions(ctx, solClient, userAddr, inputMint, amountIn, minOutAmountWithDecimals, true)
+}
+
+// BuildSwapInstructionsExactOut builds a SwapV2 instruction with amountSpecifiedIsInput unset,
+// so the program delivers exactly amountOut of outputMint and fails if more than maxIn is needed
+func (pool *WhirlpoolPool) BuildSwapInstructionsExactOut(
+	ctx context.Context,
+	solClient pkg.RPC,
+	userAddr solana.PublicKey,
+	outputMint string,
+	amountOut cosmath.Int,
+	maxIn cosmath.Int,
+) ([]solana.Instruction, error) {
+	var inputMint string
+	if outputMint == pool.TokenMintB.String() {
+		inputMint = pool.TokenMintA.String()
+	} else if outputMint == pool.TokenMintA.String() {
+		inputMint = pool.TokenMintB.String()
+	} else {
+		return nil, fmt.Errorf("output mint %s not found in pool", outputMint)
+	}
+	return pool.buildSwapInstructions(ctx, solClient, userAddr, inputMint, amountOut, maxIn, false)
+}
+
+// buildSwapInstructions builds the SwapV2 instruction for both quoting modes.
+// amount is the exact input when amountSpecifiedIsInput is true and the exact output otherwise;
+// otherAmountThreshold is the matching minimum output or maximum input.
+func (pool *WhirlpoolPool) buildSwapInstructions(
+	ctx context.Context,
+	solClient pkg.RPC,
+	userAddr solana.PublicKey,
+	inputMint string,
+	amount cosmath.Int,
+	otherAmountThreshold cosmath.Int,
+	amountSpecifiedIsInput bool,
 ) ([]solana.Instruction, error) {
 	// 1. Determine swap direction
 	var aToB bool
@@ -606,16 +589,31 @@ func (pool *WhirlpoolPool) BuildSwapInstructions(
 		return nil, fmt.Errorf("input mint %s not found in pool", inputMint)
 	}
 
-	// 2. Get or create user's token accounts - fixed as A and B, not changing with swap direction
-	userTokenAccountA, err := getOrCreateTokenAccount(ctx, solClient, userAddr, pool.TokenMintA)
+	// 2. Get user's token accounts - fixed as A and B, not changing with swap direction.
+	// Missing accounts are created ahead of the swap.
+	instructions := make([]solana.Instruction, 0, 3)
+	userTokenAccountA, createA, err := getOrCreateTokenAccount(ctx, solClient, pool.sleeper(), userAddr, pool.TokenMintA)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get token A account: %w", err)
 	}
+	if createA != nil {
+		instructions = append(instructions, createA)
+	}
 
-	userTokenAccountB, err := getOrCreateTokenAccount(ctx, solClient, userAddr, pool.TokenMintB)
+	userTokenAccountB, createB, err := getOrCreateTokenAccount(ctx, solClient, pool.sleeper(), userAddr, pool.TokenMintB)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get token B account: %w", err)
 	}
+	if createB != nil {
+		instructions = append(instructions, createB)
+	}
+	if pool.InitializeTickArrays {
+		initTickArrays, err := pool.tickArrayInitInstructions(ctx, solClient, userAddr, aToB)
+		if err != nil {
+			return nil, err
+		}
+		instructions = append(instructions, initTickArrays...)
+	}
 
 	// 3. Calculate price limit (use exact protocol bounds as per official Whirlpool SDK)
 	var sqrtPriceLimit uint128.Uint128
@@ -632,6 +630,7 @@ func (pool *WhirlpoolPool) BuildSwapInstructions(
 
 	// 4. Build tick array addresses (using real PDA derivation)
 	tickArray0, tickArray1, tickArray2, err := DeriveMultipleWhirlpoolTickArrayPDAs(
+		pool.GetProgramID(),
 		pool.PoolId,
 		int64(pool.TickCurrentIndex),
 		int64(pool.TickSpacing),
@@ -642,7 +641,7 @@ func (pool *WhirlpoolPool) BuildSwapInstructions(
 	}
 
 	// 5. Oracle address (using correct PDA derivation)
-	oracleAddr, err := DeriveWhirlpoolOraclePDA(pool.PoolId)
+	oracleAddr, err := DeriveWhirlpoolOraclePDA(pool.GetProgramID(), pool.PoolId)
 	if err != nil {
 		return nil, fmt.Errorf("failed to derive oracle PDA: %w", err)
 	}
@@ -650,138 +649,204 @@ func (pool *WhirlpoolPool) BuildSwapInstructions(
 	// 6. Build SwapV2 instruction parameters
 	instruction, err := createWhirlpoolSwapV2Instruction(
 		// Instruction parameters
-		amountIn.Uint64(),                 // amount
-		minOutAmountWithDecimals.Uint64(), // otherAmountThreshold
-		sqrtPriceLimit,                    // sqrtPriceLimit
-		true,                              // amountSpecifiedIsInput
-		aToB,                              // aToB
-		nil,                               // remainingAccountsInfo
+		amount.Uint64(),               // amount
+		otherAmountThreshold.Uint64(), // otherAmountThreshold
+		sqrtPriceLimit,                // sqrtPriceLimit
+		amountSpecifiedIsInput,        // amountSpecifiedIsInput
+		aToB,                          // aToB
+		nil,                           // remainingAccountsInfo
 
 		// Account addresses - fixed as A and B order, not changing with swap direction
-		TOKEN_PROGRAM_ID,  // tokenProgramA
-		TOKEN_PROGRAM_ID,  // tokenProgramB
-		MEMO_PROGRAM_ID,   // memoProgram
-		userAddr,          // tokenAuthority
-		pool.PoolId,       // whirlpool
-		pool.TokenMintA,   // tokenMintA
-		pool.TokenMintB,   // tokenMintB
-		userTokenAccountA, // tokenOwnerAccountA (fixed as A)
-		pool.TokenVaultA,  // tokenVaultA (fixed as A)
-		userTokenAccountB, // tokenOwnerAccountB (fixed as B)
-		pool.TokenVaultB,  // tokenVaultB (fixed as B)
-		tickArray0,        // tickArray0
-		tickArray1,        // tickArray1
-		tickArray2,        // tickArray2
-		oracleAddr,        // oracle
+		pool.GetProgramID(), // whirlpool program
+		TOKEN_PROGRAM_ID,    // tokenProgramA
+		TOKEN_PROGRAM_ID,    // tokenProgramB
+		MEMO_PROGRAM_ID,     // memoProgram
+		userAddr,            // tokenAuthority
+		pool.PoolId,         // whirlpool
+		pool.TokenMintA,     // tokenMintA
+		pool.TokenMintB,     // tokenMintB
+		userTokenAccountA,   // tokenOwnerAccountA (fixed as A)
+		pool.TokenVaultA,    // tokenVaultA (fixed as A)
+		userTokenAccountB,   // tokenOwnerAccountB (fixed as B)
+		pool.TokenVaultB,    // tokenVaultB (fixed as B)
+		tickArray0,          // tickArray0
+		tickArray1,          // tickArray1
+		tickArray2,          // tickArray2
+		oracleAddr,          // oracle
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create SwapV2 instruction: %w", err)
 	}
 
-	return []solana.Instruction{instruction}, nil
+	return append(instructions, instruction), nil
 }
 
-// whirlpoolSwapCompute - Whirlpool core swap calculation logic
+// whirlpoolSwapCompute simulates the swap instruction over the cached tick arrays. Each step
+// swaps up to the next initialized tick, or the end of the tick array sequence, and crossing a
+// tick applies its liquidity_net. Like the program, a swap that runs past the tick arrays passed
+// to the instruction fails. Exact-input swaps return the output as a negative amount, exact-output
+// swaps (negative amountSpecified) return the required input including fees.
 func (pool *WhirlpoolPool) whirlpoolSwapCompute(
-	currentTick int64,
 	zeroForOne bool,
 	amountSpecified cosmath.Int,
 	fee cosmath.Int,
-	lastSavedTickArrayStartIndex int64,
-	exTickArrayBitmap *WhirlpoolTickArrayBitmapExtensionType,
 ) (cosmath.Int, error) {
-	// Input validation
 	if amountSpecified.IsZero() {
 		return cosmath.Int{}, fmt.Errorf("input amount cannot be zero")
 	}
+	tickArrays := pool.swapTickArrays(zeroForOne)
+	if len(tickArrays) == 0 {
+		return cosmath.Int{}, fmt.Errorf("no tick arrays cached for swap direction aToB=%v", zeroForOne)
+	}
 
-	// Basic variable initialization
 	baseInput := amountSpecified.IsPositive()
-	sqrtPriceLimitX64 := cosmath.NewInt(0)
-
-	// Initialize calculation variables
-	amountSpecifiedRemaining := amountSpecified
-	amountCalculated := cosmath.NewInt(0)
-	sqrtPriceX64 := cosmath.NewIntFromBigInt(pool.SqrtPrice.Big()) // Note: Whirlpool uses SqrtPrice instead of SqrtPriceX64
+	amountRemaining := amountSpecified.Abs()
+	amountCalculated := cosmath.ZeroInt()
+	sqrtPriceX64 := cosmath.NewIntFromBigInt(pool.SqrtPrice.Big())
 	liquidity := cosmath.NewIntFromBigInt(pool.Liquidity.Big())
+	tickSpacing := int64(pool.TickSpacing)
+	currentTick := int64(pool.TickCurrentIndex)
+	arrayIndex := 0
 
-	// Set price limits - use exact protocol bounds
+	sqrtPriceLimitX64 := MAX_SQRT_PRICE_X64
 	if zeroForOne {
 		sqrtPriceLimitX64 = MIN_SQRT_PRICE_X64
-	} else {
-		sqrtPriceLimitX64 = MAX_SQRT_PRICE_X64
 	}
 
-	// Calculate target price based on available liquidity and swap direction
-	// Use a more conservative approach that considers pool constraints
-	targetPrice := sqrtPriceX64
-
-	// Calculate more accurate price impact based on input amount and available liquidity
-	// Use proper CLMM formula: ΔP = ΔA / L (for A->B) or ΔP = ΔB * P^2 / L (for B->A)
-	liquidityImpact := amountSpecified.Abs().Mul(cosmath.NewInt(10000)).Quo(liquidity) // Scale by 10000 for better precision
+	// Every step either finishes the swap or reaches a tick, and the sequence holds at most
+	// len(tickArrays)*TICK_ARRAY_SIZE ticks
+	maxSteps := len(tickArrays)*TICK_ARRAY_SIZE + 2
+	for step := 0; !amountRemaining.IsZero() && !sqrtPriceX64.Equal(sqrtPriceLimitX64); step++ {
+		if step >= maxSteps {
+			return cosmath.Int{}, fmt.Errorf("swap computation exceeded %d steps", maxSteps)
+		}
 
-	if zeroForOne {
-		// A -> B: price decreases
-		// More aggressive price movement based on actual liquidity impact
-		priceChangePercent := liquidityImpact.Quo(cosmath.NewInt(100)) // Convert to percentage
-		if priceChangePercent.GT(cosmath.NewInt(1000)) {               // Max 10% change
-			priceChangePercent = cosmath.NewInt(1000)
+		nextArrayIndex, nextTick, err := nextInitializedTickInSequence(tickArrays, currentTick, tickSpacing, zeroForOne, arrayIndex)
+		if err != nil {
+			return cosmath.Int{}, fmt.Errorf("%w in tick arrays: %w", solerrors.ErrInsufficientLiquidity, err)
 		}
-		if priceChangePercent.LT(cosmath.NewInt(10)) { // Min 0.1% change
-			priceChangePercent = cosmath.NewInt(10)
+		nextTick = max(min(nextTick, MAX_TICK), MIN_TICK)
+		nextTickSqrtPrice, err := clmmmath.WhirlpoolSqrtPriceX64FromTick(nextTick)
+		if err != nil {
+			return cosmath.Int{}, fmt.Errorf("failed to get sqrt price of tick %d: %w", nextTick, err)
 		}
-		targetPrice = sqrtPriceX64.Mul(cosmath.NewInt(10000).Sub(priceChangePercent)).Quo(cosmath.NewInt(10000))
-		if targetPrice.LT(sqrtPriceLimitX64) {
+		targetPrice := nextTickSqrtPrice
+		if (zeroForOne && targetPrice.LT(sqrtPriceLimitX64)) || (!zeroForOne && targetPrice.GT(sqrtPriceLimitX64)) {
 			targetPrice = sqrtPriceLimitX64
 		}
-	} else {
-		// B -> A: price increases
-		priceChangePercent := liquidityImpact.Quo(cosmath.NewInt(100)) // Convert to percentage
-		if priceChangePercent.GT(cosmath.NewInt(1000)) {               // Max 10% change
-			priceChangePercent = cosmath.NewInt(1000)
+
+		stepRemaining := amountRemaining
+		if !baseInput {
+			stepRemaining = amountRemaining.Neg()
 		}
-		if priceChangePercent.LT(cosmath.NewInt(10)) { // Min 0.1% change
-			priceChangePercent = cosmath.NewInt(10)
+		newSqrtPrice, amountIn, amountOut, feeAmount, err := pool.whirlpoolSwapStepCompute(
+			sqrtPriceX64,
+			targetPrice,
+			liquidity,
+			stepRemaining,
+			fee,
+			zeroForOne,
+		)
+		if err != nil {
+			return cosmath.Int{}, fmt.Errorf("swap step compute failed: %w", err)
 		}
-		targetPrice = sqrtPriceX64.Mul(cosmath.NewInt(10000).Add(priceChangePercent)).Quo(cosmath.NewInt(10000))
-		if targetPrice.GT(sqrtPriceLimitX64) {
-			targetPrice = sqrtPriceLimitX64
+
+		if baseInput {
+			amountRemaining = amountRemaining.Sub(amountIn.Add(feeAmount))
+			amountCalculated = amountCalculated.Add(amountOut)
+		} else {
+			amountRemaining = amountRemaining.Sub(amountOut)
+			amountCalculated = amountCalculated.Add(amountIn.Add(feeAmount))
 		}
-	}
 
-	// Call simplified single-step calculation
-	newSqrtPrice, amountIn, amountOut, feeAmount, err := pool.whirlpoolSwapStepCompute(
-		sqrtPriceX64,
-		targetPrice,
-		liquidity,
-		amountSpecifiedRemaining,
-		fee,
-		zeroForOne,
-	)
-	if err != nil {
-		return cosmath.Int{}, fmt.Errorf("swap step compute failed: %w", err)
+		if newSqrtPrice.Equal(nextTickSqrtPrice) {
+			if tick := tickArrays[nextArrayIndex].tick(nextTick, tickSpacing); tick != nil && tick.Initialized {
+				liquidityNet := tick.LiquidityNet
+				if zeroForOne {
+					liquidityNet = -liquidityNet
+				}
+				liquidity = liquidity.Add(cosmath.NewInt(liquidityNet))
+				if liquidity.IsNegative() {
+					return cosmath.Int{}, fmt.Errorf("liquidity underflow crossing tick %d", nextTick)
+				}
+			}
+			if zeroForOne {
+				currentTick = nextTick - 1
+			} else {
+				currentTick = nextTick
+			}
+		} else if !newSqrtPrice.Equal(sqrtPriceX64) {
+			currentTick, err = clmmmath.WhirlpoolTickFromSqrtPriceX64(newSqrtPrice)
+			if err != nil {
+				return cosmath.Int{}, fmt.Errorf("failed to get tick from sqrt price: %w", err)
+			}
+		}
+		sqrtPriceX64 = newSqrtPrice
+		arrayIndex = nextArrayIndex
 	}
 
-	// Update calculation results
-	if baseInput {
-		// Exact input mode
-		amountCalculated = amountOut.Neg() // Return negative number representing output
-	} else {
-		// Exact output mode
-		amountCalculated = amountIn.Add(feeAmount)
+	if !baseInput {
+		if !amountRemaining.IsZero() {
+			return cosmath.Int{}, fmt.Errorf("%w: can fill %s of requested %s", solerrors.ErrInsufficientLiquidity,
+				amountSpecified.Abs().Sub(amountRemaining).String(), amountSpecified.Abs().String())
+		}
+		return amountCalculated, nil
 	}
-
-	// Validate result reasonableness
 	if amountCalculated.IsZero() {
 		return cosmath.Int{}, fmt.Errorf("calculated amount is zero, input: %s, sqrtPrice: %s->%s",
-			amountSpecified.String(), sqrtPriceX64.String(), newSqrtPrice.String())
+			amountSpecified.String(), pool.SqrtPrice.String(), sqrtPriceX64.String())
 	}
+	return amountCalculated.Neg(), nil
+}
+
+// swapTickArrays returns the cached tick arrays the swap instruction would be given for the
+// direction, in order, stopping at the first array that isn't cached
+func (pool *WhirlpoolPool) swapTickArrays(aToB bool) []*WhirlpoolTickArray {
+	tickArrays := make([]*WhirlpoolTickArray, 0, 3)
+	for _, startIndex := range pool.swapTickArrayStartIndexes(aToB) {
+		tickArray, ok := pool.TickArrayCache[fmt.Sprintf("%d", startIndex)]
+		if !ok {
+			break
+		}
+		tickArrays = append(tickArrays, &tickArray)
+	}
+	return tickArrays
+}
 
-	return amountCalculated, nil
+// nextInitializedTickInSequence returns the next initialized tick across the tick array sequence,
+// like the program's get_next_initialized_tick_index. When no tick is initialized it returns the
+// last tick of the final array, past which the swap cannot continue.
+func nextInitializedTickInSequence(tickArrays []*WhirlpoolTickArray, tickIndex, tickSpacing int64, aToB bool, arrayIndex int) (int, int64, error) {
+	ticksInArray := getWhirlpoolTickCount(tickSpacing)
+	searchIndex := tickIndex
+	for {
+		tickArray := tickArrays[arrayIndex]
+		next, found, err := tickArray.nextInitializedTickIndex(searchIndex, tickSpacing, aToB)
+		if err != nil {
+			return 0, 0, err
+		}
+		if found {
+			return arrayIndex, next, nil
+		}
+
+		start := int64(tickArray.StartTickIndex)
+		if arrayIndex+1 == len(tickArrays) {
+			if aToB {
+				return arrayIndex, start, nil
+			}
+			return arrayIndex, start + tickSpacing*(TICK_ARRAY_SIZE-1), nil
+		}
+		if aToB {
+			searchIndex = start - 1
+		} else {
+			searchIndex = start + ticksInArray - 1
+		}
+		arrayIndex++
+	}
 }
 
-// whirlpoolSwapStepCompute - Whirlpool precise CLMM calculation (based on Raydium CLMM algorithm)
-// Uses same precise mathematical formulas as Raydium CLMM to ensure calculation accuracy
+// whirlpoolSwapStepCompute computes a swap step with the CLMM math Whirlpool shares with
+// Raydium
 func (pool *WhirlpoolPool) whirlpoolSwapStepCompute(
 	sqrtPriceCurrent cosmath.Int,
 	sqrtPriceTarget cosmath.Int,
@@ -791,291 +856,79 @@ func (pool *WhirlpoolPool) whirlpoolSwapStepCompute(
 	zeroForOne bool,
 ) (sqrtPriceNext cosmath.Int, amountIn cosmath.Int, amountOut cosmath.Int, feeAmount cosmath.Int, err error) {
 
-	// Basic validation
+	// Without liquidity the price moves to the target for free
 	if liquidity.IsZero() {
-		return cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, fmt.Errorf("liquidity is zero")
+		return sqrtPriceTarget, cosmath.ZeroInt(), cosmath.ZeroInt(), cosmath.ZeroInt(), nil
 	}
 
-	baseAmount := amountRemaining.Abs()
-	if baseAmount.IsZero() {
+	if amountRemaining.IsZero() {
 		return sqrtPriceCurrent, cosmath.ZeroInt(), cosmath.ZeroInt(), cosmath.ZeroInt(), nil
 	}
 
-	// Call precise CLMM swap step calculation
-	// This function uses same algorithm as Raydium to ensure mathematical accuracy
-	return whirlpoolSwapStepComputePrecise(
-		sqrtPriceCurrent.BigInt(),
-		sqrtPriceTarget.BigInt(),
-		liquidity.BigInt(),
-		baseAmount.BigInt(),
-		uint32(feeRate.Int64()),
-		zeroForOne,
-	)
-}
-
-// whirlpoolSwapStepComputePrecise - precise CLMM swap step calculation
-// Based on Raydium CLMM's swapStepCompute function, adapted for Whirlpool
-func whirlpoolSwapStepComputePrecise(
-	sqrtPriceX64Current *big.Int,
-	sqrtPriceX64Target *big.Int,
-	liquidity *big.Int,
-	amountRemaining *big.Int,
-	feeRate uint32,
-	zeroForOne bool,
-) (cosmath.Int, cosmath.Int, cosmath.Int, cosmath.Int, error) {
-
-	// Define SwapStep structure to track calculation state
-	swapStep := &WhirlpoolSwapStep{
-		SqrtPriceX64Next: new(big.Int),
-		AmountIn:         new(big.Int),
-		AmountOut:        new(big.Int),
-		FeeAmount:        new(big.Int),
-	}
-
-	zero := new(big.Int)
-	baseInput := amountRemaining.Cmp(zero) >= 0
-
-	// Step 1: Calculate fee rate related constants
-	// FEE_RATE_DENOMINATOR = 1,000,000 (Whirlpool uses parts per million as fee rate unit)
-	FEE_RATE_DENOMINATOR := cosmath.NewInt(1000000)
-
-	if baseInput {
-		// Exact input mode: deduct fees first, then calculate swap
-		feeRateBig := cosmath.NewInt(int64(feeRate))
-		tmp := FEE_RATE_DENOMINATOR.Sub(feeRateBig)
-		amountRemainingSubtractFee := whirlpoolMulDivFloor(
-			cosmath.NewIntFromBigInt(amountRemaining),
-			tmp,
-			FEE_RATE_DENOMINATOR,
-		)
-
-		// Calculate maximum amount that can be swapped within current price range
-		if zeroForOne {
-			// Token A -> Token B
-			swapStep.AmountIn = whirlpoolGetTokenAmountAFromLiquidity(
-				sqrtPriceX64Target, sqrtPriceX64Current, liquidity, true)
-		} else {
-			// Token B -> Token A
-			swapStep.AmountIn = whirlpoolGetTokenAmountBFromLiquidity(
-				sqrtPriceX64Current, sqrtPriceX64Target, liquidity, true)
-		}
-
-		// Determine if target price will be reached
-		if amountRemainingSubtractFee.GTE(cosmath.NewIntFromBigInt(swapStep.AmountIn)) {
-			// Input is large enough, will reach target price
-			swapStep.SqrtPriceX64Next.Set(sqrtPriceX64Target)
-		} else {
-			// Input insufficient, calculate new price
-			swapStep.SqrtPriceX64Next = whirlpoolGetNextSqrtPriceX64FromInput(
-				sqrtPriceX64Current,
-				liquidity,
-				amountRemainingSubtractFee.BigInt(),
-				zeroForOne,
-			)
-		}
-	} else {
-		// Exact output mode: directly calculate required input
-		if zeroForOne {
-			swapStep.AmountOut = whirlpoolGetTokenAmountBFromLiquidity(
-				sqrtPriceX64Target, sqrtPriceX64Current, liquidity, false)
-		} else {
-			swapStep.AmountOut = whirlpoolGetTokenAmountAFromLiquidity(
-				sqrtPriceX64Current, sqrtPriceX64Target, liquidity, false)
-		}
-
-		negativeOne := new(big.Int).SetInt64(-1)
-		amountRemainingNeg := new(big.Int).Mul(amountRemaining, negativeOne)
-
-		if amountRemainingNeg.Cmp(swapStep.AmountOut) >= 0 {
-			swapStep.SqrtPriceX64Next.Set(sqrtPriceX64Target)
-		} else {
-			swapStep.SqrtPriceX64Next = whirlpoolGetNextSqrtPriceX64FromOutput(
-				sqrtPriceX64Current,
-				liquidity,
-				amountRemainingNeg,
-				zeroForOne,
-			)
-		}
-	}
-
-	// Step 2: Recalculate precise input and output amounts
-	reachTargetPrice := swapStep.SqrtPriceX64Next.Cmp(sqrtPriceX64Target) == 0
-
-	if zeroForOne {
-		if !(reachTargetPrice && baseInput) {
-			swapStep.AmountIn = whirlpoolGetTokenAmountAFromLiquidity(
-				swapStep.SqrtPriceX64Next,
-				sqrtPriceX64Current,
-				liquidity,
-				true,
-			)
-		}
-
-		if !(reachTargetPrice && !baseInput) {
-			swapStep.AmountOut = whirlpoolGetTokenAmountBFromLiquidity(
-				swapStep.SqrtPriceX64Next,
-				sqrtPriceX64Current,
-				liquidity,
-				false,
-			)
-		}
-	} else {
-		if !(reachTargetPrice && baseInput) {
-			swapStep.AmountIn = whirlpoolGetTokenAmountBFromLiquidity(
-				sqrtPriceX64Current,
-				swapStep.SqrtPriceX64Next,
-				liquidity,
-				true,
-			)
-		}
-
-		if !(reachTargetPrice && !baseInput) {
-			swapStep.AmountOut = whirlpoolGetTokenAmountAFromLiquidity(
-				sqrtPriceX64Current,
-				swapStep.SqrtPriceX64Next,
-				liquidity,
-				false,
-			)
-		}
-	}
-
-	// Step 3: Calculate fees
-	if baseInput && swapStep.SqrtPriceX64Next.Cmp(sqrtPriceX64Target) != 0 {
-		swapStep.FeeAmount = new(big.Int).Sub(amountRemaining, swapStep.AmountIn)
-	} else {
-		feeRateBig := cosmath.NewInt(int64(feeRate))
-		feeRateSubtracted := FEE_RATE_DENOMINATOR.Sub(feeRateBig)
-		swapStep.FeeAmount = whirlpoolMulDivCeil(
-			cosmath.NewIntFromBigInt(swapStep.AmountIn),
-			feeRateBig,
-			feeRateSubtracted,
-		).BigInt()
-	}
-
-	// Remove safety margin for quote calculation to get accurate price
-	// Safety margin should only apply during actual swap execution, not for price quotes
-	adjustedAmountOut := cosmath.NewIntFromBigInt(swapStep.AmountOut)
-
-	// Ensure minimum output
-	if adjustedAmountOut.IsZero() && swapStep.AmountOut.Cmp(zero) > 0 {
-		adjustedAmountOut = cosmath.NewInt(1)
-	}
-
-	return cosmath.NewIntFromBigInt(swapStep.SqrtPriceX64Next),
-		cosmath.NewIntFromBigInt(swapStep.AmountIn),
-		adjustedAmountOut,
-		cosmath.NewIntFromBigInt(swapStep.FeeAmount), nil
+	// The sign of amountRemaining selects exact-input (positive) or exact-output (negative) mode
+	return clmmmath.ComputeSwapStepInt(sqrtPriceCurrent, sqrtPriceTarget, liquidity, amountRemaining, uint32(feeRate.Int64()), zeroForOne)
 }
 
-// getOrCreateTokenAccount gets or creates user's token account
-func getOrCreateTokenAccount(ctx context.Context, solClient *rpc.Client, userAddr solana.PublicKey, tokenMint solana.PublicKey) (solana.PublicKey, error) {
-	// 1. Derive ATA address
+// getOrCreateTokenAccount returns the user's associated token account for the mint, and an
+// instruction creating it when it doesn't exist yet
+func getOrCreateTokenAccount(ctx context.Context, solClient pkg.RPC, sleeper clock.Sleeper, userAddr solana.PublicKey, tokenMint solana.PublicKey) (solana.PublicKey, solana.Instruction, error) {
 	ata, _, err := solana.FindAssociatedTokenAddress(userAddr, tokenMint)
 	if err != nil {
-		return solana.PublicKey{}, fmt.Errorf("failed to find associated token address: %w", err)
+		return solana.PublicKey{}, nil, fmt.Errorf("failed to find associated token address: %w", err)
 	}
 
-	// 2. Check if ATA account exists
-	accountExists, err := checkAccountExists(ctx, solClient, ata)
-	if err != nil {
-		// If RPC query fails, continue using ATA address, let transaction fail naturally
-		// This avoids blocking normal flow
-		return ata, nil
+	// When the lookup fails the account is created anyway; CreateIdempotent is a no-op for
+	// existing accounts
+	accountExists, err := checkAccountExists(ctx, solClient, sleeper, ata)
+	if err == nil && accountExists {
+		return ata, nil, nil
 	}
 
-	if !accountExists {
-		// ATA doesn't exist, but we still return the address
-		// In practical applications, caller needs to decide whether to add ATA creation instruction
-		// For mainstream tokens (like SOL, USDC), users usually already have ATA
-		return ata, nil
+	createInst, err := createAssociatedTokenAccountInstruction(userAddr, ata, userAddr, tokenMint)
+	if err != nil {
+		return solana.PublicKey{}, nil, fmt.Errorf("failed to build create ATA instruction: %w", err)
 	}
-
-	return ata, nil
+	return ata, createInst, nil
 }
 
-// checkAccountExists checks if account exists (with retry mechanism)
-func checkAccountExists(ctx context.Context, solClient *rpc.Client, accountAddr solana.PublicKey) (bool, error) {
-	// 实现简单的重试机制，应对 RPC 限流
-	maxRetries := 3
-	baseDelay := 100 // 100ms
-
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		// 使用 getAccountInfo 检查账户是否存在
-		_, err := solClient.GetAccountInfo(ctx, accountAddr)
-		if err != nil {
-			// 检查是否是"账户不存在"的错误
-			if isAccountNotFoundError(err) {
-				return false, nil
-			}
-
-			// 检查是否是 RPC 限流错误
-			if isRateLimitError(err) && attempt < maxRetries {
-				// 指数退避重试
-				delay := baseDelay * (1 << attempt) // 100ms, 200ms, 400ms
-				time.Sleep(time.Duration(delay) * time.Millisecond)
-				continue
-			}
-
-			// 其他错误直接返回
-			return false, fmt.Errorf("failed to check account existence after %d attempts: %w", attempt+1, err)
-		}
-
-		// 账户存在，成功返回
-		return true, nil
+// checkAccountExists checks if account exists, retrying rate limits and other transient
+// RPC failures
+func checkAccountExists(ctx context.Context, solClient pkg.RPC, sleeper clock.Sleeper, accountAddr solana.PublicKey) (bool, error) {
+	policy := retry.Policy{MaxAttempts: 4, BaseDelay: 100 * time.Millisecond, Sleeper: sleeper}
+	_, err := retry.Do(ctx, policy, func(ctx context.Context) (*rpc.GetAccountInfoResult, error) {
+		return solClient.GetAccountInfo(ctx, accountAddr)
+	})
+	if solerrors.IsAccountNotFound(err) {
+		return false, nil
 	}
-
-	// 不应该到达这里
-	return false, fmt.Errorf("exhausted retries checking account existence")
-}
-
-// isAccountNotFoundError 判断是否是账户不存在的错误
-func isAccountNotFoundError(err error) bool {
-	// Solana RPC 在账户不存在时返回特定错误信息
-	errorMsg := strings.ToLower(err.Error())
-	return strings.Contains(errorMsg, "account not found") ||
-		strings.Contains(errorMsg, "could not find account") ||
-		strings.Contains(errorMsg, "invalid param")
-}
-
-// isRateLimitError 判断是否是 RPC 限流错误
-func isRateLimitError(err error) bool {
-	// 检测常见的 RPC 限流错误信息
-	errorMsg := strings.ToLower(err.Error())
-	return strings.Contains(errorMsg, "too many requests") ||
-		strings.Contains(errorMsg, "rate limit") ||
-		strings.Contains(errorMsg, "429") ||
-		strings.Contains(errorMsg, "quota exceeded") ||
-		strings.Contains(errorMsg, "timeout") ||
-		strings.Contains(errorMsg, "connection reset")
+	if err != nil {
+		return false, fmt.Errorf("failed to check account existence: %w", err)
+	}
+	return true, nil
 }
 
-// createAssociatedTokenAccountInstruction 创建 ATA 账户的指令 (预留功能)
-// 注意：当前不自动添加创建指令，由调用方决定
+// createAssociatedTokenAccountInstruction builds the associated token program's
+// CreateIdempotent instruction, which succeeds when the account already exists so a swap
+// racing another transaction that creates the ATA doesn't fail
 func createAssociatedTokenAccountInstruction(
 	payer solana.PublicKey,
 	associatedTokenAddress solana.PublicKey,
 	owner solana.PublicKey,
 	tokenMint solana.PublicKey,
 ) (solana.Instruction, error) {
-	// 构建创建 ATA 的指令
-	// 参考: https://github.com/solana-labs/solana-program-library/blob/master/associated-token-account/program/src/instruction.rs
-
+	// Reference: https://github.com/solana-labs/solana-program-library/blob/master/associated-token-account/program/src/instruction.rs
 	accounts := solana.AccountMetaSlice{}
-	accounts.Append(solana.NewAccountMeta(payer, false, true))                   // 0: payer (signer)
+	accounts.Append(solana.NewAccountMeta(payer, true, true))                    // 0: payer (signer)
 	accounts.Append(solana.NewAccountMeta(associatedTokenAddress, true, false))  // 1: associated_token_account (writable)
 	accounts.Append(solana.NewAccountMeta(owner, false, false))                  // 2: owner
 	accounts.Append(solana.NewAccountMeta(tokenMint, false, false))              // 3: mint
 	accounts.Append(solana.NewAccountMeta(solana.SystemProgramID, false, false)) // 4: system_program
 	accounts.Append(solana.NewAccountMeta(TOKEN_PROGRAM_ID, false, false))       // 5: token_program
 
-	// ATA 程序 ID
-	ataProgramID := solana.MustPublicKeyFromBase58("ATokenGPvbdGVxr1b2hvZbsiqW5xWH25efTNsLJA8knL")
-
-	// 创建指令 (无需数据，ATA 程序有默认创建指令)
 	return solana.NewInstruction(
-		ataProgramID,
+		solana.SPLAssociatedTokenAccountProgramID,
 		accounts,
-		[]byte{}, // 空数据，ATA 程序的创建指令不需要参数
+		[]byte{1}, // CreateIdempotent
 	), nil
 }
 
@@ -1090,6 +943,7 @@ func createWhirlpoolSwapV2Instruction(
 	remainingAccountsInfo interface{}, // 暂时用 interface{}
 
 	// 账户
+	programID solana.PublicKey,
 	tokenProgramA solana.PublicKey,
 	tokenProgramB solana.PublicKey,
 	memoProgram solana.PublicKey,
@@ -1182,253 +1036,17 @@ func createWhirlpoolSwapV2Instruction(
 
 	// 3. 创建指令
 	return solana.NewInstruction(
-		ORCA_WHIRLPOOL_PROGRAM_ID,
+		programID,
 		accounts,
 		buf.Bytes(),
 	), nil
 }
 
-// WhirlpoolSwapStep - Whirlpool 交换步骤结构
-type WhirlpoolSwapStep struct {
-	SqrtPriceX64Next *big.Int
-	AmountIn         *big.Int
-	AmountOut        *big.Int
-	FeeAmount        *big.Int
-}
-
-// Whirlpool CLMM 精确计算相关常量
-// U64Resolution 已经在 constants.go 中定义
-
-// whirlpoolMulDivFloor - 乘除法（向下取整）
-func whirlpoolMulDivFloor(a, b, denominator cosmath.Int) cosmath.Int {
-	if denominator.IsZero() {
-		panic("division by zero")
-	}
-	numerator := a.Mul(b)
-	return numerator.Quo(denominator)
-}
-
-// whirlpoolMulDivCeil - 乘除法（向上取整）
-func whirlpoolMulDivCeil(a, b, denominator cosmath.Int) cosmath.Int {
-	if denominator.IsZero() {
-		return cosmath.Int{}
-	}
-	numerator := a.Mul(b).Add(denominator.Sub(cosmath.OneInt()))
-	return numerator.Quo(denominator)
-}
-
-// whirlpoolGetTokenAmountAFromLiquidity - 从流动性计算 Token A 数量
-func whirlpoolGetTokenAmountAFromLiquidity(
-	sqrtPriceX64A *big.Int,
-	sqrtPriceX64B *big.Int,
-	liquidity *big.Int,
-	roundUp bool,
-) *big.Int {
-	// 创建副本避免修改原始值
-	priceA := new(big.Int).Set(sqrtPriceX64A)
-	priceB := new(big.Int).Set(sqrtPriceX64B)
-
-	// 确保 priceA <= priceB
-	if priceA.Cmp(priceB) > 0 {
-		priceA, priceB = priceB, priceA
-	}
-
-	if priceA.Cmp(big.NewInt(0)) <= 0 {
-		panic("sqrtPriceX64A must be greater than 0")
-	}
-
-	// 计算 numerator1 = liquidity << U64Resolution
-	numerator1 := new(big.Int).Lsh(liquidity, U64Resolution)
-	// 计算 numerator2 = priceB - priceA
-	numerator2 := new(big.Int).Sub(priceB, priceA)
-
-	if roundUp {
-		// 向上取整计算
-		temp := whirlpoolMulDivCeil(
-			cosmath.NewIntFromBigInt(numerator1),
-			cosmath.NewIntFromBigInt(numerator2),
-			cosmath.NewIntFromBigInt(priceB),
-		)
-		return whirlpoolMulDivCeil(
-			temp,
-			cosmath.NewIntFromBigInt(big.NewInt(1)),
-			cosmath.NewIntFromBigInt(priceA),
-		).BigInt()
-	} else {
-		// 向下取整计算
-		temp := whirlpoolMulDivFloor(
-			cosmath.NewIntFromBigInt(numerator1),
-			cosmath.NewIntFromBigInt(numerator2),
-			cosmath.NewIntFromBigInt(priceB),
-		)
-		return temp.Quo(cosmath.NewIntFromBigInt(priceA)).BigInt()
-	}
-}
-
-// whirlpoolGetTokenAmountBFromLiquidity - 从流动性计算 Token B 数量
-func whirlpoolGetTokenAmountBFromLiquidity(
-	sqrtPriceX64A *big.Int,
-	sqrtPriceX64B *big.Int,
-	liquidity *big.Int,
-	roundUp bool,
-) *big.Int {
-	// 创建副本避免修改原始值
-	priceA := new(big.Int).Set(sqrtPriceX64A)
-	priceB := new(big.Int).Set(sqrtPriceX64B)
-
-	// 确保 priceA <= priceB
-	if priceA.Cmp(priceB) > 0 {
-		priceA, priceB = priceB, priceA
-	}
-
-	if priceA.Cmp(big.NewInt(0)) <= 0 {
-		panic("sqrtPriceX64A must be greater than 0")
-	}
-
-	// 计算价格差
-	priceDiff := new(big.Int).Sub(priceB, priceA)
-	denominator := new(big.Int).Lsh(big.NewInt(1), U64Resolution)
-
-	if roundUp {
-		return whirlpoolMulDivCeil(
-			cosmath.NewIntFromBigInt(liquidity),
-			cosmath.NewIntFromBigInt(priceDiff),
-			cosmath.NewIntFromBigInt(denominator),
-		).BigInt()
-	} else {
-		return whirlpoolMulDivFloor(
-			cosmath.NewIntFromBigInt(liquidity),
-			cosmath.NewIntFromBigInt(priceDiff),
-			cosmath.NewIntFromBigInt(denominator),
-		).BigInt()
-	}
-}
-
-// whirlpoolGetNextSqrtPriceX64FromInput - 从输入金额计算下个平方根价格
-func whirlpoolGetNextSqrtPriceX64FromInput(
-	sqrtPriceX64Current *big.Int,
-	liquidity *big.Int,
-	amount *big.Int,
-	zeroForOne bool,
-) *big.Int {
-	if sqrtPriceX64Current.Cmp(big.NewInt(0)) <= 0 {
-		panic("sqrtPriceX64Current must be greater than 0")
-	}
-	if liquidity.Cmp(big.NewInt(0)) <= 0 {
-		panic("liquidity must be greater than 0")
-	}
-
-	if amount.Cmp(big.NewInt(0)) == 0 {
-		return sqrtPriceX64Current
-	}
-
-	if zeroForOne {
-		return whirlpoolGetNextSqrtPriceFromTokenAmountARoundingUp(
-			sqrtPriceX64Current, liquidity, amount, true)
-	} else {
-		return whirlpoolGetNextSqrtPriceFromTokenAmountBRoundingDown(
-			sqrtPriceX64Current, liquidity, amount, true)
-	}
-}
-
-// whirlpoolGetNextSqrtPriceX64FromOutput - 从输出金额计算下个平方根价格
-func whirlpoolGetNextSqrtPriceX64FromOutput(
-	sqrtPriceX64Current *big.Int,
-	liquidity *big.Int,
-	amount *big.Int,
-	zeroForOne bool,
-) *big.Int {
-	if sqrtPriceX64Current.Cmp(big.NewInt(0)) <= 0 {
-		panic("sqrtPriceX64Current must be greater than 0")
-	}
-	if liquidity.Cmp(big.NewInt(0)) <= 0 {
-		panic("liquidity must be greater than 0")
-	}
-
-	if zeroForOne {
-		return whirlpoolGetNextSqrtPriceFromTokenAmountBRoundingDown(
-			sqrtPriceX64Current, liquidity, amount, false)
-	} else {
-		return whirlpoolGetNextSqrtPriceFromTokenAmountARoundingUp(
-			sqrtPriceX64Current, liquidity, amount, false)
-	}
-}
-
-// whirlpoolGetNextSqrtPriceFromTokenAmountARoundingUp - 从 Token A 数量计算平方根价格（向上取整）
-func whirlpoolGetNextSqrtPriceFromTokenAmountARoundingUp(
-	sqrtPriceX64 *big.Int,
-	liquidity *big.Int,
-	amount *big.Int,
-	add bool,
-) *big.Int {
-	if amount.Cmp(big.NewInt(0)) == 0 {
-		return sqrtPriceX64
-	}
-
-	liquidityLeftShift := new(big.Int).Lsh(liquidity, U64Resolution)
-
-	if add {
-		numerator1 := liquidityLeftShift
-		denominator := new(big.Int).Add(liquidityLeftShift, new(big.Int).Mul(amount, sqrtPriceX64))
-		if denominator.Cmp(numerator1) >= 0 {
-			return whirlpoolMulDivCeil(
-				cosmath.NewIntFromBigInt(numerator1),
-				cosmath.NewIntFromBigInt(sqrtPriceX64),
-				cosmath.NewIntFromBigInt(denominator),
-			).BigInt()
-		}
-
-		temp := new(big.Int).Div(numerator1, sqrtPriceX64)
-		temp.Add(temp, amount)
-		return whirlpoolMulDivRoundingUp(numerator1, big.NewInt(1), temp)
-	} else {
-		amountMulSqrtPrice := new(big.Int).Mul(amount, sqrtPriceX64)
-		if liquidityLeftShift.Cmp(amountMulSqrtPrice) <= 0 {
-			panic("liquidity must be greater than amount * sqrtPrice")
-		}
-		denominator := new(big.Int).Sub(liquidityLeftShift, amountMulSqrtPrice)
-		return whirlpoolMulDivCeil(
-			cosmath.NewIntFromBigInt(liquidityLeftShift),
-			cosmath.NewIntFromBigInt(sqrtPriceX64),
-			cosmath.NewIntFromBigInt(denominator),
-		).BigInt()
-	}
-}
-
-// whirlpoolGetNextSqrtPriceFromTokenAmountBRoundingDown - 从 Token B 数量计算平方根价格（向下取整）
-func whirlpoolGetNextSqrtPriceFromTokenAmountBRoundingDown(
-	sqrtPriceX64 *big.Int,
-	liquidity *big.Int,
-	amount *big.Int,
-	add bool,
-) *big.Int {
-	deltaY := new(big.Int).Lsh(amount, U64Resolution)
-
-	if add {
-		return new(big.Int).Add(sqrtPriceX64, new(big.Int).Div(deltaY, liquidity))
-	} else {
-		amountDivLiquidity := whirlpoolMulDivRoundingUp(deltaY, big.NewInt(1), liquidity)
-		if sqrtPriceX64.Cmp(amountDivLiquidity) <= 0 {
-			panic("sqrtPriceX64 must be greater than amountDivLiquidity")
-		}
-		return new(big.Int).Sub(sqrtPriceX64, amountDivLiquidity)
-	}
-}
-
-// whirlpoolMulDivRoundingUp - 乘除法向上取整
-func whirlpoolMulDivRoundingUp(a, b, denominator *big.Int) *big.Int {
-	numerator := new(big.Int).Mul(a, b)
-	result := new(big.Int).Div(numerator, denominator)
-	if new(big.Int).Mod(numerator, denominator).Cmp(big.NewInt(0)) != 0 {
-		result.Add(result, big.NewInt(1))
-	}
-	return result
-}
-
 // validateTickArraySequence 确认Swap所需的3个TickArray按方向连续且已初始化
-func (pool *WhirlpoolPool) validateTickArraySequence(ctx context.Context, solClient *rpc.Client, aToB bool) error {
+func (pool *WhirlpoolPool) validateTickArraySequence(ctx context.Context, solClient pkg.RPC, aToB bool) error {
 	// 计算三个TickArray地址
 	ta0, ta1, ta2, err := DeriveMultipleWhirlpoolTickArrayPDAs(
+		pool.GetProgramID(),
 		pool.PoolId,
 		int64(pool.TickCurrentIndex),
 		int64(pool.TickSpacing),
@@ -1438,13 +1056,13 @@ func (pool *WhirlpoolPool) validateTickArraySequence(ctx context.Context, solCli
 		return err
 	}
 	addrs := []solana.PublicKey{ta0, ta1, ta2}
-	results, err := solClient.GetMultipleAccountsWithOpts(ctx, addrs, &rpc.GetMultipleAccountsOpts{Commitment: rpc.CommitmentProcessed})
+	results, err := solClient.GetMultipleAccountsWithOpts(ctx, addrs, sol.MultipleAccountsOpts(ctx, rpc.CommitmentProcessed))
 	if err != nil {
 		return err
 	}
 	// 至少第一个TickArray必须存在
 	if results == nil || len(results.Value) == 0 || results.Value[0] == nil {
-		return fmt.Errorf("primary tick array missing")
+		return fmt.Errorf("primary %w", solerrors.ErrTickArrayMissing)
 	}
 	// 解析存在的数组并检查startIndex连贯性
 	present := make([]*WhirlpoolTickArray, 0, 3)
@@ -1455,7 +1073,7 @@ func (pool *WhirlpoolPool) validateTickArraySequence(ctx context.Context, solCli
 		}
 		ta := &WhirlpoolTickArray{}
 		if err := ta.Decode(v.Data.GetBinary()); err != nil {
-			return fmt.Errorf("failed to decode tick array: %w", err)
+			return fmt.Errorf("%w: %w", errTickArrayDecode, err)
 		}
 		present = append(present, ta)
 	}
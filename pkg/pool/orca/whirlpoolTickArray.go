@@ -1,11 +1,13 @@
 package orca
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"math"
 	"math/big"
 
-	bin "github.com/gagliardetto/binary"
+	"github.com/gtdvccc/SolRouteTmp/pkg/txbuilder"
 	"github.com/gagliardetto/solana-go"
 	"lukechampine.com/uint128"
 )
@@ -17,67 +19,149 @@ type WhirlpoolTickArrayBitmapExtensionType struct {
 	NegativeTickArrayBitmap [][]uint64
 }
 
-// WhirlpoolTickArray - Whirlpool version of tick array, based on CLMM but uses Whirlpool-specific size
+// WhirlpoolTickArray is a decoded fixed or dynamic Whirlpool tick array account
 type WhirlpoolTickArray struct {
-	_                    [8]byte              `bin:"skip"`         // padding
-	PoolId               solana.PublicKey     `bin:"fixed"`        // 32 bytes
-	StartTickIndex       int32                `bin:"le"`           // 4 bytes
-	Ticks                []WhirlpoolTickState `bin:"array,len=88"` // TICK_ARRAY_SIZE=88 for Whirlpool
-	InitializedTickCount uint8                // 1 byte
-	_                    [115]byte            `bin:"skip"` // padding
+	StartTickIndex       int32
+	Ticks                []WhirlpoolTickState // TICK_ARRAY_SIZE entries, one per tick spacing step
+	PoolId               solana.PublicKey     // whirlpool the array belongs to
+	InitializedTickCount uint8
 }
 
-// WhirlpoolTickState - Whirlpool version of tick state, similar structure to CLMM but fields may differ
+// WhirlpoolTickState is a single tick of a tick array
 type WhirlpoolTickState struct {
-	Tick                    int32              `bin:"le"`   // 4 bytes
-	LiquidityNet            int64              `bin:"le"`   // 8 bytes
-	_                       [8]byte            `bin:"skip"` // skip high 8 bytes
-	LiquidityGross          uint128.Uint128    `bin:"le"`   // 16 bytes
-	FeeGrowthOutsideX64A    uint128.Uint128    `bin:"le"`   // 16 bytes
-	FeeGrowthOutsideX64B    uint128.Uint128    `bin:"le"`   // 16 bytes
-	RewardGrowthsOutsideX64 [3]uint128.Uint128 `bin:"le"`   // 48 bytes
-	_                       [52]byte           `bin:"skip"` // padding
+	Initialized bool
+	// LiquidityNet is stored as i128 on-chain; only the low 64 bits are kept, as for Raydium CLMM ticks
+	LiquidityNet            int64
+	LiquidityGross          uint128.Uint128
+	FeeGrowthOutsideX64A    uint128.Uint128
+	FeeGrowthOutsideX64B    uint128.Uint128
+	RewardGrowthsOutsideX64 [3]uint128.Uint128
 }
 
-// Decode parses Whirlpool tick array data
+const (
+	// whirlpoolTickDataSize is a tick without its initialized flag
+	whirlpoolTickDataSize = 112
+	// fixedTickArraySize is discriminator + start index + ticks + whirlpool
+	fixedTickArraySize = 8 + 4 + TICK_ARRAY_SIZE*(1+whirlpoolTickDataSize) + 32
+)
+
+var (
+	fixedTickArrayDiscriminator   = []byte{69, 97, 189, 190, 110, 7, 66, 187}
+	dynamicTickArrayDiscriminator = []byte{17, 216, 246, 142, 225, 199, 218, 56}
+)
+
+// Decode parses a Whirlpool tick array. Both the fixed layout, where every tick takes 113
+// bytes, and the dynamic layout, where uninitialized ticks take a single byte, are supported.
 func (t *WhirlpoolTickArray) Decode(data []byte) error {
-	decoder := bin.NewBinDecoder(data)
+	if len(data) < 8+4+32 {
+		return fmt.Errorf("tick array data too short: %d bytes", len(data))
+	}
+	t.StartTickIndex = int32(binary.LittleEndian.Uint32(data[8:12]))
+	t.Ticks = make([]WhirlpoolTickState, TICK_ARRAY_SIZE)
+	t.InitializedTickCount = 0
 
-	// Decode initial padding
-	var padding [8]byte
-	err := decoder.Decode(&padding)
-	if err != nil {
-		return fmt.Errorf("failed to decode padding: %w", err)
+	switch {
+	case bytes.Equal(data[:8], fixedTickArrayDiscriminator):
+		if len(data) < fixedTickArraySize {
+			return fmt.Errorf("fixed tick array data too short: %d bytes", len(data))
+		}
+		offset := 12
+		for i := range t.Ticks {
+			t.Ticks[i].Initialized = data[offset] != 0
+			t.Ticks[i].decodeData(data[offset+1 : offset+1+whirlpoolTickDataSize])
+			offset += 1 + whirlpoolTickDataSize
+		}
+		copy(t.PoolId[:], data[offset:offset+32])
+	case bytes.Equal(data[:8], dynamicTickArrayDiscriminator):
+		copy(t.PoolId[:], data[12:44])
+		// whirlpool is followed by a u128 bitmap of initialized ticks
+		offset := 44 + 16
+		for i := range t.Ticks {
+			if offset >= len(data) {
+				return fmt.Errorf("dynamic tick array truncated at tick %d", i)
+			}
+			initialized := data[offset] != 0
+			offset++
+			if !initialized {
+				continue
+			}
+			if offset+whirlpoolTickDataSize > len(data) {
+				return fmt.Errorf("dynamic tick array truncated at tick %d", i)
+			}
+			t.Ticks[i].Initialized = true
+			t.Ticks[i].decodeData(data[offset : offset+whirlpoolTickDataSize])
+			offset += whirlpoolTickDataSize
+		}
+	default:
+		return fmt.Errorf("unknown tick array discriminator %v", data[:8])
 	}
 
-	// Decode pool ID
-	err = decoder.Decode(&t.PoolId)
-	if err != nil {
-		return fmt.Errorf("failed to decode pool ID: %w", err)
+	for _, tick := range t.Ticks {
+		if tick.Initialized {
+			t.InitializedTickCount++
+		}
 	}
+	return nil
+}
 
-	// Decode start tick index
-	err = decoder.Decode(&t.StartTickIndex)
-	if err != nil {
-		return fmt.Errorf("failed to decode start tick index: %w", err)
+// decodeData reads liquidity_net, liquidity_gross, fee and reward growths
+func (tick *WhirlpoolTickState) decodeData(data []byte) {
+	tick.LiquidityNet = int64(binary.LittleEndian.Uint64(data[0:8]))
+	tick.LiquidityGross = uint128.FromBytes(data[16:32])
+	tick.FeeGrowthOutsideX64A = uint128.FromBytes(data[32:48])
+	tick.FeeGrowthOutsideX64B = uint128.FromBytes(data[48:64])
+	for i := range tick.RewardGrowthsOutsideX64 {
+		tick.RewardGrowthsOutsideX64[i] = uint128.FromBytes(data[64+16*i : 80+16*i])
 	}
+}
 
-	// Decode ticks array
-	t.Ticks = make([]WhirlpoolTickState, TICK_ARRAY_SIZE)
-	for i := 0; i < TICK_ARRAY_SIZE; i++ {
-		err = decoder.Decode(&t.Ticks[i])
-		if err != nil {
-			return fmt.Errorf("failed to decode tick %d: %w", i, err)
-		}
+// tickOffset returns the index of tickIndex in the array, which may be out of [0, TICK_ARRAY_SIZE)
+func (t *WhirlpoolTickArray) tickOffset(tickIndex, tickSpacing int64) int64 {
+	return int64(floorDivision(int32(tickIndex-int64(t.StartTickIndex)), int32(tickSpacing)))
+}
+
+// nextInitializedTickIndex searches the array for the next initialized tick in the swap
+// direction, like the program's get_next_init_tick_index. a->b searches may stop on the tick
+// at tickIndex itself; b->a searches start one tick spacing above it.
+func (t *WhirlpoolTickArray) nextInitializedTickIndex(tickIndex, tickSpacing int64, aToB bool) (int64, bool, error) {
+	ticksInArray := getWhirlpoolTickCount(tickSpacing)
+	lower := int64(t.StartTickIndex)
+	upper := lower + ticksInArray
+	if !aToB {
+		lower -= tickSpacing
+		upper -= tickSpacing
+	}
+	if tickIndex < lower || tickIndex >= upper {
+		return 0, false, fmt.Errorf("tick %d is outside tick array %d", tickIndex, t.StartTickIndex)
 	}
 
-	// Decode initialized tick count
-	err = decoder.Decode(&t.InitializedTickCount)
-	if err != nil {
-		return fmt.Errorf("failed to decode initialized tick count: %w", err)
+	offset := t.tickOffset(tickIndex, tickSpacing)
+	if !aToB {
+		offset++
 	}
+	for offset >= 0 && offset < TICK_ARRAY_SIZE {
+		if t.Ticks[offset].Initialized {
+			return int64(t.StartTickIndex) + offset*tickSpacing, true, nil
+		}
+		if aToB {
+			offset--
+		} else {
+			offset++
+		}
+	}
+	return 0, false, nil
+}
 
-	return nil
+// tick returns the tick at tickIndex, or nil when it isn't on this array's tick spacing grid
+func (t *WhirlpoolTickArray) tick(tickIndex, tickSpacing int64) *WhirlpoolTickState {
+	if (tickIndex-int64(t.StartTickIndex))%tickSpacing != 0 {
+		return nil
+	}
+	offset := t.tickOffset(tickIndex, tickSpacing)
+	if offset < 0 || offset >= TICK_ARRAY_SIZE {
+		return nil
+	}
+	return &t.Ticks[offset]
 }
 
 // Whirlpool version utility functions - Copied from CLMM implementation with adjusted parameters
@@ -138,7 +222,7 @@ func (pool *WhirlpoolPool) getFirstInitializedWhirlpoolTickArray(zeroForOne bool
 	// TODO: Implement complete bitmap lookup logic, refer to CLMM implementation
 
 	// 3. Construct tick array address (using real PDA derivation)
-	tickArrayPDA, err := DeriveWhirlpoolTickArrayPDA(pool.PoolId, startIndex)
+	tickArrayPDA, err := DeriveWhirlpoolTickArrayPDA(pool.GetProgramID(), pool.PoolId, startIndex)
 	if err != nil {
 		return 0, solana.PublicKey{}, fmt.Errorf("failed to derive tick array PDA: %w", err)
 	}
@@ -260,23 +344,10 @@ func MostSignificantBit(bitNum int, data *big.Int) *int {
 	return LeadingZeros(bitNum, data)
 }
 
-// DeriveWhirlpoolTickArrayPDA derives PDA address for Whirlpool tick array
+// DeriveWhirlpoolTickArrayPDA derives PDA address for Whirlpool tick array under programID
 // Based on Whirlpool source code implementation: seeds = ["tick_array", whirlpool_pubkey, start_tick_index.to_string()]
-func DeriveWhirlpoolTickArrayPDA(whirlpoolPubkey solana.PublicKey, startTickIndex int64) (solana.PublicKey, error) {
-	// Convert start_tick_index to string byte array, consistent with Whirlpool source code
-	// Source code: start_tick_index.to_string().as_bytes()
-	startTickIndexStr := fmt.Sprintf("%d", startTickIndex)
-	startTickIndexBytes := []byte(startTickIndexStr)
-
-	// Build seeds
-	seeds := [][]byte{
-		[]byte(TICK_ARRAY_SEED), // "tick_array"
-		whirlpoolPubkey.Bytes(), // whirlpool address (32 bytes)
-		startTickIndexBytes,     // start_tick_index string bytes
-	}
-
-	// Derive PDA
-	pda, _, err := solana.FindProgramAddress(seeds, ORCA_WHIRLPOOL_PROGRAM_ID)
+func DeriveWhirlpoolTickArrayPDA(programID, whirlpoolPubkey solana.PublicKey, startTickIndex int64) (solana.PublicKey, error) {
+	pda, err := txbuilder.WhirlpoolTickArray(programID, whirlpoolPubkey, startTickIndex)
 	if err != nil {
 		return solana.PublicKey{}, fmt.Errorf("failed to find program address for tick array: %w", err)
 	}
@@ -328,7 +399,7 @@ func getOfficialTickArrayStartIndex(tickIndex int64, tickSpacing int64, offset i
 // DeriveMultipleWhirlpoolTickArrayPDAs derives multiple tick array PDA addresses
 // Based on official Whirlpool implementation
 // Reference: whirlpools/legacy-sdk/whirlpool/src/utils/swap-utils.ts:getTickArrayPublicKeysWithStartTickIndex
-func DeriveMultipleWhirlpoolTickArrayPDAs(whirlpoolPubkey solana.PublicKey, currentTick int64, tickSpacing int64, aToB bool) (tickArray0, tickArray1, tickArray2 solana.PublicKey, err error) {
+func DeriveMultipleWhirlpoolTickArrayPDAs(programID, whirlpoolPubkey solana.PublicKey, currentTick int64, tickSpacing int64, aToB bool) (tickArray0, tickArray1, tickArray2 solana.PublicKey, err error) {
 	// Apply shift like official implementation
 	var shift int64
 	if aToB {
@@ -357,7 +428,7 @@ func DeriveMultipleWhirlpoolTickArrayPDAs(whirlpoolPubkey solana.PublicKey, curr
 		}
 
 		// Derive tick array PDA
-		tickArrayPDA, err := DeriveWhirlpoolTickArrayPDA(whirlpoolPubkey, startIndex)
+		tickArrayPDA, err := DeriveWhirlpoolTickArrayPDA(programID, whirlpoolPubkey, startIndex)
 		if err != nil {
 			return solana.PublicKey{}, solana.PublicKey{}, solana.PublicKey{}, fmt.Errorf("failed to derive tick_array%d: %w", i, err)
 		}
@@ -393,17 +464,10 @@ func floorDivision(dividend, divisor int32) int32 {
 	return dividend / divisor
 }
 
-// DeriveWhirlpoolOraclePDA derives PDA address for Whirlpool Oracle
+// DeriveWhirlpoolOraclePDA derives PDA address for Whirlpool Oracle under programID
 // Based on Solana PDA derivation rules: seeds = ["oracle", whirlpool_pubkey]
-func DeriveWhirlpoolOraclePDA(whirlpoolPubkey solana.PublicKey) (solana.PublicKey, error) {
-	// Build seeds
-	seeds := [][]byte{
-		[]byte("oracle"),        // "oracle"
-		whirlpoolPubkey.Bytes(), // whirlpool address (32 bytes)
-	}
-
-	// Derive PDA
-	pda, _, err := solana.FindProgramAddress(seeds, ORCA_WHIRLPOOL_PROGRAM_ID)
+func DeriveWhirlpoolOraclePDA(programID, whirlpoolPubkey solana.PublicKey) (solana.PublicKey, error) {
+	pda, err := txbuilder.WhirlpoolOracle(programID, whirlpoolPubkey)
 	if err != nil {
 		return solana.PublicKey{}, fmt.Errorf("failed to find program address for oracle: %w", err)
 	}
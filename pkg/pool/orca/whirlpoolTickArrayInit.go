@@ -0,0 +1,97 @@
+package orca
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// InitializeTickArrayDiscriminator is the initialize_tick_array instruction discriminator
+var InitializeTickArrayDiscriminator = []byte{11, 188, 193, 214, 141, 91, 149, 184}
+
+// swapTickArrayStartIndexes returns the start indexes of the tick arrays a swap in the
+// direction is given, in order. Sequences near the tick bounds hold fewer than three.
+func (pool *WhirlpoolPool) swapTickArrayStartIndexes(aToB bool) []int64 {
+	tickSpacing := int64(pool.TickSpacing)
+	// b->a swaps start from the array holding the tick above the current one
+	shift := int64(0)
+	if !aToB {
+		shift = tickSpacing
+	}
+
+	startIndexes := make([]int64, 0, 3)
+	for i := int64(0); i < 3; i++ {
+		offset := i
+		if aToB {
+			offset = -i
+		}
+		startIndex, err := getOfficialTickArrayStartIndex(int64(pool.TickCurrentIndex)+shift, tickSpacing, offset)
+		if err != nil {
+			break
+		}
+		startIndexes = append(startIndexes, startIndex)
+	}
+	return startIndexes
+}
+
+// emptyWhirlpoolTickArray stands in for a tick array account not created yet, which holds no
+// initialized tick
+func emptyWhirlpoolTickArray(poolId solana.PublicKey, startTickIndex int64) WhirlpoolTickArray {
+	return WhirlpoolTickArray{
+		StartTickIndex: int32(startTickIndex),
+		Ticks:          make([]WhirlpoolTickState, TICK_ARRAY_SIZE),
+		PoolId:         poolId,
+	}
+}
+
+// tickArrayInitInstructions returns initialize_tick_array instructions, funded by payer, for
+// the tick arrays a swap in the direction is given that don't exist yet. Swaps given a missing
+// tick array fail, even when they don't reach it.
+func (pool *WhirlpoolPool) tickArrayInitInstructions(ctx context.Context, solClient pkg.RPC, payer solana.PublicKey, aToB bool) ([]solana.Instruction, error) {
+	startIndexes := pool.swapTickArrayStartIndexes(aToB)
+	addresses := make([]solana.PublicKey, len(startIndexes))
+	for i, startIndex := range startIndexes {
+		address, err := DeriveWhirlpoolTickArrayPDA(pool.GetProgramID(), pool.PoolId, startIndex)
+		if err != nil {
+			return nil, err
+		}
+		addresses[i] = address
+	}
+	results, err := solClient.GetMultipleAccountsWithOpts(ctx, addresses, sol.MultipleAccountsOpts(ctx, rpc.CommitmentProcessed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tick arrays of pool %s: %w", pool.PoolId, err)
+	}
+	if len(results.Value) != len(addresses) {
+		return nil, fmt.Errorf("expected %d tick arrays, got %d", len(addresses), len(results.Value))
+	}
+
+	var instructions []solana.Instruction
+	for i, result := range results.Value {
+		if result != nil {
+			continue
+		}
+		instructions = append(instructions, createInitializeTickArrayInstruction(pool.GetProgramID(), pool.PoolId, payer, addresses[i], int32(startIndexes[i])))
+	}
+	return instructions, nil
+}
+
+// createInitializeTickArrayInstruction builds programID's initialize_tick_array instruction,
+// which creates the fixed-size tick array account at startTickIndex with rent paid by funder
+func createInitializeTickArrayInstruction(programID, whirlpool, funder, tickArray solana.PublicKey, startTickIndex int32) solana.Instruction {
+	data := make([]byte, 0, 12)
+	data = append(data, InitializeTickArrayDiscriminator...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(startTickIndex))
+
+	accounts := solana.AccountMetaSlice{
+		solana.NewAccountMeta(whirlpool, false, false),              // 0: whirlpool
+		solana.NewAccountMeta(funder, true, true),                   // 1: funder (writable, signer)
+		solana.NewAccountMeta(tickArray, true, false),               // 2: tick_array (writable)
+		solana.NewAccountMeta(solana.SystemProgramID, false, false), // 3: system_program
+	}
+	return solana.NewInstruction(programID, accounts, data)
+}
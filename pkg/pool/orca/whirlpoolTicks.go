@@ -0,0 +1,50 @@
+package orca
+
+import (
+	"iter"
+	"sort"
+)
+
+// NextInitializedTick returns the next initialized tick of the array in the swap direction:
+// at or below tickIndex for a->b swaps and above it for b->a swaps. found is false when the
+// rest of the array holds no initialized tick.
+func (t *WhirlpoolTickArray) NextInitializedTick(tickIndex, tickSpacing int64, aToB bool) (next int64, found bool, err error) {
+	return t.nextInitializedTickIndex(tickIndex, tickSpacing, aToB)
+}
+
+// InitializedTicks yields the initialized ticks an a->b or b->a swap would cross, in the
+// order it crosses them, keyed by tick index. Only arrays in TickArrayCache are walked, so
+// load them first with a quote or UpdateTickArrays.
+func (pool *WhirlpoolPool) InitializedTicks(aToB bool) iter.Seq2[int32, WhirlpoolTickState] {
+	return func(yield func(int32, WhirlpoolTickState) bool) {
+		tickArrays := make([]WhirlpoolTickArray, 0, len(pool.TickArrayCache))
+		for _, tickArray := range pool.TickArrayCache {
+			tickArrays = append(tickArrays, tickArray)
+		}
+		sort.Slice(tickArrays, func(i, j int) bool {
+			if aToB {
+				return tickArrays[i].StartTickIndex > tickArrays[j].StartTickIndex
+			}
+			return tickArrays[i].StartTickIndex < tickArrays[j].StartTickIndex
+		})
+
+		for _, tickArray := range tickArrays {
+			for i := range tickArray.Ticks {
+				if aToB {
+					i = len(tickArray.Ticks) - 1 - i
+				}
+				tick := tickArray.Ticks[i]
+				index := tickArray.StartTickIndex + int32(i)*int32(pool.TickSpacing)
+				if !tick.Initialized {
+					continue
+				}
+				if (aToB && index > pool.TickCurrentIndex) || (!aToB && index <= pool.TickCurrentIndex) {
+					continue
+				}
+				if !yield(index, tick) {
+					return
+				}
+			}
+		}
+	}
+}
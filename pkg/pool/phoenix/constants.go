@@ -0,0 +1,79 @@
+package phoenix
+
+import (
+	"github.com/gtdvccc/SolRouteTmp/pkg/txbuilder"
+	"github.com/gagliardetto/solana-go"
+)
+
+var (
+	PHOENIX_PROGRAM_ID = solana.MustPublicKeyFromBase58("PhoeNiXZ8ByJGLkxNfZRnkUfjvmuYqLR89jjFHGqdXY")
+	TOKEN_PROGRAM_ID   = solana.MustPublicKeyFromBase58("TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA")
+
+	// LOG_AUTHORITY is the PDA the program signs its event CPIs with
+	LOG_AUTHORITY = mustFindLogAuthority()
+)
+
+// Market header layout, see phoenix::program::accounts::MarketHeader
+const (
+	MarketHeaderSize = 576
+
+	statusOffset           = 8
+	bidsSizeOffset         = 16
+	asksSizeOffset         = 24
+	numSeatsOffset         = 32
+	baseDecimalsOffset     = 40
+	BaseMintOffset         = 48
+	baseVaultOffset        = 80
+	baseLotSizeOffset      = 112
+	quoteDecimalsOffset    = 120
+	QuoteMintOffset        = 128
+	quoteVaultOffset       = 160
+	quoteLotSizeOffset     = 192
+	successorOffset        = 280
+	marketPaddingSize      = 256 // FIFOMarket starts with [u64; 32] of padding
+	marketScalarFieldsSize = 48  // six u64 fields before the order trees
+)
+
+// Order tree layout: a sokoban red-black tree header, its node allocator header, then nodes
+// of four u32 registers, a FIFOOrderId key and a FIFORestingOrder value
+const (
+	treeHeaderSize      = 16
+	allocatorHeaderSize = 16
+	orderNodeSize       = 16 + 16 + 32
+)
+
+// Market statuses
+const (
+	MarketStatusUninitialized uint64 = iota
+	MarketStatusActive
+	MarketStatusPostOnly
+	MarketStatusPaused
+	MarketStatusClosed
+	MarketStatusTombstoned
+)
+
+// Instruction and order packet encodings
+const (
+	swapInstruction uint8 = 0
+
+	orderPacketImmediateOrCancel uint8 = 2
+
+	sideBid uint8 = 0
+	sideAsk uint8 = 1
+
+	selfTradeCancelProvide uint8 = 1
+)
+
+func mustFindLogAuthority() solana.PublicKey {
+	pda, err := txbuilder.PhoenixLogAuthority(PHOENIX_PROGRAM_ID)
+	if err != nil {
+		panic(err)
+	}
+	return pda
+}
+
+// deriveVaultPDA returns the market's vault for mint, which owns its own tokens
+func deriveVaultPDA(market, mint solana.PublicKey) solana.PublicKey {
+	pda, _ := txbuilder.PhoenixVault(PHOENIX_PROGRAM_ID, market, mint)
+	return pda
+}
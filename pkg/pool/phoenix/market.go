@@ -0,0 +1,167 @@
+package phoenix
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// RestingOrder is a maker order on the book. Prices are in ticks and sizes in base lots.
+type RestingOrder struct {
+	PriceInTicks             uint64
+	OrderSequenceNumber      uint64
+	NumBaseLots              uint64
+	LastValidSlot            uint64 // zero never expires
+	LastValidUnixTimestampIn uint64 // seconds, zero never expires
+}
+
+// expired reports whether the program would skip the order at the given slot and time
+func (o RestingOrder) expired(slot uint64, unixTimestamp int64) bool {
+	if o.LastValidSlot != 0 && o.LastValidSlot < slot {
+		return true
+	}
+	return o.LastValidUnixTimestampIn != 0 && unixTimestamp > 0 && o.LastValidUnixTimestampIn < uint64(unixTimestamp)
+}
+
+// Market is the decoded state of a Phoenix market account
+type Market struct {
+	Status        uint64
+	BidsSize      uint64
+	AsksSize      uint64
+	NumSeats      uint64
+	BaseDecimals  uint32
+	BaseMint      solana.PublicKey
+	BaseVault     solana.PublicKey
+	BaseLotSize   uint64 // base atoms per base lot
+	QuoteDecimals uint32
+	QuoteMint     solana.PublicKey
+	QuoteVault    solana.PublicKey
+	QuoteLotSize  uint64 // quote atoms per quote lot
+	Successor     solana.PublicKey
+
+	BaseLotsPerBaseUnit            uint64
+	TickSizeInQuoteLotsPerBaseUnit uint64
+	OrderSequenceNumber            uint64
+	TakerFeeBps                    uint64
+	CollectedQuoteLotFees          uint64
+	UnclaimedQuoteLotFees          uint64
+
+	// Bids are sorted best (highest price) first and asks best (lowest price) first,
+	// oldest first within a price
+	Bids []RestingOrder
+	Asks []RestingOrder
+}
+
+// Decode reads the market header and both sides of the book
+func (m *Market) Decode(data []byte) error {
+	if len(data) < MarketHeaderSize {
+		return fmt.Errorf("market account too short: %d bytes", len(data))
+	}
+	u64 := func(offset int) uint64 { return binary.LittleEndian.Uint64(data[offset:]) }
+	key := func(offset int) solana.PublicKey { return solana.PublicKeyFromBytes(data[offset : offset+32]) }
+
+	m.Status = u64(statusOffset)
+	m.BidsSize = u64(bidsSizeOffset)
+	m.AsksSize = u64(asksSizeOffset)
+	m.NumSeats = u64(numSeatsOffset)
+	m.BaseDecimals = binary.LittleEndian.Uint32(data[baseDecimalsOffset:])
+	m.BaseMint = key(BaseMintOffset)
+	m.BaseVault = key(baseVaultOffset)
+	m.BaseLotSize = u64(baseLotSizeOffset)
+	m.QuoteDecimals = binary.LittleEndian.Uint32(data[quoteDecimalsOffset:])
+	m.QuoteMint = key(QuoteMintOffset)
+	m.QuoteVault = key(quoteVaultOffset)
+	m.QuoteLotSize = u64(quoteLotSizeOffset)
+	m.Successor = key(successorOffset)
+
+	offset := MarketHeaderSize + marketPaddingSize
+	bidsLen := orderTreeSize(m.BidsSize)
+	asksLen := orderTreeSize(m.AsksSize)
+	if m.BidsSize == 0 || m.AsksSize == 0 || uint64(len(data)) < uint64(offset+marketScalarFieldsSize)+bidsLen+asksLen {
+		return fmt.Errorf("market account of %d bytes is too short for %d bids and %d asks", len(data), m.BidsSize, m.AsksSize)
+	}
+	m.BaseLotsPerBaseUnit = u64(offset)
+	m.TickSizeInQuoteLotsPerBaseUnit = u64(offset + 8)
+	m.OrderSequenceNumber = u64(offset + 16)
+	m.TakerFeeBps = u64(offset + 24)
+	m.CollectedQuoteLotFees = u64(offset + 32)
+	m.UnclaimedQuoteLotFees = u64(offset + 40)
+	offset += marketScalarFieldsSize
+	if m.BaseLotSize == 0 || m.QuoteLotSize == 0 || m.BaseLotsPerBaseUnit == 0 || m.TickSizeInQuoteLotsPerBaseUnit == 0 {
+		return fmt.Errorf("market has a zero lot or tick size")
+	}
+	if m.TakerFeeBps >= 10000 {
+		return fmt.Errorf("invalid taker fee %d bps", m.TakerFeeBps)
+	}
+
+	var err error
+	if m.Bids, err = decodeOrderTree(data[offset : uint64(offset)+bidsLen]); err != nil {
+		return fmt.Errorf("failed to decode bids: %w", err)
+	}
+	offset += int(bidsLen)
+	if m.Asks, err = decodeOrderTree(data[offset : uint64(offset)+asksLen]); err != nil {
+		return fmt.Errorf("failed to decode asks: %w", err)
+	}
+
+	sort.Slice(m.Bids, func(i, j int) bool {
+		if m.Bids[i].PriceInTicks != m.Bids[j].PriceInTicks {
+			return m.Bids[i].PriceInTicks > m.Bids[j].PriceInTicks
+		}
+		// Bid sequence numbers are stored bit-inverted so the tree keeps them in time order
+		return m.Bids[i].OrderSequenceNumber > m.Bids[j].OrderSequenceNumber
+	})
+	sort.Slice(m.Asks, func(i, j int) bool {
+		if m.Asks[i].PriceInTicks != m.Asks[j].PriceInTicks {
+			return m.Asks[i].PriceInTicks < m.Asks[j].PriceInTicks
+		}
+		return m.Asks[i].OrderSequenceNumber < m.Asks[j].OrderSequenceNumber
+	})
+	return nil
+}
+
+func orderTreeSize(capacity uint64) uint64 {
+	return treeHeaderSize + allocatorHeaderSize + capacity*orderNodeSize
+}
+
+// decodeOrderTree returns the live orders of a sokoban red-black tree. Nodes are read straight
+// from the allocator, which is 1-indexed and has handed out nodes below bump_index; the ones on
+// its free list (chained through register 0) are skipped.
+func decodeOrderTree(data []byte) ([]RestingOrder, error) {
+	bumpIndex := binary.LittleEndian.Uint32(data[treeHeaderSize+8:])
+	freeListHead := binary.LittleEndian.Uint32(data[treeHeaderSize+12:])
+	nodes := data[treeHeaderSize+allocatorHeaderSize:]
+	if bumpIndex == 0 || uint64(bumpIndex-1)*orderNodeSize > uint64(len(nodes)) {
+		return nil, fmt.Errorf("invalid allocator bump index %d", bumpIndex)
+	}
+	allocated := bumpIndex - 1
+
+	free := make(map[uint32]bool)
+	for freeListHead != 0 && freeListHead < bumpIndex {
+		if free[freeListHead] {
+			return nil, fmt.Errorf("free list cycles at node %d", freeListHead)
+		}
+		free[freeListHead] = true
+		freeListHead = binary.LittleEndian.Uint32(nodes[(freeListHead-1)*orderNodeSize:])
+	}
+
+	orders := make([]RestingOrder, 0, allocated-uint32(len(free)))
+	for index := uint32(1); index <= allocated; index++ {
+		if free[index] {
+			continue
+		}
+		node := nodes[(index-1)*orderNodeSize+16:]
+		order := RestingOrder{
+			PriceInTicks:             binary.LittleEndian.Uint64(node[0:]),
+			OrderSequenceNumber:      binary.LittleEndian.Uint64(node[8:]),
+			NumBaseLots:              binary.LittleEndian.Uint64(node[24:]),
+			LastValidSlot:            binary.LittleEndian.Uint64(node[32:]),
+			LastValidUnixTimestampIn: binary.LittleEndian.Uint64(node[40:]),
+		}
+		if order.NumBaseLots > 0 {
+			orders = append(orders, order)
+		}
+	}
+	return orders, nil
+}
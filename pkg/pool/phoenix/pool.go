@@ -0,0 +1,314 @@
+// Package phoenix implements the Phoenix central limit order book as a routable pool
+package phoenix
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	cosmath "cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+var (
+	_ pkg.Pool             = (*MarketPool)(nil)
+	_ pkg.VaultPool        = (*MarketPool)(nil)
+	_ pkg.DeprecatablePool = (*MarketPool)(nil)
+)
+
+// errInsufficientLiquidity is returned when the live side of the book can't fill a quote
+var errInsufficientLiquidity = solerrors.Mark(errors.New("insufficient liquidity on the book"), solerrors.ErrInsufficientLiquidity)
+
+// MarketPool routes swaps through a Phoenix market by taking liquidity with
+// immediate-or-cancel orders. Quotes walk the resting orders of the opposite side.
+type MarketPool struct {
+	PoolId solana.PublicKey
+	Market
+
+	// Slot and UnixTimestamp of the last refresh, used to skip expired orders
+	Slot          uint64
+	UnixTimestamp int64
+
+	successor string
+}
+
+// NewMarketPool decodes a market account
+func NewMarketPool(id solana.PublicKey, data []byte) (*MarketPool, error) {
+	pool := &MarketPool{PoolId: id}
+	if err := pool.Decode(data); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+func (p *MarketPool) ProtocolName() pkg.ProtocolName {
+	return pkg.ProtocolNamePhoenix
+}
+
+func (p *MarketPool) ProtocolType() pkg.ProtocolType {
+	return pkg.ProtocolTypePhoenix
+}
+
+func (p *MarketPool) GetProgramID() solana.PublicKey {
+	return PHOENIX_PROGRAM_ID
+}
+
+func (p *MarketPool) GetID() string {
+	return p.PoolId.String()
+}
+
+// FeeRateBps returns the market's taker fee in basis points
+func (p *MarketPool) FeeRateBps() uint64 {
+	return p.TakerFeeBps
+}
+
+// GetTokens returns the base and quote token mints
+func (p *MarketPool) GetTokens() (baseMint, quoteMint string) {
+	return p.BaseMint.String(), p.QuoteMint.String()
+}
+
+// TokenVaults returns the market vaults. Each vault is the market's PDA for its mint and is
+// its own token account authority.
+func (p *MarketPool) TokenVaults() []pkg.TokenVault {
+	return []pkg.TokenVault{
+		{Address: p.BaseVault, Mint: p.BaseMint, Authority: deriveVaultPDA(p.PoolId, p.BaseMint)},
+		{Address: p.QuoteVault, Mint: p.QuoteMint, Authority: deriveVaultPDA(p.PoolId, p.QuoteMint)},
+	}
+}
+
+// Deprecation reports markets that don't accept taker orders
+func (p *MarketPool) Deprecation() *pkg.Deprecation {
+	if p.Status == MarketStatusActive {
+		return nil
+	}
+	successor := p.successor
+	if successor == "" && !p.Successor.IsZero() && !p.Successor.Equals(p.PoolId) {
+		successor = p.Successor.String()
+	}
+	return &pkg.Deprecation{Reason: fmt.Sprintf("market status %d does not allow taker orders", p.Status), Successor: successor}
+}
+
+// SetSuccessor records the market that replaced this one
+func (p *MarketPool) SetSuccessor(poolID string) {
+	p.successor = poolID
+}
+
+// refresh reloads the book together with the clock so expired orders can be skipped
+func (p *MarketPool) refresh(ctx context.Context, solClient pkg.RPC) error {
+	accounts := []solana.PublicKey{p.PoolId, solana.SysVarClockPubkey}
+	results, err := solClient.GetMultipleAccountsWithOpts(ctx, accounts, sol.MultipleAccountsOpts(ctx, rpc.CommitmentProcessed))
+	if err != nil {
+		return fmt.Errorf("failed to get market %s: %w", p.PoolId, err)
+	}
+	if len(results.Value) != len(accounts) || results.Value[0] == nil || results.Value[1] == nil {
+		return fmt.Errorf("market %s or the clock sysvar not found", p.PoolId)
+	}
+	var market Market
+	if err := market.Decode(results.Value[0].Data.GetBinary()); err != nil {
+		return fmt.Errorf("failed to decode market %s: %w", p.PoolId, err)
+	}
+	clock := results.Value[1].Data.GetBinary()
+	if len(clock) < 40 {
+		return fmt.Errorf("clock sysvar too short: %d bytes", len(clock))
+	}
+	p.Market = market
+	p.Slot = binary.LittleEndian.Uint64(clock[0:])
+	p.UnixTimestamp = int64(binary.LittleEndian.Uint64(clock[32:]))
+	return nil
+}
+
+// Quote refreshes the book and returns the output of an immediate-or-cancel order spending
+// inputAmount. Input that doesn't make up a whole lot is left unspent.
+func (p *MarketPool) Quote(ctx context.Context, solClient pkg.RPC, inputMint string, inputAmount cosmath.Int) (cosmath.Int, error) {
+	if err := p.refresh(ctx, solClient); err != nil {
+		return cosmath.Int{}, err
+	}
+	return p.QuoteOffline(inputMint, inputAmount)
+}
+
+// QuoteOffline is Quote against the book as last loaded
+func (p *MarketPool) QuoteOffline(inputMint string, inputAmount cosmath.Int) (cosmath.Int, error) {
+	if !inputAmount.IsPositive() {
+		return cosmath.Int{}, fmt.Errorf("input amount must be positive")
+	}
+	switch inputMint {
+	case p.BaseMint.String():
+		baseLots := inputAmount.Quo(cosmath.NewIntFromUint64(p.BaseLotSize))
+		quoteLots, _ := p.sellBaseLots(baseLots)
+		return p.afterTakerFee(quoteLots).Mul(cosmath.NewIntFromUint64(p.QuoteLotSize)), nil
+	case p.QuoteMint.String():
+		budget := p.beforeTakerFee(inputAmount.Quo(cosmath.NewIntFromUint64(p.QuoteLotSize)))
+		baseLots, _ := p.buyWithQuoteLots(budget)
+		return baseLots.Mul(cosmath.NewIntFromUint64(p.BaseLotSize)), nil
+	default:
+		return cosmath.Int{}, fmt.Errorf("mint %s not in market %s", inputMint, p.PoolId)
+	}
+}
+
+// QuoteExactOut refreshes the book and returns the input needed to receive desiredOut,
+// rounded up to whole lots
+func (p *MarketPool) QuoteExactOut(ctx context.Context, solClient pkg.RPC, outputMint string, desiredOut cosmath.Int) (cosmath.Int, error) {
+	if err := p.refresh(ctx, solClient); err != nil {
+		return cosmath.Int{}, err
+	}
+	return p.QuoteExactOutOffline(outputMint, desiredOut)
+}
+
+// QuoteExactOutOffline is QuoteExactOut against the book as last loaded
+func (p *MarketPool) QuoteExactOutOffline(outputMint string, desiredOut cosmath.Int) (cosmath.Int, error) {
+	if !desiredOut.IsPositive() {
+		return cosmath.Int{}, fmt.Errorf("output amount must be positive")
+	}
+	switch outputMint {
+	case p.BaseMint.String():
+		baseLots := ceilDiv(desiredOut, cosmath.NewIntFromUint64(p.BaseLotSize))
+		quoteLots, err := p.quoteLotsToBuy(baseLots)
+		if err != nil {
+			return cosmath.Int{}, err
+		}
+		return p.withTakerFee(quoteLots).Mul(cosmath.NewIntFromUint64(p.QuoteLotSize)), nil
+	case p.QuoteMint.String():
+		quoteLots := p.grossForNet(ceilDiv(desiredOut, cosmath.NewIntFromUint64(p.QuoteLotSize)))
+		baseLots, err := p.baseLotsToSell(quoteLots)
+		if err != nil {
+			return cosmath.Int{}, err
+		}
+		return baseLots.Mul(cosmath.NewIntFromUint64(p.BaseLotSize)), nil
+	default:
+		return cosmath.Int{}, fmt.Errorf("mint %s not in market %s", outputMint, p.PoolId)
+	}
+}
+
+// liveOrders returns the orders of one side that haven't expired
+func (p *MarketPool) liveOrders(orders []RestingOrder) []RestingOrder {
+	live := make([]RestingOrder, 0, len(orders))
+	for _, order := range orders {
+		if !order.expired(p.Slot, p.UnixTimestamp) {
+			live = append(live, order)
+		}
+	}
+	return live
+}
+
+// quoteLotsFor converts base lots at a price to quote lots, rounding down like the matching engine
+func (p *MarketPool) quoteLotsFor(baseLots cosmath.Int, priceInTicks uint64) cosmath.Int {
+	return baseLots.Mul(cosmath.NewIntFromUint64(priceInTicks)).
+		Mul(cosmath.NewIntFromUint64(p.TickSizeInQuoteLotsPerBaseUnit)).
+		Quo(cosmath.NewIntFromUint64(p.BaseLotsPerBaseUnit))
+}
+
+// sellBaseLots matches baseLots against the bids and returns the quote lots received before
+// fees and the base lots filled
+func (p *MarketPool) sellBaseLots(baseLots cosmath.Int) (quoteLots, filled cosmath.Int) {
+	quoteLots, filled = cosmath.ZeroInt(), cosmath.ZeroInt()
+	for _, order := range p.liveOrders(p.Bids) {
+		remaining := baseLots.Sub(filled)
+		if !remaining.IsPositive() {
+			break
+		}
+		fill := cosmath.MinInt(remaining, cosmath.NewIntFromUint64(order.NumBaseLots))
+		quoteLots = quoteLots.Add(p.quoteLotsFor(fill, order.PriceInTicks))
+		filled = filled.Add(fill)
+	}
+	return quoteLots, filled
+}
+
+// buyWithQuoteLots matches a quote lot budget, net of fees, against the asks and returns the
+// base lots bought and the quote lots spent
+func (p *MarketPool) buyWithQuoteLots(budget cosmath.Int) (baseLots, spent cosmath.Int) {
+	baseLots, spent = cosmath.ZeroInt(), cosmath.ZeroInt()
+	for _, order := range p.liveOrders(p.Asks) {
+		remaining := budget.Sub(spent)
+		if !remaining.IsPositive() {
+			break
+		}
+		perBaseLot := cosmath.NewIntFromUint64(order.PriceInTicks).Mul(cosmath.NewIntFromUint64(p.TickSizeInQuoteLotsPerBaseUnit))
+		affordable := remaining.Mul(cosmath.NewIntFromUint64(p.BaseLotsPerBaseUnit)).Quo(perBaseLot)
+		fill := cosmath.MinInt(affordable, cosmath.NewIntFromUint64(order.NumBaseLots))
+		if fill.IsZero() {
+			break
+		}
+		spent = spent.Add(ceilDiv(fill.Mul(perBaseLot), cosmath.NewIntFromUint64(p.BaseLotsPerBaseUnit)))
+		baseLots = baseLots.Add(fill)
+	}
+	return baseLots, spent
+}
+
+// quoteLotsToBuy returns the quote lots, before fees, that buying baseLots from the asks costs
+func (p *MarketPool) quoteLotsToBuy(baseLots cosmath.Int) (cosmath.Int, error) {
+	quoteLots, filled := cosmath.ZeroInt(), cosmath.ZeroInt()
+	for _, order := range p.liveOrders(p.Asks) {
+		remaining := baseLots.Sub(filled)
+		if !remaining.IsPositive() {
+			break
+		}
+		fill := cosmath.MinInt(remaining, cosmath.NewIntFromUint64(order.NumBaseLots))
+		perBaseLot := cosmath.NewIntFromUint64(order.PriceInTicks).Mul(cosmath.NewIntFromUint64(p.TickSizeInQuoteLotsPerBaseUnit))
+		quoteLots = quoteLots.Add(ceilDiv(fill.Mul(perBaseLot), cosmath.NewIntFromUint64(p.BaseLotsPerBaseUnit)))
+		filled = filled.Add(fill)
+	}
+	if filled.LT(baseLots) {
+		return cosmath.Int{}, fmt.Errorf("%w: asks hold %s of %s base lots", errInsufficientLiquidity, filled, baseLots)
+	}
+	return quoteLots, nil
+}
+
+// baseLotsToSell returns the base lots that must be sold into the bids to receive quoteLots
+// before fees
+func (p *MarketPool) baseLotsToSell(quoteLots cosmath.Int) (cosmath.Int, error) {
+	received, sold := cosmath.ZeroInt(), cosmath.ZeroInt()
+	for _, order := range p.liveOrders(p.Bids) {
+		remaining := quoteLots.Sub(received)
+		if !remaining.IsPositive() {
+			break
+		}
+		size := cosmath.NewIntFromUint64(order.NumBaseLots)
+		orderQuoteLots := p.quoteLotsFor(size, order.PriceInTicks)
+		if orderQuoteLots.LT(remaining) {
+			received = received.Add(orderQuoteLots)
+			sold = sold.Add(size)
+			continue
+		}
+		perBaseLot := cosmath.NewIntFromUint64(order.PriceInTicks).Mul(cosmath.NewIntFromUint64(p.TickSizeInQuoteLotsPerBaseUnit))
+		fill := cosmath.MinInt(size, ceilDiv(remaining.Mul(cosmath.NewIntFromUint64(p.BaseLotsPerBaseUnit)), perBaseLot))
+		received = received.Add(p.quoteLotsFor(fill, order.PriceInTicks))
+		sold = sold.Add(fill)
+	}
+	if received.LT(quoteLots) {
+		return cosmath.Int{}, fmt.Errorf("%w: bids pay %s of %s quote lots", errInsufficientLiquidity, received, quoteLots)
+	}
+	return sold, nil
+}
+
+// afterTakerFee deducts the taker fee, rounded up, from the quote lots a sell receives
+func (p *MarketPool) afterTakerFee(quoteLots cosmath.Int) cosmath.Int {
+	return quoteLots.Sub(ceilDiv(quoteLots.MulRaw(int64(p.TakerFeeBps)), cosmath.NewInt(10000)))
+}
+
+// beforeTakerFee is the part of a buy's quote lot budget left to match once the fee is reserved
+func (p *MarketPool) beforeTakerFee(budget cosmath.Int) cosmath.Int {
+	return budget.MulRaw(10000).QuoRaw(int64(10000 + p.TakerFeeBps))
+}
+
+// withTakerFee adds the taker fee, rounded up, to the quote lots a buy matches
+func (p *MarketPool) withTakerFee(quoteLots cosmath.Int) cosmath.Int {
+	return ceilDiv(quoteLots.MulRaw(int64(10000+p.TakerFeeBps)), cosmath.NewInt(10000))
+}
+
+// grossForNet returns the smallest quote lot amount a sell must match to net quoteLots after fees
+func (p *MarketPool) grossForNet(quoteLots cosmath.Int) cosmath.Int {
+	gross := ceilDiv(quoteLots.MulRaw(10000), cosmath.NewInt(int64(10000-p.TakerFeeBps)))
+	for p.afterTakerFee(gross).LT(quoteLots) {
+		gross = gross.AddRaw(1)
+	}
+	return gross
+}
+
+func ceilDiv(a, b cosmath.Int) cosmath.Int {
+	return a.Add(b).SubRaw(1).Quo(b)
+}
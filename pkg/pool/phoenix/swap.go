@@ -0,0 +1,124 @@
+package phoenix
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	cosmath "cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+)
+
+// BuildSwapInstructions sends an immediate-or-cancel order that spends inputAmount, rounded
+// down to whole lots, and fails unless at least minOut is filled. The user's base and quote
+// associated token accounts must exist.
+func (p *MarketPool) BuildSwapInstructions(ctx context.Context, solClient pkg.RPC, user solana.PublicKey, inputMint string, inputAmount cosmath.Int, minOut cosmath.Int) ([]solana.Instruction, error) {
+	var order immediateOrCancel
+	switch inputMint {
+	case p.BaseMint.String():
+		order.side = sideAsk
+		order.numBaseLots = inputAmount.Quo(cosmath.NewIntFromUint64(p.BaseLotSize))
+		order.minQuoteLotsToFill = ceilDiv(minOut, cosmath.NewIntFromUint64(p.QuoteLotSize))
+	case p.QuoteMint.String():
+		order.side = sideBid
+		order.numQuoteLots = inputAmount.Quo(cosmath.NewIntFromUint64(p.QuoteLotSize))
+		order.minBaseLotsToFill = ceilDiv(minOut, cosmath.NewIntFromUint64(p.BaseLotSize))
+	default:
+		return nil, fmt.Errorf("mint %s not in market %s", inputMint, p.PoolId)
+	}
+	if !order.numBaseLots.IsPositive() && !order.numQuoteLots.IsPositive() {
+		return nil, fmt.Errorf("input amount %s is smaller than one lot", inputAmount)
+	}
+	inst, err := p.swapInstruction(user, order)
+	if err != nil {
+		return nil, err
+	}
+	return []solana.Instruction{inst}, nil
+}
+
+// BuildSwapInstructionsExactOut sends an immediate-or-cancel order for amountOut, rounded up
+// to whole lots, that spends at most maxIn
+func (p *MarketPool) BuildSwapInstructionsExactOut(ctx context.Context, solClient pkg.RPC, user solana.PublicKey, outputMint string, amountOut cosmath.Int, maxIn cosmath.Int) ([]solana.Instruction, error) {
+	var order immediateOrCancel
+	switch outputMint {
+	case p.BaseMint.String():
+		order.side = sideBid
+		order.numBaseLots = ceilDiv(amountOut, cosmath.NewIntFromUint64(p.BaseLotSize))
+		order.numQuoteLots = maxIn.Quo(cosmath.NewIntFromUint64(p.QuoteLotSize))
+		order.minBaseLotsToFill = order.numBaseLots
+	case p.QuoteMint.String():
+		order.side = sideAsk
+		order.numBaseLots = maxIn.Quo(cosmath.NewIntFromUint64(p.BaseLotSize))
+		order.minQuoteLotsToFill = ceilDiv(amountOut, cosmath.NewIntFromUint64(p.QuoteLotSize))
+	default:
+		return nil, fmt.Errorf("mint %s not in market %s", outputMint, p.PoolId)
+	}
+	if !order.numBaseLots.IsPositive() || (order.side == sideBid && !order.numQuoteLots.IsPositive()) {
+		return nil, fmt.Errorf("maximum input %s is smaller than one lot", maxIn)
+	}
+	inst, err := p.swapInstruction(user, order)
+	if err != nil {
+		return nil, err
+	}
+	return []solana.Instruction{inst}, nil
+}
+
+// immediateOrCancel is an OrderPacket::ImmediateOrCancel without a limit price; zero budgets
+// are unlimited
+type immediateOrCancel struct {
+	side               uint8
+	numBaseLots        cosmath.Int
+	numQuoteLots       cosmath.Int
+	minBaseLotsToFill  cosmath.Int
+	minQuoteLotsToFill cosmath.Int
+}
+
+func (o immediateOrCancel) encode() ([]byte, error) {
+	lots := []cosmath.Int{o.numBaseLots, o.numQuoteLots, o.minBaseLotsToFill, o.minQuoteLotsToFill}
+	data := []byte{swapInstruction, orderPacketImmediateOrCancel, o.side, 0} // price_in_ticks: None
+	for _, n := range lots {
+		if n.IsNil() {
+			n = cosmath.ZeroInt()
+		}
+		if !n.IsUint64() {
+			return nil, fmt.Errorf("order size %s does not fit in u64", n)
+		}
+		data = binary.LittleEndian.AppendUint64(data, n.Uint64())
+	}
+	data = append(data, selfTradeCancelProvide)
+	data = append(data, 0)                   // match_limit: None
+	data = append(data, make([]byte, 16)...) // client_order_id: 0
+	data = append(data, 0)                   // use_only_deposited_funds: false
+	data = append(data, 0, 0)                // last_valid_slot, last_valid_unix_timestamp_in_seconds: None
+	return data, nil
+}
+
+// swapInstruction builds the Swap instruction, which settles against the user's associated
+// token accounts rather than a seat
+func (p *MarketPool) swapInstruction(user solana.PublicKey, order immediateOrCancel) (solana.Instruction, error) {
+	data, err := order.encode()
+	if err != nil {
+		return nil, err
+	}
+	userBase, _, err := solana.FindAssociatedTokenAddress(user, p.BaseMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive base token account: %w", err)
+	}
+	userQuote, _, err := solana.FindAssociatedTokenAddress(user, p.QuoteMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive quote token account: %w", err)
+	}
+	accounts := solana.AccountMetaSlice{
+		solana.NewAccountMeta(PHOENIX_PROGRAM_ID, false, false),
+		solana.NewAccountMeta(LOG_AUTHORITY, false, false),
+		solana.NewAccountMeta(p.PoolId, true, false),
+		solana.NewAccountMeta(user, false, true),
+		solana.NewAccountMeta(userBase, true, false),
+		solana.NewAccountMeta(userQuote, true, false),
+		solana.NewAccountMeta(p.BaseVault, true, false),
+		solana.NewAccountMeta(p.QuoteVault, true, false),
+		solana.NewAccountMeta(TOKEN_PROGRAM_ID, false, false),
+	}
+	return solana.NewInstruction(PHOENIX_PROGRAM_ID, accounts, data), nil
+}
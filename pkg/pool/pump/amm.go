@@ -1,3 +1,4 @@
+// Package pump implements PumpSwap AMM pools
 package pump
 
 import (
@@ -7,6 +8,8 @@ import (
 	"fmt"
 
 	"github.com/gtdvccc/SolRouteTmp/pkg"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
 	"github.com/gtdvccc/SolRouteTmp/utils"
 	"cosmossdk.io/math"
 	bin "github.com/gagliardetto/binary"
@@ -50,6 +53,9 @@ type PumpAMMPool struct {
 	QuoteAmount      math.Int
 	UserBaseAccount  solana.PublicKey
 	UserQuoteAccount solana.PublicKey
+	// Configs supplies the global config's fee recipients and rates; without it swaps read
+	// the config each time they are built and quotes use DefaultFeeRate
+	Configs *GlobalConfigCache `bin:"-" json:"-"`
 }
 
 func (pool *PumpAMMPool) ProtocolName() pkg.ProtocolName {
@@ -86,6 +92,9 @@ func (p *PumpAMMPool) Decode(data []byte) error {
 	if len(data) < PoolDataSize {
 		return fmt.Errorf("data too short: expected %d bytes, got %d", PoolDataSize, len(data))
 	}
+	// The decoder runs on past the account layout, so the pool's ID is kept aside
+	id := p.PoolId
+	defer func() { p.PoolId = id }()
 	dec := bin.NewBinDecoder(data)
 	return dec.Decode(p)
 }
@@ -135,22 +144,76 @@ func (l *PumpAMMPool) GetTokens() (string, string) {
 	return l.BaseMint.String(), l.QuoteMint.String()
 }
 
+// TokenVaults returns the pool token accounts, which are controlled by the pool
+func (l *PumpAMMPool) TokenVaults() []pkg.TokenVault {
+	return []pkg.TokenVault{
+		{Address: l.PoolBaseTokenAccount, Mint: l.BaseMint, Authority: l.PoolId},
+		{Address: l.PoolQuoteTokenAccount, Mint: l.QuoteMint, Authority: l.PoolId},
+	}
+}
+
 func (s *PumpAMMPool) BuildSwapInstructions(
 	ctx context.Context,
-	solClient *rpc.Client,
+	solClient pkg.RPC,
 	user solana.PublicKey,
 	inputMint string,
 	inputAmount math.Int,
 	minOut math.Int,
 ) ([]solana.Instruction, error) {
+	config, err := s.globalConfig(ctx, solClient)
+	if err != nil {
+		return nil, err
+	}
 	if inputMint == s.BaseMint.String() {
-		return s.buyInAMMPool(user, s, inputAmount, minOut)
+		return s.buyInAMMPool(user, s, config, inputAmount, minOut)
 	} else {
-		return s.sellInAMMPool(user, s, inputAmount, minOut)
+		return s.sellInAMMPool(user, s, config, inputAmount, minOut)
+	}
+}
+
+// globalConfig returns the global config swaps are built with
+func (s *PumpAMMPool) globalConfig(ctx context.Context, solClient pkg.RPC) (*GlobalConfig, error) {
+	configs := s.Configs
+	if configs == nil {
+		configs = NewGlobalConfigCache()
+	}
+	return configs.Get(ctx, solClient)
+}
+
+// hasCoinCreator reports whether the pool pays a coin creator fee
+func (s *PumpAMMPool) hasCoinCreator() bool {
+	return !s.CoinCreator.IsZero()
+}
+
+// FeeRateBps returns the swap fee of the last global config read, or DefaultFeeRate before one
+func (s *PumpAMMPool) FeeRateBps() uint64 {
+	if s.Configs != nil {
+		if config := s.Configs.Cached(); config != nil {
+			return config.FeeBps(s.hasCoinCreator())
+		}
+	}
+	return uint64(DefaultFeeRate * 10_000)
+}
+
+// feeMultiplier returns the share of the input left after fees, scaled by BaseDecimal
+func (s *PumpAMMPool) feeMultiplier() math.Int {
+	return math.NewIntFromUint64((10_000 - s.FeeRateBps()) * uint64(BaseDecimalInt) / 10_000)
+}
+
+// feeAccountMetas returns the protocol fee recipient and its quote token account the swap pays
+func (s *PumpAMMPool) feeAccountMetas(config *GlobalConfig) (*solana.AccountMeta, *solana.AccountMeta, error) {
+	recipient, err := config.FeeRecipient(s.PoolId)
+	if err != nil {
+		return nil, nil, err
+	}
+	recipientATA, _, err := solana.FindAssociatedTokenAddress(recipient, s.QuoteMint)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find fee recipient token account: %w", err)
 	}
+	return solana.NewAccountMeta(recipient, false, false), solana.NewAccountMeta(recipientATA, true, false), nil
 }
 
-func (s *PumpAMMPool) buyInAMMPool(userAddr solana.PublicKey, pool *PumpAMMPool,
+func (s *PumpAMMPool) buyInAMMPool(userAddr solana.PublicKey, pool *PumpAMMPool, config *GlobalConfig,
 	maxInputAmountWithDecimals math.Int, outAmountWithDecimals math.Int) ([]solana.Instruction, error) {
 	// Initialize instruction array
 	instrs := []solana.Instruction{}
@@ -178,8 +241,12 @@ func (s *PumpAMMPool) buyInAMMPool(userAddr solana.PublicKey, pool *PumpAMMPool,
 	inst.AccountMetaSlice[6] = solana.NewAccountMeta(pool.UserQuoteAccount, true, false)
 	inst.AccountMetaSlice[7] = solana.NewAccountMeta(pool.PoolBaseTokenAccount, true, false)
 	inst.AccountMetaSlice[8] = solana.NewAccountMeta(pool.PoolQuoteTokenAccount, true, false)
-	inst.AccountMetaSlice[9] = solana.NewAccountMeta(PumpProtocolFeeRecipient, false, false)
-	inst.AccountMetaSlice[10] = solana.NewAccountMeta(PumpProtocolFeeRecipientTokenAccount, true, false)
+	recipient, recipientATA, err := pool.feeAccountMetas(config)
+	if err != nil {
+		return nil, err
+	}
+	inst.AccountMetaSlice[9] = recipient
+	inst.AccountMetaSlice[10] = recipientATA
 	tokenProgramID := solana.MustPublicKeyFromBase58("TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA")
 	inst.AccountMetaSlice[11] = solana.NewAccountMeta(tokenProgramID, false, false)
 	inst.AccountMetaSlice[12] = solana.NewAccountMeta(tokenProgramID, false, false)
@@ -188,7 +255,7 @@ func (s *PumpAMMPool) buyInAMMPool(userAddr solana.PublicKey, pool *PumpAMMPool,
 	inst.AccountMetaSlice[15] = solana.NewAccountMeta(solana.MustPublicKeyFromBase58("GS4CU59F31iL7aR2Q8zVS8DRrcRnXX1yjQ66TqNVQnaR"), false, false)
 	inst.AccountMetaSlice[16] = solana.NewAccountMeta(PumpSwapProgramID, false, false)
 	if pool.CoinCreator != solana.MustPublicKeyFromBase58("11111111111111111111111111111111") {
-		ata, err := GetCoinCreatorVaultATA(pool.CoinCreator)
+		ata, err := CoinCreatorVaultATA(pool.CoinCreator, pool.QuoteMint)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get coin creator vault ata: %w", err)
 		}
@@ -205,7 +272,7 @@ func (s *PumpAMMPool) buyInAMMPool(userAddr solana.PublicKey, pool *PumpAMMPool,
 }
 
 func (s *PumpAMMPool) sellInAMMPool(userAddr solana.PublicKey,
-	pool *PumpAMMPool, baseAmountIn math.Int, minQuoteAmountOut math.Int) ([]solana.Instruction, error) {
+	pool *PumpAMMPool, config *GlobalConfig, baseAmountIn math.Int, minQuoteAmountOut math.Int) ([]solana.Instruction, error) {
 	instrs := []solana.Instruction{}
 
 	inst := SellSwapInstruction{
@@ -230,8 +297,12 @@ func (s *PumpAMMPool) sellInAMMPool(userAddr solana.PublicKey,
 	inst.AccountMetaSlice[6] = solana.NewAccountMeta(pool.UserQuoteAccount, true, false)
 	inst.AccountMetaSlice[7] = solana.NewAccountMeta(pool.PoolBaseTokenAccount, true, false)
 	inst.AccountMetaSlice[8] = solana.NewAccountMeta(pool.PoolQuoteTokenAccount, true, false)
-	inst.AccountMetaSlice[9] = solana.NewAccountMeta(PumpProtocolFeeRecipient, false, false)
-	inst.AccountMetaSlice[10] = solana.NewAccountMeta(PumpProtocolFeeRecipientTokenAccount, true, false)
+	recipient, recipientATA, err := pool.feeAccountMetas(config)
+	if err != nil {
+		return nil, err
+	}
+	inst.AccountMetaSlice[9] = recipient
+	inst.AccountMetaSlice[10] = recipientATA
 	tokenProgramID := solana.MustPublicKeyFromBase58("TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA")
 	inst.AccountMetaSlice[11] = solana.NewAccountMeta(tokenProgramID, false, false)
 	inst.AccountMetaSlice[12] = solana.NewAccountMeta(tokenProgramID, false, false)
@@ -240,7 +311,7 @@ func (s *PumpAMMPool) sellInAMMPool(userAddr solana.PublicKey,
 	inst.AccountMetaSlice[15] = solana.NewAccountMeta(solana.MustPublicKeyFromBase58("GS4CU59F31iL7aR2Q8zVS8DRrcRnXX1yjQ66TqNVQnaR"), false, false)
 	inst.AccountMetaSlice[16] = solana.NewAccountMeta(PumpSwapProgramID, false, false)
 	if pool.CoinCreator != solana.MustPublicKeyFromBase58("11111111111111111111111111111111") {
-		ata, err := GetCoinCreatorVaultATA(pool.CoinCreator)
+		ata, err := CoinCreatorVaultATA(pool.CoinCreator, pool.QuoteMint)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get coin creator vault ata: %w", err)
 		}
@@ -336,23 +407,118 @@ func (inst *SellSwapInstruction) Data() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-func (pool *PumpAMMPool) Quote(ctx context.Context, solClient *rpc.Client, inputMint string, inputAmount math.Int) (math.Int, error) {
-	// update pool data first
+// QuoteAccounts returns the pool token accounts Quote reads the reserves from
+func (pool *PumpAMMPool) QuoteAccounts(inputMint string) []solana.PublicKey {
+	return []solana.PublicKey{pool.PoolBaseTokenAccount, pool.PoolQuoteTokenAccount}
+}
+
+func (pool *PumpAMMPool) Quote(ctx context.Context, solClient pkg.RPC, inputMint string, inputAmount math.Int) (math.Int, error) {
+	if err := pool.updateReserves(ctx, solClient); err != nil {
+		return math.NewInt(0), err
+	}
+
+	feeMultiplier := pool.feeMultiplier()
+
+	// Calculate k = baseAmount * quoteAmount
+	k := pool.BaseAmount.Mul(pool.QuoteAmount)
+
+	if inputMint == pool.BaseMint.String() {
+		// Calculate newBase = baseAmount + amountWithFee
+		newBase := pool.BaseAmount.Add(inputAmount.Mul(feeMultiplier).Quo(BaseDecimal))
+		// Calculate newQuote = k / newBase
+		newQuote := k.Quo(newBase)
+		priceBaseToQuote := pool.QuoteAmount.Sub(newQuote)
+		return priceBaseToQuote, nil
+	} else {
+		// Calculate newQuote = quoteAmount + amountWithFee
+		newQuote := pool.QuoteAmount.Add(inputAmount.Mul(feeMultiplier).Quo(BaseDecimal))
+		// Calculate newBase = k / newQuote
+		newBase := k.Quo(newQuote)
+		priceQuoteToBase := pool.BaseAmount.Sub(newBase)
+		return priceQuoteToBase, nil
+	}
+}
+
+// QuoteExactOut calculates the input amount required to receive desiredOut of outputMint
+func (pool *PumpAMMPool) QuoteExactOut(ctx context.Context, solClient pkg.RPC, outputMint string, desiredOut math.Int) (math.Int, error) {
+	if !desiredOut.IsPositive() {
+		return math.NewInt(0), fmt.Errorf("output amount must be positive")
+	}
+	if err := pool.updateReserves(ctx, solClient); err != nil {
+		return math.NewInt(0), err
+	}
+
+	reserveIn, reserveOut := pool.QuoteAmount, pool.BaseAmount
+	if outputMint == pool.QuoteMint.String() {
+		reserveIn, reserveOut = pool.BaseAmount, pool.QuoteAmount
+	}
+	if desiredOut.GTE(reserveOut) {
+		return math.NewInt(0), fmt.Errorf("%w: requested %s, reserve %s", solerrors.ErrInsufficientLiquidity, desiredOut, reserveOut)
+	}
+
+	// Invert Quote: the new input reserve must satisfy k / newReserveIn <= reserveOut - desiredOut
+	k := pool.BaseAmount.Mul(pool.QuoteAmount)
+	newReserveOut := reserveOut.Sub(desiredOut)
+	newReserveIn := k.Add(newReserveOut).SubRaw(1).Quo(newReserveOut)
+	amountInWithFee := newReserveIn.Sub(reserveIn)
+
+	feeMultiplier := pool.feeMultiplier()
+	amountIn := amountInWithFee.Mul(BaseDecimal).Add(feeMultiplier).SubRaw(1).Quo(feeMultiplier)
+	return amountIn, nil
+}
+
+// BuildSwapInstructionsExactOut builds a swap that receives amountOut of outputMint.
+// Buying base maps directly onto the buy instruction; pump has no exact-output sell, so
+// selling for quote spends the quoted input and requires at least amountOut back.
+func (s *PumpAMMPool) BuildSwapInstructionsExactOut(
+	ctx context.Context,
+	solClient pkg.RPC,
+	user solana.PublicKey,
+	outputMint string,
+	amountOut math.Int,
+	maxIn math.Int,
+) ([]solana.Instruction, error) {
+	config, err := s.globalConfig(ctx, solClient)
+	if err != nil {
+		return nil, err
+	}
+	if outputMint == s.BaseMint.String() {
+		return s.buyInAMMPool(user, s, config, maxIn, amountOut)
+	}
+
+	amountIn, err := s.QuoteExactOut(ctx, solClient, outputMint, amountOut)
+	if err != nil {
+		return nil, fmt.Errorf("failed to quote sell input: %w", err)
+	}
+	if amountIn.GT(maxIn) {
+		return nil, fmt.Errorf("required input %s exceeds max input %s", amountIn, maxIn)
+	}
+	return s.sellInAMMPool(user, s, config, amountIn, amountOut)
+}
+
+// SpotPrice returns the price of the pool's reserves after refreshing them
+func (pool *PumpAMMPool) SpotPrice(ctx context.Context, solClient pkg.RPC) (pkg.SpotPrice, error) {
+	if err := pool.updateReserves(ctx, solClient); err != nil {
+		return pkg.SpotPrice{}, err
+	}
+	return pkg.ReserveSpotPrice(pool.BaseAmount, pool.QuoteAmount)
+}
+
+// updateReserves refreshes the pool token account balances
+func (pool *PumpAMMPool) updateReserves(ctx context.Context, solClient pkg.RPC) error {
 	accounts := make([]solana.PublicKey, 0)
 	accounts = append(accounts, pool.PoolBaseTokenAccount)
 	accounts = append(accounts, pool.PoolQuoteTokenAccount)
 	results, err := solClient.GetMultipleAccountsWithOpts(ctx,
 		accounts,
-		&rpc.GetMultipleAccountsOpts{
-			Commitment: rpc.CommitmentProcessed,
-		},
+		sol.MultipleAccountsOpts(ctx, rpc.CommitmentProcessed),
 	)
 	if err != nil {
-		return math.NewInt(0), fmt.Errorf("batch request failed: %v", err)
+		return fmt.Errorf("batch request failed: %v", err)
 	}
 	for i, result := range results.Value {
 		if result == nil {
-			return math.NewInt(0), fmt.Errorf("result is nil, account: %v", accounts[i].String())
+			return fmt.Errorf("result is nil, account: %v", accounts[i].String())
 		}
 		accountKey := accounts[i].String()
 		if pool.PoolBaseTokenAccount.String() == accountKey {
@@ -367,26 +533,5 @@ func (pool *PumpAMMPool) Quote(ctx context.Context, solClient *rpc.Client, input
 			pool.QuoteAmount = amount
 		}
 	}
-
-	feeRate := 1 - DefaultFeeRate
-	feeMultiplier := math.NewInt(int64(feeRate * float64(BaseDecimalInt)))
-
-	// Calculate k = baseAmount * quoteAmount
-	k := pool.BaseAmount.Mul(pool.QuoteAmount)
-
-	if inputMint == pool.BaseMint.String() {
-		// Calculate newBase = baseAmount + amountWithFee
-		newBase := pool.BaseAmount.Add(inputAmount.Mul(feeMultiplier).Quo(BaseDecimal))
-		// Calculate newQuote = k / newBase
-		newQuote := k.Quo(newBase)
-		priceBaseToQuote := pool.QuoteAmount.Sub(newQuote)
-		return priceBaseToQuote, nil
-	} else {
-		// Calculate newQuote = quoteAmount + amountWithFee
-		newQuote := pool.QuoteAmount.Add(inputAmount.Mul(feeMultiplier).Quo(BaseDecimal))
-		// Calculate newBase = k / newQuote
-		newBase := k.Quo(newQuote)
-		priceQuoteToBase := pool.BaseAmount.Sub(newBase)
-		return priceQuoteToBase, nil
-	}
+	return nil
 }
@@ -0,0 +1,158 @@
+package pump
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	// DefaultGlobalConfigMaxAge is how long a read global config is used before reading it
+	// again, so rotated fee recipients are picked up
+	DefaultGlobalConfigMaxAge = 5 * time.Minute
+
+	// globalConfigRecipients is how many protocol fee recipients the global config holds
+	globalConfigRecipients = 8
+	// globalConfigRecipientsOffset is the offset of the protocol fee recipients, after the
+	// discriminator, admin, LP and protocol fees and disable flags
+	globalConfigRecipientsOffset = 8 + 32 + 8 + 8 + 1
+	// globalConfigCreatorFeeOffset is the offset of the coin creator fee, which configs from
+	// before creator fees lack
+	globalConfigCreatorFeeOffset = globalConfigRecipientsOffset + globalConfigRecipients*32
+)
+
+// GlobalConfig is the PumpSwap global config account: fee rates and the protocol fee
+// recipients swaps pay
+type GlobalConfig struct {
+	Admin solana.PublicKey
+	// LpFeeBps, ProtocolFeeBps and CoinCreatorFeeBps are charged on each swap's quote amount;
+	// the creator fee only in pools with a coin creator
+	LpFeeBps              uint64
+	ProtocolFeeBps        uint64
+	CoinCreatorFeeBps     uint64
+	DisableFlags          uint8
+	ProtocolFeeRecipients [globalConfigRecipients]solana.PublicKey
+}
+
+// ParseGlobalConfig decodes the global config account data
+func ParseGlobalConfig(data []byte) (*GlobalConfig, error) {
+	if len(data) < globalConfigCreatorFeeOffset {
+		return nil, fmt.Errorf("global config too short: expected %d bytes, got %d", globalConfigCreatorFeeOffset, len(data))
+	}
+	config := &GlobalConfig{
+		Admin:          solana.PublicKeyFromBytes(data[8:40]),
+		LpFeeBps:       binary.LittleEndian.Uint64(data[40:48]),
+		ProtocolFeeBps: binary.LittleEndian.Uint64(data[48:56]),
+		DisableFlags:   data[56],
+	}
+	for i := range config.ProtocolFeeRecipients {
+		offset := globalConfigRecipientsOffset + i*32
+		config.ProtocolFeeRecipients[i] = solana.PublicKeyFromBytes(data[offset : offset+32])
+	}
+	if len(data) >= globalConfigCreatorFeeOffset+8 {
+		config.CoinCreatorFeeBps = binary.LittleEndian.Uint64(data[globalConfigCreatorFeeOffset:])
+	}
+	return config, nil
+}
+
+// FeeRecipient returns the protocol fee recipient swaps through pool pay. Pools are spread
+// over the configured recipients, so swaps in different pools don't all write-lock one
+// account.
+func (c *GlobalConfig) FeeRecipient(pool solana.PublicKey) (solana.PublicKey, error) {
+	recipients := make([]solana.PublicKey, 0, len(c.ProtocolFeeRecipients))
+	for _, recipient := range c.ProtocolFeeRecipients {
+		if !recipient.IsZero() {
+			recipients = append(recipients, recipient)
+		}
+	}
+	if len(recipients) == 0 {
+		return solana.PublicKey{}, fmt.Errorf("global config has no protocol fee recipient")
+	}
+	return recipients[int(pool[0])%len(recipients)], nil
+}
+
+// FeeBps returns the total fee of a swap, the creator fee included when the pool has a coin
+// creator
+func (c *GlobalConfig) FeeBps(hasCoinCreator bool) uint64 {
+	fee := c.LpFeeBps + c.ProtocolFeeBps
+	if hasCoinCreator {
+		fee += c.CoinCreatorFeeBps
+	}
+	return fee
+}
+
+// GlobalConfigCache keeps the global config read from chain, reading it again once it is
+// older than the max age. It is safe for concurrent use.
+type GlobalConfigCache struct {
+	address solana.PublicKey
+	maxAge  time.Duration
+	clock   clock.Clock
+
+	mu     sync.Mutex
+	config *GlobalConfig
+	readAt time.Time
+}
+
+// NewGlobalConfigCache returns an empty cache of the PumpSwap global config
+func NewGlobalConfigCache() *GlobalConfigCache {
+	return &GlobalConfigCache{
+		address: PumpGlobalConfig,
+		maxAge:  DefaultGlobalConfigMaxAge,
+		clock:   clock.System{},
+	}
+}
+
+// SetMaxAge sets how long a read config is used before reading it again
+func (c *GlobalConfigCache) SetMaxAge(d time.Duration) {
+	c.maxAge = d
+}
+
+// SetClock replaces the time source, e.g. with clock.Fake in tests
+func (c *GlobalConfigCache) SetClock(clk clock.Clock) {
+	c.clock = clk
+}
+
+// Invalidate drops the cached config, e.g. after a swap failed on a rotated fee recipient
+func (c *GlobalConfigCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config = nil
+}
+
+// Cached returns the last config read, however old, or nil before the first read
+func (c *GlobalConfigCache) Cached() *GlobalConfig {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.config
+}
+
+// Get returns the global config, reading it through solClient when none is cached or the
+// cached one is older than the max age
+func (c *GlobalConfigCache) Get(ctx context.Context, solClient pkg.RPC) (*GlobalConfig, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.config != nil && c.clock.Now().Sub(c.readAt) < c.maxAge {
+		return c.config, nil
+	}
+	results, err := solClient.GetMultipleAccountsWithOpts(ctx, []solana.PublicKey{c.address}, sol.MultipleAccountsOpts(ctx, rpc.CommitmentConfirmed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get global config %s: %w", c.address, err)
+	}
+	if len(results.Value) != 1 || results.Value[0] == nil {
+		return nil, fmt.Errorf("global config %s not found", c.address)
+	}
+	config, err := ParseGlobalConfig(results.Value[0].Data.GetBinary())
+	if err != nil {
+		return nil, err
+	}
+	c.config, c.readAt = config, c.clock.Now()
+	return config, nil
+}
@@ -0,0 +1,65 @@
+package pump
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/utils"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+)
+
+// Discriminators of the events PumpSwap emits for buys and sells
+var (
+	buyEventDiscriminator  = utils.GetDiscriminator("event", "BuyEvent")
+	sellEventDiscriminator = utils.GetDiscriminator("event", "SellEvent")
+)
+
+// tradeEvent is the leading fields shared by the BuyEvent and SellEvent layouts. BaseAmount
+// is the base bought or sold and UserQuoteAmount the quote the user paid or received, fees
+// included.
+type tradeEvent struct {
+	Timestamp              int64
+	BaseAmount             uint64
+	QuoteLimit             uint64
+	UserBaseTokenReserves  uint64
+	UserQuoteTokenReserves uint64
+	PoolBaseTokenReserves  uint64
+	PoolQuoteTokenReserves uint64
+	QuoteAmount            uint64
+	LpFeeBasisPoints       uint64
+	LpFee                  uint64
+	ProtocolFeeBasisPoints uint64
+	ProtocolFee            uint64
+	QuoteAmountWithLpFee   uint64
+	UserQuoteAmount        uint64
+	Pool                   solana.PublicKey
+	User                   solana.PublicKey
+}
+
+// DecodeSwapEvent decodes the BuyEvent and SellEvent PumpSwap emits for each trade. Buys sell
+// the quote token for the base token.
+func DecodeSwapEvent(event pkg.ProgramEvent) (pkg.SwapEvent, bool, error) {
+	buy := bytes.HasPrefix(event.Data, buyEventDiscriminator)
+	if !buy && !bytes.HasPrefix(event.Data, sellEventDiscriminator) {
+		return pkg.SwapEvent{}, false, nil
+	}
+	var ev tradeEvent
+	if err := bin.NewBinDecoder(event.Data[8:]).Decode(&ev); err != nil {
+		return pkg.SwapEvent{}, false, fmt.Errorf("failed to decode pump trade event: %w", err)
+	}
+	swap := pkg.SwapEvent{
+		Protocol:   pkg.ProtocolNamePumpAmm,
+		Pool:       ev.Pool,
+		User:       ev.User,
+		ZeroForOne: !buy,
+		AmountIn:   ev.BaseAmount,
+		AmountOut:  ev.UserQuoteAmount,
+		Fee:        ev.LpFee + ev.ProtocolFee,
+	}
+	if buy {
+		swap.AmountIn, swap.AmountOut = ev.UserQuoteAmount, ev.BaseAmount
+	}
+	return swap, true, nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gtdvccc/SolRouteTmp/pkg/txbuilder"
 	"github.com/gagliardetto/solana-go"
 )
 
@@ -18,12 +19,7 @@ func GetCoinCreatorVaultAuthority(coinCreator solana.PublicKey) (solana.PublicKe
 		return solana.PublicKey{}, fmt.Errorf("invalid coin creator public key")
 	}
 
-	seeds := [][]byte{
-		[]byte(CreatorVaultSeed),
-		coinCreator.Bytes(),
-	}
-
-	pda, _, err := solana.FindProgramAddress(seeds, PumpSwapProgramID)
+	pda, err := txbuilder.PumpCoinCreatorVaultAuthority(PumpSwapProgramID, coinCreator)
 	if err != nil {
 		return solana.PublicKey{}, fmt.Errorf("failed to find program address: %w", err)
 	}
@@ -32,7 +28,14 @@ func GetCoinCreatorVaultAuthority(coinCreator solana.PublicKey) (solana.PublicKe
 }
 
 // GetCoinCreatorVaultATA derives the Associated Token Account (ATA) for the coin creator's vault authority
+// in WSOL, the quote mint of most pools
 func GetCoinCreatorVaultATA(coinCreator solana.PublicKey) (solana.PublicKey, error) {
+	return CoinCreatorVaultATA(coinCreator, sol.WSOL)
+}
+
+// CoinCreatorVaultATA derives the token account of the coin creator's vault authority in quoteMint,
+// which receives the creator fee of swaps
+func CoinCreatorVaultATA(coinCreator, quoteMint solana.PublicKey) (solana.PublicKey, error) {
 	if coinCreator.IsZero() {
 		return solana.PublicKey{}, fmt.Errorf("invalid coin creator public key")
 	}
@@ -44,7 +47,7 @@ func GetCoinCreatorVaultATA(coinCreator solana.PublicKey) (solana.PublicKey, err
 
 	ata, _, err := solana.FindAssociatedTokenAddress(
 		creatorVaultAuthority, // owner
-		sol.WSOL,              // mint
+		quoteMint,             // mint
 	)
 	if err != nil {
 		return solana.PublicKey{}, fmt.Errorf("failed to find associated token address: %w", err)
@@ -0,0 +1,142 @@
+package raydium
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gtdvccc/SolRouteTmp/pkg/txbuilder"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// marketBatchSize is how many markets are read per getMultipleAccounts request
+const marketBatchSize = 100
+
+// ammAuthority is the AMM v4 program's authority over every pool's vaults
+var ammAuthority, _, _ = txbuilder.RaydiumAmmAuthority(RAYDIUM_AMM_PROGRAM_ID)
+
+// AMMMarket holds the OpenBook (formerly Serum) market accounts an AMM v4 swap passes
+type AMMMarket struct {
+	Market     solana.PublicKey
+	Bids       solana.PublicKey
+	Asks       solana.PublicKey
+	EventQueue solana.PublicKey
+	BaseVault  solana.PublicKey
+	QuoteVault solana.PublicKey
+	// VaultSigner is the market's authority over its vaults
+	VaultSigner solana.PublicKey
+}
+
+// ParseAMMMarket decodes the market account at address, owned by the market program
+// programID, and derives its vault signer
+func ParseAMMMarket(address, programID solana.PublicKey, data []byte) (AMMMarket, error) {
+	var layout MarketStateLayoutV3
+	if err := layout.Decode(data); err != nil {
+		return AMMMarket{}, fmt.Errorf("failed to decode market %s: %w", address, err)
+	}
+	nonce := binary.LittleEndian.AppendUint64(nil, layout.VaultSignerNonce)
+	vaultSigner, err := solana.CreateProgramAddress([][]byte{address.Bytes(), nonce}, programID)
+	if err != nil {
+		return AMMMarket{}, fmt.Errorf("failed to derive vault signer of market %s: %w", address, err)
+	}
+	return AMMMarket{
+		Market:      address,
+		Bids:        layout.Bids,
+		Asks:        layout.Asks,
+		EventQueue:  layout.EventQueue,
+		BaseVault:   layout.BaseVault,
+		QuoteVault:  layout.QuoteVault,
+		VaultSigner: vaultSigner,
+	}, nil
+}
+
+// SetMarket records the pool's market accounts
+func (p *AMMPool) SetMarket(market AMMMarket) {
+	p.Authority = ammAuthority
+	p.MarketBids = market.Bids
+	p.MarketAsks = market.Asks
+	p.MarketEventQueue = market.EventQueue
+	p.MarketBaseVault = market.BaseVault
+	p.MarketQuoteVault = market.QuoteVault
+	p.MarketAuthority = market.VaultSigner
+}
+
+// marketResolved reports whether the pool's market accounts are set
+func (p *AMMPool) marketResolved() bool {
+	return !p.MarketBids.IsZero() && !p.MarketAuthority.IsZero()
+}
+
+// ensureMarket reads the pool's market when discovery didn't resolve it
+func (p *AMMPool) ensureMarket(ctx context.Context, solClient pkg.RPC) error {
+	if p.marketResolved() {
+		return nil
+	}
+	return NewMarketResolver().Resolve(ctx, solClient, p)
+}
+
+// MarketResolver derives and caches the OpenBook market accounts of AMM v4 pools, so swaps
+// are built without reading the market. A pool's market never changes, so entries never
+// expire. It is safe for concurrent use.
+type MarketResolver struct {
+	mu      sync.RWMutex
+	markets map[solana.PublicKey]AMMMarket
+}
+
+// NewMarketResolver creates an empty resolver
+func NewMarketResolver() *MarketResolver {
+	return &MarketResolver{markets: make(map[solana.PublicKey]AMMMarket)}
+}
+
+// SetMarket records a market so it is never fetched, e.g. from the Raydium API
+func (r *MarketResolver) SetMarket(market AMMMarket) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.markets[market.Market] = market
+}
+
+// Resolve sets the market accounts of each pool, reading the markets not cached in batched
+// requests
+func (r *MarketResolver) Resolve(ctx context.Context, solClient pkg.RPC, pools ...*AMMPool) error {
+	var missing []solana.PublicKey
+	seen := make(map[solana.PublicKey]bool)
+	r.mu.RLock()
+	for _, pool := range pools {
+		if _, ok := r.markets[pool.MarketId]; !ok && !seen[pool.MarketId] {
+			missing = append(missing, pool.MarketId)
+			seen[pool.MarketId] = true
+		}
+	}
+	r.mu.RUnlock()
+
+	for start := 0; start < len(missing); start += marketBatchSize {
+		batch := missing[start:min(start+marketBatchSize, len(missing))]
+		results, err := solClient.GetMultipleAccountsWithOpts(ctx, batch, sol.MultipleAccountsOpts(ctx, rpc.CommitmentConfirmed))
+		if err != nil {
+			return fmt.Errorf("failed to get market accounts: %w", err)
+		}
+		if len(results.Value) != len(batch) {
+			return fmt.Errorf("expected %d market accounts, got %d", len(batch), len(results.Value))
+		}
+		for i, account := range results.Value {
+			if account == nil {
+				return fmt.Errorf("market %s not found", batch[i])
+			}
+			market, err := ParseAMMMarket(batch[i], account.Owner, account.Data.GetBinary())
+			if err != nil {
+				return err
+			}
+			r.SetMarket(market)
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, pool := range pools {
+		pool.SetMarket(r.markets[pool.MarketId])
+	}
+	return nil
+}
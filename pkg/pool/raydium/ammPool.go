@@ -10,9 +10,12 @@ import (
 	"fmt"
 	"log"
 	"reflect"
+	"time"
 	"unsafe"
 
 	"github.com/gtdvccc/SolRouteTmp/pkg"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
 	"cosmossdk.io/math"
 	cosmath "cosmossdk.io/math"
 	bin "github.com/gagliardetto/binary"
@@ -100,6 +103,12 @@ type AMMPool struct {
 	QuoteReserve     cosmath.Int
 	UserBaseAccount  solana.PublicKey
 	UserQuoteAccount solana.PublicKey
+
+	// Successor is the pool this one's liquidity migrated to, set by the router
+	Successor string
+
+	vaultBalances *VaultBalanceCache
+	reservesSlot  uint64
 }
 
 func (pool *AMMPool) ProtocolName() pkg.ProtocolName {
@@ -324,96 +333,163 @@ func (p *AMMPool) GetID() string {
 	return p.PoolId.String()
 }
 
+// FeeRateBps returns the trade fee in basis points, rounded up
+func (p *AMMPool) FeeRateBps() uint64 {
+	if p.TradeFeeDenominator == 0 {
+		return 0
+	}
+	return (p.TradeFeeNumerator*10000 + p.TradeFeeDenominator - 1) / p.TradeFeeDenominator
+}
+
+// OpenedAt returns when the pool opened for trading
+func (p *AMMPool) OpenedAt() time.Time {
+	return time.Unix(int64(p.PoolOpenTime), 0)
+}
+
 // GetTokens returns the base and quote token mints
 func (p *AMMPool) GetTokens() (baseMint, quoteMint string) {
 	return p.BaseMint.String(), p.QuoteMint.String()
 }
 
+// TokenVaults returns the pool vaults, which are controlled by the AMM authority
+func (p *AMMPool) TokenVaults() []pkg.TokenVault {
+	return []pkg.TokenVault{
+		{Address: p.BaseVault, Mint: p.BaseMint, Authority: p.Authority},
+		{Address: p.QuoteVault, Mint: p.QuoteMint, Authority: p.Authority},
+	}
+}
+
+// QuoteAccounts returns the vaults Quote reads the reserves from
+func (p *AMMPool) QuoteAccounts(inputMint string) []solana.PublicKey {
+	return []solana.PublicKey{p.BaseVault, p.QuoteVault}
+}
+
+// Deprecation reports pools that can no longer be swapped through: a status without swaps
+// enabled, or liquidity fully withdrawn, as happens when a pool is migrated to CPMM or CLMM
+func (p *AMMPool) Deprecation() *pkg.Deprecation {
+	var reason string
+	switch {
+	case !ammSwapStatuses[p.Status]:
+		reason = fmt.Sprintf("status %d does not allow swaps", p.Status)
+	case p.LpReserve == 0:
+		reason = "all liquidity withdrawn"
+	case !p.BaseReserve.IsNil() && !p.QuoteReserve.IsNil() && (!p.BaseReserve.IsPositive() || !p.QuoteReserve.IsPositive()):
+		reason = "reserves drained"
+	default:
+		return nil
+	}
+	return &pkg.Deprecation{Reason: reason, Successor: p.Successor}
+}
+
+// SetSuccessor records the pool that replaced this one
+func (p *AMMPool) SetSuccessor(poolID string) {
+	p.Successor = poolID
+}
+
+// SpotPrice returns the price of the pool's reserves, refreshing them as Quote does
+func (p *AMMPool) SpotPrice(ctx context.Context, solClient pkg.RPC) (pkg.SpotPrice, error) {
+	if err := p.updateReserves(ctx, solClient); err != nil {
+		return pkg.SpotPrice{}, err
+	}
+	return pkg.ReserveSpotPrice(p.BaseReserve, p.QuoteReserve)
+}
+
 // Quote calculates the expected output amount for a given input amount
 // It takes into account the current pool reserves and fees
 func (p *AMMPool) Quote(
 	ctx context.Context,
-	solClient *rpc.Client,
+	solClient pkg.RPC,
 	inputMint string,
 	inputAmount cosmath.Int,
 ) (cosmath.Int, error) {
-	// update pool data first
-	accounts := make([]solana.PublicKey, 0)
-	accounts = append(accounts, p.BaseVault)
-	accounts = append(accounts, p.QuoteVault)
+	if err := p.updateReserves(ctx, solClient); err != nil {
+		return math.NewInt(0), err
+	}
+	return p.Reserves().Quote(inputMint, inputAmount), nil
+}
+
+// updateReserves refreshes the vault balances, from the vault balance cache when it holds
+// them, and recomputes the effective reserves
+func (p *AMMPool) updateReserves(ctx context.Context, solClient pkg.RPC) error {
+	if p.cachedReserves() {
+		return nil
+	}
+	accounts := []solana.PublicKey{p.BaseVault, p.QuoteVault}
 	results, err := solClient.GetMultipleAccountsWithOpts(ctx,
 		accounts,
-		&rpc.GetMultipleAccountsOpts{
-			Commitment: rpc.CommitmentProcessed,
-		},
+		sol.MultipleAccountsOpts(ctx, rpc.CommitmentProcessed),
 	)
 	if err != nil {
-		return math.NewInt(0), fmt.Errorf("batch request failed: %v", err)
+		return fmt.Errorf("batch request failed: %v", err)
 	}
+	amounts := make([]cosmath.Int, len(accounts))
 	for i, result := range results.Value {
 		if result == nil {
-			return math.NewInt(0), fmt.Errorf("result is nil, account: %v", accounts[i].String())
-		}
-		accountKey := accounts[i].String()
-		if p.BaseVault.String() == accountKey {
-			amountBytes := result.Data.GetBinary()[64:72]
-			amountUint := binary.LittleEndian.Uint64(amountBytes)
-			amount := math.NewIntFromUint64(amountUint)
-			p.BaseAmount = amount
-		} else {
-			amountBytes := result.Data.GetBinary()[64:72]
-			amountUint := binary.LittleEndian.Uint64(amountBytes)
-			amount := math.NewIntFromUint64(amountUint)
-			p.QuoteAmount = amount
+			return fmt.Errorf("result is nil, account: %v", accounts[i].String())
 		}
+		amounts[i] = math.NewIntFromUint64(binary.LittleEndian.Uint64(result.Data.GetBinary()[64:72]))
 	}
+	p.setReserves(amounts[0], amounts[1], results.Context.Slot)
+	return nil
+}
 
-	// Calculate effective reserves by subtracting pending PnL
-	p.BaseReserve = p.BaseAmount.Sub(cosmath.NewInt(int64(p.BaseNeedTakePnl)))
-	p.QuoteReserve = p.QuoteAmount.Sub(cosmath.NewInt(int64(p.QuoteNeedTakePnl)))
-
-	// Set reserves and decimals based on swap direction
-	reserves := []cosmath.Int{p.BaseReserve, p.QuoteReserve}
-	mintDecimals := []int{int(p.BaseDecimal), int(p.QuoteDecimal)}
-
-	// Swap reserves if input is quote token
-	if inputMint == p.QuoteMint.String() {
-		reserves[0], reserves[1] = reserves[1], reserves[0]
-		mintDecimals[0], mintDecimals[1] = mintDecimals[1], mintDecimals[0]
+// QuoteExactOut calculates the input amount required to receive desiredOut of outputMint
+func (p *AMMPool) QuoteExactOut(
+	ctx context.Context,
+	solClient pkg.RPC,
+	outputMint string,
+	desiredOut cosmath.Int,
+) (cosmath.Int, error) {
+	if err := p.updateReserves(ctx, solClient); err != nil {
+		return math.NewInt(0), err
 	}
 
-	reserveIn := reserves[0]
-	reserveOut := reserves[1]
+	reserveIn, reserveOut := p.QuoteReserve, p.BaseReserve
+	if outputMint == p.QuoteMint.String() {
+		reserveIn, reserveOut = p.BaseReserve, p.QuoteReserve
+	}
+	return getAmountInForExactOut(reserveIn, reserveOut, desiredOut)
+}
 
-	// Initialize output values
-	amountOutRaw := cosmath.ZeroInt()
-	feeRaw := cosmath.ZeroInt()
+// getAmountInForExactOut inverts the constant product formula used by Quote.
+// It returns the smallest fee-inclusive input that yields at least amountOut.
+func getAmountInForExactOut(reserveIn, reserveOut, amountOut cosmath.Int) (cosmath.Int, error) {
+	return amountInForExactOut(reserveIn, reserveOut, amountOut, LIQUIDITY_FEES_NUMERATOR, LIQUIDITY_FEES_DENOMINATOR)
+}
 
-	// Calculate output amount if input is non-zero
-	if !inputAmount.IsZero() {
-		// Calculate fee based on input amount
-		feeRaw = inputAmount.Mul(LIQUIDITY_FEES_NUMERATOR).Quo(LIQUIDITY_FEES_DENOMINATOR)
+// amountInForExactOut is the input buying amountOut from a constant product pool charging
+// feeNumerator/feeDenominator of the input
+func amountInForExactOut(reserveIn, reserveOut, amountOut, feeNumerator, feeDenominator cosmath.Int) (cosmath.Int, error) {
+	if !amountOut.IsPositive() {
+		return math.NewInt(0), fmt.Errorf("output amount must be positive")
+	}
+	if amountOut.GTE(reserveOut) {
+		return math.NewInt(0), fmt.Errorf("%w: requested %s, reserve %s", solerrors.ErrInsufficientLiquidity, amountOut, reserveOut)
+	}
 
-		// Calculate amount after fee
-		amountInWithFee := inputAmount.Sub(feeRaw)
+	// amountInWithFee = ceil(reserveIn * amountOut / (reserveOut - amountOut))
+	denominator := reserveOut.Sub(amountOut)
+	amountInWithFee := reserveIn.Mul(amountOut).Add(denominator).SubRaw(1).Quo(denominator)
 
-		// Calculate output using constant product formula: x * y = k
-		denominator := reserveIn.Add(amountInWithFee)
-		amountOutRaw = reserveOut.Mul(amountInWithFee).Quo(denominator)
-	}
-	return amountOutRaw, nil
+	// Gross up for the trade fee, rounding up so the fee deduction in Quote leaves enough
+	netDenominator := feeDenominator.Sub(feeNumerator)
+	amountIn := amountInWithFee.Mul(feeDenominator).Add(netDenominator).SubRaw(1).Quo(netDenominator)
+	return amountIn, nil
 }
 
 // BuildSwapInstructions constructs the necessary instructions for executing a swap
 // It handles both base-to-quote and quote-to-base swaps
 func (pool *AMMPool) BuildSwapInstructions(
 	ctx context.Context,
-	solClient *rpc.Client,
+	solClient pkg.RPC,
 	user solana.PublicKey,
 	inputMint string,
 	inputAmount cosmath.Int,
 	minOut cosmath.Int,
 ) ([]solana.Instruction, error) {
+	if err := pool.ensureMarket(ctx, solClient); err != nil {
+		return nil, err
+	}
 	instrs := []solana.Instruction{}
 
 	// Determine input token mint
@@ -438,37 +514,75 @@ func (pool *AMMPool) BuildSwapInstructions(
 	inst := InSwapInstruction{
 		InAmount:         inputAmount.Uint64(),
 		MinimumOutAmount: minOut.Uint64(),
-		AccountMetaSlice: make(solana.AccountMetaSlice, 18),
+		AccountMetaSlice: pool.swapAccountMetas(user, fromAccount, toAccount),
 	}
 	inst.BaseVariant = bin.BaseVariant{
 		Impl: inst,
 	}
 
-	// Set up account metas for the swap instruction
-	tokenProgramID := solana.MustPublicKeyFromBase58("TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA")
-	inst.AccountMetaSlice[0] = solana.NewAccountMeta(tokenProgramID, false, false)
-	inst.AccountMetaSlice[1] = solana.NewAccountMeta(pool.PoolId, true, false)
-	inst.AccountMetaSlice[2] = solana.NewAccountMeta(pool.Authority, false, false)
-	inst.AccountMetaSlice[3] = solana.NewAccountMeta(pool.OpenOrders, true, false)
-	inst.AccountMetaSlice[4] = solana.NewAccountMeta(pool.TargetOrders, true, false)
-	inst.AccountMetaSlice[5] = solana.NewAccountMeta(pool.BaseVault, true, false)
-	inst.AccountMetaSlice[6] = solana.NewAccountMeta(pool.QuoteVault, true, false)
-	inst.AccountMetaSlice[7] = solana.NewAccountMeta(pool.MarketProgramId, false, false)
-	inst.AccountMetaSlice[8] = solana.NewAccountMeta(pool.MarketId, true, false)
-	inst.AccountMetaSlice[9] = solana.NewAccountMeta(pool.MarketBids, true, false)
-	inst.AccountMetaSlice[10] = solana.NewAccountMeta(pool.MarketAsks, true, false)
-	inst.AccountMetaSlice[11] = solana.NewAccountMeta(pool.MarketEventQueue, true, false)
-	inst.AccountMetaSlice[12] = solana.NewAccountMeta(pool.MarketBaseVault, true, false)
-	inst.AccountMetaSlice[13] = solana.NewAccountMeta(pool.MarketQuoteVault, true, false)
-	inst.AccountMetaSlice[14] = solana.NewAccountMeta(pool.MarketAuthority, false, false)
-	inst.AccountMetaSlice[15] = solana.NewAccountMeta(fromAccount, true, false)
-	inst.AccountMetaSlice[16] = solana.NewAccountMeta(toAccount, true, false)
-	inst.AccountMetaSlice[17] = solana.NewAccountMeta(user, true, true)
-
 	instrs = append(instrs, &inst)
 	return instrs, nil
 }
 
+// BuildSwapInstructionsExactOut constructs a swap_base_out instruction that receives
+// exactly amountOut of outputMint while spending at most maxIn
+func (pool *AMMPool) BuildSwapInstructionsExactOut(
+	ctx context.Context,
+	solClient pkg.RPC,
+	user solana.PublicKey,
+	outputMint string,
+	amountOut cosmath.Int,
+	maxIn cosmath.Int,
+) ([]solana.Instruction, error) {
+	if err := pool.ensureMarket(ctx, solClient); err != nil {
+		return nil, err
+	}
+	// Set up source and destination accounts based on swap direction
+	var fromAccount, toAccount solana.PublicKey
+	if outputMint == pool.QuoteMint.String() {
+		fromAccount = pool.UserBaseAccount
+		toAccount = pool.UserQuoteAccount
+	} else {
+		fromAccount = pool.UserQuoteAccount
+		toAccount = pool.UserBaseAccount
+	}
+
+	inst := OutSwapInstruction{
+		MaxInAmount:      maxIn.Uint64(),
+		OutAmount:        amountOut.Uint64(),
+		AccountMetaSlice: pool.swapAccountMetas(user, fromAccount, toAccount),
+	}
+	inst.BaseVariant = bin.BaseVariant{
+		Impl: inst,
+	}
+	return []solana.Instruction{&inst}, nil
+}
+
+// swapAccountMetas returns the account list shared by swap_base_in and swap_base_out
+func (pool *AMMPool) swapAccountMetas(user, fromAccount, toAccount solana.PublicKey) solana.AccountMetaSlice {
+	metas := make(solana.AccountMetaSlice, 18)
+	tokenProgramID := solana.MustPublicKeyFromBase58("TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA")
+	metas[0] = solana.NewAccountMeta(tokenProgramID, false, false)
+	metas[1] = solana.NewAccountMeta(pool.PoolId, true, false)
+	metas[2] = solana.NewAccountMeta(pool.Authority, false, false)
+	metas[3] = solana.NewAccountMeta(pool.OpenOrders, true, false)
+	metas[4] = solana.NewAccountMeta(pool.TargetOrders, true, false)
+	metas[5] = solana.NewAccountMeta(pool.BaseVault, true, false)
+	metas[6] = solana.NewAccountMeta(pool.QuoteVault, true, false)
+	metas[7] = solana.NewAccountMeta(pool.MarketProgramId, false, false)
+	metas[8] = solana.NewAccountMeta(pool.MarketId, true, false)
+	metas[9] = solana.NewAccountMeta(pool.MarketBids, true, false)
+	metas[10] = solana.NewAccountMeta(pool.MarketAsks, true, false)
+	metas[11] = solana.NewAccountMeta(pool.MarketEventQueue, true, false)
+	metas[12] = solana.NewAccountMeta(pool.MarketBaseVault, true, false)
+	metas[13] = solana.NewAccountMeta(pool.MarketQuoteVault, true, false)
+	metas[14] = solana.NewAccountMeta(pool.MarketAuthority, false, false)
+	metas[15] = solana.NewAccountMeta(fromAccount, true, false)
+	metas[16] = solana.NewAccountMeta(toAccount, true, false)
+	metas[17] = solana.NewAccountMeta(user, true, true)
+	return metas
+}
+
 type InSwapInstruction struct {
 	bin.BaseVariant
 	InAmount                uint64
@@ -508,3 +622,43 @@ func (inst *InSwapInstruction) MarshalWithEncoder(encoder *bin.Encoder) (err err
 	}
 	return nil
 }
+
+type OutSwapInstruction struct {
+	bin.BaseVariant
+	MaxInAmount             uint64
+	OutAmount               uint64
+	solana.AccountMetaSlice `bin:"-" borsh_skip:"true"`
+}
+
+func (inst *OutSwapInstruction) ProgramID() solana.PublicKey {
+	return RAYDIUM_AMM_PROGRAM_ID
+}
+
+func (inst *OutSwapInstruction) Accounts() (out []*solana.AccountMeta) {
+	return inst.Impl.(solana.AccountsGettable).GetAccounts()
+}
+
+func (inst *OutSwapInstruction) Data() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := bin.NewBorshEncoder(buf).Encode(inst); err != nil {
+		return nil, fmt.Errorf("unable to encode instruction: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (inst *OutSwapInstruction) MarshalWithEncoder(encoder *bin.Encoder) (err error) {
+	// SwapBaseOut instruction is number 11
+	err = encoder.WriteUint8(11)
+	if err != nil {
+		return err
+	}
+	err = encoder.WriteUint64(inst.MaxInAmount, binary.LittleEndian)
+	if err != nil {
+		return err
+	}
+	err = encoder.WriteUint64(inst.OutAmount, binary.LittleEndian)
+	if err != nil {
+		return err
+	}
+	return nil
+}
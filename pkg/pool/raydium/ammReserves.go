@@ -0,0 +1,146 @@
+package raydium
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	cosmath "cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+)
+
+// AMMReserves is a snapshot of an AMM v4 pool's effective reserves, enough to quote the pool
+// without an RPC call
+type AMMReserves struct {
+	BaseMint     solana.PublicKey
+	QuoteMint    solana.PublicKey
+	BaseReserve  cosmath.Int
+	QuoteReserve cosmath.Int
+	// Slot is the oldest slot the vault balances were read at, zero when unknown
+	Slot uint64
+}
+
+// Quote returns the output of swapping amountIn of inputMint against the snapshot, as
+// AMMPool.Quote computes it
+func (r AMMReserves) Quote(inputMint string, amountIn cosmath.Int) cosmath.Int {
+	reserveIn, reserveOut := r.BaseReserve, r.QuoteReserve
+	if inputMint == r.QuoteMint.String() {
+		reserveIn, reserveOut = reserveOut, reserveIn
+	}
+	if amountIn.IsZero() {
+		return cosmath.ZeroInt()
+	}
+	fee := amountIn.Mul(LIQUIDITY_FEES_NUMERATOR).Quo(LIQUIDITY_FEES_DENOMINATOR)
+	amountInWithFee := amountIn.Sub(fee)
+	return reserveOut.Mul(amountInWithFee).Quo(reserveIn.Add(amountInWithFee))
+}
+
+// Reserves returns the pool's reserves as of its last refresh
+func (p *AMMPool) Reserves() AMMReserves {
+	return AMMReserves{
+		BaseMint:     p.BaseMint,
+		QuoteMint:    p.QuoteMint,
+		BaseReserve:  p.BaseReserve,
+		QuoteReserve: p.QuoteReserve,
+		Slot:         p.reservesSlot,
+	}
+}
+
+// SetVaultBalances makes the pool read its vault balances from cache while it holds both,
+// instead of fetching them on every quote
+func (p *AMMPool) SetVaultBalances(cache *VaultBalanceCache) {
+	p.vaultBalances = cache
+}
+
+// cachedReserves sets the reserves from the vault balance cache, reporting whether it held
+// both vaults
+func (p *AMMPool) cachedReserves() bool {
+	if p.vaultBalances == nil {
+		return false
+	}
+	base, baseSlot, ok := p.vaultBalances.Balance(p.BaseVault)
+	if !ok {
+		return false
+	}
+	quote, quoteSlot, ok := p.vaultBalances.Balance(p.QuoteVault)
+	if !ok {
+		return false
+	}
+	p.setReserves(cosmath.NewIntFromUint64(base), cosmath.NewIntFromUint64(quote), min(baseSlot, quoteSlot))
+	return true
+}
+
+// setReserves records the vault balances and recomputes the effective reserves by
+// subtracting pending PnL
+func (p *AMMPool) setReserves(baseAmount, quoteAmount cosmath.Int, slot uint64) {
+	p.BaseAmount = baseAmount
+	p.QuoteAmount = quoteAmount
+	p.BaseReserve = p.BaseAmount.Sub(cosmath.NewIntFromUint64(p.BaseNeedTakePnl))
+	p.QuoteReserve = p.QuoteAmount.Sub(cosmath.NewIntFromUint64(p.QuoteNeedTakePnl))
+	p.reservesSlot = slot
+}
+
+type vaultBalance struct {
+	amount uint64
+	slot   uint64
+}
+
+// VaultBalanceCache holds token account balances fed by account subscriptions, so AMM pools
+// set up with SetVaultBalances quote from memory. It is safe for concurrent use.
+type VaultBalanceCache struct {
+	mu       sync.RWMutex
+	balances map[solana.PublicKey]vaultBalance
+}
+
+// NewVaultBalanceCache returns an empty cache
+func NewVaultBalanceCache() *VaultBalanceCache {
+	return &VaultBalanceCache{balances: make(map[solana.PublicKey]vaultBalance)}
+}
+
+// Balance returns the cached balance of a vault and the slot it was read at
+func (c *VaultBalanceCache) Balance(vault solana.PublicKey) (uint64, uint64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	balance, ok := c.balances[vault]
+	return balance.amount, balance.slot, ok
+}
+
+// Apply records a token account update. Updates older than the cached balance are ignored,
+// and an update that isn't a token account drops the vault so pools fetch it again.
+func (c *VaultBalanceCache) Apply(update sol.AccountUpdate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.balances[update.Pubkey]; ok && update.Slot < cached.slot {
+		return
+	}
+	if len(update.Data) < 72 {
+		delete(c.balances, update.Pubkey)
+		return
+	}
+	c.balances[update.Pubkey] = vaultBalance{amount: binary.LittleEndian.Uint64(update.Data[64:72]), slot: update.Slot}
+}
+
+// Watch subscribes to the vaults of the pools through watcher and sets the pools to quote
+// from the cache. It returns once subscribed; balances are applied until ctx is done or the
+// subscription ends.
+func (c *VaultBalanceCache) Watch(ctx context.Context, watcher sol.AccountWatcher, pools ...*AMMPool) error {
+	vaults := make([]solana.PublicKey, 0, 2*len(pools))
+	for _, pool := range pools {
+		vaults = append(vaults, pool.BaseVault, pool.QuoteVault)
+	}
+	updates, err := watcher.SubscribeAccounts(ctx, vaults)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to vaults: %w", err)
+	}
+	for _, pool := range pools {
+		pool.SetVaultBalances(c)
+	}
+	go func() {
+		for update := range updates {
+			c.Apply(update)
+		}
+	}()
+	return nil
+}
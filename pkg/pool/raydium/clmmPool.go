@@ -6,12 +6,15 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"log"
 	"math"
 	"math/big"
 	"strconv"
+	"time"
 
 	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clmmmath"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
 	cosmath "cosmossdk.io/math"
 	bin "github.com/gagliardetto/binary"
 	"github.com/gagliardetto/solana-go"
@@ -68,12 +71,16 @@ type CLMMPool struct {
 	Padding2    [32]uint64
 
 	PoolId            solana.PublicKey
+	ProgramID         solana.PublicKey // owner of the pool, ClmmProgramID when zero
 	FeeRate           uint32
 	ExBitmapAddress   solana.PublicKey
 	exTickArrayBitmap *TickArrayBitmapExtensionType
 	TickArrayCache    map[string]TickArray
 	UserBaseAccount   solana.PublicKey
 	UserQuoteAccount  solana.PublicKey
+
+	// Logger receives swap building warnings; defaults to slog's default logger when nil
+	Logger pkg.Logger
 }
 
 type RewardInfo struct {
@@ -99,188 +106,66 @@ func (pool *CLMMPool) ProtocolType() pkg.ProtocolType {
 }
 
 func (pool *CLMMPool) GetProgramID() solana.PublicKey {
-	return RAYDIUM_CLMM_PROGRAM_ID
+	if pool.ProgramID.IsZero() {
+		return clmmProgramID
+	}
+	return pool.ProgramID
 }
 
+// Decode parses a pool account through ClmmPoolState
 func (l *CLMMPool) Decode(data []byte) error {
-	// Skip 8 bytes discriminator if present
-	if len(data) > 8 {
-		data = data[8:]
-	}
-
-	offset := 0
-
-	// Parse core states
-	l.Bump = data[offset]
-	offset += 1
-
-	l.AmmConfig = solana.PublicKeyFromBytes(data[offset : offset+32])
-	offset += 32
-
-	l.Owner = solana.PublicKeyFromBytes(data[offset : offset+32])
-	offset += 32
-
-	l.TokenMint0 = solana.PublicKeyFromBytes(data[offset : offset+32])
-	offset += 32
-
-	l.TokenMint1 = solana.PublicKeyFromBytes(data[offset : offset+32])
-	offset += 32
-
-	l.TokenVault0 = solana.PublicKeyFromBytes(data[offset : offset+32])
-	offset += 32
-
-	l.TokenVault1 = solana.PublicKeyFromBytes(data[offset : offset+32])
-	offset += 32
-
-	l.ObservationKey = solana.PublicKeyFromBytes(data[offset : offset+32])
-	offset += 32
-
-	l.MintDecimals0 = data[offset]
-	offset += 1
-
-	l.MintDecimals1 = data[offset]
-	offset += 1
-
-	l.TickSpacing = binary.LittleEndian.Uint16(data[offset : offset+2])
-	offset += 2
-
-	// Parse liquidity states
-	l.Liquidity = uint128.FromBytes(data[offset : offset+16])
-	offset += 16
-
-	l.SqrtPriceX64 = uint128.FromBytes(data[offset : offset+16])
-	offset += 16
-
-	l.TickCurrent = int32(binary.LittleEndian.Uint32(data[offset : offset+4]))
-	offset += 4
-
-	l.ObservationIndex = binary.LittleEndian.Uint16(data[offset : offset+2])
-	offset += 2
-
-	l.ObservationUpdateDuration = binary.LittleEndian.Uint16(data[offset : offset+2])
-	offset += 2
-
-	l.FeeGrowthGlobal0X64 = uint128.FromBytes(data[offset : offset+16])
-	offset += 16
-
-	l.FeeGrowthGlobal1X64 = uint128.FromBytes(data[offset : offset+16])
-	offset += 16
-
-	l.ProtocolFeesToken0 = binary.LittleEndian.Uint64(data[offset : offset+8])
-	offset += 8
-
-	l.ProtocolFeesToken1 = binary.LittleEndian.Uint64(data[offset : offset+8])
-	offset += 8
-
-	l.SwapInAmountToken0 = uint128.FromBytes(data[offset : offset+16])
-	offset += 16
-
-	l.SwapOutAmountToken1 = uint128.FromBytes(data[offset : offset+16])
-	offset += 16
-
-	l.SwapInAmountToken1 = uint128.FromBytes(data[offset : offset+16])
-	offset += 16
-
-	l.SwapOutAmountToken0 = uint128.FromBytes(data[offset : offset+16])
-	offset += 16
-
-	l.Status = data[offset]
-	offset += 1
-
-	// Skip padding
-	offset += 7
-
-	// Parse reward states
-	for i := 0; i < 3; i++ {
-		l.RewardInfos[i].RewardState = data[offset]
-		offset += 1
-
-		l.RewardInfos[i].OpenTime = binary.LittleEndian.Uint64(data[offset : offset+8])
-		offset += 8
-
-		l.RewardInfos[i].EndTime = binary.LittleEndian.Uint64(data[offset : offset+8])
-		offset += 8
-
-		l.RewardInfos[i].LastUpdateTime = binary.LittleEndian.Uint64(data[offset : offset+8])
-		offset += 8
-
-		l.RewardInfos[i].EmissionsPerSecondX64 = uint128.FromBytes(data[offset : offset+16])
-		offset += 16
-
-		l.RewardInfos[i].RewardTotalEmissioned = binary.LittleEndian.Uint64(data[offset : offset+8])
-		offset += 8
-
-		l.RewardInfos[i].RewardClaimed = binary.LittleEndian.Uint64(data[offset : offset+8])
-		offset += 8
-
-		l.RewardInfos[i].TokenMint = solana.PublicKeyFromBytes(data[offset : offset+32])
-		offset += 32
-
-		l.RewardInfos[i].TokenVault = solana.PublicKeyFromBytes(data[offset : offset+32])
-		offset += 32
-
-		l.RewardInfos[i].Authority = solana.PublicKeyFromBytes(data[offset : offset+32])
-		offset += 32
-
-		l.RewardInfos[i].RewardGrowthGlobalX64 = uint128.FromBytes(data[offset : offset+16])
-		offset += 16
-	}
-
-	// Parse tick array bitmap
-	for i := 0; i < 16; i++ {
-		l.TickArrayBitmap[i] = binary.LittleEndian.Uint64(data[offset : offset+8])
-		offset += 8
-	}
-
-	// Parse fee states
-	l.TotalFeesToken0 = binary.LittleEndian.Uint64(data[offset : offset+8])
-	offset += 8
-
-	l.TotalFeesClaimedToken0 = binary.LittleEndian.Uint64(data[offset : offset+8])
-	offset += 8
-
-	l.TotalFeesToken1 = binary.LittleEndian.Uint64(data[offset : offset+8])
-	offset += 8
-
-	l.TotalFeesClaimedToken1 = binary.LittleEndian.Uint64(data[offset : offset+8])
-	offset += 8
-
-	l.FundFeesToken0 = binary.LittleEndian.Uint64(data[offset : offset+8])
-	offset += 8
-
-	l.FundFeesToken1 = binary.LittleEndian.Uint64(data[offset : offset+8])
-	offset += 8
-
-	// Parse other states
-	l.OpenTime = binary.LittleEndian.Uint64(data[offset : offset+8])
-	offset += 8
-
-	l.RecentEpoch = binary.LittleEndian.Uint64(data[offset : offset+8])
-	offset += 8
-
-	// Skip padding1
-	offset += 24 * 8
-
-	// Skip padding2
-	offset += 32 * 8
+	var state ClmmPoolState
+	if err := clmmPoolLayout.Decode(data, &state); err != nil {
+		return err
+	}
+	l.Bump = state.Bump
+	l.AmmConfig = state.AmmConfig
+	l.Owner = state.Owner
+	l.TokenMint0 = state.TokenMint0
+	l.TokenMint1 = state.TokenMint1
+	l.TokenVault0 = state.TokenVault0
+	l.TokenVault1 = state.TokenVault1
+	l.ObservationKey = state.ObservationKey
+	l.MintDecimals0 = state.MintDecimals0
+	l.MintDecimals1 = state.MintDecimals1
+	l.TickSpacing = state.TickSpacing
+	l.Liquidity = state.Liquidity
+	l.SqrtPriceX64 = state.SqrtPriceX64
+	l.TickCurrent = state.TickCurrent
+	l.ObservationIndex = state.ObservationIndex
+	l.ObservationUpdateDuration = state.ObservationUpdateDuration
+	l.FeeGrowthGlobal0X64 = state.FeeGrowthGlobal0X64
+	l.FeeGrowthGlobal1X64 = state.FeeGrowthGlobal1X64
+	l.ProtocolFeesToken0 = state.ProtocolFeesToken0
+	l.ProtocolFeesToken1 = state.ProtocolFeesToken1
+	l.SwapInAmountToken0 = state.SwapInAmountToken0
+	l.SwapOutAmountToken1 = state.SwapOutAmountToken1
+	l.SwapInAmountToken1 = state.SwapInAmountToken1
+	l.SwapOutAmountToken0 = state.SwapOutAmountToken0
+	l.Status = state.Status
+	l.Padding = state.Padding
+	l.RewardInfos = state.RewardInfos
+	l.TickArrayBitmap = state.TickArrayBitmap
+	l.TotalFeesToken0 = state.TotalFeesToken0
+	l.TotalFeesClaimedToken0 = state.TotalFeesClaimedToken0
+	l.TotalFeesToken1 = state.TotalFeesToken1
+	l.TotalFeesClaimedToken1 = state.TotalFeesClaimedToken1
+	l.FundFeesToken0 = state.FundFeesToken0
+	l.FundFeesToken1 = state.FundFeesToken1
+	l.OpenTime = state.OpenTime
+	l.RecentEpoch = state.RecentEpoch
 	return nil
 }
 
+// Span returns the account size, 1544 bytes including the discriminator
 func (l *CLMMPool) Span() uint64 {
-	return uint64(1544)
+	return clmmPoolLayout.Size()
 }
 
+// Offset returns the offset of a ClmmPoolState field, or 0 for unknown fields
 func (l *CLMMPool) Offset(field string) uint64 {
-	// Add 8 bytes for discriminator
-	baseOffset := uint64(8)
-
-	switch field {
-	case "TokenMint0":
-		return baseOffset + 1 + 32 + 32 // bump + ammConfig + owner
-	case "TokenMint1":
-		return baseOffset + 1 + 32 + 32 + 32 // bump + ammConfig + owner + tokenMint0
-	}
-	return 0
+	offset, _ := clmmPoolLayout.Offset(field)
+	return offset
 }
 
 func (l *CLMMPool) CurrentPrice() float64 {
@@ -293,6 +178,12 @@ func (l *CLMMPool) CurrentPrice() float64 {
 	return price
 }
 
+// SpotPrice returns the pool's price and in-range liquidity from its state
+func (l *CLMMPool) SpotPrice(ctx context.Context, solClient pkg.RPC) (pkg.SpotPrice, error) {
+	price, depth := clmmmath.SpotPrice(l.SqrtPriceX64.Big(), l.Liquidity.Big())
+	return pkg.SpotPrice{Price: price, Depth: depth}, nil
+}
+
 // IsSwapEnabled checks if swap functionality is enabled for this pool
 func (l *CLMMPool) IsSwapEnabled() bool {
 	// Bit 4 corresponds to Swap functionality
@@ -303,12 +194,46 @@ func (l *CLMMPool) IsSwapEnabled() bool {
 
 func (p *CLMMPool) BuildSwapInstructions(
 	ctx context.Context,
-	solClient *rpc.Client,
+	solClient pkg.RPC,
 	userAddr solana.PublicKey,
 	inputMint string,
 	amountIn cosmath.Int,
 	minOutAmountWithDecimals cosmath.Int,
 ) ([]solana.Instruction, error) {
+	return p.buildSwapInstructions(ctx, solClient, userAddr, inputMint, amountIn, minOutAmountWithDecimals, true, uint128.Zero)
+}
+
+// BuildSwapInstructionsExactOut builds a swap_v2 instruction with is_base_input unset, so the
+// program delivers exactly amountOut of outputMint and rejects the swap if more than maxIn is needed
+func (p *CLMMPool) BuildSwapInstructionsExactOut(
+	ctx context.Context,
+	solClient pkg.RPC,
+	userAddr solana.PublicKey,
+	outputMint string,
+	amountOut cosmath.Int,
+	maxIn cosmath.Int,
+) ([]solana.Instruction, error) {
+	inputMint := p.TokenMint0.String()
+	if outputMint == p.TokenMint0.String() {
+		inputMint = p.TokenMint1.String()
+	}
+	return p.buildSwapInstructions(ctx, solClient, userAddr, inputMint, amountOut, maxIn, false, uint128.Zero)
+}
+
+// buildSwapInstructions builds a swap_v2 instruction. When isBaseInput is true amount is the exact
+// input and otherAmountThreshold the minimum output, otherwise amount is the exact output and
+// otherAmountThreshold the maximum input. A zero sqrtPriceLimitX64 lets the program apply its
+// bounds.
+func (p *CLMMPool) buildSwapInstructions(
+	ctx context.Context,
+	solClient pkg.RPC,
+	userAddr solana.PublicKey,
+	inputMint string,
+	amount cosmath.Int,
+	otherAmountThreshold cosmath.Int,
+	isBaseInput bool,
+	sqrtPriceLimitX64 uint128.Uint128,
+) ([]solana.Instruction, error) {
 
 	// Initialize instruction array and signers
 	instrs := []solana.Instruction{}
@@ -355,10 +280,10 @@ func (p *CLMMPool) BuildSwapInstructions(
 
 	outputATAInfo, err := solClient.GetAccountInfo(ctx, outputATAAccount)
 	if err != nil || outputATAInfo.Value == nil || outputATAInfo.Value.Owner.IsZero() {
-		// ATA doesn't exist, need to create it
-		// Temporarily skip creating ATA instruction, let user create manually
-		// Or can use solana CLI: solana spl-token create-account <mint>
-		log.Printf("Warning: Output ATA account %s does not exist, please create it manually", outputATAAccount.String())
+		// Not created here; build through sol.Client.BuildSwapInstructions with
+		// sol.SwapOptions{CreateOutputAccount: true} to prepend the creation
+		pkg.LoggerOrDefault(p.Logger).Warn("output token account does not exist; create it or set sol.SwapOptions.CreateOutputAccount",
+			pkg.LogKeyPool, p.PoolId.String(), "account", outputATAAccount.String())
 	}
 
 	// Remove Approve instruction, CLMM may use different authorization mechanism
@@ -392,10 +317,11 @@ func (p *CLMMPool) BuildSwapInstructions(
 	}
 
 	inst := RayCLMMSwapInstruction{
-		Amount:               amountIn.Uint64(),
-		OtherAmountThreshold: minOutAmountWithDecimals.Uint64(),
-		SqrtPriceLimitX64:    uint128.Zero,
-		IsBaseInput:          inputValueMint == p.TokenMint0,
+		Amount:               amount.Uint64(),
+		OtherAmountThreshold: otherAmountThreshold.Uint64(),
+		SqrtPriceLimitX64:    sqrtPriceLimitX64,
+		IsBaseInput:          isBaseInput,
+		Program:              p.GetProgramID(),
 		AccountMetaSlice:     make(solana.AccountMetaSlice, 0),
 	}
 	inst.BaseVariant = bin.BaseVariant{
@@ -420,17 +346,15 @@ func (p *CLMMPool) BuildSwapInstructions(
 	)
 
 	// Add bitmap extension as remaining account if it exists
-	exBitmapAddress, _, err := GetPdaExBitmapAccount(RAYDIUM_CLMM_PROGRAM_ID, p.PoolId)
+	exBitmapAddress, _, err := GetPdaExBitmapAccount(p.GetProgramID(), p.PoolId)
 	if err != nil {
-		log.Printf("get pda address error: %v", err)
-		return nil, fmt.Errorf("get pda address error: %v", err)
+		return nil, fmt.Errorf("get pda address error: %w", err)
 	}
 	inst.AccountMetaSlice = append(inst.AccountMetaSlice, solana.NewAccountMeta(exBitmapAddress, true, false)) // exTickArrayBitmap (is_writable = true, is_signer = false)
 
 	// Add tick arrays as remaining accounts
 	remainingAccounts, err := p.GetRemainAccounts(ctx, solClient, inputValueMint.String())
 	if err != nil {
-		log.Printf("GetRemainAccounts error: %v", err)
 		return nil, err
 	}
 
@@ -445,16 +369,21 @@ func (p *CLMMPool) BuildSwapInstructions(
 // RayCLMMSwapInstruction represents a swap instruction for the Raydium CLMM pool
 type RayCLMMSwapInstruction struct {
 	bin.BaseVariant
-	Amount                  uint64
-	OtherAmountThreshold    uint64
-	SqrtPriceLimitX64       uint128.Uint128
-	IsBaseInput             bool
+	Amount               uint64
+	OtherAmountThreshold uint64
+	SqrtPriceLimitX64    uint128.Uint128
+	IsBaseInput          bool
+	// Program is the CLMM program the instruction calls, ClmmProgramID when zero
+	Program                 solana.PublicKey `bin:"-" borsh_skip:"true"`
 	solana.AccountMetaSlice `bin:"-" borsh_skip:"true"`
 }
 
 // ProgramID returns the program ID for the Raydium CLMM program
 func (inst *RayCLMMSwapInstruction) ProgramID() solana.PublicKey {
-	return RAYDIUM_CLMM_PROGRAM_ID
+	if inst.Program.IsZero() {
+		return clmmProgramID
+	}
+	return inst.Program
 }
 
 // Accounts returns the account metas for the instruction
@@ -482,13 +411,13 @@ func (inst *RayCLMMSwapInstruction) Data() ([]byte, error) {
 		return nil, fmt.Errorf("failed to encode other amount threshold: %w", err)
 	}
 
-	// Write sqrt price limit x64
-	if err := bin.NewBorshEncoder(buf).WriteUint64(inst.SqrtPriceLimitX64.Hi, binary.LittleEndian); err != nil {
-		return nil, fmt.Errorf("failed to encode sqrt price limit hi: %w", err)
-	}
+	// Write sqrt price limit x64, a little-endian u128
 	if err := bin.NewBorshEncoder(buf).WriteUint64(inst.SqrtPriceLimitX64.Lo, binary.LittleEndian); err != nil {
 		return nil, fmt.Errorf("failed to encode sqrt price limit lo: %w", err)
 	}
+	if err := bin.NewBorshEncoder(buf).WriteUint64(inst.SqrtPriceLimitX64.Hi, binary.LittleEndian); err != nil {
+		return nil, fmt.Errorf("failed to encode sqrt price limit hi: %w", err)
+	}
 
 	// Write is base input
 	if err := bin.NewBorshEncoder(buf).WriteBool(inst.IsBaseInput); err != nil {
@@ -503,21 +432,96 @@ func (pool *CLMMPool) GetID() string {
 	return pool.PoolId.String()
 }
 
+// FeeRateBps returns the trade fee of the pool's config in basis points, rounded up from
+// hundredths of a basis point
+func (pool *CLMMPool) FeeRateBps() uint64 {
+	return (uint64(pool.FeeRate) + 99) / 100
+}
+
+// OpenedAt returns when the pool opened for trading
+func (pool *CLMMPool) OpenedAt() time.Time {
+	return time.Unix(int64(pool.OpenTime), 0)
+}
+
 // GetTokens returns the base and quote token mints
 func (pool *CLMMPool) GetTokens() (baseMint, quoteMint string) {
 	return pool.TokenMint0.String(), pool.TokenMint1.String()
 }
 
-func (pool *CLMMPool) Quote(ctx context.Context, solClient *rpc.Client, inputMint string, inputAmount cosmath.Int) (cosmath.Int, error) {
-	// update pool state first
+// TokenVaults returns the pool vaults, which are controlled by the pool state account
+func (pool *CLMMPool) TokenVaults() []pkg.TokenVault {
+	return []pkg.TokenVault{
+		{Address: pool.TokenVault0, Mint: pool.TokenMint0, Authority: pool.PoolId},
+		{Address: pool.TokenVault1, Mint: pool.TokenMint1, Authority: pool.PoolId},
+	}
+}
+
+// QuoteAccounts returns the bitmap extension and the tick arrays the current bitmap marks as
+// initialized around the current tick
+func (pool *CLMMPool) QuoteAccounts(inputMint string) []solana.PublicKey {
+	accounts := []solana.PublicKey{pool.ExBitmapAddress}
+	tickArrays, err := pool.GetTickArrayAddresses()
+	if err != nil {
+		return accounts
+	}
+	return append(accounts, tickArrays...)
+}
+
+// Quote returns the output of swapping inputAmount. A swap that would run out of liquidity
+// before consuming all of it fails with a *PartialFillError instead of quoting the part filled.
+func (pool *CLMMPool) Quote(ctx context.Context, solClient pkg.RPC, inputMint string, inputAmount cosmath.Int) (cosmath.Int, error) {
+	quote, err := pool.QuoteWithPriceLimit(ctx, solClient, inputMint, inputAmount, cosmath.ZeroInt())
+	if err != nil {
+		return cosmath.Int{}, err
+	}
+	if quote.PartialFill {
+		return cosmath.Int{}, quote.partialFillError(pool, inputAmount)
+	}
+	return quote.AmountOut, nil
+}
+
+// QuoteExactOut calculates the input amount required to receive desiredOut of outputMint
+func (pool *CLMMPool) QuoteExactOut(ctx context.Context, solClient pkg.RPC, outputMint string, desiredOut cosmath.Int) (cosmath.Int, error) {
+	if !desiredOut.IsPositive() {
+		return cosmath.Int{}, errors.New("output amount must be positive")
+	}
+	if err := pool.refreshTickArrays(ctx, solClient); err != nil {
+		return cosmath.Int{}, err
+	}
+
+	zeroForOne := outputMint == pool.TokenMint1.String()
+	firstTickArrayStartIndex, _, err := pool.getFirstInitializedTickArray(zeroForOne, pool.exTickArrayBitmap)
+	if err != nil {
+		return cosmath.Int{}, fmt.Errorf("failed to get first initialized tick array: %w", err)
+	}
+
+	// A negative amountSpecified switches swapCompute into exact-output mode
+	swap, err := pool.swapCompute(
+		int64(pool.TickCurrent),
+		zeroForOne,
+		desiredOut.Neg(),
+		cosmath.NewIntFromUint64(uint64(pool.FeeRate)),
+		firstTickArrayStartIndex,
+		pool.exTickArrayBitmap,
+		cosmath.ZeroInt(),
+	)
+	if err != nil {
+		return cosmath.Int{}, fmt.Errorf("failed to compute swap amount: %w", err)
+	}
+	if !swap.remaining.IsZero() {
+		return cosmath.Int{}, solerrors.ErrInsufficientLiquidity
+	}
+	return swap.amountCalculated, nil
+}
+
+// refreshTickArrays reloads the bitmap extension and the tick arrays around the current tick
+func (pool *CLMMPool) refreshTickArrays(ctx context.Context, solClient pkg.RPC) error {
 	results, err := solClient.GetMultipleAccountsWithOpts(ctx,
 		[]solana.PublicKey{pool.ExBitmapAddress},
-		&rpc.GetMultipleAccountsOpts{
-			Commitment: rpc.CommitmentProcessed,
-		},
+		sol.MultipleAccountsOpts(ctx, rpc.CommitmentProcessed),
 	)
 	if err != nil {
-		return cosmath.Int{}, fmt.Errorf("batch request failed: %v", err)
+		return fmt.Errorf("batch request failed: %v", err)
 	}
 	for _, result := range results.Value {
 		pool.ParseExBitmapInfo(result.Data.GetBinary())
@@ -525,20 +529,17 @@ func (pool *CLMMPool) Quote(ctx context.Context, solClient *rpc.Client, inputMin
 
 	tickArrayAddresses, err := pool.GetTickArrayAddresses()
 	if err != nil {
-		return cosmath.Int{}, fmt.Errorf("get tick array address error: %v", err)
+		return fmt.Errorf("get tick array address error: %v", err)
 	}
-	results, err = solClient.GetMultipleAccountsWithOpts(ctx, tickArrayAddresses, &rpc.GetMultipleAccountsOpts{
-		Commitment: rpc.CommitmentProcessed,
-	})
+	results, err = solClient.GetMultipleAccountsWithOpts(ctx, tickArrayAddresses, sol.MultipleAccountsOpts(ctx, rpc.CommitmentProcessed))
 	if err != nil {
-		log.Printf("batch request failed: %v", err)
-		return cosmath.Int{}, fmt.Errorf("batch request failed: %v", err)
+		return fmt.Errorf("batch request failed: %w", err)
 	}
 	for _, result := range results.Value {
 		tickArray := &TickArray{}
 		err := tickArray.Decode(result.Data.GetBinary())
 		if err != nil {
-			return cosmath.Int{}, fmt.Errorf("failed to decode tick array: %w", err)
+			return fmt.Errorf("failed to decode tick array: %w", err)
 		}
 		if pool.TickArrayCache == nil {
 			pool.TickArrayCache = make(map[string]TickArray)
@@ -546,46 +547,63 @@ func (pool *CLMMPool) Quote(ctx context.Context, solClient *rpc.Client, inputMin
 		pool.TickArrayCache[strconv.FormatInt(int64(tickArray.StartTickIndex), 10)] = *tickArray
 	}
 
-	if inputMint == pool.TokenMint0.String() {
-		priceBaseToQuote, err := pool.ComputeAmountOutFormat(pool.TokenMint0.String(), inputAmount)
-		if err != nil {
-			return cosmath.Int{}, err
-		}
-		return priceBaseToQuote.Neg(), nil
-	} else {
-		priceQuoteToBase, err := pool.ComputeAmountOutFormat(pool.TokenMint1.String(), inputAmount)
-		if err != nil {
-			return cosmath.Int{}, err
-		}
-		return priceQuoteToBase.Neg(), nil
-	}
+	return nil
 }
 
-// ComputeAmountOutFormat calculates the expected output amount for a given input amount
+// ComputeAmountOutFormat calculates the expected output amount for a given input amount,
+// negated. Swaps that only partially fill fail with ErrInsufficientLiquidity.
 func (pool *CLMMPool) ComputeAmountOutFormat(inputTokenMint string, inputAmount cosmath.Int) (cosmath.Int, error) {
+	swap, err := pool.computeExactIn(inputTokenMint, inputAmount, cosmath.ZeroInt())
+	if err != nil {
+		return cosmath.Int{}, err
+	}
+	if !swap.remaining.IsZero() {
+		return cosmath.Int{}, solerrors.ErrInsufficientLiquidity
+	}
+	return swap.amountCalculated, nil
+}
+
+// computeExactIn runs swapCompute for an exact input from the current tick
+func (pool *CLMMPool) computeExactIn(inputTokenMint string, inputAmount, sqrtPriceLimitX64 cosmath.Int) (clmmSwap, error) {
 	zeroForOne := inputTokenMint == pool.TokenMint0.String()
 
 	firstTickArrayStartIndex, _, err := pool.getFirstInitializedTickArray(zeroForOne, pool.exTickArrayBitmap)
 	if err != nil {
-		return cosmath.Int{}, fmt.Errorf("failed to get first initialized tick array: %w", err)
+		return clmmSwap{}, fmt.Errorf("failed to get first initialized tick array: %w", err)
 	}
 
-	expectedAmountOut, err := pool.swapCompute(
+	swap, err := pool.swapCompute(
 		int64(pool.TickCurrent),
 		zeroForOne,
 		inputAmount,
 		cosmath.NewIntFromUint64(uint64(pool.FeeRate)),
 		firstTickArrayStartIndex,
 		pool.exTickArrayBitmap,
+		sqrtPriceLimitX64,
 	)
 	if err != nil {
-		return cosmath.Int{}, fmt.Errorf("failed to compute swap amount: %w", err)
+		return clmmSwap{}, fmt.Errorf("failed to compute swap amount: %w", err)
 	}
+	return swap, nil
+}
 
-	return expectedAmountOut, nil
+// clmmSwap is the outcome of swapCompute
+type clmmSwap struct {
+	// amountCalculated is the negated output of an exact-input swap, or the input of an
+	// exact-output swap
+	amountCalculated cosmath.Int
+	// remaining is the part of the specified amount left unfilled, zero on a full fill
+	remaining cosmath.Int
+	// sqrtPriceX64 is the price the swap ends at
+	sqrtPriceX64 cosmath.Int
+	// liquidityExhausted is set when the swap stopped for lack of initialized ticks rather
+	// than at the price limit
+	liquidityExhausted bool
 }
 
-// swapCompute performs the core swap calculation logic
+// swapCompute performs the core swap calculation logic. The swap stops at sqrtPriceLimitX64,
+// or at the program's bounds when it is zero, or where liquidity runs out; what is left of
+// amountSpecified is reported as remaining.
 func (pool *CLMMPool) swapCompute(
 	currentTick int64,
 	zeroForOne bool,
@@ -593,13 +611,18 @@ func (pool *CLMMPool) swapCompute(
 	fee cosmath.Int,
 	lastSavedTickArrayStartIndex int64,
 	exTickArrayBitmap *TickArrayBitmapExtensionType,
-) (cosmath.Int, error) {
+	sqrtPriceLimitX64 cosmath.Int,
+) (clmmSwap, error) {
 	if amountSpecified.IsZero() {
-		return cosmath.Int{}, errors.New("input amount cannot be zero")
+		return clmmSwap{}, errors.New("input amount cannot be zero")
 	}
 
 	baseInput := amountSpecified.IsPositive()
-	sqrtPriceLimitX64 := cosmath.NewInt(0)
+	sqrtPriceLimitX64, err := pool.clampSqrtPriceLimit(zeroForOne, sqrtPriceLimitX64)
+	if err != nil {
+		return clmmSwap{}, err
+	}
+	liquidityExhausted := false
 
 	// Initialize calculation variables
 	amountSpecifiedRemaining := amountSpecified
@@ -627,12 +650,6 @@ func (pool *CLMMPool) swapCompute(
 	tickAarrayStartIndex := lastSavedTickArrayStartIndex
 	tickArrayCurrent := pool.TickArrayCache[strconv.FormatInt(lastSavedTickArrayStartIndex, 10)]
 
-	// Set price limits based on direction
-	if baseInput {
-		sqrtPriceLimitX64 = MIN_SQRT_PRICE_X64.Add(cosmath.NewInt(1))
-	} else {
-		sqrtPriceLimitX64 = MAX_SQRT_PRICE_X64.Sub(cosmath.NewInt(1))
-	}
 	t := !zeroForOne && int64(tickArrayCurrent.StartTickIndex) == tick
 
 	// Main swap calculation loop
@@ -657,21 +674,22 @@ func (pool *CLMMPool) swapCompute(
 				pool.TickArrayBitmap,
 				zeroForOne,
 			)
-			if err != nil {
-				return cosmath.Int{}, fmt.Errorf("failed to get next initialized tick array: %w", err)
+			if err != nil && !errors.Is(err, errTickArraysExhausted) {
+				return clmmSwap{}, fmt.Errorf("failed to get next initialized tick array: %w", err)
 			}
 			if !isExist {
-				return cosmath.Int{}, errors.New("insufficient liquidity")
+				liquidityExhausted = true
+				break
 			}
 
 			tickAarrayStartIndex := nextInitTickArrayIndex
-			expectedNextTickArrayAddress := getPdaTickArrayAddress(RAYDIUM_CLMM_PROGRAM_ID, pool.PoolId, tickAarrayStartIndex)
+			expectedNextTickArrayAddress := getPdaTickArrayAddress(pool.GetProgramID(), pool.PoolId, tickAarrayStartIndex)
 
 			tickArrayAddress = &expectedNextTickArrayAddress
 			tickArrayCurrent = pool.TickArrayCache[strconv.FormatInt(tickAarrayStartIndex, 10)]
 			nextInitTick, err = firstInitializedTick(&tickArrayCurrent, zeroForOne)
 			if err != nil {
-				return cosmath.Int{}, fmt.Errorf("failed to get first initialized tick: %w", err)
+				return clmmSwap{}, fmt.Errorf("failed to get first initialized tick: %w", err)
 			}
 		}
 
@@ -690,9 +708,9 @@ func (pool *CLMMPool) swapCompute(
 			tickNext = MAX_TICK
 		}
 
-		sqrtPriceNextX64, err := getSqrtPriceX64FromTick(int64(tickNext))
+		sqrtPriceNextX64, err := clmmmath.SqrtPriceX64FromTick(int64(tickNext))
 		if err != nil {
-			return cosmath.Int{}, fmt.Errorf("failed to get sqrt price from tick: %w", err)
+			return clmmSwap{}, fmt.Errorf("failed to get sqrt price from tick: %w", err)
 		}
 
 		// Calculate target price
@@ -705,14 +723,17 @@ func (pool *CLMMPool) swapCompute(
 		}
 
 		// Calculate swap step
-		sqrtPriceX64, amountIn, amountOut, feeAmount = swapStepCompute(
-			sqrtPriceX64.BigInt(),
-			targetPrice.BigInt(),
-			liquidity.BigInt(),
-			amountSpecifiedRemaining.BigInt(),
+		sqrtPriceX64, amountIn, amountOut, feeAmount, err = clmmmath.ComputeSwapStepInt(
+			sqrtPriceX64,
+			targetPrice,
+			liquidity,
+			amountSpecifiedRemaining,
 			uint32(fee.Int64()),
 			zeroForOne,
 		)
+		if err != nil {
+			return clmmSwap{}, fmt.Errorf("failed to compute swap step: %w", err)
+		}
 
 		// Update amounts
 		if baseInput {
@@ -739,9 +760,9 @@ func (pool *CLMMPool) swapCompute(
 				tick = tickNext
 			}
 		} else if sqrtPriceX64 != sqrtPriceStartX64 {
-			_T, err := getTickFromSqrtPriceX64(sqrtPriceX64)
+			_T, err := clmmmath.TickFromSqrtPriceX64(sqrtPriceX64)
 			if err != nil {
-				return cosmath.Int{}, fmt.Errorf("failed to get tick from sqrt price: %w", err)
+				return clmmSwap{}, fmt.Errorf("failed to get tick from sqrt price: %w", err)
 			}
 			t = _T != tick && !zeroForOne && int64(tickArrayCurrent.StartTickIndex) == _T
 			tick = _T
@@ -750,17 +771,22 @@ func (pool *CLMMPool) swapCompute(
 		// Safety check for infinite loops
 		loop++
 		if loop > 100 {
-			return cosmath.Int{}, errors.New("swap computation exceeded maximum iterations")
+			return clmmSwap{}, errors.New("swap computation exceeded maximum iterations")
 		}
 	}
 
-	return amountCalculated, nil
+	return clmmSwap{
+		amountCalculated:   amountCalculated,
+		remaining:          amountSpecifiedRemaining,
+		sqrtPriceX64:       sqrtPriceX64,
+		liquidityExhausted: liquidityExhausted,
+	}, nil
 }
 
 // GetRemainAccounts returns the remaining accounts needed for the swap
 func (pool *CLMMPool) GetRemainAccounts(
 	ctx context.Context,
-	client *rpc.Client,
+	client pkg.RPC,
 	inputTokenMint string,
 ) ([]solana.PublicKey, error) {
 	// Determine swap direction
@@ -784,7 +810,7 @@ func (pool *CLMMPool) GetRemainAccounts(
 		pool.exTickArrayBitmap,
 	)
 
-	exTickArrayBitmapAddress := getPdaTickArrayAddress(RAYDIUM_CLMM_PROGRAM_ID, pool.PoolId, tickAarrayStartIndex)
+	exTickArrayBitmapAddress := getPdaTickArrayAddress(pool.GetProgramID(), pool.PoolId, tickAarrayStartIndex)
 	if !exTickArrayBitmapAddress.Equals(firstTickArray) {
 		allNeededAccounts = append(allNeededAccounts, exTickArrayBitmapAddress)
 	}
@@ -0,0 +1,110 @@
+package raydium
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	cosmath "cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"lukechampine.com/uint128"
+)
+
+// CLMMQuote is an exact-input quote that may stop short of its input
+type CLMMQuote struct {
+	// AmountIn is the input the swap consumes, less than requested on a partial fill
+	AmountIn  cosmath.Int
+	AmountOut cosmath.Int
+	// SqrtPriceX64 is the price the swap ends at
+	SqrtPriceX64 cosmath.Int
+	// PartialFill is set when the swap reaches the price limit or runs out of liquidity
+	// before consuming the requested input
+	PartialFill bool
+	// AtLimit is set on a partial fill that stopped at the price limit
+	AtLimit bool
+}
+
+// PartialFillError is returned by Quote for swaps that would only partially fill. It matches
+// ErrInsufficientLiquidity.
+type PartialFillError struct {
+	PoolID    string
+	Requested cosmath.Int
+	// Filled is the input the swap consumes and AmountOut what it yields
+	Filled    cosmath.Int
+	AmountOut cosmath.Int
+}
+
+func (e *PartialFillError) Error() string {
+	return fmt.Sprintf("%s: pool %s fills %s of %s input", solerrors.ErrInsufficientLiquidity, e.PoolID, e.Filled, e.Requested)
+}
+
+func (e *PartialFillError) Unwrap() error {
+	return solerrors.ErrInsufficientLiquidity
+}
+
+func (q CLMMQuote) partialFillError(pool *CLMMPool, requested cosmath.Int) error {
+	return &PartialFillError{PoolID: pool.GetID(), Requested: requested, Filled: q.AmountIn, AmountOut: q.AmountOut}
+}
+
+// QuoteWithPriceLimit quotes an exact input that stops once the pool's sqrt price reaches
+// sqrtPriceLimitX64, as a swap built with the same limit does. A zero limit stops at the
+// program's price bounds only.
+func (pool *CLMMPool) QuoteWithPriceLimit(ctx context.Context, solClient pkg.RPC, inputMint string, inputAmount, sqrtPriceLimitX64 cosmath.Int) (CLMMQuote, error) {
+	if err := pool.refreshTickArrays(ctx, solClient); err != nil {
+		return CLMMQuote{}, err
+	}
+	swap, err := pool.computeExactIn(inputMint, inputAmount, sqrtPriceLimitX64)
+	if err != nil {
+		return CLMMQuote{}, err
+	}
+	partial := !swap.remaining.IsZero()
+	return CLMMQuote{
+		AmountIn:     inputAmount.Sub(swap.remaining),
+		AmountOut:    swap.amountCalculated.Neg(),
+		SqrtPriceX64: swap.sqrtPriceX64,
+		PartialFill:  partial,
+		AtLimit:      partial && !swap.liquidityExhausted,
+	}, nil
+}
+
+// BuildSwapInstructionsWithPriceLimit builds an exact-input swap that stops at
+// sqrtPriceLimitX64. The program then takes only the input QuoteWithPriceLimit reports, and
+// still checks minOut against the output.
+func (pool *CLMMPool) BuildSwapInstructionsWithPriceLimit(
+	ctx context.Context,
+	solClient pkg.RPC,
+	userAddr solana.PublicKey,
+	inputMint string,
+	amountIn cosmath.Int,
+	minOut cosmath.Int,
+	sqrtPriceLimitX64 cosmath.Int,
+) ([]solana.Instruction, error) {
+	limit, err := pool.clampSqrtPriceLimit(inputMint == pool.TokenMint0.String(), sqrtPriceLimitX64)
+	if err != nil {
+		return nil, err
+	}
+	return pool.buildSwapInstructions(ctx, solClient, userAddr, inputMint, amountIn, minOut, true, uint128.FromBig(limit.BigInt()))
+}
+
+// clampSqrtPriceLimit returns the limit the program enforces for sqrtPriceLimitX64: zero and
+// limits past the price bounds become the bound, and a limit on the wrong side of the current
+// price is rejected as the program would
+func (pool *CLMMPool) clampSqrtPriceLimit(zeroForOne bool, sqrtPriceLimitX64 cosmath.Int) (cosmath.Int, error) {
+	lower, upper := MIN_SQRT_PRICE_X64.AddRaw(1), MAX_SQRT_PRICE_X64.SubRaw(1)
+	if sqrtPriceLimitX64.IsNil() || sqrtPriceLimitX64.IsZero() {
+		if zeroForOne {
+			return lower, nil
+		}
+		return upper, nil
+	}
+	limit := cosmath.MinInt(cosmath.MaxInt(sqrtPriceLimitX64, lower), upper)
+	current := cosmath.NewIntFromBigInt(pool.SqrtPriceX64.Big())
+	if zeroForOne && limit.GTE(current) {
+		return cosmath.Int{}, fmt.Errorf("sqrt price limit %s must be below the current sqrt price %s", limit, current)
+	}
+	if !zeroForOne && limit.LTE(current) {
+		return cosmath.Int{}, fmt.Errorf("sqrt price limit %s must be above the current sqrt price %s", limit, current)
+	}
+	return limit, nil
+}
@@ -9,10 +9,12 @@ import (
 	"math/big"
 	"strconv"
 
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clmmmath"
+	"github.com/gtdvccc/SolRouteTmp/pkg/txbuilder"
 	cosmath "cosmossdk.io/math"
 	bin "github.com/gagliardetto/binary"
 	"github.com/gagliardetto/solana-go"
-	"github.com/gagliardetto/solana-go/rpc"
 	"lukechampine.com/uint128"
 )
 
@@ -44,6 +46,9 @@ type TickState struct {
 
 // Decode decodes the tick array data
 func (t *TickArray) Decode(data []byte) error {
+	if minSize := 8 + 32 + 4 + TICK_ARRAY_SIZE*TickSize + 1; len(data) < minSize {
+		return fmt.Errorf("tick array account too short: %d bytes", len(data))
+	}
 	decoder := bin.NewBinDecoder(data)
 
 	// Decode initial padding
@@ -111,14 +116,14 @@ func (p *CLMMPool) GetTickArrayAddresses() ([]solana.PublicKey, error) {
 	startIndexArray := p.getInitializedTickArrayInRange(10) // Get 10 tick arrays
 	tickArrayAddresses := make([]solana.PublicKey, 0, len(startIndexArray))
 	for _, itemIndex := range startIndexArray {
-		tickArrayAddress := getPdaTickArrayAddress(RAYDIUM_CLMM_PROGRAM_ID, p.PoolId, itemIndex)
+		tickArrayAddress := getPdaTickArrayAddress(p.GetProgramID(), p.PoolId, itemIndex)
 		tickArrayAddresses = append(tickArrayAddresses, tickArrayAddress)
 	}
 	return tickArrayAddresses, nil
 }
 
 // FetchPoolTickArrays fetches tick arrays for the pool
-func (p *CLMMPool) FetchPoolTickArrays(ctx context.Context, client *rpc.Client) error {
+func (p *CLMMPool) FetchPoolTickArrays(ctx context.Context, client pkg.RPC) error {
 	tickArrayAddresses, err := p.GetTickArrayAddresses()
 	if err != nil {
 		return fmt.Errorf("get tick array address error: %v", err)
@@ -155,29 +160,18 @@ func (p *CLMMPool) ParseExBitmapInfo(data []byte) {
 	copy(bitmap.PoolId[:], data[:32])
 	data = data[32:]
 
-	// Parse positiveTickArrayBitmap
-	positiveBitmaps := make([][]uint64, EXTENSION_TICKARRAY_BITMAP_SIZE)
-	for i := 0; i < EXTENSION_TICKARRAY_BITMAP_SIZE; i++ {
-		arr := make([]uint64, 8)
-		for j := 0; j < 8; j++ {
-			arr[j] = binary.LittleEndian.Uint64(data[j*8 : (j+1)*8])
-		}
-		positiveBitmaps[i] = arr
-		data = data[64:] // Skip 8 uint64s (64 bytes)
+	// Parse positiveTickArrayBitmap then negativeTickArrayBitmap, each a row of 8 uint64s
+	// per bitmap, into one backing array
+	words := make([]uint64, 2*EXTENSION_TICKARRAY_BITMAP_SIZE*8)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint64(data[i*8:])
 	}
-	bitmap.PositiveTickArrayBitmap = positiveBitmaps
-
-	// Parse negativeTickArrayBitmap
-	negativeBitmaps := make([][]uint64, EXTENSION_TICKARRAY_BITMAP_SIZE)
-	for i := 0; i < EXTENSION_TICKARRAY_BITMAP_SIZE; i++ {
-		arr := make([]uint64, 8)
-		for j := 0; j < 8; j++ {
-			arr[j] = binary.LittleEndian.Uint64(data[j*8 : (j+1)*8])
-		}
-		negativeBitmaps[i] = arr
-		data = data[64:] // Skip 8 uint64s (64 bytes)
+	bitmaps := make([][]uint64, 2*EXTENSION_TICKARRAY_BITMAP_SIZE)
+	for i := range bitmaps {
+		bitmaps[i] = words[i*8 : (i+1)*8 : (i+1)*8]
 	}
-	bitmap.NegativeTickArrayBitmap = negativeBitmaps
+	bitmap.PositiveTickArrayBitmap = bitmaps[:EXTENSION_TICKARRAY_BITMAP_SIZE]
+	bitmap.NegativeTickArrayBitmap = bitmaps[EXTENSION_TICKARRAY_BITMAP_SIZE:]
 
 	p.exTickArrayBitmap = &bitmap
 }
@@ -252,6 +246,23 @@ func getTickCount(tickSpacing int64) int64 {
 	return tickSpacing * TICK_ARRAY_SIZE
 }
 
+// tickArrayBitmapRows lists the bitmaps of 512 tick arrays each from the lowest start index:
+// the extension's negative bitmaps, the pool's own two halves, then the positive bitmaps.
+// Bits are tested on the words in place rather than merged into big.Ints on every search.
+func tickArrayBitmapRows(tickArrayBitmap *[16]uint64, exTickArrayBitmap *TickArrayBitmapExtensionType) [][]uint64 {
+	rows := make([][]uint64, 0, len(exTickArrayBitmap.NegativeTickArrayBitmap)+2+len(exTickArrayBitmap.PositiveTickArrayBitmap))
+	for i := len(exTickArrayBitmap.NegativeTickArrayBitmap) - 1; i >= 0; i-- {
+		rows = append(rows, exTickArrayBitmap.NegativeTickArrayBitmap[i])
+	}
+	rows = append(rows, tickArrayBitmap[0:8], tickArrayBitmap[8:16])
+	return append(rows, exTickArrayBitmap.PositiveTickArrayBitmap...)
+}
+
+// bitmapBit reports whether bit is set in a bitmap of little endian words
+func bitmapBit(words []uint64, bit int64) bool {
+	return words[bit/64]>>(bit%64)&1 == 1
+}
+
 func SearchLowBitFromStart(
 	tickArrayBitmap [16]uint64,
 	exTickArrayBitmap *TickArrayBitmapExtensionType,
@@ -259,23 +270,14 @@ func SearchLowBitFromStart(
 	expectedCount int64,
 	tickSpacing int64) []int64 {
 
-	var tickArrayBitmaps []*big.Int
-
-	for i := len(exTickArrayBitmap.NegativeTickArrayBitmap) - 1; i >= 0; i-- {
-		tickArrayBitmaps = append(tickArrayBitmaps, MergeTickArrayBitmap(exTickArrayBitmap.NegativeTickArrayBitmap[i]))
-	}
-	tickArrayBitmaps = append(tickArrayBitmaps, MergeTickArrayBitmap(tickArrayBitmap[0:8]))
-	tickArrayBitmaps = append(tickArrayBitmaps, MergeTickArrayBitmap(tickArrayBitmap[8:16]))
-	for _, bitmap := range exTickArrayBitmap.PositiveTickArrayBitmap {
-		tickArrayBitmaps = append(tickArrayBitmaps, MergeTickArrayBitmap(bitmap))
-	}
+	tickArrayBitmaps := tickArrayBitmapRows(&tickArrayBitmap, exTickArrayBitmap)
 
 	result := make([]int64, 0)
 	for currentTickArrayBitStartIndex >= -7680 {
 		arrayIndex := (currentTickArrayBitStartIndex + 7680) / 512
 		searchIndex := (currentTickArrayBitStartIndex + 7680) % 512
 
-		if tickArrayBitmaps[arrayIndex].Bit(int(searchIndex)) == 1 {
+		if bitmapBit(tickArrayBitmaps[arrayIndex], searchIndex) {
 			result = append(result, (currentTickArrayBitStartIndex))
 		}
 
@@ -301,26 +303,14 @@ func SearchHighBitFromStart(tickArrayBitmap [16]uint64,
 	expectedCount int64,
 	tickSpacing int64) []int64 {
 
-	var tickArrayBitmaps []*big.Int
-
-	for i := len(exTickArrayBitmap.NegativeTickArrayBitmap) - 1; i >= 0; i-- {
-		tickArrayBitmaps = append(tickArrayBitmaps, MergeTickArrayBitmap(exTickArrayBitmap.NegativeTickArrayBitmap[i]))
-	}
-
-	firstPart := MergeTickArrayBitmap(tickArrayBitmap[0:8])
-	secondPart := MergeTickArrayBitmap(tickArrayBitmap[8:16])
-	tickArrayBitmaps = append(tickArrayBitmaps, firstPart, secondPart)
-
-	for _, bitmap := range exTickArrayBitmap.PositiveTickArrayBitmap {
-		tickArrayBitmaps = append(tickArrayBitmaps, MergeTickArrayBitmap(bitmap))
-	}
+	tickArrayBitmaps := tickArrayBitmapRows(&tickArrayBitmap, exTickArrayBitmap)
 
 	result := make([]int64, 0)
 	for currentTickArrayBitStartIndex < 7680 {
 		arrayIndex := (currentTickArrayBitStartIndex + 7680) / 512
 		searchIndex := (currentTickArrayBitStartIndex + 7680) % 512
 
-		if tickArrayBitmaps[arrayIndex].Bit(int(searchIndex)) == 1 {
+		if bitmapBit(tickArrayBitmaps[arrayIndex], searchIndex) {
 			result = append(result, currentTickArrayBitStartIndex)
 		}
 
@@ -446,6 +436,10 @@ func ExtensionTickBoundary(tickSpacing int64) (int64, int64, error) {
 	return positiveTickBoundary, negativeTickBoundary, nil
 }
 
+// errTickArraysExhausted is returned when no initialized tick array is left in the swap
+// direction
+var errTickArraysExhausted = errors.New("error: out of range")
+
 func nextInitializedTickArrayStartIndexUtils(exTickArrayBitmap *TickArrayBitmapExtensionType, tickCurrent, tickSpacing int64, tickArrayBitmap [16]uint64,
 	zeroForOne bool) (bool, int64, error) {
 	lastTickArrayStartIndex := GetArrayStartIndex(tickCurrent, tickSpacing)
@@ -477,7 +471,7 @@ func nextInitializedTickArrayStartIndexUtils(exTickArrayBitmap *TickArrayBitmapE
 		lastTickArrayStartIndex = tickIndex
 
 		if lastTickArrayStartIndex < MIN_TICK || lastTickArrayStartIndex > MAX_TICK {
-			return false, 0, errors.New("error: out of range")
+			return false, 0, errTickArraysExhausted
 		}
 	}
 }
@@ -797,7 +791,7 @@ func (poolInfo *CLMMPool) getFirstInitializedTickArray(zeroForOne bool, exTickAr
 	if isInitialized {
 		// 3. 如果已初始化，获取其 PDA 地址
 		address := getPdaTickArrayAddress(
-			RAYDIUM_CLMM_PROGRAM_ID,
+			poolInfo.GetProgramID(),
 			poolInfo.PoolId,
 			startIndex,
 		)
@@ -817,7 +811,7 @@ func (poolInfo *CLMMPool) getFirstInitializedTickArray(zeroForOne bool, exTickAr
 	}
 	if isExist {
 		address := getPdaTickArrayAddress(
-			RAYDIUM_CLMM_PROGRAM_ID,
+			poolInfo.GetProgramID(),
 			poolInfo.PoolId,
 			nextStartIndex,
 		)
@@ -828,32 +822,19 @@ func (poolInfo *CLMMPool) getFirstInitializedTickArray(zeroForOne bool, exTickAr
 
 // getPdaTickArrayAddress 获取 tick array 的 PDA 地址
 func getPdaTickArrayAddress(programId solana.PublicKey, poolId solana.PublicKey, startIndex int64) solana.PublicKey {
-	startIndexBytes := i32ToBytes(startIndex)
-	seeds := [][]byte{
-		[]byte("tick_array"), poolId.Bytes(), startIndexBytes,
-	}
-	pk, _, _ := solana.FindProgramAddress(seeds, programId)
+	pk, _ := txbuilder.RaydiumClmmTickArray(programId, poolId, int32(startIndex))
 	return pk
 }
 
+// GetPdaExBitmapAccount derives the pool's tick array bitmap extension
 func GetPdaExBitmapAccount(programId solana.PublicKey, id solana.PublicKey) (solana.PublicKey, uint8, error) {
-	seeds := [][]byte{
-		[]byte("pool_tick_array_bitmap_extension"),
-		id.Bytes(),
-	}
-	return solana.FindProgramAddress(seeds, programId)
+	return txbuilder.RaydiumClmmBitmapExtension(programId, id)
 }
 
 func getTickArrayStartIndexByTick(tickIndex int64, tickSpacing int64) int64 {
 	return getTickArrayBitIndex(tickIndex, tickSpacing) * getTickCount(tickSpacing)
 }
 
-func i32ToBytes(num int64) []byte {
-	b := make([]byte, 4)
-	binary.BigEndian.PutUint32(b, uint32(num))
-	return b
-}
-
 // 添加一个辅助函数来正确解析 uint128
 func parseUint128LE(data []byte) uint128.Uint128 {
 	lo := binary.LittleEndian.Uint64(data[:8])
@@ -899,211 +880,19 @@ func IsZero(bitNum int, data *big.Int) bool {
 	return result.Sign() == 0
 }
 
+// Tick and price bounds, kept for callers of this package; the math lives in clmmmath
 const (
-	MinTick = -443636 // This should match your MIN_TICK constant
-	MaxTick = 443636  // This should match your MAX_TICK constant
+	MinTick = clmmmath.MinTick
+	MaxTick = clmmmath.MaxTick
 )
 
 var (
-	MaxUint128    = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
-	MaxUint128Int = cosmath.NewIntFromBigInt(MaxUint128)
+	MaxUint128      = clmmmath.MaxUint128
+	MaxUint128Int   = cosmath.NewIntFromBigInt(MaxUint128)
+	MaxSqrtPriceX64 = clmmmath.MaxSqrtPriceX64
+	MinSqrtPriceX64 = clmmmath.MinSqrtPriceX64
 )
 
-func mulRightShift(val, mulBy cosmath.Int) cosmath.Int {
-	// 先乘法
-	result := val.Mul(mulBy)
-
-	// 然后右移 64 位
-	// 2^64 = 18446744073709551616
-	pow64Big, ok := cosmath.NewIntFromString("18446744073709551616")
-	if !ok {
-		panic("failed to create pow64Big")
-	}
-
-	// 除以 2^64 相当于右移 64 位
-	return result.Quo(pow64Big)
-}
-
-// getSqrtPriceX64FromTick calculates the sqrt price from a tick value
-func getSqrtPriceX64FromTick(tick int64) (cosmath.Int, error) {
-	if tick < MinTick || tick > MaxTick {
-		return cosmath.Int{}, errors.New("tick must be in MIN_TICK and MAX_TICK")
-	}
-
-	tickAbs := tick
-	if tick < 0 {
-		tickAbs = -tick
-	}
-
-	ratio := cosmath.Int{}
-	if (tickAbs & 0x1) != 0 {
-		ratio, _ = cosmath.NewIntFromString("18445821805675395072")
-	} else {
-		ratio, _ = cosmath.NewIntFromString("18446744073709551616")
-	}
-
-	if (tickAbs & 0x2) != 0 {
-		mulBy, _ := cosmath.NewIntFromString("18444899583751176192")
-		ratio = mulRightShift(ratio, mulBy)
-	}
-	if (tickAbs & 0x4) != 0 {
-		mulBy, _ := cosmath.NewIntFromString("18443055278223355904")
-		ratio = mulRightShift(ratio, mulBy)
-	}
-	if (tickAbs & 0x8) != 0 {
-		mulBy, _ := cosmath.NewIntFromString("18439367220385607680")
-		ratio = mulRightShift(ratio, mulBy)
-	}
-	if (tickAbs & 0x10) != 0 {
-		mulBy, _ := cosmath.NewIntFromString("18431993317065453568")
-		ratio = mulRightShift(ratio, mulBy)
-	}
-	if (tickAbs & 0x20) != 0 {
-		mulBy, _ := cosmath.NewIntFromString("18417254355718170624")
-		ratio = mulRightShift(ratio, mulBy)
-	}
-	if (tickAbs & 0x40) != 0 {
-		mulBy, _ := cosmath.NewIntFromString("18387811781193609216")
-		ratio = mulRightShift(ratio, mulBy)
-	}
-	if (tickAbs & 0x80) != 0 {
-		mulBy, _ := cosmath.NewIntFromString("18329067761203558400")
-		ratio = mulRightShift(ratio, mulBy)
-	}
-	if (tickAbs & 0x100) != 0 {
-		mulBy, _ := cosmath.NewIntFromString("18212142134806163456")
-		ratio = mulRightShift(ratio, mulBy)
-	}
-	if (tickAbs & 0x200) != 0 {
-		mulBy, _ := cosmath.NewIntFromString("17980523815641700352")
-		ratio = mulRightShift(ratio, mulBy)
-	}
-	if (tickAbs & 0x400) != 0 {
-		mulBy, _ := cosmath.NewIntFromString("17526086738831433728")
-		ratio = mulRightShift(ratio, mulBy)
-	}
-	if (tickAbs & 0x800) != 0 {
-		mulBy, _ := cosmath.NewIntFromString("16651378430235570176")
-		ratio = mulRightShift(ratio, mulBy)
-	}
-	if (tickAbs & 0x1000) != 0 {
-		mulBy, _ := cosmath.NewIntFromString("15030750278694412288")
-		ratio = mulRightShift(ratio, mulBy)
-	}
-	if (tickAbs & 0x2000) != 0 {
-		mulBy, _ := cosmath.NewIntFromString("12247334978884435968")
-		ratio = mulRightShift(ratio, mulBy)
-	}
-	if (tickAbs & 0x4000) != 0 {
-		mulBy, _ := cosmath.NewIntFromString("8131365268886854656")
-		ratio = mulRightShift(ratio, mulBy)
-	}
-	if (tickAbs & 0x8000) != 0 {
-		mulBy, _ := cosmath.NewIntFromString("3584323654725218816")
-		ratio = mulRightShift(ratio, mulBy)
-	}
-	if (tickAbs & 0x10000) != 0 {
-		mulBy, _ := cosmath.NewIntFromString("696457651848324352")
-		ratio = mulRightShift(ratio, mulBy)
-	}
-	if (tickAbs & 0x20000) != 0 {
-		mulBy, _ := cosmath.NewIntFromString("26294789957507116")
-		ratio = mulRightShift(ratio, mulBy)
-	}
-	if (tickAbs & 0x40000) != 0 {
-		mulBy, _ := cosmath.NewIntFromString("37481735321082")
-		ratio = mulRightShift(ratio, mulBy)
-	}
-
-	if tick > 0 {
-		ratio = MaxUint128Int.Quo(ratio)
-	}
-
-	return ratio, nil
-}
-
-// Constants
-var (
-	MaxSqrtPriceX64, _        = cosmath.NewIntFromString("79226673515401279992447579055")
-	MinSqrtPriceX64, _        = cosmath.NewIntFromString("4295048016")
-	BitPrecision              = 14
-	LogB2X32, _               = cosmath.NewIntFromString("59543866431248")
-	LogBPErrMarginLowerX64, _ = cosmath.NewIntFromString("184467440737095516")
-	LogBPErrMarginUpperX64, _ = cosmath.NewIntFromString("15793534762490258745")
-)
-
-// signedLeftShift performs a left shift operation on a big.Int with sign handling
-func signedLeftShift(n *big.Int, shiftBy int, bitWidth int) *big.Int {
-	result := new(big.Int).Lsh(n, uint(shiftBy))
-	mask := new(big.Int).Lsh(big.NewInt(1), uint(bitWidth))
-	mask.Sub(mask, big.NewInt(1))
-	return new(big.Int).And(result, mask)
-}
-
-// signedRightShift performs a right shift operation on a big.Int with sign handling
-func signedRightShift(n *big.Int, shiftBy int, bitWidth int) *big.Int {
-	return new(big.Int).Rsh(n, uint(shiftBy))
-}
-
-func getTickFromSqrtPriceX64(sqrtPriceX64 cosmath.Int) (int64, error) {
-	if sqrtPriceX64.GT(MaxSqrtPriceX64) || sqrtPriceX64.LT(MinSqrtPriceX64) {
-		return 0, errors.New("provided sqrtPrice is not within the supported sqrtPrice range")
-	}
-
-	// Calculate MSB (most significant bit)
-	msb := sqrtPriceX64.BigInt().BitLen() - 1
-	adjustedMsb := big.NewInt(int64(msb - 64))
-	log2pIntegerX32 := signedLeftShift(adjustedMsb, 32, 128)
-
-	// Initialize variables for the loop
-	bit, _ := new(big.Int).SetString("8000000000000000", 16)
-	precision := 0
-	log2pFractionX64 := big.NewInt(0)
-
-	// Calculate initial r value
-	var r *big.Int
-	if msb >= 64 {
-		r = new(big.Int).Rsh(sqrtPriceX64.BigInt(), uint(msb-63))
-	} else {
-		r = new(big.Int).Lsh(sqrtPriceX64.BigInt(), uint(63-msb))
-	}
-
-	zero := big.NewInt(0)
-	for bit.Cmp(zero) > 0 && precision < BitPrecision {
-		r = new(big.Int).Mul(r, r)
-		rMoreThanTwo := new(big.Int).Rsh(r, 127)
-		r = new(big.Int).Rsh(r, uint(63+rMoreThanTwo.Int64()))
-		log2pFractionX64 = new(big.Int).Add(log2pFractionX64, new(big.Int).Mul(bit, rMoreThanTwo))
-		bit = new(big.Int).Rsh(bit, 1)
-		precision++
-	}
-
-	log2pFractionX32 := new(big.Int).Rsh(log2pFractionX64, 32)
-	log2pX32 := new(big.Int).Add(log2pIntegerX32, log2pFractionX32)
-	logbpX64 := new(big.Int).Mul(log2pX32, LogB2X32.BigInt())
-
-	tickLow := new(big.Int).Sub(logbpX64, LogBPErrMarginLowerX64.BigInt())
-	tickLow = signedRightShift(tickLow, 64, 128)
-
-	tickHigh := new(big.Int).Add(logbpX64, LogBPErrMarginUpperX64.BigInt())
-	tickHigh = signedRightShift(tickHigh, 64, 128)
-
-	if tickLow.Cmp(tickHigh) == 0 {
-		return tickLow.Int64(), nil
-	}
-
-	// Get sqrt price for high tick and compare
-	derivedTickHighSqrtPriceX64, err := getSqrtPriceX64FromTick(tickHigh.Int64())
-	if err != nil {
-		return 0, err
-	}
-
-	if derivedTickHighSqrtPriceX64.LTE(sqrtPriceX64) {
-		return tickHigh.Int64(), nil
-	}
-	return tickLow.Int64(), nil
-}
-
 // mergeBitmap 合并 bitmap
 func mergeBitmap(bns [16]uint64) uint64 {
 	var result uint64
@@ -1112,339 +901,3 @@ func mergeBitmap(bns [16]uint64) uint64 {
 	}
 	return result
 }
-
-type SwapStep struct {
-	SqrtPriceX64Next *big.Int
-	AmountIn         *big.Int
-	AmountOut        *big.Int
-	FeeAmount        *big.Int
-}
-
-// swapStepCompute calculates the next sqrt price, amounts in/out and fee amount for a single swap step
-func swapStepCompute(
-	sqrtPriceX64Current *big.Int,
-	sqrtPriceX64Target *big.Int,
-	liquidity *big.Int,
-	amountRemaining *big.Int,
-	feeRate uint32,
-	zeroForOne bool,
-) (cosmath.Int, cosmath.Int, cosmath.Int, cosmath.Int) {
-
-	swapStep := &SwapStep{
-		SqrtPriceX64Next: new(big.Int),
-		AmountIn:         new(big.Int),
-		AmountOut:        new(big.Int),
-		FeeAmount:        new(big.Int),
-	}
-
-	zero := new(big.Int)
-	baseInput := amountRemaining.Cmp(zero) >= 0
-
-	if baseInput {
-		feeRateBig := cosmath.NewInt(int64(feeRate))
-		tmp := FEE_RATE_DENOMINATOR.Sub(feeRateBig)
-		amountRemainingSubtractFee := mulDivFloor(cosmath.NewIntFromBigInt(amountRemaining), tmp, FEE_RATE_DENOMINATOR)
-		if zeroForOne {
-			swapStep.AmountIn = getTokenAmountAFromLiquidity(sqrtPriceX64Target, sqrtPriceX64Current, liquidity, true)
-		} else {
-			swapStep.AmountIn = getTokenAmountBFromLiquidity(sqrtPriceX64Current, sqrtPriceX64Target, liquidity, true)
-		}
-
-		if amountRemainingSubtractFee.GTE(cosmath.NewIntFromBigInt(swapStep.AmountIn)) {
-			swapStep.SqrtPriceX64Next.Set(sqrtPriceX64Target)
-		} else {
-			swapStep.SqrtPriceX64Next = getNextSqrtPriceX64FromInput(
-				sqrtPriceX64Current,
-				liquidity,
-				amountRemainingSubtractFee.BigInt(),
-				zeroForOne,
-			)
-		}
-	} else {
-		if zeroForOne {
-			swapStep.AmountOut = getTokenAmountBFromLiquidity(sqrtPriceX64Target, sqrtPriceX64Current, liquidity, false)
-		} else {
-			swapStep.AmountOut = getTokenAmountAFromLiquidity(sqrtPriceX64Current, sqrtPriceX64Target, liquidity, false)
-		}
-
-		negativeOne := new(big.Int).SetInt64(-1)
-		amountRemainingNeg := new(big.Int).Mul(amountRemaining, negativeOne)
-
-		if amountRemainingNeg.Cmp(swapStep.AmountOut) >= 0 {
-			swapStep.SqrtPriceX64Next.Set(sqrtPriceX64Target)
-		} else {
-			swapStep.SqrtPriceX64Next = getNextSqrtPriceX64FromOutput(
-				sqrtPriceX64Current,
-				liquidity,
-				amountRemainingNeg,
-				zeroForOne,
-			)
-		}
-	}
-
-	reachTargetPrice := swapStep.SqrtPriceX64Next.Cmp(sqrtPriceX64Target) == 0
-
-	if zeroForOne {
-		if !(reachTargetPrice && baseInput) {
-			swapStep.AmountIn = getTokenAmountAFromLiquidity(
-				swapStep.SqrtPriceX64Next,
-				sqrtPriceX64Current,
-				liquidity,
-				true,
-			)
-		}
-
-		if !(reachTargetPrice && !baseInput) {
-			swapStep.AmountOut = getTokenAmountBFromLiquidity(
-				swapStep.SqrtPriceX64Next,
-				sqrtPriceX64Current,
-				liquidity,
-				false,
-			)
-		}
-	} else {
-		if reachTargetPrice && baseInput {
-			// Keep existing amountIn
-		} else {
-			swapStep.AmountIn = getTokenAmountBFromLiquidity(
-				sqrtPriceX64Current,
-				swapStep.SqrtPriceX64Next,
-				liquidity,
-				true,
-			)
-		}
-
-		if reachTargetPrice && !baseInput {
-			// Keep existing amountOut
-		} else {
-			swapStep.AmountOut = getTokenAmountAFromLiquidity(
-				sqrtPriceX64Current,
-				swapStep.SqrtPriceX64Next,
-				liquidity,
-				false,
-			)
-		}
-	}
-
-	if !baseInput {
-		negativeOne := new(big.Int).SetInt64(-1)
-		amountRemainingNeg := new(big.Int).Mul(amountRemaining, negativeOne)
-		if swapStep.AmountOut.Cmp(amountRemainingNeg) > 0 {
-			swapStep.AmountOut.Set(amountRemainingNeg)
-		}
-	}
-
-	if baseInput && swapStep.SqrtPriceX64Next.Cmp(sqrtPriceX64Target) != 0 {
-		swapStep.FeeAmount = new(big.Int).Sub(amountRemaining, swapStep.AmountIn)
-	} else {
-		feeRateBig := cosmath.NewInt(int64(feeRate))
-		feeRateSubtracted := FEE_RATE_DENOMINATOR.Sub(feeRateBig)
-		swapStep.FeeAmount = mulDivCeil(cosmath.NewIntFromBigInt(swapStep.AmountIn), feeRateBig, feeRateSubtracted).BigInt()
-	}
-
-	return cosmath.NewIntFromBigInt(swapStep.SqrtPriceX64Next), cosmath.NewIntFromBigInt(swapStep.AmountIn),
-		cosmath.NewIntFromBigInt(swapStep.AmountOut), cosmath.NewIntFromBigInt(swapStep.FeeAmount)
-}
-
-// Helper function for ceiling division
-func mulDivCeil(a, b, denominator cosmath.Int) cosmath.Int {
-	// 检查除数是否为0
-	if denominator.IsZero() {
-		return cosmath.Int{}
-	}
-
-	// 计算 a * b
-	numerator := a.Mul(b).Add(denominator.Sub(cosmath.OneInt()))
-	// 计算最终结果 numerator / denominator
-	return numerator.Quo(denominator)
-}
-
-// getTokenAmountAFromLiquidity calculates token amount A from liquidity
-func getTokenAmountAFromLiquidity(
-	sqrtPriceX64A *big.Int,
-	sqrtPriceX64B *big.Int,
-	liquidity *big.Int,
-	roundUp bool,
-) *big.Int {
-	// Create copies to avoid modifying the original values
-	priceA := new(big.Int).Set(sqrtPriceX64A)
-	priceB := new(big.Int).Set(sqrtPriceX64B)
-
-	// Swap if priceA > priceB
-	if priceA.Cmp(priceB) > 0 {
-		priceA, priceB = priceB, priceA
-	}
-
-	// Check if priceA > 0
-	if priceA.Cmp(big.NewInt(0)) <= 0 {
-		panic("sqrtPriceX64A must be greater than 0")
-	}
-
-	// Calculate numerator1 = liquidity << U64Resolution
-	numerator1 := new(big.Int).Lsh(liquidity, U64Resolution)
-
-	// Calculate numerator2 = priceB - priceA
-	numerator2 := new(big.Int).Sub(priceB, priceA)
-
-	if roundUp {
-		// First calculate mulDivCeil(numerator1, numerator2, priceB)
-		temp := mulDivCeil(cosmath.NewIntFromBigInt(numerator1), cosmath.NewIntFromBigInt(numerator2), cosmath.NewIntFromBigInt(priceB))
-		// Then calculate mulDivCeil(temp, 1, priceA)
-		return mulDivCeil(temp, cosmath.NewIntFromBigInt(big.NewInt(1)), cosmath.NewIntFromBigInt(priceA)).BigInt()
-	} else {
-		// Calculate mulDivFloor(numerator1, numerator2, priceB)
-		temp := mulDivFloor(cosmath.NewIntFromBigInt(numerator1), cosmath.NewIntFromBigInt(numerator2), cosmath.NewIntFromBigInt(priceB))
-		// Then divide by priceA
-		return temp.Quo(cosmath.NewIntFromBigInt(priceA)).BigInt()
-	}
-}
-
-// getTokenAmountBFromLiquidity calculates token amount B from liquidity
-func getTokenAmountBFromLiquidity(
-	sqrtPriceX64A *big.Int,
-	sqrtPriceX64B *big.Int,
-	liquidity *big.Int,
-	roundUp bool,
-) *big.Int {
-	// Create copies to avoid modifying the original values
-	priceA := new(big.Int).Set(sqrtPriceX64A)
-	priceB := new(big.Int).Set(sqrtPriceX64B)
-
-	// Swap if priceA > priceB
-	if priceA.Cmp(priceB) > 0 {
-		priceA, priceB = priceB, priceA
-	}
-
-	// Check if priceA > 0
-	if priceA.Cmp(big.NewInt(0)) <= 0 {
-		panic("sqrtPriceX64A must be greater than 0")
-	}
-
-	// Calculate price difference
-	priceDiff := new(big.Int).Sub(priceB, priceA)
-
-	if roundUp {
-		return mulDivCeil(cosmath.NewIntFromBigInt(liquidity), cosmath.NewIntFromBigInt(priceDiff), cosmath.NewIntFromBigInt(new(big.Int).Lsh(big.NewInt(1), U64Resolution))).BigInt()
-	} else {
-		return mulDivFloor(cosmath.NewIntFromBigInt(liquidity), cosmath.NewIntFromBigInt(priceDiff), cosmath.NewIntFromBigInt(new(big.Int).Lsh(big.NewInt(1), U64Resolution))).BigInt()
-	}
-}
-
-// mulDivFloor performs multiplication and division with floor rounding
-func mulDivFloor(a, b, denominator cosmath.Int) cosmath.Int {
-	if denominator.IsZero() {
-		panic("division by zero")
-	}
-
-	numerator := a.Mul(b)
-	return numerator.Quo(denominator)
-}
-
-func getNextSqrtPriceX64FromInput(
-	sqrtPriceX64Current *big.Int,
-	liquidity *big.Int,
-	amount *big.Int,
-	zeroForOne bool,
-) *big.Int {
-
-	if sqrtPriceX64Current.Cmp(big.NewInt(0)) <= 0 {
-		panic("sqrtPriceX64Current must be greater than 0")
-	}
-	if liquidity.Cmp(big.NewInt(0)) <= 0 {
-		panic("liquidity must be greater than 0")
-	}
-
-	if amount.Cmp(big.NewInt(0)) == 0 {
-		return sqrtPriceX64Current
-	}
-
-	if zeroForOne {
-		return getNextSqrtPriceFromTokenAmountARoundingUp(sqrtPriceX64Current, liquidity, amount, true)
-	} else {
-		return getNextSqrtPriceFromTokenAmountBRoundingDown(sqrtPriceX64Current, liquidity, amount, true)
-	}
-}
-
-// getNextSqrtPriceX64FromOutput calculates the next sqrt price from output amount
-func getNextSqrtPriceX64FromOutput(
-	sqrtPriceX64Current *big.Int,
-	liquidity *big.Int,
-	amount *big.Int,
-	zeroForOne bool,
-) *big.Int {
-	if sqrtPriceX64Current.Cmp(big.NewInt(0)) <= 0 {
-		panic("sqrtPriceX64Current must be greater than 0")
-	}
-	if liquidity.Cmp(big.NewInt(0)) <= 0 {
-		panic("liquidity must be greater than 0")
-	}
-
-	if zeroForOne {
-		return getNextSqrtPriceFromTokenAmountBRoundingDown(sqrtPriceX64Current, liquidity, amount, false)
-	} else {
-		return getNextSqrtPriceFromTokenAmountARoundingUp(sqrtPriceX64Current, liquidity, amount, false)
-	}
-}
-
-func getNextSqrtPriceFromTokenAmountARoundingUp(
-	sqrtPriceX64 *big.Int,
-	liquidity *big.Int,
-	amount *big.Int,
-	add bool,
-) *big.Int {
-
-	if amount.Cmp(big.NewInt(0)) == 0 {
-		return sqrtPriceX64
-	}
-
-	liquidityLeftShift := new(big.Int).Lsh(liquidity, U64Resolution)
-
-	if add {
-		numerator1 := liquidityLeftShift
-		denominator := new(big.Int).Add(liquidityLeftShift, new(big.Int).Mul(amount, sqrtPriceX64))
-		if denominator.Cmp(numerator1) >= 0 {
-			return mulDivCeil(cosmath.NewIntFromBigInt(numerator1), cosmath.NewIntFromBigInt(sqrtPriceX64), cosmath.NewIntFromBigInt(denominator)).BigInt()
-		}
-
-		temp := new(big.Int).Div(numerator1, sqrtPriceX64)
-		temp.Add(temp, amount)
-		return mulDivRoundingUp(numerator1, big.NewInt(1), temp)
-	} else {
-		amountMulSqrtPrice := new(big.Int).Mul(amount, sqrtPriceX64)
-		if liquidityLeftShift.Cmp(amountMulSqrtPrice) <= 0 {
-			panic("getNextSqrtPriceFromTokenAmountARoundingUp: liquidityLeftShift must be greater than amountMulSqrtPrice")
-		}
-		denominator := new(big.Int).Sub(liquidityLeftShift, amountMulSqrtPrice)
-		return mulDivCeil(cosmath.NewIntFromBigInt(liquidityLeftShift), cosmath.NewIntFromBigInt(sqrtPriceX64), cosmath.NewIntFromBigInt(denominator)).BigInt()
-	}
-}
-
-// getNextSqrtPriceFromTokenAmountBRoundingDown calculates next sqrt price from token B amount
-func getNextSqrtPriceFromTokenAmountBRoundingDown(
-	sqrtPriceX64 *big.Int,
-	liquidity *big.Int,
-	amount *big.Int,
-	add bool,
-) *big.Int {
-	deltaY := new(big.Int).Lsh(amount, U64Resolution)
-
-	if add {
-		return new(big.Int).Add(sqrtPriceX64, new(big.Int).Div(deltaY, liquidity))
-	} else {
-		amountDivLiquidity := mulDivRoundingUp(deltaY, big.NewInt(1), liquidity)
-		if sqrtPriceX64.Cmp(amountDivLiquidity) <= 0 {
-			panic("getNextSqrtPriceFromTokenAmountBRoundingDown: sqrtPriceX64 must be greater than amountDivLiquidity")
-		}
-		return new(big.Int).Sub(sqrtPriceX64, amountDivLiquidity)
-	}
-}
-
-// mulDivRoundingUp performs multiplication and division with ceiling rounding
-func mulDivRoundingUp(a, b, denominator *big.Int) *big.Int {
-	numerator := new(big.Int).Mul(a, b)
-	result := new(big.Int).Div(numerator, denominator)
-	if !new(big.Int).Mod(numerator, denominator).IsInt64() {
-		result.Add(result, big.NewInt(1))
-	}
-	return result
-}
@@ -0,0 +1,75 @@
+package raydium
+
+import (
+	"iter"
+	"sort"
+)
+
+// NextInitializedTickArray returns the start index of the next initialized tick array past
+// the one holding tick, searching down for zeroForOne swaps and up otherwise. It reads the
+// pool's bitmap and, when loaded, its bitmap extension.
+func (pool *CLMMPool) NextInitializedTickArray(tick int64, zeroForOne bool) (int64, bool) {
+	extension := pool.exTickArrayBitmap
+	if extension == nil {
+		extension = emptyTickArrayBitmapExtension()
+	}
+	return nextInitializedTickArray(tick, int64(pool.TickSpacing), zeroForOne, pool.TickArrayBitmap, extension)
+}
+
+// FirstInitializedTick returns the first tick with liquidity a swap entering the array meets:
+// the highest for zeroForOne swaps and the lowest otherwise
+func (t *TickArray) FirstInitializedTick(zeroForOne bool) (*TickState, error) {
+	return firstInitializedTick(t, zeroForOne)
+}
+
+// InitializedTicks yields the initialized ticks a swap in the direction would cross, in the
+// order it crosses them, keyed by tick index: ticks at or below the current tick for
+// zeroForOne swaps and above it otherwise. Only arrays in TickArrayCache are walked, so
+// load them first with a quote or FetchPoolTickArrays.
+func (pool *CLMMPool) InitializedTicks(zeroForOne bool) iter.Seq2[int32, TickState] {
+	return func(yield func(int32, TickState) bool) {
+		tickArrays := make([]TickArray, 0, len(pool.TickArrayCache))
+		for _, tickArray := range pool.TickArrayCache {
+			tickArrays = append(tickArrays, tickArray)
+		}
+		sort.Slice(tickArrays, func(i, j int) bool {
+			if zeroForOne {
+				return tickArrays[i].StartTickIndex > tickArrays[j].StartTickIndex
+			}
+			return tickArrays[i].StartTickIndex < tickArrays[j].StartTickIndex
+		})
+
+		for _, tickArray := range tickArrays {
+			for i := range tickArray.Ticks {
+				if zeroForOne {
+					i = len(tickArray.Ticks) - 1 - i
+				}
+				tick := tickArray.Ticks[i]
+				index := tickArray.StartTickIndex + int32(i)*int32(pool.TickSpacing)
+				if tick.LiquidityGross.IsZero() {
+					continue
+				}
+				if (zeroForOne && index > pool.TickCurrent) || (!zeroForOne && index <= pool.TickCurrent) {
+					continue
+				}
+				if !yield(index, tick) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// emptyTickArrayBitmapExtension stands in for the extension of pools whose extension account
+// hasn't been loaded; the bitmap searches index into it unconditionally
+func emptyTickArrayBitmapExtension() *TickArrayBitmapExtensionType {
+	extension := &TickArrayBitmapExtensionType{
+		PositiveTickArrayBitmap: make([][]uint64, EXTENSION_TICKARRAY_BITMAP_SIZE),
+		NegativeTickArrayBitmap: make([][]uint64, EXTENSION_TICKARRAY_BITMAP_SIZE),
+	}
+	for i := range EXTENSION_TICKARRAY_BITMAP_SIZE {
+		extension.PositiveTickArrayBitmap[i] = make([]uint64, 8)
+		extension.NegativeTickArrayBitmap[i] = make([]uint64, 8)
+	}
+	return extension
+}
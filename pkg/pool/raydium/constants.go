@@ -1,8 +1,10 @@
 package raydium
 
 import (
+	"fmt"
 	"math/big"
 
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
 	"cosmossdk.io/math"
 	"github.com/gagliardetto/solana-go"
 )
@@ -14,12 +16,41 @@ var (
 	MEMO_PROGRAM_ID       = solana.MustPublicKeyFromBase58("MemoSq4gqABAXKb96qnH8TysNcWxMyWCqXgDLGmfcHr")
 
 	// Raydium Program IDs
-	RAYDIUM_AMM_PROGRAM_ID         = solana.MustPublicKeyFromBase58("675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8")
-	RAYDIUM_CPMM_PROGRAM_ID        = solana.MustPublicKeyFromBase58("CPMMoo8L3F4NbTegBCKVNunggL7H1ZpdTHKxQB5qKP1C")
-	RAYDIUM_CLMM_PROGRAM_ID        = solana.MustPublicKeyFromBase58("CAMMCzo5YL8w4VFF8KVHrK22GGUsp5VTaW7grrKgrWqK")
-	RAYDIUM_CLMM_DEVNET_PROGRAM_ID = solana.MustPublicKeyFromBase58("DRayAUgENGQBKVaX8owNhgzkEDyoHTGVEGHVJT1E9pfH")
+	RAYDIUM_AMM_PROGRAM_ID       = solana.MustPublicKeyFromBase58("675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8")
+	RAYDIUM_CPMM_PROGRAM_ID      = solana.MustPublicKeyFromBase58("CPMMoo8L3F4NbTegBCKVNunggL7H1ZpdTHKxQB5qKP1C")
+	RAYDIUM_LAUNCHLAB_PROGRAM_ID = solana.MustPublicKeyFromBase58("LanMV9sAd7wArD4vJFi2qDdfnVhFxYSUg6eADduJ3uj")
 )
 
+// CLMM program IDs, which differ between clusters. They are unexported so no caller can
+// repoint every pool in the process; pools and protocols carry the one they use.
+var (
+	clmmProgramID       = solana.MustPublicKeyFromBase58("CAMMCzo5YL8w4VFF8KVHrK22GGUsp5VTaW7grrKgrWqK")
+	clmmDevnetProgramID = solana.MustPublicKeyFromBase58("DRayAUgENGQBKVaX8owNhgzkEDyoHTGVEGHVJT1E9pfH")
+)
+
+// ClmmProgramID returns the Raydium CLMM program ID on mainnet
+func ClmmProgramID() solana.PublicKey {
+	return clmmProgramID
+}
+
+// ClmmDevnetProgramID returns the Raydium CLMM program ID on devnet
+func ClmmDevnetProgramID() solana.PublicKey {
+	return clmmDevnetProgramID
+}
+
+// ClmmProgramIDForCluster returns the Raydium CLMM program ID on the cluster; custom clusters
+// get mainnet's
+func ClmmProgramIDForCluster(cluster sol.Cluster) (solana.PublicKey, error) {
+	switch cluster {
+	case sol.ClusterMainnet, sol.ClusterCustom:
+		return clmmProgramID, nil
+	case sol.ClusterDevnet:
+		return clmmDevnetProgramID, nil
+	default:
+		return solana.PublicKey{}, fmt.Errorf("raydium clmm is not deployed on %s", cluster)
+	}
+}
+
 // Tick Array Configuration
 const (
 	TICK_ARRAY_SIZE                 = 60
@@ -44,8 +75,39 @@ var (
 	LIQUIDITY_FEES_DENOMINATOR = math.NewInt(10000)
 )
 
+// AMM statuses that allow swaps: Initialized, SwapOnly and WaitingTrade
+var ammSwapStatuses = map[uint64]bool{
+	1: true,
+	6: true,
+	7: true,
+}
+
 // Seeds and Discriminators
 var (
-	AUTH_SEED                  = "vault_and_lp_mint_auth_seed"
-	SwapBaseInputDiscriminator = []byte{143, 190, 90, 218, 196, 30, 51, 222}
+	AUTH_SEED                   = "vault_and_lp_mint_auth_seed"
+	SwapBaseInputDiscriminator  = []byte{143, 190, 90, 218, 196, 30, 51, 222}
+	SwapBaseOutputDiscriminator = []byte{55, 217, 98, 86, 163, 74, 180, 173}
 )
+
+// LaunchLab seeds and discriminators
+var (
+	LAUNCHLAB_AUTH_SEED            = "vault_auth_seed"
+	LAUNCHLAB_EVENT_AUTHORITY_SEED = "__event_authority"
+
+	LaunchLabPoolDiscriminator = []byte{247, 237, 227, 245, 215, 195, 222, 70}
+	LaunchLabBuyExactInDiscm   = []byte{250, 234, 13, 123, 213, 156, 19, 236}
+	LaunchLabSellExactInDiscm  = []byte{149, 39, 222, 155, 211, 124, 152, 26}
+	LaunchLabBuyExactOutDiscm  = []byte{24, 211, 116, 40, 105, 3, 153, 56}
+	LaunchLabSellExactOutDiscm = []byte{95, 200, 71, 34, 8, 9, 11, 166}
+)
+
+// LaunchLab bonding curve types, set by the pool's global config
+const (
+	LaunchLabCurveConstantProduct uint8 = 0
+	LaunchLabCurveFixedPrice      uint8 = 1
+	LaunchLabCurveLinearPrice     uint8 = 2
+)
+
+// LaunchLabStatusTrading is the status of pools still selling on their curve; later statuses
+// are migrating or graduated to an AMM pool
+const LaunchLabStatusTrading uint8 = 0
@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"time"
 
 	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gtdvccc/SolRouteTmp/pkg/txbuilder"
 	"cosmossdk.io/math"
 	cosmath "cosmossdk.io/math"
 	bin "github.com/gagliardetto/binary"
@@ -50,6 +53,14 @@ type CPMMPool struct {
 	QuoteDecimal     uint64
 	BaseNeedTakePnl  uint64
 	QuoteNeedTakePnl uint64
+
+	// TradeFeeRate is the trade fee of the pool's AmmConfig in millionths of the input, set
+	// when the pool is discovered
+	TradeFeeRate uint64
+
+	// Transfer fees in force for the mints as of the last refresh, zero for SPL Token mints
+	token0TransferFee sol.TransferFee
+	token1TransferFee sol.TransferFee
 }
 
 func (pool *CPMMPool) ProtocolName() pkg.ProtocolName {
@@ -64,41 +75,75 @@ func (pool *CPMMPool) GetProgramID() solana.PublicKey {
 	return RAYDIUM_CPMM_PROGRAM_ID
 }
 
+// Decode parses a pool account through CpmmPoolState
 func (p *CPMMPool) Decode(data []byte) error {
-	if len(data) > 8 {
-		data = data[8:]
+	var state CpmmPoolState
+	if err := cpmmPoolLayout.Decode(data, &state); err != nil {
+		return err
 	}
-
-	dec := bin.NewBinDecoder(data)
-	return dec.Decode(p)
+	p.AmmConfig = state.AmmConfig
+	p.PoolCreator = state.PoolCreator
+	p.Token0Vault = state.Token0Vault
+	p.Token1Vault = state.Token1Vault
+	p.LpMint = state.LpMint
+	p.Token0Mint = state.Token0Mint
+	p.Token1Mint = state.Token1Mint
+	p.Token0Program = state.Token0Program
+	p.Token1Program = state.Token1Program
+	p.ObservationKey = state.ObservationKey
+	p.AuthBump = state.AuthBump
+	p.Status = state.Status
+	p.LpMintDecimals = state.LpMintDecimals
+	p.Mint0Decimals = state.Mint0Decimals
+	p.Mint1Decimals = state.Mint1Decimals
+	p.LpSupply = state.LpSupply
+	p.ProtocolFeesToken0 = state.ProtocolFeesToken0
+	p.ProtocolFeesToken1 = state.ProtocolFeesToken1
+	p.FundFeesToken0 = state.FundFeesToken0
+	p.FundFeesToken1 = state.FundFeesToken1
+	p.OpenTime = state.OpenTime
+	return nil
 }
 
+// Span returns the account size, 637 bytes including the discriminator
 func (p *CPMMPool) Span() uint64 {
-	return 584 // Total size in bytes (including discriminator)
+	return cpmmPoolLayout.Size()
 }
 
+// Offset returns the offset of a CpmmPoolState field, or 0 for unknown fields
 func (p *CPMMPool) Offset(field string) uint64 {
-	switch field {
-	case "Token0Mint":
-		return 8 + 32*5 // discriminator + 5 pubkeys
-	case "Token1Mint":
-		return 8 + 32*6 // discriminator + 6 pubkeys
-	default:
-		return 0
-	}
+	offset, _ := cpmmPoolLayout.Offset(field)
+	return offset
 }
 
 func (pool *CPMMPool) GetID() string {
 	return pool.PoolId.String()
 }
 
+// OpenedAt returns when the pool opened for trading
+func (pool *CPMMPool) OpenedAt() time.Time {
+	return time.Unix(int64(pool.OpenTime), 0)
+}
+
 func (pool *CPMMPool) GetTokens() (string, string) {
 	return pool.Token0Mint.String(), pool.Token1Mint.String()
 }
 
+// TokenVaults returns the pool vaults, which are controlled by the program authority PDA
+func (pool *CPMMPool) TokenVaults() []pkg.TokenVault {
+	authority, _, _ := getAuthorityPDA()
+	return []pkg.TokenVault{
+		{Address: pool.Token0Vault, Mint: pool.Token0Mint, Authority: authority},
+		{Address: pool.Token1Vault, Mint: pool.Token1Mint, Authority: authority},
+	}
+}
+
+// BuildSwapInstructions builds a swap_base_input instruction. The program checks
+// minOutAmountWithDecimals against what the user receives after the output mint's transfer
+// fee, which is what Quote returns.
 func (pool *CPMMPool) BuildSwapInstructions(
 	ctx context.Context,
-	solClient *rpc.Client,
+	solClient pkg.RPC,
 	userAddr solana.PublicKey,
 	inputMint string,
 	amountIn math.Int,
@@ -108,32 +153,65 @@ func (pool *CPMMPool) BuildSwapInstructions(
 	// 初始化指令数组
 	instrs := []solana.Instruction{}
 
-	var inputValueMint solana.PublicKey
-	if inputMint == pool.Token0Mint.String() {
-		inputValueMint = pool.Token0Mint
-	} else {
-		inputValueMint = pool.Token1Mint
-	}
-
-	var fromAccount solana.PublicKey
-	var toAccount solana.PublicKey
-	if inputValueMint.String() == pool.Token0Mint.String() {
-		fromAccount = pool.UserBaseAccount
-		toAccount = pool.UserQuoteAccount
-	} else {
-		fromAccount = pool.UserQuoteAccount
-		toAccount = pool.UserBaseAccount
+	accounts, err := pool.swapAccountMetas(userAddr, inputMint == pool.Token0Mint.String())
+	if err != nil {
+		return nil, err
 	}
 
 	// 创建 swap 指令
 	swapInst := CPMMSwapInstruction{
 		InAmount:         amountIn.Uint64(),
 		MinimumOutAmount: minOutAmountWithDecimals.Uint64(),
-		AccountMetaSlice: make(solana.AccountMetaSlice, 13),
+		AccountMetaSlice: accounts,
 	}
 	swapInst.BaseVariant = bin.BaseVariant{
 		Impl: swapInst,
 	}
+	instrs = append(instrs, &swapInst)
+
+	return instrs, nil
+}
+
+// BuildSwapInstructionsExactOut builds a swap_base_output instruction that receives
+// exactly amountOut of outputMint while spending at most maxIn. Both are what the user
+// receives and sends, transfer fees included, as QuoteExactOut computes them.
+func (pool *CPMMPool) BuildSwapInstructionsExactOut(
+	ctx context.Context,
+	solClient pkg.RPC,
+	userAddr solana.PublicKey,
+	outputMint string,
+	amountOut math.Int,
+	maxIn math.Int,
+) ([]solana.Instruction, error) {
+	accounts, err := pool.swapAccountMetas(userAddr, outputMint == pool.Token1Mint.String())
+	if err != nil {
+		return nil, err
+	}
+
+	swapInst := CPMMSwapBaseOutputInstruction{
+		MaxAmountIn:      maxIn.Uint64(),
+		AmountOut:        amountOut.Uint64(),
+		AccountMetaSlice: accounts,
+	}
+	swapInst.BaseVariant = bin.BaseVariant{
+		Impl: swapInst,
+	}
+	return []solana.Instruction{&swapInst}, nil
+}
+
+// swapAccountMetas returns the account list shared by swap_base_input and swap_base_output,
+// ordered for the given swap direction
+func (pool *CPMMPool) swapAccountMetas(userAddr solana.PublicKey, zeroForOne bool) (solana.AccountMetaSlice, error) {
+	fromAccount, toAccount := pool.UserBaseAccount, pool.UserQuoteAccount
+	inputVault, outputVault := pool.Token0Vault, pool.Token1Vault
+	inputMint, outputMint := pool.Token0Mint, pool.Token1Mint
+	inputProgram, outputProgram := tokenProgram(pool.Token0Program), tokenProgram(pool.Token1Program)
+	if !zeroForOne {
+		fromAccount, toAccount = toAccount, fromAccount
+		inputVault, outputVault = outputVault, inputVault
+		inputMint, outputMint = outputMint, inputMint
+		inputProgram, outputProgram = outputProgram, inputProgram
+	}
 
 	// Get the authority PDA
 	authority, _, err := getAuthorityPDA()
@@ -141,22 +219,21 @@ func (pool *CPMMPool) BuildSwapInstructions(
 		return nil, fmt.Errorf("failed to get authority PDA: %v", err)
 	}
 	// 设置账户
-	swapInst.AccountMetaSlice[0] = solana.NewAccountMeta(userAddr, true, true)                // payer
-	swapInst.AccountMetaSlice[1] = solana.NewAccountMeta(authority, false, false)             // authority
-	swapInst.AccountMetaSlice[2] = solana.NewAccountMeta(pool.AmmConfig, false, false)        // amm_config
-	swapInst.AccountMetaSlice[3] = solana.NewAccountMeta(pool.PoolId, true, false)            // pool_state
-	swapInst.AccountMetaSlice[4] = solana.NewAccountMeta(fromAccount, true, false)            // input_token_account
-	swapInst.AccountMetaSlice[5] = solana.NewAccountMeta(toAccount, true, false)              // output_token_account
-	swapInst.AccountMetaSlice[6] = solana.NewAccountMeta(pool.Token0Vault, true, false)       // input_vault
-	swapInst.AccountMetaSlice[7] = solana.NewAccountMeta(pool.Token1Vault, true, false)       // output_vault
-	swapInst.AccountMetaSlice[8] = solana.NewAccountMeta(solana.TokenProgramID, false, false) // input_token_program
-	swapInst.AccountMetaSlice[9] = solana.NewAccountMeta(solana.TokenProgramID, false, false) // output_token_program
-	swapInst.AccountMetaSlice[10] = solana.NewAccountMeta(pool.Token0Mint, false, false)      // input_token_mint
-	swapInst.AccountMetaSlice[11] = solana.NewAccountMeta(pool.Token1Mint, false, false)      // output_token_mint
-	swapInst.AccountMetaSlice[12] = solana.NewAccountMeta(pool.ObservationKey, true, false)   // observation_state
-	instrs = append(instrs, &swapInst)
-
-	return instrs, nil
+	accounts := make(solana.AccountMetaSlice, 13)
+	accounts[0] = solana.NewAccountMeta(userAddr, true, true)              // payer
+	accounts[1] = solana.NewAccountMeta(authority, false, false)           // authority
+	accounts[2] = solana.NewAccountMeta(pool.AmmConfig, false, false)      // amm_config
+	accounts[3] = solana.NewAccountMeta(pool.PoolId, true, false)          // pool_state
+	accounts[4] = solana.NewAccountMeta(fromAccount, true, false)          // input_token_account
+	accounts[5] = solana.NewAccountMeta(toAccount, true, false)            // output_token_account
+	accounts[6] = solana.NewAccountMeta(inputVault, true, false)           // input_vault
+	accounts[7] = solana.NewAccountMeta(outputVault, true, false)          // output_vault
+	accounts[8] = solana.NewAccountMeta(inputProgram, false, false)        // input_token_program
+	accounts[9] = solana.NewAccountMeta(outputProgram, false, false)       // output_token_program
+	accounts[10] = solana.NewAccountMeta(inputMint, false, false)          // input_token_mint
+	accounts[11] = solana.NewAccountMeta(outputMint, false, false)         // output_token_mint
+	accounts[12] = solana.NewAccountMeta(pool.ObservationKey, true, false) // observation_state
+	return accounts, nil
 }
 
 // CPMMSwapInstruction represents the data for a CPMM swap instruction
@@ -191,52 +268,52 @@ func (inst *CPMMSwapInstruction) Data() ([]byte, error) {
 	return data, nil
 }
 
+// CPMMSwapBaseOutputInstruction represents the data for a CPMM swap_base_output instruction
+type CPMMSwapBaseOutputInstruction struct {
+	bin.BaseVariant
+	MaxAmountIn             uint64
+	AmountOut               uint64
+	solana.AccountMetaSlice `bin:"-" borsh_skip:"true"`
+}
+
+func (inst *CPMMSwapBaseOutputInstruction) ProgramID() solana.PublicKey {
+	return RAYDIUM_CPMM_PROGRAM_ID
+}
+
+func (inst *CPMMSwapBaseOutputInstruction) Accounts() (out []*solana.AccountMeta) {
+	return inst.AccountMetaSlice
+}
+
+func (inst *CPMMSwapBaseOutputInstruction) Data() ([]byte, error) {
+	data := make([]byte, 8+8+8) // discriminator(8) + max_amount_in(8) + amount_out(8)
+	copy(data[0:8], SwapBaseOutputDiscriminator)
+	binary.LittleEndian.PutUint64(data[8:16], inst.MaxAmountIn)
+	binary.LittleEndian.PutUint64(data[16:24], inst.AmountOut)
+	return data, nil
+}
+
 // Add a helper function to get the authority PDA
 func getAuthorityPDA() (solana.PublicKey, uint8, error) {
-	seeds := [][]byte{
-		[]byte(AUTH_SEED),
-	}
-	authority, bump, err := solana.FindProgramAddress(seeds, RAYDIUM_CPMM_PROGRAM_ID)
+	authority, bump, err := txbuilder.RaydiumCpmmAuthority(RAYDIUM_CPMM_PROGRAM_ID)
 	if err != nil {
 		return solana.PublicKey{}, 0, fmt.Errorf("failed to find authority PDA: %v", err)
 	}
 	return authority, bump, nil
 }
 
-func (pool *CPMMPool) Quote(ctx context.Context, solClient *rpc.Client, inputMint string, inputAmount math.Int) (math.Int, error) {
-	// update pool data first
-	accounts := make([]solana.PublicKey, 0)
-	accounts = append(accounts, pool.Token0Vault)
-	accounts = append(accounts, pool.Token1Vault)
-	results, err := solClient.GetMultipleAccountsWithOpts(ctx,
-		accounts,
-		&rpc.GetMultipleAccountsOpts{
-			Commitment: rpc.CommitmentProcessed,
-		},
-	)
-	if err != nil {
-		return math.NewInt(0), fmt.Errorf("batch request failed: %v", err)
-	}
-	for i, result := range results.Value {
-		if result == nil {
-			return math.NewInt(0), fmt.Errorf("result is nil, account: %v", accounts[i].String())
-		}
-		accountKey := accounts[i].String()
-		if pool.Token0Vault.String() == accountKey {
-			amountBytes := result.Data.GetBinary()[64:72]
-			amountUint := binary.LittleEndian.Uint64(amountBytes)
-			amount := math.NewIntFromUint64(amountUint)
-			pool.BaseAmount = amount
-		} else {
-			amountBytes := result.Data.GetBinary()[64:72]
-			amountUint := binary.LittleEndian.Uint64(amountBytes)
-			amount := math.NewIntFromUint64(amountUint)
-			pool.QuoteAmount = amount
-		}
-	}
+// QuoteAccounts returns the vaults Quote reads the reserves from, and the mints and clock
+// the transfer fees are read from when either mint is a Token-2022 mint
+func (pool *CPMMPool) QuoteAccounts(inputMint string) []solana.PublicKey {
+	return append([]solana.PublicKey{pool.Token0Vault, pool.Token1Vault}, pool.transferFeeAccounts()...)
+}
 
-	pool.BaseReserve = pool.BaseAmount.Sub(math.NewInt(int64(pool.BaseNeedTakePnl)))
-	pool.QuoteReserve = pool.QuoteAmount.Sub(math.NewInt(int64(pool.QuoteNeedTakePnl)))
+// Quote returns what the user receives for inputAmount, net of the AmmConfig's trade fee and
+// the Token-2022 transfer fees withheld from the input on its way to the vault and from the
+// output on its way back
+func (pool *CPMMPool) Quote(ctx context.Context, solClient pkg.RPC, inputMint string, inputAmount math.Int) (math.Int, error) {
+	if err := pool.updateReserves(ctx, solClient); err != nil {
+		return math.NewInt(0), err
+	}
 
 	// Set reserves based on direction
 	reserves := []math.Int{pool.BaseReserve, pool.QuoteReserve}
@@ -256,15 +333,16 @@ func (pool *CPMMPool) Quote(ctx context.Context, solClient *rpc.Client, inputMin
 
 	reserveIn := reserves[0]
 	reserveOut := reserves[1]
+	inputFee, outputFee := pool.transferFees(inputMint)
+	inputAmount = netOfFee(inputFee, inputAmount)
 
 	// Initialize output values
 	amountOutRaw := math.ZeroInt()
-	feeRaw := math.ZeroInt()
 
 	// If amountIn is not zero, calculate amountOut
 	if !inputAmount.IsZero() {
-		// Calculate fee
-		feeRaw = inputAmount.Mul(LIQUIDITY_FEES_NUMERATOR).Quo(LIQUIDITY_FEES_DENOMINATOR)
+		// The trade fee is rounded up, as the program charges it
+		feeRaw := inputAmount.Mul(pool.tradeFeeRate()).Add(FEE_RATE_DENOMINATOR).SubRaw(1).Quo(FEE_RATE_DENOMINATOR)
 
 		// Calculate amountInWithFee
 		amountInWithFee := inputAmount.Sub(feeRaw)
@@ -273,5 +351,83 @@ func (pool *CPMMPool) Quote(ctx context.Context, solClient *rpc.Client, inputMin
 		denominator := reserveIn.Add(amountInWithFee)
 		amountOutRaw = reserveOut.Mul(amountInWithFee).Quo(denominator)
 	}
-	return amountOutRaw, nil
+	return netOfFee(outputFee, amountOutRaw), nil
+}
+
+// QuoteExactOut calculates the input amount required to receive desiredOut of outputMint,
+// both including the Token-2022 transfer fees and the input grossed up for the trade fee
+func (pool *CPMMPool) QuoteExactOut(ctx context.Context, solClient pkg.RPC, outputMint string, desiredOut math.Int) (math.Int, error) {
+	if err := pool.updateReserves(ctx, solClient); err != nil {
+		return math.NewInt(0), err
+	}
+
+	reserveIn, reserveOut := pool.QuoteReserve, pool.BaseReserve
+	inputMint := pool.Token1Mint.String()
+	if outputMint == pool.Token1Mint.String() {
+		reserveIn, reserveOut = pool.BaseReserve, pool.QuoteReserve
+		inputMint = pool.Token0Mint.String()
+	}
+	inputFee, outputFee := pool.transferFees(inputMint)
+	amountIn, err := amountInForExactOut(reserveIn, reserveOut, grossOfFee(outputFee, desiredOut), pool.tradeFeeRate(), FEE_RATE_DENOMINATOR)
+	if err != nil {
+		return math.NewInt(0), err
+	}
+	return grossOfFee(inputFee, amountIn), nil
+}
+
+func (pool *CPMMPool) tradeFeeRate() math.Int {
+	return math.NewIntFromUint64(pool.TradeFeeRate)
+}
+
+// SpotPrice returns the price of the pool's reserves after refreshing them
+func (pool *CPMMPool) SpotPrice(ctx context.Context, solClient pkg.RPC) (pkg.SpotPrice, error) {
+	if err := pool.updateReserves(ctx, solClient); err != nil {
+		return pkg.SpotPrice{}, err
+	}
+	return pkg.ReserveSpotPrice(pool.BaseReserve, pool.QuoteReserve)
+}
+
+// updateReserves refreshes the vault balances and recomputes the effective reserves, reading
+// the mints' transfer fees in the same request
+func (pool *CPMMPool) updateReserves(ctx context.Context, solClient pkg.RPC) error {
+	accounts := pool.QuoteAccounts(pool.Token0Mint.String())
+	results, err := solClient.GetMultipleAccountsWithOpts(ctx,
+		accounts,
+		sol.MultipleAccountsOpts(ctx, rpc.CommitmentProcessed),
+	)
+	if err != nil {
+		return fmt.Errorf("batch request failed: %v", err)
+	}
+	if len(results.Value) != len(accounts) {
+		return fmt.Errorf("expected %d accounts, got %d", len(accounts), len(results.Value))
+	}
+	for i, result := range results.Value {
+		if result == nil {
+			return fmt.Errorf("result is nil, account: %v", accounts[i].String())
+		}
+	}
+	if len(accounts) > 2 {
+		if err := pool.setTransferFees(results.Value[2:]); err != nil {
+			return err
+		}
+	}
+	for i, result := range results.Value[:2] {
+		accountKey := accounts[i].String()
+		if pool.Token0Vault.String() == accountKey {
+			amountBytes := result.Data.GetBinary()[64:72]
+			amountUint := binary.LittleEndian.Uint64(amountBytes)
+			amount := math.NewIntFromUint64(amountUint)
+			pool.BaseAmount = amount
+		} else {
+			amountBytes := result.Data.GetBinary()[64:72]
+			amountUint := binary.LittleEndian.Uint64(amountBytes)
+			amount := math.NewIntFromUint64(amountUint)
+			pool.QuoteAmount = amount
+		}
+	}
+
+	pool.BaseReserve = pool.BaseAmount.Sub(math.NewInt(int64(pool.BaseNeedTakePnl)))
+	pool.QuoteReserve = pool.QuoteAmount.Sub(math.NewInt(int64(pool.QuoteNeedTakePnl)))
+
+	return nil
 }
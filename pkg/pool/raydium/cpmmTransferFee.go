@@ -0,0 +1,80 @@
+package raydium
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// HandlesTransferFees reports that the pool's quotes are already net of its mints' Token-2022
+// transfer fees
+func (pool *CPMMPool) HandlesTransferFees() bool {
+	return true
+}
+
+// hasToken2022 reports whether either of the pool's mints is a Token-2022 mint
+func (pool *CPMMPool) hasToken2022() bool {
+	return pool.Token0Program.Equals(solana.Token2022ProgramID) || pool.Token1Program.Equals(solana.Token2022ProgramID)
+}
+
+// tokenProgram returns the token program of a mint of the pool, SPL Token when unset
+func tokenProgram(program solana.PublicKey) solana.PublicKey {
+	if program.IsZero() {
+		return solana.TokenProgramID
+	}
+	return program
+}
+
+// transferFeeAccounts returns the accounts the pool's transfer fees are read from: its mints
+// and the clock sysvar for the epoch, or none when neither mint is a Token-2022 mint
+func (pool *CPMMPool) transferFeeAccounts() []solana.PublicKey {
+	if !pool.hasToken2022() {
+		return nil
+	}
+	return []solana.PublicKey{pool.Token0Mint, pool.Token1Mint, solana.SysVarClockPubkey}
+}
+
+// setTransferFees reads the fees in force for both mints from the accounts listed by
+// transferFeeAccounts
+func (pool *CPMMPool) setTransferFees(accounts []*rpc.Account) error {
+	clock := accounts[2].Data.GetBinary()
+	if len(clock) != sol.ClockAccountDataSize {
+		return fmt.Errorf("invalid clock account data length %d", len(clock))
+	}
+	epoch := binary.LittleEndian.Uint64(clock[16:24])
+
+	fees := make([]sol.TransferFee, 2)
+	for i, mint := range []solana.PublicKey{pool.Token0Mint, pool.Token1Mint} {
+		ext, err := sol.ParseMintExtensions(mint, accounts[i].Owner, accounts[i].Data.GetBinary())
+		if err != nil {
+			return err
+		}
+		if ext.TransferFee != nil {
+			fees[i] = ext.TransferFee.At(epoch)
+		}
+	}
+	pool.token0TransferFee, pool.token1TransferFee = fees[0], fees[1]
+	return nil
+}
+
+// transferFees returns the transfer fees of the input and output mints
+func (pool *CPMMPool) transferFees(inputMint string) (sol.TransferFee, sol.TransferFee) {
+	if inputMint == pool.Token1Mint.String() {
+		return pool.token1TransferFee, pool.token0TransferFee
+	}
+	return pool.token0TransferFee, pool.token1TransferFee
+}
+
+// netOfFee returns amount less the fee a transfer of it withholds
+func netOfFee(fee sol.TransferFee, amount math.Int) math.Int {
+	return amount.Sub(fee.Fee(amount))
+}
+
+// grossOfFee returns the transfer that delivers exactly amount
+func grossOfFee(fee sol.TransferFee, amount math.Int) math.Int {
+	return amount.Add(fee.InverseFee(amount))
+}
@@ -0,0 +1,470 @@
+package raydium
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gtdvccc/SolRouteTmp/pkg/txbuilder"
+	"cosmossdk.io/math"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Offsets in the LaunchLab global and platform config accounts
+const (
+	launchLabCurveTypeOffset      = 16
+	launchLabTradeFeeRateOffset   = 27
+	launchLabPlatformFeeOffset    = 104
+	launchLabCreatorFeeRateOffset = 720
+)
+
+var (
+	_ pkg.VaultPool        = (*LaunchLabPool)(nil)
+	_ pkg.DeprecatablePool = (*LaunchLabPool)(nil)
+)
+
+// LaunchLabVestingSchedule is the creator's locked allocation of a LaunchLab token
+type LaunchLabVestingSchedule struct {
+	TotalLockedAmount   uint64
+	CliffPeriod         uint64
+	UnlockPeriod        uint64
+	StartTime           uint64
+	TotalAllocatedShare uint64
+}
+
+// LaunchLabPoolState is the on-chain state of a LaunchLab pool after the discriminator
+type LaunchLabPoolState struct {
+	Epoch             uint64
+	AuthBump          uint8
+	Status            uint8
+	MintDecimalsA     uint8
+	MintDecimalsB     uint8
+	MigrateType       uint8
+	Supply            uint64
+	TotalSellA        uint64 // tokens sold on the curve before the pool graduates
+	VirtualA          uint64
+	VirtualB          uint64
+	RealA             uint64 // tokens sold so far
+	RealB             uint64 // quote raised so far
+	TotalFundRaisingB uint64
+	ProtocolFee       uint64
+	PlatformFee       uint64
+	MigrateFee        uint64
+	VestingSchedule   LaunchLabVestingSchedule
+	GlobalConfig      solana.PublicKey
+	PlatformConfig    solana.PublicKey
+	MintA             solana.PublicKey // the launched token
+	MintB             solana.PublicKey // the quote token, usually WSOL
+	VaultA            solana.PublicKey
+	VaultB            solana.PublicKey
+	Creator           solana.PublicKey
+}
+
+// LaunchLabPool is a Raydium LaunchLab bonding curve. Tokens are bought from and sold back to
+// the curve until TotalSellA has been sold, when the pool migrates to an AMM pool. Fees are
+// always charged in the quote token: on the input of buys and the output of sells.
+type LaunchLabPool struct {
+	LaunchLabPoolState
+
+	PoolId solana.PublicKey
+
+	// Loaded from the global and platform configs, in parts per FEE_RATE_DENOMINATOR
+	CurveType       uint8
+	TradeFeeRate    uint64
+	PlatformFeeRate uint64
+	CreatorFeeRate  uint64
+
+	Successor string
+}
+
+func (pool *LaunchLabPool) ProtocolName() pkg.ProtocolName {
+	return pkg.ProtocolNameRaydiumLaunchLab
+}
+
+func (pool *LaunchLabPool) ProtocolType() pkg.ProtocolType {
+	return pkg.ProtocolTypeRaydiumLaunchLab
+}
+
+func (pool *LaunchLabPool) GetProgramID() solana.PublicKey {
+	return RAYDIUM_LAUNCHLAB_PROGRAM_ID
+}
+
+func (pool *LaunchLabPool) Decode(data []byte) error {
+	if len(data) < 8 || string(data[:8]) != string(LaunchLabPoolDiscriminator) {
+		return fmt.Errorf("invalid launchlab pool discriminator")
+	}
+	return bin.NewBinDecoder(data[8:]).Decode(&pool.LaunchLabPoolState)
+}
+
+func (pool *LaunchLabPool) Offset(field string) uint64 {
+	switch field {
+	case "MintA":
+		return 205
+	case "MintB":
+		return 237
+	default:
+		return 0
+	}
+}
+
+func (pool *LaunchLabPool) GetID() string {
+	return pool.PoolId.String()
+}
+
+// GetTokens returns the launched token and the quote token
+func (pool *LaunchLabPool) GetTokens() (string, string) {
+	return pool.MintA.String(), pool.MintB.String()
+}
+
+// TokenVaults returns the curve's vaults, controlled by the program authority PDA
+func (pool *LaunchLabPool) TokenVaults() []pkg.TokenVault {
+	authority, _, _ := getLaunchLabAuthorityPDA()
+	return []pkg.TokenVault{
+		{Address: pool.VaultA, Mint: pool.MintA, Authority: authority},
+		{Address: pool.VaultB, Mint: pool.MintB, Authority: authority},
+	}
+}
+
+// Deprecation reports pools that have left the curve to migrate to an AMM pool
+func (pool *LaunchLabPool) Deprecation() *pkg.Deprecation {
+	if pool.Status == LaunchLabStatusTrading {
+		return nil
+	}
+	return &pkg.Deprecation{Reason: fmt.Sprintf("bonding curve graduated (status %d)", pool.Status), Successor: pool.Successor}
+}
+
+// SetSuccessor records the pool the curve migrated to
+func (pool *LaunchLabPool) SetSuccessor(poolID string) {
+	pool.Successor = poolID
+}
+
+func getLaunchLabAuthorityPDA() (solana.PublicKey, uint8, error) {
+	authority, bump, err := txbuilder.RaydiumLaunchLabAuthority(RAYDIUM_LAUNCHLAB_PROGRAM_ID)
+	if err != nil {
+		return solana.PublicKey{}, 0, fmt.Errorf("failed to find authority PDA: %v", err)
+	}
+	return authority, bump, nil
+}
+
+// UpdateState reloads the pool together with its global and platform configs
+func (pool *LaunchLabPool) UpdateState(ctx context.Context, solClient pkg.RPC) error {
+	accounts := []solana.PublicKey{pool.PoolId, pool.GlobalConfig, pool.PlatformConfig}
+	results, err := solClient.GetMultipleAccountsWithOpts(ctx, accounts, sol.MultipleAccountsOpts(ctx, rpc.CommitmentProcessed))
+	if err != nil {
+		return fmt.Errorf("failed to get launchlab accounts: %w", err)
+	}
+	for i, account := range results.Value {
+		if account == nil {
+			return fmt.Errorf("account %s not found", accounts[i])
+		}
+	}
+	var state LaunchLabPool
+	if err := state.Decode(results.Value[0].Data.GetBinary()); err != nil {
+		return fmt.Errorf("failed to decode pool %s: %w", pool.PoolId, err)
+	}
+	global := results.Value[1].Data.GetBinary()
+	if len(global) < launchLabTradeFeeRateOffset+8 {
+		return fmt.Errorf("global config too short: %d bytes", len(global))
+	}
+	platform := results.Value[2].Data.GetBinary()
+	if len(platform) < launchLabPlatformFeeOffset+8 {
+		return fmt.Errorf("platform config too short: %d bytes", len(platform))
+	}
+
+	pool.LaunchLabPoolState = state.LaunchLabPoolState
+	pool.CurveType = global[launchLabCurveTypeOffset]
+	pool.TradeFeeRate = binary.LittleEndian.Uint64(global[launchLabTradeFeeRateOffset:])
+	pool.PlatformFeeRate = binary.LittleEndian.Uint64(platform[launchLabPlatformFeeOffset:])
+	pool.CreatorFeeRate = 0
+	// Older platform configs predate creator fees
+	if len(platform) >= launchLabCreatorFeeRateOffset+8 {
+		pool.CreatorFeeRate = binary.LittleEndian.Uint64(platform[launchLabCreatorFeeRateOffset:])
+	}
+	return nil
+}
+
+func (pool *LaunchLabPool) Quote(ctx context.Context, solClient pkg.RPC, inputMint string, inputAmount math.Int) (math.Int, error) {
+	if err := pool.UpdateState(ctx, solClient); err != nil {
+		return math.Int{}, err
+	}
+	return pool.QuoteOffline(inputMint, inputAmount)
+}
+
+// QuoteOffline prices a buy (quote token in) or sell (launched token in) against the state
+// as last loaded. Buys that would sell past TotalSellA are filled up to it.
+func (pool *LaunchLabPool) QuoteOffline(inputMint string, inputAmount math.Int) (math.Int, error) {
+	if !inputAmount.IsPositive() {
+		return math.Int{}, fmt.Errorf("input amount must be positive")
+	}
+	buy, err := pool.isBuy(inputMint, true)
+	if err != nil {
+		return math.Int{}, err
+	}
+	if err := pool.checkTradable(); err != nil {
+		return math.Int{}, err
+	}
+
+	if buy {
+		amountIn := inputAmount.Sub(pool.fee(inputAmount))
+		if !amountIn.IsPositive() {
+			return math.ZeroInt(), nil
+		}
+		out, err := pool.curveOut(true, amountIn)
+		if err != nil {
+			return math.Int{}, err
+		}
+		return math.MinInt(out, pool.remainingA()), nil
+	}
+
+	out, err := pool.curveOut(false, inputAmount)
+	if err != nil {
+		return math.Int{}, err
+	}
+	if out.GT(math.NewIntFromUint64(pool.RealB)) {
+		return math.Int{}, fmt.Errorf("%w: curve holds %d of the quote token", solerrors.ErrInsufficientLiquidity, pool.RealB)
+	}
+	return math.MaxInt(out.Sub(pool.fee(out)), math.ZeroInt()), nil
+}
+
+func (pool *LaunchLabPool) QuoteExactOut(ctx context.Context, solClient pkg.RPC, outputMint string, desiredOut math.Int) (math.Int, error) {
+	if err := pool.UpdateState(ctx, solClient); err != nil {
+		return math.Int{}, err
+	}
+	return pool.QuoteExactOutOffline(outputMint, desiredOut)
+}
+
+// QuoteExactOutOffline returns the input needed for desiredOut against the state as last loaded
+func (pool *LaunchLabPool) QuoteExactOutOffline(outputMint string, desiredOut math.Int) (math.Int, error) {
+	if !desiredOut.IsPositive() {
+		return math.Int{}, fmt.Errorf("output amount must be positive")
+	}
+	buy, err := pool.isBuy(outputMint, false)
+	if err != nil {
+		return math.Int{}, err
+	}
+	if err := pool.checkTradable(); err != nil {
+		return math.Int{}, err
+	}
+
+	if buy {
+		if desiredOut.GT(pool.remainingA()) {
+			return math.Int{}, fmt.Errorf("%w: curve has %s tokens left to sell", solerrors.ErrInsufficientLiquidity, pool.remainingA())
+		}
+		amountIn, err := pool.curveIn(true, desiredOut)
+		if err != nil {
+			return math.Int{}, err
+		}
+		return pool.preFee(amountIn), nil
+	}
+
+	grossOut := pool.preFee(desiredOut)
+	if grossOut.GT(math.NewIntFromUint64(pool.RealB)) {
+		return math.Int{}, fmt.Errorf("%w: curve holds %d of the quote token", solerrors.ErrInsufficientLiquidity, pool.RealB)
+	}
+	return pool.curveIn(false, grossOut)
+}
+
+// isBuy reports whether the swap buys the launched token; mint is the input when isInput
+func (pool *LaunchLabPool) isBuy(mint string, isInput bool) (bool, error) {
+	switch mint {
+	case pool.MintB.String():
+		return isInput, nil
+	case pool.MintA.String():
+		return !isInput, nil
+	default:
+		return false, fmt.Errorf("mint %s not in pool %s", mint, pool.PoolId)
+	}
+}
+
+func (pool *LaunchLabPool) checkTradable() error {
+	if pool.Status != LaunchLabStatusTrading {
+		return fmt.Errorf("pool %s has left the bonding curve (status %d)", pool.PoolId, pool.Status)
+	}
+	return nil
+}
+
+// remainingA is what the curve has left to sell before it graduates
+func (pool *LaunchLabPool) remainingA() math.Int {
+	if pool.RealA >= pool.TotalSellA {
+		return math.ZeroInt()
+	}
+	return math.NewIntFromUint64(pool.TotalSellA - pool.RealA)
+}
+
+// fee is the trade, platform and creator fee on a quote token amount; each is rounded up
+func (pool *LaunchLabPool) fee(amount math.Int) math.Int {
+	total := math.ZeroInt()
+	for _, rate := range []uint64{pool.TradeFeeRate, pool.PlatformFeeRate, pool.CreatorFeeRate} {
+		total = total.Add(ceilDiv(amount.Mul(math.NewIntFromUint64(rate)), FEE_RATE_DENOMINATOR))
+	}
+	return total
+}
+
+// preFee is the smallest amount that is at least postFee once fees are taken
+func (pool *LaunchLabPool) preFee(postFee math.Int) math.Int {
+	rate := math.NewIntFromUint64(pool.TradeFeeRate + pool.PlatformFeeRate + pool.CreatorFeeRate)
+	amount := ceilDiv(postFee.Mul(FEE_RATE_DENOMINATOR), FEE_RATE_DENOMINATOR.Sub(rate))
+	// Rounding each fee up separately can cost a unit or two more than the combined rate
+	for amount.Sub(pool.fee(amount)).LT(postFee) {
+		amount = amount.AddRaw(1)
+	}
+	return amount
+}
+
+// reserves returns the curve's input and output reserves for a constant-product trade
+func (pool *LaunchLabPool) reserves(buy bool) (math.Int, math.Int) {
+	reserveA := math.NewIntFromUint64(pool.VirtualA).Sub(math.NewIntFromUint64(pool.RealA))
+	reserveB := math.NewIntFromUint64(pool.VirtualB).Add(math.NewIntFromUint64(pool.RealB))
+	if buy {
+		return reserveB, reserveA
+	}
+	return reserveA, reserveB
+}
+
+// curveOut is the output of amountIn, after fees on buys, along the bonding curve, rounded down
+func (pool *LaunchLabPool) curveOut(buy bool, amountIn math.Int) (math.Int, error) {
+	virtualA, virtualB := math.NewIntFromUint64(pool.VirtualA), math.NewIntFromUint64(pool.VirtualB)
+	switch pool.CurveType {
+	case LaunchLabCurveConstantProduct:
+		reserveIn, reserveOut := pool.reserves(buy)
+		return amountIn.Mul(reserveOut).Quo(reserveIn.Add(amountIn)), nil
+	case LaunchLabCurveFixedPrice:
+		if virtualA.IsZero() || virtualB.IsZero() {
+			return math.Int{}, fmt.Errorf("fixed-price curve has a zero price")
+		}
+		if buy {
+			return amountIn.Mul(virtualA).Quo(virtualB), nil
+		}
+		return amountIn.Mul(virtualB).Quo(virtualA), nil
+	default:
+		return math.Int{}, fmt.Errorf("unsupported launchlab curve type %d", pool.CurveType)
+	}
+}
+
+// curveIn is the input, before fees on buys, that buys amountOut along the curve, rounded up
+func (pool *LaunchLabPool) curveIn(buy bool, amountOut math.Int) (math.Int, error) {
+	virtualA, virtualB := math.NewIntFromUint64(pool.VirtualA), math.NewIntFromUint64(pool.VirtualB)
+	switch pool.CurveType {
+	case LaunchLabCurveConstantProduct:
+		reserveIn, reserveOut := pool.reserves(buy)
+		if amountOut.GTE(reserveOut) {
+			return math.Int{}, fmt.Errorf("%w: output %s exceeds the curve reserve %s", solerrors.ErrInsufficientLiquidity, amountOut, reserveOut)
+		}
+		return ceilDiv(amountOut.Mul(reserveIn), reserveOut.Sub(amountOut)), nil
+	case LaunchLabCurveFixedPrice:
+		if virtualA.IsZero() || virtualB.IsZero() {
+			return math.Int{}, fmt.Errorf("fixed-price curve has a zero price")
+		}
+		if buy {
+			return ceilDiv(amountOut.Mul(virtualB), virtualA), nil
+		}
+		return ceilDiv(amountOut.Mul(virtualA), virtualB), nil
+	default:
+		return math.Int{}, fmt.Errorf("unsupported launchlab curve type %d", pool.CurveType)
+	}
+}
+
+func ceilDiv(numerator, denominator math.Int) math.Int {
+	return numerator.Add(denominator).SubRaw(1).Quo(denominator)
+}
+
+// BuildSwapInstructions buys with the quote token or sells the launched token for an exact
+// input. The user's token accounts for both mints must exist.
+func (pool *LaunchLabPool) BuildSwapInstructions(
+	ctx context.Context,
+	solClient pkg.RPC,
+	userAddr solana.PublicKey,
+	inputMint string,
+	amountIn math.Int,
+	minOut math.Int,
+) ([]solana.Instruction, error) {
+	buy, err := pool.isBuy(inputMint, true)
+	if err != nil {
+		return nil, err
+	}
+	discriminator := LaunchLabSellExactInDiscm
+	if buy {
+		discriminator = LaunchLabBuyExactInDiscm
+	}
+	inst, err := pool.swapInstruction(userAddr, discriminator, amountIn, minOut)
+	if err != nil {
+		return nil, err
+	}
+	return []solana.Instruction{inst}, nil
+}
+
+// BuildSwapInstructionsExactOut buys or sells for an exact output, spending at most maxIn
+func (pool *LaunchLabPool) BuildSwapInstructionsExactOut(
+	ctx context.Context,
+	solClient pkg.RPC,
+	userAddr solana.PublicKey,
+	outputMint string,
+	amountOut math.Int,
+	maxIn math.Int,
+) ([]solana.Instruction, error) {
+	buy, err := pool.isBuy(outputMint, false)
+	if err != nil {
+		return nil, err
+	}
+	discriminator := LaunchLabSellExactOutDiscm
+	if buy {
+		discriminator = LaunchLabBuyExactOutDiscm
+	}
+	inst, err := pool.swapInstruction(userAddr, discriminator, amountOut, maxIn)
+	if err != nil {
+		return nil, err
+	}
+	return []solana.Instruction{inst}, nil
+}
+
+// swapInstruction encodes the two amounts of the instruction with a zero share fee rate
+func (pool *LaunchLabPool) swapInstruction(userAddr solana.PublicKey, discriminator []byte, amount, limit math.Int) (solana.Instruction, error) {
+	if !amount.IsUint64() || !limit.IsUint64() {
+		return nil, fmt.Errorf("swap amounts must fit in u64")
+	}
+	authority, _, err := getLaunchLabAuthorityPDA()
+	if err != nil {
+		return nil, err
+	}
+	eventAuthority, err := txbuilder.EventAuthority(RAYDIUM_LAUNCHLAB_PROGRAM_ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find event authority PDA: %v", err)
+	}
+	userBase, _, err := solana.FindAssociatedTokenAddress(userAddr, pool.MintA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive base token account: %w", err)
+	}
+	userQuote, _, err := solana.FindAssociatedTokenAddress(userAddr, pool.MintB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive quote token account: %w", err)
+	}
+
+	data := append([]byte{}, discriminator...)
+	data = binary.LittleEndian.AppendUint64(data, amount.Uint64())
+	data = binary.LittleEndian.AppendUint64(data, limit.Uint64())
+	data = binary.LittleEndian.AppendUint64(data, 0) // share_fee_rate
+
+	accounts := solana.AccountMetaSlice{
+		solana.NewAccountMeta(userAddr, false, true),
+		solana.NewAccountMeta(authority, false, false),
+		solana.NewAccountMeta(pool.GlobalConfig, false, false),
+		solana.NewAccountMeta(pool.PlatformConfig, false, false),
+		solana.NewAccountMeta(pool.PoolId, true, false),
+		solana.NewAccountMeta(userBase, true, false),
+		solana.NewAccountMeta(userQuote, true, false),
+		solana.NewAccountMeta(pool.VaultA, true, false),
+		solana.NewAccountMeta(pool.VaultB, true, false),
+		solana.NewAccountMeta(pool.MintA, false, false),
+		solana.NewAccountMeta(pool.MintB, false, false),
+		solana.NewAccountMeta(solana.TokenProgramID, false, false), // base_token_program
+		solana.NewAccountMeta(solana.TokenProgramID, false, false), // quote_token_program
+		solana.NewAccountMeta(eventAuthority, false, false),
+		solana.NewAccountMeta(RAYDIUM_LAUNCHLAB_PROGRAM_ID, false, false),
+	}
+	return solana.NewInstruction(RAYDIUM_LAUNCHLAB_PROGRAM_ID, accounts, data), nil
+}
@@ -0,0 +1,84 @@
+package raydium
+
+import (
+	"github.com/gtdvccc/SolRouteTmp/pkg/layout"
+	"github.com/gagliardetto/solana-go"
+	"lukechampine.com/uint128"
+)
+
+// ClmmPoolState is the PoolState account of the Raydium CLMM program IDL, after the
+// discriminator. CLMMPool decodes through it, and its layout gives the pool's size and field
+// offsets.
+type ClmmPoolState struct {
+	Bump                      uint8
+	AmmConfig                 solana.PublicKey
+	Owner                     solana.PublicKey
+	TokenMint0                solana.PublicKey
+	TokenMint1                solana.PublicKey
+	TokenVault0               solana.PublicKey
+	TokenVault1               solana.PublicKey
+	ObservationKey            solana.PublicKey
+	MintDecimals0             uint8
+	MintDecimals1             uint8
+	TickSpacing               uint16
+	Liquidity                 uint128.Uint128
+	SqrtPriceX64              uint128.Uint128
+	TickCurrent               int32
+	ObservationIndex          uint16
+	ObservationUpdateDuration uint16
+	FeeGrowthGlobal0X64       uint128.Uint128
+	FeeGrowthGlobal1X64       uint128.Uint128
+	ProtocolFeesToken0        uint64
+	ProtocolFeesToken1        uint64
+	SwapInAmountToken0        uint128.Uint128
+	SwapOutAmountToken1       uint128.Uint128
+	SwapInAmountToken1        uint128.Uint128
+	SwapOutAmountToken0       uint128.Uint128
+	Status                    uint8
+	Padding                   [7]uint8
+	RewardInfos               [3]RewardInfo
+	TickArrayBitmap           [16]uint64
+	TotalFeesToken0           uint64
+	TotalFeesClaimedToken0    uint64
+	TotalFeesToken1           uint64
+	TotalFeesClaimedToken1    uint64
+	FundFeesToken0            uint64
+	FundFeesToken1            uint64
+	OpenTime                  uint64
+	RecentEpoch               uint64
+	Padding1                  [24]uint64
+	Padding2                  [32]uint64
+}
+
+// CpmmPoolState is the PoolState account of the Raydium CPMM program IDL, after the
+// discriminator. The program packs it, so the decimals are followed directly by the LP supply.
+type CpmmPoolState struct {
+	AmmConfig          solana.PublicKey
+	PoolCreator        solana.PublicKey
+	Token0Vault        solana.PublicKey
+	Token1Vault        solana.PublicKey
+	LpMint             solana.PublicKey
+	Token0Mint         solana.PublicKey
+	Token1Mint         solana.PublicKey
+	Token0Program      solana.PublicKey
+	Token1Program      solana.PublicKey
+	ObservationKey     solana.PublicKey
+	AuthBump           uint8
+	Status             uint8
+	LpMintDecimals     uint8
+	Mint0Decimals      uint8
+	Mint1Decimals      uint8
+	LpSupply           uint64
+	ProtocolFeesToken0 uint64
+	ProtocolFeesToken1 uint64
+	FundFeesToken0     uint64
+	FundFeesToken1     uint64
+	OpenTime           uint64
+	RecentEpoch        uint64
+	Padding            [31]uint64
+}
+
+var (
+	clmmPoolLayout = layout.MustOf(ClmmPoolState{})
+	cpmmPoolLayout = layout.MustOf(CpmmPoolState{})
+)
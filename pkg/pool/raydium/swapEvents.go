@@ -0,0 +1,141 @@
+package raydium
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/utils"
+	cosmath "cosmossdk.io/math"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+)
+
+// swapEventDiscriminator identifies the SwapEvent of the CLMM and CPMM programs
+var swapEventDiscriminator = utils.GetDiscriminator("event", "SwapEvent")
+
+// AMM v4 ray_log types of swaps and directions
+const (
+	rayLogSwapBaseIn  = 3
+	rayLogSwapBaseOut = 4
+	// rayDirectionCoinToPC sells the coin (base) for the pc (quote)
+	rayDirectionCoinToPC = 2
+)
+
+// ammSwapLog is an AMM v4 SwapBaseIn or SwapBaseOut ray_log. In and Out are the
+// instruction's arguments: the input and minimum output of a SwapBaseIn, the maximum input
+// and output of a SwapBaseOut. Result is the amount the swap computed, the output of a
+// SwapBaseIn and the input deducted by a SwapBaseOut.
+type ammSwapLog struct {
+	LogType    uint8
+	In         uint64
+	Out        uint64
+	Direction  uint64
+	UserSource uint64
+	PoolCoin   uint64
+	PoolPC     uint64
+	Result     uint64
+}
+
+// DecodeAMMSwapEvent decodes the ray_log AMM v4 swaps write. The log doesn't name the pool, so
+// it is read from the swap instruction's accounts, as is the user from its last account.
+func DecodeAMMSwapEvent(event pkg.ProgramEvent) (pkg.SwapEvent, bool, error) {
+	if len(event.Data) == 0 || (event.Data[0] != rayLogSwapBaseIn && event.Data[0] != rayLogSwapBaseOut) {
+		return pkg.SwapEvent{}, false, nil
+	}
+	var log ammSwapLog
+	if err := bin.NewBinDecoder(event.Data).Decode(&log); err != nil {
+		return pkg.SwapEvent{}, false, fmt.Errorf("failed to decode ray_log swap: %w", err)
+	}
+	if len(event.Accounts) < 2 {
+		return pkg.SwapEvent{}, false, fmt.Errorf("ray_log swap without its pool account")
+	}
+	swap := pkg.SwapEvent{
+		Protocol:   pkg.ProtocolNameRaydiumAmm,
+		Pool:       event.Accounts[1],
+		User:       event.Accounts[len(event.Accounts)-1],
+		ZeroForOne: log.Direction == rayDirectionCoinToPC,
+	}
+	if log.LogType == rayLogSwapBaseIn {
+		swap.AmountIn, swap.AmountOut = log.In, log.Result
+	} else {
+		swap.AmountIn, swap.AmountOut = log.Result, log.Out
+	}
+	return swap, true, nil
+}
+
+type clmmSwapEvent struct {
+	PoolState     solana.PublicKey
+	Sender        solana.PublicKey
+	TokenAccount0 solana.PublicKey
+	TokenAccount1 solana.PublicKey
+	Amount0       uint64
+	TransferFee0  uint64
+	Amount1       uint64
+	TransferFee1  uint64
+	ZeroForOne    bool
+	SqrtPriceX64  bin.Uint128
+	Liquidity     bin.Uint128
+	Tick          int32
+}
+
+// DecodeCLMMSwapEvent decodes the SwapEvent the CLMM program logs for each swap
+func DecodeCLMMSwapEvent(event pkg.ProgramEvent) (pkg.SwapEvent, bool, error) {
+	if !bytes.HasPrefix(event.Data, swapEventDiscriminator) {
+		return pkg.SwapEvent{}, false, nil
+	}
+	var ev clmmSwapEvent
+	if err := bin.NewBinDecoder(event.Data[8:]).Decode(&ev); err != nil {
+		return pkg.SwapEvent{}, false, fmt.Errorf("failed to decode CLMM swap event: %w", err)
+	}
+	swap := pkg.SwapEvent{
+		Protocol:     pkg.ProtocolNameRaydiumClmm,
+		Pool:         ev.PoolState,
+		User:         ev.Sender,
+		ZeroForOne:   ev.ZeroForOne,
+		AmountIn:     ev.Amount0,
+		AmountOut:    ev.Amount1,
+		SqrtPriceX64: cosmath.NewIntFromBigInt(ev.SqrtPriceX64.BigInt()),
+	}
+	if !ev.ZeroForOne {
+		swap.AmountIn, swap.AmountOut = ev.Amount1, ev.Amount0
+	}
+	return swap, true, nil
+}
+
+type cpmmSwapEvent struct {
+	PoolID            solana.PublicKey
+	InputVaultBefore  uint64
+	OutputVaultBefore uint64
+	InputAmount       uint64
+	OutputAmount      uint64
+	InputTransferFee  uint64
+	OutputTransferFee uint64
+	BaseInput         bool
+}
+
+// DecodeCPMMSwapEvent decodes the SwapEvent the CPMM program logs for each swap. The event
+// doesn't say which way the swap went, so it is read from the mints of the swap instruction:
+// a pool's token 0 mint sorts before its token 1 mint.
+func DecodeCPMMSwapEvent(event pkg.ProgramEvent) (pkg.SwapEvent, bool, error) {
+	if !bytes.HasPrefix(event.Data, swapEventDiscriminator) {
+		return pkg.SwapEvent{}, false, nil
+	}
+	var ev cpmmSwapEvent
+	if err := bin.NewBinDecoder(event.Data[8:]).Decode(&ev); err != nil {
+		return pkg.SwapEvent{}, false, fmt.Errorf("failed to decode CPMM swap event: %w", err)
+	}
+	// payer, authority, config, pool, input and output accounts, vaults and token programs,
+	// then the input and output mints
+	if len(event.Accounts) < 12 {
+		return pkg.SwapEvent{}, false, fmt.Errorf("CPMM swap event without its swap accounts")
+	}
+	return pkg.SwapEvent{
+		Protocol:   pkg.ProtocolNameRaydiumCpmm,
+		Pool:       ev.PoolID,
+		User:       event.Accounts[0],
+		ZeroForOne: bytes.Compare(event.Accounts[10][:], event.Accounts[11][:]) < 0,
+		AmountIn:   ev.InputAmount,
+		AmountOut:  ev.OutputAmount,
+	}, true, nil
+}
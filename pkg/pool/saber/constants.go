@@ -0,0 +1,47 @@
+// Package saber implements Saber StableSwap pools, which price two like-valued tokens on
+// Curve's StableSwap invariant with an amplification coefficient A
+package saber
+
+import (
+	"github.com/gagliardetto/solana-go"
+)
+
+// ProgramID is the Saber StableSwap program
+var ProgramID = solana.MustPublicKeyFromBase58("SSwpkEEcbUqx4vtoEByFjSkhKdCT862DNVb52nZg1UZ")
+
+// SwapInfo account layout
+const (
+	SwapInfoSize = 395
+
+	isInitializedOffset       = 0
+	isPausedOffset            = 1
+	nonceOffset               = 2
+	initialAmpFactorOffset    = 3
+	targetAmpFactorOffset     = 11
+	startRampTsOffset         = 19
+	stopRampTsOffset          = 27
+	TokenAReserveOffset       = 107
+	TokenBReserveOffset       = 139
+	PoolMintOffset            = 171
+	TokenAMintOffset          = 203
+	TokenBMintOffset          = 235
+	AdminFeeAccountAOffset    = 267
+	AdminFeeAccountBOffset    = 299
+	tradeFeeNumeratorOffset   = 363
+	tradeFeeDenominatorOffset = 371
+)
+
+// swapIx is the Swap instruction tag
+const swapIx uint8 = 1
+
+// Token account and mint layout
+const (
+	tokenAccountAmountOffset = 64
+	mintDecimalsOffset       = 44
+)
+
+// clockUnixTimestampOffset is the unix timestamp in the clock sysvar
+const clockUnixTimestampOffset = 32
+
+// stableSwapIterations bounds the Newton iterations for D and y, as on chain
+const stableSwapIterations = 256
@@ -0,0 +1,381 @@
+package saber
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	cosmath "cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+var _ pkg.Pool = (*Pool)(nil)
+
+// Pool is a two-token Saber StableSwap pool
+type Pool struct {
+	PoolId              solana.PublicKey
+	IsInitialized       bool
+	IsPaused            bool
+	Nonce               uint8
+	InitialAmpFactor    uint64
+	TargetAmpFactor     uint64
+	StartRampTs         int64
+	StopRampTs          int64
+	TokenAReserve       solana.PublicKey
+	TokenBReserve       solana.PublicKey
+	PoolMint            solana.PublicKey
+	TokenAMint          solana.PublicKey
+	TokenBMint          solana.PublicKey
+	AdminFeeAccountA    solana.PublicKey
+	AdminFeeAccountB    solana.PublicKey
+	TradeFeeNumerator   uint64
+	TradeFeeDenominator uint64
+
+	// Loaded by Quote
+	ReserveA  uint64
+	ReserveB  uint64
+	DecimalsA uint8
+	DecimalsB uint8
+	Now       int64 // cluster unix time, for the amplification ramp
+}
+
+// NewPool decodes a SwapInfo account
+func NewPool(id solana.PublicKey, data []byte) (*Pool, error) {
+	pool := &Pool{PoolId: id}
+	if err := pool.Decode(data); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// Decode reads a SwapInfo account
+func (pool *Pool) Decode(data []byte) error {
+	if len(data) < SwapInfoSize {
+		return fmt.Errorf("swap info account too short: %d bytes", len(data))
+	}
+	key := func(offset int) solana.PublicKey {
+		return solana.PublicKeyFromBytes(data[offset : offset+32])
+	}
+	pool.IsInitialized = data[isInitializedOffset] != 0
+	pool.IsPaused = data[isPausedOffset] != 0
+	pool.Nonce = data[nonceOffset]
+	pool.InitialAmpFactor = binary.LittleEndian.Uint64(data[initialAmpFactorOffset:])
+	pool.TargetAmpFactor = binary.LittleEndian.Uint64(data[targetAmpFactorOffset:])
+	pool.StartRampTs = int64(binary.LittleEndian.Uint64(data[startRampTsOffset:]))
+	pool.StopRampTs = int64(binary.LittleEndian.Uint64(data[stopRampTsOffset:]))
+	pool.TokenAReserve = key(TokenAReserveOffset)
+	pool.TokenBReserve = key(TokenBReserveOffset)
+	pool.PoolMint = key(PoolMintOffset)
+	pool.TokenAMint = key(TokenAMintOffset)
+	pool.TokenBMint = key(TokenBMintOffset)
+	pool.AdminFeeAccountA = key(AdminFeeAccountAOffset)
+	pool.AdminFeeAccountB = key(AdminFeeAccountBOffset)
+	pool.TradeFeeNumerator = binary.LittleEndian.Uint64(data[tradeFeeNumeratorOffset:])
+	pool.TradeFeeDenominator = binary.LittleEndian.Uint64(data[tradeFeeDenominatorOffset:])
+	return nil
+}
+
+func (pool *Pool) ProtocolName() pkg.ProtocolName {
+	return pkg.ProtocolNameSaber
+}
+
+func (pool *Pool) ProtocolType() pkg.ProtocolType {
+	return pkg.ProtocolTypeSaber
+}
+
+func (pool *Pool) GetProgramID() solana.PublicKey {
+	return ProgramID
+}
+
+func (pool *Pool) GetID() string {
+	return pool.PoolId.String()
+}
+
+func (pool *Pool) GetTokens() (string, string) {
+	return pool.TokenAMint.String(), pool.TokenBMint.String()
+}
+
+// TokenVaults returns the reserves, which are owned by the swap authority
+func (pool *Pool) TokenVaults() []pkg.TokenVault {
+	authority, _ := pool.authority()
+	return []pkg.TokenVault{
+		{Address: pool.TokenAReserve, Mint: pool.TokenAMint, Authority: authority},
+		{Address: pool.TokenBReserve, Mint: pool.TokenBMint, Authority: authority},
+	}
+}
+
+// refresh reloads the swap info, both reserves, the mint decimals and the cluster time
+func (pool *Pool) refresh(ctx context.Context, solClient pkg.RPC) error {
+	accounts := []solana.PublicKey{pool.PoolId, pool.TokenAReserve, pool.TokenBReserve, pool.TokenAMint, pool.TokenBMint, solana.SysVarClockPubkey}
+	results, err := solClient.GetMultipleAccountsWithOpts(ctx, accounts, sol.MultipleAccountsOpts(ctx, rpc.CommitmentProcessed))
+	if err != nil {
+		return fmt.Errorf("failed to get pool %s: %w", pool.PoolId, err)
+	}
+	if len(results.Value) != len(accounts) {
+		return fmt.Errorf("expected %d accounts, got %d", len(accounts), len(results.Value))
+	}
+	data := make([][]byte, len(accounts))
+	for i, account := range results.Value {
+		if account == nil {
+			return fmt.Errorf("account %s not found", accounts[i])
+		}
+		data[i] = account.Data.GetBinary()
+	}
+	if err := pool.Decode(data[0]); err != nil {
+		return fmt.Errorf("failed to decode pool %s: %w", pool.PoolId, err)
+	}
+	for i, account := range data[1:5] {
+		if len(account) < tokenAccountAmountOffset+8 {
+			return fmt.Errorf("account %s too short: %d bytes", accounts[i+1], len(account))
+		}
+	}
+	if len(data[5]) < clockUnixTimestampOffset+8 {
+		return fmt.Errorf("clock sysvar too short: %d bytes", len(data[5]))
+	}
+	pool.ReserveA = binary.LittleEndian.Uint64(data[1][tokenAccountAmountOffset:])
+	pool.ReserveB = binary.LittleEndian.Uint64(data[2][tokenAccountAmountOffset:])
+	pool.DecimalsA = data[3][mintDecimalsOffset]
+	pool.DecimalsB = data[4][mintDecimalsOffset]
+	pool.Now = int64(binary.LittleEndian.Uint64(data[5][clockUnixTimestampOffset:]))
+	return nil
+}
+
+// Quote refreshes the pool and prices an exact-input swap
+func (pool *Pool) Quote(ctx context.Context, solClient pkg.RPC, inputMint string, inputAmount cosmath.Int) (cosmath.Int, error) {
+	if err := pool.refresh(ctx, solClient); err != nil {
+		return cosmath.Int{}, err
+	}
+	return pool.QuoteOffline(inputMint, inputAmount)
+}
+
+// QuoteOffline prices an exact-input swap against the reserves as last loaded. The trade fee
+// is taken from the output.
+func (pool *Pool) QuoteOffline(inputMint string, inputAmount cosmath.Int) (cosmath.Int, error) {
+	if !inputAmount.IsPositive() {
+		return cosmath.Int{}, fmt.Errorf("input amount must be positive")
+	}
+	aToB, err := pool.isAToB(inputMint)
+	if err != nil {
+		return cosmath.Int{}, err
+	}
+	if err := pool.checkTradable(); err != nil {
+		return cosmath.Int{}, err
+	}
+	out, err := pool.swapOut(aToB, inputAmount)
+	if err != nil {
+		return cosmath.Int{}, err
+	}
+	if !out.IsPositive() {
+		return cosmath.Int{}, fmt.Errorf("amount too small to swap")
+	}
+	return out, nil
+}
+
+// QuoteExactOut refreshes the pool and returns the input needed for desiredOut
+func (pool *Pool) QuoteExactOut(ctx context.Context, solClient pkg.RPC, outputMint string, desiredOut cosmath.Int) (cosmath.Int, error) {
+	if err := pool.refresh(ctx, solClient); err != nil {
+		return cosmath.Int{}, err
+	}
+	return pool.QuoteExactOutOffline(outputMint, desiredOut)
+}
+
+// QuoteExactOutOffline is QuoteExactOut against the reserves as last loaded
+func (pool *Pool) QuoteExactOutOffline(outputMint string, desiredOut cosmath.Int) (cosmath.Int, error) {
+	if !desiredOut.IsPositive() {
+		return cosmath.Int{}, fmt.Errorf("output amount must be positive")
+	}
+	aToB, err := pool.isAToB(outputMint)
+	if err != nil {
+		return cosmath.Int{}, err
+	}
+	aToB = !aToB
+	if err := pool.checkTradable(); err != nil {
+		return cosmath.Int{}, err
+	}
+	out := func(amountIn cosmath.Int) cosmath.Int {
+		amountOut, err := pool.swapOut(aToB, amountIn)
+		if err != nil {
+			return cosmath.ZeroInt()
+		}
+		return amountOut
+	}
+
+	// Double the bound until it buys enough, then bisect; the reserve plus the input must
+	// fit in u64
+	_, destination := pool.reserves(aToB)
+	if desiredOut.GTE(cosmath.NewIntFromUint64(destination)) {
+		return cosmath.Int{}, fmt.Errorf("%w: pool holds %d", solerrors.ErrInsufficientLiquidity, destination)
+	}
+	low, high := cosmath.ZeroInt(), desiredOut
+	for out(high).LT(desiredOut) {
+		if !high.IsUint64() {
+			return cosmath.Int{}, fmt.Errorf("%w: pool can't pay out %s", solerrors.ErrInsufficientLiquidity, desiredOut)
+		}
+		low, high = high, high.MulRaw(2)
+	}
+	for high.Sub(low).GT(cosmath.OneInt()) {
+		mid := low.Add(high).QuoRaw(2)
+		if out(mid).GTE(desiredOut) {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+	return high, nil
+}
+
+// swapOut follows the program's swap_to: solve for the new destination reserve, keep one
+// unit for rounding and take the trade fee from what leaves the pool
+func (pool *Pool) swapOut(aToB bool, amountIn cosmath.Int) (cosmath.Int, error) {
+	source, destination := pool.reserves(aToB)
+	newSource := cosmath.NewIntFromUint64(source).Add(amountIn)
+	if !newSource.IsUint64() {
+		return cosmath.Int{}, fmt.Errorf("input overflows the pool reserve")
+	}
+	amp := ampFactor(pool.InitialAmpFactor, pool.TargetAmpFactor, pool.StartRampTs, pool.StopRampTs, pool.Now)
+	d := computeD(amp, new(big.Int).SetUint64(source), new(big.Int).SetUint64(destination))
+	y := computeY(amp, newSource.BigInt(), d)
+
+	dy := new(big.Int).Sub(new(big.Int).SetUint64(destination), y)
+	dy.Sub(dy, big.NewInt(1))
+	if dy.Sign() <= 0 {
+		return cosmath.ZeroInt(), nil
+	}
+	amountOut := cosmath.NewIntFromBigInt(dy)
+	return amountOut.Sub(pool.tradeFee(amountOut)), nil
+}
+
+func (pool *Pool) tradeFee(amount cosmath.Int) cosmath.Int {
+	if pool.TradeFeeDenominator == 0 {
+		return cosmath.ZeroInt()
+	}
+	return amount.Mul(cosmath.NewIntFromUint64(pool.TradeFeeNumerator)).Quo(cosmath.NewIntFromUint64(pool.TradeFeeDenominator))
+}
+
+// reserves returns the source and destination reserves of a swap direction
+func (pool *Pool) reserves(aToB bool) (uint64, uint64) {
+	if aToB {
+		return pool.ReserveA, pool.ReserveB
+	}
+	return pool.ReserveB, pool.ReserveA
+}
+
+func (pool *Pool) isAToB(inputMint string) (bool, error) {
+	switch inputMint {
+	case pool.TokenAMint.String():
+		return true, nil
+	case pool.TokenBMint.String():
+		return false, nil
+	default:
+		return false, fmt.Errorf("mint %s not in pool %s", inputMint, pool.PoolId)
+	}
+}
+
+// checkTradable rejects paused pools, and pools pairing tokens of different decimals: the
+// program runs the invariant on raw amounts, so such pairs are priced as if one unit of each
+// were worth the same. Saber lists those through decimal wrapper tokens instead.
+func (pool *Pool) checkTradable() error {
+	if !pool.IsInitialized {
+		return fmt.Errorf("pool %s is not initialized", pool.PoolId)
+	}
+	if pool.IsPaused {
+		return fmt.Errorf("pool %s is paused", pool.PoolId)
+	}
+	if pool.DecimalsA != pool.DecimalsB {
+		return fmt.Errorf("pool %s pairs %d and %d decimal tokens", pool.PoolId, pool.DecimalsA, pool.DecimalsB)
+	}
+	if pool.ReserveA == 0 || pool.ReserveB == 0 {
+		return fmt.Errorf("pool %s has no liquidity", pool.PoolId)
+	}
+	return nil
+}
+
+// authority derives the PDA that owns the reserves
+func (pool *Pool) authority() (solana.PublicKey, error) {
+	authority, err := solana.CreateProgramAddress([][]byte{pool.PoolId.Bytes(), {pool.Nonce}}, ProgramID)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to derive swap authority: %w", err)
+	}
+	return authority, nil
+}
+
+// BuildSwapInstructions swaps between the user's associated token accounts, which must exist
+func (pool *Pool) BuildSwapInstructions(ctx context.Context, solClient pkg.RPC, user solana.PublicKey, inputMint string, inputAmount cosmath.Int, minOut cosmath.Int) ([]solana.Instruction, error) {
+	aToB, err := pool.isAToB(inputMint)
+	if err != nil {
+		return nil, err
+	}
+	inst, err := pool.swapInstruction(user, aToB, inputAmount, minOut)
+	if err != nil {
+		return nil, err
+	}
+	return []solana.Instruction{inst}, nil
+}
+
+// BuildSwapInstructionsExactOut swaps the input quoted for amountOut, which must not exceed
+// maxIn. The program only swaps exact inputs, so amountOut is enforced as the minimum output.
+func (pool *Pool) BuildSwapInstructionsExactOut(ctx context.Context, solClient pkg.RPC, user solana.PublicKey, outputMint string, amountOut cosmath.Int, maxIn cosmath.Int) ([]solana.Instruction, error) {
+	aToB, err := pool.isAToB(outputMint)
+	if err != nil {
+		return nil, err
+	}
+	amountIn, err := pool.QuoteExactOut(ctx, solClient, outputMint, amountOut)
+	if err != nil {
+		return nil, err
+	}
+	if amountIn.GT(maxIn) {
+		return nil, fmt.Errorf("required input %s exceeds max input %s", amountIn, maxIn)
+	}
+	inst, err := pool.swapInstruction(user, !aToB, amountIn, amountOut)
+	if err != nil {
+		return nil, err
+	}
+	return []solana.Instruction{inst}, nil
+}
+
+func (pool *Pool) swapInstruction(user solana.PublicKey, aToB bool, amountIn, minOut cosmath.Int) (solana.Instruction, error) {
+	if !amountIn.IsUint64() || !minOut.IsUint64() {
+		return nil, fmt.Errorf("swap amounts must fit in u64")
+	}
+	authority, err := pool.authority()
+	if err != nil {
+		return nil, err
+	}
+	sourceMint, destinationMint := pool.TokenAMint, pool.TokenBMint
+	sourceReserve, destinationReserve := pool.TokenAReserve, pool.TokenBReserve
+	adminFeeAccount := pool.AdminFeeAccountB
+	if !aToB {
+		sourceMint, destinationMint = destinationMint, sourceMint
+		sourceReserve, destinationReserve = destinationReserve, sourceReserve
+		adminFeeAccount = pool.AdminFeeAccountA
+	}
+	userSource, _, err := solana.FindAssociatedTokenAddress(user, sourceMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive user source account: %w", err)
+	}
+	userDestination, _, err := solana.FindAssociatedTokenAddress(user, destinationMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive user destination account: %w", err)
+	}
+
+	data := []byte{swapIx}
+	data = binary.LittleEndian.AppendUint64(data, amountIn.Uint64())
+	data = binary.LittleEndian.AppendUint64(data, minOut.Uint64())
+	accounts := solana.AccountMetaSlice{
+		solana.NewAccountMeta(pool.PoolId, false, false),
+		solana.NewAccountMeta(authority, false, false),
+		solana.NewAccountMeta(user, false, true),
+		solana.NewAccountMeta(userSource, true, false),
+		solana.NewAccountMeta(sourceReserve, true, false),
+		solana.NewAccountMeta(destinationReserve, true, false),
+		solana.NewAccountMeta(userDestination, true, false),
+		solana.NewAccountMeta(adminFeeAccount, true, false),
+		solana.NewAccountMeta(solana.TokenProgramID, false, false),
+	}
+	return solana.NewInstruction(ProgramID, accounts, data), nil
+}
@@ -0,0 +1,43 @@
+// Package stakepool implements SOL deposits into and withdrawals from SPL stake pools, the
+// program behind most liquid staking tokens. Sanctum's single- and multi-validator stake pool
+// programs are forks with the same layout and instructions. Sanctum Infinity prices LSTs
+// through per-token calculator programs and is not covered here.
+package stakepool
+
+import (
+	"github.com/gagliardetto/solana-go"
+)
+
+var (
+	// SplProgramID is the SPL stake pool program, used by jitoSOL, bSOL and others
+	SplProgramID = solana.MustPublicKeyFromBase58("SPoo1Ku8WFXoNDMHPsrGSTSG1Y47rzgn41SLUNakuHy")
+	// SanctumSingleProgramID and SanctumMultiProgramID are Sanctum's stake pool deployments
+	SanctumSingleProgramID = solana.MustPublicKeyFromBase58("SP12tWFxD9oJsVWNavTTBZvMbA6gkAmxtVgxdqvyvhY")
+	SanctumMultiProgramID  = solana.MustPublicKeyFromBase58("SPMBzsVUuoHA4Jm6KunbsotaahvVikZs1JyTW6iJvbn")
+
+	// ProgramIDs are the stake pool programs searched for pools
+	ProgramIDs = []solana.PublicKey{SplProgramID, SanctumSingleProgramID, SanctumMultiProgramID}
+)
+
+// AccountTypeStakePool tags stake pool accounts, as opposed to validator lists
+const AccountTypeStakePool uint8 = 1
+
+// Stake pool account layout, up to the first variable-length field
+const (
+	AccountTypeOffset = 0
+	PoolMintOffset    = 162
+	feesOffset        = 330
+	fixedLayoutSize   = feesOffset + 16
+)
+
+// Instruction indices
+const (
+	depositSolWithSlippageIx  uint8 = 25
+	withdrawSolWithSlippageIx uint8 = 26
+)
+
+// stakeRentExemptReserveOffset is Meta.rent_exempt_reserve in an initialized stake account
+const stakeRentExemptReserveOffset = 4
+
+// clockEpochOffset is the epoch in the clock sysvar
+const clockEpochOffset = 16
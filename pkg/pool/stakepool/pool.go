@@ -0,0 +1,450 @@
+package stakepool
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gtdvccc/SolRouteTmp/pkg/txbuilder"
+	cosmath "cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+var _ pkg.Pool = (*Pool)(nil)
+
+// Fee is a fraction charged in pool tokens, rounded up
+type Fee struct {
+	Denominator uint64
+	Numerator   uint64
+}
+
+// apply returns the fee on amount, rounded up like the program does
+func (f Fee) apply(amount cosmath.Int) cosmath.Int {
+	if f.Denominator == 0 {
+		return cosmath.ZeroInt()
+	}
+	return ceilDiv(amount.Mul(cosmath.NewIntFromUint64(f.Numerator)), cosmath.NewIntFromUint64(f.Denominator))
+}
+
+// Pool is a stake pool quoted as a market between its pool token and WSOL. Trades move
+// native SOL: deposits spend the user's lamports and withdrawals pay lamports out of the
+// pool's reserve stake.
+type Pool struct {
+	PoolId               solana.PublicKey
+	ProgramID            solana.PublicKey
+	ReserveStake         solana.PublicKey
+	PoolMint             solana.PublicKey
+	ManagerFeeAccount    solana.PublicKey
+	TokenProgramID       solana.PublicKey
+	TotalLamports        uint64
+	PoolTokenSupply      uint64
+	LastUpdateEpoch      uint64
+	SolDepositAuthority  *solana.PublicKey // deposits need this signer when set
+	SolDepositFee        Fee
+	SolWithdrawAuthority *solana.PublicKey // withdrawals need this signer when set
+	SolWithdrawalFee     Fee
+
+	// Loaded by Quote
+	CurrentEpoch     uint64
+	ReserveAvailable uint64 // lamports withdrawable from the reserve stake
+}
+
+// NewPool decodes a stake pool account owned by programID
+func NewPool(id, programID solana.PublicKey, data []byte) (*Pool, error) {
+	pool := &Pool{PoolId: id, ProgramID: programID}
+	if err := pool.Decode(data); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// Decode reads a stake pool account. Optional fields and scheduled fee changes make the
+// layout variable-length after the epoch fee, so it is read field by field.
+func (pool *Pool) Decode(data []byte) error {
+	if len(data) < fixedLayoutSize {
+		return fmt.Errorf("stake pool account too short: %d bytes", len(data))
+	}
+	if data[AccountTypeOffset] != AccountTypeStakePool {
+		return fmt.Errorf("not a stake pool account: type %d", data[AccountTypeOffset])
+	}
+	r := &reader{data: data, pos: 130}
+	pool.ReserveStake = r.pubkey()
+	pool.PoolMint = r.pubkey()
+	pool.ManagerFeeAccount = r.pubkey()
+	pool.TokenProgramID = r.pubkey()
+	pool.TotalLamports = r.u64()
+	pool.PoolTokenSupply = r.u64()
+	pool.LastUpdateEpoch = r.u64()
+
+	r.pos = feesOffset
+	r.fee()          // epoch_fee
+	r.futureFee()    // next_epoch_fee
+	r.optionPubkey() // preferred_deposit_validator_vote_address
+	r.optionPubkey() // preferred_withdraw_validator_vote_address
+	r.fee()          // stake_deposit_fee
+	r.fee()          // stake_withdrawal_fee
+	r.futureFee()    // next_stake_withdrawal_fee
+	r.u8()           // stake_referral_fee
+	pool.SolDepositAuthority = r.optionPubkey()
+	pool.SolDepositFee = r.fee()
+	r.u8() // sol_referral_fee
+	pool.SolWithdrawAuthority = r.optionPubkey()
+	pool.SolWithdrawalFee = r.fee()
+	return r.err
+}
+
+// reader decodes borsh fields in order, remembering the first overrun
+type reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *reader) take(n int) []byte {
+	if r.err != nil {
+		return make([]byte, n)
+	}
+	if r.pos+n > len(r.data) {
+		r.err = fmt.Errorf("stake pool account truncated at offset %d", r.pos)
+		return make([]byte, n)
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *reader) u8() uint8 {
+	return r.take(1)[0]
+}
+
+func (r *reader) u64() uint64 {
+	return binary.LittleEndian.Uint64(r.take(8))
+}
+
+func (r *reader) pubkey() solana.PublicKey {
+	return solana.PublicKeyFromBytes(r.take(32))
+}
+
+func (r *reader) fee() Fee {
+	return Fee{Denominator: r.u64(), Numerator: r.u64()}
+}
+
+func (r *reader) optionPubkey() *solana.PublicKey {
+	if r.u8() == 0 {
+		return nil
+	}
+	key := r.pubkey()
+	return &key
+}
+
+// futureFee skips a FutureEpoch<Fee>: None, or a fee tagged with the epoch it applies from
+func (r *reader) futureFee() {
+	if r.u8() != 0 {
+		r.fee()
+	}
+}
+
+func (pool *Pool) ProtocolName() pkg.ProtocolName {
+	return pkg.ProtocolNameStakePool
+}
+
+func (pool *Pool) ProtocolType() pkg.ProtocolType {
+	return pkg.ProtocolTypeStakePool
+}
+
+func (pool *Pool) GetProgramID() solana.PublicKey {
+	return pool.ProgramID
+}
+
+func (pool *Pool) GetID() string {
+	return pool.PoolId.String()
+}
+
+// GetTokens returns the pool token and WSOL, which stands in for native SOL
+func (pool *Pool) GetTokens() (string, string) {
+	return pool.PoolMint.String(), solana.WrappedSol.String()
+}
+
+// refresh reloads the pool, the reserve stake's withdrawable lamports and the current epoch
+func (pool *Pool) refresh(ctx context.Context, solClient pkg.RPC) error {
+	accounts := []solana.PublicKey{pool.PoolId, pool.ReserveStake, solana.SysVarClockPubkey}
+	results, err := solClient.GetMultipleAccountsWithOpts(ctx, accounts, sol.MultipleAccountsOpts(ctx, rpc.CommitmentProcessed))
+	if err != nil {
+		return fmt.Errorf("failed to get stake pool %s: %w", pool.PoolId, err)
+	}
+	if len(results.Value) != len(accounts) {
+		return fmt.Errorf("expected %d accounts, got %d", len(accounts), len(results.Value))
+	}
+	for i, account := range results.Value {
+		if account == nil {
+			return fmt.Errorf("account %s not found", accounts[i])
+		}
+	}
+	if err := pool.Decode(results.Value[0].Data.GetBinary()); err != nil {
+		return fmt.Errorf("failed to decode stake pool %s: %w", pool.PoolId, err)
+	}
+	reserve := results.Value[1]
+	reserveData := reserve.Data.GetBinary()
+	if len(reserveData) < stakeRentExemptReserveOffset+8 {
+		return fmt.Errorf("reserve stake account too short: %d bytes", len(reserveData))
+	}
+	rentExempt := binary.LittleEndian.Uint64(reserveData[stakeRentExemptReserveOffset:])
+	pool.ReserveAvailable = reserve.Lamports - min(reserve.Lamports, rentExempt)
+	clock := results.Value[2].Data.GetBinary()
+	if len(clock) < clockEpochOffset+8 {
+		return fmt.Errorf("clock sysvar too short: %d bytes", len(clock))
+	}
+	pool.CurrentEpoch = binary.LittleEndian.Uint64(clock[clockEpochOffset:])
+	return nil
+}
+
+// Quote refreshes the pool and prices a deposit (WSOL in) or withdrawal (pool token in)
+func (pool *Pool) Quote(ctx context.Context, solClient pkg.RPC, inputMint string, inputAmount cosmath.Int) (cosmath.Int, error) {
+	if err := pool.refresh(ctx, solClient); err != nil {
+		return cosmath.Int{}, err
+	}
+	return pool.QuoteOffline(inputMint, inputAmount)
+}
+
+// QuoteOffline prices the trade against the pool as last loaded. Deposit and withdrawal
+// fees are charged in pool tokens.
+func (pool *Pool) QuoteOffline(inputMint string, inputAmount cosmath.Int) (cosmath.Int, error) {
+	if !inputAmount.IsPositive() {
+		return cosmath.Int{}, fmt.Errorf("input amount must be positive")
+	}
+	deposit, err := pool.isDeposit(inputMint, true)
+	if err != nil {
+		return cosmath.Int{}, err
+	}
+	if err := pool.checkTradable(deposit); err != nil {
+		return cosmath.Int{}, err
+	}
+	var out cosmath.Int
+	if deposit {
+		out = pool.depositOut(inputAmount)
+	} else {
+		out = pool.withdrawOut(inputAmount)
+		if out.GT(cosmath.NewIntFromUint64(pool.ReserveAvailable)) {
+			return cosmath.Int{}, fmt.Errorf("%w: reserve holds %d withdrawable lamports", solerrors.ErrInsufficientLiquidity, pool.ReserveAvailable)
+		}
+	}
+	if !out.IsPositive() {
+		return cosmath.Int{}, fmt.Errorf("amount too small to convert")
+	}
+	return out, nil
+}
+
+// QuoteExactOut refreshes the pool and returns the input needed for desiredOut
+func (pool *Pool) QuoteExactOut(ctx context.Context, solClient pkg.RPC, outputMint string, desiredOut cosmath.Int) (cosmath.Int, error) {
+	if err := pool.refresh(ctx, solClient); err != nil {
+		return cosmath.Int{}, err
+	}
+	return pool.QuoteExactOutOffline(outputMint, desiredOut)
+}
+
+// QuoteExactOutOffline is QuoteExactOut against the pool as last loaded
+func (pool *Pool) QuoteExactOutOffline(outputMint string, desiredOut cosmath.Int) (cosmath.Int, error) {
+	if !desiredOut.IsPositive() {
+		return cosmath.Int{}, fmt.Errorf("output amount must be positive")
+	}
+	deposit, err := pool.isDeposit(outputMint, false)
+	if err != nil {
+		return cosmath.Int{}, err
+	}
+	if err := pool.checkTradable(deposit); err != nil {
+		return cosmath.Int{}, err
+	}
+	if pool.TotalLamports == 0 || pool.PoolTokenSupply == 0 {
+		if deposit {
+			return pool.smallestInput(desiredOut, desiredOut, pool.depositOut), nil
+		}
+		return cosmath.Int{}, fmt.Errorf("pool holds no lamports")
+	}
+	total := cosmath.NewIntFromUint64(pool.TotalLamports)
+	supply := cosmath.NewIntFromUint64(pool.PoolTokenSupply)
+
+	if deposit {
+		minted := preFee(desiredOut, pool.SolDepositFee)
+		return pool.smallestInput(ceilDiv(minted.Mul(total), supply), desiredOut, pool.depositOut), nil
+	}
+	if desiredOut.GT(cosmath.NewIntFromUint64(pool.ReserveAvailable)) {
+		return cosmath.Int{}, fmt.Errorf("%w: reserve holds %d withdrawable lamports", solerrors.ErrInsufficientLiquidity, pool.ReserveAvailable)
+	}
+	burnt := ceilDiv(desiredOut.Mul(supply), total)
+	return pool.smallestInput(preFee(burnt, pool.SolWithdrawalFee), desiredOut, pool.withdrawOut), nil
+}
+
+// smallestInput steps up from estimate until out yields at least desiredOut; the closed-form
+// estimates are at most a few units short from rounding
+func (pool *Pool) smallestInput(estimate, desiredOut cosmath.Int, out func(cosmath.Int) cosmath.Int) cosmath.Int {
+	for out(estimate).LT(desiredOut) {
+		estimate = estimate.AddRaw(1)
+	}
+	return estimate
+}
+
+// depositOut is the pool tokens minted to the user for lamports, after the deposit fee
+func (pool *Pool) depositOut(lamports cosmath.Int) cosmath.Int {
+	minted := lamports
+	if pool.TotalLamports != 0 && pool.PoolTokenSupply != 0 {
+		minted = lamports.Mul(cosmath.NewIntFromUint64(pool.PoolTokenSupply)).Quo(cosmath.NewIntFromUint64(pool.TotalLamports))
+	}
+	return minted.Sub(pool.SolDepositFee.apply(minted))
+}
+
+// withdrawOut is the lamports paid for burning poolTokens, after the withdrawal fee
+func (pool *Pool) withdrawOut(poolTokens cosmath.Int) cosmath.Int {
+	if pool.PoolTokenSupply == 0 {
+		return cosmath.ZeroInt()
+	}
+	burnt := poolTokens.Sub(pool.SolWithdrawalFee.apply(poolTokens))
+	return burnt.Mul(cosmath.NewIntFromUint64(pool.TotalLamports)).Quo(cosmath.NewIntFromUint64(pool.PoolTokenSupply))
+}
+
+// preFee is the smallest amount that is at least postFee once fee is taken
+func preFee(postFee cosmath.Int, fee Fee) cosmath.Int {
+	if fee.Denominator == 0 || fee.Numerator >= fee.Denominator {
+		return postFee
+	}
+	denominator := cosmath.NewIntFromUint64(fee.Denominator)
+	amount := ceilDiv(postFee.Mul(denominator), denominator.Sub(cosmath.NewIntFromUint64(fee.Numerator)))
+	for amount.Sub(fee.apply(amount)).LT(postFee) {
+		amount = amount.AddRaw(1)
+	}
+	return amount
+}
+
+func ceilDiv(numerator, denominator cosmath.Int) cosmath.Int {
+	return numerator.Add(denominator).SubRaw(1).Quo(denominator)
+}
+
+// isDeposit reports whether the trade deposits SOL; mint is the input when isInput
+func (pool *Pool) isDeposit(mint string, isInput bool) (bool, error) {
+	switch mint {
+	case solana.WrappedSol.String():
+		return isInput, nil
+	case pool.PoolMint.String():
+		return !isInput, nil
+	default:
+		return false, fmt.Errorf("mint %s not in stake pool %s", mint, pool.PoolId)
+	}
+}
+
+// checkTradable rejects directions gated by an authority and pools not yet updated for the
+// current epoch, which the program refuses until the update crank runs
+func (pool *Pool) checkTradable(deposit bool) error {
+	if deposit && pool.SolDepositAuthority != nil {
+		return fmt.Errorf("stake pool %s restricts SOL deposits to %s", pool.PoolId, pool.SolDepositAuthority)
+	}
+	if !deposit && pool.SolWithdrawAuthority != nil {
+		return fmt.Errorf("stake pool %s restricts SOL withdrawals to %s", pool.PoolId, pool.SolWithdrawAuthority)
+	}
+	if pool.LastUpdateEpoch < pool.CurrentEpoch {
+		return fmt.Errorf("stake pool %s not updated since epoch %d", pool.PoolId, pool.LastUpdateEpoch)
+	}
+	return nil
+}
+
+// WithdrawAuthority derives the PDA that mints and burns the pool's tokens
+func (pool *Pool) WithdrawAuthority() (solana.PublicKey, error) {
+	address, err := txbuilder.StakePoolWithdrawAuthority(pool.ProgramID, pool.PoolId)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to derive withdraw authority: %w", err)
+	}
+	return address, nil
+}
+
+// BuildSwapInstructions deposits or withdraws an exact input with minOut enforced on chain.
+// The user's pool token account must exist.
+func (pool *Pool) BuildSwapInstructions(ctx context.Context, solClient pkg.RPC, user solana.PublicKey, inputMint string, inputAmount cosmath.Int, minOut cosmath.Int) ([]solana.Instruction, error) {
+	deposit, err := pool.isDeposit(inputMint, true)
+	if err != nil {
+		return nil, err
+	}
+	inst, err := pool.tradeInstruction(user, deposit, inputAmount, minOut)
+	if err != nil {
+		return nil, err
+	}
+	return []solana.Instruction{inst}, nil
+}
+
+// BuildSwapInstructionsExactOut deposits or withdraws the input quoted for amountOut, which
+// must not exceed maxIn. The program has no exact-output form, so a rate change between the
+// quote and execution moves the output rather than the input.
+func (pool *Pool) BuildSwapInstructionsExactOut(ctx context.Context, solClient pkg.RPC, user solana.PublicKey, outputMint string, amountOut cosmath.Int, maxIn cosmath.Int) ([]solana.Instruction, error) {
+	deposit, err := pool.isDeposit(outputMint, false)
+	if err != nil {
+		return nil, err
+	}
+	amountIn, err := pool.QuoteExactOut(ctx, solClient, outputMint, amountOut)
+	if err != nil {
+		return nil, err
+	}
+	if amountIn.GT(maxIn) {
+		return nil, fmt.Errorf("required input %s exceeds max input %s", amountIn, maxIn)
+	}
+	inst, err := pool.tradeInstruction(user, deposit, amountIn, amountOut)
+	if err != nil {
+		return nil, err
+	}
+	return []solana.Instruction{inst}, nil
+}
+
+// tradeInstruction builds DepositSolWithSlippage or WithdrawSolWithSlippage
+func (pool *Pool) tradeInstruction(user solana.PublicKey, deposit bool, amountIn, minOut cosmath.Int) (solana.Instruction, error) {
+	if !amountIn.IsUint64() || !minOut.IsUint64() {
+		return nil, fmt.Errorf("trade amounts must fit in u64")
+	}
+	withdrawAuthority, err := pool.WithdrawAuthority()
+	if err != nil {
+		return nil, err
+	}
+	userPoolToken, err := txbuilder.AssociatedTokenAddress(user, pool.PoolMint, pool.TokenProgramID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive user pool token account: %w", err)
+	}
+
+	ix := withdrawSolWithSlippageIx
+	if deposit {
+		ix = depositSolWithSlippageIx
+	}
+	data := []byte{ix}
+	data = binary.LittleEndian.AppendUint64(data, amountIn.Uint64())
+	data = binary.LittleEndian.AppendUint64(data, minOut.Uint64())
+
+	var accounts solana.AccountMetaSlice
+	if deposit {
+		accounts = solana.AccountMetaSlice{
+			solana.NewAccountMeta(pool.PoolId, true, false),
+			solana.NewAccountMeta(withdrawAuthority, false, false),
+			solana.NewAccountMeta(pool.ReserveStake, true, false),
+			solana.NewAccountMeta(user, true, true),
+			solana.NewAccountMeta(userPoolToken, true, false),
+			solana.NewAccountMeta(pool.ManagerFeeAccount, true, false),
+			solana.NewAccountMeta(pool.ManagerFeeAccount, true, false), // referrer
+			solana.NewAccountMeta(pool.PoolMint, true, false),
+			solana.NewAccountMeta(solana.SystemProgramID, false, false),
+			solana.NewAccountMeta(pool.TokenProgramID, false, false),
+		}
+	} else {
+		accounts = solana.AccountMetaSlice{
+			solana.NewAccountMeta(pool.PoolId, true, false),
+			solana.NewAccountMeta(withdrawAuthority, false, false),
+			solana.NewAccountMeta(user, false, true),
+			solana.NewAccountMeta(userPoolToken, true, false),
+			solana.NewAccountMeta(pool.ReserveStake, true, false),
+			solana.NewAccountMeta(user, true, false),
+			solana.NewAccountMeta(pool.ManagerFeeAccount, true, false),
+			solana.NewAccountMeta(pool.PoolMint, true, false),
+			solana.NewAccountMeta(solana.SysVarClockPubkey, false, false),
+			solana.NewAccountMeta(solana.SysVarStakeHistoryPubkey, false, false),
+			solana.NewAccountMeta(solana.StakeProgramID, false, false),
+			solana.NewAccountMeta(pool.TokenProgramID, false, false),
+		}
+	}
+	return solana.NewInstruction(pool.ProgramID, accounts, data), nil
+}
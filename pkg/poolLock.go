@@ -0,0 +1,19 @@
+package pkg
+
+import "sync"
+
+// poolLocks holds a mutex per pool, keyed by protocol and ID so that wrappers forwarding
+// GetID share the lock of the pool they wrap
+var poolLocks sync.Map
+
+// LockPool locks pool and returns the function that unlocks it. Pools refresh their state in
+// place when they quote and build swaps, so code sharing a pool between goroutines holds its
+// lock around Quote, QuoteExactOut, the swap builders and reads of the pool's state such as
+// Deprecation and QuoteAccounts. Pool implementations don't lock themselves, and callers
+// must not lock a pool they already hold.
+func LockPool(pool Pool) (unlock func()) {
+	key := string(pool.ProtocolName()) + "/" + pool.GetID()
+	mu, _ := poolLocks.LoadOrStore(key, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
@@ -0,0 +1,221 @@
+package price
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// DefaultMaxOracleAge is how old a feed's last update may be before its price is stale
+const DefaultMaxOracleAge = time.Minute
+
+var (
+	// ErrStalePrice is returned for feeds not updated within the oracle's maximum age
+	ErrStalePrice = errors.New("stale oracle price")
+	// ErrNoFeed is returned for mints without a configured feed
+	ErrNoFeed = errors.New("no oracle feed")
+)
+
+// OraclePrice is a pair's price according to an oracle
+type OraclePrice struct {
+	// Price is in quote tokens per base token, in decimal units
+	Price float64
+	// Confidence is the uncertainty of Price, in the same units
+	Confidence float64
+	// PublishTime is when the older of the pair's feeds was last updated
+	PublishTime time.Time
+}
+
+// Oracle prices pairs from a source independent of the pools being routed through
+type Oracle interface {
+	Price(ctx context.Context, baseMint, quoteMint string) (OraclePrice, error)
+}
+
+// FeedKind is the oracle program a feed account belongs to
+type FeedKind int
+
+const (
+	// FeedPyth is a Pyth pull oracle PriceUpdateV2 account
+	FeedPyth FeedKind = iota
+	// FeedSwitchboard is a Switchboard On-Demand PullFeed account
+	FeedSwitchboard
+)
+
+// Feed is an on-chain account pricing a token in a unit shared by the other feeds, usually USD
+type Feed struct {
+	Account solana.PublicKey
+	Kind    FeedKind
+}
+
+// FeedOracle prices pairs from the Pyth and Switchboard feeds of their two tokens, dividing
+// the base token's price by the quote token's. It is safe for concurrent use once its feeds
+// are set.
+type FeedOracle struct {
+	solClient pkg.RPC
+	feeds     map[string]Feed
+	maxAge    time.Duration
+	clock     clock.Clock
+}
+
+// NewFeedOracle creates an oracle reading feeds through solClient. Feeds are added with SetFeed.
+func NewFeedOracle(solClient pkg.RPC) *FeedOracle {
+	return &FeedOracle{
+		solClient: solClient,
+		feeds:     make(map[string]Feed),
+		maxAge:    DefaultMaxOracleAge,
+		clock:     clock.System{},
+	}
+}
+
+// SetFeed sets the feed pricing mint
+func (o *FeedOracle) SetFeed(mint string, feed Feed) {
+	o.feeds[mint] = feed
+}
+
+// SetMaxAge sets how old a feed's price may be. Zero accepts any age.
+func (o *FeedOracle) SetMaxAge(d time.Duration) {
+	o.maxAge = d
+}
+
+// SetClock replaces the clock prices are aged against
+func (o *FeedOracle) SetClock(clk clock.Clock) {
+	o.clock = clk
+}
+
+// Price reads the feeds of both mints in one request and returns the base mint's price in
+// the quote mint
+func (o *FeedOracle) Price(ctx context.Context, baseMint, quoteMint string) (OraclePrice, error) {
+	mints := []string{baseMint, quoteMint}
+	accounts := make([]solana.PublicKey, len(mints))
+	for i, mint := range mints {
+		feed, ok := o.feeds[mint]
+		if !ok {
+			return OraclePrice{}, fmt.Errorf("%w for %s", ErrNoFeed, mint)
+		}
+		accounts[i] = feed.Account
+	}
+	result, err := o.solClient.GetMultipleAccountsWithOpts(ctx, accounts, sol.MultipleAccountsOpts(ctx, rpc.CommitmentConfirmed))
+	if err != nil {
+		return OraclePrice{}, fmt.Errorf("failed to get oracle feeds: %w", err)
+	}
+	if len(result.Value) != len(accounts) {
+		return OraclePrice{}, fmt.Errorf("expected %d oracle feeds, got %d", len(accounts), len(result.Value))
+	}
+
+	prices := make([]OraclePrice, len(mints))
+	for i, account := range result.Value {
+		if account == nil {
+			return OraclePrice{}, fmt.Errorf("oracle feed %s not found", accounts[i])
+		}
+		parse := ParsePythPriceUpdate
+		if o.feeds[mints[i]].Kind == FeedSwitchboard {
+			parse = ParseSwitchboardPullFeed
+		}
+		prices[i], err = parse(account.Data.GetBinary())
+		if err != nil {
+			return OraclePrice{}, fmt.Errorf("failed to parse oracle feed %s: %w", accounts[i], err)
+		}
+		if prices[i].Price <= 0 {
+			return OraclePrice{}, fmt.Errorf("oracle feed %s has no price", accounts[i])
+		}
+		if age := o.clock.Now().Sub(prices[i].PublishTime); o.maxAge > 0 && age > o.maxAge {
+			return OraclePrice{}, fmt.Errorf("%w: feed %s of %s is %s old", ErrStalePrice, accounts[i], mints[i], age.Round(time.Second))
+		}
+	}
+
+	base, quote := prices[0], prices[1]
+	pair := OraclePrice{
+		Price:       base.Price / quote.Price,
+		PublishTime: base.PublishTime,
+	}
+	// Relative uncertainties add up through the division
+	pair.Confidence = pair.Price * (base.Confidence/base.Price + quote.Confidence/quote.Price)
+	if quote.PublishTime.Before(pair.PublishTime) {
+		pair.PublishTime = quote.PublishTime
+	}
+	return pair, nil
+}
+
+// PriceUpdateV2 layout: discriminator, write authority, then a verification level enum of
+// one byte, or two for partial verification, then the price message
+const (
+	pythVerificationOffset = 8 + 32
+	pythVerificationFull   = 1
+	pythPriceMessageSize   = 32 + 8 + 8 + 4 + 8
+)
+
+// ParsePythPriceUpdate reads the price of a Pyth PriceUpdateV2 account. Updates that were
+// only partially verified by the Wormhole guardians are rejected.
+func ParsePythPriceUpdate(data []byte) (OraclePrice, error) {
+	if len(data) <= pythVerificationOffset {
+		return OraclePrice{}, fmt.Errorf("price update too short: %d bytes", len(data))
+	}
+	if data[pythVerificationOffset] != pythVerificationFull {
+		return OraclePrice{}, fmt.Errorf("price update is not fully verified")
+	}
+	message := data[pythVerificationOffset+1:]
+	if len(message) < pythPriceMessageSize {
+		return OraclePrice{}, fmt.Errorf("price update too short: %d bytes", len(data))
+	}
+	// feed id, then price, confidence, exponent and publish time
+	price := int64(binary.LittleEndian.Uint64(message[32:]))
+	confidence := binary.LittleEndian.Uint64(message[40:])
+	exponent := int32(binary.LittleEndian.Uint32(message[48:]))
+	publishTime := int64(binary.LittleEndian.Uint64(message[52:]))
+	scale := math.Pow10(int(exponent))
+	return OraclePrice{
+		Price:       float64(price) * scale,
+		Confidence:  float64(confidence) * scale,
+		PublishTime: time.Unix(publishTime, 0),
+	}, nil
+}
+
+// PullFeedAccountData layout: discriminator, 32 oracle submissions of 64 bytes, then the
+// feed's configuration up to the last update time and the current result, whose values are
+// i128 scaled by 10^18
+const (
+	switchboardLastUpdateOffset = 2216
+	switchboardResultOffset     = 2264
+	switchboardStdDevOffset     = switchboardResultOffset + 16
+	switchboardDecimals         = 18
+)
+
+// ParseSwitchboardPullFeed reads the current result of a Switchboard On-Demand PullFeed
+// account, using its standard deviation as the confidence
+func ParseSwitchboardPullFeed(data []byte) (OraclePrice, error) {
+	if len(data) < switchboardStdDevOffset+16 {
+		return OraclePrice{}, fmt.Errorf("pull feed too short: %d bytes", len(data))
+	}
+	scaled := func(offset int) float64 {
+		value, _ := new(big.Float).Quo(new(big.Float).SetInt(readI128(data[offset:])), big.NewFloat(math.Pow10(switchboardDecimals))).Float64()
+		return value
+	}
+	return OraclePrice{
+		Price:       scaled(switchboardResultOffset),
+		Confidence:  scaled(switchboardStdDevOffset),
+		PublishTime: time.Unix(int64(binary.LittleEndian.Uint64(data[switchboardLastUpdateOffset:])), 0),
+	}, nil
+}
+
+// readI128 reads a little-endian two's complement i128
+func readI128(data []byte) *big.Int {
+	be := make([]byte, 16)
+	for i := range be {
+		be[i] = data[15-i]
+	}
+	value := new(big.Int).SetBytes(be)
+	if be[0]&0x80 != 0 {
+		value.Sub(value, new(big.Int).Lsh(big.NewInt(1), 128))
+	}
+	return value
+}
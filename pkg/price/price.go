@@ -0,0 +1,158 @@
+// Package price computes spot prices from pool states, sqrt prices for concentrated liquidity
+// pools, reserves for constant product pools and the active bin for DLMM pools, and
+// aggregates them across venues weighted by liquidity, so quotes can be checked against the
+// market and off-market pools spotted. FeedOracle reads Pyth and Switchboard feeds for a
+// reference independent of the pools.
+package price
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	cosmath "cosmossdk.io/math"
+)
+
+var (
+	// ErrUnsupported is returned for pools that don't implement pkg.SpotPricePool
+	ErrUnsupported = errors.New("pool does not report a spot price")
+	// ErrNoPrice is returned when none of the pools of a pair could be priced
+	ErrNoPrice = errors.New("no price")
+	// ErrOffMarket is returned by CheckQuote for quotes too far from the market price
+	ErrOffMarket = errors.New("quote off market")
+)
+
+// Mid is a pool's spot price oriented to a pair
+type Mid struct {
+	Pool pkg.Pool
+	// Price is in raw units of the quote token per raw unit of the base token
+	Price float64
+	// Depth is the pool's liquidity at the price in raw units of the quote token
+	Depth float64
+}
+
+// PoolMid returns pool's spot price in quote per base, whichever way round the pool holds
+// the pair
+func PoolMid(ctx context.Context, solClient pkg.RPC, pool pkg.Pool, base, quote string) (Mid, error) {
+	pricer, ok := pool.(pkg.SpotPricePool)
+	if !ok {
+		return Mid{}, fmt.Errorf("%s pool %s: %w", pool.ProtocolName(), pool.GetID(), ErrUnsupported)
+	}
+	token0, token1 := pool.GetTokens()
+	if !(token0 == base && token1 == quote) && !(token0 == quote && token1 == base) {
+		return Mid{}, fmt.Errorf("pool %s does not trade %s/%s", pool.GetID(), base, quote)
+	}
+	unlock := pkg.LockPool(pool)
+	spot, err := pricer.SpotPrice(ctx, solClient)
+	unlock()
+	if err != nil {
+		return Mid{}, fmt.Errorf("failed to get spot price of pool %s: %w", pool.GetID(), err)
+	}
+	if spot.Price <= 0 || math.IsInf(spot.Price, 0) || math.IsNaN(spot.Price) {
+		return Mid{}, fmt.Errorf("pool %s has no valid price", pool.GetID())
+	}
+	mid := Mid{Pool: pool, Price: spot.Price, Depth: spot.Depth}
+	if token0 == quote {
+		// The depth is valued in the pool's second token, here the base
+		mid.Price, mid.Depth = 1/spot.Price, spot.Depth/spot.Price
+	}
+	return mid, nil
+}
+
+// Market is the price of a pair across pools
+type Market struct {
+	Base  string
+	Quote string
+	// Price is the liquidity weighted median of the pools' prices, which a shallow pool far
+	// from the others can't move. Pools are weighted equally when none reports its depth.
+	Price float64
+	// Depth is the pools' total liquidity in raw units of the quote token
+	Depth float64
+	// Mids are the prices of the pools that could be priced, cheapest first
+	Mids []Mid
+	// Failed maps the IDs of the pools that couldn't be priced to the reason
+	Failed map[string]error
+}
+
+// Aggregate prices the pools of the base/quote pair. Pools that can't be priced are
+// reported in Failed; it fails only when none can.
+func Aggregate(ctx context.Context, solClient pkg.RPC, pools []pkg.Pool, base, quote string) (Market, error) {
+	market := Market{Base: base, Quote: quote, Failed: make(map[string]error)}
+	for _, pool := range pools {
+		mid, err := PoolMid(ctx, solClient, pool, base, quote)
+		if err != nil {
+			market.Failed[pool.GetID()] = err
+			continue
+		}
+		market.Mids = append(market.Mids, mid)
+		market.Depth += mid.Depth
+	}
+	if len(market.Mids) == 0 {
+		return market, fmt.Errorf("%s/%s: %w", base, quote, ErrNoPrice)
+	}
+
+	sort.SliceStable(market.Mids, func(i, j int) bool { return market.Mids[i].Price < market.Mids[j].Price })
+	weight := func(mid Mid) float64 {
+		if market.Depth <= 0 {
+			return 1
+		}
+		return mid.Depth
+	}
+	total := 0.0
+	for _, mid := range market.Mids {
+		total += weight(mid)
+	}
+	cumulative := 0.0
+	for _, mid := range market.Mids {
+		cumulative += weight(mid)
+		if cumulative >= total/2 {
+			market.Price = mid.Price
+			break
+		}
+	}
+	return market, nil
+}
+
+// DeviationBps returns how far price is from the market price in basis points, negative
+// below it
+func (m Market) DeviationBps(price float64) float64 {
+	return (price - m.Price) / m.Price * 10_000
+}
+
+// Outliers returns the pools priced more than maxDeviationBps away from the market
+func (m Market) Outliers(maxDeviationBps float64) []Mid {
+	var outliers []Mid
+	for _, mid := range m.Mids {
+		if math.Abs(m.DeviationBps(mid.Price)) > maxDeviationBps {
+			outliers = append(outliers, mid)
+		}
+	}
+	return outliers
+}
+
+// CheckQuote fails with ErrOffMarket when the price of swapping amountIn of inputMint for
+// amountOut is more than maxDeviationBps from the market price either way. The quote's
+// price includes fees and price impact, so the bound must allow for them.
+func (m Market) CheckQuote(inputMint string, amountIn, amountOut cosmath.Int, maxDeviationBps float64) error {
+	if !amountIn.IsPositive() || !amountOut.IsPositive() {
+		return fmt.Errorf("quote of %s for %s has no price", amountIn, amountOut)
+	}
+	in, _ := cosmath.LegacyNewDecFromInt(amountIn).Float64()
+	out, _ := cosmath.LegacyNewDecFromInt(amountOut).Float64()
+	var price float64
+	switch inputMint {
+	case m.Base:
+		price = out / in
+	case m.Quote:
+		price = in / out
+	default:
+		return fmt.Errorf("mint %s is not in %s/%s", inputMint, m.Base, m.Quote)
+	}
+	if deviation := m.DeviationBps(price); math.Abs(deviation) > maxDeviationBps {
+		return fmt.Errorf("%w: price %g is %.0f bps from the market's %g", ErrOffMarket, price, deviation, m.Price)
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package protocol
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gagliardetto/solana-go"
+)
+
+// Discovery finds the pools trading a pair without scanning program accounts, e.g. through a
+// venue's public API. WithDiscovery makes a protocol discover through one.
+type Discovery interface {
+	PoolsByPair(ctx context.Context, baseMint, quoteMint string) ([]solana.PublicKey, error)
+}
+
+// Public API endpoints queried by the API discoveries
+const (
+	RaydiumAPIEndpoint     = "https://api-v3.raydium.io"
+	OrcaAPIEndpoint        = "https://api.orca.so"
+	MeteoraDlmmAPIEndpoint = "https://dlmm-api.meteora.ag"
+
+	// DefaultAPIDiscoveryTimeout bounds one request to a discovery API
+	DefaultAPIDiscoveryTimeout = 5 * time.Second
+	// apiDiscoveryPageSize is how many pools are asked for; pairs rarely have more
+	apiDiscoveryPageSize = 100
+)
+
+var _ Discovery = (*APIDiscovery)(nil)
+
+// APIDiscovery lists a venue's pools for a pair through its public HTTP API
+type APIDiscovery struct {
+	endpoint string
+	client   *http.Client
+	// requests returns the URLs to query for the pair, relative to the endpoint
+	requests func(baseMint, quoteMint string) []string
+	// decode extracts the pool addresses from a response
+	decode func(body []byte) ([]solana.PublicKey, error)
+}
+
+// NewRaydiumAPIDiscovery lists Raydium pools of programID, one of the AMM v4, CPMM or CLMM
+// programs, through the Raydium v3 API
+func NewRaydiumAPIDiscovery(programID solana.PublicKey) *APIDiscovery {
+	return &APIDiscovery{
+		endpoint: RaydiumAPIEndpoint,
+		client:   &http.Client{Timeout: DefaultAPIDiscoveryTimeout},
+		requests: func(baseMint, quoteMint string) []string {
+			query := url.Values{
+				"mint1":         {baseMint},
+				"mint2":         {quoteMint},
+				"poolType":      {"all"},
+				"poolSortField": {"liquidity"},
+				"sortType":      {"desc"},
+				"pageSize":      {fmt.Sprint(apiDiscoveryPageSize)},
+				"page":          {"1"},
+			}
+			return []string{"/pools/info/mint?" + query.Encode()}
+		},
+		decode: func(body []byte) ([]solana.PublicKey, error) {
+			var resp struct {
+				Success bool `json:"success"`
+				Data    struct {
+					Data []struct {
+						ID        string `json:"id"`
+						ProgramID string `json:"programId"`
+					} `json:"data"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(body, &resp); err != nil {
+				return nil, err
+			}
+			if !resp.Success {
+				return nil, fmt.Errorf("request unsuccessful")
+			}
+			var pools []solana.PublicKey
+			for _, pool := range resp.Data.Data {
+				if pool.ProgramID != programID.String() {
+					continue
+				}
+				id, err := solana.PublicKeyFromBase58(pool.ID)
+				if err != nil {
+					return nil, fmt.Errorf("invalid pool address %q: %w", pool.ID, err)
+				}
+				pools = append(pools, id)
+			}
+			return pools, nil
+		},
+	}
+}
+
+// NewOrcaAPIDiscovery lists Whirlpools through the Orca v2 API
+func NewOrcaAPIDiscovery() *APIDiscovery {
+	return &APIDiscovery{
+		endpoint: OrcaAPIEndpoint,
+		client:   &http.Client{Timeout: DefaultAPIDiscoveryTimeout},
+		requests: func(baseMint, quoteMint string) []string {
+			query := url.Values{"tokensBothOf": {baseMint + "," + quoteMint}, "size": {fmt.Sprint(apiDiscoveryPageSize)}}
+			return []string{"/v2/solana/pools?" + query.Encode()}
+		},
+		decode: func(body []byte) ([]solana.PublicKey, error) {
+			var resp struct {
+				Data []struct {
+					Address string `json:"address"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(body, &resp); err != nil {
+				return nil, err
+			}
+			pools := make([]solana.PublicKey, 0, len(resp.Data))
+			for _, pool := range resp.Data {
+				id, err := solana.PublicKeyFromBase58(pool.Address)
+				if err != nil {
+					return nil, fmt.Errorf("invalid pool address %q: %w", pool.Address, err)
+				}
+				pools = append(pools, id)
+			}
+			return pools, nil
+		},
+	}
+}
+
+// NewMeteoraDlmmAPIDiscovery lists DLMM pairs through the Meteora DLMM API. Pairs are
+// keyed by mint order, so both orders are asked for.
+func NewMeteoraDlmmAPIDiscovery() *APIDiscovery {
+	return &APIDiscovery{
+		endpoint: MeteoraDlmmAPIEndpoint,
+		client:   &http.Client{Timeout: DefaultAPIDiscoveryTimeout},
+		requests: func(baseMint, quoteMint string) []string {
+			requests := make([]string, 0, 2)
+			for _, pair := range [][2]string{{baseMint, quoteMint}, {quoteMint, baseMint}} {
+				query := url.Values{"include_pool_token_pairs": {pair[0] + "-" + pair[1]}, "limit": {fmt.Sprint(apiDiscoveryPageSize)}}
+				requests = append(requests, "/pair/all_with_pagination?"+query.Encode())
+			}
+			return requests
+		},
+		decode: func(body []byte) ([]solana.PublicKey, error) {
+			var resp struct {
+				Pairs []struct {
+					Address string `json:"address"`
+				} `json:"pairs"`
+			}
+			if err := json.Unmarshal(body, &resp); err != nil {
+				return nil, err
+			}
+			pools := make([]solana.PublicKey, 0, len(resp.Pairs))
+			for _, pair := range resp.Pairs {
+				id, err := solana.PublicKeyFromBase58(pair.Address)
+				if err != nil {
+					return nil, fmt.Errorf("invalid pair address %q: %w", pair.Address, err)
+				}
+				pools = append(pools, id)
+			}
+			return pools, nil
+		},
+	}
+}
+
+// SetEndpoint points the discovery at another deployment of the API, e.g. a mirror
+func (d *APIDiscovery) SetEndpoint(endpoint string) {
+	d.endpoint = endpoint
+}
+
+// SetHTTPClient replaces the client requests are sent with
+func (d *APIDiscovery) SetHTTPClient(client *http.Client) {
+	d.client = client
+}
+
+// PoolsByPair returns the addresses the API lists for the pair, without duplicates
+func (d *APIDiscovery) PoolsByPair(ctx context.Context, baseMint, quoteMint string) ([]solana.PublicKey, error) {
+	seen := make(map[solana.PublicKey]bool)
+	var pools []solana.PublicKey
+	for _, path := range d.requests(baseMint, quoteMint) {
+		body, err := d.get(ctx, d.endpoint+path)
+		if err != nil {
+			return nil, err
+		}
+		found, err := d.decode(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode pools from %s: %w", d.endpoint, err)
+		}
+		for _, pool := range found {
+			if !seen[pool] {
+				seen[pool] = true
+				pools = append(pools, pool)
+			}
+		}
+	}
+	return pools, nil
+}
+
+func (d *APIDiscovery) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("pool discovery request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("pool discovery API returned %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// DiscoveringProtocol is a protocol whose pools are listed by a Discovery and fetched by ID.
+// When the discovery fails it falls back to the protocol's own getProgramAccounts scan.
+type DiscoveringProtocol struct {
+	pkg.Protocol
+	discovery Discovery
+	logger    pkg.Logger
+}
+
+// WithDiscovery makes proto discover pools for a pair through discovery, e.g.
+// WithDiscovery(NewOrcaWhirlpool(client), NewOrcaAPIDiscovery())
+func WithDiscovery(proto pkg.Protocol, discovery Discovery) *DiscoveringProtocol {
+	return &DiscoveringProtocol{Protocol: proto, discovery: discovery, logger: pkg.LoggerOrDefault(nil)}
+}
+
+// SetLogger sets where discovery failures are reported. Nil restores slog's default logger.
+func (p *DiscoveringProtocol) SetLogger(logger pkg.Logger) {
+	p.logger = pkg.LoggerOrDefault(logger)
+}
+
+// Name returns the wrapped protocol's name, or "" when it doesn't report one
+func (p *DiscoveringProtocol) Name() pkg.ProtocolName {
+	if named, ok := p.Protocol.(pkg.NamedProtocol); ok {
+		return named.Name()
+	}
+	return ""
+}
+
+// FetchPoolsByPair fetches the pools the discovery lists for the pair. Listed pools that
+// can't be fetched or decoded, e.g. ones closed since the API last indexed, are skipped.
+func (p *DiscoveringProtocol) FetchPoolsByPair(ctx context.Context, baseMint, quoteMint string) ([]pkg.Pool, error) {
+	ids, err := p.discovery.PoolsByPair(ctx, baseMint, quoteMint)
+	if err != nil {
+		p.logger.Warn("pool discovery failed, scanning instead", pkg.LogKeyProtocol, p.Name(), pkg.LogKeyError, err)
+		return p.Protocol.FetchPoolsByPair(ctx, baseMint, quoteMint)
+	}
+	pools := make([]pkg.Pool, 0, len(ids))
+	for _, id := range ids {
+		pool, err := p.Protocol.FetchPoolByID(ctx, id.String())
+		if err != nil {
+			p.logger.Debug("skipping listed pool", pkg.LogKeyPool, id.String(), pkg.LogKeyError, err)
+			continue
+		}
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
@@ -0,0 +1,25 @@
+package protocol
+
+import (
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+)
+
+// Defaults returns every built-in protocol, discovering pools through solClient
+func Defaults(solClient *sol.Client) []pkg.Protocol {
+	return []pkg.Protocol{
+		NewPumpAmm(solClient),
+		NewRaydiumAmm(solClient),
+		NewRaydiumClmm(solClient),
+		NewRaydiumCpmm(solClient),
+		NewMeteoraDlmm(solClient),
+		NewOrcaWhirlpool(solClient),
+		NewPhoenix(solClient),
+		NewMeteoraDamm(solClient),
+		NewMeteoraDammV2(solClient),
+		NewRaydiumLaunchLab(solClient),
+		NewMoonshot(solClient),
+		NewStakePool(solClient),
+		NewSaber(solClient),
+	}
+}
@@ -0,0 +1,100 @@
+package protocol
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/meteora"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// MeteoraDammProtocol handles Meteora Dynamic AMM (DAMM v1) pools
+type MeteoraDammProtocol struct {
+	SolClient *sol.Client
+}
+
+// NewMeteoraDamm creates a new MeteoraDammProtocol instance
+func NewMeteoraDamm(solClient *sol.Client) *MeteoraDammProtocol {
+	return &MeteoraDammProtocol{
+		SolClient: solClient,
+	}
+}
+
+// Name returns the protocol name of the pools it discovers
+func (protocol *MeteoraDammProtocol) Name() pkg.ProtocolName {
+	return pkg.ProtocolNameMeteoraDamm
+}
+
+// FetchPoolsByPair retrieves the enabled constant-product Dynamic AMM pools for a token pair
+func (protocol *MeteoraDammProtocol) FetchPoolsByPair(ctx context.Context, baseMint string, quoteMint string) ([]pkg.Pool, error) {
+	programAccounts := rpc.GetProgramAccountsResult{}
+	for _, pair := range [][2]string{{baseMint, quoteMint}, {quoteMint, baseMint}} {
+		accounts, err := protocol.getPoolAccountsByTokenPair(ctx, pair[0], pair[1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch pools with %s as token A: %w", pair[0], err)
+		}
+		programAccounts = append(programAccounts, accounts...)
+	}
+
+	pools := make([]pkg.Pool, 0, len(programAccounts))
+	for _, account := range programAccounts {
+		pool := &meteora.MeteoraDammPool{}
+		if err := pool.Decode(account.Account.Data.GetBinary()); err != nil || !pool.Enabled {
+			// Skip stable-curve, disabled and undecodable pools
+			continue
+		}
+		pool.PoolId = account.Pubkey
+		pool.TimeSource = protocol.SolClient.TimeSource
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
+
+func (protocol *MeteoraDammProtocol) getPoolAccountsByTokenPair(ctx context.Context, tokenAMint string, tokenBMint string) (rpc.GetProgramAccountsResult, error) {
+	tokenAKey, err := solana.PublicKeyFromBase58(tokenAMint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token A mint address: %w", err)
+	}
+	tokenBKey, err := solana.PublicKeyFromBase58(tokenBMint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token B mint address: %w", err)
+	}
+	var poolLayout meteora.MeteoraDammPool
+	result, err := protocol.SolClient.RpcClient.GetProgramAccountsWithOpts(ctx, meteora.MeteoraDammProgramID, &rpc.GetProgramAccountsOpts{
+		Filters: []rpc.RPCFilter{
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: 0, Bytes: meteora.DammPoolDiscriminator[:]}},
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: poolLayout.Offset("TokenAMint"), Bytes: tokenAKey.Bytes()}},
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: poolLayout.Offset("TokenBMint"), Bytes: tokenBKey.Bytes()}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get program accounts: %w", err)
+	}
+	return result, nil
+}
+
+// FetchPoolByID retrieves a specific Dynamic AMM pool by its ID
+func (protocol *MeteoraDammProtocol) FetchPoolByID(ctx context.Context, poolID string) (pkg.Pool, error) {
+	poolKey, err := solana.PublicKeyFromBase58(poolID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pool id: %w", err)
+	}
+	account, err := protocol.SolClient.RpcClient.GetAccountInfo(ctx, poolKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool account: %w", err)
+	}
+	if !account.Value.Owner.Equals(meteora.MeteoraDammProgramID) {
+		return nil, fmt.Errorf("pool %s is owned by %s, not the dynamic amm program", poolID, account.Value.Owner)
+	}
+
+	pool := &meteora.MeteoraDammPool{}
+	if err := pool.Decode(account.Value.Data.GetBinary()); err != nil {
+		return nil, fmt.Errorf("failed to decode pool data: %w", err)
+	}
+	pool.PoolId = poolKey
+	pool.TimeSource = protocol.SolClient.TimeSource
+	return pool, nil
+}
@@ -0,0 +1,96 @@
+package protocol
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/meteora/dammv2"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// MeteoraDammV2Protocol handles Meteora DAMM v2 (cp-amm) pools
+type MeteoraDammV2Protocol struct {
+	SolClient *sol.Client
+}
+
+// NewMeteoraDammV2 creates a new MeteoraDammV2Protocol instance
+func NewMeteoraDammV2(solClient *sol.Client) *MeteoraDammV2Protocol {
+	return &MeteoraDammV2Protocol{
+		SolClient: solClient,
+	}
+}
+
+// Name returns the protocol name of the pools it discovers
+func (protocol *MeteoraDammV2Protocol) Name() pkg.ProtocolName {
+	return pkg.ProtocolNameMeteoraDammV2
+}
+
+// FetchPoolsByPair retrieves the enabled DAMM v2 pools for a token pair
+func (protocol *MeteoraDammV2Protocol) FetchPoolsByPair(ctx context.Context, baseMint string, quoteMint string) ([]pkg.Pool, error) {
+	programAccounts := rpc.GetProgramAccountsResult{}
+	for _, pair := range [][2]string{{baseMint, quoteMint}, {quoteMint, baseMint}} {
+		accounts, err := protocol.getPoolAccountsByTokenPair(ctx, pair[0], pair[1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch pools with %s as token A: %w", pair[0], err)
+		}
+		programAccounts = append(programAccounts, accounts...)
+	}
+
+	pools := make([]pkg.Pool, 0, len(programAccounts))
+	for _, account := range programAccounts {
+		pool, err := dammv2.NewPool(account.Pubkey, account.Account.Data.GetBinary())
+		if err != nil || pool.PoolStatus != dammv2.PoolStatusEnabled {
+			// Skip disabled and undecodable pools
+			continue
+		}
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
+
+func (protocol *MeteoraDammV2Protocol) getPoolAccountsByTokenPair(ctx context.Context, tokenAMint string, tokenBMint string) (rpc.GetProgramAccountsResult, error) {
+	tokenAKey, err := solana.PublicKeyFromBase58(tokenAMint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token A mint address: %w", err)
+	}
+	tokenBKey, err := solana.PublicKeyFromBase58(tokenBMint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token B mint address: %w", err)
+	}
+	result, err := protocol.SolClient.RpcClient.GetProgramAccountsWithOpts(ctx, dammv2.ProgramID, &rpc.GetProgramAccountsOpts{
+		Filters: []rpc.RPCFilter{
+			{DataSize: dammv2.PoolAccountSize},
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: 0, Bytes: dammv2.PoolDiscriminator[:]}},
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: dammv2.TokenAMintOffset, Bytes: tokenAKey.Bytes()}},
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: dammv2.TokenBMintOffset, Bytes: tokenBKey.Bytes()}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get program accounts: %w", err)
+	}
+	return result, nil
+}
+
+// FetchPoolByID retrieves a specific DAMM v2 pool by its ID
+func (protocol *MeteoraDammV2Protocol) FetchPoolByID(ctx context.Context, poolID string) (pkg.Pool, error) {
+	poolKey, err := solana.PublicKeyFromBase58(poolID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pool id: %w", err)
+	}
+	account, err := protocol.SolClient.RpcClient.GetAccountInfo(ctx, poolKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool account: %w", err)
+	}
+	if !account.Value.Owner.Equals(dammv2.ProgramID) {
+		return nil, fmt.Errorf("pool %s is owned by %s, not the damm v2 program", poolID, account.Value.Owner)
+	}
+
+	pool, err := dammv2.NewPool(poolKey, account.Value.Data.GetBinary())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode pool data: %w", err)
+	}
+	return pool, nil
+}
@@ -24,6 +24,11 @@ func NewMeteoraDlmm(solClient *sol.Client) *MeteoraDlmmProtocol {
 	}
 }
 
+// Name returns the protocol name of the pools it discovers
+func (protocol *MeteoraDlmmProtocol) Name() pkg.ProtocolName {
+	return pkg.ProtocolNameMeteoraDlmm
+}
+
 // FetchPoolsByPair retrieves all Meteora DLMM pools for a given token pair
 func (protocol *MeteoraDlmmProtocol) FetchPoolsByPair(ctx context.Context, baseMint string, quoteMint string) ([]pkg.Pool, error) {
 	programAccounts := rpc.GetProgramAccountsResult{}
@@ -51,6 +56,7 @@ func (protocol *MeteoraDlmmProtocol) FetchPoolsByPair(ctx context.Context, baseM
 		}
 
 		poolData.PoolId = account.Pubkey
+		poolData.TimeSource = protocol.SolClient.TimeSource
 		if err := poolData.GetBinArrayForSwap(ctx, protocol.SolClient); err != nil {
 			// Skip pools that can't get bin array
 			continue
@@ -108,5 +114,6 @@ func (protocol *MeteoraDlmmProtocol) FetchPoolByID(ctx context.Context, poolID s
 
 	bitmapExtensionKey, _ := meteora.DeriveBinArrayBitmapExtension(poolData.PoolId)
 	poolData.BitmapExtensionKey = bitmapExtensionKey
+	poolData.TimeSource = protocol.SolClient.TimeSource
 	return poolData, nil
 }
@@ -0,0 +1,83 @@
+package protocol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/moonshot"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// MoonshotProtocol handles Moonshot launchpad bonding curves
+type MoonshotProtocol struct {
+	SolClient *sol.Client
+}
+
+// NewMoonshot creates a new MoonshotProtocol instance
+func NewMoonshot(solClient *sol.Client) *MoonshotProtocol {
+	return &MoonshotProtocol{
+		SolClient: solClient,
+	}
+}
+
+// Name returns the protocol name of the pools it discovers
+func (protocol *MoonshotProtocol) Name() pkg.ProtocolName {
+	return pkg.ProtocolNameMoonshot
+}
+
+// FetchPoolsByPair returns the curve of a token traded against WSOL. Each token has at most
+// one curve, at an address derived from its mint, so no program scan is needed.
+func (protocol *MoonshotProtocol) FetchPoolsByPair(ctx context.Context, baseMint string, quoteMint string) ([]pkg.Pool, error) {
+	tokenMint := baseMint
+	switch solana.WrappedSol.String() {
+	case baseMint:
+		tokenMint = quoteMint
+	case quoteMint:
+	default:
+		return nil, nil
+	}
+	mint, err := solana.PublicKeyFromBase58(tokenMint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token mint address: %w", err)
+	}
+	curve, err := moonshot.CurveAddress(mint)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := protocol.fetchCurve(ctx, curve)
+	if errors.Is(err, rpc.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []pkg.Pool{pool}, nil
+}
+
+// FetchPoolByID retrieves a curve by its account address
+func (protocol *MoonshotProtocol) FetchPoolByID(ctx context.Context, poolID string) (pkg.Pool, error) {
+	curve, err := solana.PublicKeyFromBase58(poolID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pool id: %w", err)
+	}
+	return protocol.fetchCurve(ctx, curve)
+}
+
+func (protocol *MoonshotProtocol) fetchCurve(ctx context.Context, curve solana.PublicKey) (*moonshot.CurvePool, error) {
+	account, err := protocol.SolClient.RpcClient.GetAccountInfo(ctx, curve)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get curve account %s: %w", curve, err)
+	}
+	if !account.Value.Owner.Equals(moonshot.ProgramID) {
+		return nil, fmt.Errorf("curve %s is owned by %s, not the moonshot program", curve, account.Value.Owner)
+	}
+	pool, err := moonshot.NewCurvePool(curve, account.Value.Data.GetBinary())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode curve %s: %w", curve, err)
+	}
+	return pool, nil
+}
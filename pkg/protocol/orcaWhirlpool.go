@@ -25,6 +25,13 @@ import (
 // - SwapV2 instruction support
 type OrcaWhirlpoolProtocol struct {
 	SolClient *sol.Client
+
+	programID        solana.PublicKey
+	whirlpoolsConfig solana.PublicKey // zero matches pools under any config
+	// clusterErr is set when Orca isn't deployed on the cluster
+	clusterErr error
+	// initializeTickArrays keeps pools missing tick arrays, which their swaps create
+	initializeTickArrays bool
 }
 
 // NewOrcaWhirlpool creates a new Orca Whirlpool protocol instance
@@ -33,16 +40,59 @@ type OrcaWhirlpoolProtocol struct {
 //   - solClient: Solana client for blockchain interaction
 //
 // Returns:
-//   - *OrcaWhirlpoolProtocol: protocol instance
+//   - *OrcaWhirlpoolProtocol: protocol instance for Orca's deployment on solClient's cluster
 func NewOrcaWhirlpool(solClient *sol.Client) *OrcaWhirlpoolProtocol {
-	return &OrcaWhirlpoolProtocol{
+	p := &OrcaWhirlpoolProtocol{
 		SolClient: solClient,
 	}
+	p.clusterErr = p.SetCluster(sol.ClusterOf(solClient))
+	return p
+}
+
+// Name returns the protocol name of the pools it discovers
+func (p *OrcaWhirlpoolProtocol) Name() pkg.ProtocolName {
+	return pkg.ProtocolNameOrcaWhirlpool
+}
+
+// SetCluster points pool discovery at the program and WhirlpoolsConfig of Orca's deployment
+// on the cluster, overriding the cluster of the client
+func (p *OrcaWhirlpoolProtocol) SetCluster(cluster sol.Cluster) error {
+	programID, whirlpoolsConfig, err := orca.ProgramIDsForCluster(cluster)
+	if err != nil {
+		return err
+	}
+	p.programID = programID
+	p.whirlpoolsConfig = whirlpoolsConfig
+	p.clusterErr = nil
+	return nil
+}
+
+// SetProgramID points discovery and the pools found at a Whirlpool program deployed at
+// programID, e.g. on a cluster Orca isn't on; pair it with SetWhirlpoolsConfig
+func (p *OrcaWhirlpoolProtocol) SetProgramID(programID solana.PublicKey) {
+	p.programID = programID
+	p.clusterErr = nil
+}
+
+// SetWhirlpoolsConfig restricts discovery to pools created under config, e.g. a third-party
+// deployment's config; the zero key matches pools under any config
+func (p *OrcaWhirlpoolProtocol) SetWhirlpoolsConfig(config solana.PublicKey) {
+	p.whirlpoolsConfig = config
+}
+
+// SetInitializeTickArrays keeps pools whose swaps need tick arrays not created yet rather than
+// discarding them. Their swaps start with initialize_tick_array instructions, the user paying
+// each array's rent of about 0.07 SOL.
+func (p *OrcaWhirlpoolProtocol) SetInitializeTickArrays(enabled bool) {
+	p.initializeTickArrays = enabled
 }
 
 // FetchPoolsByPair gets Whirlpool pool list by token pair
 // Reference raydiumClmm.go implementation, adjust field name mapping
 func (p *OrcaWhirlpoolProtocol) FetchPoolsByPair(ctx context.Context, baseMint string, quoteMint string) ([]pkg.Pool, error) {
+	if p.clusterErr != nil {
+		return nil, p.clusterErr
+	}
 	accounts := make([]*rpc.KeyedAccount, 0)
 
 	// Query pools for baseMint -> quoteMint
@@ -59,6 +109,7 @@ func (p *OrcaWhirlpoolProtocol) FetchPoolsByPair(ctx context.Context, baseMint s
 	}
 	accounts = append(accounts, programAccounts...)
 
+	logger := pkg.LoggerOrDefault(p.SolClient.Logger)
 	res := make([]pkg.Pool, 0)
 	for _, v := range accounts {
 		data := v.Account.Data.GetBinary()
@@ -67,25 +118,28 @@ func (p *OrcaWhirlpoolProtocol) FetchPoolsByPair(ctx context.Context, baseMint s
 			continue
 		}
 		layout.PoolId = v.Pubkey
+		layout.ProgramID = p.getProgramID()
+		layout.Sleeper = p.SolClient.Sleeper
+		layout.Logger = p.SolClient.Logger
+		layout.InitializeTickArrays = p.initializeTickArrays
 
 		// Add pool quality checks similar to CLMM's IsSwapEnabled check
 		// Filter out unhealthy pools at search time to prevent selection of problematic pools
 		if healthy, err := layout.IsHealthy(); !healthy {
-			// Log the reason but don't fail completely - just skip this pool
-			fmt.Printf("Skipping unhealthy pool %s: %v\n", layout.PoolId.String(), err)
+			logger.Debug("skipping unhealthy pool", pkg.LogKeyPool, layout.PoolId.String(), pkg.LogKeyError, err)
 			continue
 		}
 
 		// Basic pool state validation before adding to results
 		if err := layout.ValidatePoolState(); err != nil {
-			fmt.Printf("Skipping invalid pool %s: %v\n", layout.PoolId.String(), err)
+			logger.Debug("skipping invalid pool", pkg.LogKeyPool, layout.PoolId.String(), pkg.LogKeyError, err)
 			continue
 		}
 
 		// Critical tick array validation at search time to prevent 6038 errors
 		// Check for missing tick arrays that would definitely cause transaction failures
 		if err := p.validateCriticalTickArrays(ctx, layout); err != nil {
-			fmt.Printf("Skipping pool with critical tick array issues %s: %v\n", layout.PoolId.String(), err)
+			logger.Debug("skipping pool with missing tick arrays", pkg.LogKeyPool, layout.PoolId.String(), pkg.LogKeyError, err)
 			continue
 		}
 
@@ -110,31 +164,40 @@ func (p *OrcaWhirlpoolProtocol) getWhirlpoolAccountsByTokenPair(ctx context.Cont
 	whirlpoolDiscriminator := [8]byte{63, 149, 209, 12, 225, 128, 99, 9}
 
 	var knownPoolLayout orca.WhirlpoolPool
-	result, err := p.SolClient.RpcClient.GetProgramAccountsWithOpts(ctx, orca.ORCA_WHIRLPOOL_PROGRAM_ID, &rpc.GetProgramAccountsOpts{
-		Filters: []rpc.RPCFilter{
-			{
-				// First filter Whirlpool discriminator (ensure only querying Whirlpool accounts)
-				Memcmp: &rpc.RPCFilterMemcmp{
-					Offset: 0, // Discriminator at beginning of account data
-					Bytes:  whirlpoolDiscriminator[:],
-				},
-			},
-			{
-				DataSize: uint64(knownPoolLayout.Span()),
+	filters := []rpc.RPCFilter{
+		{
+			// First filter Whirlpool discriminator (ensure only querying Whirlpool accounts)
+			Memcmp: &rpc.RPCFilterMemcmp{
+				Offset: 0, // Discriminator at beginning of account data
+				Bytes:  whirlpoolDiscriminator[:],
 			},
-			{
-				Memcmp: &rpc.RPCFilterMemcmp{
-					Offset: knownPoolLayout.Offset("TokenMintA"), // Note: CLMM uses TokenMint0
-					Bytes:  baseKey.Bytes(),
-				},
+		},
+		{
+			DataSize: uint64(knownPoolLayout.Span()),
+		},
+		{
+			Memcmp: &rpc.RPCFilterMemcmp{
+				Offset: knownPoolLayout.Offset("TokenMintA"), // Note: CLMM uses TokenMint0
+				Bytes:  baseKey.Bytes(),
 			},
-			{
-				Memcmp: &rpc.RPCFilterMemcmp{
-					Offset: knownPoolLayout.Offset("TokenMintB"), // Note: CLMM uses TokenMint1
-					Bytes:  quoteKey.Bytes(),
-				},
+		},
+		{
+			Memcmp: &rpc.RPCFilterMemcmp{
+				Offset: knownPoolLayout.Offset("TokenMintB"), // Note: CLMM uses TokenMint1
+				Bytes:  quoteKey.Bytes(),
 			},
 		},
+	}
+	if !p.whirlpoolsConfig.IsZero() {
+		filters = append(filters, rpc.RPCFilter{
+			Memcmp: &rpc.RPCFilterMemcmp{
+				Offset: knownPoolLayout.Offset("WhirlpoolsConfig"),
+				Bytes:  p.whirlpoolsConfig.Bytes(),
+			},
+		})
+	}
+	result, err := p.SolClient.RpcClient.GetProgramAccountsWithOpts(ctx, p.getProgramID(), &rpc.GetProgramAccountsOpts{
+		Filters: filters,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pools: %w", err)
@@ -146,6 +209,9 @@ func (p *OrcaWhirlpoolProtocol) getWhirlpoolAccountsByTokenPair(ctx context.Cont
 // FetchPoolByID gets single Whirlpool pool by pool ID
 // Reference raydiumClmm.go implementation
 func (p *OrcaWhirlpoolProtocol) FetchPoolByID(ctx context.Context, poolId string) (pkg.Pool, error) {
+	if p.clusterErr != nil {
+		return nil, p.clusterErr
+	}
 	poolIdKey, err := solana.PublicKeyFromBase58(poolId)
 	if err != nil {
 		return nil, fmt.Errorf("invalid pool id: %w", err)
@@ -156,16 +222,33 @@ func (p *OrcaWhirlpoolProtocol) FetchPoolByID(ctx context.Context, poolId string
 		return nil, fmt.Errorf("failed to get pool account %s: %w", poolId, err)
 	}
 
+	if !account.Value.Owner.Equals(p.getProgramID()) {
+		return nil, fmt.Errorf("pool %s is owned by %s, not the whirlpool program", poolId, account.Value.Owner)
+	}
+
 	data := account.Value.Data.GetBinary()
 	layout := &orca.WhirlpoolPool{}
 	if err := layout.Decode(data); err != nil {
 		return nil, fmt.Errorf("failed to decode pool data for %s: %w", poolId, err)
 	}
 	layout.PoolId = poolIdKey
+	layout.ProgramID = p.getProgramID()
+	layout.Sleeper = p.SolClient.Sleeper
+	layout.Logger = p.SolClient.Logger
+	layout.InitializeTickArrays = p.initializeTickArrays
 
 	return layout, nil
 }
 
+// getProgramID returns the configured program, falling back to the package default for
+// protocols not built with NewOrcaWhirlpool
+func (p *OrcaWhirlpoolProtocol) getProgramID() solana.PublicKey {
+	if p.programID.IsZero() {
+		return orca.WhirlpoolProgramID()
+	}
+	return p.programID
+}
+
 // validatePoolTickArrays validates pool's tick array integrity to prevent 6038 errors
 func (p *OrcaWhirlpoolProtocol) validatePoolTickArrays(ctx context.Context, pool *orca.WhirlpoolPool) error {
 	// Check both directions (A->B and B->A) to ensure tick arrays are valid
@@ -174,6 +257,7 @@ func (p *OrcaWhirlpoolProtocol) validatePoolTickArrays(ctx context.Context, pool
 	for _, aToB := range directions {
 		// Get required tick array addresses
 		tickArray0, tickArray1, tickArray2, err := orca.DeriveMultipleWhirlpoolTickArrayPDAs(
+			pool.GetProgramID(),
 			pool.PoolId,
 			int64(pool.TickCurrentIndex),
 			int64(pool.TickSpacing),
@@ -238,6 +322,7 @@ func (p *OrcaWhirlpoolProtocol) validateCriticalTickArrays(ctx context.Context,
 	for _, aToB := range directions {
 		// Get required tick array addresses
 		tickArray0, tickArray1, tickArray2, err := orca.DeriveMultipleWhirlpoolTickArrayPDAs(
+			pool.GetProgramID(),
 			pool.PoolId,
 			int64(pool.TickCurrentIndex),
 			int64(pool.TickSpacing),
@@ -256,6 +341,11 @@ func (p *OrcaWhirlpoolProtocol) validateCriticalTickArrays(ctx context.Context,
 			return fmt.Errorf("failed to query tick arrays for direction aToB=%v: %w", aToB, err)
 		}
 
+		// Missing tick arrays are fine when the swap creates them
+		if p.initializeTickArrays && results.Value[0] == nil {
+			continue
+		}
+
 		// Primary tick array must exist
 		if results.Value[0] == nil {
 			return fmt.Errorf("primary tick array missing for direction aToB=%v", aToB)
@@ -271,7 +361,7 @@ func (p *OrcaWhirlpoolProtocol) validateCriticalTickArrays(ctx context.Context,
 		}
 
 		// If more than one tick array is missing, this pool is problematic
-		if missingArrays > 1 {
+		if missingArrays > 1 && !p.initializeTickArrays {
 			return fmt.Errorf("too many missing tick arrays (%d) for direction aToB=%v", missingArrays, aToB)
 		}
 
@@ -0,0 +1,96 @@
+package protocol
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/phoenix"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// PhoenixProtocol discovers Phoenix order book markets so their resting liquidity competes
+// with AMM pools
+type PhoenixProtocol struct {
+	SolClient *sol.Client
+}
+
+// NewPhoenix creates a new Phoenix protocol instance
+func NewPhoenix(solClient *sol.Client) *PhoenixProtocol {
+	return &PhoenixProtocol{
+		SolClient: solClient,
+	}
+}
+
+// Name returns the protocol name of the pools it discovers
+func (p *PhoenixProtocol) Name() pkg.ProtocolName {
+	return pkg.ProtocolNamePhoenix
+}
+
+// FetchPoolsByPair finds markets trading the pair with either mint as base. Markets that
+// don't accept taker orders are skipped.
+func (p *PhoenixProtocol) FetchPoolsByPair(ctx context.Context, baseMint string, quoteMint string) ([]pkg.Pool, error) {
+	accounts := make([]*rpc.KeyedAccount, 0)
+	for _, pair := range [][2]string{{baseMint, quoteMint}, {quoteMint, baseMint}} {
+		programAccounts, err := p.getMarketAccountsByTokenPair(ctx, pair[0], pair[1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch markets with base token %s: %w", pair[0], err)
+		}
+		accounts = append(accounts, programAccounts...)
+	}
+
+	res := make([]pkg.Pool, 0)
+	for _, v := range accounts {
+		market, err := phoenix.NewMarketPool(v.Pubkey, v.Account.Data.GetBinary())
+		if err != nil || market.Status != phoenix.MarketStatusActive {
+			continue
+		}
+		res = append(res, market)
+	}
+	return res, nil
+}
+
+// getMarketAccountsByTokenPair matches the mints in the market header. Market accounts vary
+// in size with the book capacity, so there is no size filter.
+func (p *PhoenixProtocol) getMarketAccountsByTokenPair(ctx context.Context, baseMint string, quoteMint string) (rpc.GetProgramAccountsResult, error) {
+	baseKey, err := solana.PublicKeyFromBase58(baseMint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base mint address: %w", err)
+	}
+	quoteKey, err := solana.PublicKeyFromBase58(quoteMint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quote mint address: %w", err)
+	}
+	result, err := p.SolClient.RpcClient.GetProgramAccountsWithOpts(ctx, phoenix.PHOENIX_PROGRAM_ID, &rpc.GetProgramAccountsOpts{
+		Filters: []rpc.RPCFilter{
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: phoenix.BaseMintOffset, Bytes: baseKey.Bytes()}},
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: phoenix.QuoteMintOffset, Bytes: quoteKey.Bytes()}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get markets: %w", err)
+	}
+	return result, nil
+}
+
+// FetchPoolByID gets a single market by its address
+func (p *PhoenixProtocol) FetchPoolByID(ctx context.Context, poolId string) (pkg.Pool, error) {
+	poolIdKey, err := solana.PublicKeyFromBase58(poolId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pool id: %w", err)
+	}
+	account, err := p.SolClient.RpcClient.GetAccountInfo(ctx, poolIdKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get market account %s: %w", poolId, err)
+	}
+	if !account.Value.Owner.Equals(phoenix.PHOENIX_PROGRAM_ID) {
+		return nil, fmt.Errorf("market %s is owned by %s, not the phoenix program", poolId, account.Value.Owner)
+	}
+	market, err := phoenix.NewMarketPool(poolIdKey, account.Value.Data.GetBinary())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode market data for %s: %w", poolId, err)
+	}
+	return market, nil
+}
@@ -0,0 +1,87 @@
+package protocol
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// PluginProtocol routes through a venue implemented outside this module
+type PluginProtocol struct {
+	SolClient *sol.Client
+	venue     pkg.Venue
+}
+
+// NewPluginProtocol creates a Protocol that discovers and decodes pools with venue
+func NewPluginProtocol(solClient *sol.Client, venue pkg.Venue) *PluginProtocol {
+	return &PluginProtocol{
+		SolClient: solClient,
+		venue:     venue,
+	}
+}
+
+// Name returns the venue's name
+func (p *PluginProtocol) Name() pkg.ProtocolName {
+	return p.venue.Name()
+}
+
+// FetchPoolsByPair runs one getProgramAccounts scan per filter set of the venue and decodes
+// the results; accounts the venue can't decode are skipped
+func (p *PluginProtocol) FetchPoolsByPair(ctx context.Context, baseMint string, quoteMint string) ([]pkg.Pool, error) {
+	baseKey, err := solana.PublicKeyFromBase58(baseMint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base mint address: %w", err)
+	}
+	quoteKey, err := solana.PublicKeyFromBase58(quoteMint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quote mint address: %w", err)
+	}
+
+	pools := make([]pkg.Pool, 0)
+	seen := make(map[solana.PublicKey]bool)
+	for _, filters := range p.venue.PairFilters(baseKey, quoteKey) {
+		result, err := p.SolClient.RpcClient.GetProgramAccountsWithOpts(ctx, p.venue.ProgramID(), &rpc.GetProgramAccountsOpts{
+			Filters: filters,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s pools: %w", p.venue.Name(), err)
+		}
+		for _, account := range result {
+			if seen[account.Pubkey] {
+				continue
+			}
+			pool, err := p.venue.DecodePool(account.Pubkey, account.Account.Data.GetBinary())
+			if err != nil {
+				continue
+			}
+			seen[account.Pubkey] = true
+			pools = append(pools, pool)
+		}
+	}
+	return pools, nil
+}
+
+// FetchPoolByID retrieves and decodes a single pool of the venue
+func (p *PluginProtocol) FetchPoolByID(ctx context.Context, poolID string) (pkg.Pool, error) {
+	poolKey, err := solana.PublicKeyFromBase58(poolID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pool id: %w", err)
+	}
+	account, err := p.SolClient.RpcClient.GetAccountInfo(ctx, poolKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool account %s: %w", poolID, err)
+	}
+	if !account.Value.Owner.Equals(p.venue.ProgramID()) {
+		return nil, fmt.Errorf("pool %s is owned by %s, not the %s program", poolID, account.Value.Owner, p.venue.Name())
+	}
+
+	pool, err := p.venue.DecodePool(poolKey, account.Value.Data.GetBinary())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode pool data for %s: %w", poolID, err)
+	}
+	return pool, nil
+}
@@ -13,14 +13,34 @@ import (
 
 type PumpAmmProtocol struct {
 	SolClient *sol.Client
+	// Configs caches the global config shared by the discovered pools
+	Configs *pump.GlobalConfigCache
 }
 
 func NewPumpAmm(solClient *sol.Client) *PumpAmmProtocol {
 	return &PumpAmmProtocol{
 		SolClient: solClient,
+		Configs:   pump.NewGlobalConfigCache(),
 	}
 }
 
+// configs returns the protocol's global config cache with the config read, so quotes use its
+// fee rates. It creates the cache for protocols built without NewPumpAmm.
+func (p *PumpAmmProtocol) configs(ctx context.Context) (*pump.GlobalConfigCache, error) {
+	if p.Configs == nil {
+		p.Configs = pump.NewGlobalConfigCache()
+	}
+	if _, err := p.Configs.Get(ctx, p.SolClient.RpcClient); err != nil {
+		return nil, err
+	}
+	return p.Configs, nil
+}
+
+// Name returns the protocol name of the pools it discovers
+func (p *PumpAmmProtocol) Name() pkg.ProtocolName {
+	return pkg.ProtocolNamePumpAmm
+}
+
 func (p *PumpAmmProtocol) FetchPoolsByPair(ctx context.Context, baseMint string, quoteMint string) ([]pkg.Pool, error) {
 	programAccounts := rpc.GetProgramAccountsResult{}
 	data, err := p.getPumpAMMPoolAccountsByTokenPair(ctx, baseMint, quoteMint)
@@ -34,6 +54,10 @@ func (p *PumpAmmProtocol) FetchPoolsByPair(ctx context.Context, baseMint string,
 	}
 	programAccounts = append(programAccounts, data...)
 
+	configs, err := p.configs(ctx)
+	if err != nil {
+		return nil, err
+	}
 	res := make([]pkg.Pool, 0)
 	for _, v := range programAccounts {
 		layout, err := pump.ParsePoolData(v.Account.Data.GetBinary())
@@ -41,6 +65,7 @@ func (p *PumpAmmProtocol) FetchPoolsByPair(ctx context.Context, baseMint string,
 			continue
 		}
 		layout.PoolId = v.Pubkey
+		layout.Configs = configs
 		res = append(res, layout)
 	}
 	return res, nil
@@ -94,5 +119,8 @@ func (p *PumpAmmProtocol) FetchPoolByID(ctx context.Context, poolId string) (pkg
 		return nil, fmt.Errorf("failed to parse pool data for pool %s: %w", poolId, err)
 	}
 	layout.PoolId = poolPubkey
+	if layout.Configs, err = p.configs(ctx); err != nil {
+		return nil, err
+	}
 	return layout, nil
 }
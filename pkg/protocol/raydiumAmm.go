@@ -1,10 +1,7 @@
 package protocol
 
 import (
-	"bytes"
 	"context"
-	"encoding/binary"
-	"errors"
 	"fmt"
 
 	"github.com/gtdvccc/SolRouteTmp/pkg"
@@ -16,14 +13,22 @@ import (
 
 type RaydiumAMMProtocol struct {
 	SolClient *sol.Client
+	// Markets caches the OpenBook market accounts of discovered pools
+	Markets *raydium.MarketResolver
 }
 
 func NewRaydiumAmm(solClient *sol.Client) *RaydiumAMMProtocol {
 	return &RaydiumAMMProtocol{
 		SolClient: solClient,
+		Markets:   raydium.NewMarketResolver(),
 	}
 }
 
+// Name returns the protocol name of the pools it discovers
+func (p *RaydiumAMMProtocol) Name() pkg.ProtocolName {
+	return pkg.ProtocolNameRaydiumAmm
+}
+
 func (p *RaydiumAMMProtocol) FetchPoolsByPair(ctx context.Context, baseMint, quoteMint string) ([]pkg.Pool, error) {
 	accounts := make([]*rpc.KeyedAccount, 0)
 	programAccounts, err := p.getAMMPoolAccountsByTokenPair(ctx, baseMint, quoteMint)
@@ -37,21 +42,34 @@ func (p *RaydiumAMMProtocol) FetchPoolsByPair(ctx context.Context, baseMint, quo
 	}
 	accounts = append(accounts, programAccounts...)
 
-	res := make([]pkg.Pool, 0)
+	pools := make([]*raydium.AMMPool, 0, len(accounts))
 	for _, v := range accounts {
 		layout := &raydium.AMMPool{}
 		if err := layout.Decode(v.Account.Data.GetBinary()); err != nil {
 			continue
 		}
 		layout.PoolId = v.Pubkey
-		if err := p.processAMMPool(ctx, layout); err != nil {
-			return nil, fmt.Errorf("failed to process AMM pool %s: %w", v.Pubkey.String(), err)
-		}
-		res = append(res, layout)
+		pools = append(pools, layout)
+	}
+	if err := p.markets().Resolve(ctx, p.SolClient.RpcClient, pools...); err != nil {
+		return nil, fmt.Errorf("failed to resolve AMM pool markets: %w", err)
+	}
+	res := make([]pkg.Pool, len(pools))
+	for i, pool := range pools {
+		res[i] = pool
 	}
 	return res, nil
 }
 
+// markets returns the protocol's market cache, creating it for protocols built without
+// NewRaydiumAmm
+func (p *RaydiumAMMProtocol) markets() *raydium.MarketResolver {
+	if p.Markets == nil {
+		p.Markets = raydium.NewMarketResolver()
+	}
+	return p.Markets
+}
+
 func (p *RaydiumAMMProtocol) getAMMPoolAccountsByTokenPair(ctx context.Context, baseMint string, quoteMint string) (rpc.GetProgramAccountsResult, error) {
 	var layout raydium.AMMPool
 	baseMintPubkey, err := solana.PublicKeyFromBase58(baseMint)
@@ -101,60 +119,8 @@ func (r *RaydiumAMMProtocol) FetchPoolByID(ctx context.Context, poolID string) (
 		return nil, fmt.Errorf("failed to decode pool data for %s: %w", poolID, err)
 	}
 	layout.PoolId = poolPubkey
-	if err := r.processAMMPool(ctx, layout); err != nil {
-		return nil, fmt.Errorf("failed to process AMM pool %s: %w", poolID, err)
+	if err := r.markets().Resolve(ctx, r.SolClient.RpcClient, layout); err != nil {
+		return nil, fmt.Errorf("failed to resolve market of AMM pool %s: %w", poolID, err)
 	}
 	return layout, nil
 }
-
-func getAssociatedAuthority(programID solana.PublicKey, marketID solana.PublicKey) (solana.PublicKey, uint8, error) {
-	seeds := [][]byte{marketID.Bytes()}
-	var nonce uint8 = 0
-
-	for nonce < 100 {
-		seedsWithNonce := append(seeds, int8ToBuf(nonce))
-		seedsWithNonce = append(seedsWithNonce, make([]byte, 7))
-
-		publicKey, err := solana.CreateProgramAddress(seedsWithNonce, programID)
-		if err != nil {
-			nonce++
-			continue
-		}
-
-		return publicKey, nonce, nil
-	}
-
-	return solana.PublicKey{}, 0, errors.New("unable to find a viable program address nonce")
-}
-
-func int8ToBuf(value uint8) []byte {
-	buf := new(bytes.Buffer)
-	binary.Write(buf, binary.LittleEndian, value)
-	return buf.Bytes()
-}
-
-func (p *RaydiumAMMProtocol) processAMMPool(ctx context.Context, layout *raydium.AMMPool) error {
-	marketAccount, err := p.SolClient.RpcClient.GetAccountInfo(ctx, layout.MarketId)
-	if err != nil {
-		return fmt.Errorf("failed to get market account: %w", err)
-	}
-
-	var marketLayout raydium.MarketStateLayoutV3
-	if err := marketLayout.Decode(marketAccount.Value.Data.GetBinary()); err != nil {
-		return fmt.Errorf("failed to decode market layout: %w", err)
-	}
-
-	authority, _, err := solana.FindProgramAddress([][]byte{{97, 109, 109, 32, 97, 117, 116, 104, 111, 114, 105, 116, 121}}, raydium.RAYDIUM_AMM_PROGRAM_ID)
-	if err != nil {
-		return fmt.Errorf("failed to find program address: %w", err)
-	}
-
-	marketAuthority, _, err := getAssociatedAuthority(marketAccount.Value.Owner, marketLayout.OwnAddress)
-	if err != nil {
-		return fmt.Errorf("failed to get associated authority: %w", err)
-	}
-
-	layout.Authority = authority
-	layout.MarketAuthority = marketAuthority
-	return nil
-}
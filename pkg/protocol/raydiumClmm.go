@@ -14,15 +14,50 @@ import (
 
 type RaydiumClmmProtocol struct {
 	SolClient *sol.Client
+
+	programID solana.PublicKey
+	// clusterErr is set when the program isn't deployed on the cluster
+	clusterErr error
 }
 
+// NewRaydiumClmm creates a Raydium CLMM protocol instance for the program on solClient's
+// cluster
 func NewRaydiumClmm(solClient *sol.Client) *RaydiumClmmProtocol {
-	return &RaydiumClmmProtocol{
+	p := &RaydiumClmmProtocol{
 		SolClient: solClient,
 	}
+	p.clusterErr = p.SetCluster(sol.ClusterOf(solClient))
+	return p
+}
+
+// SetCluster points discovery and the pools found at the program deployed on cluster,
+// overriding the cluster of the client
+func (p *RaydiumClmmProtocol) SetCluster(cluster sol.Cluster) error {
+	programID, err := raydium.ClmmProgramIDForCluster(cluster)
+	if err != nil {
+		return err
+	}
+	p.programID = programID
+	p.clusterErr = nil
+	return nil
+}
+
+// Name returns the protocol name of the pools it discovers
+func (p *RaydiumClmmProtocol) Name() pkg.ProtocolName {
+	return pkg.ProtocolNameRaydiumClmm
+}
+
+// SetProgramID points discovery and the pools found at a CLMM program deployed at programID,
+// e.g. on a cluster Raydium isn't on
+func (p *RaydiumClmmProtocol) SetProgramID(programID solana.PublicKey) {
+	p.programID = programID
+	p.clusterErr = nil
 }
 
 func (p *RaydiumClmmProtocol) FetchPoolsByPair(ctx context.Context, baseMint string, quoteMint string) ([]pkg.Pool, error) {
+	if p.clusterErr != nil {
+		return nil, p.clusterErr
+	}
 	accounts := make([]*rpc.KeyedAccount, 0)
 	programAccounts, err := p.getCLMMPoolAccountsByTokenPair(ctx, baseMint, quoteMint)
 	if err != nil {
@@ -43,6 +78,8 @@ func (p *RaydiumClmmProtocol) FetchPoolsByPair(ctx context.Context, baseMint str
 			continue
 		}
 		layout.PoolId = v.Pubkey
+		layout.ProgramID = p.getProgramID()
+		layout.Logger = p.SolClient.Logger
 
 		ammConfigData, err := p.SolClient.RpcClient.GetAccountInfo(ctx, layout.AmmConfig)
 		if err != nil {
@@ -54,7 +91,7 @@ func (p *RaydiumClmmProtocol) FetchPoolsByPair(ctx context.Context, baseMint str
 		}
 		layout.FeeRate = feeRate
 
-		exBitmapAddress, _, err := raydium.GetPdaExBitmapAccount(raydium.RAYDIUM_CLMM_PROGRAM_ID, layout.PoolId)
+		exBitmapAddress, _, err := raydium.GetPdaExBitmapAccount(p.getProgramID(), layout.PoolId)
 		if err != nil {
 			continue
 		}
@@ -82,7 +119,7 @@ func (p *RaydiumClmmProtocol) getCLMMPoolAccountsByTokenPair(ctx context.Context
 	}
 
 	var knownPoolLayout raydium.CLMMPool
-	result, err := p.SolClient.RpcClient.GetProgramAccountsWithOpts(ctx, raydium.RAYDIUM_CLMM_PROGRAM_ID, &rpc.GetProgramAccountsOpts{
+	result, err := p.SolClient.RpcClient.GetProgramAccountsWithOpts(ctx, p.getProgramID(), &rpc.GetProgramAccountsOpts{
 		Filters: []rpc.RPCFilter{
 			{
 				DataSize: uint64(knownPoolLayout.Span()),
@@ -109,6 +146,9 @@ func (p *RaydiumClmmProtocol) getCLMMPoolAccountsByTokenPair(ctx context.Context
 }
 
 func (r *RaydiumClmmProtocol) FetchPoolByID(ctx context.Context, poolId string) (pkg.Pool, error) {
+	if r.clusterErr != nil {
+		return nil, r.clusterErr
+	}
 	poolIdKey, err := solana.PublicKeyFromBase58(poolId)
 	if err != nil {
 		return nil, fmt.Errorf("invalid pool id: %w", err)
@@ -124,6 +164,19 @@ func (r *RaydiumClmmProtocol) FetchPoolByID(ctx context.Context, poolId string)
 		return nil, fmt.Errorf("failed to decode pool data for %s: %w", poolId, err)
 	}
 	layout.PoolId = poolIdKey
+	layout.ProgramID = r.getProgramID()
+	layout.Logger = r.SolClient.Logger
+
+	ammConfigData, err := r.SolClient.RpcClient.GetAccountInfo(ctx, layout.AmmConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get amm config %s: %w", layout.AmmConfig, err)
+	}
+	if layout.FeeRate, err = parseAmmConfig(ammConfigData.Value.Data.GetBinary()); err != nil {
+		return nil, err
+	}
+	if layout.ExBitmapAddress, _, err = raydium.GetPdaExBitmapAccount(r.getProgramID(), poolIdKey); err != nil {
+		return nil, fmt.Errorf("failed to derive bitmap extension of %s: %w", poolId, err)
+	}
 
 	// Check if pool has Swap functionality enabled
 	if !layout.IsSwapEnabled() {
@@ -133,6 +186,15 @@ func (r *RaydiumClmmProtocol) FetchPoolByID(ctx context.Context, poolId string)
 	return layout, nil
 }
 
+// getProgramID returns the configured program, falling back to the package default for
+// protocols not built with NewRaydiumClmm
+func (p *RaydiumClmmProtocol) getProgramID() solana.PublicKey {
+	if p.programID.IsZero() {
+		return raydium.ClmmProgramID()
+	}
+	return p.programID
+}
+
 func parseAmmConfig(data []byte) (uint32, error) {
 	var ammConfig AmmConfig
 	if err := ammConfig.Decode(data); err != nil {
@@ -3,10 +3,12 @@ package protocol
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	"github.com/gtdvccc/SolRouteTmp/pkg"
 	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
 	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	bin "github.com/gagliardetto/binary"
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 )
@@ -23,6 +25,11 @@ func NewRaydiumCpmm(solClient *sol.Client) *RaydiumCpmmProtocol {
 	}
 }
 
+// Name returns the protocol name of the pools it discovers
+func (p *RaydiumCpmmProtocol) Name() pkg.ProtocolName {
+	return pkg.ProtocolNameRaydiumCpmm
+}
+
 // FetchPoolsByPair retrieves all pools for a given token pair
 func (p *RaydiumCpmmProtocol) FetchPoolsByPair(ctx context.Context, baseMint string, quoteMint string) ([]pkg.Pool, error) {
 	// Fetch pools with baseMint as token0
@@ -31,7 +38,7 @@ func (p *RaydiumCpmmProtocol) FetchPoolsByPair(ctx context.Context, baseMint str
 		return nil, fmt.Errorf("failed to fetch pools with base token %s: %w", baseMint, err)
 	}
 
-	pools := make([]pkg.Pool, 0)
+	decoded := make([]*raydium.CPMMPool, 0)
 	for _, account := range programAccounts {
 		data := account.Account.Data.GetBinary()
 		pool := &raydium.CPMMPool{}
@@ -39,7 +46,7 @@ func (p *RaydiumCpmmProtocol) FetchPoolsByPair(ctx context.Context, baseMint str
 			continue
 		}
 		pool.PoolId = account.Pubkey
-		pools = append(pools, pool)
+		decoded = append(decoded, pool)
 	}
 
 	// Fetch pools with quoteMint as token0
@@ -55,12 +62,54 @@ func (p *RaydiumCpmmProtocol) FetchPoolsByPair(ctx context.Context, baseMint str
 			continue
 		}
 		pool.PoolId = account.Pubkey
-		pools = append(pools, pool)
+		decoded = append(decoded, pool)
 	}
 
+	// Pools whose config can't be read are left out rather than quoted without their fee
+	feeRates, err := p.tradeFeeRates(ctx, decoded)
+	if err != nil {
+		return nil, err
+	}
+	pools := make([]pkg.Pool, 0, len(decoded))
+	for _, pool := range decoded {
+		feeRate, ok := feeRates[pool.AmmConfig]
+		if !ok {
+			continue
+		}
+		pool.TradeFeeRate = feeRate
+		pools = append(pools, pool)
+	}
 	return pools, nil
 }
 
+// tradeFeeRates reads the trade fee rate of every config the pools use in one request.
+// Configs that are missing or don't decode are left out.
+func (p *RaydiumCpmmProtocol) tradeFeeRates(ctx context.Context, pools []*raydium.CPMMPool) (map[solana.PublicKey]uint64, error) {
+	configs := make([]solana.PublicKey, 0)
+	for _, pool := range pools {
+		if !slices.Contains(configs, pool.AmmConfig) {
+			configs = append(configs, pool.AmmConfig)
+		}
+	}
+	feeRates := make(map[solana.PublicKey]uint64, len(configs))
+	if len(configs) == 0 {
+		return feeRates, nil
+	}
+	results, err := p.SolClient.RpcClient.GetMultipleAccountsWithOpts(ctx, configs, sol.MultipleAccountsOpts(ctx, rpc.CommitmentProcessed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get amm configs: %w", err)
+	}
+	for i, account := range results.Value {
+		if account == nil || i >= len(configs) {
+			continue
+		}
+		if feeRate, err := parseCpmmAmmConfig(account.Data.GetBinary()); err == nil {
+			feeRates[configs[i]] = feeRate
+		}
+	}
+	return feeRates, nil
+}
+
 // getCPMMPoolAccountsByTokenPair retrieves CPMM pool accounts for a given token pair
 func (p *RaydiumCpmmProtocol) getCPMMPoolAccountsByTokenPair(ctx context.Context, baseMint string, quoteMint string) (rpc.GetProgramAccountsResult, error) {
 	baseKey, err := solana.PublicKeyFromBase58(baseMint)
@@ -115,5 +164,47 @@ func (p *RaydiumCpmmProtocol) FetchPoolByID(ctx context.Context, poolID string)
 	}
 	pool.PoolId = solana.MustPublicKeyFromBase58(poolID)
 
+	config, err := p.SolClient.RpcClient.GetAccountInfo(ctx, pool.AmmConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get amm config %s: %w", pool.AmmConfig, err)
+	}
+	if pool.TradeFeeRate, err = parseCpmmAmmConfig(config.Value.Data.GetBinary()); err != nil {
+		return nil, err
+	}
 	return pool, nil
 }
+
+func parseCpmmAmmConfig(data []byte) (uint64, error) {
+	var ammConfig CpmmAmmConfig
+	if err := ammConfig.Decode(data); err != nil {
+		return 0, fmt.Errorf("failed to decode amm config: %w", err)
+	}
+	if ammConfig.TradeFeeRate >= raydium.FEE_RATE_DENOMINATOR.Uint64() {
+		return 0, fmt.Errorf("invalid trade fee rate %d", ammConfig.TradeFeeRate)
+	}
+	return ammConfig.TradeFeeRate, nil
+}
+
+// CpmmAmmConfig is the fee configuration shared by CPMM pools; rates are in millionths
+type CpmmAmmConfig struct {
+	Bump              uint8
+	DisableCreatePool bool
+	Index             uint16
+	TradeFeeRate      uint64
+	ProtocolFeeRate   uint64
+	FundFeeRate       uint64
+	CreatePoolFee     uint64
+	ProtocolOwner     solana.PublicKey
+	FundOwner         solana.PublicKey
+	Padding           [16]uint64
+}
+
+func (l *CpmmAmmConfig) Decode(data []byte) error {
+	// Skip 8 bytes discriminator if present
+	if len(data) > 8 {
+		data = data[8:]
+	}
+
+	dec := bin.NewBinDecoder(data)
+	return dec.Decode(l)
+}
@@ -0,0 +1,99 @@
+package protocol
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// RaydiumLaunchLabProtocol handles Raydium LaunchLab bonding curves
+type RaydiumLaunchLabProtocol struct {
+	SolClient *sol.Client
+}
+
+// NewRaydiumLaunchLab creates a new RaydiumLaunchLabProtocol instance
+func NewRaydiumLaunchLab(solClient *sol.Client) *RaydiumLaunchLabProtocol {
+	return &RaydiumLaunchLabProtocol{
+		SolClient: solClient,
+	}
+}
+
+// Name returns the protocol name of the pools it discovers
+func (p *RaydiumLaunchLabProtocol) Name() pkg.ProtocolName {
+	return pkg.ProtocolNameRaydiumLaunchLab
+}
+
+// FetchPoolsByPair retrieves the curves still trading a token pair; either mint may be the
+// launched token
+func (p *RaydiumLaunchLabProtocol) FetchPoolsByPair(ctx context.Context, baseMint string, quoteMint string) ([]pkg.Pool, error) {
+	programAccounts := rpc.GetProgramAccountsResult{}
+	for _, pair := range [][2]string{{baseMint, quoteMint}, {quoteMint, baseMint}} {
+		accounts, err := p.getPoolAccountsByTokenPair(ctx, pair[0], pair[1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch pools launching %s: %w", pair[0], err)
+		}
+		programAccounts = append(programAccounts, accounts...)
+	}
+
+	pools := make([]pkg.Pool, 0, len(programAccounts))
+	for _, account := range programAccounts {
+		pool := &raydium.LaunchLabPool{}
+		if err := pool.Decode(account.Account.Data.GetBinary()); err != nil || pool.Status != raydium.LaunchLabStatusTrading {
+			// Skip graduated and undecodable curves
+			continue
+		}
+		pool.PoolId = account.Pubkey
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
+
+func (p *RaydiumLaunchLabProtocol) getPoolAccountsByTokenPair(ctx context.Context, mintA string, mintB string) (rpc.GetProgramAccountsResult, error) {
+	mintAKey, err := solana.PublicKeyFromBase58(mintA)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base mint address: %w", err)
+	}
+	mintBKey, err := solana.PublicKeyFromBase58(mintB)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quote mint address: %w", err)
+	}
+	var layout raydium.LaunchLabPool
+	result, err := p.SolClient.RpcClient.GetProgramAccountsWithOpts(ctx, raydium.RAYDIUM_LAUNCHLAB_PROGRAM_ID, &rpc.GetProgramAccountsOpts{
+		Filters: []rpc.RPCFilter{
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: 0, Bytes: raydium.LaunchLabPoolDiscriminator}},
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: layout.Offset("MintA"), Bytes: mintAKey.Bytes()}},
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: layout.Offset("MintB"), Bytes: mintBKey.Bytes()}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pools: %w", err)
+	}
+	return result, nil
+}
+
+// FetchPoolByID retrieves a LaunchLab pool by its ID
+func (p *RaydiumLaunchLabProtocol) FetchPoolByID(ctx context.Context, poolID string) (pkg.Pool, error) {
+	poolKey, err := solana.PublicKeyFromBase58(poolID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pool id: %w", err)
+	}
+	account, err := p.SolClient.RpcClient.GetAccountInfo(ctx, poolKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool account %s: %w", poolID, err)
+	}
+	if !account.Value.Owner.Equals(raydium.RAYDIUM_LAUNCHLAB_PROGRAM_ID) {
+		return nil, fmt.Errorf("pool %s is owned by %s, not the launchlab program", poolID, account.Value.Owner)
+	}
+
+	pool := &raydium.LaunchLabPool{}
+	if err := pool.Decode(account.Value.Data.GetBinary()); err != nil {
+		return nil, fmt.Errorf("failed to decode pool data for %s: %w", poolID, err)
+	}
+	pool.PoolId = poolKey
+	return pool, nil
+}
@@ -0,0 +1,95 @@
+package protocol
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/saber"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// SaberProtocol handles Saber StableSwap pools
+type SaberProtocol struct {
+	SolClient *sol.Client
+}
+
+// NewSaber creates a new SaberProtocol instance
+func NewSaber(solClient *sol.Client) *SaberProtocol {
+	return &SaberProtocol{
+		SolClient: solClient,
+	}
+}
+
+// Name returns the protocol name of the pools it discovers
+func (protocol *SaberProtocol) Name() pkg.ProtocolName {
+	return pkg.ProtocolNameSaber
+}
+
+// FetchPoolsByPair retrieves the initialized, unpaused Saber pools for a token pair
+func (protocol *SaberProtocol) FetchPoolsByPair(ctx context.Context, baseMint string, quoteMint string) ([]pkg.Pool, error) {
+	programAccounts := rpc.GetProgramAccountsResult{}
+	for _, pair := range [][2]string{{baseMint, quoteMint}, {quoteMint, baseMint}} {
+		accounts, err := protocol.getPoolAccountsByTokenPair(ctx, pair[0], pair[1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch pools with %s as token A: %w", pair[0], err)
+		}
+		programAccounts = append(programAccounts, accounts...)
+	}
+
+	pools := make([]pkg.Pool, 0, len(programAccounts))
+	for _, account := range programAccounts {
+		pool, err := saber.NewPool(account.Pubkey, account.Account.Data.GetBinary())
+		if err != nil || !pool.IsInitialized || pool.IsPaused {
+			// Skip paused and undecodable pools
+			continue
+		}
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
+
+func (protocol *SaberProtocol) getPoolAccountsByTokenPair(ctx context.Context, tokenAMint string, tokenBMint string) (rpc.GetProgramAccountsResult, error) {
+	tokenAKey, err := solana.PublicKeyFromBase58(tokenAMint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token A mint address: %w", err)
+	}
+	tokenBKey, err := solana.PublicKeyFromBase58(tokenBMint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token B mint address: %w", err)
+	}
+	result, err := protocol.SolClient.RpcClient.GetProgramAccountsWithOpts(ctx, saber.ProgramID, &rpc.GetProgramAccountsOpts{
+		Filters: []rpc.RPCFilter{
+			{DataSize: saber.SwapInfoSize},
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: saber.TokenAMintOffset, Bytes: tokenAKey.Bytes()}},
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: saber.TokenBMintOffset, Bytes: tokenBKey.Bytes()}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get program accounts: %w", err)
+	}
+	return result, nil
+}
+
+// FetchPoolByID retrieves a specific Saber pool by its ID
+func (protocol *SaberProtocol) FetchPoolByID(ctx context.Context, poolID string) (pkg.Pool, error) {
+	poolKey, err := solana.PublicKeyFromBase58(poolID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pool id: %w", err)
+	}
+	account, err := protocol.SolClient.RpcClient.GetAccountInfo(ctx, poolKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool account: %w", err)
+	}
+	if !account.Value.Owner.Equals(saber.ProgramID) {
+		return nil, fmt.Errorf("pool %s is owned by %s, not the saber program", poolID, account.Value.Owner)
+	}
+
+	pool, err := saber.NewPool(poolKey, account.Value.Data.GetBinary())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode pool data: %w", err)
+	}
+	return pool, nil
+}
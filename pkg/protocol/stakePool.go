@@ -0,0 +1,90 @@
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/stakepool"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// StakePoolProtocol handles SOL deposits and withdrawals of SPL stake pools, including
+// Sanctum's stake pool programs
+type StakePoolProtocol struct {
+	SolClient *sol.Client
+}
+
+// NewStakePool creates a new StakePoolProtocol instance
+func NewStakePool(solClient *sol.Client) *StakePoolProtocol {
+	return &StakePoolProtocol{
+		SolClient: solClient,
+	}
+}
+
+// Name returns the protocol name of the pools it discovers
+func (protocol *StakePoolProtocol) Name() pkg.ProtocolName {
+	return pkg.ProtocolNameStakePool
+}
+
+// FetchPoolsByPair returns the stake pools whose pool token is paired with WSOL
+func (protocol *StakePoolProtocol) FetchPoolsByPair(ctx context.Context, baseMint string, quoteMint string) ([]pkg.Pool, error) {
+	lstMint := baseMint
+	switch solana.WrappedSol.String() {
+	case baseMint:
+		lstMint = quoteMint
+	case quoteMint:
+	default:
+		return nil, nil
+	}
+	mint, err := solana.PublicKeyFromBase58(lstMint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pool token mint address: %w", err)
+	}
+
+	var pools []pkg.Pool
+	for _, programID := range stakepool.ProgramIDs {
+		accounts, err := protocol.SolClient.RpcClient.GetProgramAccountsWithOpts(ctx, programID, &rpc.GetProgramAccountsOpts{
+			Filters: []rpc.RPCFilter{
+				{Memcmp: &rpc.RPCFilterMemcmp{Offset: stakepool.AccountTypeOffset, Bytes: []byte{stakepool.AccountTypeStakePool}}},
+				{Memcmp: &rpc.RPCFilterMemcmp{Offset: stakepool.PoolMintOffset, Bytes: mint.Bytes()}},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stake pools of %s: %w", programID, err)
+		}
+		for _, account := range accounts {
+			pool, err := stakepool.NewPool(account.Pubkey, programID, account.Account.Data.GetBinary())
+			if err != nil {
+				// Skip undecodable pools
+				continue
+			}
+			pools = append(pools, pool)
+		}
+	}
+	return pools, nil
+}
+
+// FetchPoolByID retrieves a stake pool by its account address
+func (protocol *StakePoolProtocol) FetchPoolByID(ctx context.Context, poolID string) (pkg.Pool, error) {
+	poolKey, err := solana.PublicKeyFromBase58(poolID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pool id: %w", err)
+	}
+	account, err := protocol.SolClient.RpcClient.GetAccountInfo(ctx, poolKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool account: %w", err)
+	}
+	owner := account.Value.Owner
+	if !slices.Contains(stakepool.ProgramIDs, owner) {
+		return nil, fmt.Errorf("pool %s is owned by %s, not a stake pool program", poolID, owner)
+	}
+	pool, err := stakepool.NewPool(poolKey, owner, account.Value.Data.GetBinary())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode pool data: %w", err)
+	}
+	return pool, nil
+}
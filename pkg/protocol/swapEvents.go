@@ -0,0 +1,115 @@
+package protocol
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/meteora"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/meteora/dammv2"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/orca"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/pump"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// SwapEventDecoders maps the programs whose swap events can be decoded to their decoder.
+// Callers may register decoders for further programs.
+var SwapEventDecoders = map[solana.PublicKey]pkg.SwapEventDecoder{
+	raydium.RAYDIUM_AMM_PROGRAM_ID: raydium.DecodeAMMSwapEvent,
+	raydium.ClmmProgramID():        raydium.DecodeCLMMSwapEvent,
+	// Devnet's CLMM program emits the same events under its own address
+	raydium.ClmmDevnetProgramID():   raydium.DecodeCLMMSwapEvent,
+	raydium.RAYDIUM_CPMM_PROGRAM_ID: raydium.DecodeCPMMSwapEvent,
+	orca.WhirlpoolProgramID():       orca.DecodeWhirlpoolSwapEvent,
+	meteora.MeteoraProgramID:        meteora.DecodeDLMMSwapEvent,
+	dammv2.ProgramID:                dammv2.DecodeSwapEvent,
+	pump.PumpSwapProgramID:          pump.DecodeSwapEvent,
+}
+
+// SignatureLister is implemented by clients that can list the transactions touching an
+// account, like *rpc.Client. BackfillSwapEvents reads through it.
+type SignatureLister interface {
+	GetSignaturesForAddressWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error)
+}
+
+// DecodeSwapEvents returns the swaps of a confirmed transaction, in execution order, stamped
+// with its signature and slot. Failed transactions have none.
+func DecodeSwapEvents(signature solana.Signature, slot uint64, tx *solana.Transaction, meta *rpc.TransactionMeta) ([]pkg.SwapEvent, error) {
+	if meta == nil || meta.Err != nil {
+		return nil, nil
+	}
+	var swaps []pkg.SwapEvent
+	for _, event := range sol.ProgramEvents(tx, meta) {
+		decode, ok := SwapEventDecoders[event.Program]
+		if !ok {
+			continue
+		}
+		swap, ok, err := decode(event)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s event of %s: %w", event.Program, signature, err)
+		}
+		if ok {
+			swap.Signature, swap.Slot = signature, slot
+			swaps = append(swaps, swap)
+		}
+	}
+	return swaps, nil
+}
+
+// BackfillSwapEvents returns the swaps through pool in up to limit of its latest
+// transactions before the before signature, or the very latest when it is zero, newest
+// first. The client's RPC must implement SignatureLister and sol.TransactionReader.
+func BackfillSwapEvents(ctx context.Context, solClient *sol.Client, pool solana.PublicKey, before solana.Signature, limit int) ([]pkg.SwapEvent, error) {
+	lister, ok := solClient.RpcClient.(SignatureLister)
+	if !ok {
+		return nil, fmt.Errorf("rpc client %T can't list signatures", solClient.RpcClient)
+	}
+	reader, ok := solClient.RpcClient.(sol.TransactionReader)
+	if !ok {
+		return nil, fmt.Errorf("rpc client %T can't fetch transactions", solClient.RpcClient)
+	}
+	signatures, err := lister.GetSignaturesForAddressWithOpts(ctx, pool, &rpc.GetSignaturesForAddressOpts{
+		Limit:      &limit,
+		Before:     before,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list signatures of %s: %w", pool, err)
+	}
+
+	maxVersion := uint64(0)
+	var swaps []pkg.SwapEvent
+	for _, signature := range signatures {
+		if signature.Err != nil {
+			continue
+		}
+		result, err := reader.GetTransaction(ctx, signature.Signature, &rpc.GetTransactionOpts{
+			Encoding:                       solana.EncodingBase64,
+			Commitment:                     rpc.CommitmentConfirmed,
+			MaxSupportedTransactionVersion: &maxVersion,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transaction %s: %w", signature.Signature, err)
+		}
+		if result == nil || result.Transaction == nil || result.Meta == nil {
+			continue
+		}
+		tx, err := result.Transaction.GetTransaction()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode transaction %s: %w", signature.Signature, err)
+		}
+		events, err := DecodeSwapEvents(signature.Signature, result.Slot, tx, result.Meta)
+		if err != nil {
+			return nil, err
+		}
+		for _, swap := range events {
+			if swap.Pool.Equals(pool) {
+				swaps = append(swaps, swap)
+			}
+		}
+	}
+	return swaps, nil
+}
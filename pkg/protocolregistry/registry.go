@@ -0,0 +1,173 @@
+// Package protocolregistry maps protocol names to constructors, so routers can be built from a
+// configured list of names. The built-in protocols are registered under their pkg.ProtocolName;
+// out-of-tree modules register theirs, e.g. from an init function, without changes here.
+package protocolregistry
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/protocol"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+)
+
+var (
+	// ErrUnknownProtocol is returned when building a protocol no constructor is registered for
+	ErrUnknownProtocol = errors.New("unknown protocol")
+	// ErrDuplicateProtocol is returned when registering a name twice
+	ErrDuplicateProtocol = errors.New("protocol already registered")
+)
+
+// Constructor creates a protocol discovering pools through solClient
+type Constructor func(solClient *sol.Client) pkg.Protocol
+
+// Registry maps protocol names to constructors. It is safe for concurrent use.
+type Registry struct {
+	mu           sync.RWMutex
+	constructors map[pkg.ProtocolName]Constructor
+	names        []pkg.ProtocolName // registration order
+}
+
+// New creates an empty registry
+func New() *Registry {
+	return &Registry{constructors: make(map[pkg.ProtocolName]Constructor)}
+}
+
+// Register adds constructor under name
+func (r *Registry) Register(name pkg.ProtocolName, constructor Constructor) error {
+	if name == "" || constructor == nil {
+		return fmt.Errorf("protocol name and constructor are required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.constructors[name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateProtocol, name)
+	}
+	r.constructors[name] = constructor
+	r.names = append(r.names, name)
+	return nil
+}
+
+// RegisterVenue registers an out-of-tree venue under its name, routed through
+// protocol.NewPluginProtocol
+func (r *Registry) RegisterVenue(venue pkg.Venue) error {
+	return r.Register(venue.Name(), func(solClient *sol.Client) pkg.Protocol {
+		return protocol.NewPluginProtocol(solClient, venue)
+	})
+}
+
+// Names returns the registered names in registration order
+func (r *Registry) Names() []pkg.ProtocolName {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]pkg.ProtocolName(nil), r.names...)
+}
+
+// Build creates the named protocols in the order given, or every registered one without names.
+// Unknown names fail the whole build, so a typo in a config doesn't silently drop a venue.
+func (r *Registry) Build(solClient *sol.Client, names ...pkg.ProtocolName) ([]pkg.Protocol, error) {
+	if len(names) == 0 {
+		names = r.Names()
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	protocols := make([]pkg.Protocol, 0, len(names))
+	seen := make(map[pkg.ProtocolName]bool, len(names))
+	for _, name := range names {
+		constructor, ok := r.constructors[name]
+		if !ok {
+			return nil, fmt.Errorf("%w %q, registered: %s", ErrUnknownProtocol, name, r.joinedNames())
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		protocols = append(protocols, constructor(solClient))
+	}
+	return protocols, nil
+}
+
+// joinedNames lists the registered names for error messages; r.mu must be held
+func (r *Registry) joinedNames() string {
+	names := make([]string, len(r.names))
+	for i, name := range r.names {
+		names[i] = string(name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// defaultRegistry holds the built-in protocols and those registered through the package
+// functions
+var defaultRegistry = newDefaultRegistry()
+
+// newDefaultRegistry registers the built-in protocols, in the order of protocol.Defaults
+func newDefaultRegistry() *Registry {
+	r := New()
+	builtins := []struct {
+		name        pkg.ProtocolName
+		constructor Constructor
+	}{
+		{pkg.ProtocolNamePumpAmm, func(c *sol.Client) pkg.Protocol { return protocol.NewPumpAmm(c) }},
+		{pkg.ProtocolNameRaydiumAmm, func(c *sol.Client) pkg.Protocol { return protocol.NewRaydiumAmm(c) }},
+		{pkg.ProtocolNameRaydiumClmm, func(c *sol.Client) pkg.Protocol { return protocol.NewRaydiumClmm(c) }},
+		{pkg.ProtocolNameRaydiumCpmm, func(c *sol.Client) pkg.Protocol { return protocol.NewRaydiumCpmm(c) }},
+		{pkg.ProtocolNameMeteoraDlmm, func(c *sol.Client) pkg.Protocol { return protocol.NewMeteoraDlmm(c) }},
+		{pkg.ProtocolNameOrcaWhirlpool, func(c *sol.Client) pkg.Protocol { return protocol.NewOrcaWhirlpool(c) }},
+		{pkg.ProtocolNamePhoenix, func(c *sol.Client) pkg.Protocol { return protocol.NewPhoenix(c) }},
+		{pkg.ProtocolNameMeteoraDamm, func(c *sol.Client) pkg.Protocol { return protocol.NewMeteoraDamm(c) }},
+		{pkg.ProtocolNameMeteoraDammV2, func(c *sol.Client) pkg.Protocol { return protocol.NewMeteoraDammV2(c) }},
+		{pkg.ProtocolNameRaydiumLaunchLab, func(c *sol.Client) pkg.Protocol { return protocol.NewRaydiumLaunchLab(c) }},
+		{pkg.ProtocolNameMoonshot, func(c *sol.Client) pkg.Protocol { return protocol.NewMoonshot(c) }},
+		{pkg.ProtocolNameStakePool, func(c *sol.Client) pkg.Protocol { return protocol.NewStakePool(c) }},
+		{pkg.ProtocolNameSaber, func(c *sol.Client) pkg.Protocol { return protocol.NewSaber(c) }},
+	}
+	for _, builtin := range builtins {
+		if err := r.Register(builtin.name, builtin.constructor); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Register adds constructor to the default registry under name
+func Register(name pkg.ProtocolName, constructor Constructor) error {
+	return defaultRegistry.Register(name, constructor)
+}
+
+// MustRegister is Register for init functions, panicking when name is taken
+func MustRegister(name pkg.ProtocolName, constructor Constructor) {
+	if err := Register(name, constructor); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterVenue registers an out-of-tree venue with the default registry
+func RegisterVenue(venue pkg.Venue) error {
+	return defaultRegistry.RegisterVenue(venue)
+}
+
+// Names returns the names registered with the default registry, built-ins first
+func Names() []pkg.ProtocolName {
+	return defaultRegistry.Names()
+}
+
+// Build creates the named protocols from the default registry, or every registered one
+// without names
+func Build(solClient *sol.Client, names ...pkg.ProtocolName) ([]pkg.Protocol, error) {
+	return defaultRegistry.Build(solClient, names...)
+}
+
+// ParseNames splits a comma-separated list of protocol names, e.g. from a flag, ignoring
+// blanks
+func ParseNames(list string) []pkg.ProtocolName {
+	var names []pkg.ProtocolName
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, pkg.ProtocolName(name))
+		}
+	}
+	return names
+}
@@ -0,0 +1,180 @@
+// Package retry repeats requests that fail with transient errors, backing off exponentially
+// with jitter between attempts, within a budget shared across requests and never past the
+// caller's context
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+)
+
+// ErrBudgetExhausted is returned, wrapping the last failure, when a Budget has no retries left
+var ErrBudgetExhausted = errors.New("retry budget exhausted")
+
+// Policy decides whether and when a failed request is repeated. The zero Policy makes a single
+// attempt.
+type Policy struct {
+	// MaxAttempts bounds the attempts, the first included; zero or one never retries
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry
+	BaseDelay time.Duration
+	// MaxDelay caps the wait between attempts; zero leaves it uncapped
+	MaxDelay time.Duration
+	// Multiplier grows the wait after each retry, 2 when zero
+	Multiplier float64
+	// Jitter is the fraction of each wait that is randomized, from 0 to 1, so clients failing
+	// together don't retry together
+	Jitter float64
+	// Retryable reports whether an error is worth another attempt; solerrors.IsRetryable when
+	// nil
+	Retryable func(error) bool
+	// Budget, when set, is drawn on by every retry, capping retries across the requests
+	// sharing it
+	Budget *Budget
+	// Sleeper and Rand drive waits and jitter; the system clock when nil
+	Sleeper clock.Sleeper
+	Rand    clock.Rand
+}
+
+// DefaultPolicy makes up to three attempts, waiting around 100ms and then 200ms
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts: 3,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    2 * time.Second,
+		Multiplier:  2,
+		Jitter:      0.2,
+	}
+}
+
+// Delay returns the wait before retry n, counting from 1, without jitter
+func (p Policy) Delay(n int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	delay := float64(p.BaseDelay) * math.Pow(multiplier, float64(n-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		return p.MaxDelay
+	}
+	return time.Duration(delay)
+}
+
+// Run calls fn until it succeeds, fails with an error that isn't retryable, runs out of
+// attempts or budget, or ctx is done
+func (p Policy) Run(ctx context.Context, fn func(context.Context) error) error {
+	_, err := Do(ctx, p, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// Do is Run for requests returning a value
+func Do[T any](ctx context.Context, p Policy, fn func(context.Context) (T, error)) (T, error) {
+	var zero T
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+		out, err := fn(ctx)
+		if attempt == 1 && p.Budget != nil {
+			p.Budget.deposit()
+		}
+		if err == nil {
+			return out, nil
+		}
+		if !p.retryable(err) || ctx.Err() != nil {
+			return zero, err
+		}
+		if attempt >= p.MaxAttempts {
+			if attempt > 1 {
+				return zero, fmt.Errorf("failed after %d attempts: %w", attempt, err)
+			}
+			return zero, err
+		}
+		if p.Budget != nil && !p.Budget.withdraw() {
+			return zero, fmt.Errorf("%w: %w", ErrBudgetExhausted, err)
+		}
+		if sleepErr := p.sleeper().Sleep(ctx, p.jitter(p.Delay(attempt))); sleepErr != nil {
+			return zero, fmt.Errorf("%w after %d attempts: %w", sleepErr, attempt, err)
+		}
+	}
+}
+
+func (p Policy) retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if p.Retryable == nil {
+		return solerrors.IsRetryable(err)
+	}
+	return p.Retryable(err)
+}
+
+// jitter shortens delay by up to the policy's Jitter fraction
+func (p Policy) jitter(delay time.Duration) time.Duration {
+	if p.Jitter <= 0 {
+		return delay
+	}
+	var rand clock.Rand = clock.System{}
+	if p.Rand != nil {
+		rand = p.Rand
+	}
+	return delay - time.Duration(float64(delay)*min(p.Jitter, 1)*rand.Float64())
+}
+
+func (p Policy) sleeper() clock.Sleeper {
+	if p.Sleeper == nil {
+		return clock.System{}
+	}
+	return p.Sleeper
+}
+
+// Budget caps retries at a share of requests, so a failing node sees its load grow by that
+// share rather than by the attempts of every policy. Each request earns ratio of a retry, up
+// to burst held at once, and each retry spends one. It starts full and is safe for concurrent
+// use.
+type Budget struct {
+	ratio float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+}
+
+// NewBudget allows ratio retries per request, e.g. 0.1 for one retry per ten requests, with
+// up to burst retries in a row
+func NewBudget(ratio float64, burst int) *Budget {
+	burst = max(burst, 1)
+	return &Budget{ratio: ratio, burst: float64(burst), tokens: float64(burst)}
+}
+
+// Remaining returns how many retries the budget allows right now
+func (b *Budget) Remaining() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return int(b.tokens)
+}
+
+func (b *Budget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.tokens+b.ratio, b.burst)
+}
+
+func (b *Budget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,114 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"cosmossdk.io/math"
+)
+
+// maxMinAmountSteps bounds each of the doubling and bisection phases of the minimum amount
+// search, so an input up to 2^64 times the requested one is found
+const maxMinAmountSteps = 64
+
+// ErrAmountTooSmall is returned by GetBestPool when every pool that could be quoted rounds
+// the output down to zero
+var ErrAmountTooSmall = errors.New("amount too small to quote")
+
+// AmountTooSmallError reports the smallest input that routes to a non-zero output. It
+// matches ErrAmountTooSmall with errors.Is.
+type AmountTooSmallError struct {
+	AmountIn math.Int
+	// MinAmountIn is the smallest input any pool quotes above zero, nil if none was found
+	MinAmountIn math.Int
+	// Pool quotes MinAmountIn
+	Pool pkg.Pool
+	// Price is the output per unit of input at MinAmountIn, exact rather than floored, so
+	// dust probes still get a price
+	Price *big.Rat
+}
+
+func (e *AmountTooSmallError) Error() string {
+	if e.MinAmountIn.IsNil() {
+		return fmt.Sprintf("%s: %s quotes to zero in every pool", ErrAmountTooSmall, e.AmountIn)
+	}
+	return fmt.Sprintf("%s: %s quotes to zero, the smallest routable input is %s through pool %s",
+		ErrAmountTooSmall, e.AmountIn, e.MinAmountIn, e.Pool.GetID())
+}
+
+func (e *AmountTooSmallError) Unwrap() error {
+	return ErrAmountTooSmall
+}
+
+// amountTooSmall searches the pools that quoted amountIn to zero for the smallest input
+// with a non-zero output
+func (r *SimpleRouter) amountTooSmall(ctx context.Context, solClient pkg.RPC, pools []pkg.Pool, tokenIn string, amountIn math.Int) error {
+	tooSmall := &AmountTooSmallError{AmountIn: amountIn}
+	for _, pool := range pools {
+		minIn, out, err := r.minAmountIn(ctx, solClient, pool, tokenIn, amountIn)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+		if tooSmall.MinAmountIn.IsNil() || minIn.LT(tooSmall.MinAmountIn) {
+			tooSmall.MinAmountIn = minIn
+			tooSmall.Pool = pool
+			tooSmall.Price = new(big.Rat).SetFrac(out.BigInt(), minIn.BigInt())
+		}
+	}
+	return tooSmall
+}
+
+// minAmountIn doubles amountIn until pool quotes it above zero, then bisects down to the
+// smallest such input. It returns that input and its output.
+func (r *SimpleRouter) minAmountIn(ctx context.Context, solClient pkg.RPC, pool pkg.Pool, tokenIn string, amountIn math.Int) (math.Int, math.Int, error) {
+	quote := func(in math.Int) (math.Int, error) {
+		quoteCtx := ctx
+		if r.quoteTimeout > 0 {
+			var cancel context.CancelFunc
+			quoteCtx, cancel = context.WithTimeout(ctx, r.quoteTimeout)
+			defer cancel()
+		}
+		defer pkg.LockPool(pool)()
+		return pool.Quote(quoteCtx, solClient, tokenIn, in)
+	}
+
+	low := amountIn
+	if !low.IsPositive() {
+		low = math.ZeroInt()
+	}
+	high, out := math.MaxInt(low.MulRaw(2), math.OneInt()), math.ZeroInt()
+	for step := 0; ; step++ {
+		if step == maxMinAmountSteps {
+			return math.Int{}, math.Int{}, fmt.Errorf("no non-zero quote up to %s", high)
+		}
+		var err error
+		if out, err = quote(high); err != nil {
+			return math.Int{}, math.Int{}, err
+		}
+		if out.IsPositive() {
+			break
+		}
+		low, high = high, high.MulRaw(2)
+	}
+
+	// low quotes to zero and high doesn't
+	for step := 0; step < maxMinAmountSteps && high.Sub(low).GT(math.OneInt()); step++ {
+		mid := low.Add(high).QuoRaw(2)
+		midOut, err := quote(mid)
+		if err != nil {
+			return math.Int{}, math.Int{}, err
+		}
+		if midOut.IsPositive() {
+			high, out = mid, midOut
+		} else {
+			low = mid
+		}
+	}
+	return high, out, nil
+}
@@ -0,0 +1,129 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+)
+
+// ArbitrageOptions configures FindArbitrage
+type ArbitrageOptions struct {
+	// AmountIn is the amount of the starting mint each cycle is quoted with
+	AmountIn math.Int
+	// ComputeUnitPrice is the priority fee the cycle's transaction would pay, in
+	// micro-lamports per compute unit
+	ComputeUnitPrice uint64
+	// MinProfit drops cycles earning less, net of network fees when they can be priced in
+	// the starting mint. Nil keeps every cycle with a positive profit.
+	MinProfit math.Int
+}
+
+// ArbitrageCycle is a round trip from a mint through another and back, buying in one pool
+// and selling in another
+type ArbitrageCycle struct {
+	Mint         string
+	Intermediate string
+	// Pools are the cycle's two pools in hop order
+	Pools     []pkg.Pool
+	AmountIn  math.Int
+	AmountMid math.Int // of Intermediate, out of the first pool
+	AmountOut math.Int
+	// Profit is AmountOut less AmountIn, after the pools' fees
+	Profit math.Int
+	// ComputeUnits is the estimated compute unit use of a transaction executing both swaps
+	ComputeUnits uint64
+	// NetworkFee is the signature and priority fee of that transaction, in lamports
+	NetworkFee uint64
+	// NetProfit is Profit less NetworkFee priced in Mint, nil when no known pool prices SOL
+	// in Mint
+	NetProfit math.Int
+}
+
+// FindArbitrage searches the pairs discovered with mint for cycles that start and end in
+// mint across two different pools, quoting each leg through the router like GetBestPool, so
+// disabled, deprecated and blocked pools are left out. The cycles are returned most
+// profitable first. A nil solClient uses the router's quote client.
+func (r *SimpleRouter) FindArbitrage(ctx context.Context, solClient pkg.RPC, mint string, opts ArbitrageOptions) ([]ArbitrageCycle, error) {
+	solClient, err := r.quoteClientFor(solClient)
+	if err != nil {
+		return nil, err
+	}
+	if opts.AmountIn.IsNil() || !opts.AmountIn.IsPositive() {
+		return nil, fmt.Errorf("arbitrage amount must be positive")
+	}
+	model := r.computeUnits
+	if model == nil {
+		model = NewComputeUnitModel()
+	}
+
+	var cycles []ArbitrageCycle
+	for _, intermediate := range r.pools.counterparts(mint) {
+		for _, first := range r.pools.get(mint, intermediate) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			cycle, ok := r.arbitrageThrough(ctx, solClient, mint, intermediate, first, opts.AmountIn)
+			if !ok {
+				continue
+			}
+			cycle.ComputeUnits = model.RouteUnits(cycle.Pools)
+			cycle.NetworkFee = lamportsPerSignature + (cycle.ComputeUnits*opts.ComputeUnitPrice+999_999)/1_000_000
+			if fee, ok := r.lamportsIn(ctx, solClient, mint, cycle.NetworkFee); ok {
+				cycle.NetProfit = cycle.Profit.Sub(fee)
+			}
+			if !opts.MinProfit.IsNil() && cycle.netOrGross().LT(opts.MinProfit) {
+				continue
+			}
+			cycles = append(cycles, cycle)
+		}
+	}
+	sort.SliceStable(cycles, func(i, j int) bool { return cycles[i].netOrGross().GT(cycles[j].netOrGross()) })
+	return cycles, nil
+}
+
+// arbitrageThrough quotes mint into intermediate through first, then back through the best
+// other pool, reporting false unless the round trip is profitable
+func (r *SimpleRouter) arbitrageThrough(ctx context.Context, solClient pkg.RPC, mint, intermediate string, first pkg.Pool, amountIn math.Int) (ArbitrageCycle, bool) {
+	_, mid, _, err := r.withRouteOptions(RouteOptions{Pools: []string{first.GetID()}}).bestPool(ctx, solClient, mint, intermediate, amountIn)
+	if err != nil {
+		return ArbitrageCycle{}, false
+	}
+	second, out, _, err := r.withRouteOptions(RouteOptions{ExcludePools: []string{first.GetID()}}).bestPool(ctx, solClient, intermediate, mint, mid)
+	if err != nil || !out.GT(amountIn) {
+		return ArbitrageCycle{}, false
+	}
+	return ArbitrageCycle{
+		Mint:         mint,
+		Intermediate: intermediate,
+		Pools:        []pkg.Pool{first, second},
+		AmountIn:     amountIn,
+		AmountMid:    mid,
+		AmountOut:    out,
+		Profit:       out.Sub(amountIn),
+	}, true
+}
+
+// lamportsIn prices lamports in mint, through the router's SOL pools unless mint is SOL
+func (r *SimpleRouter) lamportsIn(ctx context.Context, solClient pkg.RPC, mint string, lamports uint64) (math.Int, bool) {
+	amount := math.NewIntFromUint64(lamports)
+	if mint == solana.WrappedSol.String() {
+		return amount, true
+	}
+	_, out, _, err := r.bestPool(ctx, solClient, solana.WrappedSol.String(), mint, amount)
+	if err != nil {
+		return math.Int{}, false
+	}
+	return out, true
+}
+
+// netOrGross returns the net profit when known, the profit otherwise
+func (c ArbitrageCycle) netOrGross() math.Int {
+	if c.NetProfit.IsNil() {
+		return c.Profit
+	}
+	return c.NetProfit
+}
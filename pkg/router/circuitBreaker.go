@@ -0,0 +1,145 @@
+package router
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+)
+
+const (
+	// DefaultBreakerFailures is the number of consecutive discovery failures that open a
+	// protocol's circuit
+	DefaultBreakerFailures = 3
+	// DefaultBreakerCooldown is how long an open circuit skips its protocol
+	DefaultBreakerCooldown = 30 * time.Second
+)
+
+type breakerState struct {
+	proto     pkg.Protocol
+	failures  int
+	openUntil time.Time
+}
+
+// CircuitBreaker skips protocols during discovery after repeated consecutive failures. Once
+// its cooldown passes a protocol is tried again: a success closes its circuit and a failure
+// opens it for another cooldown. Protocols are told apart by identity, so one breaker can be
+// shared by routers over the same protocol values.
+type CircuitBreaker struct {
+	failures int
+	cooldown time.Duration
+	clock    clock.Clock
+
+	mu     sync.Mutex
+	states map[any]*breakerState
+}
+
+// NewCircuitBreaker creates a breaker that opens after failures consecutive failures and
+// stays open for cooldown. Values below 1 use the defaults.
+func NewCircuitBreaker(failures int, cooldown time.Duration) *CircuitBreaker {
+	if failures < 1 {
+		failures = DefaultBreakerFailures
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultBreakerCooldown
+	}
+	return &CircuitBreaker{
+		failures: failures,
+		cooldown: cooldown,
+		clock:    clock.System{},
+		states:   make(map[any]*breakerState),
+	}
+}
+
+// SetClock replaces the time source used for cooldowns
+func (b *CircuitBreaker) SetClock(clk clock.Clock) {
+	b.clock = clk
+}
+
+// Allow reports whether proto should be queried, false while its circuit is open. A nil
+// breaker allows every protocol.
+func (b *CircuitBreaker) Allow(proto pkg.Protocol) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.states[breakerKey(proto)]
+	return !ok || !b.clock.Now().Before(state.openUntil)
+}
+
+// Record counts the outcome of querying proto
+func (b *CircuitBreaker) Record(proto pkg.Protocol, err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := breakerKey(proto)
+	if err == nil {
+		delete(b.states, key)
+		return
+	}
+	state, ok := b.states[key]
+	if !ok {
+		state = &breakerState{proto: proto}
+		b.states[key] = state
+	}
+	state.failures++
+	if state.failures >= b.failures {
+		state.openUntil = b.clock.Now().Add(b.cooldown)
+	}
+}
+
+// Open lists the protocols whose circuit is currently open
+func (b *CircuitBreaker) Open() []pkg.Protocol {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+	var open []pkg.Protocol
+	for _, state := range b.states {
+		if now.Before(state.openUntil) {
+			open = append(open, state.proto)
+		}
+	}
+	return open
+}
+
+// Reset closes every circuit
+func (b *CircuitBreaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	clear(b.states)
+}
+
+// breakerKey identifies proto in the breaker's state. Protocols of comparable types are their
+// own key; others, such as slice types, fall back to their type and address.
+func breakerKey(proto pkg.Protocol) any {
+	if reflect.TypeOf(proto).Comparable() {
+		return proto
+	}
+	v := reflect.ValueOf(proto)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Func:
+		return fmt.Sprintf("%T@%x", proto, v.Pointer())
+	}
+	return fmt.Sprintf("%T", proto)
+}
+
+// SetCircuitBreaker makes discovery skip protocols whose circuit is open and record every
+// protocol's outcome in breaker. Skipped protocols are reported by DiscoverPools. Nil
+// disables the breaker.
+func (r *SimpleRouter) SetCircuitBreaker(breaker *CircuitBreaker) {
+	r.breaker = breaker
+}
+
+// SetProtocolTimeout bounds how long discovery waits for any single protocol. A protocol
+// that runs past it is cancelled and reported as failed, so one slow venue can't hold up the
+// others. Zero disables the timeout. Unlike the discovery budget it counts as a failure
+// towards the circuit breaker.
+func (r *SimpleRouter) SetProtocolTimeout(d time.Duration) {
+	r.protocolTimeout = d
+}
@@ -0,0 +1,152 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"cosmossdk.io/math"
+)
+
+var (
+	// ErrBlocked is returned for mints and pools matched by the router's Blocklist
+	ErrBlocked = errors.New("blocked by policy")
+	// ErrRouteVetoed wraps the error a ComplianceHook rejected a route with
+	ErrRouteVetoed = errors.New("route vetoed")
+)
+
+// Blocklist holds the mints, pools and authorities a router must not route through. It is
+// safe to update while the router is quoting.
+type Blocklist struct {
+	mu          sync.RWMutex
+	mints       map[string]bool
+	pools       map[string]bool
+	authorities map[string]bool
+}
+
+// NewBlocklist creates an empty blocklist
+func NewBlocklist() *Blocklist {
+	return &Blocklist{
+		mints:       make(map[string]bool),
+		pools:       make(map[string]bool),
+		authorities: make(map[string]bool),
+	}
+}
+
+// BlockMint blocks routes that take, return or pass through the mint
+func (b *Blocklist) BlockMint(mint string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.mints[mint] = true
+}
+
+// BlockPool blocks a pool by its ID
+func (b *Blocklist) BlockPool(poolID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pools[poolID] = true
+}
+
+// BlockAuthority blocks pools owned by the program, or whose token vaults are controlled by
+// the account. Vault authorities are only known for pools implementing pkg.VaultPool.
+func (b *Blocklist) BlockAuthority(authority string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.authorities[authority] = true
+}
+
+// Unblock removes an address from every list
+func (b *Blocklist) Unblock(address string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.mints, address)
+	delete(b.pools, address)
+	delete(b.authorities, address)
+}
+
+// CheckMints returns ErrBlocked if any of the mints is blocked
+func (b *Blocklist) CheckMints(mints ...string) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, mint := range mints {
+		if b.mints[mint] {
+			return fmt.Errorf("%w: mint %s", ErrBlocked, mint)
+		}
+	}
+	return nil
+}
+
+// CheckPool returns ErrBlocked if the pool, one of its mints or one of its authorities is
+// blocked
+func (b *Blocklist) CheckPool(pool pkg.Pool) error {
+	baseMint, quoteMint := pool.GetTokens()
+	if err := b.CheckMints(baseMint, quoteMint); err != nil {
+		return err
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.pools[pool.GetID()] {
+		return fmt.Errorf("%w: pool %s", ErrBlocked, pool.GetID())
+	}
+	if program := pool.GetProgramID().String(); b.authorities[program] {
+		return fmt.Errorf("%w: program %s", ErrBlocked, program)
+	}
+	if vaultPool, ok := pool.(pkg.VaultPool); ok {
+		for _, vault := range vaultPool.TokenVaults() {
+			if authority := vault.Authority.String(); !vault.Authority.IsZero() && b.authorities[authority] {
+				return fmt.Errorf("%w: vault authority %s", ErrBlocked, authority)
+			}
+		}
+	}
+	return nil
+}
+
+// RouteCandidate is a route offered to a ComplianceHook before it is quoted
+type RouteCandidate struct {
+	InputMint  string
+	OutputMint string
+	AmountIn   math.Int
+	Pools      []pkg.Pool // in hop order
+}
+
+// ComplianceHook vetoes a route by returning an error. The router calls it once per
+// candidate, concurrently across candidates, after the blocklist has passed it.
+type ComplianceHook func(ctx context.Context, route RouteCandidate) error
+
+// SetBlocklist makes the router skip blocked pools and refuse blocked mints. Nil disables
+// the blocklist.
+func (r *SimpleRouter) SetBlocklist(blocklist *Blocklist) {
+	r.blocklist = blocklist
+}
+
+// SetComplianceHook sets a hook that can veto each route before it is quoted. Nil removes it.
+func (r *SimpleRouter) SetComplianceHook(hook ComplianceHook) {
+	r.complianceHook = hook
+}
+
+// checkRequest applies the blocklist to the requested mints
+func (r *SimpleRouter) checkRequest(tokenIn, tokenOut string) error {
+	if r.blocklist == nil {
+		return nil
+	}
+	return r.blocklist.CheckMints(tokenIn, tokenOut)
+}
+
+// policyError applies the blocklist and the compliance hook to a single-pool route
+func (r *SimpleRouter) policyError(ctx context.Context, route RouteCandidate) error {
+	if r.blocklist != nil {
+		for _, pool := range route.Pools {
+			if err := r.blocklist.CheckPool(pool); err != nil {
+				return err
+			}
+		}
+	}
+	if r.complianceHook != nil {
+		if err := r.complianceHook(ctx, route); err != nil {
+			return fmt.Errorf("%w: %w", ErrRouteVetoed, err)
+		}
+	}
+	return nil
+}
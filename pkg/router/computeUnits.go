@@ -0,0 +1,203 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+)
+
+var _ sol.ComputeUnitObserver = (*ComputeUnitModel)(nil)
+
+// ErrComputeBudgetExceeded is returned for routes estimated to need more compute units than a
+// transaction may use
+var ErrComputeBudgetExceeded = errors.New("route exceeds the transaction compute unit limit")
+
+const (
+	// DefaultTxOverheadComputeUnits covers the compute budget, token account and WSOL
+	// instructions a swap transaction carries besides the swaps themselves
+	DefaultTxOverheadComputeUnits = 40_000
+	// defaultUnknownComputeUnits is assumed for protocols without an estimate
+	defaultUnknownComputeUnits = 200_000
+	// observationWeight is the share of a new measurement in the running estimate, out of 10
+	observationWeight = 3
+)
+
+// defaultComputeUnits are typical per-swap costs until measurements replace them. Tick and
+// bin crossings make the concentrated liquidity venues the most expensive.
+var defaultComputeUnits = map[pkg.ProtocolName]uint32{
+	pkg.ProtocolNameRaydiumAmm:       40_000,
+	pkg.ProtocolNameRaydiumCpmm:      50_000,
+	pkg.ProtocolNameRaydiumClmm:      120_000,
+	pkg.ProtocolNameRaydiumLaunchLab: 60_000,
+	pkg.ProtocolNameMeteoraDlmm:      110_000,
+	pkg.ProtocolNameMeteoraDamm:      120_000,
+	pkg.ProtocolNameMeteoraDammV2:    60_000,
+	pkg.ProtocolNamePumpAmm:          80_000,
+	pkg.ProtocolNameOrcaWhirlpool:    100_000,
+	pkg.ProtocolNamePhoenix:          60_000,
+	pkg.ProtocolNameMoonshot:         50_000,
+	pkg.ProtocolNameStakePool:        60_000,
+	pkg.ProtocolNameSaber:            50_000,
+}
+
+// ComputeUnitModel keeps per-protocol compute unit estimates for a single swap, starting from
+// defaults and refined with simulation measurements, so routes that can't fit in one
+// transaction are rejected or split while planning rather than failing in simulation. It is
+// safe for concurrent use.
+type ComputeUnitModel struct {
+	mu        sync.RWMutex
+	estimates map[pkg.ProtocolName]uint32
+	measured  map[pkg.ProtocolName]bool
+	overhead  uint32
+	limit     uint32
+}
+
+// NewComputeUnitModel creates a model with the default estimates, overhead and the
+// transaction compute unit cap
+func NewComputeUnitModel() *ComputeUnitModel {
+	estimates := make(map[pkg.ProtocolName]uint32, len(defaultComputeUnits))
+	for name, units := range defaultComputeUnits {
+		estimates[name] = units
+	}
+	return &ComputeUnitModel{
+		estimates: estimates,
+		measured:  make(map[pkg.ProtocolName]bool),
+		overhead:  DefaultTxOverheadComputeUnits,
+		limit:     sol.MaxComputeUnitLimit,
+	}
+}
+
+// SetEstimate fixes a protocol's per-swap estimate, e.g. from offline benchmarks
+func (m *ComputeUnitModel) SetEstimate(name pkg.ProtocolName, units uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.estimates[name] = units
+	m.measured[name] = true
+}
+
+// SetOverhead sets the units reserved per transaction for non-swap instructions
+func (m *ComputeUnitModel) SetOverhead(units uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.overhead = units
+}
+
+// SetLimit sets the per-transaction budget routes must fit in; zero or values above the
+// cap use sol.MaxComputeUnitLimit
+func (m *ComputeUnitModel) SetLimit(units uint32) {
+	if units == 0 || units > sol.MaxComputeUnitLimit {
+		units = sol.MaxComputeUnitLimit
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limit = units
+}
+
+// Estimate returns the units one swap through a protocol is expected to use
+func (m *ComputeUnitModel) Estimate(name pkg.ProtocolName) uint32 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.estimate(name)
+}
+
+func (m *ComputeUnitModel) estimate(name pkg.ProtocolName) uint32 {
+	if units, ok := m.estimates[name]; ok {
+		return units
+	}
+	return defaultUnknownComputeUnits
+}
+
+// Observe records a measured single swap. The first measurement replaces the default; later
+// ones move the estimate towards the measurement.
+func (m *ComputeUnitModel) Observe(name pkg.ProtocolName, units uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observe(name, units)
+}
+
+func (m *ComputeUnitModel) observe(name pkg.ProtocolName, units uint32) {
+	if !m.measured[name] {
+		m.estimates[name] = units
+		m.measured[name] = true
+		return
+	}
+	current := uint64(m.estimate(name))
+	m.estimates[name] = uint32((current*(10-observationWeight) + uint64(units)*observationWeight) / 10)
+}
+
+// ObserveSimulation attributes the units a simulated transaction consumed, less the
+// overhead, to its hops in proportion to their current estimates. unitsConsumed is what
+// the simulation reports, e.g. through sol.ComputeBudgetManager.EstimateComputeUnits
+// with a zero margin.
+func (m *ComputeUnitModel) ObserveSimulation(pools []pkg.Pool, unitsConsumed uint64) {
+	if len(pools) == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	swapUnits := unitsConsumed - min(unitsConsumed, uint64(m.overhead))
+	var total uint64
+	for _, pool := range pools {
+		total += uint64(m.estimate(pool.ProtocolName()))
+	}
+	// Shares are computed before any estimate moves
+	shares := make([]uint32, len(pools))
+	for i, pool := range pools {
+		shares[i] = uint32(swapUnits * uint64(m.estimate(pool.ProtocolName())) / max(total, 1))
+	}
+	for i, pool := range pools {
+		m.observe(pool.ProtocolName(), shares[i])
+	}
+}
+
+// RouteUnits estimates the units of a transaction executing pools in order
+func (m *ComputeUnitModel) RouteUnits(pools []pkg.Pool) uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	units := uint64(m.overhead)
+	for _, pool := range pools {
+		units += uint64(m.estimate(pool.ProtocolName()))
+	}
+	return units
+}
+
+// CheckRoute returns ErrComputeBudgetExceeded when the route can't fit in one transaction
+func (m *ComputeUnitModel) CheckRoute(pools []pkg.Pool) error {
+	units := m.RouteUnits(pools)
+	m.mu.RLock()
+	limit := m.limit
+	m.mu.RUnlock()
+	if units > uint64(limit) {
+		return fmt.Errorf("%w: %d hops need about %d units, limit %d", ErrComputeBudgetExceeded, len(pools), units, limit)
+	}
+	return nil
+}
+
+// SplitRoute groups consecutive hops into as few transactions as fit the limit, keeping hop
+// order. It fails only if a single hop doesn't fit on its own.
+func (m *ComputeUnitModel) SplitRoute(pools []pkg.Pool) ([][]pkg.Pool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var groups [][]pkg.Pool
+	var current []pkg.Pool
+	units := uint64(m.overhead)
+	for _, pool := range pools {
+		hop := uint64(m.estimate(pool.ProtocolName()))
+		if uint64(m.overhead)+hop > uint64(m.limit) {
+			return nil, fmt.Errorf("%w: a %s swap alone needs about %d units", ErrComputeBudgetExceeded, pool.ProtocolName(), uint64(m.overhead)+hop)
+		}
+		if units+hop > uint64(m.limit) {
+			groups = append(groups, current)
+			current, units = nil, uint64(m.overhead)
+		}
+		current = append(current, pool)
+		units += hop
+	}
+	if len(current) > 0 {
+		groups = append(groups, current)
+	}
+	return groups, nil
+}
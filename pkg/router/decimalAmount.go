@@ -0,0 +1,63 @@
+package router
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"cosmossdk.io/math"
+)
+
+// ParseAmount converts a decimal amount of mint, e.g. "1.5" or "1.5 SOL", to base units with
+// the mint's decimals, reading them the first time. See sol.ParseAmount for the accepted
+// forms. A nil solClient uses the router's quote client.
+func (r *SimpleRouter) ParseAmount(ctx context.Context, solClient pkg.RPC, mint, amount string) (math.Int, error) {
+	token, err := r.TokenInfo(ctx, solClient, mint)
+	if err != nil {
+		return math.Int{}, err
+	}
+	return token.ParseAmount(amount)
+}
+
+// QuoteDecimal quotes a decimal amount of tokenIn like Quote. The result's Units and
+// MinOutUnits give the amounts in decimal units.
+func (r *SimpleRouter) QuoteDecimal(ctx context.Context, solClient pkg.RPC, tokenIn, tokenOut, amount string, slippage pkg.SlippageConfig) (QuoteResult, error) {
+	amountIn, err := r.ParseAmount(ctx, solClient, tokenIn, amount)
+	if err != nil {
+		return QuoteResult{}, err
+	}
+	return r.Quote(ctx, solClient, tokenIn, tokenOut, amountIn, slippage)
+}
+
+// QuotePoolDecimal quotes a decimal amount of inputMint through one pool, returning the
+// output in decimal units. A nil solClient uses the router's quote client.
+func (r *SimpleRouter) QuotePoolDecimal(ctx context.Context, solClient pkg.RPC, pool pkg.Pool, inputMint, amount string) (AmountUnits, error) {
+	solClient, err := r.quoteClientFor(solClient)
+	if err != nil {
+		return AmountUnits{}, err
+	}
+	baseMint, quoteMint := pool.GetTokens()
+	outputMint := quoteMint
+	switch inputMint {
+	case baseMint:
+	case quoteMint:
+		outputMint = baseMint
+	default:
+		return AmountUnits{}, fmt.Errorf("mint %s not in pool %s", inputMint, pool.GetID())
+	}
+	tokens, err := r.tokenInfos(ctx, solClient, inputMint, outputMint)
+	if err != nil {
+		return AmountUnits{}, err
+	}
+	amountIn, err := tokens[0].ParseAmount(amount)
+	if err != nil {
+		return AmountUnits{}, err
+	}
+	unlock := pkg.LockPool(pool)
+	out, err := pool.Quote(ctx, solClient, inputMint, amountIn)
+	unlock()
+	if err != nil {
+		return AmountUnits{}, fmt.Errorf("failed to quote pool %s: %w", pool.GetID(), err)
+	}
+	return TokenAmountUnits(tokens[1], out), nil
+}
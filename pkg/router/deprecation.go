@@ -0,0 +1,81 @@
+package router
+
+import (
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+)
+
+// successorPreference orders the protocols liquidity is usually migrated to
+var successorPreference = []pkg.ProtocolName{
+	pkg.ProtocolNameRaydiumCpmm,
+	pkg.ProtocolNameRaydiumClmm,
+}
+
+// linkSuccessors points every deprecated pool without a known successor at a routable pool
+// of the same pair, preferring the protocols Raydium migrates AMM liquidity to
+func linkSuccessors(pools []pkg.Pool) {
+	for _, pool := range pools {
+		deprecated, ok := pool.(pkg.DeprecatablePool)
+		if !ok {
+			continue
+		}
+		unlock := pkg.LockPool(pool)
+		d := deprecated.Deprecation()
+		unlock()
+		if d == nil || d.Successor != "" {
+			continue
+		}
+		if successor := findSuccessor(pool, pools); successor != nil {
+			unlock := pkg.LockPool(pool)
+			deprecated.SetSuccessor(successor.GetID())
+			unlock()
+		}
+	}
+}
+
+func findSuccessor(pool pkg.Pool, pools []pkg.Pool) pkg.Pool {
+	var fallback pkg.Pool
+	for _, name := range successorPreference {
+		for _, candidate := range pools {
+			if candidate == pool || !samePair(pool, candidate) || lockedDeprecationOf(candidate) != nil {
+				continue
+			}
+			if candidate.ProtocolName() == name {
+				return candidate
+			}
+			if fallback == nil {
+				fallback = candidate
+			}
+		}
+	}
+	return fallback
+}
+
+func samePair(a, b pkg.Pool) bool {
+	aBase, aQuote := a.GetTokens()
+	bBase, bQuote := b.GetTokens()
+	return (aBase == bBase && aQuote == bQuote) || (aBase == bQuote && aQuote == bBase)
+}
+
+func deprecationOf(pool pkg.Pool) *pkg.Deprecation {
+	if deprecated, ok := pool.(pkg.DeprecatablePool); ok {
+		return deprecated.Deprecation()
+	}
+	return nil
+}
+
+// lockedDeprecationOf is deprecationOf for a pool the caller doesn't hold the lock of
+func lockedDeprecationOf(pool pkg.Pool) *pkg.Deprecation {
+	defer pkg.LockPool(pool)()
+	return deprecationOf(pool)
+}
+
+// deprecationError describes a pool skipped by the router. It matches ErrPoolDisabled.
+func deprecationError(d *pkg.Deprecation) error {
+	if d.Successor != "" {
+		return solerrors.Mark(fmt.Errorf("pool deprecated (%s), successor %s", d.Reason, d.Successor), solerrors.ErrPoolDisabled)
+	}
+	return solerrors.Mark(fmt.Errorf("pool deprecated (%s)", d.Reason), solerrors.ErrPoolDisabled)
+}
@@ -0,0 +1,264 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gtdvccc/SolRouteTmp/pkg/telemetry"
+)
+
+// InteractiveDiscoveryBudget is a discovery budget suited to interactive quoting, where a
+// quote from most venues now beats a quote from every venue seconds later
+const InteractiveDiscoveryBudget = 800 * time.Millisecond
+
+// Discovery is the outcome of DiscoverPools
+type Discovery struct {
+	Pools []pkg.Pool
+	// TimedOut lists the protocols still searching when the discovery budget ran out; their
+	// pools are missing from Pools
+	TimedOut []pkg.Protocol
+	// Failed lists the protocols whose search failed or ran past the protocol timeout
+	Failed []ProtocolFailure
+	// Skipped lists the protocols not queried because their circuit was open
+	Skipped []pkg.Protocol
+}
+
+// ProtocolFailure is a protocol's failed search for a pair's pools
+type ProtocolFailure struct {
+	Protocol pkg.Protocol
+	Err      error
+}
+
+// Partial reports whether some protocols were cut off by the discovery budget
+func (d Discovery) Partial() bool {
+	return len(d.TimedOut) > 0
+}
+
+// Err joins the failures of the protocols that failed, nil when none did
+func (d Discovery) Err() error {
+	errs := make([]error, len(d.Failed))
+	for i, failure := range d.Failed {
+		errs[i] = failure.Err
+	}
+	return errors.Join(errs...)
+}
+
+// SetDiscoveryBudget bounds how long QueryAllPools and DiscoverPools wait for protocols.
+// Once it runs out the router proceeds with the pools found so far. Zero waits for every
+// protocol.
+func (r *SimpleRouter) SetDiscoveryBudget(d time.Duration) {
+	r.discoveryBudget = d
+}
+
+// DiscoverPools is QueryAllPools reporting the protocols cut off by the discovery budget.
+// With a pool cache, a partial discovery is returned but not cached, so the next call asks
+// the slow protocols again.
+func (r *SimpleRouter) DiscoverPools(ctx context.Context, baseMint, quoteMint string) (Discovery, error) {
+	ctx, span := r.telemetry.Start(ctx, telemetry.SpanDiscover,
+		telemetry.String(pkg.LogKeyBaseMint, baseMint),
+		telemetry.String(pkg.LogKeyQuoteMint, quoteMint),
+	)
+	discovery, err := r.discoverPools(ctx, baseMint, quoteMint)
+	if err == nil {
+		perProtocol := make(map[pkg.ProtocolName]int)
+		for _, pool := range discovery.Pools {
+			perProtocol[pool.ProtocolName()]++
+		}
+		for name, n := range perProtocol {
+			r.telemetry.Add(ctx, telemetry.MetricDiscoveredPools, int64(n), telemetry.String(pkg.LogKeyProtocol, string(name)))
+		}
+		span.SetAttributes(
+			telemetry.Int("pools", len(discovery.Pools)),
+			telemetry.Int("failed", len(discovery.Failed)),
+			telemetry.Int("timed_out", len(discovery.TimedOut)),
+		)
+	}
+	telemetry.End(span, err)
+	return discovery, err
+}
+
+func (r *SimpleRouter) discoverPools(ctx context.Context, baseMint, quoteMint string) (Discovery, error) {
+	if r.poolCache != nil {
+		discovery, err := r.poolCache.get(ctx, baseMint, quoteMint, r.discoveryPolicy())
+		if err != nil {
+			return Discovery{}, err
+		}
+		pools, err := r.applyLiquidityFilter(ctx, r.filterPools(discovery.Pools), quoteMint)
+		if err != nil {
+			return Discovery{}, err
+		}
+		if pools, err = r.wrapMintExtensions(ctx, pools); err != nil {
+			return Discovery{}, err
+		}
+		linkSuccessors(pools)
+		r.pools.set(baseMint, quoteMint, pools)
+		discovery.Pools = pools
+		return discovery, nil
+	}
+	discovery := Discovery{Pools: []pkg.Pool{}}
+	for i, res := range discover(ctx, r.protocols, r.registry, baseMint, quoteMint, r.discoveryPolicy()) {
+		proto := r.protocols[i]
+		if !discovery.add(proto, res) {
+			continue
+		}
+		pools, err := r.applyLiquidityFilter(ctx, r.filterPools(res.pools), quoteMint)
+		if err != nil {
+			return Discovery{}, err
+		}
+		if pools, err = r.wrapMintExtensions(ctx, pools); err != nil {
+			return Discovery{}, err
+		}
+		discovery.Pools = append(discovery.Pools, pools...)
+	}
+	linkSuccessors(discovery.Pools)
+	r.pools.set(baseMint, quoteMint, discovery.Pools)
+	return discovery, nil
+}
+
+// add records a protocol that timed out, failed or was skipped, and reports whether res
+// holds pools instead
+func (d *Discovery) add(proto pkg.Protocol, res discoveryResult) bool {
+	switch {
+	case res.timedOut:
+		d.TimedOut = append(d.TimedOut, proto)
+	case res.skipped:
+		d.Skipped = append(d.Skipped, proto)
+	case res.err != nil:
+		d.Failed = append(d.Failed, ProtocolFailure{Protocol: proto, Err: res.err})
+	default:
+		return true
+	}
+	return false
+}
+
+// SetPoolRegistry makes discovery fetch the pools a registry knows for a pair by ID instead of
+// scanning for them, and register the pools it scans for. A pool cache set with SetPoolCache
+// discovers through its own registry, see PoolCache.SetRegistry.
+func (r *SimpleRouter) SetPoolRegistry(registry *PoolRegistry) {
+	r.registry = registry
+}
+
+// SetMintInspector makes discovery wrap pools trading Token-2022 mints with transfer fees or
+// transfer hooks, so their quotes are net of the fees and their swaps carry the hook
+// accounts. Nil leaves pools unwrapped.
+func (r *SimpleRouter) SetMintInspector(inspector *sol.MintInspector) {
+	r.mintInspector = inspector
+}
+
+func (r *SimpleRouter) wrapMintExtensions(ctx context.Context, pools []pkg.Pool) ([]pkg.Pool, error) {
+	if r.mintInspector == nil {
+		return pools, nil
+	}
+	wrapped, err := r.mintInspector.WrapPools(ctx, pools)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect pool mints: %w", err)
+	}
+	return wrapped, nil
+}
+
+// discoveryResult is one protocol's answer to discover
+type discoveryResult struct {
+	pools    []pkg.Pool
+	err      error
+	timedOut bool
+	skipped  bool
+}
+
+// discoveryPolicy bounds a discovery: budget for all protocols together, protocolTimeout for
+// each one, and breaker for skipping protocols that keep failing. Zero values disable each.
+type discoveryPolicy struct {
+	budget          time.Duration
+	protocolTimeout time.Duration
+	breaker         *CircuitBreaker
+}
+
+func (r *SimpleRouter) discoveryPolicy() discoveryPolicy {
+	return discoveryPolicy{budget: r.discoveryBudget, protocolTimeout: r.protocolTimeout, breaker: r.breaker}
+}
+
+// discover queries the protocols for the pair concurrently and returns their results in
+// protocol order. Protocols still running after the budget are cancelled and marked timed
+// out; those running past the protocol timeout fail with context.DeadlineExceeded even if
+// they ignore cancellation. Pools known to a non-nil registry are fetched by ID.
+func discover(ctx context.Context, protocols []pkg.Protocol, registry *PoolRegistry, baseMint, quoteMint string, policy discoveryPolicy) []discoveryResult {
+	results := make([]discoveryResult, len(protocols))
+	for i := range results {
+		results[i].timedOut = true
+	}
+	discoverEach(ctx, protocols, registry, baseMint, quoteMint, policy, func(i int, res discoveryResult) {
+		results[i] = res
+	})
+	return results
+}
+
+// discoverEach is discover passing each protocol's result to yield, by protocol index, as
+// soon as it answers. Protocols cut off by the budget are never yielded. yield is called from
+// the calling goroutine.
+func discoverEach(ctx context.Context, protocols []pkg.Protocol, registry *PoolRegistry, baseMint, quoteMint string, policy discoveryPolicy, yield func(int, discoveryResult)) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type answer struct {
+		i   int
+		res discoveryResult
+	}
+	// Buffered so protocols cut off by the budget can still finish and exit
+	answers := make(chan answer, len(protocols))
+	pending := 0
+	for i, proto := range protocols {
+		if !policy.breaker.Allow(proto) {
+			yield(i, discoveryResult{skipped: true})
+			continue
+		}
+		pending++
+		go func(i int, proto pkg.Protocol) {
+			pools, err := fetchWithTimeout(ctx, proto, registry, baseMint, quoteMint, policy.protocolTimeout)
+			policy.breaker.Record(proto, err)
+			answers <- answer{i, discoveryResult{pools: pools, err: err}}
+		}(i, proto)
+	}
+
+	var expired <-chan time.Time
+	if policy.budget > 0 {
+		timer := time.NewTimer(policy.budget)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	for ; pending > 0; pending-- {
+		select {
+		case a := <-answers:
+			yield(a.i, a.res)
+		case <-expired:
+			return
+		}
+	}
+}
+
+// fetchWithTimeout fetches the pair's pools through proto, giving up after timeout when it is
+// positive
+func fetchWithTimeout(ctx context.Context, proto pkg.Protocol, registry *PoolRegistry, baseMint, quoteMint string, timeout time.Duration) ([]pkg.Pool, error) {
+	if timeout <= 0 {
+		return registry.fetchPools(ctx, proto, baseMint, quoteMint)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	type fetched struct {
+		pools []pkg.Pool
+		err   error
+	}
+	ch := make(chan fetched, 1)
+	go func() {
+		pools, err := registry.fetchPools(ctx, proto, baseMint, quoteMint)
+		ch <- fetched{pools, err}
+	}()
+	select {
+	case res := <-ch:
+		return res.pools, res.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("protocol timed out after %s: %w", timeout, ctx.Err())
+	}
+}
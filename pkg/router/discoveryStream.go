@@ -0,0 +1,68 @@
+package router
+
+import (
+	"context"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+)
+
+// QueryAllPoolsStream is QueryAllPools delivering pools on the returned channel as each
+// protocol answers, so quoting can start on the first pools while slower protocols are still
+// scanning. Pools pass the same filters as with QueryAllPools, and with a pool cache they
+// arrive all at once. The channel is closed once every protocol has answered, the discovery
+// budget runs out or ctx is done, and only then are the pair's pools in the router replaced;
+// quote through the router after it closes. A caller that stops reading early must cancel ctx.
+// Failures are logged rather than returned; use DiscoverPools to have them reported.
+func (r *SimpleRouter) QueryAllPoolsStream(ctx context.Context, baseMint, quoteMint string) <-chan pkg.Pool {
+	out := make(chan pkg.Pool)
+	go func() {
+		defer close(out)
+		var found []pkg.Pool
+		// send filters a protocol's pools and delivers them, returning false once ctx is done
+		send := func(pools []pkg.Pool) bool {
+			pools, err := r.applyLiquidityFilter(ctx, r.filterPools(pools), quoteMint)
+			if err == nil {
+				pools, err = r.wrapMintExtensions(ctx, pools)
+			}
+			if err != nil {
+				r.logger.Warn("pool discovery failed", pkg.LogKeyBaseMint, baseMint, pkg.LogKeyQuoteMint, quoteMint, pkg.LogKeyError, err)
+				return ctx.Err() == nil
+			}
+			for _, pool := range pools {
+				select {
+				case out <- pool:
+					found = append(found, pool)
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		}
+
+		if r.poolCache != nil {
+			discovery, err := r.poolCache.get(ctx, baseMint, quoteMint, r.discoveryPolicy())
+			if err != nil {
+				r.logger.Warn("pool discovery failed", pkg.LogKeyBaseMint, baseMint, pkg.LogKeyQuoteMint, quoteMint, pkg.LogKeyError, err)
+				return
+			}
+			if send(discovery.Pools) {
+				linkSuccessors(found)
+				r.pools.set(baseMint, quoteMint, found)
+			}
+			return
+		}
+
+		discoverEach(ctx, r.protocols, r.registry, baseMint, quoteMint, r.discoveryPolicy(), func(i int, res discoveryResult) {
+			if res.err != nil {
+				r.logger.Warn("pool discovery failed", pkg.LogKeyBaseMint, baseMint, pkg.LogKeyQuoteMint, quoteMint, pkg.LogKeyError, res.err)
+				return
+			}
+			if ctx.Err() == nil {
+				send(res.pools)
+			}
+		})
+		linkSuccessors(found)
+		r.pools.set(baseMint, quoteMint, found)
+	}()
+	return out
+}
@@ -0,0 +1,130 @@
+package router
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// LiquidityFilter drops dust and freshly created pools at discovery so they are never quoted.
+// Each check applies only to pools exposing what it needs; zero fields are skipped.
+type LiquidityFilter struct {
+	// MinQuoteLiquidity is the least a pool may be worth in the pair's quote mint, estimated
+	// as twice its quote vault balance. Needs pkg.VaultPool.
+	MinQuoteLiquidity math.Int
+	// MinVaultBalance is the least every vault of a pool must hold, in its mint's base units.
+	// Needs pkg.VaultPool.
+	MinVaultBalance uint64
+	// MaxFeeBps is the highest swap fee accepted. Needs pkg.FeeRatePool.
+	MaxFeeBps uint64
+	// MinAge is how long a pool must have been open. Needs pkg.OpenTimePool.
+	MinAge time.Duration
+}
+
+func (f LiquidityFilter) checksVaults() bool {
+	return f.MinVaultBalance > 0 || (!f.MinQuoteLiquidity.IsNil() && f.MinQuoteLiquidity.IsPositive())
+}
+
+// SetLiquidityFilter makes discovery drop pools failing the filter. Vault balances are read
+// through the client set with SetQuoteClient. A zero filter disables it.
+func (r *SimpleRouter) SetLiquidityFilter(filter LiquidityFilter) {
+	r.liquidityFilter = filter
+}
+
+// applyLiquidityFilter returns the pools that pass the liquidity filter for the pair
+func (r *SimpleRouter) applyLiquidityFilter(ctx context.Context, pools []pkg.Pool, quoteMint string) ([]pkg.Pool, error) {
+	filter := r.liquidityFilter
+	now := r.clock.Now()
+	kept := make([]pkg.Pool, 0, len(pools))
+	for _, pool := range pools {
+		if p, ok := pool.(pkg.FeeRatePool); ok && filter.MaxFeeBps > 0 && p.FeeRateBps() > filter.MaxFeeBps {
+			r.logger.Debug("filtered pool", pkg.LogKeyPool, pool.GetID(), "reason", fmt.Sprintf("fee %d bps", p.FeeRateBps()))
+			continue
+		}
+		if p, ok := pool.(pkg.OpenTimePool); ok && filter.MinAge > 0 && now.Sub(p.OpenedAt()) < filter.MinAge {
+			r.logger.Debug("filtered pool", pkg.LogKeyPool, pool.GetID(), "reason", "opened "+p.OpenedAt().String())
+			continue
+		}
+		kept = append(kept, pool)
+	}
+	if !filter.checksVaults() {
+		return kept, nil
+	}
+
+	solClient, err := r.quoteClientFor(nil)
+	if err != nil {
+		return nil, fmt.Errorf("liquidity filter: %w", err)
+	}
+	balances, err := vaultBalances(ctx, solClient, kept)
+	if err != nil {
+		return nil, err
+	}
+	liquid := make([]pkg.Pool, 0, len(kept))
+	for _, pool := range kept {
+		vaultPool, ok := pool.(pkg.VaultPool)
+		if !ok {
+			liquid = append(liquid, pool)
+			continue
+		}
+		if reason := filter.vaultReason(vaultPool, balances, quoteMint); reason != "" {
+			r.logger.Debug("filtered pool", pkg.LogKeyPool, pool.GetID(), "reason", reason)
+			continue
+		}
+		liquid = append(liquid, pool)
+	}
+	return liquid, nil
+}
+
+// vaultReason explains why the pool's vaults fail the filter, or returns "" when they pass
+func (f LiquidityFilter) vaultReason(pool pkg.VaultPool, balances map[solana.PublicKey]uint64, quoteMint string) string {
+	for _, vault := range pool.TokenVaults() {
+		balance := balances[vault.Address]
+		if balance < f.MinVaultBalance {
+			return fmt.Sprintf("vault %s holds %d", vault.Address, balance)
+		}
+		if vault.Mint.String() != quoteMint || f.MinQuoteLiquidity.IsNil() {
+			continue
+		}
+		if liquidity := math.NewIntFromUint64(balance).MulRaw(2); liquidity.LT(f.MinQuoteLiquidity) {
+			return fmt.Sprintf("liquidity %s of %s", liquidity, quoteMint)
+		}
+	}
+	return ""
+}
+
+// vaultBalances reads the token balance of every vault of the pools in batches. Missing
+// vaults read as empty.
+func vaultBalances(ctx context.Context, solClient pkg.RPC, pools []pkg.Pool) (map[solana.PublicKey]uint64, error) {
+	var addrs []solana.PublicKey
+	for _, pool := range pools {
+		if vaultPool, ok := pool.(pkg.VaultPool); ok {
+			for _, vault := range vaultPool.TokenVaults() {
+				addrs = append(addrs, vault.Address)
+			}
+		}
+	}
+	balances := make(map[solana.PublicKey]uint64, len(addrs))
+	for start := 0; start < len(addrs); start += maxAccountsPerRequest {
+		end := min(start+maxAccountsPerRequest, len(addrs))
+		results, err := solClient.GetMultipleAccountsWithOpts(ctx, addrs[start:end], sol.MultipleAccountsOpts(ctx, rpc.CommitmentProcessed))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get vault accounts: %w", err)
+		}
+		for i, account := range results.Value {
+			if account == nil {
+				continue
+			}
+			if data := account.Data.GetBinary(); len(data) >= 72 {
+				balances[addrs[start+i]] = binary.LittleEndian.Uint64(data[64:72])
+			}
+		}
+	}
+	return balances, nil
+}
@@ -0,0 +1,78 @@
+package router
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+)
+
+// pairPools holds the pools last discovered for each token pair, independent of mint order.
+// A pair's pools are replaced as a whole on rediscovery, never modified in place, so a quote
+// holding them keeps a consistent view while the pair is rediscovered concurrently.
+type pairPools struct {
+	mu    sync.RWMutex
+	pairs map[string][]pkg.Pool
+}
+
+func newPairPools() *pairPools {
+	return &pairPools{pairs: make(map[string][]pkg.Pool)}
+}
+
+// get returns the pair's pools. The slice must not be modified.
+func (p *pairPools) get(mintA, mintB string) []pkg.Pool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.pairs[pairKey(mintA, mintB)]
+}
+
+// set replaces the pair's pools
+func (p *pairPools) set(mintA, mintB string, pools []pkg.Pool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pairs[pairKey(mintA, mintB)] = pools
+}
+
+// counterparts returns the mints paired with mint in pairs with pools, sorted
+func (p *pairPools) counterparts(mint string) []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var mints []string
+	for key, pools := range p.pairs {
+		base, quote, _ := strings.Cut(key, "/")
+		if len(pools) == 0 {
+			continue
+		}
+		switch mint {
+		case base:
+			mints = append(mints, quote)
+		case quote:
+			mints = append(mints, base)
+		}
+	}
+	sort.Strings(mints)
+	return mints
+}
+
+// filter returns a copy holding the pools keep allows
+func (p *pairPools) filter(keep func(pkg.Pool) bool) *pairPools {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	filtered := newPairPools()
+	for key, pools := range p.pairs {
+		kept := make([]pkg.Pool, 0, len(pools))
+		for _, pool := range pools {
+			if keep(pool) {
+				kept = append(kept, pool)
+			}
+		}
+		filtered.pairs[key] = kept
+	}
+	return filtered
+}
+
+// Pools returns the pools last discovered for the pair, in either mint order
+func (r *SimpleRouter) Pools(baseMint, quoteMint string) []pkg.Pool {
+	return append([]pkg.Pool(nil), r.pools.get(baseMint, quoteMint)...)
+}
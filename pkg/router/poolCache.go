@@ -0,0 +1,190 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gagliardetto/solana-go"
+)
+
+// DefaultPoolCacheTTL is how long discovered pools are reused before being fetched again
+const DefaultPoolCacheTTL = 30 * time.Second
+
+type poolCacheEntry struct {
+	baseMint  string
+	quoteMint string
+	pools     []pkg.Pool
+	fetchedAt time.Time
+}
+
+// PoolCache caches the pools discovered for each token pair so repeated quotes
+// don't re-run getProgramAccounts. Cached pools are shared between callers and
+// refresh their own reserves when quoted.
+type PoolCache struct {
+	protocols []pkg.Protocol
+	ttl       time.Duration
+	clock     clock.Clock
+	logger    pkg.Logger
+	registry  *PoolRegistry
+
+	mu      sync.Mutex
+	entries map[string]*poolCacheEntry
+	// owners remembers which protocol produced each kind of pool so single pools can be re-fetched
+	owners map[pkg.ProtocolName]pkg.Protocol
+	// streamSlots is the slot of the last streamed update applied to each pool
+	streamSlots map[solana.PublicKey]uint64
+}
+
+// NewPoolCache creates a cache that discovers pools through the given protocols
+func NewPoolCache(ttl time.Duration, protocols ...pkg.Protocol) *PoolCache {
+	if ttl <= 0 {
+		ttl = DefaultPoolCacheTTL
+	}
+	return &PoolCache{
+		protocols: protocols,
+		ttl:       ttl,
+		clock:     clock.System{},
+		logger:    slog.Default(),
+		entries:   make(map[string]*poolCacheEntry),
+		owners:    make(map[pkg.ProtocolName]pkg.Protocol),
+	}
+}
+
+// SetClock replaces the time source used for TTL expiry and, when it implements
+// clock.Ticker, for StartRefresh's interval
+func (c *PoolCache) SetClock(clk clock.Clock) {
+	c.clock = clk
+}
+
+// SetLogger sets where background refresh failures are reported. Nil restores slog's
+// default logger.
+func (c *PoolCache) SetLogger(logger pkg.Logger) {
+	c.logger = pkg.LoggerOrDefault(logger)
+}
+
+// SetRegistry makes the cache fetch the pools a registry knows for a pair by ID instead of
+// scanning for them, and register the pools it scans for
+func (c *PoolCache) SetRegistry(registry *PoolRegistry) {
+	c.registry = registry
+}
+
+// pairKey is independent of mint order since protocols fetch both directions
+func pairKey(baseMint, quoteMint string) string {
+	if baseMint > quoteMint {
+		baseMint, quoteMint = quoteMint, baseMint
+	}
+	return baseMint + "/" + quoteMint
+}
+
+// Get returns the cached pools for the pair, fetching them if missing or expired
+func (c *PoolCache) Get(ctx context.Context, baseMint, quoteMint string) ([]pkg.Pool, error) {
+	discovery, err := c.get(ctx, baseMint, quoteMint, discoveryPolicy{})
+	return discovery.Pools, err
+}
+
+// get is Get under a discovery policy, also reporting the protocols that timed out, failed
+// or were skipped. Pools fetched without the protocols cut off by the budget or skipped by
+// the breaker aren't cached.
+func (c *PoolCache) get(ctx context.Context, baseMint, quoteMint string, policy discoveryPolicy) (Discovery, error) {
+	key := pairKey(baseMint, quoteMint)
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && c.clock.Now().Sub(entry.fetchedAt) < c.ttl {
+		return Discovery{Pools: append([]pkg.Pool(nil), entry.pools...)}, nil
+	}
+
+	discovery, err := c.fetch(ctx, baseMint, quoteMint, policy)
+	if err != nil {
+		return Discovery{}, err
+	}
+	if len(discovery.TimedOut) == 0 && len(discovery.Skipped) == 0 {
+		c.mu.Lock()
+		c.entries[key] = &poolCacheEntry{baseMint: baseMint, quoteMint: quoteMint, pools: discovery.Pools, fetchedAt: c.clock.Now()}
+		c.mu.Unlock()
+	}
+	discovery.Pools = append([]pkg.Pool(nil), discovery.Pools...)
+	return discovery, nil
+}
+
+// fetch queries every protocol for the pair, skipping protocols that fail or run past the
+// budget. It only errors when all protocols fail so an outage isn't cached as an empty pair.
+func (c *PoolCache) fetch(ctx context.Context, baseMint, quoteMint string, policy discoveryPolicy) (Discovery, error) {
+	discovery := Discovery{Pools: []pkg.Pool{}}
+	for i, res := range discover(ctx, c.protocols, c.registry, baseMint, quoteMint, policy) {
+		proto := c.protocols[i]
+		if !discovery.add(proto, res) {
+			continue
+		}
+		c.mu.Lock()
+		for _, pool := range res.pools {
+			c.owners[pool.ProtocolName()] = proto
+		}
+		c.mu.Unlock()
+		discovery.Pools = append(discovery.Pools, res.pools...)
+	}
+	if failed := len(discovery.Failed); failed > 0 && failed == len(c.protocols) {
+		return Discovery{}, fmt.Errorf("failed to fetch pools for %s/%s: %w", baseMint, quoteMint, discovery.Failed[failed-1].Err)
+	}
+	return discovery, nil
+}
+
+// Invalidate drops the cached pools for the pair
+func (c *PoolCache) Invalidate(baseMint, quoteMint string) {
+	c.mu.Lock()
+	delete(c.entries, pairKey(baseMint, quoteMint))
+	c.mu.Unlock()
+}
+
+// InvalidateAll drops every cached pair
+func (c *PoolCache) InvalidateAll() {
+	c.mu.Lock()
+	c.entries = make(map[string]*poolCacheEntry)
+	c.mu.Unlock()
+}
+
+// StartRefresh re-fetches every cached pair at the given interval until ctx is done.
+// Pairs that fail to refresh keep their previous pools.
+func (c *PoolCache) StartRefresh(ctx context.Context, interval time.Duration) {
+	ticks, stop := clock.NewTicker(c.clock, interval)
+	go func() {
+		defer stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticks:
+				c.refresh(ctx)
+			}
+		}
+	}()
+}
+
+func (c *PoolCache) refresh(ctx context.Context) {
+	c.mu.Lock()
+	pairs := make([]*poolCacheEntry, 0, len(c.entries))
+	for _, entry := range c.entries {
+		pairs = append(pairs, entry)
+	}
+	c.mu.Unlock()
+
+	for _, pair := range pairs {
+		discovery, err := c.fetch(ctx, pair.baseMint, pair.quoteMint, discoveryPolicy{})
+		if err != nil {
+			c.logger.Warn("pool refresh failed", pkg.LogKeyBaseMint, pair.baseMint, pkg.LogKeyQuoteMint, pair.quoteMint, pkg.LogKeyError, err)
+			continue
+		}
+		key := pairKey(pair.baseMint, pair.quoteMint)
+		c.mu.Lock()
+		// Skip pairs invalidated while the refresh was in flight
+		if _, ok := c.entries[key]; ok {
+			c.entries[key] = &poolCacheEntry{baseMint: pair.baseMint, quoteMint: pair.quoteMint, pools: discovery.Pools, fetchedAt: c.clock.Now()}
+		}
+		c.mu.Unlock()
+	}
+}
@@ -0,0 +1,222 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+)
+
+// poolRegistryVersion is bumped when the snapshot format changes; other versions are ignored
+const poolRegistryVersion = 1
+
+// RegisteredPool is a discovered pool as persisted by PoolRegistry
+type RegisteredPool struct {
+	ID        string `json:"id"`
+	ProgramID string `json:"programId"`
+	BaseMint  string `json:"baseMint"`
+	QuoteMint string `json:"quoteMint"`
+}
+
+// registeredPair is what one protocol found for a pair. An empty Pools records that the
+// protocol has no pool for the pair, which also saves the scan.
+type registeredPair struct {
+	BaseMint     string           `json:"baseMint"`
+	QuoteMint    string           `json:"quoteMint"`
+	Protocol     pkg.ProtocolName `json:"protocol"`
+	Pools        []RegisteredPool `json:"pools"`
+	DiscoveredAt time.Time        `json:"discoveredAt"`
+}
+
+type poolRegistrySnapshot struct {
+	Version int              `json:"version"`
+	Pairs   []registeredPair `json:"pairs"`
+}
+
+// PoolRegistry remembers which pools each protocol found for a pair and persists them to a
+// JSON file, so after a restart discovery fetches the known pools by ID instead of scanning
+// with getProgramAccounts. Only protocols implementing pkg.NamedProtocol with a non-empty
+// name are recorded. It is safe for concurrent use.
+type PoolRegistry struct {
+	path   string
+	maxAge time.Duration
+	clock  clock.Clock
+
+	mu    sync.Mutex
+	pairs map[string]registeredPair // by pairKey and protocol
+}
+
+// NewPoolRegistry returns a registry persisted at path. Call Load to read an earlier
+// snapshot and Save to write one.
+func NewPoolRegistry(path string) *PoolRegistry {
+	return &PoolRegistry{path: path, clock: clock.System{}, pairs: make(map[string]registeredPair)}
+}
+
+// SetMaxAge makes pairs discovered longer ago than d be scanned again, picking up pools
+// created since. Zero, the default, keeps pairs until they are forgotten.
+func (r *PoolRegistry) SetMaxAge(d time.Duration) {
+	r.maxAge = d
+}
+
+// SetClock replaces the time source that stamps discovered pairs and ages them
+func (r *PoolRegistry) SetClock(clk clock.Clock) {
+	r.clock = clk
+}
+
+func registryKey(baseMint, quoteMint string, protocol pkg.ProtocolName) string {
+	return pairKey(baseMint, quoteMint) + "/" + string(protocol)
+}
+
+// Load reads the snapshot at the registry's path, replacing what is registered. A missing
+// file or a snapshot of another version leaves the registry empty.
+func (r *PoolRegistry) Load() error {
+	data, err := os.ReadFile(r.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read pool registry: %w", err)
+	}
+	var snapshot poolRegistrySnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to decode pool registry %s: %w", r.path, err)
+	}
+	pairs := make(map[string]registeredPair, len(snapshot.Pairs))
+	if snapshot.Version == poolRegistryVersion {
+		for _, pair := range snapshot.Pairs {
+			pairs[registryKey(pair.BaseMint, pair.QuoteMint, pair.Protocol)] = pair
+		}
+	}
+	r.mu.Lock()
+	r.pairs = pairs
+	r.mu.Unlock()
+	return nil
+}
+
+// Save writes the registry to its path. The file is replaced atomically so a crash mid-write
+// leaves the previous snapshot.
+func (r *PoolRegistry) Save() error {
+	r.mu.Lock()
+	snapshot := poolRegistrySnapshot{Version: poolRegistryVersion, Pairs: make([]registeredPair, 0, len(r.pairs))}
+	for _, pair := range r.pairs {
+		snapshot.Pairs = append(snapshot.Pairs, pair)
+	}
+	r.mu.Unlock()
+	sort.Slice(snapshot.Pairs, func(i, j int) bool {
+		a, b := snapshot.Pairs[i], snapshot.Pairs[j]
+		return registryKey(a.BaseMint, a.QuoteMint, a.Protocol) < registryKey(b.BaseMint, b.QuoteMint, b.Protocol)
+	})
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write pool registry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write pool registry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write pool registry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("failed to write pool registry: %w", err)
+	}
+	return nil
+}
+
+// Record registers the pools a protocol discovered for a pair, replacing earlier ones
+func (r *PoolRegistry) Record(protocol pkg.ProtocolName, baseMint, quoteMint string, pools []pkg.Pool) {
+	registered := make([]RegisteredPool, 0, len(pools))
+	for _, pool := range pools {
+		base, quote := pool.GetTokens()
+		registered = append(registered, RegisteredPool{
+			ID:        pool.GetID(),
+			ProgramID: pool.GetProgramID().String(),
+			BaseMint:  base,
+			QuoteMint: quote,
+		})
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pairs[registryKey(baseMint, quoteMint, protocol)] = registeredPair{
+		BaseMint:     baseMint,
+		QuoteMint:    quoteMint,
+		Protocol:     protocol,
+		Pools:        registered,
+		DiscoveredAt: r.clock.Now(),
+	}
+}
+
+// Lookup returns the pools registered for a protocol and pair, and whether the pair is
+// registered for it and not older than the max age
+func (r *PoolRegistry) Lookup(protocol pkg.ProtocolName, baseMint, quoteMint string) ([]RegisteredPool, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pair, ok := r.pairs[registryKey(baseMint, quoteMint, protocol)]
+	if !ok || (r.maxAge > 0 && r.clock.Now().Sub(pair.DiscoveredAt) > r.maxAge) {
+		return nil, false
+	}
+	return append([]RegisteredPool(nil), pair.Pools...), true
+}
+
+// Forget drops what is registered for the pair, so the next discovery scans for it
+func (r *PoolRegistry) Forget(baseMint, quoteMint string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, pair := range r.pairs {
+		if pairKey(pair.BaseMint, pair.QuoteMint) == pairKey(baseMint, quoteMint) {
+			delete(r.pairs, key)
+		}
+	}
+}
+
+// Len returns how many protocol and pair combinations are registered
+func (r *PoolRegistry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.pairs)
+}
+
+// fetchPools discovers a protocol's pools for the pair. When the registry knows them they
+// are fetched by ID; otherwise, or if any of them can't be fetched, the protocol scans and
+// the result is registered.
+func (r *PoolRegistry) fetchPools(ctx context.Context, proto pkg.Protocol, baseMint, quoteMint string) ([]pkg.Pool, error) {
+	named, ok := proto.(pkg.NamedProtocol)
+	if r == nil || !ok || named.Name() == "" {
+		return proto.FetchPoolsByPair(ctx, baseMint, quoteMint)
+	}
+	if registered, ok := r.Lookup(named.Name(), baseMint, quoteMint); ok {
+		pools := make([]pkg.Pool, 0, len(registered))
+		for _, entry := range registered {
+			pool, err := proto.FetchPoolByID(ctx, entry.ID)
+			if err != nil {
+				pools = nil
+				break
+			}
+			pools = append(pools, pool)
+		}
+		if pools != nil {
+			return pools, nil
+		}
+	}
+	pools, err := proto.FetchPoolsByPair(ctx, baseMint, quoteMint)
+	if err != nil {
+		return nil, err
+	}
+	r.Record(named.Name(), baseMint, quoteMint, pools)
+	return pools, nil
+}
@@ -0,0 +1,121 @@
+package router
+
+import (
+	"sync"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"cosmossdk.io/math"
+)
+
+// poolFailureDecay is the weight of the latest outcome in a pool's failure rate
+const poolFailureDecay = 0.1
+
+// PoolCandidate is a pool that quoted successfully, with what a PoolScorer weighs it by
+type PoolCandidate struct {
+	Pool      pkg.Pool
+	AmountOut math.Int
+	// FeeBps is the pool's fee rate when it implements pkg.FeeRatePool
+	FeeBps   uint64
+	FeeKnown bool
+	// ComputeUnits is the estimated cost of swapping through the pool, zero without a
+	// ComputeUnitModel
+	ComputeUnits uint32
+	// FailureRate is the recent share of failed quotes and swaps through the pool, from 0 to 1
+	FailureRate float64
+	// CostOut is the swap's lamport costs in output base units, zero without SwapCosts
+	CostOut math.Int
+}
+
+// PoolScorer ranks quoted pools; GetBestPool picks the highest score, ties going to the
+// earlier pool
+type PoolScorer interface {
+	Score(candidate PoolCandidate) float64
+}
+
+// PoolScorerFunc adapts a function to PoolScorer
+type PoolScorerFunc func(candidate PoolCandidate) float64
+
+func (f PoolScorerFunc) Score(candidate PoolCandidate) float64 {
+	return f(candidate)
+}
+
+// WeightedScorer scores a pool by its output less penalties and swap costs, all in output
+// base units. The zero value scores by output net of swap costs.
+type WeightedScorer struct {
+	// FailureWeight discounts the output by the pool's failure rate: at 1 a pool failing 10%
+	// of the time loses 10% of its output
+	FailureWeight float64
+	// FeeWeight discounts the output by the pool's fee rate on top of the fee already taken
+	// from the quote, favouring cheaper pools for near-equal outputs
+	FeeWeight float64
+	// ComputeUnitCost is what one compute unit costs in output base units
+	ComputeUnitCost float64
+}
+
+func (s WeightedScorer) Score(c PoolCandidate) float64 {
+	out, _ := c.AmountOut.ToLegacyDec().Float64()
+	score := out * (1 - s.FailureWeight*c.FailureRate)
+	if c.FeeKnown {
+		score -= out * s.FeeWeight * float64(c.FeeBps) / 10000
+	}
+	if !c.CostOut.IsNil() {
+		cost, _ := c.CostOut.ToLegacyDec().Float64()
+		score -= cost
+	}
+	return score - s.ComputeUnitCost*float64(c.ComputeUnits)
+}
+
+// SetPoolScorer makes GetBestPool pick the pool with the highest score instead of the largest
+// output. Nil restores picking by output.
+func (r *SimpleRouter) SetPoolScorer(scorer PoolScorer) {
+	r.scorer = scorer
+}
+
+// RecordSwapResult feeds the outcome of a swap through a pool into its failure rate, next to
+// the quote failures the router records itself
+func (r *SimpleRouter) RecordSwapResult(poolID string, err error) {
+	r.health.record(poolID, err != nil)
+}
+
+// PoolFailureRate returns the recent share of failed quotes and swaps through the pool
+func (r *SimpleRouter) PoolFailureRate(poolID string) float64 {
+	return r.health.rate(poolID)
+}
+
+// candidate collects what a scorer weighs the pool by
+func (r *SimpleRouter) candidate(pool pkg.Pool, out math.Int) PoolCandidate {
+	c := PoolCandidate{Pool: pool, AmountOut: out, FailureRate: r.health.rate(pool.GetID())}
+	if feePool, ok := pool.(pkg.FeeRatePool); ok {
+		c.FeeBps, c.FeeKnown = feePool.FeeRateBps(), true
+	}
+	if r.computeUnits != nil {
+		c.ComputeUnits = r.computeUnits.Estimate(pool.ProtocolName())
+	}
+	return c
+}
+
+// poolHealth tracks an exponentially decaying failure rate per pool. It is shared by pointer
+// between a router and the routers derived from it.
+type poolHealth struct {
+	mu    sync.Mutex
+	rates map[string]float64
+}
+
+func (h *poolHealth) record(poolID string, failed bool) {
+	outcome := 0.0
+	if failed {
+		outcome = 1
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.rates == nil {
+		h.rates = make(map[string]float64)
+	}
+	h.rates[poolID] = h.rates[poolID]*(1-poolFailureDecay) + outcome*poolFailureDecay
+}
+
+func (h *poolHealth) rate(poolID string) float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.rates[poolID]
+}
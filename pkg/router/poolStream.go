@@ -0,0 +1,118 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gagliardetto/solana-go"
+)
+
+// Stream keeps cached pools current from an account stream, such as a WSAccountStream or a
+// yellowstone.Client, instead of waiting for the TTL. Updates to a cached pool's state account
+// replace the pool with one decoded from them when it implements pkg.DecodablePool; updates
+// older than the last applied one are dropped. With no programs given it follows the programs of the pools
+// cached at the time of the call. It returns once subscribed and stops when ctx is done or
+// the stream closes.
+func (c *PoolCache) Stream(ctx context.Context, stream sol.AccountStream, programs ...solana.PublicKey) error {
+	if len(programs) == 0 {
+		programs = c.cachedPrograms()
+	}
+	if len(programs) == 0 {
+		return fmt.Errorf("no programs to stream")
+	}
+	updates, err := stream.Subscribe(ctx, programs)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to pool accounts: %w", err)
+	}
+	go func() {
+		for update := range updates {
+			c.ApplyUpdate(update)
+		}
+	}()
+	return nil
+}
+
+// ApplyUpdate decodes an account update into copies of the cached pools with its address and
+// caches the copies in their place, reporting whether any was replaced. Pools already handed
+// out are left as they were, so quotes holding them never see a half-decoded state; routers
+// pick the update up on their next discovery. Updates from a slot before the last one applied
+// are ignored. A pool that fails to decode is dropped with its pair so the next Get fetches
+// it again.
+func (c *PoolCache) ApplyUpdate(update sol.AccountUpdate) bool {
+	id := update.Pubkey.String()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if update.Slot < c.streamSlots[update.Pubkey] {
+		return false
+	}
+	applied := false
+	for key, entry := range c.entries {
+		for i, pool := range entry.pools {
+			decodable, ok := pool.(pkg.DecodablePool)
+			if !ok || pool.GetID() != id {
+				continue
+			}
+			fresh, err := decodeCopy(decodable, update.Data)
+			if err == nil && fresh.GetID() != id {
+				err = fmt.Errorf("decoded pool has ID %s", fresh.GetID())
+			}
+			if err != nil {
+				c.logger.Warn("failed to apply streamed pool update", pkg.LogKeyPool, id, pkg.LogKeyError, err)
+				delete(c.entries, key)
+				break
+			}
+			// The entry's slice may be held by a router, so it is replaced rather than written
+			pools := slices.Clone(entry.pools)
+			pools[i] = fresh
+			c.entries[key] = &poolCacheEntry{baseMint: entry.baseMint, quoteMint: entry.quoteMint, pools: pools, fetchedAt: entry.fetchedAt}
+			entry = c.entries[key]
+			applied = true
+		}
+	}
+	if applied {
+		if c.streamSlots == nil {
+			c.streamSlots = make(map[solana.PublicKey]uint64)
+		}
+		c.streamSlots[update.Pubkey] = update.Slot
+	}
+	return applied
+}
+
+// decodeCopy decodes data into a shallow copy of pool, taken under the pool's lock so no
+// quote refreshes it midway. The copy keeps the pool's ID and the caches it shares with it.
+func decodeCopy(pool pkg.DecodablePool, data []byte) (pkg.Pool, error) {
+	v := reflect.ValueOf(pool)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return nil, fmt.Errorf("cannot copy pool of type %T", pool)
+	}
+	unlock := pkg.LockPool(pool)
+	defer unlock()
+	fresh := reflect.New(v.Elem().Type())
+	fresh.Elem().Set(v.Elem())
+	decodable := fresh.Interface().(pkg.DecodablePool)
+	if err := decodable.Decode(data); err != nil {
+		return nil, err
+	}
+	return decodable, nil
+}
+
+// cachedPrograms returns the distinct programs of the cached pools
+func (c *PoolCache) cachedPrograms() []solana.PublicKey {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	seen := make(map[solana.PublicKey]bool)
+	var programs []solana.PublicKey
+	for _, entry := range c.entries {
+		for _, pool := range entry.pools {
+			if program := pool.GetProgramID(); !seen[program] {
+				seen[program] = true
+				programs = append(programs, program)
+			}
+		}
+	}
+	return programs
+}
@@ -0,0 +1,195 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// maxAccountsPerRequest is the getMultipleAccounts limit
+const maxAccountsPerRequest = 100
+
+// IssueSeverity ranks how badly a pool failed validation
+type IssueSeverity string
+
+const (
+	// SeverityBroken pools can't be decoded or traded safely and should be dropped
+	SeverityBroken IssueSeverity = "broken"
+	// SeveritySuspicious pools still decode but look wrong and need a closer look
+	SeveritySuspicious IssueSeverity = "suspicious"
+)
+
+// PoolIssue is a failed check on a cached pool
+type PoolIssue struct {
+	PoolID   string
+	Protocol pkg.ProtocolName
+	Severity IssueSeverity
+	Check    string // account, decode, tokens, vaults, deprecated or quote
+	Detail   string
+}
+
+// ValidationReport lists the problems found by PoolCache.Validate
+type ValidationReport struct {
+	Checked int
+	Issues  []PoolIssue
+}
+
+// Broken returns the issues that make a pool unusable
+func (r ValidationReport) Broken() []PoolIssue {
+	broken := make([]PoolIssue, 0)
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityBroken {
+			broken = append(broken, issue)
+		}
+	}
+	return broken
+}
+
+// String renders the report one issue per line
+func (r ValidationReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "checked %d pools, %d issues (%d broken)\n", r.Checked, len(r.Issues), len(r.Broken()))
+	for _, issue := range r.Issues {
+		fmt.Fprintf(&b, "%-10s %-14s %-44s %-10s %s\n", issue.Severity, issue.Protocol, issue.PoolID, issue.Check, issue.Detail)
+	}
+	return b.String()
+}
+
+// ValidateOptions tunes PoolCache.Validate
+type ValidateOptions struct {
+	// ProbeAmount is quoted from each pool's base mint; nil or zero skips the quote check
+	ProbeAmount math.Int
+}
+
+// Pools returns every cached pool across all pairs
+func (c *PoolCache) Pools() []pkg.Pool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pools := make([]pkg.Pool, 0)
+	for _, entry := range c.entries {
+		pools = append(pools, entry.pools...)
+	}
+	return pools
+}
+
+// Validate re-fetches every cached pool and reports the ones that no longer decode, changed
+// owner or mints, fail vault verification, are deprecated or can't be quoted. Run it after a
+// DEX program upgrade to find pools whose layout the decoders no longer match.
+// Pools that re-fetch successfully replace their cached copy.
+func (c *PoolCache) Validate(ctx context.Context, solClient pkg.RPC, opts ValidateOptions) (ValidationReport, error) {
+	pools := c.Pools()
+	report := ValidationReport{Checked: len(pools)}
+	issue := func(pool pkg.Pool, severity IssueSeverity, check, format string, args ...any) {
+		report.Issues = append(report.Issues, PoolIssue{
+			PoolID:   pool.GetID(),
+			Protocol: pool.ProtocolName(),
+			Severity: severity,
+			Check:    check,
+			Detail:   fmt.Sprintf(format, args...),
+		})
+	}
+
+	owners, err := poolAccountOwners(ctx, solClient, pools)
+	if err != nil {
+		return report, err
+	}
+
+	for i, pool := range pools {
+		owner, ok := owners[i]
+		if !ok {
+			issue(pool, SeverityBroken, "account", "pool account not found")
+			continue
+		}
+		if !owner.Equals(pool.GetProgramID()) {
+			issue(pool, SeverityBroken, "account", "owned by %s, expected %s", owner, pool.GetProgramID())
+			continue
+		}
+
+		c.mu.Lock()
+		proto := c.owners[pool.ProtocolName()]
+		c.mu.Unlock()
+		if proto == nil {
+			issue(pool, SeveritySuspicious, "decode", "no protocol to re-fetch the pool with")
+			continue
+		}
+		fresh, err := proto.FetchPoolByID(ctx, pool.GetID())
+		if err != nil {
+			issue(pool, SeverityBroken, "decode", "%v", err)
+			continue
+		}
+		oldBase, oldQuote := pool.GetTokens()
+		newBase, newQuote := fresh.GetTokens()
+		if oldBase != newBase || oldQuote != newQuote {
+			issue(pool, SeverityBroken, "tokens", "mints changed from %s/%s to %s/%s", oldBase, oldQuote, newBase, newQuote)
+			continue
+		}
+		c.replace(fresh)
+
+		if err := sol.VerifyPoolVaults(ctx, solClient, fresh); err != nil {
+			issue(pool, SeverityBroken, "vaults", "%v", err)
+			continue
+		}
+		if d := deprecationOf(fresh); d != nil {
+			issue(pool, SeveritySuspicious, "deprecated", "%s", d.Reason)
+			continue
+		}
+		if !opts.ProbeAmount.IsNil() && opts.ProbeAmount.IsPositive() {
+			amountOut, err := fresh.Quote(ctx, solClient, newBase, opts.ProbeAmount)
+			if err != nil {
+				issue(pool, SeveritySuspicious, "quote", "%v", err)
+			} else if !amountOut.IsPositive() {
+				issue(pool, SeveritySuspicious, "quote", "quoting %s of %s returned %s", opts.ProbeAmount, newBase, amountOut)
+			}
+		}
+	}
+	return report, nil
+}
+
+// poolAccountOwners returns the owning program of each pool account by index; missing
+// accounts and unparsable IDs are left out
+func poolAccountOwners(ctx context.Context, solClient pkg.RPC, pools []pkg.Pool) (map[int]solana.PublicKey, error) {
+	owners := make(map[int]solana.PublicKey, len(pools))
+	indexes := make([]int, 0, len(pools))
+	keys := make([]solana.PublicKey, 0, len(pools))
+	for i, pool := range pools {
+		key, err := solana.PublicKeyFromBase58(pool.GetID())
+		if err != nil {
+			continue
+		}
+		indexes = append(indexes, i)
+		keys = append(keys, key)
+	}
+
+	for start := 0; start < len(keys); start += maxAccountsPerRequest {
+		end := min(start+maxAccountsPerRequest, len(keys))
+		results, err := solClient.GetMultipleAccountsWithOpts(ctx, keys[start:end], sol.MultipleAccountsOpts(ctx, rpc.CommitmentProcessed))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pool accounts: %w", err)
+		}
+		for j, account := range results.Value {
+			if account != nil {
+				owners[indexes[start+j]] = account.Owner
+			}
+		}
+	}
+	return owners, nil
+}
+
+// replace swaps the cached copy of a pool for a freshly fetched one with the same ID
+func (c *PoolCache) replace(pool pkg.Pool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, entry := range c.entries {
+		for i, cached := range entry.pools {
+			if cached.GetID() == pool.GetID() && cached.ProtocolName() == pool.ProtocolName() {
+				entry.pools[i] = pool
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/price"
+	cosmath "cosmossdk.io/math"
+)
+
+// DefaultMaxOracleDeviationBps is the deviation from the oracle price a PriceGuard allows
+// unless told otherwise, enough for the fees and price impact of ordinary swaps
+const DefaultMaxOracleDeviationBps = 300
+
+// ErrOracleDeviation is returned for quotes whose price strays too far from the oracle's
+var ErrOracleDeviation = errors.New("quote deviates from oracle price")
+
+// PriceGuard rejects quotes whose execution price deviates from an oracle's price of the
+// pair, protecting against pools that were manipulated or went stale
+type PriceGuard struct {
+	oracle          price.Oracle
+	maxDeviationBps float64
+}
+
+// NewPriceGuard creates a guard allowing maxDeviationBps between a quote's price and the
+// oracle's, either way. The bound includes the swap's fees and price impact.
+func NewPriceGuard(oracle price.Oracle, maxDeviationBps float64) *PriceGuard {
+	return &PriceGuard{oracle: oracle, maxDeviationBps: maxDeviationBps}
+}
+
+// SetPriceGuard makes the router skip pools whose quotes the guard rejects. Routes fail when
+// the oracle price can't be read, stale feeds included. Nil removes the guard.
+func (r *SimpleRouter) SetPriceGuard(guard *PriceGuard) {
+	r.priceGuard = guard
+}
+
+// boundPriceGuard is a guard with the oracle price and decimals of one request's pair
+type boundPriceGuard struct {
+	guard          *PriceGuard
+	reference      price.OraclePrice
+	decimalsOut    uint8
+	tokenIn        string
+	tokenOut       string
+	amountInScaled float64
+}
+
+// bindPriceGuard reads the oracle price of tokenIn in tokenOut and the mints' decimals, or
+// returns nil without a guard
+func (r *SimpleRouter) bindPriceGuard(ctx context.Context, solClient pkg.RPC, tokenIn, tokenOut string, amountIn cosmath.Int) (*boundPriceGuard, error) {
+	if r.priceGuard == nil {
+		return nil, nil
+	}
+	reference, err := r.priceGuard.oracle.Price(ctx, tokenIn, tokenOut)
+	if err != nil {
+		return nil, fmt.Errorf("price guard: failed to get oracle price of %s in %s: %w", tokenIn, tokenOut, err)
+	}
+	tokens, err := r.tokenInfos(ctx, solClient, tokenIn, tokenOut)
+	if err != nil {
+		return nil, fmt.Errorf("price guard: %w", err)
+	}
+	in, _ := cosmath.LegacyNewDecFromIntWithPrec(amountIn, int64(tokens[0].Decimals)).Float64()
+	return &boundPriceGuard{
+		guard:          r.priceGuard,
+		reference:      reference,
+		decimalsOut:    tokens[1].Decimals,
+		tokenIn:        tokenIn,
+		tokenOut:       tokenOut,
+		amountInScaled: in,
+	}, nil
+}
+
+// check returns ErrOracleDeviation when amountOut prices the swap too far from the oracle
+func (g *boundPriceGuard) check(amountOut cosmath.Int) error {
+	if g == nil || g.amountInScaled <= 0 {
+		return nil
+	}
+	out, _ := cosmath.LegacyNewDecFromIntWithPrec(amountOut, int64(g.decimalsOut)).Float64()
+	executed := out / g.amountInScaled
+	deviation := (executed - g.reference.Price) / g.reference.Price * 10_000
+	if math.Abs(deviation) > g.guard.maxDeviationBps {
+		return fmt.Errorf("%w: %g %s per %s is %.0f bps from %g", ErrOracleDeviation, executed, g.tokenOut, g.tokenIn, deviation, g.reference.Price)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+)
+
+// ErrProtocolDisabled is returned for pools of a protocol paused with SetProtocolEnabled
+var ErrProtocolDisabled = solerrors.Mark(errors.New("protocol disabled"), solerrors.ErrPoolDisabled)
+
+// protocolSwitches holds the protocols paused at runtime. It is shared by pointer between a
+// router and the routers derived from it, so pausing a venue takes effect everywhere at once.
+type protocolSwitches struct {
+	mu       sync.RWMutex
+	disabled map[pkg.ProtocolName]bool
+}
+
+func (s *protocolSwitches) set(name pkg.ProtocolName, enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if enabled {
+		delete(s.disabled, name)
+		return
+	}
+	if s.disabled == nil {
+		s.disabled = make(map[pkg.ProtocolName]bool)
+	}
+	s.disabled[name] = true
+}
+
+func (s *protocolSwitches) enabled(name pkg.ProtocolName) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return !s.disabled[name]
+}
+
+func (s *protocolSwitches) list() []pkg.ProtocolName {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]pkg.ProtocolName, 0, len(s.disabled))
+	for name := range s.disabled {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SetProtocolEnabled pauses or resumes routing through a protocol, e.g. during an exploit or
+// a program upgrade. Paused pools stay discovered but are not quoted, so resuming is
+// immediate. It is safe to call while the router is quoting, and applies to routers derived
+// with ForTenant.
+func (r *SimpleRouter) SetProtocolEnabled(name pkg.ProtocolName, enabled bool) {
+	r.switches.set(name, enabled)
+}
+
+// ProtocolEnabled reports whether pools of the protocol are routed through
+func (r *SimpleRouter) ProtocolEnabled(name pkg.ProtocolName) bool {
+	return r.switches.enabled(name)
+}
+
+// DisabledProtocols lists the paused protocols in name order
+func (r *SimpleRouter) DisabledProtocols() []pkg.ProtocolName {
+	return r.switches.list()
+}
+
+// protocolError returns ErrProtocolDisabled when the pool's protocol is paused
+func (r *SimpleRouter) protocolError(pool pkg.Pool) error {
+	if name := pool.ProtocolName(); !r.switches.enabled(name) {
+		return fmt.Errorf("%w: %s", ErrProtocolDisabled, name)
+	}
+	return nil
+}
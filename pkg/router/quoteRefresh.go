@@ -0,0 +1,77 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"cosmossdk.io/math"
+)
+
+// QuoteRefresh is a previously returned quote re-validated against current pool state
+type QuoteRefresh struct {
+	// Route is the quote re-read through the same pools, with its own QuotedAt and Slot. Its
+	// fees and spot output are carried over from the original quote.
+	Route RouteQuote
+	// MinOut is the minimum output the original quote was built with
+	MinOut math.Int
+	// Satisfiable reports whether the route still yields at least MinOut
+	Satisfiable bool
+	// Age is how old the original quote was when it was refreshed
+	Age time.Duration
+}
+
+// RefreshQuote quotes quote's route again through the same pools, so a caller can check
+// before submitting that its minimum output still holds. Pools that were paused or
+// deprecated since fail the refresh. A nil solClient uses the router's quote client.
+func (r *SimpleRouter) RefreshQuote(ctx context.Context, solClient pkg.RPC, quote QuoteResult) (QuoteRefresh, error) {
+	solClient, err := r.quoteClientFor(solClient)
+	if err != nil {
+		return QuoteRefresh{}, err
+	}
+	route := quote.Route
+	if len(route.Pools) == 0 {
+		return QuoteRefresh{}, fmt.Errorf("quote has no route to refresh")
+	}
+	refreshedAt := r.clock.Now()
+	ctx, slot, err := r.pinSlot(ctx, solClient)
+	if err != nil {
+		return QuoteRefresh{}, err
+	}
+
+	fresh := route
+	fresh.QuotedAt, fresh.Slot = refreshedAt, slot
+	inputMint, amount := route.InputMint, route.AmountIn
+	for _, pool := range route.Pools {
+		if err := r.protocolError(pool); err != nil {
+			return QuoteRefresh{}, fmt.Errorf("pool %s: %w", pool.GetID(), err)
+		}
+		unlock := pkg.LockPool(pool)
+		if d := deprecationOf(pool); d != nil {
+			unlock()
+			return QuoteRefresh{}, fmt.Errorf("pool %s: %w", pool.GetID(), deprecationError(d))
+		}
+		out, err := pool.Quote(ctx, solClient, inputMint, amount)
+		unlock()
+		r.health.record(pool.GetID(), err != nil)
+		if err != nil {
+			return QuoteRefresh{}, fmt.Errorf("failed to requote pool %s: %w", pool.GetID(), err)
+		}
+		baseMint, quoteMint := pool.GetTokens()
+		if inputMint == baseMint {
+			inputMint = quoteMint
+		} else {
+			inputMint = baseMint
+		}
+		amount = out
+	}
+	fresh.AmountOut = amount
+	if output := route.Units.Output; output.Mint != "" {
+		fresh.Units.Output = output.WithRaw(amount)
+	}
+
+	refresh := QuoteRefresh{Route: fresh, MinOut: quote.MinOut, Age: route.Age(refreshedAt)}
+	refresh.Satisfiable = !quote.MinOut.IsNil() && amount.GTE(quote.MinOut)
+	return refresh, nil
+}
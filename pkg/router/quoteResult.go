@@ -0,0 +1,121 @@
+package router
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+)
+
+// RouteHop describes one pool of a route
+type RouteHop struct {
+	PoolID     string
+	Protocol   pkg.ProtocolName
+	Venue      string // display name of the protocol
+	ProgramID  solana.PublicKey
+	InputMint  string
+	OutputMint string
+}
+
+// QuoteResult holds what UIs and bots show before executing a swap: the expected and
+// minimum output, price impact, fees and the route taken
+type QuoteResult struct {
+	InputMint   string
+	OutputMint  string
+	AmountIn    math.Int
+	ExpectedOut math.Int
+	// MinOut is the amount to pass as the swap's minimum output
+	MinOut math.Int
+	// SlippageBps is the tolerance below ExpectedOut that MinOut allows
+	SlippageBps uint64
+	// PriceImpactBps is how far the execution price is below the pre-trade price. It is only
+	// meaningful when PriceImpactKnown is set.
+	PriceImpactBps   uint64
+	PriceImpactKnown bool
+	// Fees totals the LP and protocol fees per fee token, for the hops whose pools report them
+	Fees  []pkg.FeeBreakdown
+	Hops  []RouteHop
+	Units QuoteUnits
+	// Route is the quote the result was built from
+	Route RouteQuote
+}
+
+// NewQuoteResult builds a QuoteResult from a route quote, with the minimum output set by
+// the slippage policy. Quotes over the policy's price impact ceiling are rejected.
+func NewQuoteResult(quote RouteQuote, slippage pkg.SlippageConfig) (QuoteResult, error) {
+	if !quote.AmountIn.IsPositive() || quote.AmountOut.IsNil() {
+		return QuoteResult{}, fmt.Errorf("quote amounts are not set")
+	}
+	result := QuoteResult{
+		InputMint:   quote.InputMint,
+		OutputMint:  quote.OutputMint,
+		AmountIn:    quote.AmountIn,
+		ExpectedOut: quote.AmountOut,
+		Fees:        quote.TotalFees(),
+		Units:       quote.Units,
+		Route:       quote,
+	}
+
+	if !quote.SpotAmountOut.IsNil() && quote.SpotAmountOut.IsPositive() {
+		result.PriceImpactKnown = true
+		if shortfall := quote.SpotAmountOut.Sub(quote.AmountOut); shortfall.IsPositive() {
+			result.PriceImpactBps = shortfall.MulRaw(pkg.MaxBps).Quo(quote.SpotAmountOut).Uint64()
+		}
+	}
+	var err error
+	result.MinOut, result.SlippageBps, err = slippage.MinAmountOut(quote.AmountOut, result.PriceImpactBps, result.PriceImpactKnown)
+	if err != nil {
+		return QuoteResult{}, err
+	}
+
+	inputMint := quote.InputMint
+	for _, pool := range quote.Pools {
+		baseMint, quoteMint := pool.GetTokens()
+		outputMint := quoteMint
+		if inputMint == quoteMint {
+			outputMint = baseMint
+		}
+		result.Hops = append(result.Hops, RouteHop{
+			PoolID:     pool.GetID(),
+			Protocol:   pool.ProtocolName(),
+			Venue:      VenueName(pool.ProtocolName()),
+			ProgramID:  pool.GetProgramID(),
+			InputMint:  inputMint,
+			OutputMint: outputMint,
+		})
+		inputMint = outputMint
+	}
+	return result, nil
+}
+
+// MinOutUnits describes MinOut in the output token's units, e.g. to show "at least 1.48 USDC"
+func (q QuoteResult) MinOutUnits() AmountUnits {
+	return q.Units.Output.WithRaw(q.MinOut)
+}
+
+// Quote quotes a swap like QuoteRoute and returns it with the minimum output the slippage
+// policy allows, the price impact, the fee totals and the route's pools. A nil solClient
+// uses the router's quote client.
+func (r *SimpleRouter) Quote(ctx context.Context, solClient pkg.RPC, tokenIn, tokenOut string, amountIn math.Int, slippage pkg.SlippageConfig) (QuoteResult, error) {
+	if err := slippage.Validate(); err != nil {
+		return QuoteResult{}, err
+	}
+	quote, err := r.QuoteRoute(ctx, solClient, tokenIn, tokenOut, amountIn)
+	if err != nil {
+		return QuoteResult{}, err
+	}
+	return NewQuoteResult(quote, slippage)
+}
+
+// QuoteSwap quotes the swap req describes like Quote, after validating it
+func (r *SimpleRouter) QuoteSwap(ctx context.Context, solClient pkg.RPC, req pkg.SwapRequest) (QuoteResult, error) {
+	if req.Clock == nil {
+		req = req.WithClock(r.clock)
+	}
+	if err := req.Validate(); err != nil {
+		return QuoteResult{}, err
+	}
+	return r.Quote(ctx, solClient, req.InputMint, req.OutputMint, req.Amount, req.Slippage)
+}
@@ -0,0 +1,91 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// DefaultQuoteTTL is how long a QuoteSigner's quotes are honored unless set otherwise
+const DefaultQuoteTTL = 10 * time.Second
+
+// QuoteSigner issues quotes signed with a service key for RFQ-style integrations: a separate
+// executor checks them with QuoteVerifier.VerifyRoute and may only redeem them until they
+// expire
+type QuoteSigner struct {
+	key        solana.PrivateKey
+	ttl        time.Duration
+	timeSource clock.Clock
+	slippage   pkg.SlippageConfig
+}
+
+// NewQuoteSigner creates a signer whose quotes expire ttl after signing; a ttl of zero or
+// less uses DefaultQuoteTTL
+func NewQuoteSigner(key solana.PrivateKey, ttl time.Duration) *QuoteSigner {
+	if ttl <= 0 {
+		ttl = DefaultQuoteTTL
+	}
+	return &QuoteSigner{key: key, ttl: ttl, timeSource: clock.System{}}
+}
+
+// SetTimeSource sets the clock expiries are computed from
+func (s *QuoteSigner) SetTimeSource(timeSource clock.Clock) {
+	s.timeSource = timeSource
+}
+
+// SetSlippage sets the tolerance below the quoted output that signed quotes commit to as
+// their MinOut. The default commits to the full quoted output.
+func (s *QuoteSigner) SetSlippage(slippage pkg.SlippageConfig) {
+	s.slippage = slippage
+}
+
+// PublicKey returns the key executors should trust
+func (s *QuoteSigner) PublicKey() solana.PublicKey {
+	return s.key.PublicKey()
+}
+
+// Sign stamps the snapshot with the signer's expiry and, unless it has one, the MinOut its
+// slippage allows, and signs it
+func (s *QuoteSigner) Sign(snapshot QuoteSnapshot) (SignedQuote, error) {
+	if snapshot.MinOut.IsNil() {
+		minOut, _, err := s.slippage.MinAmountOut(snapshot.AmountOut, 0, false)
+		if err != nil {
+			return SignedQuote{}, fmt.Errorf("failed to set quote minimum out: %w", err)
+		}
+		snapshot.MinOut = minOut
+	}
+	snapshot.ExpiresAt = s.timeSource.Now().Add(s.ttl).Unix()
+	return SignQuote(snapshot, s.key)
+}
+
+// SignedQuote finds the best pool like GetBestPool and returns its quote signed by signer,
+// together with the pool. The snapshot records the slot the pools were read at: quotes are
+// pinned to it under the router's slot pinning, or at processed commitment when pinning is
+// off. A nil solClient uses the router's quote client.
+func (r *SimpleRouter) SignedQuote(ctx context.Context, solClient pkg.RPC, signer *QuoteSigner, tokenIn, tokenOut string, amountIn math.Int) (SignedQuote, pkg.Pool, error) {
+	solClient, err := r.quoteClientFor(solClient)
+	if err != nil {
+		return SignedQuote{}, nil, err
+	}
+	pinned := r
+	if r.pinCommitment == "" {
+		scoped := *r
+		scoped.pinCommitment = rpc.CommitmentProcessed
+		pinned = &scoped
+	}
+	pool, amountOut, slot, err := pinned.bestPool(ctx, solClient, tokenIn, tokenOut, amountIn)
+	if err != nil {
+		return SignedQuote{}, nil, err
+	}
+	quote, err := signer.Sign(NewQuoteSnapshot(pool, tokenIn, amountIn, amountOut, slot))
+	if err != nil {
+		return SignedQuote{}, nil, err
+	}
+	return quote, pool, nil
+}
@@ -0,0 +1,183 @@
+package router
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+)
+
+// quoteSnapshotDomain separates quote hashes from any other data signed with the same key
+const quoteSnapshotDomain = "solroute-quote-v3"
+
+// ErrQuoteExpired is returned when verifying a signed quote past its expiry
+var ErrQuoteExpired = errors.New("quote expired")
+
+// QuoteSnapshot captures what a quote was computed against, so an execution service
+// can check that the route it is about to send is the one that was quoted
+type QuoteSnapshot struct {
+	Slot       uint64 // slot the pool state was read at
+	Protocol   pkg.ProtocolName
+	PoolID     string
+	InputMint  string
+	OutputMint string
+	AmountIn   math.Int
+	AmountOut  math.Int
+	// MinOut is the least output the quote commits to, the minimum an executor must swap with
+	MinOut math.Int
+	// ExpiresAt is the unix time after which the quote is no longer honored; zero never expires
+	ExpiresAt int64
+}
+
+// Expired reports whether the quote is past its expiry at now
+func (s QuoteSnapshot) Expired(now time.Time) bool {
+	return s.ExpiresAt != 0 && now.Unix() > s.ExpiresAt
+}
+
+// SignedQuote is a QuoteSnapshot signed by the quoting service
+type SignedQuote struct {
+	Snapshot  QuoteSnapshot
+	Signer    solana.PublicKey
+	Signature solana.Signature
+}
+
+// NewQuoteSnapshot builds a snapshot of a quote returned by GetBestPool. MinOut is left for
+// QuoteSigner.Sign to set from its slippage.
+func NewQuoteSnapshot(pool pkg.Pool, inputMint string, amountIn, amountOut math.Int, slot uint64) QuoteSnapshot {
+	baseMint, quoteMint := pool.GetTokens()
+	outputMint := quoteMint
+	if inputMint == quoteMint {
+		outputMint = baseMint
+	}
+	return QuoteSnapshot{
+		Slot:       slot,
+		Protocol:   pool.ProtocolName(),
+		PoolID:     pool.GetID(),
+		InputMint:  inputMint,
+		OutputMint: outputMint,
+		AmountIn:   amountIn,
+		AmountOut:  amountOut,
+	}
+}
+
+// Hash returns a deterministic SHA-256 digest of the snapshot. Every variable-length
+// field is length-prefixed so distinct snapshots can't encode to the same bytes.
+func (s QuoteSnapshot) Hash() ([32]byte, error) {
+	if s.AmountIn.IsNil() || s.AmountOut.IsNil() || s.MinOut.IsNil() {
+		return [32]byte{}, fmt.Errorf("quote amounts are not set")
+	}
+	h := sha256.New()
+	writeField := func(field string) {
+		var length [4]byte
+		binary.LittleEndian.PutUint32(length[:], uint32(len(field)))
+		h.Write(length[:])
+		h.Write([]byte(field))
+	}
+	writeField(quoteSnapshotDomain)
+	var slot [8]byte
+	binary.LittleEndian.PutUint64(slot[:], s.Slot)
+	h.Write(slot[:])
+	writeField(string(s.Protocol))
+	writeField(s.PoolID)
+	writeField(s.InputMint)
+	writeField(s.OutputMint)
+	writeField(s.AmountIn.String())
+	writeField(s.AmountOut.String())
+	writeField(s.MinOut.String())
+	var expiresAt [8]byte
+	binary.LittleEndian.PutUint64(expiresAt[:], uint64(s.ExpiresAt))
+	h.Write(expiresAt[:])
+
+	var digest [32]byte
+	copy(digest[:], h.Sum(nil))
+	return digest, nil
+}
+
+// SignQuote signs the snapshot hash with the quoting service key
+func SignQuote(snapshot QuoteSnapshot, signer solana.PrivateKey) (SignedQuote, error) {
+	digest, err := snapshot.Hash()
+	if err != nil {
+		return SignedQuote{}, err
+	}
+	sig, err := signer.Sign(digest[:])
+	if err != nil {
+		return SignedQuote{}, fmt.Errorf("failed to sign quote: %w", err)
+	}
+	return SignedQuote{
+		Snapshot:  snapshot,
+		Signer:    signer.PublicKey(),
+		Signature: sig,
+	}, nil
+}
+
+// VerifyAt checks that the quote was signed by trustedSigner, has not been altered and has
+// not expired at now
+func (q SignedQuote) VerifyAt(trustedSigner solana.PublicKey, now time.Time) error {
+	if !q.Signer.Equals(trustedSigner) {
+		return fmt.Errorf("quote signed by untrusted key %s", q.Signer)
+	}
+	digest, err := q.Snapshot.Hash()
+	if err != nil {
+		return err
+	}
+	if !q.Signature.Verify(trustedSigner, digest[:]) {
+		return fmt.Errorf("invalid quote signature")
+	}
+	if q.Snapshot.Expired(now) {
+		return fmt.Errorf("%w at %d", ErrQuoteExpired, q.Snapshot.ExpiresAt)
+	}
+	return nil
+}
+
+// QuoteVerifier checks signed quotes for an executor that redeems them
+type QuoteVerifier struct {
+	trustedSigner solana.PublicKey
+	timeSource    clock.Clock
+}
+
+// NewQuoteVerifier creates a verifier accepting quotes signed by trustedSigner
+func NewQuoteVerifier(trustedSigner solana.PublicKey) *QuoteVerifier {
+	return &QuoteVerifier{trustedSigner: trustedSigner, timeSource: clock.System{}}
+}
+
+// SetTimeSource sets the clock expiries are checked against
+func (v *QuoteVerifier) SetTimeSource(timeSource clock.Clock) {
+	v.timeSource = timeSource
+}
+
+// Verify checks that q was signed by the trusted key, has not been altered and has not expired
+func (v *QuoteVerifier) Verify(q SignedQuote) error {
+	return q.VerifyAt(v.trustedSigner, v.timeSource.Now())
+}
+
+// VerifyRoute verifies q and checks that the swap about to be executed is the quoted one: the
+// same pool, input mint and amount, with a minimum output no looser than the signed MinOut and
+// no higher than the quoted output
+func (v *QuoteVerifier) VerifyRoute(q SignedQuote, pool pkg.Pool, inputMint string, amountIn, minOut math.Int) error {
+	if err := v.Verify(q); err != nil {
+		return err
+	}
+	s := q.Snapshot
+	if pool.GetID() != s.PoolID || pool.ProtocolName() != s.Protocol {
+		return fmt.Errorf("route mismatch: quoted pool %s (%s), executing %s (%s)", s.PoolID, s.Protocol, pool.GetID(), pool.ProtocolName())
+	}
+	if inputMint != s.InputMint {
+		return fmt.Errorf("route mismatch: quoted input mint %s, executing %s", s.InputMint, inputMint)
+	}
+	if !amountIn.Equal(s.AmountIn) {
+		return fmt.Errorf("route mismatch: quoted amount in %s, executing %s", s.AmountIn, amountIn)
+	}
+	if minOut.IsNil() || minOut.LT(s.MinOut) {
+		return fmt.Errorf("route mismatch: quoted minimum out %s, executing with %s", s.MinOut, minOut)
+	}
+	if minOut.GT(s.AmountOut) {
+		return fmt.Errorf("route mismatch: minimum out %s exceeds the quoted %s", minOut, s.AmountOut)
+	}
+	return nil
+}
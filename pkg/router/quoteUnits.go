@@ -0,0 +1,126 @@
+package router
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+)
+
+// AmountUnits describes an amount in both the raw base units instructions use and the
+// decimal units people read
+type AmountUnits struct {
+	Mint       string
+	Decimals   uint8
+	Symbol     string   // empty when the mint has no metadata
+	Raw        math.Int // base units, e.g. lamports
+	Normalized string   // Raw / 10^Decimals, exact
+}
+
+// NewAmountUnits describes a raw amount of a mint with the given decimals
+func NewAmountUnits(mint string, decimals uint8, raw math.Int) AmountUnits {
+	return AmountUnits{Mint: mint, Decimals: decimals, Raw: raw, Normalized: NormalizeAmount(raw, decimals)}
+}
+
+// TokenAmountUnits describes a raw amount of a resolved token
+func TokenAmountUnits(token sol.TokenInfo, raw math.Int) AmountUnits {
+	units := NewAmountUnits(token.Mint.String(), token.Decimals, raw)
+	units.Symbol = token.Symbol
+	return units
+}
+
+// WithRaw describes another amount of the same token
+func (u AmountUnits) WithRaw(raw math.Int) AmountUnits {
+	u.Raw, u.Normalized = raw, NormalizeAmount(raw, u.Decimals)
+	return u
+}
+
+// String renders the amount for people, e.g. "1.5 USDC", naming the token by its mint when
+// it has no symbol
+func (u AmountUnits) String() string {
+	label := u.Symbol
+	if label == "" {
+		label = u.Mint
+	}
+	return u.Normalized + " " + label
+}
+
+// QuoteUnits states the units of a quote's input and output amounts
+type QuoteUnits struct {
+	Input  AmountUnits
+	Output AmountUnits
+}
+
+// NormalizeAmount renders a raw amount in decimal units without rounding, e.g. 1500000 with
+// 6 decimals is "1.5"
+func NormalizeAmount(raw math.Int, decimals uint8) string {
+	return sol.NormalizeAmount(raw, decimals)
+}
+
+// SetTokenResolver replaces the router's cache of mint decimals and token symbols, e.g. with
+// one shared by several routers or a server
+func (r *SimpleRouter) SetTokenResolver(resolver *sol.TokenResolver) {
+	r.tokens = resolver
+}
+
+// SetMintDecimals records a mint's decimals so quotes don't read the mint account, e.g. for
+// well-known tokens or offline use. Invalid mints are ignored; quotes of them fail anyway.
+func (r *SimpleRouter) SetMintDecimals(mint string, decimals uint8) {
+	key, err := solana.PublicKeyFromBase58(mint)
+	if err != nil {
+		return
+	}
+	r.tokens.SetTokenInfo(sol.TokenInfo{Mint: key, Decimals: decimals})
+}
+
+// TokenInfo returns the decimals and symbol of a mint, reading them the first time. A nil
+// solClient uses the router's quote client.
+func (r *SimpleRouter) TokenInfo(ctx context.Context, solClient pkg.RPC, mint string) (sol.TokenInfo, error) {
+	if solClient == nil {
+		solClient = r.quoteClient
+	}
+	infos, err := r.tokenInfos(ctx, solClient, mint)
+	if err != nil {
+		return sol.TokenInfo{}, err
+	}
+	return infos[0], nil
+}
+
+// tokenInfos resolves each mint through the router's token resolver
+func (r *SimpleRouter) tokenInfos(ctx context.Context, solClient pkg.RPC, mints ...string) ([]sol.TokenInfo, error) {
+	keys := make([]solana.PublicKey, len(mints))
+	for i, mint := range mints {
+		key, err := solana.PublicKeyFromBase58(mint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mint %s: %w", mint, err)
+		}
+		keys[i] = key
+	}
+	return r.tokens.Resolve(ctx, solClient, keys...)
+}
+
+// quoteUnits describes a quote's amounts, reading the decimals and symbols of mints seen for
+// the first time
+func (r *SimpleRouter) quoteUnits(ctx context.Context, solClient pkg.RPC, quote RouteQuote) (QuoteUnits, error) {
+	tokens, err := r.tokenInfos(ctx, solClient, quote.InputMint, quote.OutputMint)
+	if err != nil {
+		return QuoteUnits{}, err
+	}
+	return QuoteUnits{
+		Input:  TokenAmountUnits(tokens[0], quote.AmountIn),
+		Output: TokenAmountUnits(tokens[1], quote.AmountOut),
+	}, nil
+}
+
+// withUnits sets the quote's Units
+func (r *SimpleRouter) withUnits(ctx context.Context, solClient pkg.RPC, quote RouteQuote) (RouteQuote, error) {
+	units, err := r.quoteUnits(ctx, solClient, quote)
+	if err != nil {
+		return RouteQuote{}, err
+	}
+	quote.Units = units
+	return quote, nil
+}
@@ -0,0 +1,87 @@
+package router
+
+import (
+	"context"
+	"slices"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+)
+
+// swapAccounts are typical account counts of one swap instruction, including the tick, bin
+// or market accounts a quote of average size touches
+var swapAccounts = map[pkg.ProtocolName]int{
+	pkg.ProtocolNameRaydiumAmm:       18,
+	pkg.ProtocolNameRaydiumCpmm:      13,
+	pkg.ProtocolNameRaydiumClmm:      17,
+	pkg.ProtocolNameRaydiumLaunchLab: 15,
+	pkg.ProtocolNameMeteoraDlmm:      18,
+	pkg.ProtocolNameMeteoraDamm:      15,
+	pkg.ProtocolNameMeteoraDammV2:    14,
+	pkg.ProtocolNamePumpAmm:          19,
+	pkg.ProtocolNameOrcaWhirlpool:    15,
+	pkg.ProtocolNamePhoenix:          9,
+	pkg.ProtocolNameMoonshot:         11,
+	pkg.ProtocolNameStakePool:        12,
+	pkg.ProtocolNameSaber:            10,
+}
+
+// RouteOptions constrains the pools a single request may route through, e.g. for integrators
+// that may only call into allowlisted programs, and what the request's swap costs. The zero
+// value allows every pool.
+type RouteOptions struct {
+	ExcludeProtocols []pkg.ProtocolName
+	ExcludePools     []string
+	// Pools, when set, are the only pools a route may use
+	Pools []string
+	// Programs, when set, are the only programs a route may swap through
+	Programs []solana.PublicKey
+	// MaxAccounts caps the accounts a route's swap instructions reference, as estimated per
+	// protocol. Pools of protocols without an estimate are allowed. Zero disables the cap.
+	MaxAccounts int
+	// Costs, when set, replaces the router's swap costs for the request, e.g. with the rent
+	// of the requesting user's missing output account
+	Costs *SwapCosts
+}
+
+// SwapAccounts returns the estimated account count of a swap through the pool, or 0 when its
+// protocol has no estimate
+func SwapAccounts(pool pkg.Pool) int {
+	return swapAccounts[pool.ProtocolName()]
+}
+
+// allows reports whether a route may use the pool
+func (o RouteOptions) allows(pool pkg.Pool) bool {
+	if slices.Contains(o.ExcludeProtocols, pool.ProtocolName()) || slices.Contains(o.ExcludePools, pool.GetID()) {
+		return false
+	}
+	if len(o.Pools) > 0 && !slices.Contains(o.Pools, pool.GetID()) {
+		return false
+	}
+	if len(o.Programs) > 0 && !slices.ContainsFunc(o.Programs, pool.GetProgramID().Equals) {
+		return false
+	}
+	return o.MaxAccounts <= 0 || SwapAccounts(pool) <= o.MaxAccounts
+}
+
+// withRouteOptions returns a copy of the router limited to the pools opts allows. The copy
+// shares the router's caches, switches and health.
+func (r *SimpleRouter) withRouteOptions(opts RouteOptions) *SimpleRouter {
+	scoped := *r
+	scoped.pools = r.pools.filter(opts.allows)
+	if opts.Costs != nil {
+		scoped.swapCosts = opts.Costs
+	}
+	return &scoped
+}
+
+// GetBestPoolWithOptions is GetBestPool restricted to the pools opts allows
+func (r *SimpleRouter) GetBestPoolWithOptions(ctx context.Context, solClient pkg.RPC, tokenIn, tokenOut string, amountIn math.Int, opts RouteOptions) (pkg.Pool, math.Int, error) {
+	return r.withRouteOptions(opts).GetBestPool(ctx, solClient, tokenIn, tokenOut, amountIn)
+}
+
+// QuoteRouteWithOptions is QuoteRoute restricted to the pools opts allows
+func (r *SimpleRouter) QuoteRouteWithOptions(ctx context.Context, solClient pkg.RPC, tokenIn, tokenOut string, amountIn math.Int, opts RouteOptions) (RouteQuote, error) {
+	return r.withRouteOptions(opts).QuoteRoute(ctx, solClient, tokenIn, tokenOut, amountIn)
+}
@@ -0,0 +1,303 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"cosmossdk.io/math"
+)
+
+// lamportsPerSignature is the base fee charged per transaction signature
+const lamportsPerSignature = 5000
+
+// RouteQuote is a quote for swapping through one or more pools, in hop order
+type RouteQuote struct {
+	InputMint  string
+	OutputMint string
+	AmountIn   math.Int
+	AmountOut  math.Int
+	Pools      []pkg.Pool
+	// SpotAmountOut is AmountIn converted at the pre-trade price, used for price impact.
+	// Zero when unknown.
+	SpotAmountOut math.Int
+	// Fees holds the LP/protocol fee split of each hop whose pool reports it
+	Fees []pkg.FeeBreakdown
+	// ComputeUnits is the estimated compute unit use of a transaction executing the route,
+	// zero when the router has no ComputeUnitModel
+	ComputeUnits uint64
+	// Units restates AmountIn and AmountOut with their mints and decimals. QuoteRoute always
+	// sets it; it is zero in quotes built by hand.
+	Units QuoteUnits
+	// QuotedAt is when the pools were quoted
+	QuotedAt time.Time
+	// Slot is the slot the pools were read at under slot pinning, zero without it
+	Slot uint64
+}
+
+// Age returns how old the quote is at now
+func (q RouteQuote) Age(now time.Time) time.Duration {
+	return now.Sub(q.QuotedAt)
+}
+
+// TotalFees sums the route's fee splits per fee token, in hop order
+func (q RouteQuote) TotalFees() []pkg.FeeBreakdown {
+	totals := make([]pkg.FeeBreakdown, 0, len(q.Fees))
+	index := make(map[string]int)
+	for _, fee := range q.Fees {
+		i, ok := index[fee.Mint]
+		if !ok {
+			index[fee.Mint] = len(totals)
+			totals = append(totals, fee)
+			continue
+		}
+		totals[i].LpFee = totals[i].LpFee.Add(fee.LpFee)
+		totals[i].ProtocolFee = totals[i].ProtocolFee.Add(fee.ProtocolFee)
+	}
+	return totals
+}
+
+// QuoteRoute finds the best pool like GetBestPool, collects its fee split when the pool
+// reports one, and probes it with a small amount to estimate the pre-trade price. The quote
+// records when, and under slot pinning at which slot, the pools were read. Like GetBestPool,
+// a nil solClient uses the router's quote client.
+func (r *SimpleRouter) QuoteRoute(ctx context.Context, solClient pkg.RPC, tokenIn, tokenOut string, amountIn math.Int) (RouteQuote, error) {
+	solClient, err := r.quoteClientFor(solClient)
+	if err != nil {
+		return RouteQuote{}, err
+	}
+	quotedAt := r.clock.Now()
+	best, amountOut, slot, err := r.bestPool(ctx, solClient, tokenIn, tokenOut, amountIn)
+	if err != nil {
+		return RouteQuote{}, err
+	}
+	quote := RouteQuote{
+		InputMint:     tokenIn,
+		OutputMint:    tokenOut,
+		AmountIn:      amountIn,
+		AmountOut:     amountOut,
+		Pools:         []pkg.Pool{best},
+		SpotAmountOut: math.ZeroInt(),
+		QuotedAt:      quotedAt,
+		Slot:          slot,
+	}
+	if r.computeUnits != nil {
+		if err := r.computeUnits.CheckRoute(quote.Pools); err != nil {
+			return RouteQuote{}, err
+		}
+		quote.ComputeUnits = r.computeUnits.RouteUnits(quote.Pools)
+	}
+
+	if quote, err = r.withUnits(ctx, solClient, quote); err != nil {
+		return RouteQuote{}, err
+	}
+
+	unlock := pkg.LockPool(best)
+	defer unlock()
+	if feeQuoter, ok := best.(pkg.FeeQuoter); ok {
+		if _, fees, err := feeQuoter.QuoteWithFees(ctx, solClient, tokenIn, amountIn); err == nil {
+			quote.Fees = append(quote.Fees, fees)
+		}
+	}
+
+	probeIn := amountIn.QuoRaw(1000)
+	if probeIn.IsPositive() {
+		if probeOut, err := best.Quote(ctx, solClient, tokenIn, probeIn); err == nil {
+			quote.SpotAmountOut = probeOut.Mul(amountIn).Quo(probeIn)
+		}
+	}
+	return quote, nil
+}
+
+// NumberFormat controls how amounts are rendered for display
+type NumberFormat struct {
+	DecimalSeparator  string // defaults to "."
+	GroupSeparator    string // thousands separator, empty for none
+	MaxFractionDigits int    // defaults to 6
+}
+
+// SummaryOptions supplies the context a RouteQuote doesn't carry
+type SummaryOptions struct {
+	// InputDecimals and OutputDecimals default to the quote's Units. Values that contradict
+	// the Units are rejected.
+	InputDecimals    uint8
+	OutputDecimals   uint8
+	SlippageBps      uint64
+	PlatformFeeBps   uint64 // taken from the output amount
+	Signatures       uint64 // defaults to 1
+	ComputeUnitLimit uint32
+	ComputeUnitPrice uint64 // micro-lamports per compute unit
+	Format           NumberFormat
+}
+
+// RouteSummary holds display-ready fields for a quote
+type RouteSummary struct {
+	AmountIn       string
+	AmountOut      string
+	ExecutionPrice string // output tokens per input token
+	MinReceived    string
+	PlatformFee    string // in output tokens
+	NetworkFee     string // in SOL, to the lamport
+	PriceImpactPct string // empty when the spot price is unknown
+	Route          []string
+}
+
+// venueNames are the display names of the supported protocols
+var venueNames = map[pkg.ProtocolName]string{
+	pkg.ProtocolNameRaydiumAmm:       "Raydium AMM",
+	pkg.ProtocolNameRaydiumClmm:      "Raydium CLMM",
+	pkg.ProtocolNameRaydiumCpmm:      "Raydium CPMM",
+	pkg.ProtocolNameRaydiumLaunchLab: "Raydium LaunchLab",
+	pkg.ProtocolNameMeteoraDlmm:      "Meteora DLMM",
+	pkg.ProtocolNamePumpAmm:          "PumpSwap",
+	pkg.ProtocolNameOrcaWhirlpool:    "Orca Whirlpool",
+	pkg.ProtocolNamePhoenix:          "Phoenix",
+	pkg.ProtocolNameMoonshot:         "Moonshot",
+	pkg.ProtocolNameMeteoraDamm:      "Meteora DAMM",
+	pkg.ProtocolNameMeteoraDammV2:    "Meteora DAMM v2",
+	pkg.ProtocolNameStakePool:        "Stake Pool",
+	pkg.ProtocolNameSaber:            "Saber",
+}
+
+// VenueName returns the display name of a protocol
+func VenueName(name pkg.ProtocolName) string {
+	if venue, ok := venueNames[name]; ok {
+		return venue
+	}
+	return string(name)
+}
+
+// SummarizeRoute turns a quote into the fields a UI shows before the user confirms a swap
+func SummarizeRoute(quote RouteQuote, opts SummaryOptions) (RouteSummary, error) {
+	if !quote.AmountIn.IsPositive() || quote.AmountOut.IsNil() {
+		return RouteSummary{}, fmt.Errorf("quote amounts are not set")
+	}
+	if opts.SlippageBps > 10000 || opts.PlatformFeeBps > 10000 {
+		return RouteSummary{}, fmt.Errorf("bps values must not exceed 10000")
+	}
+	var err error
+	if opts.InputDecimals, err = unitsDecimals(quote.Units.Input, opts.InputDecimals); err != nil {
+		return RouteSummary{}, err
+	}
+	if opts.OutputDecimals, err = unitsDecimals(quote.Units.Output, opts.OutputDecimals); err != nil {
+		return RouteSummary{}, err
+	}
+
+	platformFee := quote.AmountOut.MulRaw(int64(opts.PlatformFeeBps)).QuoRaw(10000)
+	netOut := quote.AmountOut.Sub(platformFee)
+	minReceived := netOut.MulRaw(int64(10000 - opts.SlippageBps)).QuoRaw(10000)
+
+	signatures := opts.Signatures
+	if signatures == 0 {
+		signatures = 1
+	}
+	priorityFee := new(big.Int).Mul(new(big.Int).SetUint64(uint64(opts.ComputeUnitLimit)), new(big.Int).SetUint64(opts.ComputeUnitPrice))
+	priorityFee.Add(priorityFee, big.NewInt(999_999))
+	priorityFee.Quo(priorityFee, big.NewInt(1_000_000))
+	networkFee := math.NewIntFromBigInt(priorityFee).Add(math.NewIntFromUint64(signatures * lamportsPerSignature))
+
+	price := new(big.Rat).SetFrac(
+		new(big.Int).Mul(quote.AmountOut.BigInt(), pow10(opts.InputDecimals)),
+		new(big.Int).Mul(quote.AmountIn.BigInt(), pow10(opts.OutputDecimals)),
+	)
+
+	// Network fees are shown to the lamport whatever the amount precision
+	feeFormat := opts.Format
+	if feeFormat.MaxFractionDigits < 9 {
+		feeFormat.MaxFractionDigits = 9
+	}
+
+	summary := RouteSummary{
+		AmountIn:       FormatAmount(quote.AmountIn, opts.InputDecimals, opts.Format),
+		AmountOut:      FormatAmount(netOut, opts.OutputDecimals, opts.Format),
+		ExecutionPrice: formatRat(price, opts.Format),
+		MinReceived:    FormatAmount(minReceived, opts.OutputDecimals, opts.Format),
+		PlatformFee:    FormatAmount(platformFee, opts.OutputDecimals, opts.Format),
+		NetworkFee:     FormatAmount(networkFee, 9, feeFormat),
+	}
+
+	if !quote.SpotAmountOut.IsNil() && quote.SpotAmountOut.IsPositive() {
+		impact := new(big.Rat).SetFrac(quote.SpotAmountOut.Sub(quote.AmountOut).BigInt(), quote.SpotAmountOut.BigInt())
+		if impact.Sign() < 0 {
+			impact.SetInt64(0)
+		}
+		impact.Mul(impact, big.NewRat(100, 1))
+		pctFormat := opts.Format
+		pctFormat.MaxFractionDigits = 2
+		summary.PriceImpactPct = formatRat(impact, pctFormat)
+	}
+
+	for _, pool := range quote.Pools {
+		summary.Route = append(summary.Route, fmt.Sprintf("%s (%s)", VenueName(pool.ProtocolName()), pool.GetID()))
+	}
+	return summary, nil
+}
+
+// unitsDecimals returns the decimals to format an amount with: the quote's when it carries
+// Units, checked against any the caller passed
+func unitsDecimals(units AmountUnits, decimals uint8) (uint8, error) {
+	if units.Mint == "" {
+		return decimals, nil
+	}
+	if decimals != 0 && decimals != units.Decimals {
+		return 0, fmt.Errorf("%d decimals given for %s, which has %d", decimals, units.Mint, units.Decimals)
+	}
+	return units.Decimals, nil
+}
+
+// FormatAmount renders a raw token amount with the token's decimals
+func FormatAmount(amount math.Int, decimals uint8, format NumberFormat) string {
+	if amount.IsNil() {
+		return ""
+	}
+	return formatRat(new(big.Rat).SetFrac(amount.BigInt(), pow10(decimals)), format)
+}
+
+func pow10(n uint8) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// formatRat rounds to MaxFractionDigits, trims trailing zeros and applies the separators
+func formatRat(r *big.Rat, format NumberFormat) string {
+	digits := format.MaxFractionDigits
+	if digits <= 0 {
+		digits = 6
+	}
+	decimalSep := format.DecimalSeparator
+	if decimalSep == "" {
+		decimalSep = "."
+	}
+
+	s := r.FloatString(digits)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	fracPart = strings.TrimRight(fracPart, "0")
+
+	if format.GroupSeparator != "" && len(intPart) > 3 {
+		var b strings.Builder
+		lead := len(intPart) % 3
+		if lead > 0 {
+			b.WriteString(intPart[:lead])
+		}
+		for i := lead; i < len(intPart); i += 3 {
+			if b.Len() > 0 {
+				b.WriteString(format.GroupSeparator)
+			}
+			b.WriteString(intPart[i : i+3])
+		}
+		intPart = b.String()
+	}
+
+	out := intPart
+	if fracPart != "" {
+		out += decimalSep + fracPart
+	}
+	if negative && out != "0" {
+		out = "-" + out
+	}
+	return out
+}
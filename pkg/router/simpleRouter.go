@@ -1,54 +1,407 @@
+// Package router discovers pools through a set of protocols and picks the best one for a
+// swap, with caching, sizing, routing policy, compute unit estimates and signed quotes
+// layered on SimpleRouter.
 package router
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"runtime/pprof"
+	"sync"
+	"time"
 
 	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gtdvccc/SolRouteTmp/pkg/telemetry"
 	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 )
 
+const (
+	// DefaultQuoteConcurrency is the number of pools quoted in parallel by GetBestPool
+	DefaultQuoteConcurrency = 16
+	// DefaultQuoteTimeout bounds how long a single pool quote may take
+	DefaultQuoteTimeout = 5 * time.Second
+)
+
+// SimpleRouter is safe for concurrent use once configured. Discovery replaces a pair's pools
+// as a whole, and quotes refresh a pool's state under its pkg.LockPool lock, so pairs can be
+// discovered and quoted from many goroutines. Code that builds swaps through a returned pool
+// while the router keeps quoting it holds the same lock, as sol.Client's builders do. The Set
+// methods are not synchronized and belong before the router is shared.
 type SimpleRouter struct {
 	protocols []pkg.Protocol
-	pools     []pkg.Pool
+	pools     *pairPools
+	poolCache *PoolCache
+	registry  *PoolRegistry
+
+	quoteConcurrency int
+	quoteTimeout     time.Duration
+	pinCommitment    rpc.CommitmentType
+	prefetch         bool
+	profileLabels    bool
+	quoteClient      pkg.RPC
+	discoveryBudget  time.Duration
+	protocolTimeout  time.Duration
+	breaker          *CircuitBreaker
+	mintInspector    *sol.MintInspector
+	poolFilter       func(pkg.Pool) bool
+	liquidityFilter  LiquidityFilter
+	scorer           PoolScorer
+	health           *poolHealth
+	computeUnits     *ComputeUnitModel
+	switches         *protocolSwitches
+	blocklist        *Blocklist
+	complianceHook   ComplianceHook
+	priceGuard       *PriceGuard
+	swapCosts        *SwapCosts
+	tokens           *sol.TokenResolver
+	clock            clock.Clock
+	logger           pkg.Logger
+	telemetry        *telemetry.Telemetry
 }
 
 func NewSimpleRouter(protocols ...pkg.Protocol) *SimpleRouter {
 	return &SimpleRouter{
-		protocols: protocols,
-		pools:     []pkg.Pool{},
+		protocols:        protocols,
+		pools:            newPairPools(),
+		quoteConcurrency: DefaultQuoteConcurrency,
+		quoteTimeout:     DefaultQuoteTimeout,
+		prefetch:         true,
+		switches:         &protocolSwitches{},
+		health:           &poolHealth{},
+		tokens:           sol.NewTokenResolver(nil),
+		clock:            clock.System{},
+		logger:           slog.Default(),
+	}
+}
+
+// SetClock replaces the time source that stamps and refreshes quotes, ages pools for the
+// liquidity filter and checks swap request deadlines. The pool cache and registry take
+// theirs separately.
+func (r *SimpleRouter) SetClock(clk clock.Clock) {
+	r.clock = clk
+}
+
+// SetLogger sets where the router reports failed quotes and per-pool quote latency. Nil
+// restores slog's default logger.
+func (r *SimpleRouter) SetLogger(logger pkg.Logger) {
+	r.logger = pkg.LoggerOrDefault(logger)
+}
+
+// SetTelemetry traces discovery and pool selection, and counts quotes by protocol and
+// outcome, discovered pools and how long picking a pool takes. Nil turns it off.
+func (r *SimpleRouter) SetTelemetry(t *telemetry.Telemetry) {
+	r.telemetry = t
+}
+
+// SetQuoteConcurrency sets how many pools GetBestPool quotes at the same time.
+// Values below 1 quote pools sequentially.
+func (r *SimpleRouter) SetQuoteConcurrency(n int) {
+	if n < 1 {
+		n = 1
+	}
+	r.quoteConcurrency = n
+}
+
+// SetQuoteTimeout sets the per-pool quote timeout. Zero disables the timeout
+// and leaves cancellation to the caller's context.
+func (r *SimpleRouter) SetQuoteTimeout(d time.Duration) {
+	r.quoteTimeout = d
+}
+
+// SetSlotPinning makes GetBestPool read the current slot at the given commitment once
+// and pass it as minContextSlot to every pool read, so pools aren't compared across
+// different slots. An empty commitment disables pinning.
+func (r *SimpleRouter) SetSlotPinning(commitment rpc.CommitmentType) {
+	r.pinCommitment = commitment
+}
+
+// SetPrefetch sets whether GetBestPool fetches the accounts every pool's quote reads in
+// batched getMultipleAccounts requests before quoting, instead of each pool fetching its own.
+// Prefetching is on by default.
+func (r *SimpleRouter) SetPrefetch(enabled bool) {
+	r.prefetch = enabled
+}
+
+// SetProfileLabels sets whether GetBestPool runs each pool's quote under pprof labels
+// naming the pool and its protocol, with the keys pkg.LogKeyPool and pkg.LogKeyProtocol, so
+// CPU profiles can be split by venue with pprof's -tagfocus. It is off by default, as the
+// labels cost a few allocations per quote.
+func (r *SimpleRouter) SetProfileLabels(enabled bool) {
+	r.profileLabels = enabled
+}
+
+// SetQuoteClient sets the client quotes are read through when GetBestPool or QuoteRoute is
+// passed a nil client. Discovery keeps using each protocol's own client, so a gPA-capable
+// endpoint can serve discovery while a low-latency one serves quotes.
+func (r *SimpleRouter) SetQuoteClient(solClient pkg.RPC) {
+	r.quoteClient = solClient
+}
+
+// quoteClientFor returns solClient, or the router's quote client when solClient is nil
+func (r *SimpleRouter) quoteClientFor(solClient pkg.RPC) (pkg.RPC, error) {
+	if solClient != nil {
+		return solClient, nil
+	}
+	if r.quoteClient == nil {
+		return nil, fmt.Errorf("no quote client: pass one or call SetQuoteClient")
 	}
+	return r.quoteClient, nil
 }
 
+// SetComputeUnitModel makes QuoteRoute estimate each route's compute units and reject
+// routes that can't fit in one transaction. Nil disables the check.
+func (r *SimpleRouter) SetComputeUnitModel(model *ComputeUnitModel) {
+	r.computeUnits = model
+}
+
+// SetPoolCache makes QueryAllPools serve pools from the cache instead of querying
+// every protocol on each call
+func (r *SimpleRouter) SetPoolCache(cache *PoolCache) {
+	r.poolCache = cache
+}
+
+// QueryAllPools discovers the pair's pools through every protocol, or the pool cache when
+// one is set. Protocols that fail, run past the protocol timeout or the discovery budget, or
+// are skipped by the circuit breaker are left out; DiscoverPools reports which.
 func (r *SimpleRouter) QueryAllPools(ctx context.Context, baseMint, quoteMint string) ([]pkg.Pool, error) {
-	for _, proto := range r.protocols {
-		pools, err := proto.FetchPoolsByPair(ctx, baseMint, quoteMint)
-		if err != nil {
-			continue
-		}
-		r.pools = append(r.pools, pools...)
+	discovery, err := r.DiscoverPools(ctx, baseMint, quoteMint)
+	if err != nil {
+		return nil, err
+	}
+	return discovery.Pools, nil
+}
+
+// GetBestPool quotes every known pool concurrently and returns the one with the largest output,
+// net of the costs set with SetSwapCosts, or the highest score under a scorer set with
+// SetPoolScorer. The output returned is before costs; when no pool's covers them, the error
+// wraps ErrCostsExceedOutput.
+// Deprecated pools, pools of paused protocols and routes rejected by the blocklist or the
+// compliance hook are not quoted, and quotes rejected by the price guard are not chosen.
+// Pools that fail or time out are skipped; if none succeeds
+// the returned error wraps ErrNoRoute and joins all failures. When the pools that could be quoted all round the
+// output down to zero, the error is an *AmountTooSmallError with the smallest routable input.
+// A nil solClient quotes through the client set with SetQuoteClient. Unless disabled with
+// SetPrefetch, the accounts listed by PrefetchPool pools are fetched in batches first.
+func (r *SimpleRouter) GetBestPool(ctx context.Context, solClient pkg.RPC, tokenIn, tokenOut string, amountIn math.Int) (pkg.Pool, math.Int, error) {
+	best, out, _, err := r.bestPool(ctx, solClient, tokenIn, tokenOut, amountIn)
+	return best, out, err
+}
+
+// pinSlot reads the current slot and pins ctx's reads to it when slot pinning is on. The slot
+// is zero otherwise.
+func (r *SimpleRouter) pinSlot(ctx context.Context, solClient pkg.RPC) (context.Context, uint64, error) {
+	if r.pinCommitment == "" {
+		return ctx, 0, nil
+	}
+	slot, err := solClient.GetSlot(ctx, r.pinCommitment)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get slot to pin quotes: %w", err)
 	}
-	return r.pools, nil
+	return sol.WithReadOpts(ctx, sol.ReadOpts{Commitment: r.pinCommitment, MinContextSlot: slot}), slot, nil
 }
 
-func (r *SimpleRouter) GetBestPool(ctx context.Context, solClient *rpc.Client, tokenIn, tokenOut string, amountIn math.Int) (pkg.Pool, math.Int, error) {
+// bestPool is GetBestPool, also returning the slot the quotes were pinned to
+func (r *SimpleRouter) bestPool(ctx context.Context, solClient pkg.RPC, tokenIn, tokenOut string, amountIn math.Int) (pkg.Pool, math.Int, uint64, error) {
+	ctx, span := r.telemetry.Start(ctx, telemetry.SpanRoute,
+		telemetry.String(pkg.LogKeyInputMint, tokenIn),
+		telemetry.String(pkg.LogKeyOutputMint, tokenOut),
+	)
+	start := time.Now()
+	best, out, slot, err := r.pickPool(ctx, solClient, tokenIn, tokenOut, amountIn)
+	r.telemetry.RecordDuration(ctx, telemetry.MetricRouteDuration, time.Since(start), telemetry.Outcome(err))
+	if best != nil {
+		span.SetAttributes(
+			telemetry.String(pkg.LogKeyPool, best.GetID()),
+			telemetry.String(pkg.LogKeyProtocol, string(best.ProtocolName())),
+		)
+	}
+	telemetry.End(span, err)
+	return best, out, slot, err
+}
+
+// pickPool quotes the pair's pools and picks the best for bestPool
+func (r *SimpleRouter) pickPool(ctx context.Context, solClient pkg.RPC, tokenIn, tokenOut string, amountIn math.Int) (pkg.Pool, math.Int, uint64, error) {
+	solClient, err := r.quoteClientFor(solClient)
+	if err != nil {
+		return nil, math.ZeroInt(), 0, err
+	}
+	if err := r.checkRequest(tokenIn, tokenOut); err != nil {
+		return nil, math.ZeroInt(), 0, err
+	}
+	type quoteResult struct {
+		out     math.Int
+		err     error
+		latency time.Duration
+	}
+	pools := r.pools.get(tokenIn, tokenOut)
+	results := make([]quoteResult, len(pools))
+
+	ctx, slot, err := r.pinSlot(ctx, solClient)
+	if err != nil {
+		return nil, math.ZeroInt(), 0, err
+	}
+	guard, err := r.bindPriceGuard(ctx, solClient, tokenIn, tokenOut, amountIn)
+	if err != nil {
+		return nil, math.ZeroInt(), 0, err
+	}
+	costs, err := r.bindSwapCosts(ctx, solClient, tokenOut)
+	if err != nil {
+		return nil, math.ZeroInt(), 0, err
+	}
+	if r.prefetch {
+		solClient = r.prefetchAccounts(ctx, solClient, pools, tokenIn)
+	}
+
+	concurrency := r.quoteConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, pool := range pools {
+		wg.Add(1)
+		go func(i int, pool pkg.Pool) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i].err = ctx.Err()
+				return
+			}
+
+			if err := r.protocolError(pool); err != nil {
+				results[i].err = err
+				return
+			}
+			route := RouteCandidate{InputMint: tokenIn, OutputMint: tokenOut, AmountIn: amountIn, Pools: []pkg.Pool{pool}}
+			if err := r.policyError(ctx, route); err != nil {
+				results[i].err = err
+				return
+			}
+			unlock := pkg.LockPool(pool)
+			defer unlock()
+			if d := deprecationOf(pool); d != nil {
+				results[i].err = deprecationError(d)
+				return
+			}
+
+			quoteCtx := ctx
+			if r.quoteTimeout > 0 {
+				var cancel context.CancelFunc
+				quoteCtx, cancel = context.WithTimeout(ctx, r.quoteTimeout)
+				defer cancel()
+			}
+			start := time.Now()
+			r.withProfileLabels(quoteCtx, pool, func(ctx context.Context) {
+				results[i].out, results[i].err = pool.Quote(ctx, solClient, tokenIn, amountIn)
+			})
+			results[i].latency = time.Since(start)
+			r.health.record(pool.GetID(), results[i].err != nil)
+		}(i, pool)
+	}
+	wg.Wait()
+
+	// Select in pool order so ties resolve the same way as a sequential scan
 	var best pkg.Pool
-	maxOut := math.NewInt(0)
-	for _, pool := range r.pools {
-		outAmount, err := pool.Quote(ctx, solClient, tokenIn, amountIn)
-		if err != nil {
-			log.Printf("error quoting: %v", err)
+	maxOut, bestNet := math.NewInt(0), math.NewInt(0)
+	var bestScore float64
+	var errs []error
+	var zeroPools []pkg.Pool
+	for i, res := range results {
+		pool := pools[i]
+		fields := []any{
+			pkg.LogKeyPool, pool.GetID(),
+			pkg.LogKeyProtocol, pool.ProtocolName(),
+			pkg.LogKeyInputMint, tokenIn,
+			pkg.LogKeyOutputMint, tokenOut,
+			pkg.LogKeyLatency, res.latency,
+		}
+		r.telemetry.Add(ctx, telemetry.MetricQuotes, 1, telemetry.String(pkg.LogKeyProtocol, string(pool.ProtocolName())), telemetry.Outcome(res.err))
+		if res.err != nil {
+			r.logger.Warn("quote failed", append(fields, pkg.LogKeyError, res.err)...)
+			errs = append(errs, fmt.Errorf("pool %s (%s): %w", pool.GetID(), pool.ProtocolName(), res.err))
+			continue
+		}
+		r.logger.Debug("quoted", append(fields, "amount_out", res.out.String())...)
+		if !res.out.IsPositive() {
+			zeroPools = append(zeroPools, pool)
+			continue
+		}
+		if err := guard.check(res.out); err != nil {
+			r.logger.Warn("quote rejected", append(fields, pkg.LogKeyError, err)...)
+			errs = append(errs, fmt.Errorf("pool %s (%s): %w", pool.GetID(), pool.ProtocolName(), err))
+			continue
+		}
+		cost := costs.out(pool)
+		if r.scorer != nil {
+			unlock := pkg.LockPool(pool)
+			candidate := r.candidate(pool, res.out)
+			unlock()
+			candidate.CostOut = cost
+			score := r.scorer.Score(candidate)
+			if best == nil || score > bestScore {
+				best, maxOut, bestNet, bestScore = pool, res.out, res.out.Sub(cost), score
+			}
 			continue
 		}
-		if outAmount.GT(maxOut) {
-			maxOut = outAmount
-			best = pool
+		if net := res.out.Sub(cost); best == nil || net.GT(bestNet) {
+			best, maxOut, bestNet = pool, res.out, net
 		}
 	}
+	if best != nil && costs != nil && !bestNet.IsPositive() {
+		return nil, math.ZeroInt(), 0, fmt.Errorf("%w: %s out of pool %s is worth less than %d lamports of costs",
+			ErrCostsExceedOutput, maxOut, best.GetID(), costs.lamports(best))
+	}
 	if best == nil {
-		return nil, math.ZeroInt(), fmt.Errorf("no route found")
+		if len(zeroPools) > 0 {
+			return nil, math.ZeroInt(), 0, r.amountTooSmall(ctx, solClient, zeroPools, tokenIn, amountIn)
+		}
+		if len(errs) > 0 {
+			return nil, math.ZeroInt(), 0, fmt.Errorf("%w: %w", solerrors.ErrNoRoute, errors.Join(errs...))
+		}
+		return nil, math.ZeroInt(), 0, solerrors.ErrNoRoute
+	}
+	return best, maxOut, slot, nil
+}
+
+// prefetchAccounts fetches the quote accounts of the pools that list them into a snapshot
+// that the quotes then read through. If the prefetch fails, pools fetch their own accounts.
+func (r *SimpleRouter) prefetchAccounts(ctx context.Context, solClient pkg.RPC, pools []pkg.Pool, tokenIn string) pkg.RPC {
+	var accounts []solana.PublicKey
+	for _, pool := range pools {
+		if r.protocolError(pool) != nil {
+			continue
+		}
+		unlock := pkg.LockPool(pool)
+		if prefetch, ok := pool.(pkg.PrefetchPool); ok && deprecationOf(pool) == nil {
+			accounts = append(accounts, prefetch.QuoteAccounts(tokenIn)...)
+		}
+		unlock()
+	}
+	if len(accounts) == 0 {
+		return solClient
+	}
+	snapshot := sol.NewAccountSnapshot(solClient)
+	if err := snapshot.Prefetch(ctx, accounts); err != nil {
+		r.logger.Warn("account prefetch failed", pkg.LogKeyInputMint, tokenIn, pkg.LogKeyError, err)
+		return solClient
+	}
+	return snapshot
+}
+
+// withProfileLabels calls f with ctx, labelled with pool when profile labels are on
+func (r *SimpleRouter) withProfileLabels(ctx context.Context, pool pkg.Pool, f func(context.Context)) {
+	if !r.profileLabels {
+		f(ctx)
+		return
 	}
-	return best, maxOut, nil
+	pprof.Do(ctx, pprof.Labels(pkg.LogKeyPool, pool.GetID(), pkg.LogKeyProtocol, string(pool.ProtocolName())), f)
 }
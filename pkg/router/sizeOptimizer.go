@@ -0,0 +1,102 @@
+package router
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"cosmossdk.io/math"
+)
+
+// maxSizeSearchSteps bounds the bisection of MaxSizeForImpact
+const maxSizeSearchSteps = 40
+
+// SizeOptions bounds the search of MaxSizeForImpact
+type SizeOptions struct {
+	// MaxImpactBps is the largest acceptable price impact against the spot price
+	MaxImpactBps uint16
+	// MaxAmount caps the size, e.g. at the wallet balance or the position to unwind. Required.
+	MaxAmount math.Int
+	// ProbeAmount is quoted to measure the spot price; nil or zero uses MaxAmount / 10000
+	ProbeAmount math.Int
+	// Tolerance stops the search once the size is known to within this amount; nil or zero
+	// uses MaxAmount / 1000
+	Tolerance math.Int
+}
+
+// MaxSizeForImpact finds the largest input, up to opts.MaxAmount, that the best pool fills
+// within opts.MaxImpactBps of the spot price, by bisecting over GetBestPool quotes. The
+// returned quote carries its route and spot output, so SummarizeRoute reports the impact.
+// Each step quotes every known pool, so call QueryAllPools first and expect about
+// log2(MaxAmount/Tolerance) rounds of quotes. A nil solClient uses the router's quote client.
+func (r *SimpleRouter) MaxSizeForImpact(ctx context.Context, solClient pkg.RPC, tokenIn, tokenOut string, opts SizeOptions) (RouteQuote, error) {
+	if opts.MaxAmount.IsNil() || !opts.MaxAmount.IsPositive() {
+		return RouteQuote{}, fmt.Errorf("max amount must be positive")
+	}
+	if opts.MaxImpactBps >= 10000 {
+		return RouteQuote{}, fmt.Errorf("max impact must be below 10000 bps")
+	}
+	solClient, err := r.quoteClientFor(solClient)
+	if err != nil {
+		return RouteQuote{}, err
+	}
+
+	probeIn := opts.ProbeAmount
+	if probeIn.IsNil() || !probeIn.IsPositive() {
+		probeIn = math.MaxInt(opts.MaxAmount.QuoRaw(10_000), math.OneInt())
+	}
+	tolerance := opts.Tolerance
+	if tolerance.IsNil() || !tolerance.IsPositive() {
+		tolerance = math.MaxInt(opts.MaxAmount.QuoRaw(1000), math.OneInt())
+	}
+	_, probeOut, err := r.GetBestPool(ctx, solClient, tokenIn, tokenOut, probeIn)
+	if err != nil {
+		return RouteQuote{}, fmt.Errorf("failed to quote spot price: %w", err)
+	}
+
+	// quote returns the best route for amountIn and whether it stays within the impact cap
+	quote := func(amountIn math.Int) (RouteQuote, bool, error) {
+		pool, amountOut, err := r.GetBestPool(ctx, solClient, tokenIn, tokenOut, amountIn)
+		if err != nil {
+			return RouteQuote{}, false, err
+		}
+		spotOut := probeOut.Mul(amountIn).Quo(probeIn)
+		withinCap := amountOut.MulRaw(10000).GTE(spotOut.MulRaw(int64(10000 - opts.MaxImpactBps)))
+		return RouteQuote{
+			InputMint:     tokenIn,
+			OutputMint:    tokenOut,
+			AmountIn:      amountIn,
+			AmountOut:     amountOut,
+			Pools:         []pkg.Pool{pool},
+			SpotAmountOut: spotOut,
+		}, withinCap, nil
+	}
+
+	best, ok, err := quote(opts.MaxAmount)
+	if err == nil && ok {
+		return r.withUnits(ctx, solClient, best)
+	}
+	best, ok, err = quote(probeIn)
+	if err != nil {
+		return RouteQuote{}, err
+	}
+	if !ok {
+		return RouteQuote{}, fmt.Errorf("even %s of %s exceeds the %d bps impact cap", probeIn, tokenIn, opts.MaxImpactBps)
+	}
+
+	low, high := probeIn, opts.MaxAmount
+	for step := 0; step < maxSizeSearchSteps && high.Sub(low).GT(tolerance); step++ {
+		if err := ctx.Err(); err != nil {
+			return RouteQuote{}, err
+		}
+		mid := low.Add(high).QuoRaw(2)
+		candidate, ok, err := quote(mid)
+		if err == nil && ok {
+			low, best = mid, candidate
+		} else {
+			// A size no pool can fill counts as too large
+			high = mid
+		}
+	}
+	return r.withUnits(ctx, solClient, best)
+}
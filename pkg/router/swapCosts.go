@@ -0,0 +1,129 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/price"
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+)
+
+const (
+	// lamportsPerSol scales oracle SOL prices to lamports
+	lamportsPerSol = 1_000_000_000
+	// microLamportsPerLamport scales compute unit prices to lamports
+	microLamportsPerLamport = 1_000_000
+)
+
+// ErrCostsExceedOutput is returned when no pool's output is worth more than the lamports the
+// swap costs
+var ErrCostsExceedOutput = errors.New("swap costs exceed output")
+
+// SwapCosts are the lamports a swap spends besides its input: rent of the accounts it
+// creates, its priority fee and tip. Small trades feel them most: 0.002 SOL of output account
+// rent is a fifth of a 0.01 SOL swap.
+type SwapCosts struct {
+	// RentLamports is rent left in accounts the swap creates, e.g. a missing output token
+	// account; sol.Client.EstimateSwapRent estimates it for a user
+	RentLamports        uint64
+	PriorityFeeLamports uint64
+	TipLamports         uint64
+	// ComputeUnitPrice, in micro-lamports, adds the priority fee of each candidate's estimated
+	// compute units, so venues that burn more units under a high fee lose to leaner ones. The
+	// units come from the router's ComputeUnitModel, or the default estimates without one.
+	ComputeUnitPrice uint64
+	// PoolLamports, when set, adds what swapping through a particular pool costs, e.g. rent
+	// of per-user accounts its venue creates on a first swap
+	PoolLamports func(pool pkg.Pool) uint64
+	// Oracle prices SOL in the output token, to state the costs in output terms. WSOL outputs
+	// don't need one.
+	Oracle price.Oracle
+}
+
+// Lamports returns what a swap through pool costs, before the compute unit priority fee
+func (c *SwapCosts) Lamports(pool pkg.Pool) uint64 {
+	total := c.RentLamports + c.PriorityFeeLamports + c.TipLamports
+	if c.PoolLamports != nil {
+		total += c.PoolLamports(pool)
+	}
+	return total
+}
+
+// SetSwapCosts makes GetBestPool pick the pool with the largest output net of costs, stated in
+// the output token, rather than the largest output. Routes fail when the oracle can't price
+// SOL in the output token. Nil stops accounting for costs; RouteOptions.Costs overrides them
+// per request.
+func (r *SimpleRouter) SetSwapCosts(costs *SwapCosts) {
+	r.swapCosts = costs
+}
+
+// boundSwapCosts are costs with the price of a lamport in one request's output token
+type boundSwapCosts struct {
+	costs *SwapCosts
+	units *ComputeUnitModel
+	// perLamport is output base units per lamport
+	perLamport *big.Rat
+}
+
+// bindSwapCosts prices a lamport in tokenOut, or returns nil without costs
+func (r *SimpleRouter) bindSwapCosts(ctx context.Context, solClient pkg.RPC, tokenOut string) (*boundSwapCosts, error) {
+	if r.swapCosts == nil {
+		return nil, nil
+	}
+	units := r.computeUnits
+	if units == nil && r.swapCosts.ComputeUnitPrice > 0 {
+		units = NewComputeUnitModel()
+	}
+	if tokenOut == solana.WrappedSol.String() {
+		return &boundSwapCosts{costs: r.swapCosts, units: units, perLamport: big.NewRat(1, 1)}, nil
+	}
+	if r.swapCosts.Oracle == nil {
+		return nil, fmt.Errorf("swap costs: no oracle to price SOL in %s", tokenOut)
+	}
+	solPrice, err := r.swapCosts.Oracle.Price(ctx, solana.WrappedSol.String(), tokenOut)
+	if err != nil {
+		return nil, fmt.Errorf("swap costs: failed to get oracle price of SOL in %s: %w", tokenOut, err)
+	}
+	perSol := new(big.Rat)
+	if perSol.SetFloat64(solPrice.Price) == nil || perSol.Sign() <= 0 {
+		return nil, fmt.Errorf("swap costs: invalid oracle price %g of SOL in %s", solPrice.Price, tokenOut)
+	}
+	tokens, err := r.tokenInfos(ctx, solClient, tokenOut)
+	if err != nil {
+		return nil, fmt.Errorf("swap costs: %w", err)
+	}
+	perLamport := perSol.Mul(perSol, new(big.Rat).SetFrac(pow10(tokens[0].Decimals), big.NewInt(lamportsPerSol)))
+	return &boundSwapCosts{costs: r.swapCosts, units: units, perLamport: perLamport}, nil
+}
+
+// lamports returns the cost of a swap through pool, priority fee of its units included
+func (b *boundSwapCosts) lamports(pool pkg.Pool) uint64 {
+	lamports := b.costs.Lamports(pool)
+	if price := b.costs.ComputeUnitPrice; price > 0 {
+		fee := new(big.Int).Mul(new(big.Int).SetUint64(b.units.RouteUnits([]pkg.Pool{pool})), new(big.Int).SetUint64(price))
+		lamports += ceilQuo(fee, big.NewInt(microLamportsPerLamport)).Uint64()
+	}
+	return lamports
+}
+
+// out returns the cost of a swap through pool in output base units, rounded up
+func (b *boundSwapCosts) out(pool pkg.Pool) math.Int {
+	if b == nil {
+		return math.ZeroInt()
+	}
+	cost := new(big.Rat).Mul(b.perLamport, new(big.Rat).SetInt(new(big.Int).SetUint64(b.lamports(pool))))
+	return math.NewIntFromBigInt(ceilQuo(cost.Num(), cost.Denom()))
+}
+
+// ceilQuo returns x / y rounded up
+func ceilQuo(x, y *big.Int) *big.Int {
+	quo, rem := new(big.Int).QuoRem(x, y, new(big.Int))
+	if rem.Sign() > 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+	return quo
+}
@@ -0,0 +1,167 @@
+package router
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gagliardetto/solana-go"
+)
+
+var (
+	// ErrUnknownAPIKey is returned by Authorize for keys no tenant was registered with
+	ErrUnknownAPIKey = errors.New("unknown api key")
+	// ErrRateLimited is returned by Authorize when a tenant has used up its request budget
+	ErrRateLimited = solerrors.Mark(errors.New("rate limit exceeded"), solerrors.ErrRateLimited)
+)
+
+// Tenant is one application served by a shared deployment
+type Tenant struct {
+	ID string
+	// Protocols the tenant may route through; empty allows every protocol
+	Protocols []pkg.ProtocolName
+	// DefaultSlippageBps applies when a request doesn't set its own slippage
+	DefaultSlippageBps uint64
+	// FeePayer pays the tenant's transaction fees and signs its swaps alongside the user, who
+	// still owns the token accounts; zero leaves the user as fee payer
+	FeePayer solana.PublicKey
+	// RequestsPerSecond refills the tenant's request budget; zero disables rate limiting
+	RequestsPerSecond float64
+	// Burst is the most requests the budget holds; values below 1 use one second's worth
+	Burst int
+}
+
+// Allows reports whether the tenant may route through a protocol
+func (t *Tenant) Allows(name pkg.ProtocolName) bool {
+	return len(t.Protocols) == 0 || slices.Contains(t.Protocols, name)
+}
+
+// tenantState is a tenant with its token bucket
+type tenantState struct {
+	tenant  Tenant
+	tokens  float64
+	updated time.Time
+}
+
+// TenantRegistry scopes requests to tenants by API key and enforces each tenant's rate
+// limit. Keys are only kept as SHA-256 digests. It is safe for concurrent use.
+type TenantRegistry struct {
+	mu         sync.Mutex
+	byKey      map[[32]byte]*tenantState
+	timeSource clock.Clock
+}
+
+// NewTenantRegistry creates an empty registry
+func NewTenantRegistry() *TenantRegistry {
+	return &TenantRegistry{
+		byKey:      make(map[[32]byte]*tenantState),
+		timeSource: clock.System{},
+	}
+}
+
+// SetTimeSource sets the clock rate limits are measured with
+func (r *TenantRegistry) SetTimeSource(timeSource clock.Clock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timeSource = timeSource
+}
+
+// Add registers a tenant under an API key. A tenant may have several keys, which share
+// nothing but the configuration.
+func (r *TenantRegistry) Add(apiKey string, tenant Tenant) error {
+	if apiKey == "" {
+		return fmt.Errorf("api key must not be empty")
+	}
+	if tenant.RequestsPerSecond < 0 {
+		return fmt.Errorf("tenant %s: requests per second must not be negative", tenant.ID)
+	}
+	if tenant.DefaultSlippageBps > 10000 {
+		return fmt.Errorf("tenant %s: default slippage must not exceed 10000 bps", tenant.ID)
+	}
+	if tenant.Burst < 1 {
+		tenant.Burst = max(int(tenant.RequestsPerSecond), 1)
+	}
+	tenant.Protocols = slices.Clone(tenant.Protocols)
+
+	digest := sha256.Sum256([]byte(apiKey))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.byKey[digest]; ok {
+		return fmt.Errorf("api key already registered")
+	}
+	r.byKey[digest] = &tenantState{tenant: tenant, tokens: float64(tenant.Burst), updated: r.timeSource.Now()}
+	return nil
+}
+
+// Remove unregisters an API key
+func (r *TenantRegistry) Remove(apiKey string) {
+	digest := sha256.Sum256([]byte(apiKey))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.byKey, digest)
+}
+
+// Authorize resolves an API key to its tenant and charges one request to the tenant's
+// budget. The returned tenant is a copy.
+func (r *TenantRegistry) Authorize(apiKey string) (Tenant, error) {
+	digest := sha256.Sum256([]byte(apiKey))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, ok := r.byKey[digest]
+	if !ok {
+		return Tenant{}, ErrUnknownAPIKey
+	}
+	tenant := state.tenant
+	tenant.Protocols = slices.Clone(tenant.Protocols)
+	if tenant.RequestsPerSecond == 0 {
+		return tenant, nil
+	}
+
+	now := r.timeSource.Now()
+	if elapsed := now.Sub(state.updated); elapsed > 0 {
+		state.tokens = min(state.tokens+elapsed.Seconds()*tenant.RequestsPerSecond, float64(tenant.Burst))
+	}
+	state.updated = now
+	if state.tokens < 1 {
+		return Tenant{}, fmt.Errorf("tenant %s: %w", tenant.ID, ErrRateLimited)
+	}
+	state.tokens--
+	return tenant, nil
+}
+
+// ForTenant returns a router that shares r's protocols and settings but only discovers and
+// quotes pools of the tenant's allowed protocols. Pools already known to r are carried over,
+// and scoping a scoped router narrows it further.
+func (r *SimpleRouter) ForTenant(tenant Tenant) *SimpleRouter {
+	scoped := *r
+	allowed := slices.Clone(tenant.Protocols)
+	parent := r.poolFilter
+	scoped.poolFilter = func(pool pkg.Pool) bool {
+		if parent != nil && !parent(pool) {
+			return false
+		}
+		return len(allowed) == 0 || slices.Contains(allowed, pool.ProtocolName())
+	}
+	scoped.pools = r.pools.filter(scoped.poolFilter)
+	return &scoped
+}
+
+// filterPools returns the pools that pass the router's pool filter
+func (r *SimpleRouter) filterPools(pools []pkg.Pool) []pkg.Pool {
+	if r.poolFilter == nil {
+		return pools
+	}
+	filtered := make([]pkg.Pool, 0, len(pools))
+	for _, pool := range pools {
+		if r.poolFilter(pool) {
+			filtered = append(filtered, pool)
+		}
+	}
+	return filtered
+}
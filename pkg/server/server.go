@@ -0,0 +1,449 @@
+// Package server exposes a router over HTTP with JSON bodies, so clients in any language can
+// use the routing engine as a self-hosted quote and swap API:
+//
+//	GET  /quote?inputMint=&outputMint=&amount=&slippageBps=
+//	GET  /pools?inputMint=&outputMint=
+//	POST /swap    SwapRequest in, SwapResponse with an unsigned transaction out
+//	GET  /health
+//
+// Amounts are raw token units as decimal strings. Errors are answered as {"error": "..."}.
+//
+// With a tenant registry set, every endpoint but /health takes an API key in the X-API-Key
+// header, and requests are rate limited, routed and priced by the key's tenant.
+//
+// Only HTTP is served. A gRPC service would need google.golang.org/grpc and generated
+// protobuf code, which this module doesn't depend on; JSON over HTTP is the interface for
+// other languages.
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gagliardetto/solana-go"
+)
+
+// DefaultSlippageBps is the slippage tolerance of quotes and swaps that don't set one, unless
+// changed with SetDefaultSlippageBps or by a tenant
+const DefaultSlippageBps = 50
+
+// maxBodyBytes bounds the size of a swap request body
+const maxBodyBytes = 64 << 10
+
+// APIKeyHeader carries a tenant's API key
+const APIKeyHeader = "X-API-Key"
+
+// Server answers quote, pool and swap requests through a router. Pools are discovered on
+// each request, so give the router a pool cache to serve repeated pairs from memory.
+// Requests are handled concurrently on the one router: quotes and swap builds take the
+// pkg.LockPool lock of each pool they read, so requests for the same pools queue on them.
+type Server struct {
+	router      *router.SimpleRouter
+	client      *sol.Client
+	tenants     *router.TenantRegistry
+	slippageBps uint64
+	logger      pkg.Logger
+	mux         *http.ServeMux
+}
+
+// New serves r, building swaps with client. The router quotes through its quote client,
+// see SimpleRouter.SetQuoteClient.
+func New(r *router.SimpleRouter, client *sol.Client) *Server {
+	s := &Server{router: r, client: client, slippageBps: DefaultSlippageBps, logger: slog.Default(), mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /quote", s.handleQuote)
+	s.mux.HandleFunc("GET /pools", s.handlePools)
+	s.mux.HandleFunc("POST /swap", s.handleSwap)
+	s.mux.HandleFunc("GET /health", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return s
+}
+
+// SetLogger sets where failed requests are reported. Nil restores slog's default logger.
+func (s *Server) SetLogger(logger pkg.Logger) {
+	s.logger = pkg.LoggerOrDefault(logger)
+}
+
+// SetDefaultSlippageBps sets the slippage of requests that don't set one and whose tenant has
+// no default. Zero restores DefaultSlippageBps.
+func (s *Server) SetDefaultSlippageBps(bps uint64) {
+	if bps == 0 {
+		bps = DefaultSlippageBps
+	}
+	s.slippageBps = bps
+}
+
+// SetTenants requires an API key registered with tenants on every request but /health.
+// Each request is charged to its tenant's rate limit, routed only through the tenant's
+// protocols, given the tenant's default slippage and, when the tenant has a fee payer,
+// built with it paying the transaction fee. Nil serves every request without a key.
+func (s *Server) SetTenants(tenants *router.TenantRegistry) {
+	s.tenants = tenants
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// RouteHop is one pool of a quoted route
+type RouteHop struct {
+	PoolID     string `json:"poolId"`
+	Protocol   string `json:"protocol"`
+	Venue      string `json:"venue"`
+	ProgramID  string `json:"programId"`
+	InputMint  string `json:"inputMint"`
+	OutputMint string `json:"outputMint"`
+}
+
+// Fee is the LP and protocol fee a route charges in one token
+type Fee struct {
+	Mint        string `json:"mint"`
+	LpFee       string `json:"lpFee"`
+	ProtocolFee string `json:"protocolFee"`
+}
+
+// QuoteResponse is the answer to GET /quote
+type QuoteResponse struct {
+	InputMint    string `json:"inputMint"`
+	OutputMint   string `json:"outputMint"`
+	InAmount     string `json:"inAmount"`
+	OutAmount    string `json:"outAmount"`
+	MinOutAmount string `json:"minOutAmount"`
+	SlippageBps  uint64 `json:"slippageBps"`
+	// PriceImpactBps is omitted when the route's pre-trade price is unknown
+	PriceImpactBps *uint64    `json:"priceImpactBps,omitempty"`
+	ComputeUnits   uint64     `json:"computeUnits,omitempty"`
+	Slot           uint64     `json:"slot,omitempty"`
+	Route          []RouteHop `json:"route"`
+	Fees           []Fee      `json:"fees,omitempty"`
+}
+
+// Pool is a discovered pool in the answer to GET /pools
+type Pool struct {
+	ID        string `json:"id"`
+	Protocol  string `json:"protocol"`
+	Venue     string `json:"venue"`
+	ProgramID string `json:"programId"`
+	BaseMint  string `json:"baseMint"`
+	QuoteMint string `json:"quoteMint"`
+}
+
+// PoolsResponse is the answer to GET /pools. Protocols that failed or ran out of time are
+// listed by name, and their pools are missing.
+type PoolsResponse struct {
+	Pools    []Pool   `json:"pools"`
+	Failed   []string `json:"failed,omitempty"`
+	TimedOut []string `json:"timedOut,omitempty"`
+}
+
+// SwapRequest is the body of POST /swap
+type SwapRequest struct {
+	InputMint  string `json:"inputMint"`
+	OutputMint string `json:"outputMint"`
+	Amount     string `json:"amount"`
+	// SlippageBps is the tenant's or the server's default when zero
+	SlippageBps uint64 `json:"slippageBps"`
+	// UserPublicKey signs and pays for the swap and owns its token accounts
+	UserPublicKey string `json:"userPublicKey"`
+	// PriorityFee is the compute unit price in micro-lamports, zero for none
+	PriorityFee uint64 `json:"priorityFee"`
+	// WrapSol funds a WSOL input from the user's SOL and unwraps a WSOL output
+	WrapSol bool `json:"wrapSol"`
+	// CreateOutputAccount creates the user's output token account when it doesn't exist
+	CreateOutputAccount bool `json:"createOutputAccount"`
+}
+
+// SwapResponse is the answer to POST /swap: the swap as a base64 transaction for the user to
+// sign and send before LastValidBlockHeight
+type SwapResponse struct {
+	Transaction          string        `json:"transaction"`
+	LastValidBlockHeight uint64        `json:"lastValidBlockHeight"`
+	Quote                QuoteResponse `json:"quote"`
+}
+
+// scope is what a request is served with: the whole router, or its tenant's share of it
+type scope struct {
+	router      *router.SimpleRouter
+	slippageBps uint64
+	feePayer    solana.PublicKey
+}
+
+// errMissingAPIKey answers requests without a key when tenants are set
+var errMissingAPIKey = errors.New("missing " + APIKeyHeader + " header")
+
+// scope authorizes r's API key, when tenants are set, and returns what to serve it with
+func (s *Server) scope(r *http.Request) (scope, error) {
+	if s.tenants == nil {
+		return scope{router: s.router, slippageBps: s.slippageBps}, nil
+	}
+	apiKey := r.Header.Get(APIKeyHeader)
+	if apiKey == "" {
+		return scope{}, errMissingAPIKey
+	}
+	tenant, err := s.tenants.Authorize(apiKey)
+	if err != nil {
+		return scope{}, err
+	}
+	sc := scope{router: s.router.ForTenant(tenant), slippageBps: s.slippageBps, feePayer: tenant.FeePayer}
+	if tenant.DefaultSlippageBps != 0 {
+		sc.slippageBps = tenant.DefaultSlippageBps
+	}
+	return sc, nil
+}
+
+// badRequest marks errors in what the client sent
+type badRequest struct {
+	err error
+}
+
+func (e badRequest) Error() string {
+	return e.err.Error()
+}
+
+func (e badRequest) Unwrap() error {
+	return e.err
+}
+
+func badRequestf(format string, args ...any) error {
+	return badRequest{fmt.Errorf(format, args...)}
+}
+
+func (s *Server) handleQuote(w http.ResponseWriter, r *http.Request) {
+	sc, err := s.scope(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	query := r.URL.Query()
+	req, err := swapRequest(query.Get("inputMint"), query.Get("outputMint"), query.Get("amount"), query.Get("slippageBps"), sc.slippageBps)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	result, err := quote(r.Context(), sc.router, req)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, quoteResponse(result))
+}
+
+func (s *Server) handlePools(w http.ResponseWriter, r *http.Request) {
+	sc, err := s.scope(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	query := r.URL.Query()
+	inputMint, outputMint := query.Get("inputMint"), query.Get("outputMint")
+	for _, mint := range []string{inputMint, outputMint} {
+		if _, err := solana.PublicKeyFromBase58(mint); err != nil {
+			s.writeError(w, r, badRequestf("invalid mint %q: %v", mint, err))
+			return
+		}
+	}
+	discovery, err := sc.router.DiscoverPools(r.Context(), inputMint, outputMint)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	resp := PoolsResponse{Pools: make([]Pool, 0, len(discovery.Pools))}
+	for _, pool := range discovery.Pools {
+		baseMint, quoteMint := pool.GetTokens()
+		resp.Pools = append(resp.Pools, Pool{
+			ID:        pool.GetID(),
+			Protocol:  string(pool.ProtocolName()),
+			Venue:     router.VenueName(pool.ProtocolName()),
+			ProgramID: pool.GetProgramID().String(),
+			BaseMint:  baseMint,
+			QuoteMint: quoteMint,
+		})
+	}
+	for _, failure := range discovery.Failed {
+		resp.Failed = append(resp.Failed, protocolName(failure.Protocol))
+	}
+	for _, proto := range discovery.TimedOut {
+		resp.TimedOut = append(resp.TimedOut, protocolName(proto))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleSwap(w http.ResponseWriter, r *http.Request) {
+	sc, err := s.scope(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	var body SwapRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		s.writeError(w, r, badRequestf("invalid swap request: %v", err))
+		return
+	}
+	req, err := swapRequest(body.InputMint, body.OutputMint, body.Amount, strconv.FormatUint(body.SlippageBps, 10), sc.slippageBps)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	user, err := solana.PublicKeyFromBase58(body.UserPublicKey)
+	if err != nil {
+		s.writeError(w, r, badRequestf("invalid userPublicKey %q: %v", body.UserPublicKey, err))
+		return
+	}
+	req = req.WithFeePayer(user).WithPriorityFee(body.PriorityFee)
+
+	ctx := r.Context()
+	result, err := quote(ctx, sc.router, req)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	instructions, err := s.client.BuildSwapRequest(ctx, result.Route.Pools[0], req, result.ExpectedOut, sol.SwapOptions{
+		WrapSol:             body.WrapSol,
+		CreateOutputAccount: body.CreateOutputAccount,
+	})
+	if err != nil {
+		s.writeError(w, r, fmt.Errorf("failed to build swap: %w", err))
+		return
+	}
+	if s.client.Blockhashes == nil {
+		s.writeError(w, r, fmt.Errorf("client has no blockhash cache"))
+		return
+	}
+	blockhash, lastValid, err := s.client.Blockhashes.Get(ctx)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	payer := user
+	if !sc.feePayer.IsZero() {
+		payer = sc.feePayer
+	}
+	tx, err := solana.NewTransaction(instructions, blockhash, solana.TransactionPayer(payer))
+	if err != nil {
+		s.writeError(w, r, fmt.Errorf("failed to build transaction: %w", err))
+		return
+	}
+	// Leave a blank signature per signer for the user's wallet, and the tenant's fee payer,
+	// to fill in
+	tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		s.writeError(w, r, fmt.Errorf("failed to encode transaction: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, SwapResponse{
+		Transaction:          base64.StdEncoding.EncodeToString(data),
+		LastValidBlockHeight: lastValid,
+		Quote:                quoteResponse(result),
+	})
+}
+
+// quote discovers the pair's pools and quotes req through the best one
+func quote(ctx context.Context, r *router.SimpleRouter, req pkg.SwapRequest) (router.QuoteResult, error) {
+	if err := req.Validate(); err != nil {
+		return router.QuoteResult{}, badRequest{err}
+	}
+	if _, err := r.QueryAllPools(ctx, req.InputMint, req.OutputMint); err != nil {
+		return router.QuoteResult{}, fmt.Errorf("failed to discover pools: %w", err)
+	}
+	return r.QuoteSwap(ctx, nil, req)
+}
+
+// protocolName names proto by the pools it discovers, or by its type when it doesn't say
+func protocolName(proto pkg.Protocol) string {
+	if named, ok := proto.(pkg.NamedProtocol); ok {
+		return string(named.Name())
+	}
+	return fmt.Sprintf("%T", proto)
+}
+
+// swapRequest parses the fields quotes and swaps share, with defaultBps when the request
+// doesn't set a slippage
+func swapRequest(inputMint, outputMint, amount, slippageBps string, defaultBps uint64) (pkg.SwapRequest, error) {
+	// Raw amounts are whole base units; parsed strictly so "010" isn't read as octal
+	amountIn, err := sol.ParseAmount(amount, 0)
+	if err != nil {
+		return pkg.SwapRequest{}, badRequest{err}
+	}
+	bps := defaultBps
+	if slippageBps != "" && slippageBps != "0" {
+		if bps, err = strconv.ParseUint(slippageBps, 10, 64); err != nil {
+			return pkg.SwapRequest{}, badRequestf("invalid slippageBps %q", slippageBps)
+		}
+	}
+	return pkg.NewSwapRequest(inputMint, outputMint, amountIn).WithSlippageBps(bps), nil
+}
+
+func quoteResponse(result router.QuoteResult) QuoteResponse {
+	resp := QuoteResponse{
+		InputMint:    result.InputMint,
+		OutputMint:   result.OutputMint,
+		InAmount:     result.AmountIn.String(),
+		OutAmount:    result.ExpectedOut.String(),
+		MinOutAmount: result.MinOut.String(),
+		SlippageBps:  result.SlippageBps,
+		ComputeUnits: result.Route.ComputeUnits,
+		Slot:         result.Route.Slot,
+		Route:        make([]RouteHop, 0, len(result.Hops)),
+	}
+	if result.PriceImpactKnown {
+		impact := result.PriceImpactBps
+		resp.PriceImpactBps = &impact
+	}
+	for _, hop := range result.Hops {
+		resp.Route = append(resp.Route, RouteHop{
+			PoolID:     hop.PoolID,
+			Protocol:   string(hop.Protocol),
+			Venue:      hop.Venue,
+			ProgramID:  hop.ProgramID.String(),
+			InputMint:  hop.InputMint,
+			OutputMint: hop.OutputMint,
+		})
+	}
+	for _, fee := range result.Fees {
+		resp.Fees = append(resp.Fees, Fee{Mint: fee.Mint, LpFee: fee.LpFee.String(), ProtocolFee: fee.ProtocolFee.String()})
+	}
+	return resp
+}
+
+// writeError answers with err and the status its kind calls for
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	var bad badRequest
+	switch {
+	case errors.As(err, &bad):
+		status = http.StatusBadRequest
+	case errors.Is(err, errMissingAPIKey), errors.Is(err, router.ErrUnknownAPIKey):
+		status = http.StatusUnauthorized
+	case errors.Is(err, solerrors.ErrNoRoute), errors.Is(err, solerrors.ErrInsufficientLiquidity):
+		status = http.StatusNotFound
+	case errors.Is(err, solerrors.ErrPriceImpactTooHigh):
+		status = http.StatusUnprocessableEntity
+	case solerrors.IsRateLimited(err):
+		status = http.StatusTooManyRequests
+	case errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusGatewayTimeout
+	}
+	if status >= http.StatusInternalServerError {
+		s.logger.Error("request failed", "path", r.URL.Path, pkg.LogKeyError, err)
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/protocolregistry"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gagliardetto/solana-go"
+)
+
+// TenantConfig is one entry of a tenants file
+type TenantConfig struct {
+	APIKey string `json:"apiKey"`
+	ID     string `json:"id"`
+	// Protocols are protocolregistry names; empty allows every protocol
+	Protocols   []string `json:"protocols"`
+	SlippageBps uint64   `json:"slippageBps"`
+	// FeePayer is the public key paying the tenant's transaction fees; its key stays with the
+	// tenant, which signs the swaps it is returned
+	FeePayer          string  `json:"feePayer"`
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+	Burst             int     `json:"burst"`
+}
+
+// LoadTenants reads a JSON array of TenantConfig from path into a registry for SetTenants
+func LoadTenants(path string) (*router.TenantRegistry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenants: %w", err)
+	}
+	var configs []TenantConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse tenants %s: %w", path, err)
+	}
+	known := protocolregistry.Names()
+	registry := router.NewTenantRegistry()
+	for i, cfg := range configs {
+		tenant := router.Tenant{
+			ID:                 cfg.ID,
+			DefaultSlippageBps: cfg.SlippageBps,
+			RequestsPerSecond:  cfg.RequestsPerSecond,
+			Burst:              cfg.Burst,
+		}
+		for _, name := range cfg.Protocols {
+			if !slices.Contains(known, pkg.ProtocolName(name)) {
+				return nil, fmt.Errorf("tenants[%d]: unknown protocol %q", i, name)
+			}
+			tenant.Protocols = append(tenant.Protocols, pkg.ProtocolName(name))
+		}
+		if cfg.FeePayer != "" {
+			if tenant.FeePayer, err = solana.PublicKeyFromBase58(cfg.FeePayer); err != nil {
+				return nil, fmt.Errorf("tenants[%d]: invalid fee payer %q: %w", i, cfg.FeePayer, err)
+			}
+		}
+		if err := registry.Add(cfg.APIKey, tenant); err != nil {
+			return nil, fmt.Errorf("tenants[%d]: %w", i, err)
+		}
+	}
+	return registry, nil
+}
@@ -0,0 +1,98 @@
+package pkg
+
+import (
+	"fmt"
+
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"cosmossdk.io/math"
+)
+
+// MaxBps is 100% in basis points
+const MaxBps = 10000
+
+// SlippageConfig sets the minimum output a swap accepts relative to its quote. The tolerance
+// is Bps, widened by ImpactFactorBps of the quote's price impact up to MaxBps. MinOut, when
+// set, replaces the tolerance with an absolute minimum.
+type SlippageConfig struct {
+	// Bps is the tolerance below the expected output
+	Bps uint64
+	// MinOut is an absolute minimum output; the quote is rejected if it expects less
+	MinOut math.Int
+	// ImpactFactorBps adds this share of the price impact to the tolerance, e.g. 5000 adds
+	// half of it, so larger trades get more room against price moves before they land
+	ImpactFactorBps uint64
+	// MaxBps caps the widened tolerance; zero caps it at 100%
+	MaxBps uint64
+	// MaxImpactBps rejects quotes with a higher price impact, or an unknown one; zero accepts
+	// any impact
+	MaxImpactBps uint64
+}
+
+// SlippageBps is a fixed tolerance of bps below the expected output
+func SlippageBps(bps uint64) SlippageConfig {
+	return SlippageConfig{Bps: bps}
+}
+
+// Validate rejects tolerances above 100% and absolute minimums that aren't positive
+func (c SlippageConfig) Validate() error {
+	if c.Bps > MaxBps || c.MaxBps > MaxBps || c.MaxImpactBps > MaxBps {
+		return fmt.Errorf("slippage bps values must not exceed %d", MaxBps)
+	}
+	if c.MaxBps != 0 && c.MaxBps < c.Bps {
+		return fmt.Errorf("max slippage %d bps is below the base %d bps", c.MaxBps, c.Bps)
+	}
+	if !c.MinOut.IsNil() && !c.MinOut.IsPositive() {
+		return fmt.Errorf("minimum output must be positive")
+	}
+	return nil
+}
+
+// ToleranceBps returns the tolerance for a quote with the given price impact
+func (c SlippageConfig) ToleranceBps(impactBps uint64) uint64 {
+	tolerance := c.Bps + impactBps*c.ImpactFactorBps/MaxBps
+	limit := c.MaxBps
+	if limit == 0 {
+		limit = MaxBps
+	}
+	return min(tolerance, limit)
+}
+
+// CheckImpact rejects a price impact above MaxImpactBps with ErrPriceImpactTooHigh
+func (c SlippageConfig) CheckImpact(impactBps uint64, impactKnown bool) error {
+	if c.MaxImpactBps == 0 {
+		return nil
+	}
+	if !impactKnown {
+		return fmt.Errorf("%w: impact unknown, limit %d bps", solerrors.ErrPriceImpactTooHigh, c.MaxImpactBps)
+	}
+	if impactBps > c.MaxImpactBps {
+		return fmt.Errorf("%w: %d bps exceeds %d bps", solerrors.ErrPriceImpactTooHigh, impactBps, c.MaxImpactBps)
+	}
+	return nil
+}
+
+// MinAmountOut returns the minimum output to swap with for a quote of expectedOut at the
+// given price impact, and the tolerance applied in bps. An unknown impact widens nothing.
+func (c SlippageConfig) MinAmountOut(expectedOut math.Int, impactBps uint64, impactKnown bool) (math.Int, uint64, error) {
+	if err := c.Validate(); err != nil {
+		return math.Int{}, 0, err
+	}
+	if expectedOut.IsNil() || !expectedOut.IsPositive() {
+		return math.Int{}, 0, fmt.Errorf("expected output must be positive")
+	}
+	if err := c.CheckImpact(impactBps, impactKnown); err != nil {
+		return math.Int{}, 0, err
+	}
+	if !c.MinOut.IsNil() {
+		if c.MinOut.GT(expectedOut) {
+			return math.Int{}, 0, fmt.Errorf("expected output %s is below the minimum %s", expectedOut, c.MinOut)
+		}
+		tolerance := expectedOut.Sub(c.MinOut).MulRaw(MaxBps).Quo(expectedOut).Uint64()
+		return c.MinOut, tolerance, nil
+	}
+	if !impactKnown {
+		impactBps = 0
+	}
+	tolerance := c.ToleranceBps(impactBps)
+	return expectedOut.MulRaw(int64(MaxBps - tolerance)).QuoRaw(MaxBps), tolerance, nil
+}
@@ -0,0 +1,156 @@
+package sol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// AccountSnapshot serves account reads from accounts fetched ahead of time with Prefetch,
+// and reads the accounts it doesn't hold from the wrapped RPC. Every other request goes to
+// the wrapped RPC. Accounts served from the snapshot are shared by every reader and must
+// not be modified. It is safe for concurrent use.
+type AccountSnapshot struct {
+	RPC
+
+	mu       sync.RWMutex
+	accounts map[solana.PublicKey]*rpc.Account // nil for accounts known not to exist
+	slot     rpc.Context                       // context of the latest batch fetched
+}
+
+var _ RPC = (*AccountSnapshot)(nil)
+
+// NewAccountSnapshot returns an empty snapshot reading through inner
+func NewAccountSnapshot(inner RPC) *AccountSnapshot {
+	return &AccountSnapshot{RPC: inner, accounts: make(map[solana.PublicKey]*rpc.Account)}
+}
+
+// Prefetch fetches the accounts not yet held, in concurrent getMultipleAccounts batches
+// read with ctx's read options
+func (s *AccountSnapshot) Prefetch(ctx context.Context, accounts []solana.PublicKey) error {
+	if err := s.fetchMissing(ctx, accounts, MultipleAccountsOpts(ctx, rpc.CommitmentProcessed)); err != nil {
+		return fmt.Errorf("failed to prefetch accounts: %w", err)
+	}
+	return nil
+}
+
+// Len returns the number of accounts held, including those known not to exist
+func (s *AccountSnapshot) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.accounts)
+}
+
+// missing returns the accounts not held, without duplicates
+func (s *AccountSnapshot) missing(accounts []solana.PublicKey) []solana.PublicKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	seen := make(map[solana.PublicKey]bool, len(accounts))
+	var missing []solana.PublicKey
+	for _, account := range accounts {
+		if _, ok := s.accounts[account]; ok || seen[account] {
+			continue
+		}
+		seen[account] = true
+		missing = append(missing, account)
+	}
+	return missing
+}
+
+// fetchMissing reads the accounts not held from the wrapped RPC in concurrent batches
+func (s *AccountSnapshot) fetchMissing(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) error {
+	missing := s.missing(accounts)
+	errs := make([]error, (len(missing)+maxMultipleAccounts-1)/maxMultipleAccounts)
+	var wg sync.WaitGroup
+	for i := range errs {
+		batch := missing[i*maxMultipleAccounts : min((i+1)*maxMultipleAccounts, len(missing))]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.fetch(ctx, batch, opts)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// fetch reads a batch of accounts from the wrapped RPC and stores them
+func (s *AccountSnapshot) fetch(ctx context.Context, batch []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) error {
+	results, err := s.RPC.GetMultipleAccountsWithOpts(ctx, batch, opts)
+	if err != nil {
+		return err
+	}
+	if len(results.Value) != len(batch) {
+		return fmt.Errorf("expected %d accounts, got %d", len(batch), len(results.Value))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, account := range results.Value {
+		s.accounts[batch[i]] = account
+	}
+	if results.Context.Slot > s.slot.Slot {
+		s.slot = results.Context
+	}
+	return nil
+}
+
+// lookup returns the held accounts, or false if any of them isn't held
+func (s *AccountSnapshot) lookup(accounts []solana.PublicKey) ([]*rpc.Account, rpc.Context, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*rpc.Account, len(accounts))
+	for i, account := range accounts {
+		held, ok := s.accounts[account]
+		if !ok {
+			return nil, rpc.Context{}, false
+		}
+		out[i] = held
+	}
+	return out, s.slot, true
+}
+
+func (s *AccountSnapshot) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	return s.GetAccountInfoWithOpts(ctx, account, nil)
+}
+
+func (s *AccountSnapshot) GetAccountInfoWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetAccountInfoOpts) (*rpc.GetAccountInfoResult, error) {
+	var multiOpts *rpc.GetMultipleAccountsOpts
+	if opts != nil {
+		multiOpts = (*rpc.GetMultipleAccountsOpts)(opts)
+	}
+	results, err := s.GetMultipleAccountsWithOpts(ctx, []solana.PublicKey{account}, multiOpts)
+	if err != nil {
+		return nil, err
+	}
+	if results.Value[0] == nil {
+		return nil, rpc.ErrNotFound
+	}
+	return &rpc.GetAccountInfoResult{RPCContext: results.RPCContext, Value: results.Value[0]}, nil
+}
+
+func (s *AccountSnapshot) GetMultipleAccounts(ctx context.Context, accounts ...solana.PublicKey) (*rpc.GetMultipleAccountsResult, error) {
+	return s.GetMultipleAccountsWithOpts(ctx, accounts, nil)
+}
+
+// GetMultipleAccountsWithOpts serves the accounts held and fetches the rest. Reads of a data
+// slice always go to the wrapped RPC.
+func (s *AccountSnapshot) GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error) {
+	if opts != nil && opts.DataSlice != nil {
+		return s.RPC.GetMultipleAccountsWithOpts(ctx, accounts, opts)
+	}
+	if opts == nil {
+		opts = MultipleAccountsOpts(ctx, rpc.CommitmentProcessed)
+	}
+	if err := s.fetchMissing(ctx, accounts, opts); err != nil {
+		return nil, err
+	}
+	held, slot, ok := s.lookup(accounts)
+	if !ok {
+		return nil, fmt.Errorf("accounts missing from snapshot after fetch")
+	}
+	return &rpc.GetMultipleAccountsResult{RPCContext: rpc.RPCContext{Context: slot}, Value: held}, nil
+}
@@ -0,0 +1,155 @@
+package sol
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+)
+
+// AccountUpdate is a new state of an account, as pushed by a streaming source
+type AccountUpdate struct {
+	Pubkey   solana.PublicKey
+	Owner    solana.PublicKey
+	Slot     uint64
+	Lamports uint64
+	Data     []byte
+}
+
+// AccountStream pushes updates to every account owned by the given programs. WSAccountStream
+// implements it over the RPC node's WebSocket; yellowstone.Client, in the pkg/sol/yellowstone
+// module, over a Yellowstone gRPC (geyser) endpoint. The channel is closed when ctx is done
+// or the stream fails.
+type AccountStream interface {
+	Subscribe(ctx context.Context, programs []solana.PublicKey) (<-chan AccountUpdate, error)
+}
+
+// AccountWatcher pushes updates to the given accounts, e.g. pool vaults. yellowstone.Client
+// implements it with an accounts filter listing them; WSAccountStream implements it with
+// accountSubscribe. The channel is closed when ctx is done or the stream fails.
+type AccountWatcher interface {
+	SubscribeAccounts(ctx context.Context, accounts []solana.PublicKey) (<-chan AccountUpdate, error)
+}
+
+// WSAccountStream streams program accounts with programSubscribe and single accounts with
+// accountSubscribe. It is slower than a geyser plugin but needs nothing beyond the RPC node.
+type WSAccountStream struct {
+	client     *ws.Client
+	commitment rpc.CommitmentType
+}
+
+// NewWSAccountStream streams confirmed updates through wsClient
+func NewWSAccountStream(wsClient *ws.Client) *WSAccountStream {
+	return &WSAccountStream{client: wsClient, commitment: rpc.CommitmentConfirmed}
+}
+
+// SetCommitment sets the commitment updates are pushed at
+func (s *WSAccountStream) SetCommitment(commitment rpc.CommitmentType) {
+	s.commitment = commitment
+}
+
+func (s *WSAccountStream) Subscribe(ctx context.Context, programs []solana.PublicKey) (<-chan AccountUpdate, error) {
+	subs := make([]*ws.ProgramSubscription, 0, len(programs))
+	for _, program := range programs {
+		sub, err := s.client.ProgramSubscribeWithOpts(program, s.commitment, solana.EncodingBase64, nil)
+		if err != nil {
+			for _, sub := range subs {
+				sub.Unsubscribe()
+			}
+			return nil, fmt.Errorf("failed to subscribe to %s: %w", program, err)
+		}
+		subs = append(subs, sub)
+	}
+	sources := make([]updateSource, len(subs))
+	for i, sub := range subs {
+		sources[i] = updateSource{
+			recv: func() (AccountUpdate, error) {
+				res, err := sub.Recv(ctx)
+				if err != nil {
+					return AccountUpdate{}, err
+				}
+				return newAccountUpdate(res.Value.Pubkey, res.Context.Slot, res.Value.Account), nil
+			},
+			close: sub.Unsubscribe,
+		}
+	}
+	return fanIn(ctx, sources), nil
+}
+
+func (s *WSAccountStream) SubscribeAccounts(ctx context.Context, accounts []solana.PublicKey) (<-chan AccountUpdate, error) {
+	subs := make([]*ws.AccountSubscription, 0, len(accounts))
+	for _, account := range accounts {
+		sub, err := s.client.AccountSubscribeWithOpts(account, s.commitment, solana.EncodingBase64)
+		if err != nil {
+			for _, sub := range subs {
+				sub.Unsubscribe()
+			}
+			return nil, fmt.Errorf("failed to subscribe to %s: %w", account, err)
+		}
+		subs = append(subs, sub)
+	}
+	sources := make([]updateSource, len(subs))
+	for i, sub := range subs {
+		account := accounts[i]
+		sources[i] = updateSource{
+			recv: func() (AccountUpdate, error) {
+				res, err := sub.Recv(ctx)
+				if err != nil {
+					return AccountUpdate{}, err
+				}
+				return newAccountUpdate(account, res.Context.Slot, &res.Value.Account), nil
+			},
+			close: sub.Unsubscribe,
+		}
+	}
+	return fanIn(ctx, sources), nil
+}
+
+func newAccountUpdate(pubkey solana.PublicKey, slot uint64, account *rpc.Account) AccountUpdate {
+	update := AccountUpdate{Pubkey: pubkey, Slot: slot}
+	if account != nil {
+		update.Owner = account.Owner
+		update.Lamports = account.Lamports
+		update.Data = account.Data.GetBinary()
+	}
+	return update
+}
+
+// updateSource is one subscription feeding a stream
+type updateSource struct {
+	recv  func() (AccountUpdate, error)
+	close func()
+}
+
+// fanIn merges the sources into one channel, closed once every source has failed or ctx is
+// done
+func fanIn(ctx context.Context, sources []updateSource) <-chan AccountUpdate {
+	updates := make(chan AccountUpdate, 256)
+	var wg sync.WaitGroup
+	for _, source := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer source.close()
+			for {
+				update, err := source.recv()
+				if err != nil {
+					return
+				}
+				select {
+				case updates <- update:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(updates)
+	}()
+	return updates
+}
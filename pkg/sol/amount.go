@@ -0,0 +1,53 @@
+package sol
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"cosmossdk.io/math"
+)
+
+// ErrInvalidAmount is returned for decimal amounts that don't convert exactly to base units
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// ParseAmount converts a decimal amount such as "1.5" to base units of a token with the given
+// decimals, exactly and without floating point. Only plain non-negative decimals are
+// accepted: no signs, exponents, separators or spaces, and no more fractional digits than the
+// token has, since those can't be sent.
+func ParseAmount(s string, decimals uint8) (math.Int, error) {
+	intPart, fracPart, hasPoint := strings.Cut(s, ".")
+	if intPart == "" || (hasPoint && fracPart == "") || !isDigits(intPart) || !isDigits(fracPart) {
+		return math.Int{}, fmt.Errorf("%w %q: not a decimal number", ErrInvalidAmount, s)
+	}
+	if len(fracPart) > int(decimals) {
+		return math.Int{}, fmt.Errorf("%w %q: more than %d decimal places", ErrInvalidAmount, s, decimals)
+	}
+	// Base 10 explicitly: math.NewIntFromString would read a leading zero as octal
+	raw, ok := new(big.Int).SetString(intPart+fracPart+strings.Repeat("0", int(decimals)-len(fracPart)), 10)
+	if !ok || raw.BitLen() > math.MaxBitLen {
+		return math.Int{}, fmt.Errorf("%w %q: out of range", ErrInvalidAmount, s)
+	}
+	return math.NewIntFromBigInt(raw), nil
+}
+
+// ParseAmount converts an amount such as "1.5" or "1.5 USDC" to base units of the token, as
+// the package's ParseAmount does. A unit, when given, must be the token's symbol, in any
+// case, or its mint.
+func (t TokenInfo) ParseAmount(s string) (math.Int, error) {
+	amount, unit, hasUnit := strings.Cut(s, " ")
+	if hasUnit && !strings.EqualFold(unit, t.Symbol) && unit != t.Mint.String() {
+		return math.Int{}, fmt.Errorf("%w %q: not in %s", ErrInvalidAmount, s, t.Label())
+	}
+	return ParseAmount(amount, t.Decimals)
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
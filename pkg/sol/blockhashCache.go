@@ -0,0 +1,141 @@
+package sol
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+)
+
+const (
+	// DefaultBlockhashMaxAge is how long a cached blockhash is used before fetching another.
+	// Blockhashes expire after 150 blocks, about a minute, so this leaves time to land.
+	DefaultBlockhashMaxAge = 20 * time.Second
+	// blockhashRefreshSlots is how many slots pass between refreshes when following slots
+	blockhashRefreshSlots = 50
+)
+
+// BlockhashCache keeps a recent blockhash so sending a transaction doesn't wait on
+// getLatestBlockhash. A blockhash older than the max age is fetched again on use, and
+// StartPolling or FollowSlots keep it fresh in the background. It is safe for concurrent use.
+type BlockhashCache struct {
+	client     RPC
+	commitment rpc.CommitmentType
+	maxAge     time.Duration
+	clock      clock.Clock
+
+	mu                   sync.Mutex
+	hash                 solana.Hash
+	lastValidBlockHeight uint64
+	fetchedAt            time.Time
+}
+
+// NewBlockhashCache returns an empty cache reading confirmed blockhashes through client
+func NewBlockhashCache(client RPC) *BlockhashCache {
+	return &BlockhashCache{
+		client:     client,
+		commitment: rpc.CommitmentConfirmed,
+		maxAge:     DefaultBlockhashMaxAge,
+		clock:      clock.System{},
+	}
+}
+
+// SetCommitment sets the commitment blockhashes are read at
+func (b *BlockhashCache) SetCommitment(commitment rpc.CommitmentType) {
+	b.commitment = commitment
+}
+
+// SetMaxAge sets how long a blockhash is used before fetching another
+func (b *BlockhashCache) SetMaxAge(d time.Duration) {
+	b.maxAge = d
+}
+
+// SetClock replaces the time source, e.g. with clock.Fake in tests
+func (b *BlockhashCache) SetClock(clk clock.Clock) {
+	b.clock = clk
+}
+
+// Get returns the cached blockhash and the last block height it is valid at, fetching a new
+// one when none is cached or it is older than the max age
+func (b *BlockhashCache) Get(ctx context.Context) (solana.Hash, uint64, error) {
+	b.mu.Lock()
+	hash, lastValid, fetchedAt := b.hash, b.lastValidBlockHeight, b.fetchedAt
+	b.mu.Unlock()
+	if !fetchedAt.IsZero() && b.clock.Now().Sub(fetchedAt) < b.maxAge {
+		return hash, lastValid, nil
+	}
+	return b.Refresh(ctx)
+}
+
+// Refresh fetches the latest blockhash and caches it
+func (b *BlockhashCache) Refresh(ctx context.Context) (solana.Hash, uint64, error) {
+	res, err := b.client.GetLatestBlockhash(ctx, b.commitment)
+	if err != nil {
+		return solana.Hash{}, 0, fmt.Errorf("failed to get blockhash: %w", err)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.hash = res.Value.Blockhash
+	b.lastValidBlockHeight = res.Value.LastValidBlockHeight
+	b.fetchedAt = b.clock.Now()
+	return b.hash, b.lastValidBlockHeight, nil
+}
+
+// Invalidate drops the cached blockhash, e.g. after a node reported it expired
+func (b *BlockhashCache) Invalidate() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.fetchedAt = time.Time{}
+}
+
+// StartPolling refreshes the blockhash each interval until ctx is done
+func (b *BlockhashCache) StartPolling(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				b.Refresh(ctx)
+			}
+		}
+	}()
+}
+
+// FollowSlots refreshes the blockhash every 50 slots reported by a WebSocket slot
+// subscription, until ctx is done or the subscription fails
+func (b *BlockhashCache) FollowSlots(ctx context.Context, wsClient *ws.Client) error {
+	sub, err := wsClient.SlotSubscribe()
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to slots: %w", err)
+	}
+	go func() {
+		defer sub.Unsubscribe()
+		var refreshedAt uint64
+		for {
+			slot, err := sub.Recv(ctx)
+			if err != nil {
+				return
+			}
+			if slot.Slot >= refreshedAt+blockhashRefreshSlots {
+				if _, _, err := b.Refresh(ctx); err == nil {
+					refreshedAt = slot.Slot
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// isBlockhashExpired reports whether a node rejected a transaction for its blockhash
+func isBlockhashExpired(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "blockhash not found")
+}
@@ -1,24 +1,61 @@
+// Package sol wraps the Solana RPC and WebSocket clients with what executing a swap needs:
+// transaction sending and signing, WSOL wrapping, token account and PDA helpers, Token-2022
+// mint extensions, priority fees and pool vault verification.
 package sol
 
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gtdvccc/SolRouteTmp/pkg/telemetry"
 	"github.com/gagliardetto/solana-go/rpc"
 	"github.com/gagliardetto/solana-go/rpc/ws"
 )
 
+// RPC is the RPC API the client and the venues built on it use; see pkg.RPC
+type RPC = pkg.RPC
+
 // Client represents a Solana client that handles both RPC and WebSocket connections
 type Client struct {
-	RpcClient *rpc.Client
+	RpcClient RPC
 	WsClient  *ws.Client
+
+	// Cluster is the network the client reads, which protocols built on it consult for their
+	// program IDs and default pools. NewClient detects it from the endpoint; see ClusterOf.
+	Cluster Cluster
+
+	// TimeSource, Sleeper and Rand back retry and expiry logic; replace them with
+	// clock.Fake or a seeded source to make that logic deterministic in tests
+	TimeSource clock.Clock
+	Sleeper    clock.Sleeper
+	Rand       clock.Rand
+
+	// Logger receives the client's diagnostics and those of the protocols and pools built on
+	// it; nil logs to slog's default logger
+	Logger pkg.Logger
+
+	// PriorityFees backs SendTxWithFeeTier
+	PriorityFees *PriorityFeeOracle
+
+	// Blockhashes supplies the blockhash of transactions sent without one
+	Blockhashes *BlockhashCache
+
+	// telemetry traces and meters the client, see SetTelemetry
+	telemetry *telemetry.Telemetry
+
+	// UnwrapWsolOutput makes WrapWsolSwap close the WSOL account after swaps into WSOL, so the
+	// user receives native SOL, unless WsolOptions.UnwrapOutput says otherwise. NewClient
+	// enables it.
+	UnwrapWsolOutput bool
 }
 
 // NewClient creates a new Solana client with both RPC and WebSocket connections
 func NewClient(ctx context.Context, endpoint, wsEndpoint string) (*Client, error) {
-	c := &Client{
-		RpcClient: rpc.New(endpoint),
-	}
+	c := newClient(rpc.New(endpoint))
+	c.Cluster = DetectCluster(endpoint)
 	if wsEndpoint != "" {
 		// Initialize WebSocket client
 		wsClient, err := ws.Connect(ctx, wsEndpoint)
@@ -30,6 +67,27 @@ func NewClient(ctx context.Context, endpoint, wsEndpoint string) (*Client, error
 	return c, nil
 }
 
+// logger returns the client's logger, defaulting to slog's
+func (c *Client) logger() pkg.Logger {
+	return pkg.LoggerOrDefault(c.Logger)
+}
+
+// newClient creates a client reading and sending through rpcClient, without WebSocket
+func newClient(rpcClient RPC) *Client {
+	c := &Client{
+		RpcClient:  rpcClient,
+		TimeSource: clock.System{},
+		Sleeper:    clock.System{},
+		Rand:       clock.System{},
+		Logger:     slog.Default(),
+
+		UnwrapWsolOutput: true,
+	}
+	c.PriorityFees = NewPriorityFeeOracle(c)
+	c.Blockhashes = NewBlockhashCache(rpcClient)
+	return c
+}
+
 // Close terminates all client connections
 func (c *Client) Close() error {
 	if c.WsClient != nil {
@@ -35,8 +35,11 @@ func (c *Client) GetClock(ctx context.Context) (*Clock, error) {
 		return nil, errors.New("clock account not found in the network")
 	}
 
-	// Parse account data
-	data := resp.Value.Data.GetBinary()
+	return ParseClock(resp.Value.Data.GetBinary())
+}
+
+// ParseClock decodes the clock sysvar account data
+func ParseClock(data []byte) (*Clock, error) {
 	if len(data) != ClockAccountDataSize {
 		return nil, fmt.Errorf("invalid clock account data length: expected %d bytes, got %d", ClockAccountDataSize, len(data))
 	}
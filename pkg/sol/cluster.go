@@ -0,0 +1,55 @@
+package sol
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Cluster identifies the Solana network an endpoint serves
+type Cluster string
+
+const (
+	ClusterMainnet Cluster = "mainnet"
+	ClusterDevnet  Cluster = "devnet"
+	ClusterTestnet Cluster = "testnet"
+	// ClusterCustom is a local validator or private cluster. Protocols use their mainnet
+	// program IDs there, as validators clone mainnet programs at their addresses.
+	ClusterCustom Cluster = "custom"
+)
+
+// DetectCluster guesses the cluster from an RPC or WebSocket URL. Local endpoints are custom;
+// endpoints that don't name devnet or testnet, including most paid providers, are assumed
+// to be mainnet.
+func DetectCluster(endpoint string) Cluster {
+	endpoint = strings.ToLower(endpoint)
+	switch {
+	case strings.Contains(endpoint, "devnet"):
+		return ClusterDevnet
+	case strings.Contains(endpoint, "testnet"):
+		return ClusterTestnet
+	case strings.Contains(endpoint, "localhost"), strings.Contains(endpoint, "127.0.0.1"):
+		return ClusterCustom
+	default:
+		return ClusterMainnet
+	}
+}
+
+// ParseCluster parses a cluster name, accepting mainnet-beta for mainnet
+func ParseCluster(name string) (Cluster, error) {
+	switch cluster := Cluster(strings.ToLower(strings.TrimSpace(name))); cluster {
+	case ClusterMainnet, ClusterDevnet, ClusterTestnet, ClusterCustom:
+		return cluster, nil
+	case "mainnet-beta":
+		return ClusterMainnet, nil
+	default:
+		return "", fmt.Errorf("unknown cluster %q, expected mainnet, devnet, testnet or custom", name)
+	}
+}
+
+// ClusterOf returns the cluster of solClient, mainnet for clients built without one
+func ClusterOf(solClient *Client) Cluster {
+	if solClient == nil || solClient.Cluster == "" {
+		return ClusterMainnet
+	}
+	return solClient.Cluster
+}
@@ -0,0 +1,232 @@
+package sol
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Config sets the commitment levels a client trades latency for consistency at. An empty
+// level keeps each call's own: processed for pool state, confirmed for wallet accounts and
+// blockhashes, and processed for simulations.
+type Config struct {
+	// ReadCommitment is what account, balance and slot reads are evaluated at
+	ReadCommitment rpc.CommitmentType
+	// ExecuteCommitment is what blockhashes are fetched and transactions are simulated and
+	// preflighted at
+	ExecuteCommitment rpc.CommitmentType
+}
+
+// CommitmentRPC applies a Config to every request it passes to an RPC, overriding the
+// commitment the caller asked for. Reads under a context carrying a commitment through
+// WithReadOpts keep it. Transactions are still looked up at the commitment asked for, since
+// nodes only serve confirmed and finalized ones. It is safe for concurrent use.
+type CommitmentRPC struct {
+	inner RPC
+
+	mu     sync.RWMutex
+	config Config
+}
+
+var _ RPC = (*CommitmentRPC)(nil)
+
+// NewCommitmentRPC wraps inner
+func NewCommitmentRPC(inner RPC, config Config) *CommitmentRPC {
+	return &CommitmentRPC{inner: inner, config: config}
+}
+
+// SetConfig replaces the commitment levels
+func (c *CommitmentRPC) SetConfig(config Config) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config = config
+}
+
+// Config returns the commitment levels
+func (c *CommitmentRPC) Config() Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config
+}
+
+// SetConfig makes every RPC call through the client use config: its own, and those of the
+// protocols, pools and router reading through RpcClient. Set it before the client is shared.
+func (c *Client) SetConfig(config Config) {
+	if committed := c.commitmentRPC(); committed != nil {
+		committed.SetConfig(config)
+		return
+	}
+	// Below the retry policy and rate limit, so a later SetRateLimit replacing the limit keeps
+	// the config
+	inner, replace := c.underRetry()
+	if limited, ok := inner.(*LimitedRPC); ok {
+		limited.inner = NewCommitmentRPC(limited.inner, config)
+		return
+	}
+	replace(NewCommitmentRPC(inner, config))
+}
+
+// Config returns the commitment levels set with SetConfig
+func (c *Client) Config() Config {
+	if committed := c.commitmentRPC(); committed != nil {
+		return committed.Config()
+	}
+	return Config{}
+}
+
+// commitmentRPC finds the CommitmentRPC of a previous SetConfig, under the retry policy and
+// rate limit if there are any
+func (c *Client) commitmentRPC() *CommitmentRPC {
+	inner, _ := c.underRetry()
+	if limited, ok := inner.(*LimitedRPC); ok {
+		inner = limited.inner
+	}
+	committed, _ := inner.(*CommitmentRPC)
+	return committed
+}
+
+// read returns the commitment a read asking for commitment is made at
+func (c *CommitmentRPC) read(ctx context.Context, commitment rpc.CommitmentType) rpc.CommitmentType {
+	if opts, ok := ReadOptsFromContext(ctx); ok && opts.Commitment != "" {
+		return commitment
+	}
+	if config := c.Config(); config.ReadCommitment != "" {
+		return config.ReadCommitment
+	}
+	return commitment
+}
+
+// execute returns the commitment a blockhash fetch or simulation asking for commitment is
+// made at
+func (c *CommitmentRPC) execute(commitment rpc.CommitmentType) rpc.CommitmentType {
+	if config := c.Config(); config.ExecuteCommitment != "" {
+		return config.ExecuteCommitment
+	}
+	return commitment
+}
+
+func (c *CommitmentRPC) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	return c.GetAccountInfoWithOpts(ctx, account, nil)
+}
+
+func (c *CommitmentRPC) GetAccountInfoWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetAccountInfoOpts) (*rpc.GetAccountInfoResult, error) {
+	var out rpc.GetAccountInfoOpts
+	if opts != nil {
+		out = *opts
+	}
+	if commitment := c.read(ctx, out.Commitment); commitment != out.Commitment {
+		out.Commitment = commitment
+		opts = &out
+	}
+	return c.inner.GetAccountInfoWithOpts(ctx, account, opts)
+}
+
+func (c *CommitmentRPC) GetMultipleAccounts(ctx context.Context, accounts ...solana.PublicKey) (*rpc.GetMultipleAccountsResult, error) {
+	return c.GetMultipleAccountsWithOpts(ctx, accounts, nil)
+}
+
+func (c *CommitmentRPC) GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error) {
+	var out rpc.GetMultipleAccountsOpts
+	if opts != nil {
+		out = *opts
+	}
+	if commitment := c.read(ctx, out.Commitment); commitment != out.Commitment {
+		out.Commitment = commitment
+		opts = &out
+	}
+	return c.inner.GetMultipleAccountsWithOpts(ctx, accounts, opts)
+}
+
+func (c *CommitmentRPC) GetProgramAccountsWithOpts(ctx context.Context, program solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (rpc.GetProgramAccountsResult, error) {
+	var out rpc.GetProgramAccountsOpts
+	if opts != nil {
+		out = *opts
+	}
+	if commitment := c.read(ctx, out.Commitment); commitment != out.Commitment {
+		out.Commitment = commitment
+		opts = &out
+	}
+	return c.inner.GetProgramAccountsWithOpts(ctx, program, opts)
+}
+
+func (c *CommitmentRPC) GetTokenAccountsByOwner(ctx context.Context, owner solana.PublicKey, conf *rpc.GetTokenAccountsConfig, opts *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error) {
+	var out rpc.GetTokenAccountsOpts
+	if opts != nil {
+		out = *opts
+	}
+	if commitment := c.read(ctx, out.Commitment); commitment != out.Commitment {
+		out.Commitment = commitment
+		opts = &out
+	}
+	return c.inner.GetTokenAccountsByOwner(ctx, owner, conf, opts)
+}
+
+func (c *CommitmentRPC) GetTokenAccountBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetTokenAccountBalanceResult, error) {
+	return c.inner.GetTokenAccountBalance(ctx, account, c.read(ctx, commitment))
+}
+
+func (c *CommitmentRPC) GetBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+	return c.inner.GetBalance(ctx, account, c.read(ctx, commitment))
+}
+
+func (c *CommitmentRPC) GetSlot(ctx context.Context, commitment rpc.CommitmentType) (uint64, error) {
+	return c.inner.GetSlot(ctx, c.read(ctx, commitment))
+}
+
+func (c *CommitmentRPC) GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+	return c.inner.GetLatestBlockhash(ctx, c.execute(commitment))
+}
+
+func (c *CommitmentRPC) GetMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64, commitment rpc.CommitmentType) (uint64, error) {
+	return c.inner.GetMinimumBalanceForRentExemption(ctx, dataSize, c.read(ctx, commitment))
+}
+
+func (c *CommitmentRPC) GetRecentPrioritizationFees(ctx context.Context, accounts solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error) {
+	return c.inner.GetRecentPrioritizationFees(ctx, accounts)
+}
+
+func (c *CommitmentRPC) SendTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts rpc.TransactionOpts) (solana.Signature, error) {
+	opts.PreflightCommitment = c.execute(opts.PreflightCommitment)
+	return c.inner.SendTransactionWithOpts(ctx, tx, opts)
+}
+
+func (c *CommitmentRPC) SimulateTransaction(ctx context.Context, tx *solana.Transaction) (*rpc.SimulateTransactionResponse, error) {
+	return c.SimulateTransactionWithOpts(ctx, tx, nil)
+}
+
+func (c *CommitmentRPC) SimulateTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts *rpc.SimulateTransactionOpts) (*rpc.SimulateTransactionResponse, error) {
+	var out rpc.SimulateTransactionOpts
+	if opts != nil {
+		out = *opts
+	}
+	if commitment := c.execute(out.Commitment); commitment != out.Commitment {
+		out.Commitment = commitment
+		opts = &out
+	}
+	return c.inner.SimulateTransactionWithOpts(ctx, tx, opts)
+}
+
+// GetTransaction passes through to an inner TransactionReader, so ExecutionReport keeps
+// working on a configured client
+func (c *CommitmentRPC) GetTransaction(ctx context.Context, signature solana.Signature, opts *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+	reader, ok := c.inner.(TransactionReader)
+	if !ok {
+		return nil, fmt.Errorf("rpc client %T can't fetch transactions", c.inner)
+	}
+	return reader.GetTransaction(ctx, signature, opts)
+}
+
+// GetSignaturesForAddressWithOpts passes through to the inner RPC, so swap event listing keeps
+// working on a configured client
+func (c *CommitmentRPC) GetSignaturesForAddressWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error) {
+	lister, ok := c.inner.(interface {
+		GetSignaturesForAddressWithOpts(context.Context, solana.PublicKey, *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("rpc client %T can't list signatures", c.inner)
+	}
+	return lister.GetSignaturesForAddressWithOpts(ctx, account, opts)
+}
@@ -0,0 +1,166 @@
+package sol
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	// MaxComputeUnitLimit is the per-transaction compute unit cap
+	MaxComputeUnitLimit = 1_400_000
+	// DefaultComputeUnitMarginBps is added on top of simulated usage
+	DefaultComputeUnitMarginBps = 1000
+	// DefaultMinComputeUnitLimit keeps tiny estimates from failing on small state changes
+	DefaultMinComputeUnitLimit = 20_000
+)
+
+// PriorityFeeStrategy decides the compute unit price, in micro-lamports, for a transaction
+type PriorityFeeStrategy interface {
+	ComputeUnitPrice(ctx context.Context, insts []solana.Instruction) (uint64, error)
+}
+
+// FixedPriorityFee always pays the same compute unit price
+type FixedPriorityFee uint64
+
+func (f FixedPriorityFee) ComputeUnitPrice(ctx context.Context, insts []solana.Instruction) (uint64, error) {
+	return uint64(f), nil
+}
+
+// ComputeBudgetManager sizes the compute unit limit from a simulation and prices it
+// with a priority fee strategy
+type ComputeBudgetManager struct {
+	client      *Client
+	marginBps   uint64
+	minUnits    uint32
+	maxUnits    uint32
+	maxPrice    uint64 // zero leaves the strategy's price uncapped
+	feeStrategy PriorityFeeStrategy
+}
+
+// NewComputeBudgetManager creates a manager with a 10% safety margin and no priority fee
+func NewComputeBudgetManager(client *Client) *ComputeBudgetManager {
+	return &ComputeBudgetManager{
+		client:      client,
+		marginBps:   DefaultComputeUnitMarginBps,
+		minUnits:    DefaultMinComputeUnitLimit,
+		maxUnits:    MaxComputeUnitLimit,
+		feeStrategy: FixedPriorityFee(0),
+	}
+}
+
+// SetSafetyMarginBps sets the margin added to simulated compute units, in basis points
+func (m *ComputeBudgetManager) SetSafetyMarginBps(bps uint64) {
+	m.marginBps = bps
+}
+
+// SetUnitLimitBounds clamps the estimated compute unit limit to [min, max]
+func (m *ComputeBudgetManager) SetUnitLimitBounds(min, max uint32) {
+	if max == 0 || max > MaxComputeUnitLimit {
+		max = MaxComputeUnitLimit
+	}
+	if min > max {
+		min = max
+	}
+	m.minUnits = min
+	m.maxUnits = max
+}
+
+// SetMaxComputeUnitPrice caps the strategy's compute unit price, in micro-lamports, so a
+// spike in recent fees can't overpay; zero removes the cap
+func (m *ComputeBudgetManager) SetMaxComputeUnitPrice(microLamports uint64) {
+	m.maxPrice = microLamports
+}
+
+// SetPriorityFeeStrategy sets how the compute unit price is chosen
+func (m *ComputeBudgetManager) SetPriorityFeeStrategy(strategy PriorityFeeStrategy) {
+	m.feeStrategy = strategy
+}
+
+// EstimateComputeUnits simulates the instructions with the maximum limit and returns
+// the consumed units plus the safety margin
+func (m *ComputeBudgetManager) EstimateComputeUnits(ctx context.Context, payer solana.PublicKey, insts []solana.Instruction) (uint32, error) {
+	limitIx, err := computebudget.NewSetComputeUnitLimitInstruction(m.maxUnits).ValidateAndBuild()
+	if err != nil {
+		return 0, fmt.Errorf("failed to build CU limit instruction: %w", err)
+	}
+	tx, err := solana.NewTransaction(
+		append([]solana.Instruction{limitIx}, stripComputeBudget(insts)...),
+		solana.Hash{},
+		solana.TransactionPayer(payer),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+
+	res, err := m.client.RpcClient.SimulateTransactionWithOpts(ctx, tx, &rpc.SimulateTransactionOpts{
+		SigVerify:              false,
+		ReplaceRecentBlockhash: true,
+		Commitment:             rpc.CommitmentProcessed,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to simulate transaction: %w", err)
+	}
+	if res.Value.Err != nil {
+		return 0, fmt.Errorf("simulation failed: %v", res.Value.Err)
+	}
+	if res.Value.UnitsConsumed == nil {
+		return 0, fmt.Errorf("simulation did not report consumed units")
+	}
+
+	units := *res.Value.UnitsConsumed * (10000 + m.marginBps) / 10000
+	if units < uint64(m.minUnits) {
+		units = uint64(m.minUnits)
+	}
+	if units > uint64(m.maxUnits) {
+		units = uint64(m.maxUnits)
+	}
+	return uint32(units), nil
+}
+
+// Apply replaces any compute budget instructions in insts with a simulated unit limit
+// and the strategy's unit price, capped by SetMaxComputeUnitPrice
+func (m *ComputeBudgetManager) Apply(ctx context.Context, payer solana.PublicKey, insts []solana.Instruction) ([]solana.Instruction, error) {
+	units, err := m.EstimateComputeUnits(ctx, payer, insts)
+	if err != nil {
+		return nil, err
+	}
+	price, err := m.feeStrategy.ComputeUnitPrice(ctx, insts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get compute unit price: %w", err)
+	}
+	if m.maxPrice > 0 && price > m.maxPrice {
+		price = m.maxPrice
+	}
+
+	budget := make([]solana.Instruction, 0, 2)
+	if price > 0 {
+		priceIx, err := computebudget.NewSetComputeUnitPriceInstruction(price).ValidateAndBuild()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build CU price instruction: %w", err)
+		}
+		budget = append(budget, priceIx)
+	}
+	limitIx, err := computebudget.NewSetComputeUnitLimitInstruction(units).ValidateAndBuild()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build CU limit instruction: %w", err)
+	}
+	budget = append(budget, limitIx)
+	return append(budget, stripComputeBudget(insts)...), nil
+}
+
+// stripComputeBudget drops existing compute budget instructions, which may appear only once per transaction
+func stripComputeBudget(insts []solana.Instruction) []solana.Instruction {
+	out := make([]solana.Instruction, 0, len(insts))
+	for _, inst := range insts {
+		if inst.ProgramID().Equals(solana.ComputeBudget) {
+			continue
+		}
+		out = append(out, inst)
+	}
+	return out
+}
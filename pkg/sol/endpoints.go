@@ -0,0 +1,86 @@
+package sol
+
+import (
+	"context"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/retry"
+	"github.com/gtdvccc/SolRouteTmp/pkg/telemetry"
+)
+
+// Endpoints splits RPC work between two clients. Providers price getProgramAccounts
+// separately from plain account reads, so pool discovery can go to a heavyweight endpoint
+// while quoting and sending use a low-latency one.
+type Endpoints struct {
+	Discovery *Client // getProgramAccounts scans and pool fetches; pass to the protocols
+	Quote     *Client // account reads while quoting and building swaps, and sending
+}
+
+// NewEndpoints connects the quote client to endpoint and wsEndpoint. Discovery gets its own
+// RPC-only client when discoveryEndpoint is set and differs, and shares the quote client otherwise.
+func NewEndpoints(ctx context.Context, endpoint, wsEndpoint, discoveryEndpoint string) (*Endpoints, error) {
+	quote, err := NewClient(ctx, endpoint, wsEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	if discoveryEndpoint == "" || discoveryEndpoint == endpoint {
+		return &Endpoints{Discovery: quote, Quote: quote}, nil
+	}
+	discovery, err := NewClient(ctx, discoveryEndpoint, "")
+	if err != nil {
+		quote.Close()
+		return nil, err
+	}
+	// Both endpoints serve one network, which the quote endpoint's URL is likelier to name
+	discovery.Cluster = quote.Cluster
+	return &Endpoints{Discovery: discovery, Quote: quote}, nil
+}
+
+// SetRateLimit applies Client.SetRateLimit to each client, so each endpoint gets its own
+// budget
+func (e *Endpoints) SetRateLimit(requestsPerSecond float64, burst int) {
+	e.Quote.SetRateLimit(requestsPerSecond, burst)
+	if e.Discovery != e.Quote {
+		e.Discovery.SetRateLimit(requestsPerSecond, burst)
+	}
+}
+
+// SetConfig applies Client.SetConfig to each client
+func (e *Endpoints) SetConfig(config Config) {
+	e.Quote.SetConfig(config)
+	if e.Discovery != e.Quote {
+		e.Discovery.SetConfig(config)
+	}
+}
+
+// SetCluster sets the cluster of each client, for endpoints whose URL doesn't name it
+func (e *Endpoints) SetCluster(cluster Cluster) {
+	e.Quote.Cluster = cluster
+	e.Discovery.Cluster = cluster
+}
+
+// SetRetryPolicy applies Client.SetRetryPolicy to each client
+func (e *Endpoints) SetRetryPolicy(policy retry.Policy) {
+	e.Quote.SetRetryPolicy(policy)
+	if e.Discovery != e.Quote {
+		e.Discovery.SetRetryPolicy(policy)
+	}
+}
+
+// SetTelemetry applies Client.SetTelemetry to each client
+func (e *Endpoints) SetTelemetry(t *telemetry.Telemetry) {
+	e.Quote.SetTelemetry(t)
+	if e.Discovery != e.Quote {
+		e.Discovery.SetTelemetry(t)
+	}
+}
+
+// Close closes both clients
+func (e *Endpoints) Close() error {
+	if e.Discovery != nil && e.Discovery != e.Quote {
+		e.Discovery.Close()
+	}
+	if e.Quote != nil {
+		return e.Quote.Close()
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+package sol
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/telemetry"
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// DefaultExecuteAttempts is how many times ExecuteSwap builds and simulates a swap before
+// giving up on failures that a fresh quote may fix
+const DefaultExecuteAttempts = 3
+
+// SimulationFailure classifies why a simulated transaction failed
+type SimulationFailure string
+
+const (
+	SimulationFailureUnknown           SimulationFailure = "unknown"
+	SimulationFailureTickArray         SimulationFailure = "tick_array"
+	SimulationFailureSlippage          SimulationFailure = "slippage"
+	SimulationFailureInsufficientFunds SimulationFailure = "insufficient_funds"
+	SimulationFailureBlockhash         SimulationFailure = "blockhash"
+)
+
+// Retryable reports whether rebuilding the swap from a fresh quote and blockhash may succeed.
+// Missing funds aren't retried, since nothing the executor changes makes them appear.
+func (f SimulationFailure) Retryable() bool {
+	switch f {
+	case SimulationFailureTickArray, SimulationFailureSlippage, SimulationFailureBlockhash:
+		return true
+	}
+	return false
+}
+
+// sentinel returns the error from pkg/errors a failure matches, or nil
+func (f SimulationFailure) sentinel() error {
+	switch f {
+	case SimulationFailureTickArray:
+		return solerrors.ErrTickArrayMissing
+	case SimulationFailureSlippage:
+		return solerrors.ErrSlippageExceeded
+	case SimulationFailureInsufficientFunds:
+		return solerrors.ErrInsufficientFunds
+	}
+	return nil
+}
+
+// knownProgramErrors maps custom error codes of the programs swaps run through
+var knownProgramErrors = map[solana.PublicKey]map[uint64]SimulationFailure{
+	// Orca Whirlpool
+	solana.MustPublicKeyFromBase58("whirLbMiicVdio4qvUfM5KAg6Ct8VwpYzGff3uctyCc"): {
+		6023: SimulationFailureTickArray,
+		6036: SimulationFailureSlippage,
+		6038: SimulationFailureTickArray,
+	},
+	// Raydium CLMM
+	solana.MustPublicKeyFromBase58("CAMMCzo5YL8w4VFF8KVHrK22GGUsp5VTaW7grrKgrWqK"): {
+		6022: SimulationFailureSlippage,
+		6023: SimulationFailureSlippage,
+	},
+	// Raydium CPMM
+	solana.MustPublicKeyFromBase58("CPMMoo8L3F4NbTegBCKVNunggL7H1ZpdTHKxQB5qKP1C"): {
+		6005: SimulationFailureSlippage,
+	},
+	// Raydium AMM v4
+	solana.MustPublicKeyFromBase58("675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8"): {
+		30: SimulationFailureSlippage,
+	},
+	// PumpSwap
+	solana.MustPublicKeyFromBase58("pAMMBay6oceH9fJKBRHGP5D4bD4sWpmSwMn52FMfXEA"): {
+		6004: SimulationFailureSlippage,
+	},
+	// The token programs fail transfers from short accounts with code 1
+	solana.TokenProgramID:     {1: SimulationFailureInsufficientFunds},
+	solana.Token2022ProgramID: {1: SimulationFailureInsufficientFunds},
+	solana.SystemProgramID:    {1: SimulationFailureInsufficientFunds},
+}
+
+// SimulationError describes a failed simulation. It matches the pkg/errors sentinel of its
+// failure, e.g. ErrSlippageExceeded.
+type SimulationError struct {
+	Failure SimulationFailure
+	Program solana.PublicKey // zero when the failure isn't tied to an instruction
+	Code    *uint64          // the program's custom error code, if any
+	Err     any              // the error the node reported
+	Logs    []string
+}
+
+func (e *SimulationError) Error() string {
+	msg := fmt.Sprintf("simulation failed (%s): %v", e.Failure, e.Err)
+	if !e.Program.IsZero() {
+		msg += fmt.Sprintf(" in program %s", e.Program)
+	}
+	return msg
+}
+
+func (e *SimulationError) Unwrap() error {
+	return e.Failure.sentinel()
+}
+
+// ParseSimulationError classifies the error of a simulation of tx from its instruction
+// error and logs. It returns nil when the simulation succeeded.
+func ParseSimulationError(tx *solana.Transaction, result *rpc.SimulateTransactionResult) *SimulationError {
+	if result == nil || result.Err == nil {
+		return nil
+	}
+	simErr := &SimulationError{Failure: SimulationFailureUnknown, Err: result.Err, Logs: result.Logs}
+
+	if s, ok := result.Err.(string); ok {
+		switch s {
+		case "BlockhashNotFound":
+			simErr.Failure = SimulationFailureBlockhash
+		case "InsufficientFundsForFee", "InsufficientFundsForRent":
+			simErr.Failure = SimulationFailureInsufficientFunds
+		}
+		return simErr
+	}
+
+	if index, code, ok := instructionError(result.Err); ok {
+		if tx != nil && index < len(tx.Message.Instructions) {
+			if program, err := tx.ResolveProgramIDIndex(tx.Message.Instructions[index].ProgramIDIndex); err == nil {
+				simErr.Program = program
+			}
+		}
+		if code != nil {
+			simErr.Code = code
+			if failure, ok := knownProgramErrors[simErr.Program][*code]; ok {
+				simErr.Failure = failure
+				return simErr
+			}
+		}
+	}
+
+	// Programs outside the table are classified by what they log
+	for _, line := range result.Logs {
+		lower := strings.ToLower(line)
+		switch {
+		case strings.Contains(lower, "slippage") || strings.Contains(lower, "too little output"):
+			simErr.Failure = SimulationFailureSlippage
+		case strings.Contains(lower, "insufficient funds") || strings.Contains(lower, "insufficient lamports"):
+			simErr.Failure = SimulationFailureInsufficientFunds
+		case strings.Contains(lower, "tick array") || strings.Contains(lower, "tickarray"):
+			simErr.Failure = SimulationFailureTickArray
+		default:
+			continue
+		}
+		break
+	}
+	return simErr
+}
+
+// instructionError extracts the failing instruction and its custom code, if any, from an
+// error like {"InstructionError":[2,{"Custom":6038}]}
+func instructionError(err any) (int, *uint64, bool) {
+	raw, marshalErr := json.Marshal(err)
+	if marshalErr != nil {
+		return 0, nil, false
+	}
+	var parsed struct {
+		InstructionError []json.RawMessage
+	}
+	if json.Unmarshal(raw, &parsed) != nil || len(parsed.InstructionError) != 2 {
+		return 0, nil, false
+	}
+	var index int
+	if json.Unmarshal(parsed.InstructionError[0], &index) != nil {
+		return 0, nil, false
+	}
+	var custom struct {
+		Custom *uint64
+	}
+	if json.Unmarshal(parsed.InstructionError[1], &custom) != nil {
+		return index, nil, true
+	}
+	return index, custom.Custom, true
+}
+
+// ExecuteOptions controls ExecuteSwap
+type ExecuteOptions struct {
+	SwapOptions
+	// Slippage sets the minimum output below each attempt's quote
+	Slippage pkg.SlippageConfig
+	// MaxAttempts bounds how often the swap is rebuilt after a retryable simulation failure,
+	// DefaultExecuteAttempts when zero
+	MaxAttempts int
+	// Budget, when set, sizes and prices the compute budget of each attempt
+	Budget *ComputeBudgetManager
+	// DryRun stops after a successful simulation without sending
+	DryRun bool
+	// Deadline, when set, stops the swap from being rebuilt or sent once it passes, so a stale
+	// quote is never executed. It is read from the client's TimeSource.
+	Deadline time.Time
+	// ComputeUnits, when set, learns from the units each successful simulation consumed, e.g.
+	// the router's ComputeUnitModel refining the per-protocol estimates it routes by
+	ComputeUnits ComputeUnitObserver
+}
+
+// ComputeUnitObserver learns the compute units swaps use from simulations of them;
+// router.ComputeUnitModel is one
+type ComputeUnitObserver interface {
+	ObserveSimulation(pools []pkg.Pool, unitsConsumed uint64)
+}
+
+// ExecuteResult reports the attempt that was sent, or simulated for a dry run
+type ExecuteResult struct {
+	Signature     solana.Signature // zero for a dry run
+	ExpectedOut   math.Int
+	MinOut        math.Int
+	Attempts      int
+	UnitsConsumed uint64 // as simulated
+	Logs          []string
+}
+
+// ExecuteSwap quotes, builds and simulates a swap of amountIn of inputMint through pool, and
+// sends it only once the simulation succeeds. Simulation failures a fresh quote or blockhash
+// may fix, like a missing tick array or exceeded slippage, are retried up to MaxAttempts;
+// the last one is returned as a *SimulationError. The first signer pays and swaps. Past
+// opts.Deadline no further attempt is made and the error wraps ErrDeadlineExceeded.
+func (c *Client) ExecuteSwap(ctx context.Context, pool pkg.Pool, signers []Signer, inputMint string, amountIn math.Int, opts ExecuteOptions) (ExecuteResult, error) {
+	ctx, span := c.telemetry.Start(ctx, telemetry.SpanExecute,
+		telemetry.String(pkg.LogKeyPool, pool.GetID()),
+		telemetry.String(pkg.LogKeyProtocol, string(pool.ProtocolName())),
+		telemetry.String(pkg.LogKeyInputMint, inputMint),
+	)
+	result, err := c.executeSwap(ctx, pool, signers, inputMint, amountIn, opts)
+	span.SetAttributes(telemetry.Int("attempts", result.Attempts))
+	telemetry.End(span, err)
+	return result, err
+}
+
+func (c *Client) executeSwap(ctx context.Context, pool pkg.Pool, signers []Signer, inputMint string, amountIn math.Int, opts ExecuteOptions) (ExecuteResult, error) {
+	if len(signers) == 0 {
+		return ExecuteResult{}, fmt.Errorf("at least one signer is required")
+	}
+	if err := opts.Slippage.Validate(); err != nil {
+		return ExecuteResult{}, err
+	}
+	attempts := opts.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultExecuteAttempts
+	}
+	user := signers[0].PublicKey()
+	quoteMint, _ := poolMint(inputMint, opts.SwapOptions)
+	blockhashes := c.blockhashCache()
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err := c.checkDeadline(opts.Deadline); err != nil {
+			return ExecuteResult{}, err
+		}
+		// Quoting again refreshes the pool state the previous attempt may have failed on
+		unlock := pkg.LockPool(pool)
+		expectedOut, err := pool.Quote(ctx, c.RpcClient, quoteMint, amountIn)
+		unlock()
+		if err != nil {
+			return ExecuteResult{}, fmt.Errorf("failed to quote swap: %w", err)
+		}
+		minOut, _, err := opts.Slippage.MinAmountOut(expectedOut, 0, false)
+		if err != nil {
+			return ExecuteResult{}, err
+		}
+		instructions, err := c.BuildSwapInstructions(ctx, pool, user, inputMint, amountIn, minOut, opts.SwapOptions)
+		if err != nil {
+			return ExecuteResult{}, fmt.Errorf("failed to build swap: %w", err)
+		}
+		if opts.Budget != nil {
+			if instructions, err = opts.Budget.Apply(ctx, user, instructions); err != nil {
+				return ExecuteResult{}, fmt.Errorf("failed to set compute budget: %w", err)
+			}
+		}
+		blockhash, _, err := blockhashes.Get(ctx)
+		if err != nil {
+			return ExecuteResult{}, err
+		}
+		tx, err := signTransaction(blockhash, signers, instructions...)
+		if err != nil {
+			return ExecuteResult{}, err
+		}
+
+		sim, err := c.RpcClient.SimulateTransactionWithOpts(ctx, tx, &rpc.SimulateTransactionOpts{Commitment: rpc.CommitmentProcessed})
+		if err != nil {
+			return ExecuteResult{}, fmt.Errorf("failed to simulate transaction: %w", err)
+		}
+		if simErr := ParseSimulationError(tx, sim.Value); simErr != nil {
+			lastErr = simErr
+			c.logger().Warn("swap simulation failed", pkg.LogKeyPool, pool.GetID(), "attempt", attempt, "failure", string(simErr.Failure), pkg.LogKeyError, simErr)
+			if !simErr.Failure.Retryable() {
+				return ExecuteResult{}, simErr
+			}
+			if simErr.Failure == SimulationFailureBlockhash {
+				blockhashes.Invalidate()
+			}
+			continue
+		}
+
+		result := ExecuteResult{ExpectedOut: expectedOut, MinOut: minOut, Attempts: attempt, Logs: sim.Value.Logs}
+		if sim.Value.UnitsConsumed != nil {
+			result.UnitsConsumed = *sim.Value.UnitsConsumed
+			if opts.ComputeUnits != nil {
+				opts.ComputeUnits.ObserveSimulation([]pkg.Pool{pool}, result.UnitsConsumed)
+			}
+		}
+		if opts.DryRun {
+			return result, nil
+		}
+		if err := c.checkDeadline(opts.Deadline); err != nil {
+			return ExecuteResult{}, err
+		}
+		if result.Signature, err = c.sendSignedTx(ctx, tx, false); err != nil {
+			return ExecuteResult{}, err
+		}
+		return result, nil
+	}
+	return ExecuteResult{}, fmt.Errorf("swap failed after %d attempts: %w", attempts, lastErr)
+}
+
+// checkDeadline fails with ErrDeadlineExceeded once deadline has passed
+func (c *Client) checkDeadline(deadline time.Time) error {
+	if deadline.IsZero() {
+		return nil
+	}
+	now := time.Now()
+	if c.TimeSource != nil {
+		now = c.TimeSource.Now()
+	}
+	if now.After(deadline) {
+		return fmt.Errorf("%w: deadline %s, now %s", solerrors.ErrDeadlineExceeded, deadline.Format(time.RFC3339), now.Format(time.RFC3339))
+	}
+	return nil
+}
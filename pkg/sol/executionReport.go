@@ -0,0 +1,256 @@
+package sol
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/telemetry"
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// SPL token instructions that move tokens between accounts
+const (
+	tokenInstructionTransfer        = 3
+	tokenInstructionTransferChecked = 12
+)
+
+// wsolDecimals is the decimals of the native mint, used when a swap's WSOL account doesn't
+// outlive its transaction
+const wsolDecimals = 9
+
+// TransactionReader is implemented by clients that can fetch confirmed transactions, like
+// *rpc.Client. ExecutionReport reads through it.
+type TransactionReader interface {
+	GetTransaction(ctx context.Context, signature solana.Signature, opts *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error)
+}
+
+// ExecutionReport is what a confirmed swap actually did, as opposed to what it was quoted at
+type ExecutionReport struct {
+	Signature  solana.Signature
+	Slot       uint64
+	InputMint  string
+	OutputMint string
+	// AmountIn and AmountOut are the tokens that left and reached the owner
+	AmountIn  math.Int
+	AmountOut math.Int
+	// Price is the realized price in output per input token, adjusted for decimals
+	Price float64
+	// Fee is the lamports the transaction was charged, priority fee included
+	Fee           uint64
+	UnitsConsumed uint64
+	// ExpectedOut is the quoted output the report was asked to compare against
+	ExpectedOut math.Int
+	// SlippageBps is how far AmountOut fell short of ExpectedOut, negative when it beat it.
+	// It is zero without an expected output.
+	SlippageBps int64
+}
+
+// ExecutionReport fetches the confirmed transaction of a swap sent for owner and reports its
+// fill, comparing the output with expectedOut when it is set. The RPC client must implement
+// TransactionReader.
+func (c *Client) ExecutionReport(ctx context.Context, signature solana.Signature, owner solana.PublicKey, inputMint, outputMint string, expectedOut math.Int) (ExecutionReport, error) {
+	ctx, span := c.telemetry.Start(ctx, telemetry.SpanExecutionReport, telemetry.String("signature", signature.String()))
+	report, err := c.executionReport(ctx, signature, owner, inputMint, outputMint, expectedOut)
+	telemetry.End(span, err)
+	return report, err
+}
+
+func (c *Client) executionReport(ctx context.Context, signature solana.Signature, owner solana.PublicKey, inputMint, outputMint string, expectedOut math.Int) (ExecutionReport, error) {
+	reader, ok := c.RpcClient.(TransactionReader)
+	if !ok {
+		return ExecutionReport{}, fmt.Errorf("rpc client %T can't fetch transactions", c.RpcClient)
+	}
+	maxVersion := uint64(0)
+	result, err := reader.GetTransaction(ctx, signature, &rpc.GetTransactionOpts{
+		Encoding:                       solana.EncodingBase64,
+		Commitment:                     rpc.CommitmentConfirmed,
+		MaxSupportedTransactionVersion: &maxVersion,
+	})
+	if err != nil {
+		return ExecutionReport{}, fmt.Errorf("failed to get transaction %s: %w", signature, err)
+	}
+	if result == nil || result.Transaction == nil || result.Meta == nil {
+		return ExecutionReport{}, fmt.Errorf("transaction %s has no metadata", signature)
+	}
+	tx, err := result.Transaction.GetTransaction()
+	if err != nil {
+		return ExecutionReport{}, fmt.Errorf("failed to decode transaction %s: %w", signature, err)
+	}
+	report, err := ParseExecutionReport(tx, result.Meta, owner, inputMint, outputMint, expectedOut)
+	if err != nil {
+		return ExecutionReport{}, err
+	}
+	report.Signature, report.Slot = signature, result.Slot
+	return report, nil
+}
+
+// ParseExecutionReport reports the fill of a confirmed swap from its transaction and meta.
+// The amounts are the changes in owner's token balances. When the owner's account for a mint
+// doesn't outlive the transaction, as with WSOL wrapped and unwrapped around the swap, they
+// are read from the token transfers in the transaction's instructions and inner instructions
+// instead.
+func ParseExecutionReport(tx *solana.Transaction, meta *rpc.TransactionMeta, owner solana.PublicKey, inputMint, outputMint string, expectedOut math.Int) (ExecutionReport, error) {
+	if meta.Err != nil {
+		return ExecutionReport{}, fmt.Errorf("swap transaction failed: %v", meta.Err)
+	}
+	fill := newFillParser(tx, meta, owner)
+	report := ExecutionReport{
+		InputMint:   inputMint,
+		OutputMint:  outputMint,
+		AmountIn:    fill.delta(inputMint).Neg(),
+		AmountOut:   fill.delta(outputMint),
+		Fee:         meta.Fee,
+		ExpectedOut: expectedOut,
+	}
+	if meta.ComputeUnitsConsumed != nil {
+		report.UnitsConsumed = *meta.ComputeUnitsConsumed
+	}
+	if !report.AmountIn.IsPositive() {
+		report.AmountIn = fill.transferred(inputMint, true)
+	}
+	if !report.AmountOut.IsPositive() {
+		report.AmountOut = fill.transferred(outputMint, false)
+	}
+	if !report.AmountIn.IsPositive() || !report.AmountOut.IsPositive() {
+		return ExecutionReport{}, fmt.Errorf("no %s to %s fill found for %s", inputMint, outputMint, owner)
+	}
+
+	in, _ := math.LegacyNewDecFromIntWithPrec(report.AmountIn, int64(fill.decimals(inputMint))).Float64()
+	out, _ := math.LegacyNewDecFromIntWithPrec(report.AmountOut, int64(fill.decimals(outputMint))).Float64()
+	report.Price = out / in
+	if !expectedOut.IsNil() && expectedOut.IsPositive() {
+		report.SlippageBps = expectedOut.Sub(report.AmountOut).MulRaw(10_000).Quo(expectedOut).Int64()
+	}
+	return report, nil
+}
+
+// fillParser reads an owner's token movements from a confirmed transaction
+type fillParser struct {
+	tx    *solana.Transaction
+	meta  *rpc.TransactionMeta
+	owner solana.PublicKey
+	keys  solana.PublicKeySlice
+	// balances maps the token accounts with a balance before or after the transaction to it
+	balances map[solana.PublicKey]rpc.TokenBalance
+}
+
+func newFillParser(tx *solana.Transaction, meta *rpc.TransactionMeta, owner solana.PublicKey) *fillParser {
+	// Accounts loaded from lookup tables follow the static keys, writable first
+	keys := append(solana.PublicKeySlice{}, tx.Message.AccountKeys...)
+	keys = append(keys, meta.LoadedAddresses.Writable...)
+	keys = append(keys, meta.LoadedAddresses.ReadOnly...)
+	p := &fillParser{tx: tx, meta: meta, owner: owner, keys: keys, balances: make(map[solana.PublicKey]rpc.TokenBalance)}
+	for _, balances := range [][]rpc.TokenBalance{meta.PreTokenBalances, meta.PostTokenBalances} {
+		for _, balance := range balances {
+			if int(balance.AccountIndex) < len(keys) {
+				p.balances[keys[balance.AccountIndex]] = balance
+			}
+		}
+	}
+	return p
+}
+
+// delta returns the change in the owner's balance of mint across its token accounts
+func (p *fillParser) delta(mint string) math.Int {
+	total := math.ZeroInt()
+	for sign, balances := range map[int64][]rpc.TokenBalance{-1: p.meta.PreTokenBalances, 1: p.meta.PostTokenBalances} {
+		for _, balance := range balances {
+			if balance.Owner == nil || !balance.Owner.Equals(p.owner) || balance.Mint.String() != mint || balance.UiTokenAmount == nil {
+				continue
+			}
+			amount, ok := math.NewIntFromString(balance.UiTokenAmount.Amount)
+			if ok {
+				total = total.Add(amount.MulRaw(sign))
+			}
+		}
+	}
+	return total
+}
+
+// transferred sums the token transfers of mint the owner signed when fromOwner is set, and
+// otherwise those from others that reached an account without a lasting balance, such as a
+// WSOL account closed in the same transaction
+func (p *fillParser) transferred(mint string, fromOwner bool) math.Int {
+	total := math.ZeroInt()
+	for _, inst := range p.instructions() {
+		program, ok := p.key(inst.ProgramIDIndex)
+		if !ok || !(program.Equals(solana.TokenProgramID) || program.Equals(solana.Token2022ProgramID)) || len(inst.Data) < 9 {
+			continue
+		}
+		accounts, ok := p.accounts(inst)
+		if !ok {
+			continue
+		}
+		var source, destination, authority, transferMint solana.PublicKey
+		switch {
+		case inst.Data[0] == tokenInstructionTransfer && len(accounts) >= 3:
+			source, destination, authority = accounts[0], accounts[1], accounts[2]
+			transferMint = p.mintOf(source, destination)
+		case inst.Data[0] == tokenInstructionTransferChecked && len(accounts) >= 4:
+			source, transferMint, destination, authority = accounts[0], accounts[1], accounts[2], accounts[3]
+		default:
+			continue
+		}
+		if transferMint.String() != mint {
+			continue
+		}
+		_, lasting := p.balances[destination]
+		if authority.Equals(p.owner) == fromOwner && (fromOwner || !lasting) {
+			total = total.Add(math.NewIntFromUint64(binary.LittleEndian.Uint64(inst.Data[1:9])))
+		}
+	}
+	return total
+}
+
+// instructions returns the transaction's instructions followed by its inner instructions
+func (p *fillParser) instructions() []solana.CompiledInstruction {
+	instructions := append([]solana.CompiledInstruction{}, p.tx.Message.Instructions...)
+	for _, inner := range p.meta.InnerInstructions {
+		instructions = append(instructions, inner.Instructions...)
+	}
+	return instructions
+}
+
+func (p *fillParser) key(index uint16) (solana.PublicKey, bool) {
+	if int(index) >= len(p.keys) {
+		return solana.PublicKey{}, false
+	}
+	return p.keys[index], true
+}
+
+// accounts resolves the accounts of inst, failing on indexes past the account keys
+func (p *fillParser) accounts(inst solana.CompiledInstruction) ([]solana.PublicKey, bool) {
+	accounts := make([]solana.PublicKey, len(inst.Accounts))
+	for i, index := range inst.Accounts {
+		account, ok := p.key(index)
+		if !ok {
+			return nil, false
+		}
+		accounts[i] = account
+	}
+	return accounts, true
+}
+
+// mintOf returns the mint of the first of the token accounts with a balance, or WSOL's when
+// neither has one, since only wrapped SOL accounts routinely live and die within a swap
+func (p *fillParser) mintOf(accounts ...solana.PublicKey) solana.PublicKey {
+	for _, account := range accounts {
+		if balance, ok := p.balances[account]; ok {
+			return balance.Mint
+		}
+	}
+	return solana.SolMint
+}
+
+// decimals returns the decimals of mint from the transaction's token balances
+func (p *fillParser) decimals(mint string) uint8 {
+	for _, balance := range p.balances {
+		if balance.Mint.String() == mint && balance.UiTokenAmount != nil {
+			return balance.UiTokenAmount.Decimals
+		}
+	}
+	return wsolDecimals
+}
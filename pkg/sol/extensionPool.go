@@ -0,0 +1,279 @@
+package sol
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/txbuilder"
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+)
+
+// WrapPools returns the pools with those trading a Token-2022 mint that charges transfer
+// fees or runs a transfer hook wrapped, so their quotes are net of the fees and their swaps
+// carry the hook accounts. Other pools are returned as they are, and pools implementing
+// pkg.TransferFeePool are only wrapped for hooks.
+func (m *MintInspector) WrapPools(ctx context.Context, pools []pkg.Pool) ([]pkg.Pool, error) {
+	seen := make(map[solana.PublicKey]bool)
+	var mints []solana.PublicKey
+	for _, pool := range pools {
+		baseMint, quoteMint := pool.GetTokens()
+		for _, mint := range []string{baseMint, quoteMint} {
+			key, err := solana.PublicKeyFromBase58(mint)
+			if err != nil {
+				return nil, fmt.Errorf("pool %s: invalid mint %s: %w", pool.GetID(), mint, err)
+			}
+			if !seen[key] {
+				seen[key] = true
+				mints = append(mints, key)
+			}
+		}
+	}
+	exts, err := m.Inspect(ctx, mints...)
+	if err != nil {
+		return nil, err
+	}
+	byMint := make(map[string]*MintExtensions, len(exts))
+	for i, ext := range exts {
+		byMint[mints[i].String()] = ext
+	}
+
+	wrapped := make([]pkg.Pool, len(pools))
+	for i, pool := range pools {
+		baseMint, quoteMint := pool.GetTokens()
+		base, quote := byMint[baseMint], byMint[quoteMint]
+		if feePool, ok := pool.(pkg.TransferFeePool); ok && feePool.HandlesTransferFees() {
+			base, quote = withoutTransferFee(base), withoutTransferFee(quote)
+		}
+		if !base.Affects() && !quote.Affects() {
+			wrapped[i] = pool
+			continue
+		}
+		wrapped[i] = &extensionPool{Pool: pool, inspector: m, base: base, quote: quote}
+	}
+	return wrapped, nil
+}
+
+// withoutTransferFee returns ext without its transfer fee, for pools that apply it themselves
+func withoutTransferFee(ext *MintExtensions) *MintExtensions {
+	if ext == nil || ext.TransferFee == nil {
+		return ext
+	}
+	stripped := *ext
+	stripped.TransferFee = nil
+	return &stripped
+}
+
+// extensionPool applies the transfer fees and hooks of a pool's mints around the pool. The
+// pool program is expected to transfer the full input from the user, swap what its vault
+// receives, and check the minimum output against what the user receives, as programs
+// supporting Token-2022 do.
+type extensionPool struct {
+	pkg.Pool
+	inspector   *MintInspector
+	base, quote *MintExtensions
+}
+
+// Unwrap returns the pool without the mint extension adjustments
+func (p *extensionPool) Unwrap() pkg.Pool {
+	return p.Pool
+}
+
+// sides returns the extensions of the input and output mints
+func (p *extensionPool) sides(inputMint string) (*MintExtensions, *MintExtensions) {
+	if baseMint, _ := p.GetTokens(); inputMint == baseMint {
+		return p.base, p.quote
+	}
+	return p.quote, p.base
+}
+
+// afterFee returns amount less the fee a transfer of ext's mint withholds from it
+func (p *extensionPool) afterFee(ctx context.Context, ext *MintExtensions, amount math.Int) (math.Int, error) {
+	fee, err := p.inspector.transferFee(ctx, ext)
+	if err != nil || fee == nil {
+		return amount, err
+	}
+	return amount.Sub(fee.Fee(amount)), nil
+}
+
+// beforeFee returns the transfer of ext's mint that delivers exactly amount
+func (p *extensionPool) beforeFee(ctx context.Context, ext *MintExtensions, amount math.Int) (math.Int, error) {
+	fee, err := p.inspector.transferFee(ctx, ext)
+	if err != nil || fee == nil {
+		return amount, err
+	}
+	return amount.Add(fee.InverseFee(amount)), nil
+}
+
+// Quote prices the swap of what the pool receives of inputAmount and returns what the user
+// receives of the output
+func (p *extensionPool) Quote(ctx context.Context, solClient RPC, inputMint string, inputAmount math.Int) (math.Int, error) {
+	inExt, outExt := p.sides(inputMint)
+	received, err := p.afterFee(ctx, inExt, inputAmount)
+	if err != nil {
+		return math.Int{}, err
+	}
+	out, err := p.Pool.Quote(ctx, solClient, inputMint, received)
+	if err != nil {
+		return math.Int{}, err
+	}
+	return p.afterFee(ctx, outExt, out)
+}
+
+// QuoteExactOut returns the input the user sends so they receive exactly desiredOut
+func (p *extensionPool) QuoteExactOut(ctx context.Context, solClient RPC, outputMint string, desiredOut math.Int) (math.Int, error) {
+	outExt, inExt := p.sides(outputMint)
+	sent, err := p.beforeFee(ctx, outExt, desiredOut)
+	if err != nil {
+		return math.Int{}, err
+	}
+	in, err := p.Pool.QuoteExactOut(ctx, solClient, outputMint, sent)
+	if err != nil {
+		return math.Int{}, err
+	}
+	return p.beforeFee(ctx, inExt, in)
+}
+
+// QuoteWithFees is Quote with the pool's fee split, when the wrapped pool reports one
+func (p *extensionPool) QuoteWithFees(ctx context.Context, solClient RPC, inputMint string, inputAmount math.Int) (math.Int, pkg.FeeBreakdown, error) {
+	feeQuoter, ok := p.Pool.(pkg.FeeQuoter)
+	if !ok {
+		return math.Int{}, pkg.FeeBreakdown{}, fmt.Errorf("pool %s does not report fees", p.GetID())
+	}
+	inExt, outExt := p.sides(inputMint)
+	received, err := p.afterFee(ctx, inExt, inputAmount)
+	if err != nil {
+		return math.Int{}, pkg.FeeBreakdown{}, err
+	}
+	out, fees, err := feeQuoter.QuoteWithFees(ctx, solClient, inputMint, received)
+	if err != nil {
+		return math.Int{}, pkg.FeeBreakdown{}, err
+	}
+	out, err = p.afterFee(ctx, outExt, out)
+	return out, fees, err
+}
+
+// BuildSwapInstructions builds the wrapped pool's swap and adds the transfer hook accounts
+func (p *extensionPool) BuildSwapInstructions(ctx context.Context, solClient RPC, user solana.PublicKey, inputMint string, inputAmount math.Int, minOut math.Int) ([]solana.Instruction, error) {
+	instructions, err := p.Pool.BuildSwapInstructions(ctx, solClient, user, inputMint, inputAmount, minOut)
+	if err != nil {
+		return nil, err
+	}
+	inExt, outExt := p.sides(inputMint)
+	return p.withHookAccounts(ctx, solClient, instructions, user, inExt, outExt, inputAmount, minOut)
+}
+
+// BuildSwapInstructionsExactOut builds the wrapped pool's swap for the output that nets
+// amountOut after the output transfer fee, and adds the transfer hook accounts
+func (p *extensionPool) BuildSwapInstructionsExactOut(ctx context.Context, solClient RPC, user solana.PublicKey, outputMint string, amountOut math.Int, maxIn math.Int) ([]solana.Instruction, error) {
+	outExt, inExt := p.sides(outputMint)
+	sent, err := p.beforeFee(ctx, outExt, amountOut)
+	if err != nil {
+		return nil, err
+	}
+	instructions, err := p.Pool.BuildSwapInstructionsExactOut(ctx, solClient, user, outputMint, sent, maxIn)
+	if err != nil {
+		return nil, err
+	}
+	return p.withHookAccounts(ctx, solClient, instructions, user, inExt, outExt, maxIn, amountOut)
+}
+
+// withHookAccounts appends the accounts of the input and output transfer hooks to the
+// pool program's instruction. The amounts are only used by hooks deriving accounts from
+// them.
+func (p *extensionPool) withHookAccounts(ctx context.Context, solClient RPC, instructions []solana.Instruction, user solana.PublicKey, inExt, outExt *MintExtensions, amountIn, amountOut math.Int) ([]solana.Instruction, error) {
+	var extra []*solana.AccountMeta
+	for _, side := range []struct {
+		ext    *MintExtensions
+		input  bool
+		amount math.Int
+	}{{inExt, true, amountIn}, {outExt, false, amountOut}} {
+		if side.ext == nil || side.ext.TransferHook.IsZero() {
+			continue
+		}
+		transfer, err := p.hookTransfer(user, side.ext, side.input, side.amount)
+		if err != nil {
+			return nil, err
+		}
+		metas, err := ResolveTransferHookAccounts(ctx, solClient, side.ext.TransferHook, transfer)
+		if err != nil {
+			return nil, err
+		}
+		extra = append(extra, metas...)
+	}
+	if len(extra) == 0 {
+		return instructions, nil
+	}
+
+	for i := len(instructions) - 1; i >= 0; i-- {
+		inst := instructions[i]
+		if !inst.ProgramID().Equals(p.GetProgramID()) {
+			continue
+		}
+		data, err := inst.Data()
+		if err != nil {
+			return nil, err
+		}
+		accounts := append(append([]*solana.AccountMeta(nil), inst.Accounts()...), extra...)
+		out := append([]solana.Instruction(nil), instructions...)
+		out[i] = solana.NewInstruction(inst.ProgramID(), accounts, data)
+		return out, nil
+	}
+	return nil, fmt.Errorf("pool %s: no %s instruction to add transfer hook accounts to", p.GetID(), p.GetProgramID())
+}
+
+// hookTransfer describes the transfer of ext's mint between the user and the pool's vault
+func (p *extensionPool) hookTransfer(user solana.PublicKey, ext *MintExtensions, input bool, amount math.Int) (HookTransfer, error) {
+	if !amount.IsUint64() {
+		return HookTransfer{}, fmt.Errorf("transfer amount %s overflows u64", amount)
+	}
+	userAccount, err := txbuilder.AssociatedTokenAddress(user, ext.Mint, ext.Program)
+	if err != nil {
+		return HookTransfer{}, fmt.Errorf("failed to derive token account: %w", err)
+	}
+	// Left zero when the pool doesn't expose its vaults; hooks deriving accounts from them fail
+	var vault pkg.TokenVault
+	if vaultPool, ok := p.Pool.(pkg.VaultPool); ok {
+		for _, v := range vaultPool.TokenVaults() {
+			if v.Mint.Equals(ext.Mint) {
+				vault = v
+				break
+			}
+		}
+	}
+	if input {
+		return HookTransfer{Source: userAccount, Mint: ext.Mint, Destination: vault.Address, Owner: user, Amount: amount.Uint64()}, nil
+	}
+	return HookTransfer{Source: vault.Address, Mint: ext.Mint, Destination: userAccount, Owner: vault.Authority, Amount: amount.Uint64()}, nil
+}
+
+// TokenVaults returns the wrapped pool's vaults, if it exposes them
+func (p *extensionPool) TokenVaults() []pkg.TokenVault {
+	if vaultPool, ok := p.Pool.(pkg.VaultPool); ok {
+		return vaultPool.TokenVaults()
+	}
+	return nil
+}
+
+// Deprecation returns the wrapped pool's deprecation, if it reports one
+func (p *extensionPool) Deprecation() *pkg.Deprecation {
+	if deprecated, ok := p.Pool.(pkg.DeprecatablePool); ok {
+		return deprecated.Deprecation()
+	}
+	return nil
+}
+
+func (p *extensionPool) SetSuccessor(poolID string) {
+	if deprecated, ok := p.Pool.(pkg.DeprecatablePool); ok {
+		deprecated.SetSuccessor(poolID)
+	}
+}
+
+// QuoteAccounts returns the wrapped pool's quote accounts, if it lists them
+func (p *extensionPool) QuoteAccounts(inputMint string) []solana.PublicKey {
+	if prefetch, ok := p.Pool.(pkg.PrefetchPool); ok {
+		return prefetch.QuoteAccounts(inputMint)
+	}
+	return nil
+}
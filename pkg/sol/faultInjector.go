@@ -0,0 +1,210 @@
+package sol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// ErrInjectedFault is returned by calls a FaultInjector fails on purpose
+var ErrInjectedFault = errors.New("injected rpc fault")
+
+// FaultConfig sets how often a FaultInjector misbehaves. Rates are probabilities in [0, 1]
+// drawn independently per call.
+type FaultConfig struct {
+	// ErrorRate fails calls with ErrInjectedFault without reaching the endpoint
+	ErrorRate float64
+	// LatencyRate delays calls by Latency before they are sent
+	LatencyRate float64
+	Latency     time.Duration
+	// TruncateRate cuts the data of every account in a getAccountInfo, getMultipleAccounts or
+	// getProgramAccounts response down to TruncateTo bytes, or half its length when zero
+	TruncateRate float64
+	TruncateTo   int
+	// Methods limits the faults to these RPC methods; empty applies them to every method
+	Methods []string
+}
+
+// FaultStats counts the faults a FaultInjector has injected
+type FaultStats struct {
+	Calls      int
+	Errors     int
+	Delays     int
+	Truncation int
+}
+
+// FaultInjector wraps a JSON-RPC transport and injects errors, latency spikes and truncated
+// account data, so tests can check that retries, fallbacks and decoders degrade gracefully.
+// It is safe for concurrent use.
+type FaultInjector struct {
+	inner  rpc.JSONRPCClient
+	config FaultConfig
+
+	mu      sync.Mutex
+	rand    clock.Rand
+	sleeper clock.Sleeper
+	stats   FaultStats
+}
+
+var _ rpc.JSONRPCClient = (*FaultInjector)(nil)
+
+// NewFaultInjector wraps inner, for example jsonrpc.NewClient(endpoint) or a test stub
+func NewFaultInjector(inner rpc.JSONRPCClient, config FaultConfig) *FaultInjector {
+	return &FaultInjector{
+		inner:   inner,
+		config:  config,
+		rand:    clock.System{},
+		sleeper: clock.System{},
+	}
+}
+
+// NewFaultyClient returns an RPC client for endpoint whose calls go through a FaultInjector
+func NewFaultyClient(endpoint string, config FaultConfig) (*rpc.Client, *FaultInjector) {
+	injector := NewFaultInjector(jsonrpc.NewClient(endpoint), config)
+	return injector.Client(), injector
+}
+
+// SetRand sets the source the fault rates are drawn from; use a seeded source for
+// reproducible runs
+func (f *FaultInjector) SetRand(r clock.Rand) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rand = r
+}
+
+// SetSleeper sets how injected latency is waited out; clock.Fake skips the wait
+func (f *FaultInjector) SetSleeper(s clock.Sleeper) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sleeper = s
+}
+
+// Client returns an RPC client that sends through the injector
+func (f *FaultInjector) Client() *rpc.Client {
+	return rpc.NewWithCustomRPCClient(f)
+}
+
+// Stats returns the faults injected so far
+func (f *FaultInjector) Stats() FaultStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+// plannedFaults are the faults drawn for one call
+type plannedFaults struct {
+	fail     bool
+	delay    bool
+	truncate bool
+}
+
+func (f *FaultInjector) plan(method string) (plannedFaults, clock.Sleeper) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stats.Calls++
+	if len(f.config.Methods) > 0 && !slices.Contains(f.config.Methods, method) {
+		return plannedFaults{}, f.sleeper
+	}
+	p := plannedFaults{
+		fail:     f.rand.Float64() < f.config.ErrorRate,
+		delay:    f.rand.Float64() < f.config.LatencyRate,
+		truncate: f.rand.Float64() < f.config.TruncateRate,
+	}
+	if p.fail {
+		f.stats.Errors++
+	}
+	if p.delay {
+		f.stats.Delays++
+	}
+	return p, f.sleeper
+}
+
+// before waits out injected latency and returns the injected error, if any
+func (f *FaultInjector) before(ctx context.Context, method string) (plannedFaults, error) {
+	p, sleeper := f.plan(method)
+	if p.delay {
+		if err := sleeper.Sleep(ctx, f.config.Latency); err != nil {
+			return p, err
+		}
+	}
+	if p.fail {
+		return p, fmt.Errorf("%s: %w", method, ErrInjectedFault)
+	}
+	return p, nil
+}
+
+func (f *FaultInjector) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	p, err := f.before(ctx, method)
+	if err != nil {
+		return err
+	}
+	if err := f.inner.CallForInto(ctx, out, method, params); err != nil {
+		return err
+	}
+	if p.truncate && f.truncate(out) {
+		f.mu.Lock()
+		f.stats.Truncation++
+		f.mu.Unlock()
+	}
+	return nil
+}
+
+func (f *FaultInjector) CallWithCallback(ctx context.Context, method string, params []interface{}, callback func(*http.Request, *http.Response) error) error {
+	if _, err := f.before(ctx, method); err != nil {
+		return err
+	}
+	return f.inner.CallWithCallback(ctx, method, params, callback)
+}
+
+// CallBatch fails or delays the batch as a whole; batched account data isn't truncated
+func (f *FaultInjector) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	if _, err := f.before(ctx, "batch"); err != nil {
+		return nil, err
+	}
+	return f.inner.CallBatch(ctx, requests)
+}
+
+// truncate cuts the account data in a decoded response and reports whether it found any
+func (f *FaultInjector) truncate(out interface{}) bool {
+	cut := func(account *rpc.Account) bool {
+		if account == nil || account.Data == nil {
+			return false
+		}
+		data := account.Data.GetBinary()
+		keep := f.config.TruncateTo
+		if keep <= 0 || keep > len(data) {
+			keep = len(data) / 2
+		}
+		account.Data = rpc.DataBytesOrJSONFromBytes(data[:keep])
+		return true
+	}
+
+	truncated := false
+	switch result := out.(type) {
+	case **rpc.GetAccountInfoResult:
+		if *result != nil {
+			truncated = cut((*result).Value)
+		}
+	case **rpc.GetMultipleAccountsResult:
+		if *result != nil {
+			for _, account := range (*result).Value {
+				truncated = cut(account) || truncated
+			}
+		}
+	case *rpc.GetProgramAccountsResult:
+		for _, keyed := range *result {
+			if keyed != nil {
+				truncated = cut(keyed.Account) || truncated
+			}
+		}
+	}
+	return truncated
+}
@@ -0,0 +1,301 @@
+package sol
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Token-2022 mint layout: the base mint is padded to the size of a token account, followed
+// by an account type byte and type-length-value extensions
+const (
+	mintExtensionsOffset = 166
+	mintAccountTypeMint  = 1
+
+	extensionTransferFeeConfig = 1
+	extensionTransferHook      = 14
+
+	transferFeeConfigSize = 108
+	transferHookSize      = 64
+)
+
+// DefaultMintExtensionTTL is how long a MintInspector reuses a mint's extensions. Transfer
+// fee changes are scheduled two epochs ahead, so they are seen long before they apply.
+const DefaultMintExtensionTTL = time.Hour
+
+const (
+	// epochTTL is how long a MintInspector reuses the current epoch
+	epochTTL = time.Minute
+	// maxMultipleAccounts is the getMultipleAccounts limit
+	maxMultipleAccounts = 100
+)
+
+// TransferFee is one entry of a mint's transfer fee schedule
+type TransferFee struct {
+	Epoch       uint64 // first epoch the fee applies in
+	MaximumFee  uint64
+	BasisPoints uint16
+}
+
+// Fee returns the fee withheld from a transfer of amount, rounded up and capped at the
+// maximum fee
+func (f TransferFee) Fee(amount math.Int) math.Int {
+	if f.BasisPoints == 0 || !amount.IsPositive() {
+		return math.ZeroInt()
+	}
+	fee := amount.MulRaw(int64(f.BasisPoints)).AddRaw(9999).QuoRaw(10000)
+	return math.MinInt(fee, math.NewIntFromUint64(f.MaximumFee))
+}
+
+// InverseFee returns the fee of the transfer that delivers exactly postFeeAmount
+func (f TransferFee) InverseFee(postFeeAmount math.Int) math.Int {
+	maxFee := math.NewIntFromUint64(f.MaximumFee)
+	switch {
+	case f.BasisPoints == 0 || !postFeeAmount.IsPositive():
+		return math.ZeroInt()
+	case f.BasisPoints >= 10000:
+		return maxFee
+	}
+	denominator := int64(10000 - f.BasisPoints)
+	fee := postFeeAmount.MulRaw(int64(f.BasisPoints)).AddRaw(denominator - 1).QuoRaw(denominator)
+	return math.MinInt(fee, maxFee)
+}
+
+// TransferFeeConfig is a mint's transfer fee schedule: the newer fee replaces the older one
+// from its epoch on
+type TransferFeeConfig struct {
+	Older TransferFee
+	Newer TransferFee
+}
+
+// At returns the fee in force during epoch
+func (c TransferFeeConfig) At(epoch uint64) TransferFee {
+	if epoch >= c.Newer.Epoch {
+		return c.Newer
+	}
+	return c.Older
+}
+
+// needsEpoch reports whether the fee depends on the epoch
+func (c TransferFeeConfig) needsEpoch() bool {
+	return c.Older != c.Newer
+}
+
+// MintExtensions holds the Token-2022 extensions that change how a mint transfers. Mints of
+// the SPL Token program have neither.
+type MintExtensions struct {
+	Mint         solana.PublicKey
+	Program      solana.PublicKey // SPL Token or Token-2022
+	TransferFee  *TransferFeeConfig
+	TransferHook solana.PublicKey // hook program, zero when transfers aren't hooked
+}
+
+// Affects reports whether the extensions change a swap's amounts or accounts
+func (e *MintExtensions) Affects() bool {
+	return e != nil && (e.TransferFee != nil || !e.TransferHook.IsZero())
+}
+
+// ParseMintExtensions reads the transfer fee and transfer hook extensions of a mint account
+// owned by program
+func ParseMintExtensions(mint, program solana.PublicKey, data []byte) (*MintExtensions, error) {
+	ext := &MintExtensions{Mint: mint, Program: program}
+	if !program.Equals(solana.Token2022ProgramID) || len(data) <= mintExtensionsOffset {
+		return ext, nil
+	}
+	if data[mintExtensionsOffset-1] != mintAccountTypeMint {
+		return nil, fmt.Errorf("account %s is not a mint", mint)
+	}
+	for offset := mintExtensionsOffset; offset+4 <= len(data); {
+		extType := binary.LittleEndian.Uint16(data[offset : offset+2])
+		length := int(binary.LittleEndian.Uint16(data[offset+2 : offset+4]))
+		offset += 4
+		if offset+length > len(data) {
+			return nil, fmt.Errorf("mint %s: extension %d overruns the account", mint, extType)
+		}
+		value := data[offset : offset+length]
+		offset += length
+
+		switch extType {
+		case 0:
+			// Uninitialized padding ends the extensions
+			return ext, nil
+		case extensionTransferFeeConfig:
+			if length < transferFeeConfigSize {
+				return nil, fmt.Errorf("mint %s: transfer fee config is %d bytes", mint, length)
+			}
+			ext.TransferFee = &TransferFeeConfig{
+				Older: parseTransferFee(value[72:90]),
+				Newer: parseTransferFee(value[90:108]),
+			}
+		case extensionTransferHook:
+			if length < transferHookSize {
+				return nil, fmt.Errorf("mint %s: transfer hook is %d bytes", mint, length)
+			}
+			ext.TransferHook = solana.PublicKeyFromBytes(value[32:64])
+		}
+	}
+	return ext, nil
+}
+
+func parseTransferFee(data []byte) TransferFee {
+	return TransferFee{
+		Epoch:       binary.LittleEndian.Uint64(data[0:8]),
+		MaximumFee:  binary.LittleEndian.Uint64(data[8:16]),
+		BasisPoints: binary.LittleEndian.Uint16(data[16:18]),
+	}
+}
+
+type mintExtensionsEntry struct {
+	ext       *MintExtensions
+	fetchedAt time.Time
+}
+
+// MintInspector fetches and caches the Token-2022 extensions of mints
+type MintInspector struct {
+	client RPC
+	ttl    time.Duration
+	clock  clock.Clock
+
+	mu      sync.Mutex
+	entries map[solana.PublicKey]mintExtensionsEntry
+	pinned  map[solana.PublicKey]bool
+	epoch   uint64
+	epochAt time.Time
+}
+
+// NewMintInspector creates an inspector that reads mints through solClient
+func NewMintInspector(solClient RPC) *MintInspector {
+	return &MintInspector{
+		client:  solClient,
+		ttl:     DefaultMintExtensionTTL,
+		clock:   clock.System{},
+		entries: make(map[solana.PublicKey]mintExtensionsEntry),
+		pinned:  make(map[solana.PublicKey]bool),
+	}
+}
+
+// SetTTL sets how long fetched extensions are reused
+func (m *MintInspector) SetTTL(ttl time.Duration) {
+	m.ttl = ttl
+}
+
+// SetClock replaces the time source used for cache expiry
+func (m *MintInspector) SetClock(clk clock.Clock) {
+	m.clock = clk
+}
+
+// SetMintExtensions records a mint's extensions, e.g. from an indexer, so they are never
+// fetched
+func (m *MintInspector) SetMintExtensions(ext *MintExtensions) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[ext.Mint] = mintExtensionsEntry{ext: ext}
+	m.pinned[ext.Mint] = true
+}
+
+// Inspect returns the extensions of each mint, fetching the mints not cached in one request
+func (m *MintInspector) Inspect(ctx context.Context, mints ...solana.PublicKey) ([]*MintExtensions, error) {
+	result := make([]*MintExtensions, len(mints))
+	var missing []solana.PublicKey
+	now := m.clock.Now()
+	m.mu.Lock()
+	for i, mint := range mints {
+		entry, ok := m.entries[mint]
+		if ok && (m.pinned[mint] || now.Sub(entry.fetchedAt) < m.ttl) {
+			result[i] = entry.ext
+			continue
+		}
+		missing = append(missing, mint)
+	}
+	m.mu.Unlock()
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	fetched := make(map[solana.PublicKey]*MintExtensions, len(missing))
+	for start := 0; start < len(missing); start += maxMultipleAccounts {
+		batch := missing[start:min(start+maxMultipleAccounts, len(missing))]
+		results, err := m.client.GetMultipleAccountsWithOpts(ctx, batch, MultipleAccountsOpts(ctx, rpc.CommitmentConfirmed))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get mint accounts: %w", err)
+		}
+		if len(results.Value) != len(batch) {
+			return nil, fmt.Errorf("expected %d mint accounts, got %d", len(batch), len(results.Value))
+		}
+		for i, account := range results.Value {
+			if account == nil {
+				return nil, fmt.Errorf("mint %s not found", batch[i])
+			}
+			ext, err := ParseMintExtensions(batch[i], account.Owner, account.Data.GetBinary())
+			if err != nil {
+				return nil, err
+			}
+			fetched[batch[i]] = ext
+		}
+	}
+
+	m.mu.Lock()
+	for mint, ext := range fetched {
+		m.entries[mint] = mintExtensionsEntry{ext: ext, fetchedAt: now}
+	}
+	m.mu.Unlock()
+	for i, mint := range mints {
+		if result[i] == nil {
+			result[i] = fetched[mint]
+		}
+	}
+	return result, nil
+}
+
+// Epoch returns the current epoch, read from the clock sysvar at most once a minute
+func (m *MintInspector) Epoch(ctx context.Context) (uint64, error) {
+	now := m.clock.Now()
+	m.mu.Lock()
+	if !m.epochAt.IsZero() && now.Sub(m.epochAt) < epochTTL {
+		epoch := m.epoch
+		m.mu.Unlock()
+		return epoch, nil
+	}
+	m.mu.Unlock()
+
+	resp, err := m.client.GetAccountInfo(ctx, solana.SysVarClockPubkey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch clock account: %w", err)
+	}
+	if resp.Value == nil {
+		return 0, fmt.Errorf("clock account not found")
+	}
+	data := resp.Value.Data.GetBinary()
+	if len(data) != ClockAccountDataSize {
+		return 0, fmt.Errorf("invalid clock account data length %d", len(data))
+	}
+	epoch := binary.LittleEndian.Uint64(data[16:24])
+
+	m.mu.Lock()
+	m.epoch, m.epochAt = epoch, now
+	m.mu.Unlock()
+	return epoch, nil
+}
+
+// transferFee returns the fee in force for the mint's transfers, or nil when it charges none
+func (m *MintInspector) transferFee(ctx context.Context, ext *MintExtensions) (*TransferFee, error) {
+	if ext == nil || ext.TransferFee == nil {
+		return nil, nil
+	}
+	var epoch uint64
+	if ext.TransferFee.needsEpoch() {
+		var err error
+		if epoch, err = m.Epoch(ctx); err != nil {
+			return nil, err
+		}
+	}
+	fee := ext.TransferFee.At(epoch)
+	return &fee, nil
+}
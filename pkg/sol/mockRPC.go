@@ -0,0 +1,412 @@
+package sol
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Rent exemption as computed by the runtime: two years of rent for the account's data plus
+// its 128-byte header
+const (
+	accountStorageOverhead = 128
+	lamportsPerByteYear    = 3480
+	exemptionYears         = 2
+)
+
+// Layout of the SPL token accounts and mints MockRPC reads balances from
+const (
+	tokenAccountMint    = 0
+	tokenAccountOwner   = 32
+	tokenAccountAmount  = 64
+	mintDecimalsOffset  = 44
+	blockhashValidSlots = 150
+)
+
+// MockRPC is an RPC serving accounts from memory, for testing pools, protocols and the
+// router without a node. Program account queries apply the data size and memcmp filters,
+// sent transactions are recorded rather than executed and simulations return the result
+// set with SetSimulation. It is safe for concurrent use.
+type MockRPC struct {
+	mu                 sync.Mutex
+	accounts           map[solana.PublicKey]*rpc.Account
+	slot               uint64
+	blockhash          solana.Hash
+	prioritizationFees []rpc.PriorizationFeeResult
+	simulation         rpc.SimulateTransactionResult
+	sent               []*solana.Transaction
+	simulated          []*solana.Transaction
+	transactions       map[solana.Signature]*rpc.GetTransactionResult
+}
+
+var (
+	_ RPC               = (*MockRPC)(nil)
+	_ TransactionReader = (*MockRPC)(nil)
+)
+
+// NewMockRPC creates a mock with no accounts at slot 1
+func NewMockRPC() *MockRPC {
+	return &MockRPC{
+		accounts:  make(map[solana.PublicKey]*rpc.Account),
+		slot:      1,
+		blockhash: solana.HashFromBytes(bytes.Repeat([]byte{1}, 32)),
+	}
+}
+
+// SetAccount stores an account owned by owner holding data, funded to be rent exempt
+func (m *MockRPC) SetAccount(address, owner solana.PublicKey, data []byte) {
+	m.SetAccountInfo(address, &rpc.Account{
+		Lamports: rentExemption(uint64(len(data))),
+		Owner:    owner,
+		Data:     rpc.DataBytesOrJSONFromBytes(append([]byte(nil), data...)),
+	})
+}
+
+// SetAccountInfo stores account as it is, or removes the address when account is nil
+func (m *MockRPC) SetAccountInfo(address solana.PublicKey, account *rpc.Account) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if account == nil {
+		delete(m.accounts, address)
+		return
+	}
+	m.accounts[address] = account
+}
+
+// SetTokenAccount stores an SPL token account of mint held by owner
+func (m *MockRPC) SetTokenAccount(address, mint, owner solana.PublicKey, amount uint64) {
+	data := make([]byte, TokenAccountSize)
+	copy(data[tokenAccountMint:], mint.Bytes())
+	copy(data[tokenAccountOwner:], owner.Bytes())
+	binary.LittleEndian.PutUint64(data[tokenAccountAmount:], amount)
+	data[108] = 1 // initialized
+	m.SetAccount(address, solana.TokenProgramID, data)
+}
+
+// SetSlot sets the slot reported by GetSlot and the context of every response
+func (m *MockRPC) SetSlot(slot uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.slot = slot
+}
+
+// SetBlockhash sets the blockhash GetLatestBlockhash returns
+func (m *MockRPC) SetBlockhash(blockhash solana.Hash) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.blockhash = blockhash
+}
+
+// SetPrioritizationFees sets what GetRecentPrioritizationFees returns
+func (m *MockRPC) SetPrioritizationFees(fees []rpc.PriorizationFeeResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prioritizationFees = fees
+}
+
+// SetTransaction stores a confirmed transaction for GetTransaction to return under signature
+func (m *MockRPC) SetTransaction(signature solana.Signature, tx *solana.Transaction, meta rpc.TransactionMeta) error {
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal([]string{base64.StdEncoding.EncodeToString(data), string(solana.EncodingBase64)})
+	if err != nil {
+		return err
+	}
+	envelope := new(rpc.TransactionResultEnvelope)
+	if err := envelope.UnmarshalJSON(encoded); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.transactions == nil {
+		m.transactions = make(map[solana.Signature]*rpc.GetTransactionResult)
+	}
+	m.transactions[signature] = &rpc.GetTransactionResult{Slot: m.slot, Transaction: envelope, Meta: &meta}
+	return nil
+}
+
+// SetSimulation sets the result every simulated transaction gets
+func (m *MockRPC) SetSimulation(result rpc.SimulateTransactionResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.simulation = result
+}
+
+// Sent returns the transactions sent so far
+func (m *MockRPC) Sent() []*solana.Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*solana.Transaction(nil), m.sent...)
+}
+
+// Simulated returns the transactions simulated so far
+func (m *MockRPC) Simulated() []*solana.Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*solana.Transaction(nil), m.simulated...)
+}
+
+func (m *MockRPC) context() rpc.RPCContext {
+	return rpc.RPCContext{Context: rpc.Context{Slot: m.slot}}
+}
+
+// account returns a copy of the stored account with its data sliced, or nil
+func (m *MockRPC) account(address solana.PublicKey, slice *rpc.DataSlice) *rpc.Account {
+	stored, ok := m.accounts[address]
+	if !ok {
+		return nil
+	}
+	account := *stored
+	if slice != nil && account.Data != nil {
+		data := account.Data.GetBinary()
+		start, end := uint64(0), uint64(len(data))
+		if slice.Offset != nil {
+			start = min(*slice.Offset, end)
+		}
+		if slice.Length != nil {
+			end = min(start+*slice.Length, end)
+		}
+		account.Data = rpc.DataBytesOrJSONFromBytes(data[start:end])
+	}
+	return &account
+}
+
+func (m *MockRPC) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	return m.GetAccountInfoWithOpts(ctx, account, nil)
+}
+
+// GetAccountInfoWithOpts returns rpc.ErrNotFound for unknown accounts, as rpc.Client does
+func (m *MockRPC) GetAccountInfoWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetAccountInfoOpts) (*rpc.GetAccountInfoResult, error) {
+	var slice *rpc.DataSlice
+	if opts != nil {
+		slice = opts.DataSlice
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value := m.account(account, slice)
+	if value == nil {
+		return nil, rpc.ErrNotFound
+	}
+	return &rpc.GetAccountInfoResult{RPCContext: m.context(), Value: value}, nil
+}
+
+func (m *MockRPC) GetMultipleAccounts(ctx context.Context, accounts ...solana.PublicKey) (*rpc.GetMultipleAccountsResult, error) {
+	return m.GetMultipleAccountsWithOpts(ctx, accounts, nil)
+}
+
+// GetMultipleAccountsWithOpts returns nil for unknown accounts
+func (m *MockRPC) GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error) {
+	if len(accounts) > maxMultipleAccounts {
+		return nil, fmt.Errorf("too many accounts: %d > %d", len(accounts), maxMultipleAccounts)
+	}
+	var slice *rpc.DataSlice
+	if opts != nil {
+		slice = opts.DataSlice
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	values := make([]*rpc.Account, len(accounts))
+	for i, address := range accounts {
+		values[i] = m.account(address, slice)
+	}
+	return &rpc.GetMultipleAccountsResult{RPCContext: m.context(), Value: values}, nil
+}
+
+// GetProgramAccountsWithOpts returns program's accounts passing every filter, ordered by
+// address
+func (m *MockRPC) GetProgramAccountsWithOpts(ctx context.Context, program solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (rpc.GetProgramAccountsResult, error) {
+	var filters []rpc.RPCFilter
+	var slice *rpc.DataSlice
+	if opts != nil {
+		filters, slice = opts.Filters, opts.DataSlice
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result rpc.GetProgramAccountsResult
+	for address, account := range m.accounts {
+		if !account.Owner.Equals(program) || !matchFilters(account.Data.GetBinary(), filters) {
+			continue
+		}
+		result = append(result, &rpc.KeyedAccount{Pubkey: address, Account: m.account(address, slice)})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return bytes.Compare(result[i].Pubkey[:], result[j].Pubkey[:]) < 0
+	})
+	return result, nil
+}
+
+func matchFilters(data []byte, filters []rpc.RPCFilter) bool {
+	for _, filter := range filters {
+		if filter.DataSize != 0 && uint64(len(data)) != filter.DataSize {
+			return false
+		}
+		if memcmp := filter.Memcmp; memcmp != nil {
+			end := memcmp.Offset + uint64(len(memcmp.Bytes))
+			if end > uint64(len(data)) || !bytes.Equal(data[memcmp.Offset:end], memcmp.Bytes) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// GetTokenAccountsByOwner returns owner's token accounts of conf's mint or program
+func (m *MockRPC) GetTokenAccountsByOwner(ctx context.Context, owner solana.PublicKey, conf *rpc.GetTokenAccountsConfig, opts *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error) {
+	if conf == nil || (conf.Mint == nil) == (conf.ProgramId == nil) {
+		return nil, fmt.Errorf("exactly one of mint and program id is required")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var accounts []*rpc.TokenAccount
+	for address, account := range m.accounts {
+		data := account.Data.GetBinary()
+		if !isTokenProgram(account.Owner) || uint64(len(data)) < TokenAccountSize {
+			continue
+		}
+		if !solana.PublicKeyFromBytes(data[tokenAccountOwner : tokenAccountOwner+32]).Equals(owner) {
+			continue
+		}
+		if conf.Mint != nil && !solana.PublicKeyFromBytes(data[tokenAccountMint:tokenAccountMint+32]).Equals(*conf.Mint) {
+			continue
+		}
+		if conf.ProgramId != nil && !account.Owner.Equals(*conf.ProgramId) {
+			continue
+		}
+		accounts = append(accounts, &rpc.TokenAccount{Pubkey: address, Account: *account})
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return bytes.Compare(accounts[i].Pubkey[:], accounts[j].Pubkey[:]) < 0
+	})
+	return &rpc.GetTokenAccountsResult{RPCContext: m.context(), Value: accounts}, nil
+}
+
+func isTokenProgram(program solana.PublicKey) bool {
+	return program.Equals(solana.TokenProgramID) || program.Equals(solana.Token2022ProgramID)
+}
+
+// GetTokenAccountBalance reads the account's amount and, when its mint is stored, the
+// mint's decimals
+func (m *MockRPC) GetTokenAccountBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetTokenAccountBalanceResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.accounts[account]
+	if !ok {
+		return nil, fmt.Errorf("could not find account %s", account)
+	}
+	data := stored.Data.GetBinary()
+	if !isTokenProgram(stored.Owner) || uint64(len(data)) < TokenAccountSize {
+		return nil, fmt.Errorf("account %s is not a token account", account)
+	}
+	amount := binary.LittleEndian.Uint64(data[tokenAccountAmount : tokenAccountAmount+8])
+	var decimals uint8
+	if mint, ok := m.accounts[solana.PublicKeyFromBytes(data[tokenAccountMint:tokenAccountMint+32])]; ok {
+		if mintData := mint.Data.GetBinary(); len(mintData) > mintDecimalsOffset {
+			decimals = mintData[mintDecimalsOffset]
+		}
+	}
+	return &rpc.GetTokenAccountBalanceResult{
+		RPCContext: m.context(),
+		Value: &rpc.UiTokenAmount{
+			Amount:         strconv.FormatUint(amount, 10),
+			Decimals:       decimals,
+			UiAmountString: formatTokenAmount(amount, decimals),
+		},
+	}, nil
+}
+
+func formatTokenAmount(amount uint64, decimals uint8) string {
+	s := strconv.FormatUint(amount, 10)
+	if decimals == 0 {
+		return s
+	}
+	for len(s) <= int(decimals) {
+		s = "0" + s
+	}
+	return s[:len(s)-int(decimals)] + "." + s[len(s)-int(decimals):]
+}
+
+// GetBalance returns the account's lamports, zero for unknown accounts
+func (m *MockRPC) GetBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var lamports uint64
+	if stored, ok := m.accounts[account]; ok {
+		lamports = stored.Lamports
+	}
+	return &rpc.GetBalanceResult{RPCContext: m.context(), Value: lamports}, nil
+}
+
+func (m *MockRPC) GetSlot(ctx context.Context, commitment rpc.CommitmentType) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.slot, nil
+}
+
+func (m *MockRPC) GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return &rpc.GetLatestBlockhashResult{
+		RPCContext: m.context(),
+		Value:      &rpc.LatestBlockhashResult{Blockhash: m.blockhash, LastValidBlockHeight: m.slot + blockhashValidSlots},
+	}, nil
+}
+
+func (m *MockRPC) GetMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64, commitment rpc.CommitmentType) (uint64, error) {
+	return rentExemption(dataSize), nil
+}
+
+func rentExemption(dataSize uint64) uint64 {
+	return (accountStorageOverhead + dataSize) * lamportsPerByteYear * exemptionYears
+}
+
+func (m *MockRPC) GetRecentPrioritizationFees(ctx context.Context, accounts solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]rpc.PriorizationFeeResult(nil), m.prioritizationFees...), nil
+}
+
+// SendTransactionWithOpts records tx and returns its first signature
+func (m *MockRPC) SendTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts rpc.TransactionOpts) (solana.Signature, error) {
+	if len(tx.Signatures) == 0 {
+		return solana.Signature{}, fmt.Errorf("transaction is not signed")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, tx)
+	return tx.Signatures[0], nil
+}
+
+func (m *MockRPC) SimulateTransaction(ctx context.Context, tx *solana.Transaction) (*rpc.SimulateTransactionResponse, error) {
+	return m.SimulateTransactionWithOpts(ctx, tx, nil)
+}
+
+// GetTransaction returns the transaction stored with SetTransaction, or rpc.ErrNotFound
+func (m *MockRPC) GetTransaction(ctx context.Context, signature solana.Signature, opts *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result, ok := m.transactions[signature]
+	if !ok {
+		return nil, rpc.ErrNotFound
+	}
+	return result, nil
+}
+
+// SimulateTransactionWithOpts records tx and returns the result set with SetSimulation
+func (m *MockRPC) SimulateTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts *rpc.SimulateTransactionOpts) (*rpc.SimulateTransactionResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.simulated = append(m.simulated, tx)
+	result := m.simulation
+	return &rpc.SimulateTransactionResponse{RPCContext: m.context(), Value: &result}, nil
+}
@@ -0,0 +1,349 @@
+package sol
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	// DefaultEndpointTimeout bounds a single request to one endpoint before failing over
+	DefaultEndpointTimeout = 15 * time.Second
+	// DefaultEndpointCooldown is how long a failing endpoint is skipped
+	DefaultEndpointCooldown = 30 * time.Second
+)
+
+// latencyWeight is the weight of the newest sample in an endpoint's latency average
+const latencyWeight = 0.2
+
+// EndpointStrategy decides which healthy endpoint a MultiClient tries first
+type EndpointStrategy int
+
+const (
+	// RoundRobin spreads requests evenly across the healthy endpoints
+	RoundRobin EndpointStrategy = iota
+	// LowestLatency sends requests to the healthy endpoint with the lowest average latency
+	LowestLatency
+)
+
+// EndpointStatus is a MultiClient endpoint's health as last observed
+type EndpointStatus struct {
+	Name      string
+	Healthy   bool
+	Latency   time.Duration // moving average, zero until the first response
+	LastError error
+}
+
+type multiEndpoint struct {
+	name      string
+	client    RPC
+	latency   time.Duration
+	downUntil time.Time
+	lastErr   error
+}
+
+// MultiClient spreads requests over several RPC endpoints. A request failing with a rate
+// limit, server error or timeout is retried on the next endpoint and its endpoint is skipped
+// for a cooldown; other errors, such as a missing account, are returned as they are. It
+// implements RPC, so it can back a Client through Client(). It is safe for concurrent use.
+type MultiClient struct {
+	strategy EndpointStrategy
+	timeout  time.Duration
+	cooldown time.Duration
+	clock    clock.Clock
+	next     atomic.Uint64
+
+	mu        sync.Mutex
+	endpoints []*multiEndpoint
+}
+
+var _ RPC = (*MultiClient)(nil)
+
+// NewMultiClient creates a client over the given endpoint URLs, tried round-robin
+func NewMultiClient(endpoints ...string) *MultiClient {
+	m := &MultiClient{
+		strategy: RoundRobin,
+		timeout:  DefaultEndpointTimeout,
+		cooldown: DefaultEndpointCooldown,
+		clock:    clock.System{},
+	}
+	for _, endpoint := range endpoints {
+		m.AddEndpoint(endpoint, rpc.New(endpoint))
+	}
+	return m
+}
+
+// AddEndpoint adds an endpoint served by client, e.g. one with custom transport options
+func (m *MultiClient) AddEndpoint(name string, client RPC) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.endpoints = append(m.endpoints, &multiEndpoint{name: name, client: client})
+}
+
+// SetStrategy sets how requests are spread over the healthy endpoints
+func (m *MultiClient) SetStrategy(strategy EndpointStrategy) {
+	m.strategy = strategy
+}
+
+// SetEndpointTimeout bounds each request to one endpoint; zero leaves it to the caller's
+// context
+func (m *MultiClient) SetEndpointTimeout(d time.Duration) {
+	m.timeout = d
+}
+
+// SetCooldown sets how long an endpoint is skipped after failing
+func (m *MultiClient) SetCooldown(d time.Duration) {
+	m.cooldown = d
+}
+
+// SetClock replaces the time source used for cooldowns and latency
+func (m *MultiClient) SetClock(clk clock.Clock) {
+	m.clock = clk
+}
+
+// Client returns a Client reading and sending through the endpoints, without WebSocket
+func (m *MultiClient) Client() *Client {
+	return newClient(m)
+}
+
+// Status returns each endpoint's health in the order they were added
+func (m *MultiClient) Status() []EndpointStatus {
+	now := m.clock.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status := make([]EndpointStatus, len(m.endpoints))
+	for i, e := range m.endpoints {
+		status[i] = EndpointStatus{Name: e.name, Healthy: !now.Before(e.downUntil), Latency: e.latency, LastError: e.lastErr}
+	}
+	return status
+}
+
+// StartHealthChecks reads the slot from every endpoint each interval until ctx is done, so
+// failed endpoints return once they answer and latencies stay current while idle
+func (m *MultiClient) StartHealthChecks(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.CheckHealth(ctx)
+			}
+		}
+	}()
+}
+
+// CheckHealth reads the slot from every endpoint concurrently, marking the ones that fail as
+// down and the ones that answer as healthy
+func (m *MultiClient) CheckHealth(ctx context.Context) {
+	m.mu.Lock()
+	endpoints := slices.Clone(m.endpoints)
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, e := range endpoints {
+		wg.Add(1)
+		go func(e *multiEndpoint) {
+			defer wg.Done()
+			_, err := callEndpoint(m, ctx, e, func(ctx context.Context, client RPC) (uint64, error) {
+				return client.GetSlot(ctx, rpc.CommitmentProcessed)
+			})
+			if err == nil {
+				m.mu.Lock()
+				e.downUntil = time.Time{}
+				m.mu.Unlock()
+			}
+		}(e)
+	}
+	wg.Wait()
+}
+
+// candidates orders the endpoints to try: healthy ones by strategy, then the ones cooling
+// down as a last resort
+func (m *MultiClient) candidates() []*multiEndpoint {
+	now := m.clock.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var healthy, down []*multiEndpoint
+	for _, e := range m.endpoints {
+		if now.Before(e.downUntil) {
+			down = append(down, e)
+		} else {
+			healthy = append(healthy, e)
+		}
+	}
+	switch m.strategy {
+	case LowestLatency:
+		// Unmeasured endpoints sort first so every endpoint gets measured
+		slices.SortStableFunc(healthy, func(a, b *multiEndpoint) int {
+			return cmp.Compare(a.latency, b.latency)
+		})
+	default:
+		if n := len(healthy); n > 1 {
+			start := int(m.next.Add(1) % uint64(n))
+			healthy = append(healthy[start:], healthy[:start]...)
+		}
+	}
+	slices.SortStableFunc(down, func(a, b *multiEndpoint) int {
+		return a.downUntil.Compare(b.downUntil)
+	})
+	return append(healthy, down...)
+}
+
+// callEndpoint runs one request against e and records its latency or failure
+func callEndpoint[T any](m *MultiClient, ctx context.Context, e *multiEndpoint, fn func(context.Context, RPC) (T, error)) (T, error) {
+	callCtx := ctx
+	if m.timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+	}
+	start := m.clock.Now()
+	out, err := fn(callCtx, e.client)
+	elapsed := m.clock.Now().Sub(start)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil && shouldFailOver(ctx, err) {
+		e.lastErr = err
+		e.downUntil = m.clock.Now().Add(m.cooldown)
+		return out, err
+	}
+	if e.latency == 0 {
+		e.latency = elapsed
+	} else {
+		e.latency += time.Duration(latencyWeight * float64(elapsed-e.latency))
+	}
+	return out, err
+}
+
+// shouldFailOver reports whether err condemns the endpoint rather than the request. Errors
+// caused by the caller's context ending are the caller's.
+func shouldFailOver(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return solerrors.IsRetryable(err) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// multiCall runs fn against the endpoints in turn until one doesn't need failing over
+func multiCall[T any](m *MultiClient, ctx context.Context, fn func(context.Context, RPC) (T, error)) (T, error) {
+	var zero T
+	endpoints := m.candidates()
+	if len(endpoints) == 0 {
+		return zero, fmt.Errorf("no rpc endpoints")
+	}
+	var errs []error
+	for _, e := range endpoints {
+		out, err := callEndpoint(m, ctx, e, fn)
+		if err == nil || !shouldFailOver(ctx, err) {
+			return out, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", e.name, err))
+	}
+	return zero, errors.Join(errs...)
+}
+
+func (m *MultiClient) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	return multiCall(m, ctx, func(ctx context.Context, c RPC) (*rpc.GetAccountInfoResult, error) {
+		return c.GetAccountInfo(ctx, account)
+	})
+}
+
+func (m *MultiClient) GetAccountInfoWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetAccountInfoOpts) (*rpc.GetAccountInfoResult, error) {
+	return multiCall(m, ctx, func(ctx context.Context, c RPC) (*rpc.GetAccountInfoResult, error) {
+		return c.GetAccountInfoWithOpts(ctx, account, opts)
+	})
+}
+
+func (m *MultiClient) GetMultipleAccounts(ctx context.Context, accounts ...solana.PublicKey) (*rpc.GetMultipleAccountsResult, error) {
+	return multiCall(m, ctx, func(ctx context.Context, c RPC) (*rpc.GetMultipleAccountsResult, error) {
+		return c.GetMultipleAccounts(ctx, accounts...)
+	})
+}
+
+func (m *MultiClient) GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error) {
+	return multiCall(m, ctx, func(ctx context.Context, c RPC) (*rpc.GetMultipleAccountsResult, error) {
+		return c.GetMultipleAccountsWithOpts(ctx, accounts, opts)
+	})
+}
+
+func (m *MultiClient) GetProgramAccountsWithOpts(ctx context.Context, program solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (rpc.GetProgramAccountsResult, error) {
+	return multiCall(m, ctx, func(ctx context.Context, c RPC) (rpc.GetProgramAccountsResult, error) {
+		return c.GetProgramAccountsWithOpts(ctx, program, opts)
+	})
+}
+
+func (m *MultiClient) GetTokenAccountsByOwner(ctx context.Context, owner solana.PublicKey, conf *rpc.GetTokenAccountsConfig, opts *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error) {
+	return multiCall(m, ctx, func(ctx context.Context, c RPC) (*rpc.GetTokenAccountsResult, error) {
+		return c.GetTokenAccountsByOwner(ctx, owner, conf, opts)
+	})
+}
+
+func (m *MultiClient) GetTokenAccountBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetTokenAccountBalanceResult, error) {
+	return multiCall(m, ctx, func(ctx context.Context, c RPC) (*rpc.GetTokenAccountBalanceResult, error) {
+		return c.GetTokenAccountBalance(ctx, account, commitment)
+	})
+}
+
+func (m *MultiClient) GetBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+	return multiCall(m, ctx, func(ctx context.Context, c RPC) (*rpc.GetBalanceResult, error) {
+		return c.GetBalance(ctx, account, commitment)
+	})
+}
+
+func (m *MultiClient) GetSlot(ctx context.Context, commitment rpc.CommitmentType) (uint64, error) {
+	return multiCall(m, ctx, func(ctx context.Context, c RPC) (uint64, error) {
+		return c.GetSlot(ctx, commitment)
+	})
+}
+
+func (m *MultiClient) GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+	return multiCall(m, ctx, func(ctx context.Context, c RPC) (*rpc.GetLatestBlockhashResult, error) {
+		return c.GetLatestBlockhash(ctx, commitment)
+	})
+}
+
+func (m *MultiClient) GetMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64, commitment rpc.CommitmentType) (uint64, error) {
+	return multiCall(m, ctx, func(ctx context.Context, c RPC) (uint64, error) {
+		return c.GetMinimumBalanceForRentExemption(ctx, dataSize, commitment)
+	})
+}
+
+func (m *MultiClient) GetRecentPrioritizationFees(ctx context.Context, accounts solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error) {
+	return multiCall(m, ctx, func(ctx context.Context, c RPC) ([]rpc.PriorizationFeeResult, error) {
+		return c.GetRecentPrioritizationFees(ctx, accounts)
+	})
+}
+
+// SendTransactionWithOpts fails over like reads. A transaction resent to another endpoint
+// after a timeout may land twice in the leader's queue, but executes at most once.
+func (m *MultiClient) SendTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts rpc.TransactionOpts) (solana.Signature, error) {
+	return multiCall(m, ctx, func(ctx context.Context, c RPC) (solana.Signature, error) {
+		return c.SendTransactionWithOpts(ctx, tx, opts)
+	})
+}
+
+func (m *MultiClient) SimulateTransaction(ctx context.Context, tx *solana.Transaction) (*rpc.SimulateTransactionResponse, error) {
+	return multiCall(m, ctx, func(ctx context.Context, c RPC) (*rpc.SimulateTransactionResponse, error) {
+		return c.SimulateTransaction(ctx, tx)
+	})
+}
+
+func (m *MultiClient) SimulateTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts *rpc.SimulateTransactionOpts) (*rpc.SimulateTransactionResponse, error) {
+	return multiCall(m, ctx, func(ctx context.Context, c RPC) (*rpc.SimulateTransactionResponse, error) {
+		return c.SimulateTransactionWithOpts(ctx, tx, opts)
+	})
+}
@@ -0,0 +1,185 @@
+package sol
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+)
+
+// FeeTier selects how aggressively a transaction bids for inclusion
+type FeeTier string
+
+const (
+	FeeTierEconomy FeeTier = "economy" // p50 of recent fees
+	FeeTierNormal  FeeTier = "normal"  // p75 of recent fees
+	FeeTierFast    FeeTier = "fast"    // p95 of recent fees
+)
+
+// PriorityFeeLevels are suggested compute unit prices in micro-lamports
+type PriorityFeeLevels struct {
+	P50 uint64
+	P75 uint64
+	P95 uint64
+}
+
+// ForTier returns the suggested price for the tier
+func (l PriorityFeeLevels) ForTier(tier FeeTier) (uint64, error) {
+	switch tier {
+	case FeeTierEconomy:
+		return l.P50, nil
+	case FeeTierNormal:
+		return l.P75, nil
+	case FeeTierFast:
+		return l.P95, nil
+	default:
+		return 0, fmt.Errorf("unknown fee tier: %s", tier)
+	}
+}
+
+// PriorityFeeSource returns recently paid compute unit prices for transactions
+// locking the given writable accounts
+type PriorityFeeSource interface {
+	RecentPriorityFees(ctx context.Context, accounts []solana.PublicKey) ([]uint64, error)
+}
+
+// PriorityFeeSourceFunc adapts a function, e.g. a call to an external fee API, to PriorityFeeSource
+type PriorityFeeSourceFunc func(ctx context.Context, accounts []solana.PublicKey) ([]uint64, error)
+
+func (f PriorityFeeSourceFunc) RecentPriorityFees(ctx context.Context, accounts []solana.PublicKey) ([]uint64, error) {
+	return f(ctx, accounts)
+}
+
+// rpcPriorityFeeSource reads fees with getRecentPrioritizationFees
+type rpcPriorityFeeSource struct {
+	client *Client
+}
+
+func (s rpcPriorityFeeSource) RecentPriorityFees(ctx context.Context, accounts []solana.PublicKey) ([]uint64, error) {
+	results, err := s.client.RpcClient.GetRecentPrioritizationFees(ctx, accounts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent prioritization fees: %w", err)
+	}
+	fees := make([]uint64, len(results))
+	for i, result := range results {
+		fees[i] = result.PrioritizationFee
+	}
+	return fees, nil
+}
+
+// PriorityFeeOracle suggests compute unit prices from recently paid fees
+type PriorityFeeOracle struct {
+	source PriorityFeeSource
+}
+
+// NewPriorityFeeOracle creates an oracle backed by getRecentPrioritizationFees
+func NewPriorityFeeOracle(client *Client) *PriorityFeeOracle {
+	return &PriorityFeeOracle{source: rpcPriorityFeeSource{client: client}}
+}
+
+// SetSource replaces where recent fees are read from
+func (o *PriorityFeeOracle) SetSource(source PriorityFeeSource) {
+	o.source = source
+}
+
+// Levels returns the p50/p75/p95 compute unit prices paid recently for the accounts.
+// Slots where nothing paid a priority fee count as zero, as the RPC reports them.
+func (o *PriorityFeeOracle) Levels(ctx context.Context, accounts []solana.PublicKey) (PriorityFeeLevels, error) {
+	fees, err := o.source.RecentPriorityFees(ctx, accounts)
+	if err != nil {
+		return PriorityFeeLevels{}, err
+	}
+	sorted := append([]uint64(nil), fees...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return PriorityFeeLevels{
+		P50: percentile(sorted, 50),
+		P75: percentile(sorted, 75),
+		P95: percentile(sorted, 95),
+	}, nil
+}
+
+// percentile uses the nearest-rank method on sorted values
+func percentile(sorted []uint64, p int) uint64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Strategy returns a PriorityFeeStrategy that prices transactions at the given tier,
+// using the writable accounts of the instructions to scope the fee lookup
+func (o *PriorityFeeOracle) Strategy(tier FeeTier) PriorityFeeStrategy {
+	return tierStrategy{oracle: o, tier: tier}
+}
+
+type tierStrategy struct {
+	oracle *PriorityFeeOracle
+	tier   FeeTier
+}
+
+func (s tierStrategy) ComputeUnitPrice(ctx context.Context, insts []solana.Instruction) (uint64, error) {
+	levels, err := s.oracle.Levels(ctx, writableAccounts(insts))
+	if err != nil {
+		return 0, err
+	}
+	return levels.ForTier(s.tier)
+}
+
+// writableAccounts returns the distinct writable accounts of insts; the RPC accepts at most 128
+func writableAccounts(insts []solana.Instruction) []solana.PublicKey {
+	seen := make(map[solana.PublicKey]bool)
+	accounts := make([]solana.PublicKey, 0)
+	for _, inst := range insts {
+		for _, acc := range inst.Accounts() {
+			if !acc.IsWritable || seen[acc.PublicKey] {
+				continue
+			}
+			seen[acc.PublicKey] = true
+			accounts = append(accounts, acc.PublicKey)
+			if len(accounts) == 128 {
+				return accounts
+			}
+		}
+	}
+	return accounts
+}
+
+// SendTxWithFeeTier prices the transaction at the given tier with the client's priority fee
+// oracle, replacing any existing SetComputeUnitPrice instruction, and sends it like SendTx
+func (c *Client) SendTxWithFeeTier(ctx context.Context, blockhash solana.Hash, signers []solana.PrivateKey, insts []solana.Instruction, tier FeeTier, isSimulate bool) (solana.Signature, error) {
+	oracle := c.PriorityFees
+	if oracle == nil {
+		oracle = NewPriorityFeeOracle(c)
+	}
+	price, err := oracle.Strategy(tier).ComputeUnitPrice(ctx, insts)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to get priority fee: %w", err)
+	}
+	priceIx, err := computebudget.NewSetComputeUnitPriceInstruction(price).ValidateAndBuild()
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to build CU price instruction: %w", err)
+	}
+
+	priced := []solana.Instruction{priceIx}
+	for _, inst := range insts {
+		if isSetComputeUnitPrice(inst) {
+			continue
+		}
+		priced = append(priced, inst)
+	}
+	return c.SendTx(ctx, blockhash, signers, priced, isSimulate)
+}
+
+func isSetComputeUnitPrice(inst solana.Instruction) bool {
+	if !inst.ProgramID().Equals(solana.ComputeBudget) {
+		return false
+	}
+	data, err := inst.Data()
+	return err == nil && len(data) > 0 && data[0] == computebudget.Instruction_SetComputeUnitPrice
+}
@@ -0,0 +1,145 @@
+package sol
+
+import (
+	"bytes"
+	"encoding/base64"
+	"slices"
+	"strings"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// AnchorEventTag prefixes the data of the self-invocations Anchor programs emit events
+// through with emit_cpi!
+var AnchorEventTag = []byte{0xe4, 0x45, 0xa5, 0x2e, 0x51, 0xcb, 0x9a, 0x1d}
+
+// Log prefixes of the events programs log
+const (
+	programDataLog = "Program data: "
+	rayLogPrefix   = "Program log: ray_log: "
+)
+
+// programCall is an instruction of a transaction, outer or inner
+type programCall struct {
+	program  solana.PublicKey
+	accounts []solana.PublicKey
+	data     []byte
+}
+
+// ProgramEvents returns the events a confirmed transaction's programs emitted, in execution
+// order: "Program data:" and "ray_log" logs, attributed to the instruction that logged them,
+// and Anchor events emitted through self-invocations, attributed to the instruction that
+// emitted them. Logged events are missing when the node truncated the logs.
+func ProgramEvents(tx *solana.Transaction, meta *rpc.TransactionMeta) []pkg.ProgramEvent {
+	calls := programCalls(tx, meta)
+	var events []positionedEvent
+
+	// Self-invocations carry their emitter's program; the emitter is its latest call
+	latest := make(map[solana.PublicKey]int)
+	for i, call := range calls {
+		if !bytes.HasPrefix(call.data, AnchorEventTag) {
+			latest[call.program] = i
+			continue
+		}
+		if emitter, ok := latest[call.program]; ok {
+			events = append(events, positionedEvent{emitter, pkg.ProgramEvent{
+				Program:  call.program,
+				Data:     call.data[len(AnchorEventTag):],
+				Accounts: calls[emitter].accounts,
+			}})
+		}
+	}
+
+	// Each invoke log line starts the next call in execution order
+	var stack []int
+	next := 0
+logs:
+	for _, line := range meta.LogMessages {
+		switch {
+		case programStatus(line) == "invoke":
+			if next >= len(calls) {
+				break logs
+			}
+			stack = append(stack, next)
+			next++
+		case programStatus(line) == "success" || programStatus(line) == "failed:":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case strings.HasPrefix(line, programDataLog), strings.HasPrefix(line, rayLogPrefix):
+			if len(stack) == 0 {
+				continue
+			}
+			payload := strings.TrimPrefix(strings.TrimPrefix(line, programDataLog), rayLogPrefix)
+			data, err := base64.StdEncoding.DecodeString(payload)
+			if err != nil {
+				continue
+			}
+			call := stack[len(stack)-1]
+			events = append(events, positionedEvent{call, pkg.ProgramEvent{Program: calls[call].program, Data: data, Accounts: calls[call].accounts}})
+		}
+	}
+
+	// Order by emitting call, keeping the order of each call's own events
+	slices.SortStableFunc(events, func(a, b positionedEvent) int { return a.call - b.call })
+	out := make([]pkg.ProgramEvent, len(events))
+	for i, e := range events {
+		out[i] = e.event
+	}
+	return out
+}
+
+// programStatus returns the status word of a runtime log line like "Program <id> invoke [1]"
+// or "Program <id> success", or "" for lines programs logged
+func programStatus(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[0] != "Program" {
+		return ""
+	}
+	if _, err := solana.PublicKeyFromBase58(fields[1]); err != nil {
+		return ""
+	}
+	return fields[2]
+}
+
+// positionedEvent is an event with the index of the call that emitted it
+type positionedEvent struct {
+	call  int
+	event pkg.ProgramEvent
+}
+
+// programCalls lists a transaction's instructions in execution order, each outer
+// instruction followed by the instructions it invoked
+func programCalls(tx *solana.Transaction, meta *rpc.TransactionMeta) []programCall {
+	keys := append(solana.PublicKeySlice{}, tx.Message.AccountKeys...)
+	keys = append(keys, meta.LoadedAddresses.Writable...)
+	keys = append(keys, meta.LoadedAddresses.ReadOnly...)
+	resolve := func(inst solana.CompiledInstruction) programCall {
+		if int(inst.ProgramIDIndex) >= len(keys) {
+			return programCall{}
+		}
+		call := programCall{program: keys[inst.ProgramIDIndex], data: inst.Data, accounts: make([]solana.PublicKey, 0, len(inst.Accounts))}
+		for _, index := range inst.Accounts {
+			if int(index) >= len(keys) {
+				return programCall{}
+			}
+			call.accounts = append(call.accounts, keys[index])
+		}
+		return call
+	}
+
+	inner := make(map[uint16][]solana.CompiledInstruction)
+	for _, set := range meta.InnerInstructions {
+		inner[set.Index] = append(inner[set.Index], set.Instructions...)
+	}
+	var calls []programCall
+	for i, outer := range tx.Message.Instructions {
+		for _, inst := range append([]solana.CompiledInstruction{outer}, inner[uint16(i)]...) {
+			// Unresolvable calls are kept empty so the rest stay aligned with the logs
+			calls = append(calls, resolve(inst))
+		}
+	}
+	return calls
+}
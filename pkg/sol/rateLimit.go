@@ -0,0 +1,286 @@
+package sol
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// RateLimiter is a token bucket that makes callers wait for their turn rather than fail.
+// Waiters are served in arrival order. It is safe for concurrent use.
+type RateLimiter struct {
+	rate    float64
+	burst   float64
+	clock   clock.Clock
+	sleeper clock.Sleeper
+
+	mu      sync.Mutex
+	tokens  float64
+	updated time.Time
+}
+
+// NewRateLimiter allows requestsPerSecond on average and bursts of up to burst requests
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	burst = max(burst, 1)
+	return &RateLimiter{
+		rate:    requestsPerSecond,
+		burst:   float64(burst),
+		clock:   clock.System{},
+		sleeper: clock.System{},
+		tokens:  float64(burst),
+	}
+}
+
+// SetClock replaces the time source and the waiting, e.g. with clock.Fake for both
+func (l *RateLimiter) SetClock(clk clock.Clock, sleeper clock.Sleeper) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.clock, l.sleeper = clk, sleeper
+	l.updated = time.Time{}
+}
+
+// Wait takes a token, waiting until one is available or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := l.clock.Now()
+	if !l.updated.IsZero() {
+		l.tokens = min(l.tokens+now.Sub(l.updated).Seconds()*l.rate, l.burst)
+	}
+	l.updated = now
+	// Taking the token now reserves it, so later callers queue behind this one
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	sleeper := l.sleeper
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	if err := sleeper.Sleep(ctx, wait); err != nil {
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// LimitedRPC paces requests to an RPC through a RateLimiter and merges identical account
+// reads that are in flight at the same time into one request. Merged callers share the
+// response, which must not be modified. It is safe for concurrent use.
+type LimitedRPC struct {
+	inner   RPC
+	limiter *RateLimiter // nil only coalesces
+
+	mu       sync.Mutex
+	inflight map[string]*coalescedCall
+}
+
+type coalescedCall struct {
+	done chan struct{}
+	out  any
+	err  error
+}
+
+var _ RPC = (*LimitedRPC)(nil)
+
+// NewLimitedRPC wraps inner; a nil limiter only coalesces account reads
+func NewLimitedRPC(inner RPC, limiter *RateLimiter) *LimitedRPC {
+	return &LimitedRPC{inner: inner, limiter: limiter, inflight: make(map[string]*coalescedCall)}
+}
+
+// SetRateLimit paces the client's requests to requestsPerSecond with bursts of up to burst,
+// and merges identical concurrent account reads. A rate of zero only merges reads. Every
+// protocol and pool built on the client shares the limit.
+func (c *Client) SetRateLimit(requestsPerSecond float64, burst int) {
+	var limiter *RateLimiter
+	if requestsPerSecond > 0 {
+		limiter = NewRateLimiter(requestsPerSecond, burst)
+		if c.TimeSource != nil && c.Sleeper != nil {
+			limiter.SetClock(c.TimeSource, c.Sleeper)
+		}
+	}
+	// Below the retry policy, so each retry waits for the limit too
+	inner, replace := c.underRetry()
+	if limited, ok := inner.(*LimitedRPC); ok {
+		inner = limited.inner
+	}
+	replace(NewLimitedRPC(inner, limiter))
+}
+
+func (l *LimitedRPC) wait(ctx context.Context) error {
+	if l.limiter == nil {
+		return nil
+	}
+	return l.limiter.Wait(ctx)
+}
+
+// coalesce returns the result of the request in flight under key, or makes it. The request
+// outlives a caller that gives up, so the callers merged into it aren't cancelled with it.
+func coalesce[T any](l *LimitedRPC, ctx context.Context, key string, fn func(context.Context) (T, error)) (T, error) {
+	l.mu.Lock()
+	call, ok := l.inflight[key]
+	if !ok {
+		call = &coalescedCall{done: make(chan struct{})}
+		l.inflight[key] = call
+		go func() {
+			defer close(call.done)
+			callCtx := context.WithoutCancel(ctx)
+			if err := l.wait(callCtx); err != nil {
+				call.err = err
+			} else {
+				call.out, call.err = fn(callCtx)
+			}
+			l.mu.Lock()
+			delete(l.inflight, key)
+			l.mu.Unlock()
+		}()
+	}
+	l.mu.Unlock()
+
+	var zero T
+	select {
+	case <-call.done:
+		if call.err != nil {
+			return zero, call.err
+		}
+		return call.out.(T), nil
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// accountsKey identifies an account read by its method, accounts and options
+func accountsKey(method string, accounts []solana.PublicKey, opts *rpc.GetAccountInfoOpts) string {
+	var b strings.Builder
+	b.WriteString(method)
+	for _, account := range accounts {
+		b.WriteByte('|')
+		b.WriteString(account.String())
+	}
+	if opts != nil {
+		b.WriteString("|" + string(opts.Encoding) + "|" + string(opts.Commitment))
+		if opts.DataSlice != nil {
+			if opts.DataSlice.Offset != nil {
+				b.WriteString("|o" + strconv.FormatUint(*opts.DataSlice.Offset, 10))
+			}
+			if opts.DataSlice.Length != nil {
+				b.WriteString("|l" + strconv.FormatUint(*opts.DataSlice.Length, 10))
+			}
+		}
+		if opts.MinContextSlot != nil {
+			b.WriteString("|s" + strconv.FormatUint(*opts.MinContextSlot, 10))
+		}
+	}
+	return b.String()
+}
+
+func (l *LimitedRPC) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	return coalesce(l, ctx, accountsKey("getAccountInfo", []solana.PublicKey{account}, nil), func(ctx context.Context) (*rpc.GetAccountInfoResult, error) {
+		return l.inner.GetAccountInfo(ctx, account)
+	})
+}
+
+func (l *LimitedRPC) GetAccountInfoWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetAccountInfoOpts) (*rpc.GetAccountInfoResult, error) {
+	return coalesce(l, ctx, accountsKey("getAccountInfo", []solana.PublicKey{account}, opts), func(ctx context.Context) (*rpc.GetAccountInfoResult, error) {
+		return l.inner.GetAccountInfoWithOpts(ctx, account, opts)
+	})
+}
+
+func (l *LimitedRPC) GetMultipleAccounts(ctx context.Context, accounts ...solana.PublicKey) (*rpc.GetMultipleAccountsResult, error) {
+	return coalesce(l, ctx, accountsKey("getMultipleAccounts", accounts, nil), func(ctx context.Context) (*rpc.GetMultipleAccountsResult, error) {
+		return l.inner.GetMultipleAccounts(ctx, accounts...)
+	})
+}
+
+func (l *LimitedRPC) GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error) {
+	return coalesce(l, ctx, accountsKey("getMultipleAccounts", accounts, (*rpc.GetAccountInfoOpts)(opts)), func(ctx context.Context) (*rpc.GetMultipleAccountsResult, error) {
+		return l.inner.GetMultipleAccountsWithOpts(ctx, accounts, opts)
+	})
+}
+
+func (l *LimitedRPC) GetProgramAccountsWithOpts(ctx context.Context, program solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (rpc.GetProgramAccountsResult, error) {
+	if err := l.wait(ctx); err != nil {
+		return nil, err
+	}
+	return l.inner.GetProgramAccountsWithOpts(ctx, program, opts)
+}
+
+func (l *LimitedRPC) GetTokenAccountsByOwner(ctx context.Context, owner solana.PublicKey, conf *rpc.GetTokenAccountsConfig, opts *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error) {
+	if err := l.wait(ctx); err != nil {
+		return nil, err
+	}
+	return l.inner.GetTokenAccountsByOwner(ctx, owner, conf, opts)
+}
+
+func (l *LimitedRPC) GetTokenAccountBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetTokenAccountBalanceResult, error) {
+	if err := l.wait(ctx); err != nil {
+		return nil, err
+	}
+	return l.inner.GetTokenAccountBalance(ctx, account, commitment)
+}
+
+func (l *LimitedRPC) GetBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+	if err := l.wait(ctx); err != nil {
+		return nil, err
+	}
+	return l.inner.GetBalance(ctx, account, commitment)
+}
+
+func (l *LimitedRPC) GetSlot(ctx context.Context, commitment rpc.CommitmentType) (uint64, error) {
+	if err := l.wait(ctx); err != nil {
+		return 0, err
+	}
+	return l.inner.GetSlot(ctx, commitment)
+}
+
+func (l *LimitedRPC) GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+	if err := l.wait(ctx); err != nil {
+		return nil, err
+	}
+	return l.inner.GetLatestBlockhash(ctx, commitment)
+}
+
+func (l *LimitedRPC) GetMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64, commitment rpc.CommitmentType) (uint64, error) {
+	if err := l.wait(ctx); err != nil {
+		return 0, err
+	}
+	return l.inner.GetMinimumBalanceForRentExemption(ctx, dataSize, commitment)
+}
+
+func (l *LimitedRPC) GetRecentPrioritizationFees(ctx context.Context, accounts solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error) {
+	if err := l.wait(ctx); err != nil {
+		return nil, err
+	}
+	return l.inner.GetRecentPrioritizationFees(ctx, accounts)
+}
+
+func (l *LimitedRPC) SendTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts rpc.TransactionOpts) (solana.Signature, error) {
+	if err := l.wait(ctx); err != nil {
+		return solana.Signature{}, err
+	}
+	return l.inner.SendTransactionWithOpts(ctx, tx, opts)
+}
+
+func (l *LimitedRPC) SimulateTransaction(ctx context.Context, tx *solana.Transaction) (*rpc.SimulateTransactionResponse, error) {
+	if err := l.wait(ctx); err != nil {
+		return nil, err
+	}
+	return l.inner.SimulateTransaction(ctx, tx)
+}
+
+func (l *LimitedRPC) SimulateTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts *rpc.SimulateTransactionOpts) (*rpc.SimulateTransactionResponse, error) {
+	if err := l.wait(ctx); err != nil {
+		return nil, err
+	}
+	return l.inner.SimulateTransactionWithOpts(ctx, tx, opts)
+}
@@ -0,0 +1,45 @@
+package sol
+
+import (
+	"context"
+
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+type readOptsKey struct{}
+
+// ReadOpts pins account reads made under a context to a commitment and a minimum slot
+type ReadOpts struct {
+	Commitment     rpc.CommitmentType
+	MinContextSlot uint64
+}
+
+// WithReadOpts returns a context whose pool account reads use opts. RPC nodes serve
+// minContextSlot as a lower bound, so reads are evaluated at or after that slot.
+func WithReadOpts(ctx context.Context, opts ReadOpts) context.Context {
+	return context.WithValue(ctx, readOptsKey{}, opts)
+}
+
+// ReadOptsFromContext returns the read options attached with WithReadOpts, if any
+func ReadOptsFromContext(ctx context.Context) (ReadOpts, bool) {
+	opts, ok := ctx.Value(readOptsKey{}).(ReadOpts)
+	return opts, ok
+}
+
+// MultipleAccountsOpts returns getMultipleAccounts options for ctx, falling back to
+// defaultCommitment when no read options are attached
+func MultipleAccountsOpts(ctx context.Context, defaultCommitment rpc.CommitmentType) *rpc.GetMultipleAccountsOpts {
+	opts, ok := ReadOptsFromContext(ctx)
+	if !ok {
+		return &rpc.GetMultipleAccountsOpts{Commitment: defaultCommitment}
+	}
+	out := &rpc.GetMultipleAccountsOpts{Commitment: opts.Commitment}
+	if out.Commitment == "" {
+		out.Commitment = defaultCommitment
+	}
+	if opts.MinContextSlot > 0 {
+		slot := opts.MinContextSlot
+		out.MinContextSlot = &slot
+	}
+	return out
+}
@@ -0,0 +1,269 @@
+package sol
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// RelayerFee is the token-denominated fee a user pays to a relayer that sponsors
+// the transaction as fee payer
+type RelayerFee struct {
+	Relayer solana.PublicKey // fee payer; receives the fee in its associated token account
+	Mint    solana.PublicKey // SPL Token mint the fee is paid in
+	Amount  uint64
+}
+
+// RelayerPolicy describes which relayed transactions a relayer is willing to sign
+type RelayerPolicy struct {
+	Relayer solana.PublicKey
+	MinFees map[solana.PublicKey]uint64 // accepted fee mints and the minimum fee for each
+	// MaxPriorityFee caps the priority fee, in lamports, the relayer pays on top of signature
+	// fees: the compute unit price times the compute unit limit. Zero accepts no priority fee.
+	MaxPriorityFee uint64
+}
+
+// defaultComputeUnitsPerInstruction is the limit the runtime grants each instruction of a
+// transaction without a SetComputeUnitLimit instruction
+const defaultComputeUnitsPerInstruction = 200_000
+
+// BuildRelayedTx builds a transaction paid for by the relayer. A fee transfer from the user's
+// associated token account to the relayer's is prepended to insts and the transaction is
+// partially signed by the user; the relayer completes it with SignRelayedTx.
+// Instructions must not require the user to hold SOL, e.g. token accounts must already exist.
+func BuildRelayedTx(blockhash solana.Hash, user solana.PrivateKey, fee RelayerFee, insts []solana.Instruction) (*solana.Transaction, error) {
+	if fee.Relayer.IsZero() {
+		return nil, fmt.Errorf("relayer is required")
+	}
+	userPubkey := user.PublicKey()
+	source, _, err := solana.FindAssociatedTokenAddress(userPubkey, fee.Mint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive user fee account: %w", err)
+	}
+	destination, _, err := solana.FindAssociatedTokenAddress(fee.Relayer, fee.Mint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive relayer fee account: %w", err)
+	}
+	feeInst, err := token.NewTransferInstruction(fee.Amount, source, destination, userPubkey, nil).ValidateAndBuild()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build fee transfer: %w", err)
+	}
+
+	tx, err := solana.NewTransaction(
+		append([]solana.Instruction{feeInst}, insts...),
+		blockhash,
+		solana.TransactionPayer(fee.Relayer),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	if _, err := tx.PartialSign(func(key solana.PublicKey) *solana.PrivateKey {
+		if key.Equals(userPubkey) {
+			return &user
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	return tx, nil
+}
+
+// ValidateRelayedTx checks a user-submitted transaction before the relayer signs it:
+// the relayer must be the fee payer and appear in no instruction, the first instruction
+// must be a Transfer or TransferChecked paying an accepted fee to the relayer, the priority
+// fee must be within the policy's cap, and every other signature must be valid
+func ValidateRelayedTx(tx *solana.Transaction, policy RelayerPolicy) error {
+	if tx.Message.IsVersioned() && tx.Message.NumLookups() > 0 {
+		return fmt.Errorf("address lookup tables are not supported in relayed transactions")
+	}
+	signers := tx.Message.Signers()
+	if len(signers) == 0 || !signers[0].Equals(policy.Relayer) {
+		return fmt.Errorf("fee payer is not the relayer")
+	}
+	if len(tx.Signatures) != len(signers) {
+		return fmt.Errorf("got %d signers, but %d signatures", len(signers), len(tx.Signatures))
+	}
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	for i := 1; i < len(signers); i++ {
+		if !tx.Signatures[i].Verify(signers[i], message) {
+			return fmt.Errorf("invalid signature by %s", signers[i])
+		}
+	}
+
+	if len(tx.Message.Instructions) == 0 {
+		return fmt.Errorf("transaction has no instructions")
+	}
+	for i, inst := range tx.Message.Instructions {
+		accounts, err := inst.ResolveInstructionAccounts(&tx.Message)
+		if err != nil {
+			return fmt.Errorf("failed to resolve accounts of instruction %d: %w", i, err)
+		}
+		for _, acc := range accounts {
+			if acc.PublicKey.Equals(policy.Relayer) {
+				return fmt.Errorf("instruction %d references the relayer account", i)
+			}
+		}
+	}
+
+	if err := validateRelayerPriorityFee(tx, policy); err != nil {
+		return err
+	}
+	return validateRelayerFee(tx, policy)
+}
+
+// validateRelayerPriorityFee reads the transaction's compute budget instructions and rejects
+// a priority fee above the policy's cap
+func validateRelayerPriorityFee(tx *solana.Transaction, policy RelayerPolicy) error {
+	var price uint64
+	var limit uint32
+	var hasLimit bool
+	otherInsts := 0
+	for i, compiled := range tx.Message.Instructions {
+		programID, err := tx.ResolveProgramIDIndex(compiled.ProgramIDIndex)
+		if err != nil {
+			return fmt.Errorf("failed to resolve program of instruction %d: %w", i, err)
+		}
+		if !programID.Equals(solana.ComputeBudget) {
+			otherInsts++
+			continue
+		}
+		data := compiled.Data
+		if len(data) == 0 {
+			return fmt.Errorf("invalid compute budget instruction %d", i)
+		}
+		switch data[0] {
+		case computebudget.Instruction_SetComputeUnitPrice:
+			if len(data) != 9 {
+				return fmt.Errorf("invalid compute budget instruction %d", i)
+			}
+			price = binary.LittleEndian.Uint64(data[1:])
+		case computebudget.Instruction_SetComputeUnitLimit:
+			if len(data) != 5 {
+				return fmt.Errorf("invalid compute budget instruction %d", i)
+			}
+			limit, hasLimit = binary.LittleEndian.Uint32(data[1:]), true
+		}
+	}
+	if !hasLimit {
+		limit = uint32(min(otherInsts*defaultComputeUnitsPerInstruction, MaxComputeUnitLimit))
+	}
+	limit = min(limit, MaxComputeUnitLimit)
+
+	// price is in micro-lamports per compute unit, rounded up to whole lamports like the runtime
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(price), big.NewInt(int64(limit)))
+	fee.Add(fee, big.NewInt(999_999)).Div(fee, big.NewInt(1_000_000))
+	if fee.Cmp(new(big.Int).SetUint64(policy.MaxPriorityFee)) > 0 {
+		return fmt.Errorf("priority fee %s lamports above maximum %d", fee, policy.MaxPriorityFee)
+	}
+	return nil
+}
+
+func validateRelayerFee(tx *solana.Transaction, policy RelayerPolicy) error {
+	compiled := tx.Message.Instructions[0]
+	programID, err := tx.ResolveProgramIDIndex(compiled.ProgramIDIndex)
+	if err != nil {
+		return fmt.Errorf("failed to resolve fee instruction program: %w", err)
+	}
+	if !programID.Equals(solana.TokenProgramID) {
+		return fmt.Errorf("first instruction is not a token transfer")
+	}
+	accounts, err := compiled.ResolveInstructionAccounts(&tx.Message)
+	if err != nil {
+		return fmt.Errorf("failed to resolve fee instruction accounts: %w", err)
+	}
+	decoded, err := token.DecodeInstruction(accounts, compiled.Data)
+	if err != nil {
+		return fmt.Errorf("failed to decode fee instruction: %w", err)
+	}
+	var amount uint64
+	var destination, checkedMint solana.PublicKey
+	switch transfer := decoded.Impl.(type) {
+	case *token.Transfer:
+		if transfer.Amount == nil {
+			return fmt.Errorf("first instruction is not a token transfer")
+		}
+		amount, destination = *transfer.Amount, transfer.GetDestinationAccount().PublicKey
+	case *token.TransferChecked:
+		if transfer.Amount == nil {
+			return fmt.Errorf("first instruction is not a token transfer")
+		}
+		amount, destination, checkedMint = *transfer.Amount, transfer.GetDestinationAccount().PublicKey, transfer.GetMintAccount().PublicKey
+		if _, ok := policy.MinFees[checkedMint]; !ok {
+			return fmt.Errorf("fee mint %s is not accepted", checkedMint)
+		}
+	default:
+		return fmt.Errorf("first instruction is not a token transfer")
+	}
+
+	for mint, minFee := range policy.MinFees {
+		// A checked transfer names its mint, so only that mint's fee account is accepted
+		if !checkedMint.IsZero() && !mint.Equals(checkedMint) {
+			continue
+		}
+		feeAccount, _, err := solana.FindAssociatedTokenAddress(policy.Relayer, mint)
+		if err != nil {
+			return fmt.Errorf("failed to derive relayer fee account: %w", err)
+		}
+		if !destination.Equals(feeAccount) {
+			continue
+		}
+		if amount < minFee {
+			return fmt.Errorf("fee %d below minimum %d for mint %s", amount, minFee, mint)
+		}
+		return nil
+	}
+	return fmt.Errorf("fee is not paid to a relayer account for an accepted mint")
+}
+
+// SignRelayedTx adds the relayer's fee payer signature to a validated relayed transaction
+func SignRelayedTx(tx *solana.Transaction, relayer solana.PrivateKey) error {
+	relayerPubkey := relayer.PublicKey()
+	if _, err := tx.PartialSign(func(key solana.PublicKey) *solana.PrivateKey {
+		if key.Equals(relayerPubkey) {
+			return &relayer
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	return nil
+}
+
+// SendRelayedTx validates and simulates a relayed transaction, then signs and sends it on
+// behalf of the relayer. The simulation runs before the relayer signs, so a transaction that
+// would fail, and cost the relayer its fee, is rejected unsigned. With isSimulate set it
+// stops after the simulation and returns an empty signature.
+func (c *Client) SendRelayedTx(ctx context.Context, tx *solana.Transaction, relayer solana.PrivateKey, policy RelayerPolicy, isSimulate bool) (solana.Signature, error) {
+	if !policy.Relayer.Equals(relayer.PublicKey()) {
+		return solana.Signature{}, fmt.Errorf("relayer key does not match policy")
+	}
+	if err := ValidateRelayedTx(tx, policy); err != nil {
+		return solana.Signature{}, fmt.Errorf("rejected relayed transaction: %w", err)
+	}
+	sim, err := c.RpcClient.SimulateTransactionWithOpts(ctx, tx, &rpc.SimulateTransactionOpts{
+		SigVerify:  false,
+		Commitment: rpc.CommitmentProcessed,
+	})
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to simulate relayed transaction: %w", err)
+	}
+	if simErr := ParseSimulationError(tx, sim.Value); simErr != nil {
+		return solana.Signature{}, fmt.Errorf("rejected relayed transaction: %w", simErr)
+	}
+	if isSimulate {
+		return solana.Signature{}, nil
+	}
+	if err := SignRelayedTx(tx, relayer); err != nil {
+		return solana.Signature{}, err
+	}
+	return c.sendSignedTx(ctx, tx, false)
+}
@@ -0,0 +1,201 @@
+package sol
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/retry"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// RetryRPC repeats RPC requests that fail with transient errors under a retry.Policy.
+// Transactions are resent as signed, so a retry can't land a swap twice. It is safe for
+// concurrent use.
+type RetryRPC struct {
+	inner RPC
+
+	mu     sync.RWMutex
+	policy retry.Policy
+}
+
+var _ RPC = (*RetryRPC)(nil)
+
+// NewRetryRPC wraps inner
+func NewRetryRPC(inner RPC, policy retry.Policy) *RetryRPC {
+	return &RetryRPC{inner: inner, policy: policy}
+}
+
+// SetPolicy replaces the retry policy
+func (r *RetryRPC) SetPolicy(policy retry.Policy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policy = policy
+}
+
+// Policy returns the retry policy
+func (r *RetryRPC) Policy() retry.Policy {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.policy
+}
+
+// SetRetryPolicy retries every RPC call through the client under policy: its own, and those
+// of the protocols, pools and router reading through RpcClient. Retries wait on the client's
+// Sleeper and jitter with its Rand unless policy sets its own, and each retry goes through the
+// rate limit again. Set it before the client is shared.
+func (c *Client) SetRetryPolicy(policy retry.Policy) {
+	if policy.Sleeper == nil {
+		policy.Sleeper = c.Sleeper
+	}
+	if policy.Rand == nil {
+		policy.Rand = c.Rand
+	}
+	if retried, ok := c.RpcClient.(*RetryRPC); ok {
+		retried.SetPolicy(policy)
+		return
+	}
+	c.RpcClient = NewRetryRPC(c.RpcClient, policy)
+	if c.Blockhashes != nil {
+		c.Blockhashes.client = c.RpcClient
+	}
+}
+
+// RetryPolicy returns the policy set with SetRetryPolicy, and whether there is one
+func (c *Client) RetryPolicy() (retry.Policy, bool) {
+	if retried, ok := c.RpcClient.(*RetryRPC); ok {
+		return retried.Policy(), true
+	}
+	return retry.Policy{}, false
+}
+
+// underRetry returns the RPC beneath a retry policy set with SetRetryPolicy, and a function
+// putting a replacement for it back in place
+func (c *Client) underRetry() (RPC, func(RPC)) {
+	if retried, ok := c.RpcClient.(*RetryRPC); ok {
+		return retried.inner, func(inner RPC) { retried.inner = inner }
+	}
+	return c.RpcClient, func(inner RPC) {
+		c.RpcClient = inner
+		if c.Blockhashes != nil {
+			c.Blockhashes.client = inner
+		}
+	}
+}
+
+func (r *RetryRPC) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	return retry.Do(ctx, r.Policy(), func(ctx context.Context) (*rpc.GetAccountInfoResult, error) {
+		return r.inner.GetAccountInfo(ctx, account)
+	})
+}
+
+func (r *RetryRPC) GetAccountInfoWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetAccountInfoOpts) (*rpc.GetAccountInfoResult, error) {
+	return retry.Do(ctx, r.Policy(), func(ctx context.Context) (*rpc.GetAccountInfoResult, error) {
+		return r.inner.GetAccountInfoWithOpts(ctx, account, opts)
+	})
+}
+
+func (r *RetryRPC) GetMultipleAccounts(ctx context.Context, accounts ...solana.PublicKey) (*rpc.GetMultipleAccountsResult, error) {
+	return retry.Do(ctx, r.Policy(), func(ctx context.Context) (*rpc.GetMultipleAccountsResult, error) {
+		return r.inner.GetMultipleAccounts(ctx, accounts...)
+	})
+}
+
+func (r *RetryRPC) GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error) {
+	return retry.Do(ctx, r.Policy(), func(ctx context.Context) (*rpc.GetMultipleAccountsResult, error) {
+		return r.inner.GetMultipleAccountsWithOpts(ctx, accounts, opts)
+	})
+}
+
+func (r *RetryRPC) GetProgramAccountsWithOpts(ctx context.Context, program solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (rpc.GetProgramAccountsResult, error) {
+	return retry.Do(ctx, r.Policy(), func(ctx context.Context) (rpc.GetProgramAccountsResult, error) {
+		return r.inner.GetProgramAccountsWithOpts(ctx, program, opts)
+	})
+}
+
+func (r *RetryRPC) GetTokenAccountsByOwner(ctx context.Context, owner solana.PublicKey, conf *rpc.GetTokenAccountsConfig, opts *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error) {
+	return retry.Do(ctx, r.Policy(), func(ctx context.Context) (*rpc.GetTokenAccountsResult, error) {
+		return r.inner.GetTokenAccountsByOwner(ctx, owner, conf, opts)
+	})
+}
+
+func (r *RetryRPC) GetTokenAccountBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetTokenAccountBalanceResult, error) {
+	return retry.Do(ctx, r.Policy(), func(ctx context.Context) (*rpc.GetTokenAccountBalanceResult, error) {
+		return r.inner.GetTokenAccountBalance(ctx, account, commitment)
+	})
+}
+
+func (r *RetryRPC) GetBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+	return retry.Do(ctx, r.Policy(), func(ctx context.Context) (*rpc.GetBalanceResult, error) {
+		return r.inner.GetBalance(ctx, account, commitment)
+	})
+}
+
+func (r *RetryRPC) GetSlot(ctx context.Context, commitment rpc.CommitmentType) (uint64, error) {
+	return retry.Do(ctx, r.Policy(), func(ctx context.Context) (uint64, error) {
+		return r.inner.GetSlot(ctx, commitment)
+	})
+}
+
+func (r *RetryRPC) GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+	return retry.Do(ctx, r.Policy(), func(ctx context.Context) (*rpc.GetLatestBlockhashResult, error) {
+		return r.inner.GetLatestBlockhash(ctx, commitment)
+	})
+}
+
+func (r *RetryRPC) GetMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64, commitment rpc.CommitmentType) (uint64, error) {
+	return retry.Do(ctx, r.Policy(), func(ctx context.Context) (uint64, error) {
+		return r.inner.GetMinimumBalanceForRentExemption(ctx, dataSize, commitment)
+	})
+}
+
+func (r *RetryRPC) GetRecentPrioritizationFees(ctx context.Context, accounts solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error) {
+	return retry.Do(ctx, r.Policy(), func(ctx context.Context) ([]rpc.PriorizationFeeResult, error) {
+		return r.inner.GetRecentPrioritizationFees(ctx, accounts)
+	})
+}
+
+func (r *RetryRPC) SendTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts rpc.TransactionOpts) (solana.Signature, error) {
+	return retry.Do(ctx, r.Policy(), func(ctx context.Context) (solana.Signature, error) {
+		return r.inner.SendTransactionWithOpts(ctx, tx, opts)
+	})
+}
+
+func (r *RetryRPC) SimulateTransaction(ctx context.Context, tx *solana.Transaction) (*rpc.SimulateTransactionResponse, error) {
+	return retry.Do(ctx, r.Policy(), func(ctx context.Context) (*rpc.SimulateTransactionResponse, error) {
+		return r.inner.SimulateTransaction(ctx, tx)
+	})
+}
+
+func (r *RetryRPC) SimulateTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts *rpc.SimulateTransactionOpts) (*rpc.SimulateTransactionResponse, error) {
+	return retry.Do(ctx, r.Policy(), func(ctx context.Context) (*rpc.SimulateTransactionResponse, error) {
+		return r.inner.SimulateTransactionWithOpts(ctx, tx, opts)
+	})
+}
+
+// GetTransaction passes through to an inner TransactionReader, so ExecutionReport keeps
+// working on a retrying client
+func (r *RetryRPC) GetTransaction(ctx context.Context, signature solana.Signature, opts *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+	reader, ok := r.inner.(TransactionReader)
+	if !ok {
+		return nil, fmt.Errorf("rpc client %T can't fetch transactions", r.inner)
+	}
+	return retry.Do(ctx, r.Policy(), func(ctx context.Context) (*rpc.GetTransactionResult, error) {
+		return reader.GetTransaction(ctx, signature, opts)
+	})
+}
+
+// GetSignaturesForAddressWithOpts passes through to the inner RPC, so swap event listing keeps
+// working on a retrying client
+func (r *RetryRPC) GetSignaturesForAddressWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error) {
+	lister, ok := r.inner.(interface {
+		GetSignaturesForAddressWithOpts(context.Context, solana.PublicKey, *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("rpc client %T can't list signatures", r.inner)
+	}
+	return retry.Do(ctx, r.Policy(), func(ctx context.Context) ([]*rpc.TransactionSignature, error) {
+		return lister.GetSignaturesForAddressWithOpts(ctx, account, opts)
+	})
+}
@@ -3,13 +3,18 @@ package sol
 import (
 	"context"
 	"fmt"
+	"slices"
+	"time"
 
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/telemetry"
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 )
 
-// signTransaction creates and signs a new transaction with the given instructions
-func signTransaction(blockhash solana.Hash, signers []solana.PrivateKey, instrs ...solana.Instruction) (*solana.Transaction, error) {
+// signTransaction creates a transaction with the given instructions, paid for by the first
+// signer, and signs it with every signer it requires
+func signTransaction(blockhash solana.Hash, signers []Signer, instrs ...solana.Instruction) (*solana.Transaction, error) {
 	if len(signers) == 0 {
 		return nil, fmt.Errorf("at least one signer is required")
 	}
@@ -24,29 +29,84 @@ func signTransaction(blockhash solana.Hash, signers []solana.PrivateKey, instrs
 		return nil, fmt.Errorf("failed to create transaction: %w", err)
 	}
 
-	// Sign the transaction with all provided signers
-	_, err = tx.Sign(
-		func(key solana.PublicKey) *solana.PrivateKey {
-			for _, payer := range signers {
-				if payer.PublicKey().Equals(key) {
-					return &payer
-				}
-			}
-			return nil
-		},
-	)
+	message, err := tx.Message.MarshalBinary()
 	if err != nil {
-		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+	required := tx.Message.Signers()
+	tx.Signatures = make([]solana.Signature, len(required))
+	for i, key := range required {
+		idx := slices.IndexFunc(signers, func(s Signer) bool { return s.PublicKey().Equals(key) })
+		if idx < 0 {
+			return nil, fmt.Errorf("missing signer for %s", key)
+		}
+		if tx.Signatures[i], err = signers[idx].Sign(message); err != nil {
+			return nil, fmt.Errorf("failed to sign as %s: %w", key, err)
+		}
 	}
 	return tx, nil
 }
 
-// SendTx sends or simulates a transaction based on the isSimulate flag
+// SendTx sends or simulates a transaction signed with local keys, as SendTxWithSigners
 func (c *Client) SendTx(ctx context.Context, blockhash solana.Hash, signers []solana.PrivateKey, insts []solana.Instruction, isSimulate bool) (solana.Signature, error) {
+	return c.SendTxWithSigners(ctx, blockhash, LocalSigners(signers...), insts, isSimulate)
+}
+
+// SendTxWithSigners sends or simulates a transaction based on the isSimulate flag. A zero
+// blockhash takes the client's cached one; if the node reports that blockhash expired, the
+// transaction is signed again with a fresh blockhash and sent once more, so remote and
+// hardware signers may be asked to sign twice.
+func (c *Client) SendTxWithSigners(ctx context.Context, blockhash solana.Hash, signers []Signer, insts []solana.Instruction, isSimulate bool) (solana.Signature, error) {
+	cached := blockhash == solana.Hash{}
+	if cached {
+		var err error
+		if blockhash, _, err = c.blockhashCache().Get(ctx); err != nil {
+			return solana.Signature{}, err
+		}
+	}
 	tx, err := signTransaction(blockhash, signers, insts...)
 	if err != nil {
 		return solana.Signature{}, fmt.Errorf("failed to sign transaction: %w", err)
 	}
+	sig, err := c.sendSignedTx(ctx, tx, isSimulate)
+	if !cached || !isBlockhashExpired(err) {
+		return sig, err
+	}
+
+	c.logger().Debug("blockhash expired, resending", pkg.LogKeyError, err)
+	cache := c.blockhashCache()
+	cache.Invalidate()
+	if blockhash, _, err = cache.Get(ctx); err != nil {
+		return solana.Signature{}, err
+	}
+	if tx, err = signTransaction(blockhash, signers, insts...); err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	return c.sendSignedTx(ctx, tx, isSimulate)
+}
+
+// blockhashCache returns the client's blockhash cache, or an empty one for clients built
+// without NewClient
+func (c *Client) blockhashCache() *BlockhashCache {
+	if c.Blockhashes != nil {
+		return c.Blockhashes
+	}
+	return NewBlockhashCache(c.RpcClient)
+}
+
+// sendSignedTx sends or simulates an already signed transaction
+func (c *Client) sendSignedTx(ctx context.Context, tx *solana.Transaction, isSimulate bool) (sig solana.Signature, err error) {
+	stage := telemetry.String(telemetry.AttrStage, "send")
+	if isSimulate {
+		stage = telemetry.String(telemetry.AttrStage, "simulate")
+	}
+	ctx, span := c.telemetry.Start(ctx, telemetry.SpanSend, stage)
+	defer func() {
+		if err != nil {
+			c.telemetry.Add(ctx, telemetry.MetricSendFailures, 1, stage)
+		}
+		telemetry.End(span, err)
+	}()
 
 	if isSimulate {
 		if _, err := c.RpcClient.SimulateTransaction(ctx, tx); err != nil {
@@ -57,7 +117,8 @@ func (c *Client) SendTx(ctx context.Context, blockhash solana.Hash, signers []so
 	}
 
 	// Send transaction with optimized options
-	sig, err := c.RpcClient.SendTransactionWithOpts(
+	start := time.Now()
+	sig, err = c.RpcClient.SendTransactionWithOpts(
 		ctx, tx,
 		rpc.TransactionOpts{
 			SkipPreflight:       true,
@@ -67,5 +128,7 @@ func (c *Client) SendTx(ctx context.Context, blockhash solana.Hash, signers []so
 	if err != nil {
 		return solana.Signature{}, fmt.Errorf("failed to send transaction: %w", err)
 	}
+	c.logger().Debug("transaction sent", "signature", sig.String(), pkg.LogKeyLatency, time.Since(start))
+	span.SetAttributes(telemetry.String("signature", sig.String()))
 	return sig, nil
 }
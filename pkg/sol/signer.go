@@ -0,0 +1,214 @@
+package sol
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Signer signs transaction messages for one key. solana.PrivateKey is a Signer; the remote
+// and Ledger signers keep the key out of the process.
+type Signer interface {
+	PublicKey() solana.PublicKey
+	Sign(message []byte) (solana.Signature, error)
+}
+
+var _ Signer = solana.PrivateKey(nil)
+
+// LocalSigners returns the keys as Signers
+func LocalSigners(keys ...solana.PrivateKey) []Signer {
+	signers := make([]Signer, len(keys))
+	for i, key := range keys {
+		signers[i] = key
+	}
+	return signers
+}
+
+// DefaultRemoteSignerTimeout bounds a signing request to a remote signer
+const DefaultRemoteSignerTimeout = 10 * time.Second
+
+// RemoteSigner asks a signing service to sign over HTTP. It POSTs
+// {"publicKey": <base58>, "message": <base64>} and expects {"signature": <base58>} back,
+// and checks the signature before returning it.
+type RemoteSigner struct {
+	url     string
+	key     solana.PublicKey
+	client  *http.Client
+	headers http.Header
+}
+
+// NewRemoteSigner returns a signer for key served at url
+func NewRemoteSigner(url string, key solana.PublicKey) *RemoteSigner {
+	return &RemoteSigner{
+		url:     url,
+		key:     key,
+		client:  &http.Client{Timeout: DefaultRemoteSignerTimeout},
+		headers: make(http.Header),
+	}
+}
+
+// SetHTTPClient replaces the client requests are sent with
+func (s *RemoteSigner) SetHTTPClient(client *http.Client) {
+	s.client = client
+}
+
+// SetHeader adds a header to every request, e.g. the service's authorization
+func (s *RemoteSigner) SetHeader(key, value string) {
+	s.headers.Set(key, value)
+}
+
+func (s *RemoteSigner) PublicKey() solana.PublicKey {
+	return s.key
+}
+
+func (s *RemoteSigner) Sign(message []byte) (solana.Signature, error) {
+	body, err := json.Marshal(map[string]string{
+		"publicKey": s.key.String(),
+		"message":   base64.StdEncoding.EncodeToString(message),
+	})
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to build signing request: %w", err)
+	}
+	req.Header = s.headers.Clone()
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("signing request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return solana.Signature{}, fmt.Errorf("signer returned %s: %s", resp.Status, bytes.TrimSpace(detail))
+	}
+	var out struct {
+		Signature string `json:"signature"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to decode signing response: %w", err)
+	}
+	sig, err := solana.SignatureFromBase58(out.Signature)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("invalid signature from signer: %w", err)
+	}
+	if !sig.Verify(s.key, message) {
+		return solana.Signature{}, fmt.Errorf("signer returned a signature that doesn't verify for %s", s.key)
+	}
+	return sig, nil
+}
+
+// LedgerTransport exchanges APDUs with a Ledger device, e.g. over USB HID. The response
+// includes the two status bytes.
+type LedgerTransport interface {
+	Exchange(apdu []byte) ([]byte, error)
+}
+
+// Solana Ledger app APDUs
+const (
+	ledgerCLA           = 0xe0
+	ledgerInsGetPubkey  = 0x05
+	ledgerInsSignMsg    = 0x06
+	ledgerP1Confirm     = 0x01
+	ledgerP2Extend      = 0x01
+	ledgerP2More        = 0x02
+	ledgerMaxChunk      = 255
+	ledgerStatusOK      = 0x9000
+	ledgerHardenedIndex = 0x80000000
+)
+
+// LedgerDerivationPath returns m/44'/501'/account'/0', the path wallets derive Solana
+// accounts at
+func LedgerDerivationPath(account uint32) []uint32 {
+	return []uint32{44 | ledgerHardenedIndex, 501 | ledgerHardenedIndex, account | ledgerHardenedIndex, ledgerHardenedIndex}
+}
+
+// LedgerSigner signs with the Solana app on a Ledger device. Each signature is confirmed
+// on the device.
+type LedgerSigner struct {
+	transport LedgerTransport
+	path      []uint32
+	key       solana.PublicKey
+}
+
+// NewLedgerSigner reads the public key at path from the device
+func NewLedgerSigner(transport LedgerTransport, path []uint32) (*LedgerSigner, error) {
+	s := &LedgerSigner{transport: transport, path: path}
+	resp, err := s.exchange(ledgerInsGetPubkey, 0, 0, s.encodePath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ledger public key: %w", err)
+	}
+	if len(resp) != solana.PublicKeyLength {
+		return nil, fmt.Errorf("ledger returned a %d byte public key", len(resp))
+	}
+	s.key = solana.PublicKeyFromBytes(resp)
+	return s, nil
+}
+
+func (s *LedgerSigner) PublicKey() solana.PublicKey {
+	return s.key
+}
+
+func (s *LedgerSigner) Sign(message []byte) (solana.Signature, error) {
+	// One signer path precedes the message; the payload is split over chunked APDUs
+	payload := append([]byte{1}, s.encodePath()...)
+	payload = append(payload, message...)
+	var resp []byte
+	for offset := 0; offset < len(payload); offset += ledgerMaxChunk {
+		chunk := payload[offset:min(offset+ledgerMaxChunk, len(payload))]
+		var p2 byte
+		if offset > 0 {
+			p2 |= ledgerP2Extend
+		}
+		if offset+len(chunk) < len(payload) {
+			p2 |= ledgerP2More
+		}
+		var err error
+		if resp, err = s.exchange(ledgerInsSignMsg, ledgerP1Confirm, p2, chunk); err != nil {
+			return solana.Signature{}, fmt.Errorf("ledger signing failed: %w", err)
+		}
+	}
+	if len(resp) != len(solana.Signature{}) {
+		return solana.Signature{}, fmt.Errorf("ledger returned a %d byte signature", len(resp))
+	}
+	sig := solana.SignatureFromBytes(resp)
+	if !sig.Verify(s.key, message) {
+		return solana.Signature{}, fmt.Errorf("ledger signature doesn't verify for %s", s.key)
+	}
+	return sig, nil
+}
+
+// encodePath encodes the derivation path as a count followed by big-endian indexes
+func (s *LedgerSigner) encodePath() []byte {
+	out := []byte{byte(len(s.path))}
+	for _, index := range s.path {
+		out = binary.BigEndian.AppendUint32(out, index)
+	}
+	return out
+}
+
+// exchange sends one APDU and returns the response data after checking the status
+func (s *LedgerSigner) exchange(ins, p1, p2 byte, data []byte) ([]byte, error) {
+	apdu := append([]byte{ledgerCLA, ins, p1, p2, byte(len(data))}, data...)
+	resp, err := s.transport.Exchange(apdu)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) < 2 {
+		return nil, fmt.Errorf("short ledger response")
+	}
+	if status := binary.BigEndian.Uint16(resp[len(resp)-2:]); status != ledgerStatusOK {
+		return nil, fmt.Errorf("ledger status 0x%04x", status)
+	}
+	return resp[:len(resp)-2], nil
+}
@@ -0,0 +1,217 @@
+package sol
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/txbuilder"
+	"github.com/gtdvccc/SolRouteTmp/utils"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+)
+
+// SquadsV4ProgramID is the Squads v4 multisig program
+var SquadsV4ProgramID = solana.MustPublicKeyFromBase58("SQDS4ep65T869zMMBKyuUq6aD6EgTu8psMjkvj52pCf")
+
+// squadsTransactionIndexOffset is the offset of Multisig.transaction_index:
+// discriminator(8) + create_key(32) + config_authority(32) + threshold(2) + time_lock(4)
+const squadsTransactionIndexOffset = 78
+
+// SquadsProposal describes where a swap should be proposed instead of being signed directly
+type SquadsProposal struct {
+	Multisig   solana.PublicKey // multisig account
+	VaultIndex uint8            // vault that holds the tokens and executes the swap
+	Creator    solana.PublicKey // member creating the proposal, must sign the outer transaction
+	RentPayer  solana.PublicKey // pays rent for the transaction and proposal accounts; defaults to Creator
+	Memo       string
+}
+
+// DeriveSquadsVaultPDA returns the vault address that must be used as the swap user
+// when building instructions for a Squads proposal
+func DeriveSquadsVaultPDA(multisig solana.PublicKey, vaultIndex uint8) (solana.PublicKey, error) {
+	vault, err := txbuilder.SquadsVault(SquadsV4ProgramID, multisig, vaultIndex)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to derive vault PDA: %w", err)
+	}
+	return vault, nil
+}
+
+// BuildSquadsProposal wraps the swap instructions into a Squads v4 vault transaction and an
+// active proposal. The returned instructions are signed by the proposal creator with SendTx;
+// members then approve and execute the proposal through their usual multisig flow.
+// Swap instructions must be built with the vault PDA as the user.
+func (c *Client) BuildSquadsProposal(ctx context.Context, proposal SquadsProposal, insts []solana.Instruction) ([]solana.Instruction, error) {
+	if len(insts) == 0 {
+		return nil, fmt.Errorf("no instructions to propose")
+	}
+	rentPayer := proposal.RentPayer
+	if rentPayer.IsZero() {
+		rentPayer = proposal.Creator
+	}
+
+	account, err := c.RpcClient.GetAccountInfo(ctx, proposal.Multisig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get multisig account: %w", err)
+	}
+	data := account.Value.Data.GetBinary()
+	if len(data) < squadsTransactionIndexOffset+8 {
+		return nil, fmt.Errorf("invalid multisig account data length: %d", len(data))
+	}
+	transactionIndex := binary.LittleEndian.Uint64(data[squadsTransactionIndexOffset:]) + 1
+
+	vault, err := DeriveSquadsVaultPDA(proposal.Multisig, proposal.VaultIndex)
+	if err != nil {
+		return nil, err
+	}
+	transactionPDA, err := txbuilder.SquadsTransaction(SquadsV4ProgramID, proposal.Multisig, transactionIndex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive transaction PDA: %w", err)
+	}
+	proposalPDA, err := txbuilder.SquadsProposal(SquadsV4ProgramID, proposal.Multisig, transactionIndex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive proposal PDA: %w", err)
+	}
+
+	message, err := compileSquadsTransactionMessage(vault, insts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile vault transaction message: %w", err)
+	}
+
+	// vault_transaction_create
+	buf := new(bytes.Buffer)
+	enc := bin.NewBorshEncoder(buf)
+	buf.Write(utils.GetDiscriminator("global", "vault_transaction_create"))
+	if err := enc.WriteUint8(proposal.VaultIndex); err != nil {
+		return nil, err
+	}
+	if err := enc.WriteUint8(0); err != nil { // ephemeral_signers
+		return nil, err
+	}
+	if err := enc.WriteBytes(message, true); err != nil {
+		return nil, err
+	}
+	if err := writeOptionalString(enc, proposal.Memo); err != nil {
+		return nil, err
+	}
+	createTx := solana.NewInstruction(SquadsV4ProgramID, solana.AccountMetaSlice{
+		solana.NewAccountMeta(proposal.Multisig, true, false),
+		solana.NewAccountMeta(transactionPDA, true, false),
+		solana.NewAccountMeta(proposal.Creator, false, true),
+		solana.NewAccountMeta(rentPayer, true, true),
+		solana.NewAccountMeta(solana.SystemProgramID, false, false),
+	}, buf.Bytes())
+
+	// proposal_create
+	buf = new(bytes.Buffer)
+	enc = bin.NewBorshEncoder(buf)
+	buf.Write(utils.GetDiscriminator("global", "proposal_create"))
+	if err := enc.WriteUint64(transactionIndex, binary.LittleEndian); err != nil {
+		return nil, err
+	}
+	if err := enc.WriteBool(false); err != nil { // draft
+		return nil, err
+	}
+	createProposal := solana.NewInstruction(SquadsV4ProgramID, solana.AccountMetaSlice{
+		solana.NewAccountMeta(proposal.Multisig, false, false),
+		solana.NewAccountMeta(proposalPDA, true, false),
+		solana.NewAccountMeta(proposal.Creator, false, true),
+		solana.NewAccountMeta(rentPayer, true, true),
+		solana.NewAccountMeta(solana.SystemProgramID, false, false),
+	}, buf.Bytes())
+
+	return []solana.Instruction{createTx, createProposal}, nil
+}
+
+// compileSquadsTransactionMessage serializes instructions into the Squads TransactionMessage
+// layout, which uses u8-length SmallVecs instead of the compact-u16 encoding of legacy messages
+func compileSquadsTransactionMessage(vault solana.PublicKey, insts []solana.Instruction) ([]byte, error) {
+	type keyMeta struct {
+		key                  solana.PublicKey
+		isSigner, isWritable bool
+	}
+	metas := []*keyMeta{{key: vault, isSigner: true, isWritable: true}}
+	index := map[solana.PublicKey]*keyMeta{vault: metas[0]}
+	add := func(key solana.PublicKey, isSigner, isWritable bool) {
+		if m, ok := index[key]; ok {
+			m.isSigner = m.isSigner || isSigner
+			m.isWritable = m.isWritable || isWritable
+			return
+		}
+		m := &keyMeta{key: key, isSigner: isSigner, isWritable: isWritable}
+		index[key] = m
+		metas = append(metas, m)
+	}
+	for _, inst := range insts {
+		for _, acc := range inst.Accounts() {
+			add(acc.PublicKey, acc.IsSigner, acc.IsWritable)
+		}
+		add(inst.ProgramID(), false, false)
+	}
+
+	// Order: writable signers, readonly signers, writable non-signers, readonly non-signers
+	var ordered []*keyMeta
+	for _, group := range [][2]bool{{true, true}, {true, false}, {false, true}, {false, false}} {
+		for _, m := range metas {
+			if m.isSigner == group[0] && m.isWritable == group[1] {
+				ordered = append(ordered, m)
+			}
+		}
+	}
+	if len(ordered) > 255 {
+		return nil, fmt.Errorf("too many accounts: %d", len(ordered))
+	}
+	position := make(map[solana.PublicKey]uint8, len(ordered))
+	var numSigners, numWritableSigners, numWritableNonSigners uint8
+	for i, m := range ordered {
+		position[m.key] = uint8(i)
+		if m.isSigner {
+			numSigners++
+			if m.isWritable {
+				numWritableSigners++
+			}
+		} else if m.isWritable {
+			numWritableNonSigners++
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	buf.Write([]byte{numSigners, numWritableSigners, numWritableNonSigners})
+	buf.WriteByte(uint8(len(ordered)))
+	for _, m := range ordered {
+		buf.Write(m.key.Bytes())
+	}
+	buf.WriteByte(uint8(len(insts)))
+	for _, inst := range insts {
+		data, err := inst.Data()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get instruction data: %w", err)
+		}
+		accounts := inst.Accounts()
+		if len(accounts) > 255 || len(data) > 65535 {
+			return nil, fmt.Errorf("instruction for %s too large for a vault transaction", inst.ProgramID())
+		}
+		buf.WriteByte(position[inst.ProgramID()])
+		buf.WriteByte(uint8(len(accounts)))
+		for _, acc := range accounts {
+			buf.WriteByte(position[acc.PublicKey])
+		}
+		dataLen := make([]byte, 2)
+		binary.LittleEndian.PutUint16(dataLen, uint16(len(data)))
+		buf.Write(dataLen)
+		buf.Write(data)
+	}
+	buf.WriteByte(0) // address_table_lookups
+	return buf.Bytes(), nil
+}
+
+func writeOptionalString(enc *bin.Encoder, s string) error {
+	if s == "" {
+		return enc.WriteOption(false)
+	}
+	if err := enc.WriteOption(true); err != nil {
+		return err
+	}
+	return enc.WriteString(s)
+}
@@ -0,0 +1,221 @@
+package sol
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/telemetry"
+	"github.com/gtdvccc/SolRouteTmp/pkg/txbuilder"
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// SwapOptions controls what the client adds around a pool's swap instructions
+type SwapOptions struct {
+	// WrapSol funds a WSOL or native SOL input from the user's SOL, closing the WSOL account
+	// afterwards, and unwraps a WSOL output to native SOL, so CoverWsol and CloseWsol aren't
+	// needed. Passing NativeSOL as a mint always wraps it.
+	WrapSol bool
+	// CreateOutputAccount prepends the creation of the user's output token account when it
+	// doesn't exist, so the swap doesn't fail on a missing destination
+	CreateOutputAccount bool
+}
+
+// BuildSwapInstructions builds pool's swap of amountIn of inputMint, surrounded by the WSOL
+// wrap and unwrap when opts.WrapSol is set or inputMint is NativeSOL
+func (t *Client) BuildSwapInstructions(ctx context.Context, pool pkg.Pool, user solana.PublicKey, inputMint string, amountIn, minOut math.Int, opts SwapOptions) (instructions []solana.Instruction, err error) {
+	ctx, span := t.startBuildSwap(ctx, pool, inputMint)
+	defer func() { telemetry.End(span, err) }()
+	inputMint, wrap := poolMint(inputMint, opts)
+	outputMint, err := otherMint(pool, inputMint)
+	if err != nil {
+		return nil, err
+	}
+	unlock := pkg.LockPool(pool)
+	instructions, err = pool.BuildSwapInstructions(ctx, t.RpcClient, user, inputMint, amountIn, minOut)
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+	return t.surroundSwap(ctx, user, inputMint, outputMint, amountIn, instructions, wrap, opts)
+}
+
+// BuildSwapInstructionsWithSlippage builds pool's swap like BuildSwapInstructions, with the
+// minimum output the slippage policy allows below expectedOut. The price impact isn't known
+// here, so a policy with an impact ceiling is rejected; SimpleRouter.Quote applies those.
+func (t *Client) BuildSwapInstructionsWithSlippage(ctx context.Context, pool pkg.Pool, user solana.PublicKey, inputMint string, amountIn, expectedOut math.Int, slippage pkg.SlippageConfig, opts SwapOptions) ([]solana.Instruction, error) {
+	minOut, _, err := slippage.MinAmountOut(expectedOut, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	return t.BuildSwapInstructions(ctx, pool, user, inputMint, amountIn, minOut, opts)
+}
+
+// BuildSwapInstructionsExactOut builds pool's swap for amountOut of outputMint like
+// BuildSwapInstructions, wrapping up to maxIn of a SOL input
+func (t *Client) BuildSwapInstructionsExactOut(ctx context.Context, pool pkg.Pool, user solana.PublicKey, outputMint string, amountOut, maxIn math.Int, opts SwapOptions) (instructions []solana.Instruction, err error) {
+	ctx, span := t.startBuildSwap(ctx, pool, "")
+	defer func() { telemetry.End(span, err) }()
+	outputMint, wrap := poolMint(outputMint, opts)
+	inputMint, err := otherMint(pool, outputMint)
+	if err != nil {
+		return nil, err
+	}
+	unlock := pkg.LockPool(pool)
+	instructions, err = pool.BuildSwapInstructionsExactOut(ctx, t.RpcClient, user, outputMint, amountOut, maxIn)
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+	return t.surroundSwap(ctx, user, inputMint, outputMint, maxIn, instructions, wrap, opts)
+}
+
+// startBuildSwap starts the span of building a swap through pool
+func (t *Client) startBuildSwap(ctx context.Context, pool pkg.Pool, inputMint string) (context.Context, telemetry.Span) {
+	attrs := []telemetry.Attr{
+		telemetry.String(pkg.LogKeyPool, pool.GetID()),
+		telemetry.String(pkg.LogKeyProtocol, string(pool.ProtocolName())),
+	}
+	if inputMint != "" {
+		attrs = append(attrs, telemetry.String(pkg.LogKeyInputMint, inputMint))
+	}
+	return t.telemetry.Start(ctx, telemetry.SpanBuildSwap, attrs...)
+}
+
+// poolMint maps NativeSOL to the WSOL mint pools trade, and reports whether to wrap
+func poolMint(mint string, opts SwapOptions) (string, bool) {
+	if mint == NativeSOL.String() {
+		return WSOL.String(), true
+	}
+	return mint, opts.WrapSol
+}
+
+// otherMint returns the pool's token that isn't mint
+func otherMint(pool pkg.Pool, mint string) (string, error) {
+	baseMint, quoteMint := pool.GetTokens()
+	switch mint {
+	case baseMint:
+		return quoteMint, nil
+	case quoteMint:
+		return baseMint, nil
+	}
+	return "", fmt.Errorf("mint %s not in pool %s", mint, pool.GetID())
+}
+
+// surroundSwap adds the output account creation and the SOL wrapping the options ask for
+func (t *Client) surroundSwap(ctx context.Context, user solana.PublicKey, inputMint, outputMint string, amountIn math.Int, instructions []solana.Instruction, wrap bool, opts SwapOptions) ([]solana.Instruction, error) {
+	// Wrapping prepares the WSOL output account itself
+	if opts.CreateOutputAccount && !(wrap && outputMint == WSOL.String()) {
+		create, err := t.CreateTokenAccountInstructions(ctx, user, outputMint)
+		if err != nil {
+			return nil, err
+		}
+		instructions = append(create, instructions...)
+	}
+	if !wrap {
+		return instructions, nil
+	}
+	if !amountIn.IsUint64() {
+		return nil, fmt.Errorf("input amount %s overflows u64", amountIn)
+	}
+	return t.WrapWsolSwap(ctx, user, inputMint, amountIn.Uint64(), instructions, WsolOptions{
+		Unwind:       true,
+		OutputMint:   outputMint,
+		UnwrapOutput: UnwrapAlways,
+	})
+}
+
+// CreateTokenAccountInstructions returns the instruction creating user's associated token
+// account of mint under the mint's token program, or nothing when the account exists
+func (t *Client) CreateTokenAccountInstructions(ctx context.Context, user solana.PublicKey, mint string) ([]solana.Instruction, error) {
+	mintKey, err := solana.PublicKeyFromBase58(mint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mint %s: %w", mint, err)
+	}
+	// The account's address depends on the mint's program, so read the mint with both
+	// candidates in one request
+	splAccount, err := txbuilder.AssociatedTokenAddress(user, mintKey, solana.TokenProgramID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive token account: %w", err)
+	}
+	token2022Account, err := txbuilder.AssociatedTokenAddress(user, mintKey, solana.Token2022ProgramID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive token account: %w", err)
+	}
+	results, err := t.RpcClient.GetMultipleAccountsWithOpts(ctx, []solana.PublicKey{mintKey, splAccount, token2022Account}, MultipleAccountsOpts(ctx, rpc.CommitmentConfirmed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mint %s: %w", mint, err)
+	}
+	if len(results.Value) != 3 {
+		return nil, fmt.Errorf("expected 3 accounts, got %d", len(results.Value))
+	}
+	if results.Value[0] == nil {
+		return nil, fmt.Errorf("mint %s not found", mint)
+	}
+	tokenProgram, existing := results.Value[0].Owner, results.Value[1]
+	if tokenProgram.Equals(solana.Token2022ProgramID) {
+		existing = results.Value[2]
+	}
+	if existing != nil {
+		return nil, nil
+	}
+	create, err := txbuilder.CreateAssociatedTokenAccountIdempotent(user, user, mintKey, tokenProgram)
+	if err != nil {
+		return nil, err
+	}
+	return []solana.Instruction{create}, nil
+}
+
+// BuildSwapRequest builds the swap req describes through pool, with the minimum output its
+// slippage policy allows below expectedOut and its priority fee as the compute unit price.
+// The request's fee payer swaps.
+func (t *Client) BuildSwapRequest(ctx context.Context, pool pkg.Pool, req pkg.SwapRequest, expectedOut math.Int, opts SwapOptions) ([]solana.Instruction, error) {
+	if req.Clock == nil && t.TimeSource != nil {
+		req = req.WithClock(t.TimeSource)
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if req.FeePayer.IsZero() {
+		return nil, fmt.Errorf("swap request has no fee payer")
+	}
+	inputMint, _ := poolMint(req.InputMint, opts)
+	outputMint, _ := poolMint(req.OutputMint, opts)
+	if other, err := otherMint(pool, inputMint); err != nil || other != outputMint {
+		return nil, fmt.Errorf("pool %s does not swap %s for %s", pool.GetID(), req.InputMint, req.OutputMint)
+	}
+	instructions, err := t.BuildSwapInstructionsWithSlippage(ctx, pool, req.FeePayer, req.InputMint, req.Amount, expectedOut, req.Slippage, opts)
+	if err != nil {
+		return nil, err
+	}
+	if req.PriorityFee == 0 {
+		return instructions, nil
+	}
+	priceIx, err := computebudget.NewSetComputeUnitPriceInstruction(req.PriorityFee).ValidateAndBuild()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build compute unit price instruction: %w", err)
+	}
+	return append([]solana.Instruction{priceIx}, instructions...), nil
+}
+
+// EstimateSwapRent returns the rent a swap by user into outputMint leaves in the accounts it
+// creates under opts: the output token account's, when opts create it and it doesn't exist.
+// The WSOL accounts WrapSol creates are closed in the same transaction, refunding their rent.
+func (t *Client) EstimateSwapRent(ctx context.Context, user solana.PublicKey, outputMint string, opts SwapOptions) (uint64, error) {
+	outputMint, wrap := poolMint(outputMint, opts)
+	if !opts.CreateOutputAccount || (wrap && outputMint == WSOL.String()) {
+		return 0, nil
+	}
+	create, err := t.CreateTokenAccountInstructions(ctx, user, outputMint)
+	if err != nil || len(create) == 0 {
+		return 0, err
+	}
+	rent, err := t.RpcClient.GetMinimumBalanceForRentExemption(ctx, TokenAccountSize, rpc.CommitmentConfirmed)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get token account rent: %w", err)
+	}
+	return rent, nil
+}
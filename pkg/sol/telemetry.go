@@ -0,0 +1,179 @@
+package sol
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/telemetry"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// MeteredRPC records the latency of every request it passes to an RPC as
+// telemetry.MetricRPCLatency, by JSON-RPC method and outcome. It is safe for concurrent use.
+type MeteredRPC struct {
+	inner     RPC
+	telemetry *telemetry.Telemetry
+}
+
+var _ RPC = (*MeteredRPC)(nil)
+
+// NewMeteredRPC wraps inner
+func NewMeteredRPC(inner RPC, t *telemetry.Telemetry) *MeteredRPC {
+	return &MeteredRPC{inner: inner, telemetry: t}
+}
+
+// SetTelemetry traces the client's instruction building, swap execution and sends, and
+// measures every RPC request made through it, those of its protocols and pools included.
+// Requests are measured as sent to the node, so each retry counts and reads merged by the
+// rate limit count once. Nil turns telemetry off. Set it before the client is shared.
+func (c *Client) SetTelemetry(t *telemetry.Telemetry) {
+	c.telemetry = t
+	inner, replace := c.transport()
+	if metered, ok := inner.(*MeteredRPC); ok {
+		inner = metered.inner
+	}
+	if !t.Metering() {
+		replace(inner)
+		return
+	}
+	replace(NewMeteredRPC(inner, t))
+}
+
+// transport returns the RPC beneath the client's retry policy, rate limit and commitment
+// config, and a function putting a replacement for it back in place
+func (c *Client) transport() (RPC, func(RPC)) {
+	inner, replace := c.underRetry()
+	if limited, ok := inner.(*LimitedRPC); ok {
+		inner, replace = limited.inner, func(next RPC) { limited.inner = next }
+	}
+	if committed, ok := inner.(*CommitmentRPC); ok {
+		inner, replace = committed.inner, func(next RPC) { committed.inner = next }
+	}
+	return inner, replace
+}
+
+// metered makes a request under method, recording its latency
+func metered[T any](m *MeteredRPC, ctx context.Context, method string, fn func() (T, error)) (T, error) {
+	start := time.Now()
+	out, err := fn()
+	m.telemetry.RecordDuration(ctx, telemetry.MetricRPCLatency, time.Since(start), telemetry.String(telemetry.AttrMethod, method), telemetry.Outcome(err))
+	return out, err
+}
+
+func (m *MeteredRPC) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	return metered(m, ctx, "getAccountInfo", func() (*rpc.GetAccountInfoResult, error) {
+		return m.inner.GetAccountInfo(ctx, account)
+	})
+}
+
+func (m *MeteredRPC) GetAccountInfoWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetAccountInfoOpts) (*rpc.GetAccountInfoResult, error) {
+	return metered(m, ctx, "getAccountInfo", func() (*rpc.GetAccountInfoResult, error) {
+		return m.inner.GetAccountInfoWithOpts(ctx, account, opts)
+	})
+}
+
+func (m *MeteredRPC) GetMultipleAccounts(ctx context.Context, accounts ...solana.PublicKey) (*rpc.GetMultipleAccountsResult, error) {
+	return metered(m, ctx, "getMultipleAccounts", func() (*rpc.GetMultipleAccountsResult, error) {
+		return m.inner.GetMultipleAccounts(ctx, accounts...)
+	})
+}
+
+func (m *MeteredRPC) GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error) {
+	return metered(m, ctx, "getMultipleAccounts", func() (*rpc.GetMultipleAccountsResult, error) {
+		return m.inner.GetMultipleAccountsWithOpts(ctx, accounts, opts)
+	})
+}
+
+func (m *MeteredRPC) GetProgramAccountsWithOpts(ctx context.Context, program solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (rpc.GetProgramAccountsResult, error) {
+	return metered(m, ctx, "getProgramAccounts", func() (rpc.GetProgramAccountsResult, error) {
+		return m.inner.GetProgramAccountsWithOpts(ctx, program, opts)
+	})
+}
+
+func (m *MeteredRPC) GetTokenAccountsByOwner(ctx context.Context, owner solana.PublicKey, conf *rpc.GetTokenAccountsConfig, opts *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error) {
+	return metered(m, ctx, "getTokenAccountsByOwner", func() (*rpc.GetTokenAccountsResult, error) {
+		return m.inner.GetTokenAccountsByOwner(ctx, owner, conf, opts)
+	})
+}
+
+func (m *MeteredRPC) GetTokenAccountBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetTokenAccountBalanceResult, error) {
+	return metered(m, ctx, "getTokenAccountBalance", func() (*rpc.GetTokenAccountBalanceResult, error) {
+		return m.inner.GetTokenAccountBalance(ctx, account, commitment)
+	})
+}
+
+func (m *MeteredRPC) GetBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+	return metered(m, ctx, "getBalance", func() (*rpc.GetBalanceResult, error) {
+		return m.inner.GetBalance(ctx, account, commitment)
+	})
+}
+
+func (m *MeteredRPC) GetSlot(ctx context.Context, commitment rpc.CommitmentType) (uint64, error) {
+	return metered(m, ctx, "getSlot", func() (uint64, error) {
+		return m.inner.GetSlot(ctx, commitment)
+	})
+}
+
+func (m *MeteredRPC) GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+	return metered(m, ctx, "getLatestBlockhash", func() (*rpc.GetLatestBlockhashResult, error) {
+		return m.inner.GetLatestBlockhash(ctx, commitment)
+	})
+}
+
+func (m *MeteredRPC) GetMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64, commitment rpc.CommitmentType) (uint64, error) {
+	return metered(m, ctx, "getMinimumBalanceForRentExemption", func() (uint64, error) {
+		return m.inner.GetMinimumBalanceForRentExemption(ctx, dataSize, commitment)
+	})
+}
+
+func (m *MeteredRPC) GetRecentPrioritizationFees(ctx context.Context, accounts solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error) {
+	return metered(m, ctx, "getRecentPrioritizationFees", func() ([]rpc.PriorizationFeeResult, error) {
+		return m.inner.GetRecentPrioritizationFees(ctx, accounts)
+	})
+}
+
+func (m *MeteredRPC) SendTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts rpc.TransactionOpts) (solana.Signature, error) {
+	return metered(m, ctx, "sendTransaction", func() (solana.Signature, error) {
+		return m.inner.SendTransactionWithOpts(ctx, tx, opts)
+	})
+}
+
+func (m *MeteredRPC) SimulateTransaction(ctx context.Context, tx *solana.Transaction) (*rpc.SimulateTransactionResponse, error) {
+	return metered(m, ctx, "simulateTransaction", func() (*rpc.SimulateTransactionResponse, error) {
+		return m.inner.SimulateTransaction(ctx, tx)
+	})
+}
+
+func (m *MeteredRPC) SimulateTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts *rpc.SimulateTransactionOpts) (*rpc.SimulateTransactionResponse, error) {
+	return metered(m, ctx, "simulateTransaction", func() (*rpc.SimulateTransactionResponse, error) {
+		return m.inner.SimulateTransactionWithOpts(ctx, tx, opts)
+	})
+}
+
+// GetTransaction passes through to an inner TransactionReader, so ExecutionReport keeps
+// working on a metered client
+func (m *MeteredRPC) GetTransaction(ctx context.Context, signature solana.Signature, opts *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+	reader, ok := m.inner.(TransactionReader)
+	if !ok {
+		return nil, fmt.Errorf("rpc client %T can't fetch transactions", m.inner)
+	}
+	return metered(m, ctx, "getTransaction", func() (*rpc.GetTransactionResult, error) {
+		return reader.GetTransaction(ctx, signature, opts)
+	})
+}
+
+// GetSignaturesForAddressWithOpts passes through to the inner RPC, so swap event listing keeps
+// working on a metered client
+func (m *MeteredRPC) GetSignaturesForAddressWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error) {
+	lister, ok := m.inner.(interface {
+		GetSignaturesForAddressWithOpts(context.Context, solana.PublicKey, *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("rpc client %T can't list signatures", m.inner)
+	}
+	return metered(m, ctx, "getSignaturesForAddress", func() ([]*rpc.TransactionSignature, error) {
+		return lister.GetSignaturesForAddressWithOpts(ctx, account, opts)
+	})
+}
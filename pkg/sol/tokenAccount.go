@@ -2,13 +2,29 @@ package sol
 
 import (
 	"context"
-	"log"
+	"fmt"
 
+	"github.com/gtdvccc/SolRouteTmp/pkg/txbuilder"
 	"github.com/gagliardetto/solana-go"
 	associatedtokenaccount "github.com/gagliardetto/solana-go/programs/associated-token-account"
 	"github.com/gagliardetto/solana-go/rpc"
 )
 
+// AssociatedTokenAddress derives owner's associated token account for mint under tokenProgram.
+//
+// Deprecated: use txbuilder.AssociatedTokenAddress, which doesn't pull in the RPC client.
+func AssociatedTokenAddress(owner, mint, tokenProgram solana.PublicKey) (solana.PublicKey, error) {
+	return txbuilder.AssociatedTokenAddress(owner, mint, tokenProgram)
+}
+
+// CreateAssociatedTokenAccountIdempotent builds the idempotent create instruction of owner's
+// account of mint.
+//
+// Deprecated: use txbuilder.CreateAssociatedTokenAccountIdempotent.
+func CreateAssociatedTokenAccountIdempotent(payer, owner, mint, tokenProgram solana.PublicKey) (solana.Instruction, error) {
+	return txbuilder.CreateAssociatedTokenAccountIdempotent(payer, owner, mint, tokenProgram)
+}
+
 func (t *Client) SelectOrCreateSPLTokenAccount(ctx context.Context, privateKey solana.PrivateKey, tokenMint solana.PublicKey) (solana.PublicKey, error) {
 	user := privateKey.PublicKey()
 	acc, err := t.RpcClient.GetTokenAccountsByOwner(ctx, user,
@@ -18,8 +34,7 @@ func (t *Client) SelectOrCreateSPLTokenAccount(ctx context.Context, privateKey s
 		},
 	)
 	if err != nil {
-		log.Printf("GetTokenAccountsByOwner err: %v", err)
-		return solana.PublicKey{}, err
+		return solana.PublicKey{}, fmt.Errorf("failed to get token accounts: %w", err)
 	}
 	if len(acc.Value) > 0 {
 		return acc.Value[0].Pubkey, nil
@@ -28,8 +43,7 @@ func (t *Client) SelectOrCreateSPLTokenAccount(ctx context.Context, privateKey s
 	// Find ATA address (this will always return a valid PDA)
 	ataAddress, _, err := solana.FindAssociatedTokenAddress(user, tokenMint)
 	if err != nil {
-		log.Printf("FindAssociatedTokenAddress err: %v", err)
-		return solana.PublicKey{}, err
+		return solana.PublicKey{}, fmt.Errorf("failed to derive token account: %w", err)
 	}
 	instructions := make([]solana.Instruction, 0)
 	createAtaInst, err := associatedtokenaccount.NewCreateInstruction(
@@ -45,15 +59,9 @@ func (t *Client) SelectOrCreateSPLTokenAccount(ctx context.Context, privateKey s
 	if len(instructions) == 0 {
 		return ataAddress, nil
 	} else {
-		latestBlockhash, err := t.RpcClient.GetLatestBlockhash(ctx, rpc.CommitmentConfirmed)
-		if err != nil {
-			log.Printf("Failed to get latest blockhash: %v", err)
-			return solana.PublicKey{}, err
-		}
 		signers := []solana.PrivateKey{privateKey}
-		_, err = t.SendTx(ctx, latestBlockhash.Value.Blockhash, signers, instructions, false)
+		_, err = t.SendTx(ctx, solana.Hash{}, signers, instructions, false)
 		if err != nil {
-			log.Printf("Failed to send transaction: %v", err)
 			return solana.PublicKey{}, err
 		}
 		return ataAddress, nil
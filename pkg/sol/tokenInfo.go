@@ -0,0 +1,225 @@
+package sol
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// mintAccountSize is the size of an SPL mint; Token-2022 mints with extensions are longer
+const mintAccountSize = 82
+
+// Metaplex token metadata layout: a key byte, the update authority and mint, then the name,
+// symbol and URI as borsh strings padded with zero bytes
+const (
+	metadataKeyV1      = 4
+	metadataNameOffset = 65
+	// metadataMaxString bounds the padded name and symbol; longer lengths mean a corrupt account
+	metadataMaxString = 200
+)
+
+// tokenInfoBatchSize is how many mints are read per request, each with its metadata account
+const tokenInfoBatchSize = maxMultipleAccounts / 2
+
+// TokenInfo describes a mint: its decimals and, when it has Metaplex metadata, its symbol and
+// name
+type TokenInfo struct {
+	Mint     solana.PublicKey
+	Decimals uint8
+	Symbol   string
+	Name     string
+}
+
+// Label returns the token's symbol, or its mint when it has none
+func (t TokenInfo) Label() string {
+	if t.Symbol != "" {
+		return t.Symbol
+	}
+	return t.Mint.String()
+}
+
+// Normalize renders a raw amount of the token in decimal units without rounding
+func (t TokenInfo) Normalize(raw math.Int) string {
+	return NormalizeAmount(raw, t.Decimals)
+}
+
+// Format renders a raw amount of the token for people, e.g. "1.5 USDC"
+func (t TokenInfo) Format(raw math.Int) string {
+	return t.Normalize(raw) + " " + t.Label()
+}
+
+// NormalizeAmount renders a raw amount in decimal units without rounding, e.g. 1500000 with
+// 6 decimals is "1.5"
+func NormalizeAmount(raw math.Int, decimals uint8) string {
+	if raw.IsNil() {
+		return ""
+	}
+	digits := raw.Abs().String()
+	if len(digits) <= int(decimals) {
+		digits = strings.Repeat("0", int(decimals)-len(digits)+1) + digits
+	}
+	intPart, fracPart := digits[:len(digits)-int(decimals)], strings.TrimRight(digits[len(digits)-int(decimals):], "0")
+	s := intPart
+	if fracPart != "" {
+		s += "." + fracPart
+	}
+	if raw.IsNegative() {
+		s = "-" + s
+	}
+	return s
+}
+
+// TokenResolver fetches and caches the decimals and Metaplex symbol and name of mints.
+// Decimals never change once a mint is created and metadata rarely does, so both are kept
+// until replaced with SetTokenInfo. It is safe for concurrent use.
+type TokenResolver struct {
+	client RPC
+
+	mu     sync.RWMutex
+	tokens map[solana.PublicKey]TokenInfo
+}
+
+// NewTokenResolver creates a resolver that reads mints through solClient, which may be nil
+// when every Resolve passes a client
+func NewTokenResolver(solClient RPC) *TokenResolver {
+	return &TokenResolver{
+		client: solClient,
+		tokens: map[solana.PublicKey]TokenInfo{
+			solana.WrappedSol: {Mint: solana.WrappedSol, Decimals: 9, Symbol: "SOL", Name: "Wrapped SOL"},
+		},
+	}
+}
+
+// SetTokenInfo records a token so it is never fetched, e.g. for well-known tokens or offline
+// use
+func (t *TokenResolver) SetTokenInfo(info TokenInfo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tokens[info.Mint] = info
+}
+
+// Resolve returns the info of each mint, reading the mints not cached and their metadata
+// accounts together. Mints without metadata resolve with an empty symbol and name. A nil
+// solClient uses the resolver's.
+func (t *TokenResolver) Resolve(ctx context.Context, solClient RPC, mints ...solana.PublicKey) ([]TokenInfo, error) {
+	result := make([]TokenInfo, len(mints))
+	var missing []solana.PublicKey
+	t.mu.RLock()
+	for i, mint := range mints {
+		info, ok := t.tokens[mint]
+		if !ok {
+			missing = append(missing, mint)
+		}
+		result[i] = info
+	}
+	t.mu.RUnlock()
+	if len(missing) == 0 {
+		return result, nil
+	}
+	if solClient == nil {
+		solClient = t.client
+	}
+	if solClient == nil {
+		return nil, fmt.Errorf("no rpc client to read mints with")
+	}
+
+	fetched := make(map[solana.PublicKey]TokenInfo, len(missing))
+	for start := 0; start < len(missing); start += tokenInfoBatchSize {
+		batch := missing[start:min(start+tokenInfoBatchSize, len(missing))]
+		infos, err := fetchTokenInfos(ctx, solClient, batch)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			fetched[info.Mint] = info
+		}
+	}
+
+	t.mu.Lock()
+	for mint, info := range fetched {
+		t.tokens[mint] = info
+	}
+	t.mu.Unlock()
+	for i, mint := range mints {
+		if info, ok := fetched[mint]; ok {
+			result[i] = info
+		}
+	}
+	return result, nil
+}
+
+// fetchTokenInfos reads mints and their metadata accounts in one request. Mints with
+// Token-2022 metadata rather than Metaplex's resolve unnamed.
+func fetchTokenInfos(ctx context.Context, solClient RPC, mints []solana.PublicKey) ([]TokenInfo, error) {
+	accounts := make([]solana.PublicKey, 0, 2*len(mints))
+	accounts = append(accounts, mints...)
+	for _, mint := range mints {
+		metadata, _, err := solana.FindTokenMetadataAddress(mint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive metadata address of %s: %w", mint, err)
+		}
+		accounts = append(accounts, metadata)
+	}
+	results, err := solClient.GetMultipleAccountsWithOpts(ctx, accounts, MultipleAccountsOpts(ctx, rpc.CommitmentConfirmed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mint accounts: %w", err)
+	}
+	if len(results.Value) != len(accounts) {
+		return nil, fmt.Errorf("expected %d mint and metadata accounts, got %d", len(accounts), len(results.Value))
+	}
+
+	infos := make([]TokenInfo, len(mints))
+	for i, mint := range mints {
+		account := results.Value[i]
+		if account == nil {
+			return nil, fmt.Errorf("mint %s not found", mint)
+		}
+		data := account.Data.GetBinary()
+		if len(data) < mintAccountSize {
+			return nil, fmt.Errorf("mint account %s too short: %d bytes", mint, len(data))
+		}
+		infos[i] = TokenInfo{Mint: mint, Decimals: data[mintDecimalsOffset]}
+		// Metadata is only for display, so a malformed account leaves the token unnamed
+		// rather than failing the quote that needs its decimals
+		if metadata := results.Value[len(mints)+i]; metadata != nil && metadata.Owner.Equals(solana.TokenMetadataProgramID) {
+			if name, symbol, err := parseTokenMetadata(metadata.Data.GetBinary()); err == nil {
+				infos[i].Name, infos[i].Symbol = name, symbol
+			}
+		}
+	}
+	return infos, nil
+}
+
+// parseTokenMetadata reads the name and symbol of a Metaplex metadata account
+func parseTokenMetadata(data []byte) (name, symbol string, err error) {
+	if len(data) == 0 || data[0] != metadataKeyV1 {
+		return "", "", fmt.Errorf("not a metadata account")
+	}
+	offset := metadataNameOffset
+	readString := func() (string, error) {
+		if len(data) < offset+4 {
+			return "", fmt.Errorf("account too short: %d bytes", len(data))
+		}
+		n := int(binary.LittleEndian.Uint32(data[offset:]))
+		offset += 4
+		if n > metadataMaxString || len(data) < offset+n {
+			return "", fmt.Errorf("string of %d bytes at offset %d overruns account", n, offset)
+		}
+		s := strings.TrimSpace(strings.TrimRight(string(data[offset:offset+n]), "\x00"))
+		offset += n
+		return s, nil
+	}
+	if name, err = readString(); err != nil {
+		return "", "", err
+	}
+	if symbol, err = readString(); err != nil {
+		return "", "", err
+	}
+	return name, symbol, nil
+}
@@ -0,0 +1,200 @@
+package sol
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/txbuilder"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Extra account meta layout of the SPL transfer hook interface
+const (
+	extraAccountMetaSize     = 35
+	extraAccountMetaFixed    = 0
+	extraAccountMetaHookPDA  = 1
+	extraAccountMetaExternal = 1 << 7 // plus the index of the account owning the PDA
+
+	seedEnd             = 0
+	seedLiteral         = 1
+	seedInstructionData = 2
+	seedAccountKey      = 3
+)
+
+// executeDiscriminator prefixes the transfer hook Execute instruction and tags its extra
+// account metas in the validation account
+var executeDiscriminator = func() []byte {
+	hash := sha256.Sum256([]byte("spl-transfer-hook-interface:execute"))
+	return hash[:8]
+}()
+
+// HookTransfer is a transfer of a hooked mint, as seen by the hook program
+type HookTransfer struct {
+	Source      solana.PublicKey
+	Mint        solana.PublicKey
+	Destination solana.PublicKey
+	Owner       solana.PublicKey
+	Amount      uint64
+}
+
+// ExtraAccountMetasAddress returns the validation account listing the extra accounts the
+// hook program needs for transfers of mint
+func ExtraAccountMetasAddress(hookProgram, mint solana.PublicKey) (solana.PublicKey, error) {
+	return txbuilder.TransferHookExtraAccountMetas(hookProgram, mint)
+}
+
+// ResolveTransferHookAccounts returns the accounts Token-2022 passes on to hookProgram for
+// transfer: the resolved extra accounts followed by the hook program and its validation
+// account. Swap programs that support hooked mints expect them among the swap's remaining
+// accounts. Seeds read from other accounts' data aren't supported.
+func ResolveTransferHookAccounts(ctx context.Context, solClient RPC, hookProgram solana.PublicKey, transfer HookTransfer) ([]*solana.AccountMeta, error) {
+	validation, err := ExtraAccountMetasAddress(hookProgram, transfer.Mint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive extra account metas: %w", err)
+	}
+	resp, err := solClient.GetAccountInfoWithOpts(ctx, validation, &rpc.GetAccountInfoOpts{Commitment: rpc.CommitmentConfirmed})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get extra account metas %s: %w", validation, err)
+	}
+	if resp == nil || resp.Value == nil {
+		return nil, fmt.Errorf("extra account metas %s not found", validation)
+	}
+	metas, err := resolveExtraAccountMetas(resp.Value.Data.GetBinary(), hookProgram, validation, transfer)
+	if err != nil {
+		return nil, fmt.Errorf("mint %s: %w", transfer.Mint, err)
+	}
+	return append(metas,
+		solana.NewAccountMeta(hookProgram, false, false),
+		solana.NewAccountMeta(validation, false, false),
+	), nil
+}
+
+// resolveExtraAccountMetas decodes the Execute entry of a validation account and resolves
+// each extra account against the transfer's accounts and the ones resolved before it
+func resolveExtraAccountMetas(data []byte, hookProgram, validation solana.PublicKey, transfer HookTransfer) ([]*solana.AccountMeta, error) {
+	raw, err := executeExtraAccountMetas(data)
+	if err != nil {
+		return nil, err
+	}
+	// Execute's accounts, which seeds refer to by index
+	accounts := []solana.PublicKey{transfer.Source, transfer.Mint, transfer.Destination, transfer.Owner, validation}
+	instructionData := binary.LittleEndian.AppendUint64(append([]byte(nil), executeDiscriminator...), transfer.Amount)
+
+	metas := make([]*solana.AccountMeta, 0, len(raw))
+	for i, meta := range raw {
+		discriminator, config := meta[0], meta[1:33]
+		isSigner, isWritable := meta[33] != 0, meta[34] != 0
+
+		var key solana.PublicKey
+		switch {
+		case discriminator == extraAccountMetaFixed:
+			key = solana.PublicKeyFromBytes(config)
+		case discriminator == extraAccountMetaHookPDA || discriminator >= extraAccountMetaExternal:
+			program := hookProgram
+			if discriminator >= extraAccountMetaExternal {
+				index := int(discriminator - extraAccountMetaExternal)
+				if index >= len(accounts) {
+					return nil, fmt.Errorf("extra account %d: program index %d out of range", i, index)
+				}
+				program = accounts[index]
+			}
+			seeds, err := unpackSeeds(config, accounts, instructionData)
+			if err != nil {
+				return nil, fmt.Errorf("extra account %d: %w", i, err)
+			}
+			if key, _, err = solana.FindProgramAddress(seeds, program); err != nil {
+				return nil, fmt.Errorf("extra account %d: %w", i, err)
+			}
+		default:
+			return nil, fmt.Errorf("extra account %d: unknown discriminator %d", i, discriminator)
+		}
+		accounts = append(accounts, key)
+		metas = append(metas, solana.NewAccountMeta(key, isWritable, isSigner))
+	}
+	return metas, nil
+}
+
+// executeExtraAccountMetas finds the Execute entry of a validation account's TLV data and
+// splits it into 35-byte extra account metas
+func executeExtraAccountMetas(data []byte) ([][]byte, error) {
+	for offset := 0; offset+12 <= len(data); {
+		discriminator := data[offset : offset+8]
+		length := int(binary.LittleEndian.Uint32(data[offset+8 : offset+12]))
+		offset += 12
+		if offset+length > len(data) {
+			return nil, fmt.Errorf("extra account metas entry overruns the account")
+		}
+		if string(discriminator) != string(executeDiscriminator) {
+			offset += length
+			continue
+		}
+		value := data[offset : offset+length]
+		if len(value) < 4 {
+			return nil, fmt.Errorf("extra account metas entry is %d bytes", len(value))
+		}
+		count := int(binary.LittleEndian.Uint32(value[0:4]))
+		if 4+count*extraAccountMetaSize > len(value) {
+			return nil, fmt.Errorf("%d extra account metas overrun the entry", count)
+		}
+		metas := make([][]byte, count)
+		for i := range count {
+			start := 4 + i*extraAccountMetaSize
+			metas[i] = value[start : start+extraAccountMetaSize]
+		}
+		return metas, nil
+	}
+	return nil, fmt.Errorf("no extra account metas for Execute")
+}
+
+// unpackSeeds decodes the PDA seeds packed into an extra account meta's address config
+func unpackSeeds(config []byte, accounts []solana.PublicKey, instructionData []byte) ([][]byte, error) {
+	var seeds [][]byte
+	for offset := 0; offset < len(config); {
+		kind := config[offset]
+		offset++
+		switch kind {
+		case seedEnd:
+			return seeds, nil
+		case seedLiteral:
+			if offset >= len(config) {
+				return nil, fmt.Errorf("truncated literal seed")
+			}
+			length := int(config[offset])
+			offset++
+			if offset+length > len(config) {
+				return nil, fmt.Errorf("truncated literal seed")
+			}
+			seeds = append(seeds, config[offset:offset+length])
+			offset += length
+		case seedInstructionData:
+			if offset+2 > len(config) {
+				return nil, fmt.Errorf("truncated instruction data seed")
+			}
+			index, length := int(config[offset]), int(config[offset+1])
+			offset += 2
+			if index+length > len(instructionData) {
+				return nil, fmt.Errorf("instruction data seed [%d:%d] out of range", index, index+length)
+			}
+			seeds = append(seeds, instructionData[index:index+length])
+		case seedAccountKey:
+			if offset >= len(config) {
+				return nil, fmt.Errorf("truncated account key seed")
+			}
+			index := int(config[offset])
+			offset++
+			if index >= len(accounts) {
+				return nil, fmt.Errorf("account key seed index %d out of range", index)
+			}
+			if accounts[index].IsZero() {
+				return nil, fmt.Errorf("account key seed refers to unknown account %d", index)
+			}
+			seeds = append(seeds, accounts[index].Bytes())
+		default:
+			return nil, fmt.Errorf("unsupported seed type %d", kind)
+		}
+	}
+	return seeds, nil
+}
@@ -0,0 +1,73 @@
+package sol
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// VerifyPoolVaults checks on-chain that the pool account is owned by the pool's program and that
+// every vault of the pool is a token account owned by the SPL Token or Token-2022 program, holds
+// the pool's mint and is controlled by the pool's authority. Call it before building
+// instructions to reject spoofed pool accounts or poisoned indexer data.
+// Pools that don't implement pkg.VaultPool are accepted unchecked.
+func VerifyPoolVaults(ctx context.Context, solClient RPC, pool pkg.Pool) error {
+	vaultPool, ok := pool.(pkg.VaultPool)
+	if !ok {
+		return nil
+	}
+	vaults := vaultPool.TokenVaults()
+	if len(vaults) == 0 {
+		return nil
+	}
+	poolAddress, err := solana.PublicKeyFromBase58(pool.GetID())
+	if err != nil {
+		return fmt.Errorf("invalid pool address %s: %w", pool.GetID(), err)
+	}
+	// The pool account is fetched with its vaults so both come from the same slot
+	addrs := make([]solana.PublicKey, 0, len(vaults)+1)
+	addrs = append(addrs, poolAddress)
+	for _, vault := range vaults {
+		addrs = append(addrs, vault.Address)
+	}
+	results, err := solClient.GetMultipleAccountsWithOpts(ctx, addrs, MultipleAccountsOpts(ctx, rpc.CommitmentProcessed))
+	if err != nil {
+		return fmt.Errorf("failed to get vault accounts: %w", err)
+	}
+	if len(results.Value) != len(addrs) {
+		return fmt.Errorf("expected %d accounts, got %d", len(addrs), len(results.Value))
+	}
+
+	poolAccount := results.Value[0]
+	if poolAccount == nil {
+		return fmt.Errorf("pool %s not found", pool.GetID())
+	}
+	if !poolAccount.Owner.Equals(pool.GetProgramID()) {
+		return fmt.Errorf("pool %s owned by %s, expected %s", pool.GetID(), poolAccount.Owner, pool.GetProgramID())
+	}
+	for i, vault := range vaults {
+		account := results.Value[i+1]
+		if account == nil {
+			return fmt.Errorf("pool %s: vault %s not found", pool.GetID(), vault.Address)
+		}
+		if !account.Owner.Equals(solana.TokenProgramID) && !account.Owner.Equals(solana.Token2022ProgramID) {
+			return fmt.Errorf("pool %s: vault %s owned by %s, not a token program", pool.GetID(), vault.Address, account.Owner)
+		}
+		data := account.Data.GetBinary()
+		if len(data) < int(TokenAccountSize) {
+			return fmt.Errorf("pool %s: vault %s is not a token account", pool.GetID(), vault.Address)
+		}
+		mint := solana.PublicKeyFromBytes(data[0:32])
+		if !mint.Equals(vault.Mint) {
+			return fmt.Errorf("pool %s: vault %s holds mint %s, expected %s", pool.GetID(), vault.Address, mint, vault.Mint)
+		}
+		authority := solana.PublicKeyFromBytes(data[32:64])
+		if !vault.Authority.IsZero() && !authority.Equals(vault.Authority) {
+			return fmt.Errorf("pool %s: vault %s controlled by %s, expected %s", pool.GetID(), vault.Address, authority, vault.Authority)
+		}
+	}
+	return nil
+}
@@ -2,7 +2,8 @@ package sol
 
 import (
 	"context"
-	"log"
+	"encoding/binary"
+	"fmt"
 
 	"github.com/gagliardetto/solana-go"
 	associatedtokenaccount "github.com/gagliardetto/solana-go/programs/associated-token-account"
@@ -25,8 +26,7 @@ func (t *Client) CoverWsol(ctx context.Context, privateKey solana.PrivateKey, am
 		},
 	)
 	if err != nil {
-		log.Printf("GetTokenAccountsByOwner err: %v", err)
-		return err
+		return fmt.Errorf("failed to get token accounts: %w", err)
 	}
 	if len(acc.Value) == 0 {
 		createAtaInst, err := associatedtokenaccount.NewCreateInstruction(
@@ -42,8 +42,7 @@ func (t *Client) CoverWsol(ctx context.Context, privateKey solana.PrivateKey, am
 
 	wsolAccount, _, err := solana.FindAssociatedTokenAddress(user, WSOL)
 	if err != nil {
-		log.Printf("FindAssociatedTokenAddress err: %v", err)
-		return err
+		return fmt.Errorf("failed to derive token account: %w", err)
 	}
 
 	transferInst, err := system.NewTransferInstruction(
@@ -52,8 +51,7 @@ func (t *Client) CoverWsol(ctx context.Context, privateKey solana.PrivateKey, am
 		wsolAccount,
 	).ValidateAndBuild()
 	if err != nil {
-		log.Printf("NewTransferInstruction err: %v", err)
-		return err
+		return fmt.Errorf("failed to build transfer: %w", err)
 	}
 	allInstrs = append(allInstrs, transferInst)
 
@@ -66,14 +64,8 @@ func (t *Client) CoverWsol(ctx context.Context, privateKey solana.PrivateKey, am
 	}
 	allInstrs = append(allInstrs, syncNativeInst)
 
-	recent, err := t.RpcClient.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	_, err = t.SendTx(ctx, solana.Hash{}, signers, allInstrs, false)
 	if err != nil {
-		log.Printf("GetLatestBlockhash err: %v\n", err)
-		return err
-	}
-	_, err = t.SendTx(ctx, recent.Value.Blockhash, signers, allInstrs, false)
-	if err != nil {
-		log.Printf("Failed to send transaction: %v\n", err)
 		return err
 	}
 	return nil
@@ -87,8 +79,7 @@ func (t *Client) CloseWsol(ctx context.Context, privateKey solana.PrivateKey) er
 
 	wsolAccount, _, err := solana.FindAssociatedTokenAddress(user, WSOL)
 	if err != nil {
-		log.Printf("FindAssociatedTokenAddress err: %v", err)
-		return err
+		return fmt.Errorf("failed to derive token account: %w", err)
 	}
 	closeInst, err := token.NewCloseAccountInstruction(
 		wsolAccount,
@@ -97,20 +88,171 @@ func (t *Client) CloseWsol(ctx context.Context, privateKey solana.PrivateKey) er
 		[]solana.PublicKey{},
 	).ValidateAndBuild()
 	if err != nil {
-		log.Printf("CloseAccountInstruction err: %v\n", err)
-		return err
+		return fmt.Errorf("failed to build close account: %w", err)
 	}
 	insts = append(insts, closeInst)
 
-	recent, err := t.RpcClient.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	_, err = t.SendTx(ctx, solana.Hash{}, signers, insts, false)
 	if err != nil {
-		log.Printf("GetLatestBlockhash err: %v\n", err)
 		return err
 	}
-	_, err = t.SendTx(ctx, recent.Value.Blockhash, signers, insts, false)
+	return nil
+}
+
+// WsolOptions controls how WrapWsolSwap funds and settles a swap spending or receiving WSOL
+type WsolOptions struct {
+	// Unwind closes the WSOL account after the swap, returning whatever WSOL is left and the
+	// account rent to the user as native SOL
+	Unwind bool
+	// OutputMint is the swap's output token. When it is WSOL, the WSOL account is created if
+	// missing so the swap can pay into it, and UnwrapOutput decides whether it is closed after.
+	OutputMint string
+	// UnwrapOutput overrides Client.UnwrapWsolOutput for a WSOL output
+	UnwrapOutput UnwrapMode
+}
+
+// UnwrapMode chooses whether WSOL received from a swap is unwrapped to native SOL
+type UnwrapMode int
+
+const (
+	// UnwrapDefault follows Client.UnwrapWsolOutput
+	UnwrapDefault UnwrapMode = iota
+	// UnwrapAlways closes the WSOL account after the swap
+	UnwrapAlways
+	// UnwrapNever leaves the output in the WSOL account
+	UnwrapNever
+)
+
+// WsolTopUpInstructions returns the instructions that bring user's WSOL account up to amount
+// from native SOL: creating the account when missing, transferring only the shortfall against
+// its current balance, and syncing it. Nothing is returned when the balance already covers amount.
+func (t *Client) WsolTopUpInstructions(ctx context.Context, user solana.PublicKey, amount uint64) ([]solana.Instruction, error) {
+	wsolAccount, _, err := solana.FindAssociatedTokenAddress(user, WSOL)
 	if err != nil {
-		log.Printf("Failed to send transaction: %v\n", err)
-		return err
+		return nil, fmt.Errorf("failed to find WSOL account: %w", err)
 	}
-	return nil
+	results, err := t.RpcClient.GetMultipleAccountsWithOpts(ctx, []solana.PublicKey{user, wsolAccount}, MultipleAccountsOpts(ctx, rpc.CommitmentConfirmed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get WSOL account: %w", err)
+	}
+	if len(results.Value) != 2 {
+		return nil, fmt.Errorf("expected 2 accounts, got %d", len(results.Value))
+	}
+	userAccount, wsolState := results.Value[0], results.Value[1]
+
+	var wsolBalance uint64
+	if wsolState != nil {
+		data := wsolState.Data.GetBinary()
+		if len(data) < 72 {
+			return nil, fmt.Errorf("invalid WSOL account data length %d", len(data))
+		}
+		wsolBalance = binary.LittleEndian.Uint64(data[64:72])
+	}
+	if wsolBalance >= amount {
+		return nil, nil
+	}
+	shortfall := amount - wsolBalance
+
+	instructions := make([]solana.Instruction, 0, 3)
+	required := shortfall
+	if wsolState == nil {
+		rent, err := t.RpcClient.GetMinimumBalanceForRentExemption(ctx, TokenAccountSize, rpc.CommitmentConfirmed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token account rent: %w", err)
+		}
+		required += rent
+		createInst, err := associatedtokenaccount.NewCreateInstruction(user, user, WSOL).ValidateAndBuild()
+		if err != nil {
+			return nil, err
+		}
+		instructions = append(instructions, createInst)
+	}
+	var lamports uint64
+	if userAccount != nil {
+		lamports = userAccount.Lamports
+	}
+	if lamports < required {
+		return nil, fmt.Errorf("insufficient SOL to wrap: need %d lamports, have %d", required, lamports)
+	}
+
+	transferInst, err := system.NewTransferInstruction(shortfall, user, wsolAccount).ValidateAndBuild()
+	if err != nil {
+		return nil, err
+	}
+	syncNativeInst, err := token.NewSyncNativeInstruction(wsolAccount).ValidateAndBuild()
+	if err != nil {
+		return nil, err
+	}
+	return append(instructions, transferInst, syncNativeInst), nil
+}
+
+// WsolUnwindInstruction closes user's WSOL account, unwrapping its balance to native SOL
+func WsolUnwindInstruction(user solana.PublicKey) (solana.Instruction, error) {
+	wsolAccount, _, err := solana.FindAssociatedTokenAddress(user, WSOL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find WSOL account: %w", err)
+	}
+	return token.NewCloseAccountInstruction(wsolAccount, user, user, []solana.PublicKey{}).ValidateAndBuild()
+}
+
+// WrapWsolSwap surrounds swap instructions spending amountIn of inputMint with the WSOL
+// top-up they need, and the unwind when opts.Unwind is set, so funding, swap and cleanup go
+// out in one transaction. When opts.OutputMint is WSOL the output account is prepared and,
+// per opts.UnwrapOutput, closed so the user receives native SOL. Swaps of other mints are
+// returned unchanged.
+func (t *Client) WrapWsolSwap(ctx context.Context, user solana.PublicKey, inputMint string, amountIn uint64, swapInsts []solana.Instruction, opts WsolOptions) ([]solana.Instruction, error) {
+	switch {
+	case inputMint == WSOL.String():
+		topUp, err := t.WsolTopUpInstructions(ctx, user, amountIn)
+		if err != nil {
+			return nil, err
+		}
+		instructions := append(topUp, swapInsts...)
+		if opts.Unwind {
+			closeInst, err := WsolUnwindInstruction(user)
+			if err != nil {
+				return nil, err
+			}
+			instructions = append(instructions, closeInst)
+		}
+		return instructions, nil
+	case opts.OutputMint == WSOL.String():
+		return t.wrapWsolOutput(ctx, user, swapInsts, opts.UnwrapOutput)
+	default:
+		return swapInsts, nil
+	}
+}
+
+// wrapWsolOutput creates user's WSOL account ahead of a swap paying into it when it is
+// missing, and unwraps it after the swap unless mode says otherwise
+func (t *Client) wrapWsolOutput(ctx context.Context, user solana.PublicKey, swapInsts []solana.Instruction, mode UnwrapMode) ([]solana.Instruction, error) {
+	wsolAccount, _, err := solana.FindAssociatedTokenAddress(user, WSOL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find WSOL account: %w", err)
+	}
+	results, err := t.RpcClient.GetMultipleAccountsWithOpts(ctx, []solana.PublicKey{wsolAccount}, MultipleAccountsOpts(ctx, rpc.CommitmentConfirmed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get WSOL account: %w", err)
+	}
+	if len(results.Value) != 1 {
+		return nil, fmt.Errorf("expected 1 account, got %d", len(results.Value))
+	}
+
+	instructions := make([]solana.Instruction, 0, len(swapInsts)+2)
+	if results.Value[0] == nil {
+		createInst, err := associatedtokenaccount.NewCreateInstruction(user, user, WSOL).ValidateAndBuild()
+		if err != nil {
+			return nil, err
+		}
+		instructions = append(instructions, createInst)
+	}
+	instructions = append(instructions, swapInsts...)
+	if mode == UnwrapAlways || (mode == UnwrapDefault && t.UnwrapWsolOutput) {
+		closeInst, err := WsolUnwindInstruction(user)
+		if err != nil {
+			return nil, err
+		}
+		instructions = append(instructions, closeInst)
+	}
+	return instructions, nil
 }
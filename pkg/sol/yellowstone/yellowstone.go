@@ -0,0 +1,172 @@
+// Package yellowstone streams accounts from a Yellowstone gRPC (geyser) endpoint as a
+// sol.AccountStream and sol.AccountWatcher, for pool caches and vault watchers that need
+// updates faster than the RPC node's WebSocket pushes them. It is a module of its own so the
+// SDK doesn't depend on grpc.
+//
+//	client, err := yellowstone.Dial("grpc.example.com:443", os.Getenv("GEYSER_TOKEN"))
+//	if err != nil {
+//		return err
+//	}
+//	defer client.Close()
+//	err = cache.Stream(ctx, client)
+package yellowstone
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	pb "github.com/rpcpool/yellowstone-grpc/examples/golang/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+var (
+	_ sol.AccountStream  = (*Client)(nil)
+	_ sol.AccountWatcher = (*Client)(nil)
+)
+
+// tokenHeader carries the endpoint's access token
+const tokenHeader = "x-token"
+
+// Client subscribes to account updates over a geyser connection. Each Subscribe and
+// SubscribeAccounts call opens a stream of its own. It is safe for concurrent use.
+type Client struct {
+	geyser     pb.GeyserClient
+	conn       *grpc.ClientConn
+	token      string
+	commitment pb.CommitmentLevel
+}
+
+// Dial connects to a geyser endpoint over TLS, authenticating with token when it isn't
+// empty. opts are applied after the TLS credentials, so grpc.WithTransportCredentials with
+// insecure.NewCredentials() reaches a plaintext endpoint.
+func Dial(target, token string, opts ...grpc.DialOption) (*Client, error) {
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(nil))}, opts...)
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", target, err)
+	}
+	client := NewClient(conn, token)
+	client.conn = conn
+	return client, nil
+}
+
+// NewClient streams through an existing connection, which Close leaves open
+func NewClient(conn grpc.ClientConnInterface, token string) *Client {
+	return &Client{geyser: pb.NewGeyserClient(conn), token: token, commitment: pb.CommitmentLevel_CONFIRMED}
+}
+
+// SetCommitment sets the commitment updates are pushed at, confirmed by default
+func (c *Client) SetCommitment(commitment rpc.CommitmentType) error {
+	switch commitment {
+	case rpc.CommitmentProcessed:
+		c.commitment = pb.CommitmentLevel_PROCESSED
+	case rpc.CommitmentConfirmed:
+		c.commitment = pb.CommitmentLevel_CONFIRMED
+	case rpc.CommitmentFinalized:
+		c.commitment = pb.CommitmentLevel_FINALIZED
+	default:
+		return fmt.Errorf("unsupported commitment %q", commitment)
+	}
+	return nil
+}
+
+// Close closes the connection opened by Dial
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// Subscribe streams updates to every account owned by programs
+func (c *Client) Subscribe(ctx context.Context, programs []solana.PublicKey) (<-chan sol.AccountUpdate, error) {
+	return c.subscribe(ctx, &pb.SubscribeRequestFilterAccounts{Owner: base58(programs)})
+}
+
+// SubscribeAccounts streams updates to accounts
+func (c *Client) SubscribeAccounts(ctx context.Context, accounts []solana.PublicKey) (<-chan sol.AccountUpdate, error) {
+	return c.subscribe(ctx, &pb.SubscribeRequestFilterAccounts{Account: base58(accounts)})
+}
+
+// subscribe opens a stream with filter and forwards its account updates until ctx is done or
+// the stream fails. Pings from the endpoint are answered to keep idle streams open.
+func (c *Client) subscribe(ctx context.Context, filter *pb.SubscribeRequestFilterAccounts) (<-chan sol.AccountUpdate, error) {
+	if len(filter.Owner) == 0 && len(filter.Account) == 0 {
+		return nil, fmt.Errorf("no accounts to subscribe to")
+	}
+	if c.token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, tokenHeader, c.token)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := c.geyser.Subscribe(ctx)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open geyser stream: %w", err)
+	}
+	commitment := c.commitment
+	if err := stream.Send(&pb.SubscribeRequest{
+		Accounts:   map[string]*pb.SubscribeRequestFilterAccounts{"solroute": filter},
+		Commitment: &commitment,
+	}); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	updates := make(chan sol.AccountUpdate, 256)
+	go func() {
+		defer close(updates)
+		defer cancel()
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			switch update := msg.GetUpdateOneof().(type) {
+			case *pb.SubscribeUpdate_Ping:
+				if err := stream.Send(&pb.SubscribeRequest{Ping: &pb.SubscribeRequestPing{Id: 1}}); err != nil && err != io.EOF {
+					return
+				}
+			case *pb.SubscribeUpdate_Account:
+				accountUpdate, ok := newAccountUpdate(update.Account)
+				if !ok {
+					continue
+				}
+				select {
+				case updates <- accountUpdate:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return updates, nil
+}
+
+// newAccountUpdate converts an account update, reporting false for malformed ones
+func newAccountUpdate(update *pb.SubscribeUpdateAccount) (sol.AccountUpdate, bool) {
+	info := update.GetAccount()
+	if len(info.GetPubkey()) != solana.PublicKeyLength || len(info.GetOwner()) != solana.PublicKeyLength {
+		return sol.AccountUpdate{}, false
+	}
+	return sol.AccountUpdate{
+		Pubkey:   solana.PublicKeyFromBytes(info.Pubkey),
+		Owner:    solana.PublicKeyFromBytes(info.Owner),
+		Slot:     update.GetSlot(),
+		Lamports: info.GetLamports(),
+		Data:     info.GetData(),
+	}, true
+}
+
+func base58(keys []solana.PublicKey) []string {
+	encoded := make([]string, len(keys))
+	for i, key := range keys {
+		encoded[i] = key.String()
+	}
+	return encoded
+}
@@ -0,0 +1,103 @@
+package yellowstone
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	pb "github.com/rpcpool/yellowstone-grpc/examples/golang/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// geyser answers a subscription with a ping and one update per account filter entry
+type geyser struct {
+	pb.UnimplementedGeyserServer
+	requests chan *pb.SubscribeRequest
+	tokens   chan []string
+}
+
+func (g *geyser) Subscribe(stream pb.Geyser_SubscribeServer) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	g.tokens <- md.Get(tokenHeader)
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	g.requests <- req
+	if err := stream.Send(&pb.SubscribeUpdate{UpdateOneof: &pb.SubscribeUpdate_Ping{Ping: &pb.SubscribeUpdatePing{}}}); err != nil {
+		return err
+	}
+	pong, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	g.requests <- pong
+	owner := solana.NewWallet().PublicKey()
+	for _, filter := range req.Accounts {
+		for _, account := range append(filter.Account, filter.Owner...) {
+			pubkey := solana.MustPublicKeyFromBase58(account)
+			if err := stream.Send(&pb.SubscribeUpdate{UpdateOneof: &pb.SubscribeUpdate_Account{Account: &pb.SubscribeUpdateAccount{
+				Account: &pb.SubscribeUpdateAccountInfo{Pubkey: pubkey[:], Owner: owner[:], Lamports: 7, Data: []byte{1, 2}},
+				Slot:    42,
+			}}}); err != nil {
+				return err
+			}
+		}
+	}
+	<-stream.Context().Done()
+	return nil
+}
+
+func TestSubscribeAccounts(t *testing.T) {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	g := &geyser{requests: make(chan *pb.SubscribeRequest, 2), tokens: make(chan []string, 1)}
+	pb.RegisterGeyserServer(server, g)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	client, err := Dial("passthrough:///bufnet", "secret",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+	require.NoError(t, client.SetCommitment("processed"))
+	assert.Error(t, client.SetCommitment("recent"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = client.SubscribeAccounts(ctx, nil)
+	assert.ErrorContains(t, err, "no accounts")
+
+	vault := solana.NewWallet().PublicKey()
+	updates, err := client.SubscribeAccounts(ctx, []solana.PublicKey{vault})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"secret"}, <-g.tokens)
+
+	req := <-g.requests
+	require.Len(t, req.Accounts, 1)
+	for _, filter := range req.Accounts {
+		assert.Equal(t, []string{vault.String()}, filter.Account)
+		assert.Empty(t, filter.Owner)
+	}
+	assert.Equal(t, pb.CommitmentLevel_PROCESSED, req.GetCommitment())
+	assert.NotNil(t, (<-g.requests).GetPing(), "pings are answered")
+
+	update := <-updates
+	assert.Equal(t, vault, update.Pubkey)
+	assert.Equal(t, uint64(42), update.Slot)
+	assert.Equal(t, uint64(7), update.Lamports)
+	assert.Equal(t, []byte{1, 2}, update.Data)
+
+	cancel()
+	for range updates {
+	}
+}
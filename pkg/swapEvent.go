@@ -0,0 +1,58 @@
+package pkg
+
+import (
+	"math"
+
+	cosmath "cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+)
+
+// ProgramEvent is data a program emitted while executing one of a transaction's instructions
+type ProgramEvent struct {
+	Program solana.PublicKey
+	// Data is the event as emitted: the payload of a "Program data:" log, an Anchor event
+	// emitted through a self-invocation less its event tag, or the payload of a Raydium AMM v4
+	// "ray_log". Anchor events start with their 8-byte discriminator.
+	Data []byte
+	// Accounts are the accounts of the instruction that emitted the event
+	Accounts []solana.PublicKey
+}
+
+// SwapEvent is a swap as the pool's program reported it
+type SwapEvent struct {
+	Protocol ProtocolName
+	Pool     solana.PublicKey
+	// User is the trader, zero when neither the event nor its instruction names one
+	User solana.PublicKey
+	// ZeroForOne is set for swaps selling the pool's first token (token 0, coin, A, X or
+	// base) for its second
+	ZeroForOne bool
+	AmountIn   uint64
+	AmountOut  uint64
+	// Fee is the LP and protocol fee the swap paid, zero when the event doesn't report it
+	Fee uint64
+	// SqrtPriceX64 is the pool's square root price after the swap for concentrated liquidity
+	// pools that report it, nil otherwise
+	SqrtPriceX64 cosmath.Int
+
+	// Signature and Slot locate the transaction the event was decoded from
+	Signature solana.Signature
+	Slot      uint64
+}
+
+// SwapEventDecoder decodes the swap events of one program, reporting false for its events
+// that aren't swaps
+type SwapEventDecoder func(event ProgramEvent) (SwapEvent, bool, error)
+
+// Price returns the swap's price in the pool's second token per first token, given the
+// decimals of each
+func (e SwapEvent) Price(decimals0, decimals1 uint8) float64 {
+	if e.AmountIn == 0 || e.AmountOut == 0 {
+		return 0
+	}
+	amount0, amount1 := float64(e.AmountIn), float64(e.AmountOut)
+	if !e.ZeroForOne {
+		amount0, amount1 = amount1, amount0
+	}
+	return amount1 / amount0 * math.Pow10(int(decimals0)-int(decimals1))
+}
@@ -0,0 +1,120 @@
+package pkg
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+)
+
+// SwapRequest describes a swap once, for the router to quote and the client to build, instead
+// of passing its parts positionally. Start from NewSwapRequest and set the rest with the With
+// methods, which return a modified copy.
+type SwapRequest struct {
+	InputMint  string
+	OutputMint string
+	// Amount is the exact input
+	Amount   math.Int
+	Slippage SlippageConfig
+	// Deadline, when set, is the time after which the swap must not be sent
+	Deadline time.Time
+	// FeePayer pays the transaction fees and owns the swapped token accounts
+	FeePayer solana.PublicKey
+	// PriorityFee is the compute unit price in micro-lamports, zero for none
+	PriorityFee uint64
+	// Clock reads the time for WithTimeout and the deadline check; nil is the wall clock.
+	// The router and client set theirs on requests that have none.
+	Clock clock.Clock
+}
+
+// NewSwapRequest returns a request to swap amount of inputMint for outputMint with no slippage
+// tolerance, deadline or priority fee
+func NewSwapRequest(inputMint, outputMint string, amount math.Int) SwapRequest {
+	return SwapRequest{InputMint: inputMint, OutputMint: outputMint, Amount: amount}
+}
+
+func (r SwapRequest) WithSlippage(slippage SlippageConfig) SwapRequest {
+	r.Slippage = slippage
+	return r
+}
+
+// WithSlippageBps sets a fixed tolerance of bps below the expected output
+func (r SwapRequest) WithSlippageBps(bps uint64) SwapRequest {
+	return r.WithSlippage(SlippageBps(bps))
+}
+
+func (r SwapRequest) WithDeadline(deadline time.Time) SwapRequest {
+	r.Deadline = deadline
+	return r
+}
+
+// WithTimeout sets the deadline d from the request clock's now
+func (r SwapRequest) WithTimeout(d time.Duration) SwapRequest {
+	return r.WithDeadline(r.now().Add(d))
+}
+
+func (r SwapRequest) WithClock(clk clock.Clock) SwapRequest {
+	r.Clock = clk
+	return r
+}
+
+func (r SwapRequest) now() time.Time {
+	if r.Clock == nil {
+		return clock.System{}.Now()
+	}
+	return r.Clock.Now()
+}
+
+func (r SwapRequest) WithFeePayer(feePayer solana.PublicKey) SwapRequest {
+	r.FeePayer = feePayer
+	return r
+}
+
+func (r SwapRequest) WithPriorityFee(microLamports uint64) SwapRequest {
+	r.PriorityFee = microLamports
+	return r
+}
+
+// Validate checks the request can be quoted: both mints are valid and differ, the amount is
+// positive, the slippage policy is valid and the deadline hasn't passed by its clock. The fee
+// payer is only needed to build the swap and is checked there.
+func (r SwapRequest) Validate() error {
+	for _, mint := range []string{r.InputMint, r.OutputMint} {
+		if _, err := solana.PublicKeyFromBase58(mint); err != nil {
+			return fmt.Errorf("invalid mint %q: %w", mint, err)
+		}
+	}
+	if r.InputMint == r.OutputMint {
+		return fmt.Errorf("input and output mints are both %s", r.InputMint)
+	}
+	if r.Amount.IsNil() || !r.Amount.IsPositive() {
+		return fmt.Errorf("swap amount must be positive")
+	}
+	if err := r.Slippage.Validate(); err != nil {
+		return err
+	}
+	if r.Expired(r.now()) {
+		return fmt.Errorf("swap deadline %s has passed", r.Deadline.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Expired reports whether the request has a deadline that is past at now
+func (r SwapRequest) Expired(now time.Time) bool {
+	return !r.Deadline.IsZero() && now.After(r.Deadline)
+}
+
+// BuildRequest returns the v2 build request for the swap through one pool, with minOut as its
+// limit
+func (r SwapRequest) BuildRequest(solClient RPC, minOut math.Int) SwapBuildRequest {
+	return SwapBuildRequest{
+		Client:     solClient,
+		User:       r.FeePayer,
+		InputMint:  r.InputMint,
+		OutputMint: r.OutputMint,
+		Amount:     r.Amount,
+		Limit:      minOut,
+	}
+}
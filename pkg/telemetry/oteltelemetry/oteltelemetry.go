@@ -0,0 +1,195 @@
+// Package oteltelemetry records the SDK's spans and metrics with OpenTelemetry. It is a module
+// of its own so the SDK doesn't depend on OpenTelemetry.
+//
+//	tel, err := oteltelemetry.New(otel.Tracer("solroute"), otel.Meter("solroute"))
+//	if err != nil {
+//		return err
+//	}
+//	router.SetTelemetry(tel)
+//	client.SetTelemetry(tel)
+package oteltelemetry
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	_ telemetry.Tracer = Tracer{}
+	_ telemetry.Span   = span{}
+	_ telemetry.Meter  = (*Meter)(nil)
+)
+
+// New returns a Telemetry tracing with tracer and measuring with meter. Either may be nil to
+// leave that signal off.
+func New(tracer trace.Tracer, meter metric.Meter) (*telemetry.Telemetry, error) {
+	tel := &telemetry.Telemetry{}
+	if tracer != nil {
+		tel.Tracer = Tracer{Tracer: tracer}
+	}
+	if meter != nil {
+		m, err := NewMeter(meter)
+		if err != nil {
+			return nil, err
+		}
+		tel.Meter = m
+	}
+	return tel, nil
+}
+
+// Attribute converts an Attr to an OpenTelemetry attribute. Values without an attribute type
+// of their own are recorded as strings.
+func Attribute(attr telemetry.Attr) attribute.KeyValue {
+	switch v := attr.Value.(type) {
+	case string:
+		return attribute.String(attr.Key, v)
+	case int:
+		return attribute.Int(attr.Key, v)
+	case int64:
+		return attribute.Int64(attr.Key, v)
+	case uint64:
+		if v > math.MaxInt64 {
+			return attribute.String(attr.Key, fmt.Sprint(v))
+		}
+		return attribute.Int64(attr.Key, int64(v))
+	case float64:
+		return attribute.Float64(attr.Key, v)
+	case bool:
+		return attribute.Bool(attr.Key, v)
+	case fmt.Stringer:
+		return attribute.String(attr.Key, v.String())
+	default:
+		return attribute.String(attr.Key, fmt.Sprint(v))
+	}
+}
+
+// Attributes converts attrs in order
+func Attributes(attrs []telemetry.Attr) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, len(attrs))
+	for i, attr := range attrs {
+		kvs[i] = Attribute(attr)
+	}
+	return kvs
+}
+
+// Tracer starts OpenTelemetry spans
+type Tracer struct {
+	Tracer trace.Tracer
+}
+
+func (t Tracer) Start(ctx context.Context, name string, attrs ...telemetry.Attr) (context.Context, telemetry.Span) {
+	ctx, s := t.Tracer.Start(ctx, name, trace.WithAttributes(Attributes(attrs)...))
+	return ctx, span{span: s}
+}
+
+// span records errors as events and marks the span as failed
+type span struct {
+	span trace.Span
+}
+
+func (s span) SetAttributes(attrs ...telemetry.Attr) {
+	s.span.SetAttributes(Attributes(attrs)...)
+}
+
+func (s span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s span) End() {
+	s.span.End()
+}
+
+// instrument describes the OpenTelemetry instrument of a metric
+type instrument struct {
+	unit        string
+	description string
+}
+
+// counters and histograms are the SDK's metrics, created up front with their units
+var (
+	counters = map[string]instrument{
+		telemetry.MetricQuotes:          {"{quote}", "Pool quotes by protocol and outcome"},
+		telemetry.MetricDiscoveredPools: {"{pool}", "Pools found by discovery, by protocol"},
+		telemetry.MetricSendFailures:    {"{transaction}", "Transactions that failed to send or simulate, by stage"},
+	}
+	histograms = map[string]instrument{
+		telemetry.MetricRPCLatency:    {"ms", "Latency of RPC requests by method and outcome"},
+		telemetry.MetricRouteDuration: {"ms", "Time taken to pick a pool, by outcome"},
+	}
+)
+
+// Meter records the SDK's counters and histograms with OpenTelemetry instruments. Metrics
+// without a known instrument get one, without a unit, the first time they are recorded.
+type Meter struct {
+	meter metric.Meter
+
+	mu         sync.Mutex
+	counters   map[string]metric.Int64Counter
+	histograms map[string]metric.Float64Histogram
+}
+
+// NewMeter creates the instruments of the SDK's metrics on meter
+func NewMeter(meter metric.Meter) (*Meter, error) {
+	m := &Meter{
+		meter:      meter,
+		counters:   make(map[string]metric.Int64Counter),
+		histograms: make(map[string]metric.Float64Histogram),
+	}
+	for name, inst := range counters {
+		counter, err := meter.Int64Counter(name, metric.WithUnit(inst.unit), metric.WithDescription(inst.description))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create counter %s: %w", name, err)
+		}
+		m.counters[name] = counter
+	}
+	for name, inst := range histograms {
+		histogram, err := meter.Float64Histogram(name, metric.WithUnit(inst.unit), metric.WithDescription(inst.description))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create histogram %s: %w", name, err)
+		}
+		m.histograms[name] = histogram
+	}
+	return m, nil
+}
+
+func (m *Meter) Add(ctx context.Context, name string, n int64, attrs ...telemetry.Attr) {
+	m.mu.Lock()
+	counter, ok := m.counters[name]
+	if !ok {
+		var err error
+		if counter, err = m.meter.Int64Counter(name); err != nil {
+			m.mu.Unlock()
+			otel.Handle(err)
+			return
+		}
+		m.counters[name] = counter
+	}
+	m.mu.Unlock()
+	counter.Add(ctx, n, metric.WithAttributes(Attributes(attrs)...))
+}
+
+func (m *Meter) Record(ctx context.Context, name string, value float64, attrs ...telemetry.Attr) {
+	m.mu.Lock()
+	histogram, ok := m.histograms[name]
+	if !ok {
+		var err error
+		if histogram, err = m.meter.Float64Histogram(name); err != nil {
+			m.mu.Unlock()
+			otel.Handle(err)
+			return
+		}
+		m.histograms[name] = histogram
+	}
+	m.mu.Unlock()
+	histogram.Record(ctx, value, metric.WithAttributes(Attributes(attrs)...))
+}
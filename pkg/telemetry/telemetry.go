@@ -0,0 +1,145 @@
+// Package telemetry traces and measures discovery, quoting, instruction building and sending,
+// so services built on the SDK can be monitored in production. It doesn't depend on
+// OpenTelemetry; the oteltelemetry module adapts an OTel tracer and meter to Tracer and
+// Meter, mapping Attr to attribute.KeyValue and each metric name to an instrument.
+// Nothing is recorded until a Telemetry is set on the router or client.
+package telemetry
+
+import (
+	"context"
+	"time"
+)
+
+// Span names
+const (
+	SpanDiscover        = "solroute.discover"         // SimpleRouter.DiscoverPools
+	SpanRoute           = "solroute.route"            // quoting every pool of a pair to pick one
+	SpanBuildSwap       = "solroute.build_swap"       // Client.BuildSwapInstructions and ExactOut
+	SpanExecute         = "solroute.execute"          // Client.ExecuteSwap, every attempt included
+	SpanSend            = "solroute.send"             // sending or simulating a signed transaction
+	SpanExecutionReport = "solroute.execution_report" // reading back a landed swap
+)
+
+// Metric names. Counters are added to with Meter.Add, histograms recorded with Meter.Record.
+const (
+	// MetricQuotes counts pool quotes by protocol and outcome
+	MetricQuotes = "solroute.quotes"
+	// MetricRPCLatency is the latency of each RPC request in milliseconds, by method and outcome
+	MetricRPCLatency = "solroute.rpc.latency"
+	// MetricRouteDuration is how long picking a pool took in milliseconds, by outcome
+	MetricRouteDuration = "solroute.route.duration"
+	// MetricDiscoveredPools counts the pools discovery found, by protocol
+	MetricDiscoveredPools = "solroute.discovery.pools"
+	// MetricSendFailures counts transactions that failed to send or simulate, by stage
+	MetricSendFailures = "solroute.send.failures"
+)
+
+// Attribute keys besides the pkg.LogKey ones, which spans and metrics share with log lines
+const (
+	AttrMethod  = "method"
+	AttrOutcome = "outcome"
+	AttrStage   = "stage"
+)
+
+// Outcomes
+const (
+	OutcomeOK    = "ok"
+	OutcomeError = "error"
+)
+
+// Attr is a span or metric attribute
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute
+func String(key, value string) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+// Int returns an integer attribute
+func Int(key string, value int) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+// Outcome returns the outcome attribute of err
+func Outcome(err error) Attr {
+	if err != nil {
+		return String(AttrOutcome, OutcomeError)
+	}
+	return String(AttrOutcome, OutcomeOK)
+}
+
+// Tracer starts spans, as OpenTelemetry's trace.Tracer does
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attr) (context.Context, Span)
+}
+
+// Span is a traced operation, ended once
+type Span interface {
+	SetAttributes(attrs ...Attr)
+	RecordError(err error)
+	End()
+}
+
+// Meter records counters and histograms by name
+type Meter interface {
+	Add(ctx context.Context, name string, n int64, attrs ...Attr)
+	Record(ctx context.Context, name string, value float64, attrs ...Attr)
+}
+
+// Telemetry is where spans and metrics go. Either field may be nil to leave that signal off,
+// and a nil *Telemetry records nothing.
+type Telemetry struct {
+	Tracer Tracer
+	Meter  Meter
+}
+
+// Start starts a span, or returns ctx and a span that does nothing when tracing is off
+func (t *Telemetry) Start(ctx context.Context, name string, attrs ...Attr) (context.Context, Span) {
+	if t == nil || t.Tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return t.Tracer.Start(ctx, name, attrs...)
+}
+
+// Add adds n to a counter
+func (t *Telemetry) Add(ctx context.Context, name string, n int64, attrs ...Attr) {
+	if t == nil || t.Meter == nil {
+		return
+	}
+	t.Meter.Add(ctx, name, n, attrs...)
+}
+
+// Record records a histogram value
+func (t *Telemetry) Record(ctx context.Context, name string, value float64, attrs ...Attr) {
+	if t == nil || t.Meter == nil {
+		return
+	}
+	t.Meter.Record(ctx, name, value, attrs...)
+}
+
+// RecordDuration records d in milliseconds
+func (t *Telemetry) RecordDuration(ctx context.Context, name string, d time.Duration, attrs ...Attr) {
+	t.Record(ctx, name, float64(d)/float64(time.Millisecond), attrs...)
+}
+
+// Metering reports whether metrics are recorded, so callers can skip timing otherwise
+func (t *Telemetry) Metering() bool {
+	return t != nil && t.Meter != nil
+}
+
+// End records err on span, if any, and ends it
+func End(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attr) {}
+func (noopSpan) RecordError(error)     {}
+func (noopSpan) End()                  {}
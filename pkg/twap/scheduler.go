@@ -0,0 +1,261 @@
+// Package twap executes large orders as a series of smaller swaps spread over time,
+// re-quoting before each slice, holding slices back while the price is worse than a limit,
+// and stopping when the fills slip too far from the arrival price or keep failing.
+package twap
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+)
+
+// DefaultMaxFailures is how many slices in a row may fail before an order is abandoned
+const DefaultMaxFailures = 3
+
+// StopReason says why an order stopped executing
+type StopReason string
+
+const (
+	StopCompleted StopReason = "completed"
+	StopSlippage  StopReason = "slippage"
+	StopFailures  StopReason = "failures"
+	StopDeadline  StopReason = "deadline"
+	StopCanceled  StopReason = "canceled"
+)
+
+// Quoter quotes a slice before it executes; *router.SimpleRouter implements it
+type Quoter interface {
+	QuoteRoute(ctx context.Context, solClient pkg.RPC, tokenIn, tokenOut string, amountIn math.Int) (router.RouteQuote, error)
+}
+
+// Fill is what a slice's swap did
+type Fill struct {
+	Signature solana.Signature
+	AmountIn  math.Int
+	AmountOut math.Int
+}
+
+// Executor executes a slice as quoted
+type Executor func(ctx context.Context, quote router.RouteQuote) (Fill, error)
+
+// SwapExecutor executes slices with client.ExecuteSwap through the quote's pool. The fill's
+// output is the simulated output of the swap that was sent.
+func SwapExecutor(client *sol.Client, signers []sol.Signer, opts sol.ExecuteOptions) Executor {
+	return func(ctx context.Context, quote router.RouteQuote) (Fill, error) {
+		if len(quote.Pools) != 1 {
+			return Fill{}, fmt.Errorf("slice route has %d pools, expected 1", len(quote.Pools))
+		}
+		result, err := client.ExecuteSwap(ctx, quote.Pools[0], signers, quote.InputMint, quote.AmountIn, opts)
+		if err != nil {
+			return Fill{}, err
+		}
+		return Fill{Signature: result.Signature, AmountIn: quote.AmountIn, AmountOut: result.ExpectedOut}, nil
+	}
+}
+
+// Order is a swap to execute in slices
+type Order struct {
+	InputMint  string
+	OutputMint string
+	AmountIn   math.Int
+	// Slices is how many equal parts AmountIn is split into, the last taking the remainder
+	Slices int
+	// Interval is the time between slices
+	Interval time.Duration
+	// LimitPrice, when set, holds a slice back to the next interval while its quote pays
+	// less output per input, in raw units. It requires MaxDuration.
+	LimitPrice float64
+	// MaxSlippageBps stops the order when a slice's quote, or the average fill, is more than
+	// this below the arrival price, the price of the first slice's quote. Zero disables it.
+	MaxSlippageBps float64
+	// MaxDuration stops the order once it has run this long. Zero runs until every slice is
+	// filled.
+	MaxDuration time.Duration
+	// MaxFailures is how many slices in a row may fail to quote or execute before the order
+	// stops, DefaultMaxFailures when zero. Failed slices are retried at the next interval.
+	MaxFailures int
+}
+
+// SliceResult records one interval of an order
+type SliceResult struct {
+	At       time.Time
+	AmountIn math.Int
+	// Quote is the slice's quote, zero when quoting failed
+	Quote router.RouteQuote
+	// Fill is zero unless the slice executed
+	Fill Fill
+	// Deferred is set for slices held back by the limit price
+	Deferred bool
+	Err      error
+}
+
+// Report is the progress of an order
+type Report struct {
+	Order     Order
+	FilledIn  math.Int
+	FilledOut math.Int
+	// Remaining is the input not yet swapped
+	Remaining math.Int
+	// ArrivalPrice is the output per input of the first slice's quote, in raw units
+	ArrivalPrice float64
+	// AveragePrice is the output per input of the fills, in raw units
+	AveragePrice float64
+	// SlippageBps is how far AveragePrice fell below ArrivalPrice, negative when above
+	SlippageBps float64
+	Slices      []SliceResult
+	Stop        StopReason
+}
+
+// Scheduler executes orders slice by slice. It is safe for concurrent use; each Execute runs
+// its own order.
+type Scheduler struct {
+	quoter   Quoter
+	executor Executor
+	clock    clock.Clock
+	sleeper  clock.Sleeper
+}
+
+// NewScheduler creates a scheduler quoting slices with quoter and executing them with executor
+func NewScheduler(quoter Quoter, executor Executor) *Scheduler {
+	return &Scheduler{quoter: quoter, executor: executor, clock: clock.System{}, sleeper: clock.System{}}
+}
+
+// SetClock replaces the time source durations are measured with
+func (s *Scheduler) SetClock(clk clock.Clock) {
+	s.clock = clk
+}
+
+// SetSleeper sets how the intervals between slices are waited out; clock.Fake skips the wait
+func (s *Scheduler) SetSleeper(sleeper clock.Sleeper) {
+	s.sleeper = sleeper
+}
+
+// Execute runs order until it is filled or a limit stops it, returning what was filled. The
+// error is set for invalid orders and when ctx ends the order, along with the report so far.
+func (s *Scheduler) Execute(ctx context.Context, order Order) (Report, error) {
+	if err := order.validate(); err != nil {
+		return Report{}, err
+	}
+	maxFailures := order.MaxFailures
+	if maxFailures <= 0 {
+		maxFailures = DefaultMaxFailures
+	}
+	report := Report{Order: order, FilledIn: math.ZeroInt(), FilledOut: math.ZeroInt(), Remaining: order.AmountIn}
+	sliceSize := order.AmountIn.QuoRaw(int64(order.Slices))
+	start := s.clock.Now()
+	failures := 0
+
+	for first := true; report.Remaining.IsPositive(); first = false {
+		if !first {
+			if err := s.sleeper.Sleep(ctx, order.Interval); err != nil {
+				report.Stop = StopCanceled
+				return report, err
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			report.Stop = StopCanceled
+			return report, err
+		}
+		now := s.clock.Now()
+		if order.MaxDuration > 0 && now.Sub(start) >= order.MaxDuration {
+			report.Stop = StopDeadline
+			return report, nil
+		}
+
+		amount := sliceSize
+		if report.Remaining.Sub(amount).LT(sliceSize) {
+			// The last slice takes the remainder
+			amount = report.Remaining
+		}
+		slice := SliceResult{At: now, AmountIn: amount}
+		slice.Quote, slice.Err = s.quoter.QuoteRoute(ctx, nil, order.InputMint, order.OutputMint, amount)
+		if slice.Err == nil {
+			price := priceOf(slice.Quote.AmountIn, slice.Quote.AmountOut)
+			if report.ArrivalPrice == 0 {
+				report.ArrivalPrice = price
+			}
+			if order.MaxSlippageBps > 0 && report.slippageBps(price) > order.MaxSlippageBps {
+				report.Slices = append(report.Slices, slice)
+				report.Stop = StopSlippage
+				return report, nil
+			}
+			if order.LimitPrice > 0 && price < order.LimitPrice {
+				slice.Deferred = true
+				report.Slices = append(report.Slices, slice)
+				continue
+			}
+			slice.Fill, slice.Err = s.executor(ctx, slice.Quote)
+		}
+		report.Slices = append(report.Slices, slice)
+		if slice.Err != nil {
+			if err := ctx.Err(); err != nil {
+				report.Stop = StopCanceled
+				return report, err
+			}
+			if failures++; failures >= maxFailures {
+				report.Stop = StopFailures
+				return report, nil
+			}
+			continue
+		}
+		failures = 0
+		report.record(slice.Fill)
+		if order.MaxSlippageBps > 0 && report.SlippageBps > order.MaxSlippageBps {
+			report.Stop = StopSlippage
+			return report, nil
+		}
+	}
+	report.Stop = StopCompleted
+	return report, nil
+}
+
+// record adds a fill to the totals
+func (r *Report) record(fill Fill) {
+	r.FilledIn = r.FilledIn.Add(fill.AmountIn)
+	r.FilledOut = r.FilledOut.Add(fill.AmountOut)
+	r.Remaining = r.Order.AmountIn.Sub(r.FilledIn)
+	if r.Remaining.IsNegative() {
+		r.Remaining = math.ZeroInt()
+	}
+	r.AveragePrice = priceOf(r.FilledIn, r.FilledOut)
+	r.SlippageBps = r.slippageBps(r.AveragePrice)
+}
+
+// slippageBps returns how far price is below the arrival price
+func (r *Report) slippageBps(price float64) float64 {
+	if r.ArrivalPrice == 0 {
+		return 0
+	}
+	return (r.ArrivalPrice - price) / r.ArrivalPrice * 10_000
+}
+
+func (o Order) validate() error {
+	switch {
+	case o.AmountIn.IsNil() || !o.AmountIn.IsPositive():
+		return fmt.Errorf("order amount must be positive")
+	case o.Slices < 1:
+		return fmt.Errorf("order needs at least one slice")
+	case o.AmountIn.LT(math.NewInt(int64(o.Slices))):
+		return fmt.Errorf("order of %s can't be split into %d slices", o.AmountIn, o.Slices)
+	case o.LimitPrice > 0 && o.MaxDuration <= 0:
+		return fmt.Errorf("a limit price requires a maximum duration")
+	}
+	return nil
+}
+
+// priceOf returns out per in, zero when in is zero
+func priceOf(in, out math.Int) float64 {
+	if in.IsNil() || out.IsNil() || !in.IsPositive() {
+		return 0
+	}
+	inF, _ := math.LegacyNewDecFromInt(in).Float64()
+	outF, _ := math.LegacyNewDecFromInt(out).Float64()
+	return outF / inF
+}
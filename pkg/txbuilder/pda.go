@@ -0,0 +1,138 @@
+// Package txbuilder derives the addresses swap instructions are built from: associated token
+// accounts and the program derived addresses (PDAs) of every supported program. It depends on
+// solana-go alone, so callers building their own instructions can use it without the router,
+// the RPC client or the pool decoders.
+//
+// Program IDs are parameters rather than constants, so the same derivation serves every
+// cluster a program is deployed on; the pool packages pass their own.
+package txbuilder
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// ProgramAddress finds the PDA of seeds under programID with its bump
+func ProgramAddress(programID solana.PublicKey, seeds ...[]byte) (solana.PublicKey, uint8, error) {
+	address, bump, err := solana.FindProgramAddress(seeds, programID)
+	if err != nil {
+		return solana.PublicKey{}, 0, fmt.Errorf("failed to find program address: %w", err)
+	}
+	return address, bump, nil
+}
+
+// address is ProgramAddress without the bump
+func address(programID solana.PublicKey, seeds ...[]byte) (solana.PublicKey, error) {
+	pda, _, err := ProgramAddress(programID, seeds...)
+	return pda, err
+}
+
+// EventAuthority is the account Anchor programs emit CPI events through, passed to swaps of
+// Meteora DLMM, Meteora DAMM v2 and Raydium LaunchLab
+func EventAuthority(programID solana.PublicKey) (solana.PublicKey, error) {
+	return address(programID, []byte("__event_authority"))
+}
+
+// WhirlpoolTickArray is the Orca Whirlpool tick array starting at startTickIndex, whose
+// seed is the index as a decimal string
+func WhirlpoolTickArray(programID, whirlpool solana.PublicKey, startTickIndex int64) (solana.PublicKey, error) {
+	return address(programID, []byte("tick_array"), whirlpool.Bytes(), []byte(strconv.FormatInt(startTickIndex, 10)))
+}
+
+// WhirlpoolOracle is the Orca Whirlpool oracle account of a whirlpool
+func WhirlpoolOracle(programID, whirlpool solana.PublicKey) (solana.PublicKey, error) {
+	return address(programID, []byte("oracle"), whirlpool.Bytes())
+}
+
+// RaydiumClmmTickArray is the Raydium CLMM tick array starting at startIndex, seeded with
+// the index as big-endian bytes
+func RaydiumClmmTickArray(programID, pool solana.PublicKey, startIndex int32) (solana.PublicKey, error) {
+	return address(programID, []byte("tick_array"), pool.Bytes(), binary.BigEndian.AppendUint32(nil, uint32(startIndex)))
+}
+
+// RaydiumClmmBitmapExtension is the Raydium CLMM account extending a pool's tick array bitmap
+func RaydiumClmmBitmapExtension(programID, pool solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return ProgramAddress(programID, []byte("pool_tick_array_bitmap_extension"), pool.Bytes())
+}
+
+// RaydiumAmmAuthority is the Raydium AMM v4 authority owning every pool's vaults
+func RaydiumAmmAuthority(programID solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return ProgramAddress(programID, []byte("amm authority"))
+}
+
+// RaydiumCpmmAuthority is the Raydium CPMM authority owning every pool's vaults and LP mint
+func RaydiumCpmmAuthority(programID solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return ProgramAddress(programID, []byte("vault_and_lp_mint_auth_seed"))
+}
+
+// RaydiumLaunchLabAuthority is the Raydium LaunchLab authority owning every curve's vaults
+func RaydiumLaunchLabAuthority(programID solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return ProgramAddress(programID, []byte("vault_auth_seed"))
+}
+
+// DlmmBinArray is the Meteora DLMM bin array at index, seeded with the index as
+// little-endian bytes
+func DlmmBinArray(programID, lbPair solana.PublicKey, index int64) (solana.PublicKey, uint8, error) {
+	return ProgramAddress(programID, []byte("bin_array"), lbPair.Bytes(), binary.LittleEndian.AppendUint64(nil, uint64(index)))
+}
+
+// DlmmBitmapExtension is the Meteora DLMM account extending a pair's bin array bitmap
+func DlmmBitmapExtension(programID, lbPair solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return ProgramAddress(programID, []byte("bitmap"), lbPair.Bytes())
+}
+
+// DammV2PoolAuthority is the Meteora DAMM v2 authority owning every pool's vaults
+func DammV2PoolAuthority(programID solana.PublicKey) (solana.PublicKey, error) {
+	return address(programID, []byte("pool_authority"))
+}
+
+// PhoenixVault is a Phoenix market's vault for mint, which is its own token authority
+func PhoenixVault(programID, market, mint solana.PublicKey) (solana.PublicKey, error) {
+	return address(programID, []byte("vault"), market.Bytes(), mint.Bytes())
+}
+
+// PhoenixLogAuthority is the account Phoenix logs market events through
+func PhoenixLogAuthority(programID solana.PublicKey) (solana.PublicKey, error) {
+	return address(programID, []byte("log"))
+}
+
+// MoonshotCurve is the Moonshot bonding curve account of mint
+func MoonshotCurve(programID, mint solana.PublicKey) (solana.PublicKey, error) {
+	return address(programID, []byte("token"), mint.Bytes())
+}
+
+// StakePoolWithdrawAuthority is the SPL stake pool authority minting and burning the pool's
+// tokens
+func StakePoolWithdrawAuthority(programID, pool solana.PublicKey) (solana.PublicKey, error) {
+	return address(programID, pool.Bytes(), []byte("withdraw"))
+}
+
+// PumpCoinCreatorVaultAuthority is the PumpSwap authority of the vault collecting a coin
+// creator's fees
+func PumpCoinCreatorVaultAuthority(programID, coinCreator solana.PublicKey) (solana.PublicKey, error) {
+	return address(programID, []byte("creator_vault"), coinCreator.Bytes())
+}
+
+// SquadsVault is a Squads v4 multisig's vault at vaultIndex
+func SquadsVault(programID, multisig solana.PublicKey, vaultIndex uint8) (solana.PublicKey, error) {
+	return address(programID, []byte("multisig"), multisig.Bytes(), []byte("vault"), []byte{vaultIndex})
+}
+
+// SquadsTransaction is a Squads v4 multisig's transaction account at index
+func SquadsTransaction(programID, multisig solana.PublicKey, index uint64) (solana.PublicKey, error) {
+	return address(programID, []byte("multisig"), multisig.Bytes(), []byte("transaction"), binary.LittleEndian.AppendUint64(nil, index))
+}
+
+// SquadsProposal is the Squads v4 proposal of a multisig's transaction at index
+func SquadsProposal(programID, multisig solana.PublicKey, index uint64) (solana.PublicKey, error) {
+	return address(programID, []byte("multisig"), multisig.Bytes(), []byte("transaction"), binary.LittleEndian.AppendUint64(nil, index), []byte("proposal"))
+}
+
+// TransferHookExtraAccountMetas is the validation account listing the extra accounts a
+// Token-2022 transfer hook program needs for transfers of mint
+func TransferHookExtraAccountMetas(hookProgram, mint solana.PublicKey) (solana.PublicKey, error) {
+	return address(hookProgram, []byte("extra-account-metas"), mint.Bytes())
+}
@@ -0,0 +1,33 @@
+package txbuilder
+
+import (
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// AssociatedTokenAddress derives owner's associated token account for mint under
+// tokenProgram, which is the SPL Token or Token-2022 program. solana.FindAssociatedTokenAddress
+// only covers SPL Token mints.
+func AssociatedTokenAddress(owner, mint, tokenProgram solana.PublicKey) (solana.PublicKey, error) {
+	return address(solana.SPLAssociatedTokenAccountProgramID, owner.Bytes(), tokenProgram.Bytes(), mint.Bytes())
+}
+
+// CreateAssociatedTokenAccountIdempotent builds the associated token program's
+// CreateIdempotent instruction for owner's account of mint, which succeeds when the account
+// already exists
+func CreateAssociatedTokenAccountIdempotent(payer, owner, mint, tokenProgram solana.PublicKey) (solana.Instruction, error) {
+	address, err := AssociatedTokenAddress(owner, mint, tokenProgram)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive associated token account: %w", err)
+	}
+	accounts := solana.AccountMetaSlice{
+		solana.NewAccountMeta(payer, true, true),
+		solana.NewAccountMeta(address, true, false),
+		solana.NewAccountMeta(owner, false, false),
+		solana.NewAccountMeta(mint, false, false),
+		solana.NewAccountMeta(solana.SystemProgramID, false, false),
+		solana.NewAccountMeta(tokenProgram, false, false),
+	}
+	return solana.NewInstruction(solana.SPLAssociatedTokenAccountProgramID, accounts, []byte{1}), nil
+}
@@ -0,0 +1,376 @@
+// Package uint256 is fixed width 256-bit unsigned arithmetic for the quote hot path. Values
+// live on the stack as four 64-bit limbs, so a swap step's math runs without the
+// allocations of math/big. The API follows math/big: methods set and return their receiver,
+// which may alias the operands. Products that overflow 256 bits are detected, and MulDiv
+// keeps the full 512-bit product, matching the U256/U512 math of the on-chain programs.
+package uint256
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// Int is an unsigned 256-bit integer, least significant limb first. The zero value is 0.
+type Int [4]uint64
+
+// NewInt returns v as an Int
+func NewInt(v uint64) *Int {
+	return &Int{v}
+}
+
+// FromBig returns b as an Int, and whether b is negative or doesn't fit in 256 bits, in which
+// case the Int holds b's low 256 bits
+func FromBig(b *big.Int) (*Int, bool) {
+	z := new(Int)
+	overflow := b.Sign() < 0 || b.BitLen() > 256
+	words := b.Bits()
+	if bits.UintSize == 64 {
+		for i := 0; i < len(words) && i < len(z); i++ {
+			z[i] = uint64(words[i])
+		}
+		return z, overflow
+	}
+	for i := 0; i < len(words) && i < 2*len(z); i++ {
+		z[i/2] |= uint64(words[i]) << (32 * (i % 2))
+	}
+	return z, overflow
+}
+
+// ToBig returns z as a big.Int
+func (z *Int) ToBig() *big.Int {
+	if bits.UintSize == 64 {
+		return new(big.Int).SetBits([]big.Word{big.Word(z[0]), big.Word(z[1]), big.Word(z[2]), big.Word(z[3])})
+	}
+	words := make([]big.Word, 8)
+	for i := range words {
+		words[i] = big.Word(z[i/2] >> (32 * (i % 2)))
+	}
+	return new(big.Int).SetBits(words)
+}
+
+// String returns z in decimal
+func (z *Int) String() string {
+	return z.ToBig().String()
+}
+
+// Set sets z to x
+func (z *Int) Set(x *Int) *Int {
+	*z = *x
+	return z
+}
+
+// SetUint64 sets z to v
+func (z *Int) SetUint64(v uint64) *Int {
+	*z = Int{v}
+	return z
+}
+
+// Clear sets z to 0
+func (z *Int) Clear() *Int {
+	*z = Int{}
+	return z
+}
+
+// IsZero reports whether z is 0
+func (z *Int) IsZero() bool {
+	return z[0]|z[1]|z[2]|z[3] == 0
+}
+
+// IsUint64 reports whether z fits in a uint64
+func (z *Int) IsUint64() bool {
+	return z[1]|z[2]|z[3] == 0
+}
+
+// Uint64 returns the low 64 bits of z
+func (z *Int) Uint64() uint64 {
+	return z[0]
+}
+
+// BitLen returns the number of bits needed to represent z
+func (z *Int) BitLen() int {
+	for i := len(z) - 1; i >= 0; i-- {
+		if z[i] != 0 {
+			return 64*i + bits.Len64(z[i])
+		}
+	}
+	return 0
+}
+
+// Cmp returns -1, 0 or 1 as z is less than, equal to or greater than x
+func (z *Int) Cmp(x *Int) int {
+	for i := len(z) - 1; i >= 0; i-- {
+		switch {
+		case z[i] < x[i]:
+			return -1
+		case z[i] > x[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// Eq reports whether z == x
+func (z *Int) Eq(x *Int) bool {
+	return *z == *x
+}
+
+// Lt reports whether z < x
+func (z *Int) Lt(x *Int) bool {
+	return z.Cmp(x) < 0
+}
+
+// Gt reports whether z > x
+func (z *Int) Gt(x *Int) bool {
+	return z.Cmp(x) > 0
+}
+
+// AddOverflow sets z to x + y modulo 2^256 and reports whether the sum overflowed
+func (z *Int) AddOverflow(x, y *Int) (*Int, bool) {
+	var carry uint64
+	z[0], carry = bits.Add64(x[0], y[0], 0)
+	z[1], carry = bits.Add64(x[1], y[1], carry)
+	z[2], carry = bits.Add64(x[2], y[2], carry)
+	z[3], carry = bits.Add64(x[3], y[3], carry)
+	return z, carry != 0
+}
+
+// Add sets z to x + y modulo 2^256
+func (z *Int) Add(x, y *Int) *Int {
+	z, _ = z.AddOverflow(x, y)
+	return z
+}
+
+// SubOverflow sets z to x - y modulo 2^256 and reports whether the difference underflowed
+func (z *Int) SubOverflow(x, y *Int) (*Int, bool) {
+	var borrow uint64
+	z[0], borrow = bits.Sub64(x[0], y[0], 0)
+	z[1], borrow = bits.Sub64(x[1], y[1], borrow)
+	z[2], borrow = bits.Sub64(x[2], y[2], borrow)
+	z[3], borrow = bits.Sub64(x[3], y[3], borrow)
+	return z, borrow != 0
+}
+
+// Sub sets z to x - y modulo 2^256
+func (z *Int) Sub(x, y *Int) *Int {
+	z, _ = z.SubOverflow(x, y)
+	return z
+}
+
+// MulOverflow sets z to x * y modulo 2^256 and reports whether the product overflowed
+func (z *Int) MulOverflow(x, y *Int) (*Int, bool) {
+	p := mul512(x, y)
+	copy(z[:], p[:4])
+	return z, p[4]|p[5]|p[6]|p[7] != 0
+}
+
+// Mul sets z to x * y modulo 2^256
+func (z *Int) Mul(x, y *Int) *Int {
+	z, _ = z.MulOverflow(x, y)
+	return z
+}
+
+// Div sets z to x / y rounded down, or 0 when y is 0
+func (z *Int) Div(x, y *Int) *Int {
+	var m Int
+	z, _ = z.DivMod(x, y, &m)
+	return z
+}
+
+// DivMod sets z to x / y rounded down and m to the remainder, both 0 when y is 0. z and m
+// must differ.
+func (z *Int) DivMod(x, y, m *Int) (*Int, *Int) {
+	if y.IsZero() {
+		return z.Clear(), m.Clear()
+	}
+	var quot Int
+	rem := divRem(quot[:], x[:], y)
+	*z, *m = quot, rem
+	return z, m
+}
+
+// MulDivOverflow sets z to x * y / d rounded down, computed on the full 512-bit product, and
+// reports whether d is 0 or the quotient doesn't fit in 256 bits
+func (z *Int) MulDivOverflow(x, y, d *Int) (*Int, bool) {
+	z, _, overflow := z.mulDivRem(x, y, d)
+	return z, overflow
+}
+
+// MulDivCeilOverflow is MulDivOverflow rounding up
+func (z *Int) MulDivCeilOverflow(x, y, d *Int) (*Int, bool) {
+	z, rem, overflow := z.mulDivRem(x, y, d)
+	if overflow || rem.IsZero() {
+		return z, overflow
+	}
+	return z.AddOverflow(z, &Int{1})
+}
+
+func (z *Int) mulDivRem(x, y, d *Int) (*Int, Int, bool) {
+	if d.IsZero() {
+		return z.Clear(), Int{}, true
+	}
+	p := mul512(x, y)
+	var quot [8]uint64
+	rem := divRem(quot[:], p[:], d)
+	copy(z[:], quot[:4])
+	return z, rem, quot[4]|quot[5]|quot[6]|quot[7] != 0
+}
+
+// Lsh sets z to x << n modulo 2^256
+func (z *Int) Lsh(x *Int, n uint) *Int {
+	if n >= 256 {
+		return z.Clear()
+	}
+	limbs, shift := int(n/64), n%64
+	var r Int
+	for i := len(r) - 1; i >= limbs; i-- {
+		r[i] = x[i-limbs] << shift
+		if shift != 0 && i-limbs-1 >= 0 {
+			r[i] |= x[i-limbs-1] >> (64 - shift)
+		}
+	}
+	*z = r
+	return z
+}
+
+// Rsh sets z to x >> n
+func (z *Int) Rsh(x *Int, n uint) *Int {
+	if n >= 256 {
+		return z.Clear()
+	}
+	limbs, shift := int(n/64), n%64
+	var r Int
+	for i := 0; i+limbs < len(r); i++ {
+		r[i] = x[i+limbs] >> shift
+		if shift != 0 && i+limbs+1 < len(r) {
+			r[i] |= x[i+limbs+1] << (64 - shift)
+		}
+	}
+	*z = r
+	return z
+}
+
+// mul512 returns the full product of x and y
+func mul512(x, y *Int) [8]uint64 {
+	var p [8]uint64
+	for i := range x {
+		if x[i] == 0 {
+			continue
+		}
+		var carry uint64
+		for j := range y {
+			hi, lo := bits.Mul64(x[i], y[j])
+			var c uint64
+			lo, c = bits.Add64(lo, p[i+j], 0)
+			hi += c
+			lo, c = bits.Add64(lo, carry, 0)
+			hi += c
+			p[i+j], carry = lo, hi
+		}
+		p[i+len(y)] = carry
+	}
+	return p
+}
+
+// divRem sets quot, which is as long as u, to u / d rounded down and returns the remainder.
+// d must not be 0. It is Knuth's algorithm D on 64-bit digits.
+func divRem(quot, u []uint64, d *Int) Int {
+	for i := range quot {
+		quot[i] = 0
+	}
+	dLen := len(d)
+	for dLen > 0 && d[dLen-1] == 0 {
+		dLen--
+	}
+	uLen := len(u)
+	for uLen > 0 && u[uLen-1] == 0 {
+		uLen--
+	}
+	var rem Int
+	if uLen < dLen {
+		copy(rem[:], u[:uLen])
+		return rem
+	}
+
+	if dLen == 1 {
+		var r uint64
+		for i := uLen - 1; i >= 0; i-- {
+			quot[i], r = bits.Div64(r, u[i], d[0])
+		}
+		rem[0] = r
+		return rem
+	}
+
+	// Normalize so the divisor's top digit has its high bit set, which keeps each estimated
+	// quotient digit at most one too large after refinement. Go shifts by 64 give 0, so no
+	// shift needs no special case.
+	shift := uint(bits.LeadingZeros64(d[dLen-1]))
+	var dn [4]uint64
+	for i := dLen - 1; i > 0; i-- {
+		dn[i] = d[i]<<shift | d[i-1]>>(64-shift)
+	}
+	dn[0] = d[0] << shift
+	var un [9]uint64
+	un[uLen] = u[uLen-1] >> (64 - shift)
+	for i := uLen - 1; i > 0; i-- {
+		un[i] = u[i]<<shift | u[i-1]>>(64-shift)
+	}
+	un[0] = u[0] << shift
+
+	dTop, dNext := dn[dLen-1], dn[dLen-2]
+	for j := uLen - dLen; j >= 0; j-- {
+		hi, lo := un[j+dLen], un[j+dLen-1]
+		var qhat, rhat uint64
+		refine := true
+		if hi >= dTop {
+			qhat = ^uint64(0)
+			var c uint64
+			rhat, c = bits.Add64(lo, dTop, 0)
+			refine = c == 0
+		} else {
+			qhat, rhat = bits.Div64(hi, lo, dTop)
+		}
+		for refine {
+			ph, pl := bits.Mul64(qhat, dNext)
+			if ph < rhat || (ph == rhat && pl <= un[j+dLen-2]) {
+				break
+			}
+			qhat--
+			var c uint64
+			rhat, c = bits.Add64(rhat, dTop, 0)
+			refine = c == 0
+		}
+
+		if subMul(un[j:j+dLen+1], dn[:dLen], qhat) {
+			// The estimate was one too large: add the divisor back
+			qhat--
+			var carry uint64
+			for i := 0; i < dLen; i++ {
+				un[j+i], carry = bits.Add64(un[j+i], dn[i], carry)
+			}
+			un[j+dLen] += carry
+		}
+		quot[j] = qhat
+	}
+
+	for i := 0; i < dLen; i++ {
+		rem[i] = un[i]>>shift | un[i+1]<<(64-shift)
+	}
+	return rem
+}
+
+// subMul sets x to x - y*m, x being one digit longer than y, and reports whether the result
+// went negative
+func subMul(x, y []uint64, m uint64) bool {
+	var borrow, carry uint64
+	for i := range y {
+		hi, lo := bits.Mul64(y[i], m)
+		var c uint64
+		lo, c = bits.Add64(lo, carry, 0)
+		carry = hi + c
+		x[i], borrow = bits.Sub64(x[i], lo, borrow)
+	}
+	var b1, b2 uint64
+	x[len(y)], b1 = bits.Sub64(x[len(y)], carry, 0)
+	x[len(y)], b2 = bits.Sub64(x[len(y)], 0, borrow)
+	return b1|b2 != 0
+}
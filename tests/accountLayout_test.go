@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand/v2"
+	"testing"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/layout"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/meteora"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/orca"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Sizes and offsets of the mint fields the discovery filters match on, as found on chain
+func TestAccountLayoutsMatchOnChainOffsets(t *testing.T) {
+	whirlpool := &orca.WhirlpoolPool{}
+	assert.Equal(t, uint64(653), whirlpool.Span())
+	assert.Equal(t, uint64(8), whirlpool.Offset("WhirlpoolsConfig"))
+	assert.Equal(t, uint64(101), whirlpool.Offset("TokenMintA"))
+	assert.Equal(t, uint64(181), whirlpool.Offset("TokenMintB"))
+	assert.Equal(t, uint64(65), whirlpool.Offset("SqrtPrice"))
+
+	clmm := &raydium.CLMMPool{}
+	assert.Equal(t, uint64(1544), clmm.Span())
+	assert.Equal(t, uint64(73), clmm.Offset("TokenMint0"))
+	assert.Equal(t, uint64(105), clmm.Offset("TokenMint1"))
+
+	cpmm := &raydium.CPMMPool{}
+	assert.Equal(t, uint64(637), cpmm.Span())
+	assert.Equal(t, uint64(168), cpmm.Offset("Token0Mint"))
+	assert.Equal(t, uint64(200), cpmm.Offset("Token1Mint"))
+
+	dlmm := &meteora.MeteoraDlmmPool{}
+	assert.Equal(t, uint64(904), dlmm.Span())
+	assert.Equal(t, uint64(88), dlmm.Offset("TokenXMint"))
+	assert.Equal(t, uint64(120), dlmm.Offset("TokenYMint"))
+	assert.Equal(t, uint64(552), dlmm.Offset("Oracle"))
+	assert.Equal(t, uint64(76), dlmm.Offset("ActiveId"))
+	assert.Equal(t, uint64(12), dlmm.Offset("Parameters.DecayPeriod"))
+
+	damm := &meteora.MeteoraDammPool{}
+	assert.Equal(t, uint64(40), damm.Offset("TokenAMint"))
+	assert.Equal(t, uint64(72), damm.Offset("TokenBMint"))
+	assert.Equal(t, uint64(0), damm.Offset("NoSuchField"))
+}
+
+// Decoding random account data puts each field where its offset says, and re-encoding the
+// account gives back the same bytes
+func TestAccountLayoutsRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewPCG(5, 6))
+	fixture := func(size uint64) []byte {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(rng.UintN(256))
+		}
+		return data
+	}
+	key := func(data []byte, offset uint64) solana.PublicKey {
+		return solana.PublicKeyFromBytes(data[offset : offset+32])
+	}
+
+	whirlpool := &orca.WhirlpoolPool{}
+	data := fixture(whirlpool.Span())
+	require.NoError(t, whirlpool.Decode(data))
+	assert.Equal(t, key(data, whirlpool.Offset("TokenMintA")), whirlpool.TokenMintA)
+	assert.Equal(t, key(data, whirlpool.Offset("TokenMintB")), whirlpool.TokenMintB)
+	assert.Equal(t, int32(binary.LittleEndian.Uint32(data[whirlpool.Offset("TickCurrentIndex"):])), whirlpool.TickCurrentIndex)
+	assert.Equal(t, binary.LittleEndian.Uint16(data[whirlpool.Offset("FeeRate"):]), whirlpool.FeeRate)
+	assertReencodes(t, data, &orca.WhirlpoolAccount{})
+
+	clmm := &raydium.CLMMPool{}
+	data = fixture(clmm.Span())
+	require.NoError(t, clmm.Decode(data))
+	assert.Equal(t, key(data, clmm.Offset("TokenMint1")), clmm.TokenMint1)
+	assert.Equal(t, binary.LittleEndian.Uint64(data[clmm.Offset("SqrtPriceX64"):]), clmm.SqrtPriceX64.Lo)
+	assert.Equal(t, binary.LittleEndian.Uint64(data[clmm.Offset("RecentEpoch"):]), clmm.RecentEpoch)
+	assertReencodes(t, data, &raydium.ClmmPoolState{})
+
+	cpmm := &raydium.CPMMPool{PoolId: solana.NewWallet().PublicKey()}
+	id := cpmm.PoolId
+	data = fixture(cpmm.Span())
+	require.NoError(t, cpmm.Decode(data))
+	assert.Equal(t, key(data, cpmm.Offset("Token0Vault")), cpmm.Token0Vault)
+	assert.Equal(t, binary.LittleEndian.Uint64(data[cpmm.Offset("OpenTime"):]), cpmm.OpenTime)
+	assert.Equal(t, id, cpmm.PoolId, "runtime fields are left alone")
+	assertReencodes(t, data, &raydium.CpmmPoolState{})
+
+	dlmm := &meteora.MeteoraDlmmPool{}
+	data = fixture(dlmm.Span())
+	require.NoError(t, dlmm.Decode(data))
+	assert.Equal(t, key(data, dlmm.Offset("TokenYMint")), dlmm.TokenYMint)
+	assert.Equal(t, int32(binary.LittleEndian.Uint32(data[dlmm.Offset("ActiveId"):])), dlmm.ActiveID())
+	assertReencodes(t, data, &meteora.LbPair{})
+
+	damm := &meteora.MeteoraDammPool{}
+	data = fixture(dammAccountSize(t))
+	copy(data, meteora.DammPoolDiscriminator[:])
+	data[damm.Offset("Enabled")] = 1
+	binary.LittleEndian.PutUint64(data[damm.Offset("Fees.TradeFeeDenominator"):], 10_000)
+	binary.LittleEndian.PutUint64(data[damm.Offset("Fees.ProtocolTradeFeeDenominator"):], 100)
+	data[damm.Offset("CurveType")] = meteora.DammCurveConstantProduct
+	require.NoError(t, damm.Decode(data))
+	assert.Equal(t, key(data, damm.Offset("BVaultLp")), damm.BVaultLp)
+	assert.Equal(t, binary.LittleEndian.Uint64(data[damm.Offset("Bootstrapping.ActivationPoint"):]), damm.ActivationPoint)
+	assert.True(t, damm.Enabled)
+	assertReencodes(t, data, &meteora.DammPool{})
+
+	// A byte short of any layout fails to decode
+	assert.Error(t, whirlpool.Decode(make([]byte, whirlpool.Span()-1)))
+	assert.Error(t, dlmm.Decode(make([]byte, dlmm.Span()-1)))
+}
+
+func TestLayoutRejectsVariableSizeFields(t *testing.T) {
+	_, err := layout.Of(struct{ Data []byte }{})
+	assert.Error(t, err)
+	_, err = layout.Of(struct {
+		Fee *uint64 `bin:"optional"`
+	}{})
+	assert.Error(t, err)
+	l, err := layout.Of(struct {
+		A       uint8
+		skipped uint64
+		B       uint16 `bin:"-"`
+		C       [3]uint32
+	}{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(8+1+12), l.Size())
+	offset, ok := l.Offset("C")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(9), offset)
+}
+
+func dammAccountSize(t *testing.T) uint64 {
+	l, err := layout.Of(meteora.DammPool{})
+	require.NoError(t, err)
+	return l.Size()
+}
+
+// assertReencodes decodes data into account and checks that encoding it gives data back
+func assertReencodes(t *testing.T, data []byte, account any) {
+	t.Helper()
+	l, err := layout.Of(account)
+	require.NoError(t, err)
+	require.NoError(t, l.Decode(data, account))
+	var buf bytes.Buffer
+	require.NoError(t, bin.NewBorshEncoder(&buf).Encode(account))
+	assert.Equal(t, data[layout.DiscriminatorSize:l.Size()], buf.Bytes(), "%T", account)
+}
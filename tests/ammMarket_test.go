@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAMMMarketResolver(t *testing.T) {
+	marketProgram := solana.NewWallet().PublicKey()
+	marketID := solana.NewWallet().PublicKey()
+	layout := raydium.MarketStateLayoutV3{
+		OwnAddress: marketID,
+		BaseVault:  solana.NewWallet().PublicKey(),
+		QuoteVault: solana.NewWallet().PublicKey(),
+		EventQueue: solana.NewWallet().PublicKey(),
+		Bids:       solana.NewWallet().PublicKey(),
+		Asks:       solana.NewWallet().PublicKey(),
+	}
+	// Markets store the first nonce whose address is off the curve
+	var vaultSigner solana.PublicKey
+	for ; ; layout.VaultSignerNonce++ {
+		nonce := binary.LittleEndian.AppendUint64(nil, layout.VaultSignerNonce)
+		signer, err := solana.CreateProgramAddress([][]byte{marketID.Bytes(), nonce}, marketProgram)
+		if err == nil {
+			vaultSigner = signer
+			break
+		}
+	}
+	data, err := bin.MarshalBorsh(&layout)
+	require.NoError(t, err)
+	mock := &countingRPC{MockRPC: sol.NewMockRPC()}
+	mock.SetAccount(marketID, marketProgram, data)
+
+	resolver := raydium.NewMarketResolver()
+	ctx := context.Background()
+	pools := []*raydium.AMMPool{{MarketId: marketID}, {MarketId: marketID}}
+	require.NoError(t, resolver.Resolve(ctx, mock, pools...))
+	assert.EqualValues(t, 1, mock.batches.Load())
+	for _, pool := range pools {
+		assert.Equal(t, layout.Bids, pool.MarketBids)
+		assert.Equal(t, layout.Asks, pool.MarketAsks)
+		assert.Equal(t, layout.EventQueue, pool.MarketEventQueue)
+		assert.Equal(t, layout.BaseVault, pool.MarketBaseVault)
+		assert.Equal(t, layout.QuoteVault, pool.MarketQuoteVault)
+		assert.Equal(t, vaultSigner, pool.MarketAuthority)
+		assert.False(t, pool.Authority.IsZero())
+	}
+
+	// Cached markets are set without reading them again
+	pool := &raydium.AMMPool{MarketId: marketID}
+	require.NoError(t, resolver.Resolve(ctx, mock, pool))
+	assert.EqualValues(t, 1, mock.batches.Load())
+	assert.Equal(t, vaultSigner, pool.MarketAuthority)
+
+	missing := solana.NewWallet().PublicKey()
+	assert.ErrorContains(t, resolver.Resolve(ctx, mock, &raydium.AMMPool{MarketId: missing}), "not found")
+}
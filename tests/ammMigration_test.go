@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAMMDeprecation(t *testing.T) {
+	live := func() *raydium.AMMPool {
+		return &raydium.AMMPool{PoolId: solana.NewWallet().PublicKey(), Status: 6, LpReserve: 1, BaseReserve: math.NewInt(1), QuoteReserve: math.NewInt(1)}
+	}
+	assert.Nil(t, live().Deprecation())
+	unread := live()
+	unread.BaseReserve, unread.QuoteReserve = math.Int{}, math.Int{}
+	assert.Nil(t, unread.Deprecation(), "reserves that weren't read yet aren't drained")
+
+	for reason, pool := range map[string]func(*raydium.AMMPool){
+		"status 4 does not allow swaps": func(p *raydium.AMMPool) { p.Status = 4 },
+		"all liquidity withdrawn":       func(p *raydium.AMMPool) { p.LpReserve = 0 },
+		"reserves drained":              func(p *raydium.AMMPool) { p.QuoteReserve = math.ZeroInt() },
+	} {
+		p := live()
+		pool(p)
+		require.NotNil(t, p.Deprecation(), reason)
+		assert.Equal(t, pkg.Deprecation{Reason: reason}, *p.Deprecation())
+		p.SetSuccessor("successor")
+		assert.Equal(t, "successor", p.Deprecation().Successor)
+	}
+}
+
+func TestRouterLinksMigratedAMMPools(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	migrated := func() *raydium.AMMPool {
+		return &raydium.AMMPool{PoolId: solana.NewWallet().PublicKey(), Status: 6, BaseMint: mintA, QuoteMint: mintB}
+	}
+	cpmm := &raydium.CPMMPool{PoolId: solana.NewWallet().PublicKey(), Token0Mint: mintB, Token1Mint: mintA}
+	clmm := &raydium.CLMMPool{PoolId: solana.NewWallet().PublicKey(), TokenMint0: mintA, TokenMint1: mintB}
+	other := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 1e9}
+	otherPair := &raydium.CPMMPool{PoolId: solana.NewWallet().PublicKey(), Token0Mint: mintA, Token1Mint: solana.NewWallet().PublicKey()}
+	retired := migrated()
+	ctx := context.Background()
+
+	// Successors are pools of the same pair that are still routable, preferring where Raydium
+	// migrates AMM liquidity to
+	for name, tc := range map[string]struct {
+		pools []pkg.Pool
+		want  pkg.Pool
+	}{
+		"cpmm first":            {pools: []pkg.Pool{other, clmm, cpmm}, want: cpmm},
+		"then clmm":             {pools: []pkg.Pool{other, clmm}, want: clmm},
+		"then any other":        {pools: []pkg.Pool{other}, want: other},
+		"not another pair":      {pools: []pkg.Pool{otherPair}},
+		"not a deprecated pool": {pools: []pkg.Pool{retired}},
+	} {
+		amm := migrated()
+		r := router.NewSimpleRouter(staticProtocol(append([]pkg.Pool{amm}, tc.pools...)))
+		_, err := r.QueryAllPools(ctx, mintA.String(), mintB.String())
+		require.NoError(t, err, name)
+		if tc.want == nil {
+			assert.Empty(t, amm.Successor, name)
+		} else {
+			assert.Equal(t, tc.want.GetID(), amm.Successor, name)
+		}
+	}
+}
+
+func TestRouterSkipsMigratedAMMPools(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	amm := &raydium.AMMPool{PoolId: solana.NewWallet().PublicKey(), Status: 6, BaseMint: mintA, QuoteMint: mintB}
+	successor := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 1e9}
+	ctx := context.Background()
+	bestPool := func(pools ...pkg.Pool) (pkg.Pool, error) {
+		r := router.NewSimpleRouter(staticProtocol(pools))
+		r.SetQuoteClient(sol.NewMockRPC())
+		r.SetLogger(pkg.DiscardLogger)
+		_, err := r.QueryAllPools(ctx, mintA.String(), mintB.String())
+		require.NoError(t, err)
+		best, _, err := r.GetBestPool(ctx, nil, mintA.String(), mintB.String(), math.NewInt(1_000))
+		return best, err
+	}
+
+	// The migrated pool isn't quoted, so its unread vaults don't fail the route
+	best, err := bestPool(amm, successor)
+	require.NoError(t, err)
+	assert.Equal(t, successor.GetID(), best.GetID(), "quotes go to the successor")
+
+	// A failing successor leaves the skipped pool's error naming it
+	amm.Successor = ""
+	broken := &stubQuotePool{Pool: successor, err: errors.New("stale reserves")}
+	_, err = bestPool(amm, broken)
+	assert.ErrorIs(t, err, solerrors.ErrNoRoute)
+	assert.ErrorIs(t, err, solerrors.ErrPoolDisabled)
+	assert.ErrorContains(t, err, "pool deprecated (all liquidity withdrawn), successor "+successor.GetID())
+
+	// Without a successor the pair has no route
+	amm.Successor = ""
+	_, err = bestPool(amm)
+	assert.ErrorIs(t, err, solerrors.ErrNoRoute)
+	assert.ErrorIs(t, err, solerrors.ErrPoolDisabled)
+	assert.ErrorContains(t, err, "pool deprecated (all liquidity withdrawn)")
+	assert.NotContains(t, err.Error(), "successor")
+	assert.Empty(t, amm.Successor)
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chanWatcher hands out a test-fed channel of vault updates
+type chanWatcher struct {
+	updates chan sol.AccountUpdate
+}
+
+func (w *chanWatcher) SubscribeAccounts(ctx context.Context, accounts []solana.PublicKey) (<-chan sol.AccountUpdate, error) {
+	return w.updates, nil
+}
+
+func tokenAccountUpdate(vault solana.PublicKey, slot, amount uint64) sol.AccountUpdate {
+	data := make([]byte, sol.TokenAccountSize)
+	binary.LittleEndian.PutUint64(data[64:], amount)
+	return sol.AccountUpdate{Pubkey: vault, Slot: slot, Data: data}
+}
+
+func TestAMMQuotesFromStreamedVaultBalances(t *testing.T) {
+	mock := &countingRPC{MockRPC: sol.NewMockRPC()}
+	pool := &raydium.AMMPool{
+		PoolId:     solana.NewWallet().PublicKey(),
+		BaseMint:   solana.NewWallet().PublicKey(),
+		QuoteMint:  solana.NewWallet().PublicKey(),
+		BaseVault:  solana.NewWallet().PublicKey(),
+		QuoteVault: solana.NewWallet().PublicKey(),
+	}
+	mock.SetTokenAccount(pool.BaseVault, pool.BaseMint, pool.PoolId, 1_000_000)
+	mock.SetTokenAccount(pool.QuoteVault, pool.QuoteMint, pool.PoolId, 2_000_000)
+	ctx := context.Background()
+	amount := math.NewInt(10_000)
+
+	polled, err := pool.Quote(ctx, mock, pool.BaseMint.String(), amount)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), mock.batches.Load())
+	assert.Equal(t, polled, pool.Reserves().Quote(pool.BaseMint.String(), amount), "snapshots quote offline like the pool")
+
+	watcher := &chanWatcher{updates: make(chan sol.AccountUpdate, 3)}
+	cache := raydium.NewVaultBalanceCache()
+	require.NoError(t, cache.Watch(ctx, watcher, pool))
+	watcher.updates <- tokenAccountUpdate(pool.BaseVault, 10, 1_000_000)
+	watcher.updates <- tokenAccountUpdate(pool.QuoteVault, 10, 4_000_000)
+	watcher.updates <- tokenAccountUpdate(pool.QuoteVault, 9, 1)
+	close(watcher.updates)
+	require.Eventually(t, func() bool {
+		balance, _, ok := cache.Balance(pool.QuoteVault)
+		return ok && balance == 4_000_000
+	}, time.Second, time.Millisecond)
+
+	streamed, err := pool.Quote(ctx, mock, pool.BaseMint.String(), amount)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), mock.batches.Load(), "cached vaults aren't fetched")
+	assert.True(t, streamed.GT(polled))
+	assert.Equal(t, uint64(10), pool.Reserves().Slot)
+}
@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRaydiumAPIDiscoveryFiltersByProgram(t *testing.T) {
+	program := solana.NewWallet().PublicKey()
+	listed, other := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/pools/info/mint", r.URL.Path)
+		assert.Equal(t, "mintA", r.URL.Query().Get("mint1"))
+		fmt.Fprintf(w, `{"success":true,"data":{"data":[{"id":%q,"programId":%q},{"id":%q,"programId":%q}]}}`,
+			listed, program, other, solana.NewWallet().PublicKey())
+	}))
+	defer server.Close()
+
+	discovery := protocol.NewRaydiumAPIDiscovery(program)
+	discovery.SetEndpoint(server.URL)
+	pools, err := discovery.PoolsByPair(context.Background(), "mintA", "mintB")
+	require.NoError(t, err)
+	assert.Equal(t, []solana.PublicKey{listed}, pools)
+}
+
+func TestDiscoveringProtocolFallsBackToScan(t *testing.T) {
+	pool := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: solana.NewWallet().PublicKey(), MintB: solana.NewWallet().PublicKey()}
+	healthy := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		// The closed pool the API still lists is skipped
+		fmt.Fprintf(w, `{"data":[{"address":%q},{"address":%q}]}`, pool.ID, solana.NewWallet().PublicKey())
+	}))
+	defer server.Close()
+
+	discovery := protocol.NewOrcaAPIDiscovery()
+	discovery.SetEndpoint(server.URL)
+	inner := &scanningProtocol{staticProtocol: staticProtocol{pool}}
+	proto := protocol.WithDiscovery(inner, discovery)
+	ctx := context.Background()
+
+	pools, err := proto.FetchPoolsByPair(ctx, pool.MintA.String(), pool.MintB.String())
+	require.NoError(t, err)
+	require.Len(t, pools, 1)
+	assert.Equal(t, pool.ID.String(), pools[0].GetID())
+	assert.Zero(t, inner.scans)
+	assert.Equal(t, inner.Name(), proto.Name())
+
+	healthy = false
+	pools, err = proto.FetchPoolsByPair(ctx, pool.MintA.String(), pool.MintB.String())
+	require.NoError(t, err)
+	assert.Len(t, pools, 1)
+	assert.Equal(t, 1, inner.scans)
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolV2Adapters(t *testing.T) {
+	pool := &exampledex.Pool{
+		ID:       solana.NewWallet().PublicKey(),
+		MintA:    solana.NewWallet().PublicKey(),
+		MintB:    solana.NewWallet().PublicKey(),
+		ReserveA: 1_000_000_000,
+		ReserveB: 50_000_000_000,
+		FeeBps:   30,
+	}
+	ctx := context.Background()
+	v2 := pkg.AdaptPool(pool)
+	assert.Equal(t, pool.GetID(), v2.Info().ID)
+
+	// The output mint is filled in from the pool
+	want, err := pool.Quote(ctx, nil, pool.MintA.String(), math.NewInt(1_000_000))
+	require.NoError(t, err)
+	resp, err := v2.Quote(ctx, pkg.QuoteRequest{InputMint: pool.MintA.String(), Amount: math.NewInt(1_000_000)})
+	require.NoError(t, err)
+	assert.Equal(t, pool.MintB.String(), resp.OutputMint)
+	assert.Equal(t, want, resp.AmountOut)
+
+	exact, err := v2.Quote(ctx, pkg.QuoteRequest{OutputMint: pool.MintB.String(), Amount: want, ExactOut: true})
+	require.NoError(t, err)
+	assert.Equal(t, pool.MintA.String(), exact.InputMint)
+	assert.True(t, exact.AmountIn.LTE(math.NewInt(1_000_000)))
+
+	_, err = v2.Quote(ctx, pkg.QuoteRequest{InputMint: pool.MintA.String(), OutputMint: pool.MintA.String(), Amount: want})
+	assert.Error(t, err)
+
+	// Adapting back returns the original, and v2 pools act as v1 pools
+	assert.Same(t, pool, pkg.FromPoolV2(v2))
+	v1 := pkg.FromPoolV2(struct{ pkg.PoolV2 }{v2})
+	out, err := v1.Quote(ctx, nil, pool.MintA.String(), math.NewInt(1_000_000))
+	require.NoError(t, err)
+	assert.Equal(t, want, out)
+
+	protocol := pkg.FromProtocolV2(pkg.AdaptProtocol(staticProtocol{pool}))
+	found, err := protocol.FetchPoolByID(ctx, pool.GetID())
+	require.NoError(t, err)
+	assert.Same(t, pool, found)
+}
@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindArbitrage(t *testing.T) {
+	sol, token := solana.WrappedSol, solana.NewWallet().PublicKey()
+	// token is cheap in one pool and dear in the other
+	cheap := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: sol, MintB: token, ReserveA: 1e12, ReserveB: 3e12, FeeBps: 30}
+	dear := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: sol, MintB: token, ReserveA: 1e12, ReserveB: 2e12, FeeBps: 30}
+	fair := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: sol, MintB: token, ReserveA: 1e12, ReserveB: 2e12, FeeBps: 30}
+
+	r := router.NewSimpleRouter(staticProtocol{dear, cheap, fair})
+	r.SetQuoteClient(rpc.New("http://127.0.0.1:0"))
+	ctx := context.Background()
+	_, err := r.QueryAllPools(ctx, sol.String(), token.String())
+	require.NoError(t, err)
+
+	opts := router.ArbitrageOptions{AmountIn: math.NewInt(1_000_000_000), ComputeUnitPrice: 10_000}
+	cycles, err := r.FindArbitrage(ctx, nil, sol.String(), opts)
+	require.NoError(t, err)
+	require.Len(t, cycles, 1, "only buying the token where it is cheap pays")
+	cycle := cycles[0]
+	assert.Equal(t, token.String(), cycle.Intermediate)
+	assert.Equal(t, cheap.GetID(), cycle.Pools[0].GetID())
+	assert.True(t, cycle.AmountOut.GT(cycle.AmountIn))
+	assert.Equal(t, cycle.AmountOut.Sub(cycle.AmountIn), cycle.Profit)
+	assert.Equal(t, uint64(440_000), cycle.ComputeUnits)
+	assert.Equal(t, uint64(5000+4400), cycle.NetworkFee)
+	assert.Equal(t, cycle.Profit.SubRaw(9400), cycle.NetProfit)
+
+	opts.MinProfit = cycle.NetProfit.AddRaw(1)
+	cycles, err = r.FindArbitrage(ctx, nil, sol.String(), opts)
+	require.NoError(t, err)
+	assert.Empty(t, cycles)
+}
@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"runtime/pprof"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clmmmath"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/meteora"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/uint256"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"lukechampine.com/uint128"
+)
+
+// The benchmarks cover the quote hot path, the fixed point math of the concentrated liquidity
+// and DLMM pools and the router's selection loop. Compare runs with benchstat:
+//
+//	go test ./tests -run '^$' -bench . -benchmem -count 10 > new.txt
+//
+// and profile one with -cpuprofile or -memprofile.
+
+func BenchmarkSqrtPriceX64FromTick(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		if _, err := clmmmath.SqrtPriceX64FromTick(int64(i%200_000 - 100_000)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTickFromSqrtPriceX64(b *testing.B) {
+	sqrtPrice, err := clmmmath.SqrtPriceX64FromTick(-12_345)
+	require.NoError(b, err)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := clmmmath.TickFromSqrtPriceX64(sqrtPrice); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWhirlpoolTickMath(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		sqrtPrice, err := clmmmath.WhirlpoolSqrtPriceX64FromTick(int64(i%200_000 - 100_000))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := clmmmath.WhirlpoolTickFromSqrtPriceX64(sqrtPrice); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCLMMSwapStep runs the math of one exact input swap step, shared by Raydium CLMM
+// and Orca Whirlpool: the input to reach the next tick, the price the input moves to and the
+// output between the two prices
+func BenchmarkCLMMSwapStep(b *testing.B) {
+	current, err := clmmmath.SqrtPriceX64FromTick(15)
+	require.NoError(b, err)
+	target, err := clmmmath.SqrtPriceX64FromTick(0)
+	require.NoError(b, err)
+	liquidity := big.NewInt(1_000_000_000_000)
+	amount := big.NewInt(1_000_000)
+	b.ReportAllocs()
+	for b.Loop() {
+		clmmmath.GetTokenAmountAFromLiquidity(target.BigInt(), current.BigInt(), liquidity, true)
+		next := clmmmath.GetNextSqrtPriceX64FromInput(current.BigInt(), liquidity, amount, true)
+		clmmmath.GetTokenAmountBFromLiquidity(next, current.BigInt(), liquidity, false)
+	}
+}
+
+// BenchmarkComputeSwapStep runs the 256-bit swap step the CLMM and Whirlpool quotes use
+func BenchmarkComputeSwapStep(b *testing.B) {
+	current, err := clmmmath.SqrtPriceX64FromTick(15)
+	require.NoError(b, err)
+	target, err := clmmmath.SqrtPriceX64FromTick(0)
+	require.NoError(b, err)
+	sqrtPrice, _ := uint256.FromBig(current.BigInt())
+	sqrtTarget, _ := uint256.FromBig(target.BigInt())
+	liquidity, amount := uint256.NewInt(1_000_000_000_000), uint256.NewInt(1_000_000)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := clmmmath.ComputeSwapStep(sqrtPrice, sqrtTarget, liquidity, amount, true, 2500, true); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCLMMQuote(b *testing.B) {
+	pool, mock := clmmPoolWithRange(b)
+	ctx := context.Background()
+	mint0 := pool.TokenMint0.String()
+	amount := math.NewInt(1_000_000)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := pool.Quote(ctx, mock, mint0, amount); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkDLMMBinSwap runs the math of swapping through a DLMM bin both ways at a price of 1.5
+func BenchmarkDLMMBinSwap(b *testing.B) {
+	price := uint128.From64(3).Lsh(meteora.ScaleOffset - 1)
+	var bin meteora.Bin
+	b.ReportAllocs()
+	for b.Loop() {
+		for _, swapForY := range []bool{true, false} {
+			if _, err := bin.GetAmountOut(1_000_000, price, swapForY); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := bin.GetAmountIn(1_000_000, price, swapForY); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkGetBestPool(b *testing.B) {
+	for _, bench := range []struct {
+		pools  int
+		labels bool
+	}{{4, false}, {64, false}, {64, true}} {
+		b.Run(fmt.Sprintf("pools=%d/labels=%t", bench.pools, bench.labels), func(b *testing.B) {
+			mintA, mintB := solana.WrappedSol, solana.NewWallet().PublicKey()
+			pools := make(staticProtocol, bench.pools)
+			for i := range pools {
+				pools[i] = &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e12, ReserveB: 2e12 + uint64(i)*1e9, FeeBps: 30}
+			}
+			r := router.NewSimpleRouter(pools)
+			r.SetQuoteClient(rpc.New("http://127.0.0.1:0"))
+			r.SetLogger(pkg.DiscardLogger)
+			r.SetProfileLabels(bench.labels)
+			ctx := context.Background()
+			_, err := r.QueryAllPools(ctx, mintA.String(), mintB.String())
+			require.NoError(b, err)
+			amount := math.NewInt(1_000_000_000)
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, _, err := r.GetBestPool(ctx, nil, mintA.String(), mintB.String(), amount); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// labelledPool records the pprof labels its quotes run under
+type labelledPool struct {
+	*exampledex.Pool
+	labels chan map[string]string
+}
+
+func (p labelledPool) Quote(ctx context.Context, solClient pkg.RPC, inputMint string, amountIn math.Int) (math.Int, error) {
+	labels := make(map[string]string)
+	pprof.ForLabels(ctx, func(key, value string) bool {
+		labels[key] = value
+		return true
+	})
+	p.labels <- labels
+	return p.Pool.Quote(ctx, solClient, inputMint, amountIn)
+}
+
+func TestProfileLabels(t *testing.T) {
+	mintA, mintB := solana.WrappedSol, solana.NewWallet().PublicKey()
+	pool := labelledPool{&exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e12, ReserveB: 2e12, FeeBps: 30}, make(chan map[string]string, 1)}
+	r := router.NewSimpleRouter(staticProtocol{pool})
+	r.SetQuoteClient(rpc.New("http://127.0.0.1:0"))
+	ctx := context.Background()
+	_, err := r.QueryAllPools(ctx, mintA.String(), mintB.String())
+	require.NoError(t, err)
+
+	_, _, err = r.GetBestPool(ctx, nil, mintA.String(), mintB.String(), math.NewInt(1_000_000))
+	require.NoError(t, err)
+	assert.Empty(t, <-pool.labels, "labels are off by default")
+
+	r.SetProfileLabels(true)
+	_, _, err = r.GetBestPool(ctx, nil, mintA.String(), mintB.String(), math.NewInt(1_000_000))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		pkg.LogKeyPool:     pool.GetID(),
+		pkg.LogKeyProtocol: string(pool.ProtocolName()),
+	}, <-pool.labels)
+}
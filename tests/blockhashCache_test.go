@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expiringRPC rejects the first sends as if their blockhash had expired
+type expiringRPC struct {
+	*sol.MockRPC
+	rejections  int
+	blockhashes int
+}
+
+func (r *expiringRPC) GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+	r.blockhashes++
+	return r.MockRPC.GetLatestBlockhash(ctx, commitment)
+}
+
+func (r *expiringRPC) SendTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts rpc.TransactionOpts) (solana.Signature, error) {
+	if r.rejections > 0 {
+		r.rejections--
+		return solana.Signature{}, errors.New("Transaction simulation failed: Blockhash not found")
+	}
+	return r.MockRPC.SendTransactionWithOpts(ctx, tx, opts)
+}
+
+func TestBlockhashCacheRefreshesWhenStale(t *testing.T) {
+	mock := &expiringRPC{MockRPC: sol.NewMockRPC()}
+	fake := clock.NewFake(time.Unix(0, 0))
+	cache := sol.NewBlockhashCache(mock)
+	cache.SetClock(fake)
+	ctx := context.Background()
+
+	first, _, err := cache.Get(ctx)
+	require.NoError(t, err)
+	_, _, err = cache.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, mock.blockhashes)
+
+	fresh := solana.Hash(solana.NewWallet().PublicKey())
+	mock.SetBlockhash(fresh)
+	fake.Advance(sol.DefaultBlockhashMaxAge)
+	second, _, err := cache.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, mock.blockhashes)
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, fresh, second)
+}
+
+func TestSendTxResignsExpiredBlockhash(t *testing.T) {
+	mock := &expiringRPC{MockRPC: sol.NewMockRPC(), rejections: 1}
+	client := &sol.Client{RpcClient: mock, Blockhashes: sol.NewBlockhashCache(mock)}
+	payer := solana.NewWallet().PrivateKey
+	transfer := system.NewTransferInstruction(1, payer.PublicKey(), solana.NewWallet().PublicKey()).Build()
+	ctx := context.Background()
+
+	sig, err := client.SendTx(ctx, solana.Hash{}, []solana.PrivateKey{payer}, []solana.Instruction{transfer}, false)
+	require.NoError(t, err)
+	require.Len(t, mock.Sent(), 1)
+	assert.Equal(t, sig, mock.Sent()[0].Signatures[0])
+	assert.Equal(t, 2, mock.blockhashes, "the expired blockhash should be fetched again")
+
+	// An explicit blockhash is the caller's to manage
+	mock.rejections = 1
+	_, err = client.SendTx(ctx, solana.Hash{1}, []solana.PrivateKey{payer}, []solana.Instruction{transfer}, false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clmmmath"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"lukechampine.com/uint128"
+)
+
+// clmmPoolWithRange sets up a CLMM pool at tick 15 whose only liquidity spans ticks 0 to 30
+func clmmPoolWithRange(t testing.TB) (*raydium.CLMMPool, *sol.MockRPC) {
+	const liquidity = 1_000_000_000_000
+	sqrtPrice, err := clmmmath.SqrtPriceX64FromTick(15)
+	require.NoError(t, err)
+	pool := &raydium.CLMMPool{
+		PoolId:          solana.NewWallet().PublicKey(),
+		TokenMint0:      solana.NewWallet().PublicKey(),
+		TokenMint1:      solana.NewWallet().PublicKey(),
+		ExBitmapAddress: solana.NewWallet().PublicKey(),
+		TickSpacing:     10,
+		TickCurrent:     15,
+		FeeRate:         2500,
+		Liquidity:       uint128.From64(liquidity),
+		SqrtPriceX64:    uint128.FromBig(sqrtPrice.BigInt()),
+	}
+	pool.TickArrayBitmap[8] = 1 // the tick array starting at 0
+
+	data := make([]byte, 8+32+4+raydium.TICK_ARRAY_SIZE*raydium.TickSize+1)
+	for _, tick := range []struct {
+		offset int
+		net    int64
+	}{{0, liquidity}, {3, -liquidity}} {
+		pos := 44 + tick.offset*raydium.TickSize
+		binary.LittleEndian.PutUint32(data[pos:], uint32(tick.offset*10))
+		binary.LittleEndian.PutUint64(data[pos+4:], uint64(tick.net))
+		binary.LittleEndian.PutUint64(data[pos+20:], liquidity)
+	}
+	exBitmap := make([]byte, 8+32+2*raydium.EXTENSION_TICKARRAY_BITMAP_SIZE*64)
+	pool.ParseExBitmapInfo(exBitmap)
+	mock := sol.NewMockRPC()
+	accounts := pool.QuoteAccounts(pool.TokenMint0.String())
+	require.Len(t, accounts, 2)
+	mock.SetAccount(accounts[0], raydium.ClmmProgramID(), exBitmap)
+	mock.SetAccount(accounts[1], raydium.ClmmProgramID(), data)
+	return pool, mock
+}
+
+func TestCLMMQuotePartialFill(t *testing.T) {
+	pool, mock := clmmPoolWithRange(t)
+	ctx := context.Background()
+	mint0 := pool.TokenMint0.String()
+
+	out, err := pool.Quote(ctx, mock, mint0, math.NewInt(1_000_000))
+	require.NoError(t, err)
+	assert.True(t, out.IsPositive())
+
+	// Selling past tick 0 runs out of liquidity
+	huge := math.NewInt(10_000_000_000)
+	_, err = pool.Quote(ctx, mock, mint0, huge)
+	var partial *raydium.PartialFillError
+	require.ErrorAs(t, err, &partial)
+	assert.True(t, errors.Is(err, solerrors.ErrInsufficientLiquidity))
+	assert.True(t, partial.Filled.IsPositive() && partial.Filled.LT(huge))
+	assert.True(t, partial.AmountOut.IsPositive())
+
+	quote, err := pool.QuoteWithPriceLimit(ctx, mock, mint0, huge, math.ZeroInt())
+	require.NoError(t, err)
+	assert.True(t, quote.PartialFill)
+	assert.False(t, quote.AtLimit)
+	assert.Equal(t, partial.Filled, quote.AmountIn)
+}
+
+func TestCLMMQuoteWithPriceLimit(t *testing.T) {
+	pool, mock := clmmPoolWithRange(t)
+	ctx := context.Background()
+	mint0 := pool.TokenMint0.String()
+	limit, err := clmmmath.SqrtPriceX64FromTick(5)
+	require.NoError(t, err)
+
+	quote, err := pool.QuoteWithPriceLimit(ctx, mock, mint0, math.NewInt(10_000_000_000), limit)
+	require.NoError(t, err)
+	assert.True(t, quote.PartialFill)
+	assert.True(t, quote.AtLimit)
+	assert.Equal(t, limit, quote.SqrtPriceX64)
+
+	// Amounts the limit doesn't bind are filled in full, as without a limit
+	small := math.NewInt(1_000_000)
+	limited, err := pool.QuoteWithPriceLimit(ctx, mock, mint0, small, limit)
+	require.NoError(t, err)
+	assert.False(t, limited.PartialFill)
+	out, err := pool.Quote(ctx, mock, mint0, small)
+	require.NoError(t, err)
+	assert.Equal(t, out, limited.AmountOut)
+
+	// Selling token 0 lowers the price, so the limit must be below it
+	above, err := clmmmath.SqrtPriceX64FromTick(20)
+	require.NoError(t, err)
+	_, err = pool.QuoteWithPriceLimit(ctx, mock, mint0, small, above)
+	assert.Error(t, err)
+
+	inst := raydium.RayCLMMSwapInstruction{SqrtPriceLimitX64: uint128.FromBig(limit.BigInt())}
+	data, err := inst.Data()
+	require.NoError(t, err)
+	assert.Equal(t, limit.BigInt().Uint64(), binary.LittleEndian.Uint64(data[24:32]), "the limit is a little-endian u128")
+}
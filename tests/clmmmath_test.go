@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	cosmath "cosmossdk.io/math"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clmmmath"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bigFromString(t *testing.T, s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 10)
+	require.True(t, ok, s)
+	return n
+}
+
+// Golden vectors for the Raydium CLMM program's get_sqrt_price_at_tick; the tick range
+// ends match the program's MIN_SQRT_PRICE_X64 and MAX_SQRT_PRICE_X64
+func TestSqrtPriceX64FromTickGolden(t *testing.T) {
+	cases := []struct {
+		tick int64
+		want string
+	}{
+		{clmmmath.MinTick, "4295048016"},
+		{-200000, "837899702512935"},
+		{-1, "18445821805675395072"},
+		{0, "18446744073709551616"},
+		{1, "18447666387855957090"},
+		{2, "18448588748116922877"},
+		{100, "18539204128674375874"},
+		{12345, "34195943348793463849"},
+		{200000, "406113483392345977776134"},
+		{clmmmath.MaxTick - 1, "79222712485061176096288712065"},
+		{clmmmath.MaxTick, "79226673521066979257578248091"},
+	}
+	for _, c := range cases {
+		got, err := clmmmath.SqrtPriceX64FromTick(c.tick)
+		require.NoError(t, err)
+		assert.Equal(t, c.want, got.String(), "tick %d", c.tick)
+
+		// Cross-check against floating point sqrt(1.0001^tick) * 2^64
+		want := math.Pow(1.0001, float64(c.tick)/2) * math.Pow(2, 64)
+		gotF, _ := new(big.Float).SetInt(got.BigInt()).Float64()
+		assert.InEpsilon(t, want, gotF, 1e-9, "tick %d", c.tick)
+	}
+
+	assert.True(t, clmmmath.MinSqrtPriceX64.Equal(mustSqrtPrice(t, clmmmath.MinTick)))
+	assert.True(t, clmmmath.MaxSqrtPriceX64.Equal(mustSqrtPrice(t, clmmmath.MaxTick)))
+
+	_, err := clmmmath.SqrtPriceX64FromTick(clmmmath.MaxTick + 1)
+	assert.Error(t, err)
+	_, err = clmmmath.SqrtPriceX64FromTick(clmmmath.MinTick - 1)
+	assert.Error(t, err)
+}
+
+func mustSqrtPrice(t *testing.T, tick int64) cosmath.Int {
+	price, err := clmmmath.SqrtPriceX64FromTick(tick)
+	require.NoError(t, err)
+	return price
+}
+
+func TestTickFromSqrtPriceX64(t *testing.T) {
+	for _, tick := range []int64{clmmmath.MinTick, -200000, -12345, -1, 0, 1, 12345, 200000, clmmmath.MaxTick - 1, clmmmath.MaxTick} {
+		price := mustSqrtPrice(t, tick)
+
+		got, err := clmmmath.TickFromSqrtPriceX64(price)
+		require.NoError(t, err)
+		assert.Equal(t, tick, got, "exact price of tick %d", tick)
+
+		if tick < clmmmath.MaxTick {
+			got, err = clmmmath.TickFromSqrtPriceX64(mustSqrtPrice(t, tick+1).SubRaw(1))
+			require.NoError(t, err)
+			assert.Equal(t, tick, got, "price just below tick %d", tick+1)
+		}
+	}
+
+	_, err := clmmmath.TickFromSqrtPriceX64(clmmmath.MinSqrtPriceX64.SubRaw(1))
+	assert.Error(t, err)
+	_, err = clmmmath.TickFromSqrtPriceX64(clmmmath.MaxSqrtPriceX64.AddRaw(1))
+	assert.Error(t, err)
+}
+
+func TestMulDiv(t *testing.T) {
+	cases := []struct {
+		a, b, denominator int64
+		floor, ceil       int64
+	}{
+		{10, 10, 5, 20, 20},
+		{10, 10, 3, 33, 34},
+		{1, 1, 1000000, 0, 1},
+		{0, 7, 3, 0, 0},
+		{999999, 3000, 997000, 3009, 3010},
+	}
+	for _, c := range cases {
+		a, b, d := cosmath.NewInt(c.a), cosmath.NewInt(c.b), cosmath.NewInt(c.denominator)
+		assert.Equal(t, c.floor, clmmmath.MulDivFloor(a, b, d).Int64(), "%d*%d/%d", c.a, c.b, c.denominator)
+		assert.Equal(t, c.ceil, clmmmath.MulDivCeil(a, b, d).Int64(), "%d*%d/%d", c.a, c.b, c.denominator)
+	}
+
+	// Products beyond 128 bits must not lose precision
+	q64 := cosmath.NewIntFromBigInt(clmmmath.Q64)
+	maxU128 := cosmath.NewIntFromBigInt(clmmmath.MaxUint128)
+	assert.Equal(t, "340282366920938463463374607431768211455", clmmmath.MulDivFloor(maxU128, q64, q64).String())
+
+	assert.Panics(t, func() { clmmmath.MulDivCeil(cosmath.OneInt(), cosmath.OneInt(), cosmath.ZeroInt()) })
+	assert.Panics(t, func() { clmmmath.MulDivFloor(cosmath.OneInt(), cosmath.OneInt(), cosmath.ZeroInt()) })
+}
+
+func TestTokenAmountsFromLiquidity(t *testing.T) {
+	lower := mustSqrtPrice(t, -100).BigInt()
+	upper := mustSqrtPrice(t, 100).BigInt()
+	liquidity := big.NewInt(1_000_000_000_000)
+
+	cases := []struct {
+		name    string
+		roundUp bool
+		a, b    string
+	}{
+		{"floor", false, "9999541693", "9999541693"},
+		{"ceil", true, "9999541694", "9999541694"},
+	}
+	for _, c := range cases {
+		amountA := clmmmath.GetTokenAmountAFromLiquidity(lower, upper, liquidity, c.roundUp)
+		amountB := clmmmath.GetTokenAmountBFromLiquidity(lower, upper, liquidity, c.roundUp)
+		assert.Equal(t, c.a, amountA.String(), c.name)
+		assert.Equal(t, c.b, amountB.String(), c.name)
+
+		// Argument order does not matter
+		assert.Equal(t, amountA, clmmmath.GetTokenAmountAFromLiquidity(upper, lower, liquidity, c.roundUp))
+		assert.Equal(t, amountB, clmmmath.GetTokenAmountBFromLiquidity(upper, lower, liquidity, c.roundUp))
+	}
+}
+
+func TestNextSqrtPrice(t *testing.T) {
+	q64 := clmmmath.Q64
+
+	// Adding token B moves the price up by amount/liquidity, rounded down
+	got := clmmmath.GetNextSqrtPriceX64FromInput(q64, big.NewInt(3), big.NewInt(1), false)
+	assert.Equal(t, "24595658764946068821", got.String())
+
+	// Removing token B moves the price down by amount/liquidity, rounded up
+	got = clmmmath.GetNextSqrtPriceX64FromOutput(q64, big.NewInt(3), big.NewInt(1), true)
+	assert.Equal(t, "12297829382473034410", got.String())
+
+	// Adding token A: L*P / (L + amount*P), rounded up
+	got = clmmmath.GetNextSqrtPriceX64FromInput(q64, big.NewInt(3), big.NewInt(1), true)
+	assert.Equal(t, "13835058055282163712", got.String())
+
+	// Removing token A: L*P / (L - amount*P), rounded up
+	got = clmmmath.GetNextSqrtPriceX64FromOutput(q64, big.NewInt(3), big.NewInt(1), false)
+	assert.Equal(t, "27670116110564327424", got.String())
+
+	// A zero input leaves the price unchanged
+	got = clmmmath.GetNextSqrtPriceX64FromInput(q64, big.NewInt(3), big.NewInt(0), true)
+	assert.Equal(t, q64.String(), got.String())
+}
+
+// Golden vectors for the Whirlpool program's sqrt_price_from_tick_index, which uses its own
+// constants and so differs from Raydium in the low bits
+func TestWhirlpoolSqrtPriceX64FromTick(t *testing.T) {
+	cases := []struct {
+		tick int64
+		want string
+	}{
+		{clmmmath.MinTick, "4295048016"},
+		{-1, "18445821805675392311"},
+		{0, "18446744073709551616"},
+		{1, "18447666387855959850"},
+		{clmmmath.MaxTick, "79226673515401279992447579055"},
+	}
+	for _, c := range cases {
+		got, err := clmmmath.WhirlpoolSqrtPriceX64FromTick(c.tick)
+		require.NoError(t, err)
+		assert.Equal(t, c.want, got.String(), "tick %d", c.tick)
+	}
+
+	for _, tick := range []int64{clmmmath.MinTick, -200000, -12345, -1, 0, 1, 12345, 200000, clmmmath.MaxTick - 1} {
+		price, err := clmmmath.WhirlpoolSqrtPriceX64FromTick(tick)
+		require.NoError(t, err)
+		got, err := clmmmath.WhirlpoolTickFromSqrtPriceX64(price)
+		require.NoError(t, err)
+		assert.Equal(t, tick, got, "round trip of tick %d", tick)
+
+		got, err = clmmmath.WhirlpoolTickFromSqrtPriceX64(price.AddRaw(1))
+		require.NoError(t, err)
+		assert.Equal(t, tick, got, "price just above tick %d", tick)
+	}
+
+	_, err := clmmmath.WhirlpoolSqrtPriceX64FromTick(clmmmath.MaxTick + 1)
+	assert.Error(t, err)
+}
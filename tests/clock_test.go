@@ -0,0 +1,220 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/orca"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// refetchingProtocol discovers a new pool of the pair on every scan, so tests can tell
+// fetched pools from cached ones. Scans fail with a done context or while failing is set;
+// onScan, when set, runs after every other scan.
+type refetchingProtocol struct {
+	mintA, mintB solana.PublicKey
+	scans        atomic.Int32
+	failing      atomic.Bool
+	onScan       func()
+}
+
+func (p *refetchingProtocol) FetchPoolsByPair(ctx context.Context, baseMint, quoteMint string) ([]pkg.Pool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if p.failing.Load() {
+		return nil, fmt.Errorf("scan failed")
+	}
+	p.scans.Add(1)
+	if p.onScan != nil {
+		p.onScan()
+	}
+	return []pkg.Pool{&exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: p.mintA, MintB: p.mintB, ReserveA: 1e9, ReserveB: 1e9}}, nil
+}
+
+func (p *refetchingProtocol) FetchPoolByID(ctx context.Context, poolID string) (pkg.Pool, error) {
+	return nil, fmt.Errorf("pool %s not found", poolID)
+}
+
+func TestPoolCacheTTLOnFakeClock(t *testing.T) {
+	protocol := &refetchingProtocol{mintA: solana.NewWallet().PublicKey(), mintB: solana.NewWallet().PublicKey()}
+	mintA, mintB := protocol.mintA.String(), protocol.mintB.String()
+	fake := clock.NewFake(time.Unix(1_700_000_000, 0))
+	cache := router.NewPoolCache(time.Minute, protocol)
+	cache.SetClock(fake)
+	cache.SetLogger(pkg.DiscardLogger)
+	ctx := context.Background()
+
+	fetched, err := cache.Get(ctx, mintA, mintB)
+	require.NoError(t, err)
+	fake.Advance(time.Minute - time.Nanosecond)
+	cached, err := cache.Get(ctx, mintB, mintA)
+	require.NoError(t, err)
+	assert.Equal(t, fetched, cached, "pairs are cached in either mint order until the TTL")
+	assert.Equal(t, int32(1), protocol.scans.Load())
+
+	fake.Advance(time.Nanosecond)
+	expired, err := cache.Get(ctx, mintA, mintB)
+	require.NoError(t, err)
+	assert.NotEqual(t, fetched[0].GetID(), expired[0].GetID())
+	assert.Equal(t, int32(2), protocol.scans.Load())
+
+	// A background refresh restarts the TTL at the fake time it ran. Its first scan stops it.
+	fake.Advance(time.Minute)
+	refreshCtx, cancel := context.WithCancel(ctx)
+	protocol.onScan = cancel
+	cache.StartRefresh(refreshCtx, time.Millisecond)
+	fake.Advance(time.Millisecond)
+	require.Eventually(t, func() bool {
+		pools := cache.Pools()
+		return len(pools) == 1 && pools[0].GetID() != expired[0].GetID()
+	}, time.Second, time.Millisecond)
+	refreshed := cache.Pools()
+	scans := protocol.scans.Load()
+	fake.Advance(time.Minute - time.Nanosecond)
+	cached, err = cache.Get(ctx, mintA, mintB)
+	require.NoError(t, err)
+	assert.Equal(t, refreshed, cached)
+	assert.Equal(t, scans, protocol.scans.Load())
+}
+
+func TestWhirlpoolBackoffOnFakeClock(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	fake := clock.NewFake(start)
+	pool := &orca.WhirlpoolPool{
+		PoolId:      solana.NewWallet().PublicKey(),
+		TokenMintA:  solana.NewWallet().PublicKey(),
+		TokenMintB:  solana.NewWallet().PublicKey(),
+		TickSpacing: 64,
+		Sleeper:     fake,
+	}
+	user := solana.NewWallet().PublicKey()
+	ataA, _, err := solana.FindAssociatedTokenAddress(user, pool.TokenMintA)
+	require.NoError(t, err)
+	flaky := &flakyRPC{MockRPC: sol.NewMockRPC()}
+	flaky.SetTokenAccount(ataA, pool.TokenMintA, user, 1_000_000)
+	flaky.failures.Store(2)
+
+	// The token A account lookup is rate limited twice, backing off 100ms then 200ms on the
+	// fake clock; token B's account is missing, which isn't retried
+	instructions, err := pool.BuildSwapInstructions(context.Background(), flaky, user, pool.TokenMintA.String(), math.NewInt(1_000), math.NewInt(1))
+	require.NoError(t, err)
+	assert.Len(t, instructions, 2, "token B account creation and the swap")
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}, fake.Sleeps())
+	assert.Equal(t, start.Add(300*time.Millisecond), fake.Now())
+	assert.Equal(t, int32(4), flaky.calls.Load())
+
+	// A done context stops the backoff; accounts that couldn't be looked up are created
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	flaky.failures.Store(1)
+	instructions, err = pool.BuildSwapInstructions(ctx, flaky, user, pool.TokenMintA.String(), math.NewInt(1_000), math.NewInt(1))
+	require.NoError(t, err)
+	assert.Len(t, instructions, 3)
+	assert.Len(t, fake.Sleeps(), 2)
+}
+
+func TestFakeClockTicker(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	fake := clock.NewFake(start)
+	ticks, stop := clock.NewTicker(fake, time.Minute)
+
+	fake.Advance(time.Minute - time.Nanosecond)
+	assert.Empty(t, ticks)
+	fake.Advance(time.Nanosecond)
+	assert.Equal(t, start.Add(time.Minute), <-ticks)
+
+	// Like time.Ticker, ticks nobody received are dropped
+	fake.Advance(3 * time.Minute)
+	assert.Equal(t, start.Add(2*time.Minute), <-ticks)
+	assert.Empty(t, ticks)
+	require.NoError(t, fake.Sleep(context.Background(), time.Minute))
+	assert.Equal(t, start.Add(5*time.Minute), <-ticks, "sleeping moves tickers too")
+
+	stop()
+	fake.Advance(time.Hour)
+	assert.Empty(t, ticks)
+}
+
+func TestPoolCacheRefreshOnFakeClock(t *testing.T) {
+	protocol := &refetchingProtocol{mintA: solana.NewWallet().PublicKey(), mintB: solana.NewWallet().PublicKey()}
+	fake := clock.NewFake(time.Unix(1_700_000_000, 0))
+	cache := router.NewPoolCache(time.Hour, protocol)
+	cache.SetClock(fake)
+	cache.SetLogger(pkg.DiscardLogger)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err := cache.Get(ctx, protocol.mintA.String(), protocol.mintB.String())
+	require.NoError(t, err)
+
+	cache.StartRefresh(ctx, time.Minute)
+	assert.Never(t, func() bool { return protocol.scans.Load() > 1 }, 20*time.Millisecond, time.Millisecond,
+		"the refresh waits for the fake interval")
+	fake.Advance(time.Minute)
+	require.Eventually(t, func() bool { return protocol.scans.Load() == 2 }, time.Second, time.Millisecond)
+	fake.Advance(time.Minute)
+	require.Eventually(t, func() bool { return protocol.scans.Load() == 3 }, time.Second, time.Millisecond)
+}
+
+func TestRouterOnFakeClock(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	fake := clock.NewFake(start)
+	base, quote := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	// Opened half an hour before the fake time, years before the wall clock's
+	young := &raydium.CPMMPool{PoolId: solana.NewWallet().PublicKey(), Token0Mint: base, Token1Mint: quote, OpenTime: uint64(start.Add(-30 * time.Minute).Unix())}
+	pool := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: base, MintB: quote, ReserveA: 1e9, ReserveB: 1e9, FeeBps: 30}
+	r := router.NewSimpleRouter(staticProtocol{young, pool})
+	r.SetQuoteClient(sol.NewMockRPC())
+	r.SetMintDecimals(base.String(), 9)
+	r.SetMintDecimals(quote.String(), 9)
+	r.SetLogger(pkg.DiscardLogger)
+	r.SetClock(fake)
+	r.SetLiquidityFilter(router.LiquidityFilter{MinAge: time.Hour})
+	ctx := context.Background()
+
+	pools, err := r.QueryAllPools(ctx, base.String(), quote.String())
+	require.NoError(t, err)
+	assert.Equal(t, []pkg.Pool{pool}, pools, "pools are aged by the router's clock")
+	fake.Advance(30 * time.Minute)
+	pools, err = r.QueryAllPools(ctx, base.String(), quote.String())
+	require.NoError(t, err)
+	assert.Len(t, pools, 2)
+
+	result, err := r.Quote(ctx, nil, base.String(), quote.String(), math.NewInt(1_000_000), pkg.SlippageBps(100))
+	require.NoError(t, err)
+	assert.Equal(t, fake.Now(), result.Route.QuotedAt)
+	fake.Advance(5 * time.Second)
+	refresh, err := r.RefreshQuote(ctx, nil, result)
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, refresh.Age)
+	assert.Equal(t, fake.Now(), refresh.Route.QuotedAt)
+}
+
+func TestPoolRegistryMaxAgeOnFakeClock(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1_700_000_000, 0))
+	pool := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: solana.NewWallet().PublicKey(), MintB: solana.NewWallet().PublicKey()}
+	base, quote := pool.MintA.String(), pool.MintB.String()
+	registry := router.NewPoolRegistry("")
+	registry.SetClock(fake)
+	registry.SetMaxAge(time.Hour)
+
+	registry.Record("example_dex", base, quote, []pkg.Pool{pool})
+	fake.Advance(time.Hour)
+	_, ok := registry.Lookup("example_dex", base, quote)
+	assert.True(t, ok)
+	fake.Advance(time.Nanosecond)
+	_, ok = registry.Lookup("example_dex", base, quote)
+	assert.False(t, ok, "pairs older than the max age are scanned again")
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/orca"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/gtdvccc/SolRouteTmp/pkg/protocol"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterProgramIDs(t *testing.T) {
+	assert.Equal(t, sol.ClusterCustom, sol.DetectCluster("http://127.0.0.1:8899"))
+	cluster, err := sol.ParseCluster("mainnet-beta")
+	require.NoError(t, err)
+	assert.Equal(t, sol.ClusterMainnet, cluster)
+	_, err = sol.ParseCluster("localnet")
+	assert.Error(t, err)
+
+	// A CLMM pool deployed under the devnet program
+	var layout raydium.CLMMPool
+	mint0, mint1, ammConfig := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	data := make([]byte, layout.Span())
+	copy(data[layout.Offset("AmmConfig"):], ammConfig.Bytes())
+	copy(data[layout.Offset("TokenMint0"):], mint0.Bytes())
+	copy(data[layout.Offset("TokenMint1"):], mint1.Bytes())
+	mock := sol.NewMockRPC()
+	mock.SetAccount(solana.NewWallet().PublicKey(), raydium.ClmmDevnetProgramID(), data)
+	mock.SetAccount(ammConfig, raydium.ClmmDevnetProgramID(), make([]byte, 117))
+	ctx := context.Background()
+
+	// Clients without a cluster are mainnet's, which doesn't see the pool
+	pools, err := protocol.NewRaydiumClmm(&sol.Client{RpcClient: mock}).FetchPoolsByPair(ctx, mint0.String(), mint1.String())
+	require.NoError(t, err)
+	assert.Empty(t, pools)
+
+	devnet := &sol.Client{RpcClient: mock, Cluster: sol.ClusterDevnet}
+	pools, err = protocol.NewRaydiumClmm(devnet).FetchPoolsByPair(ctx, mint0.String(), mint1.String())
+	require.NoError(t, err)
+	require.Len(t, pools, 1)
+	pool := pools[0].(*raydium.CLMMPool)
+	assert.Equal(t, raydium.ClmmDevnetProgramID(), pool.GetProgramID())
+	exBitmap, _, err := raydium.GetPdaExBitmapAccount(raydium.ClmmDevnetProgramID(), pool.PoolId)
+	require.NoError(t, err)
+	assert.Equal(t, exBitmap, pool.ExBitmapAddress)
+	assert.Equal(t, raydium.ClmmProgramID(), (&raydium.CLMMPool{}).GetProgramID(), "pools without a program ID are mainnet's")
+
+	// Protocols not deployed on the cluster say so rather than scanning another program
+	testnet := &sol.Client{RpcClient: mock, Cluster: sol.ClusterTestnet}
+	_, err = protocol.NewRaydiumClmm(testnet).FetchPoolsByPair(ctx, mint0.String(), mint1.String())
+	assert.ErrorContains(t, err, "not deployed on testnet")
+	orcaWhirlpool := protocol.NewOrcaWhirlpool(testnet)
+	_, err = orcaWhirlpool.FetchPoolByID(ctx, solana.NewWallet().PublicKey().String())
+	assert.ErrorContains(t, err, "not deployed on testnet")
+	require.NoError(t, orcaWhirlpool.SetCluster(sol.ClusterDevnet))
+	_, err = orcaWhirlpool.FetchPoolsByPair(ctx, mint0.String(), mint1.String())
+	assert.NoError(t, err)
+
+	// An explicit program ID serves deployments the clusters don't know
+	clmm := protocol.NewRaydiumClmm(testnet)
+	clmm.SetProgramID(raydium.ClmmDevnetProgramID())
+	pools, err = clmm.FetchPoolsByPair(ctx, mint0.String(), mint1.String())
+	require.NoError(t, err)
+	assert.Len(t, pools, 1)
+
+	// Whirlpool pools derive their accounts under their own program
+	programID := solana.NewWallet().PublicKey()
+	whirlpool := &orca.WhirlpoolPool{PoolId: solana.NewWallet().PublicKey(), ProgramID: programID, TickSpacing: 64}
+	tickArray, err := orca.DeriveWhirlpoolTickArrayPDA(programID, whirlpool.PoolId, 0)
+	require.NoError(t, err)
+	assert.Contains(t, whirlpool.QuoteAccounts(""), tickArray)
+}
@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/pkg/protocol"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gtdvccc/SolRouteTmp/tests/fixtures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// commitmentSpy records the commitment of each account read and blockhash fetch
+type commitmentSpy struct {
+	*sol.MockRPC
+
+	mu          sync.Mutex
+	reads       []rpc.CommitmentType
+	blockhashes []rpc.CommitmentType
+}
+
+func (s *commitmentSpy) read(commitment rpc.CommitmentType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reads = append(s.reads, commitment)
+}
+
+func (s *commitmentSpy) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	s.read("")
+	return s.MockRPC.GetAccountInfo(ctx, account)
+}
+
+func (s *commitmentSpy) GetAccountInfoWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetAccountInfoOpts) (*rpc.GetAccountInfoResult, error) {
+	var commitment rpc.CommitmentType
+	if opts != nil {
+		commitment = opts.Commitment
+	}
+	s.read(commitment)
+	return s.MockRPC.GetAccountInfoWithOpts(ctx, account, opts)
+}
+
+func (s *commitmentSpy) GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error) {
+	var commitment rpc.CommitmentType
+	if opts != nil {
+		commitment = opts.Commitment
+	}
+	s.read(commitment)
+	return s.MockRPC.GetMultipleAccountsWithOpts(ctx, accounts, opts)
+}
+
+func (s *commitmentSpy) GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+	s.mu.Lock()
+	s.blockhashes = append(s.blockhashes, commitment)
+	s.mu.Unlock()
+	return s.MockRPC.GetLatestBlockhash(ctx, commitment)
+}
+
+func (s *commitmentSpy) take() (reads, blockhashes []rpc.CommitmentType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reads, blockhashes = s.reads, s.blockhashes
+	s.reads, s.blockhashes = nil, nil
+	return reads, blockhashes
+}
+
+func TestClientConfigSetsCommitments(t *testing.T) {
+	fixture, err := fixtures.Load("fixtures/testdata/raydium_cpmm_wsol_usdc.json")
+	require.NoError(t, err)
+	spy := &commitmentSpy{MockRPC: fixture.RPC()}
+	client := &sol.Client{RpcClient: spy, Blockhashes: sol.NewBlockhashCache(spy)}
+	ctx := context.Background()
+	swap := fixture.Swaps[0]
+
+	// Unconfigured, each call keeps its own commitment
+	pool, err := protocol.NewRaydiumCpmm(client).FetchPoolByID(ctx, fixture.Pool.String())
+	require.NoError(t, err)
+	_, err = pool.Quote(ctx, client.RpcClient, swap.InputMint, swap.AmountIn)
+	require.NoError(t, err)
+	_, _, err = client.Blockhashes.Get(ctx)
+	require.NoError(t, err)
+	reads, blockhashes := spy.take()
+	// The pool and its config, then the vaults the quote reads
+	assert.Equal(t, []rpc.CommitmentType{"", "", rpc.CommitmentProcessed}, reads)
+	assert.Equal(t, []rpc.CommitmentType{rpc.CommitmentConfirmed}, blockhashes)
+
+	config := sol.Config{ReadCommitment: rpc.CommitmentConfirmed, ExecuteCommitment: rpc.CommitmentFinalized}
+	client.SetConfig(config)
+	assert.Equal(t, config, client.Config())
+	client.Blockhashes.Invalidate()
+
+	pool, err = protocol.NewRaydiumCpmm(client).FetchPoolByID(ctx, fixture.Pool.String())
+	require.NoError(t, err)
+	quoted, err := pool.Quote(ctx, client.RpcClient, swap.InputMint, swap.AmountIn)
+	require.NoError(t, err)
+	assert.Equal(t, swap.AmountOut, quoted)
+	_, _, err = client.Blockhashes.Get(ctx)
+	require.NoError(t, err)
+	reads, blockhashes = spy.take()
+	assert.Equal(t, []rpc.CommitmentType{rpc.CommitmentConfirmed, rpc.CommitmentConfirmed, rpc.CommitmentConfirmed}, reads)
+	assert.Equal(t, []rpc.CommitmentType{rpc.CommitmentFinalized}, blockhashes)
+
+	// Read options attached to the context take precedence
+	pinned := sol.WithReadOpts(ctx, sol.ReadOpts{Commitment: rpc.CommitmentProcessed})
+	_, err = pool.Quote(pinned, client.RpcClient, swap.InputMint, swap.AmountIn)
+	require.NoError(t, err)
+	reads, _ = spy.take()
+	assert.Equal(t, []rpc.CommitmentType{rpc.CommitmentProcessed}, reads)
+
+	// Setting the config again replaces it rather than stacking another wrapper
+	client.SetConfig(sol.Config{})
+	_, err = pool.Quote(ctx, client.RpcClient, swap.InputMint, swap.AmountIn)
+	require.NoError(t, err)
+	reads, _ = spy.take()
+	assert.Equal(t, []rpc.CommitmentType{rpc.CommitmentProcessed}, reads)
+
+	_, ok := client.RpcClient.(sol.TransactionReader)
+	assert.True(t, ok, "configured client must still fetch transactions")
+}
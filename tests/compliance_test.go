@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlocklistAndComplianceHook(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	deep := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 2e9}
+	shallow := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 1e9}
+
+	r := router.NewSimpleRouter(staticProtocol{deep, shallow})
+	r.SetQuoteClient(rpc.New("http://127.0.0.1:0"))
+	ctx := context.Background()
+	_, err := r.QueryAllPools(ctx, mintA.String(), mintB.String())
+	require.NoError(t, err)
+	quote := func() (pkg.Pool, error) {
+		best, _, err := r.GetBestPool(ctx, nil, mintA.String(), mintB.String(), math.NewInt(1000))
+		return best, err
+	}
+
+	blocklist := router.NewBlocklist()
+	r.SetBlocklist(blocklist)
+	blocklist.BlockPool(deep.GetID())
+	best, err := quote()
+	require.NoError(t, err)
+	assert.Equal(t, shallow, best)
+
+	blocklist.BlockMint(mintB.String())
+	_, err = quote()
+	assert.ErrorIs(t, err, router.ErrBlocked)
+	blocklist.Unblock(mintB.String())
+	blocklist.Unblock(deep.GetID())
+
+	// The hook sees each candidate and can veto it
+	r.SetComplianceHook(func(ctx context.Context, route router.RouteCandidate) error {
+		if route.Pools[0] == deep {
+			return errors.New("pool under review")
+		}
+		return nil
+	})
+	best, err = quote()
+	require.NoError(t, err)
+	assert.Equal(t, shallow, best)
+
+	r.SetComplianceHook(func(ctx context.Context, route router.RouteCandidate) error {
+		return errors.New("sanctioned counterparty")
+	})
+	_, err = quote()
+	assert.ErrorIs(t, err, router.ErrRouteVetoed)
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeBudgetManagerClamping(t *testing.T) {
+	payer := solana.NewWallet().PublicKey()
+	transfer := system.NewTransferInstruction(1, payer, solana.NewWallet().PublicKey()).Build()
+	mock := sol.NewMockRPC()
+	budget := sol.NewComputeBudgetManager(&sol.Client{RpcClient: mock})
+	ctx := context.Background()
+	estimate := func(consumed uint64) uint32 {
+		mock.SetSimulation(rpc.SimulateTransactionResult{UnitsConsumed: &consumed})
+		units, err := budget.EstimateComputeUnits(ctx, payer, []solana.Instruction{transfer})
+		require.NoError(t, err)
+		return units
+	}
+
+	// 10% margin by default, floored at the default minimum and capped at the transaction limit
+	assert.Equal(t, uint32(110_000), estimate(100_000))
+	assert.Equal(t, uint32(sol.DefaultMinComputeUnitLimit), estimate(1_000))
+	assert.Equal(t, uint32(sol.MaxComputeUnitLimit), estimate(1_300_000))
+
+	budget.SetSafetyMarginBps(0)
+	budget.SetUnitLimitBounds(50_000, 200_000)
+	assert.Equal(t, uint32(50_000), estimate(49_999))
+	assert.Equal(t, uint32(150_000), estimate(150_000))
+	assert.Equal(t, uint32(200_000), estimate(200_001))
+
+	// A minimum above the maximum collapses to it; no maximum means the transaction limit
+	budget.SetUnitLimitBounds(300_000, 200_000)
+	assert.Equal(t, uint32(200_000), estimate(1))
+	budget.SetUnitLimitBounds(0, 0)
+	assert.Equal(t, uint32(sol.MaxComputeUnitLimit), estimate(2_000_000))
+
+	// The simulation runs with the maximum limit in place of the caller's budget instructions
+	simulated := mock.Simulated()
+	limitData := simulated[len(simulated)-1].Message.Instructions[0].Data
+	assert.Equal(t, uint32(sol.MaxComputeUnitLimit), binary.LittleEndian.Uint32(limitData[1:]))
+
+	mock.SetSimulation(rpc.SimulateTransactionResult{Err: "InstructionError"})
+	_, err := budget.EstimateComputeUnits(ctx, payer, []solana.Instruction{transfer})
+	assert.ErrorContains(t, err, "simulation failed")
+	mock.SetSimulation(rpc.SimulateTransactionResult{})
+	_, err = budget.EstimateComputeUnits(ctx, payer, []solana.Instruction{transfer})
+	assert.ErrorContains(t, err, "did not report consumed units")
+}
+
+func TestComputeBudgetManagerPriorityFee(t *testing.T) {
+	payer := solana.NewWallet().PublicKey()
+	transfer := system.NewTransferInstruction(1, payer, solana.NewWallet().PublicKey()).Build()
+	consumed := uint64(100_000)
+	mock := sol.NewMockRPC()
+	mock.SetSimulation(rpc.SimulateTransactionResult{UnitsConsumed: &consumed})
+	budget := sol.NewComputeBudgetManager(&sol.Client{RpcClient: mock})
+	ctx := context.Background()
+	stale := computebudget.NewSetComputeUnitLimitInstruction(1).Build()
+	apply := func() (price uint64, units uint32, rest []solana.Instruction) {
+		insts, err := budget.Apply(ctx, payer, []solana.Instruction{stale, transfer})
+		require.NoError(t, err)
+		for i, inst := range insts {
+			data, err := inst.Data()
+			require.NoError(t, err)
+			switch {
+			case !inst.ProgramID().Equals(solana.ComputeBudget):
+				return price, units, insts[i:]
+			case data[0] == computebudget.Instruction_SetComputeUnitPrice:
+				price = binary.LittleEndian.Uint64(data[1:])
+			case data[0] == computebudget.Instruction_SetComputeUnitLimit:
+				units = binary.LittleEndian.Uint32(data[1:])
+			}
+		}
+		return price, units, nil
+	}
+
+	// Without a priority fee only the limit is set, replacing the caller's
+	price, units, rest := apply()
+	assert.Zero(t, price)
+	assert.Equal(t, uint32(110_000), units)
+	assert.Equal(t, []solana.Instruction{transfer}, rest)
+
+	budget.SetPriorityFeeStrategy(sol.FixedPriorityFee(50_000))
+	price, _, _ = apply()
+	assert.Equal(t, uint64(50_000), price)
+
+	// The cap clamps spikes and leaves cheaper prices alone; zero removes it
+	budget.SetMaxComputeUnitPrice(10_000)
+	price, _, _ = apply()
+	assert.Equal(t, uint64(10_000), price)
+	budget.SetPriorityFeeStrategy(sol.FixedPriorityFee(5_000))
+	price, _, _ = apply()
+	assert.Equal(t, uint64(5_000), price)
+	budget.SetPriorityFeeStrategy(sol.FixedPriorityFee(50_000))
+	budget.SetMaxComputeUnitPrice(0)
+	price, _, _ = apply()
+	assert.Equal(t, uint64(50_000), price)
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeUnitModelSplitsRoutes(t *testing.T) {
+	hop := func(name pkg.ProtocolName) pkg.Pool {
+		return pluginPool{&exampledex.Pool{ID: solana.NewWallet().PublicKey()}, name}
+	}
+	model := router.NewComputeUnitModel()
+	model.SetOverhead(100_000)
+	model.SetEstimate(pkg.ProtocolNameRaydiumClmm, 400_000)
+	model.SetEstimate(pkg.ProtocolNameRaydiumCpmm, 200_000)
+
+	route := []pkg.Pool{
+		hop(pkg.ProtocolNameRaydiumClmm),
+		hop(pkg.ProtocolNameRaydiumClmm),
+		hop(pkg.ProtocolNameRaydiumClmm),
+		hop(pkg.ProtocolNameRaydiumCpmm),
+	}
+	assert.Equal(t, uint64(1_500_000), model.RouteUnits(route))
+	assert.ErrorIs(t, model.CheckRoute(route), router.ErrComputeBudgetExceeded)
+	require.NoError(t, model.CheckRoute(route[:3]))
+
+	groups, err := model.SplitRoute(route)
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Len(t, groups[0], 3)
+	assert.Equal(t, route[3], groups[1][0])
+
+	model.SetEstimate(pkg.ProtocolNameRaydiumClmm, 1_350_000)
+	_, err = model.SplitRoute(route)
+	assert.ErrorIs(t, err, router.ErrComputeBudgetExceeded)
+}
+
+func TestComputeUnitModelObservesSimulations(t *testing.T) {
+	model := router.NewComputeUnitModel()
+	model.SetOverhead(20_000)
+	pools := []pkg.Pool{
+		pluginPool{&exampledex.Pool{}, "plugin_a"},
+		pluginPool{&exampledex.Pool{}, "plugin_b"},
+	}
+	// Unknown protocols start out equal, so the measured swap units are split evenly
+	model.ObserveSimulation(pools, 220_000)
+	assert.Equal(t, uint32(100_000), model.Estimate("plugin_a"))
+	assert.Equal(t, uint32(100_000), model.Estimate("plugin_b"))
+
+	// Later measurements move the estimate part of the way
+	model.Observe("plugin_a", 200_000)
+	assert.Equal(t, uint32(130_000), model.Estimate("plugin_a"))
+}
+
+func TestComputeUnitPriceCosts(t *testing.T) {
+	mint := solana.NewWallet().PublicKey()
+	// The heavy venue pays out a little more, about 10000 lamports on this swap
+	heavy := pluginPool{&exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mint, MintB: solana.WrappedSol, ReserveA: 1e12, ReserveB: 1.001e12}, "plugin_heavy"}
+	lean := pluginPool{&exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mint, MintB: solana.WrappedSol, ReserveA: 1e12, ReserveB: 1e12}, "plugin_lean"}
+	model := router.NewComputeUnitModel()
+	model.SetEstimate("plugin_heavy", 400_000)
+	model.SetEstimate("plugin_lean", 50_000)
+	r := router.NewSimpleRouter(staticProtocol{heavy, lean})
+	r.SetQuoteClient(rpc.New("http://127.0.0.1:0"))
+	r.SetComputeUnitModel(model)
+	ctx := context.Background()
+	_, err := r.QueryAllPools(ctx, mint.String(), solana.WrappedSol.String())
+	require.NoError(t, err)
+	bestPool := func(price uint64) string {
+		best, _, err := r.GetBestPoolWithOptions(ctx, nil, mint.String(), solana.WrappedSol.String(), math.NewInt(1e7), router.RouteOptions{
+			Costs: &router.SwapCosts{ComputeUnitPrice: price},
+		})
+		require.NoError(t, err)
+		return best.GetID()
+	}
+
+	assert.Equal(t, heavy.GetID(), bestPool(1_000))
+	// At 0.1 lamports a unit the heavy venue's 350000 extra units cost 35000 lamports
+	assert.Equal(t, lean.GetID(), bestPool(100_000))
+
+	// Simulations of executed swaps refine the estimate routing uses
+	consumed := uint64(90_000)
+	mock := &scriptedSimRPC{MockRPC: sol.NewMockRPC(), results: []rpc.SimulateTransactionResult{{UnitsConsumed: &consumed}}}
+	client := &sol.Client{RpcClient: mock}
+	_, err = client.ExecuteSwap(ctx, heavy, sol.LocalSigners(solana.NewWallet().PrivateKey), mint.String(), math.NewInt(1000), sol.ExecuteOptions{DryRun: true, ComputeUnits: model})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(400_000*7/10+(90_000-router.DefaultTxOverheadComputeUnits)*3/10), model.Estimate("plugin_heavy"))
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/config"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestConfigLoad(t *testing.T) {
+	for _, key := range []string{config.EnvConfigPath, config.EnvCluster, config.EnvRPCURL, config.EnvWSURL, config.EnvDiscoveryURL, config.EnvRateLimit,
+		config.EnvProtocols, config.EnvSlippageBps, config.EnvPriorityFeeTier, config.EnvPriorityFeePrice} {
+		t.Setenv(key, "")
+	}
+
+	cfg, err := config.Load("")
+	require.NoError(t, err)
+	assert.Equal(t, config.Default(), cfg)
+
+	path := writeConfig(t, "solroute.yaml", `
+rpc:
+  url: https://rpc.example.com
+  discoveryUrl: https://scan.example.com
+cluster: devnet
+protocols: [raydium_clmm, orca_whirlpool]
+slippageBps: 30
+commitment:
+  read: confirmed
+rateLimit:
+  requestsPerSecond: 20
+priorityFee:
+  tier: fast
+`)
+	cfg, err = config.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "https://rpc.example.com", cfg.RPC.URL)
+	assert.Equal(t, config.DefaultWSURL, cfg.RPC.WSURL, "unset keys keep their defaults")
+	assert.Equal(t, []string{"raydium_clmm", "orca_whirlpool"}, cfg.Protocols)
+	assert.EqualValues(t, 30, cfg.SlippageBps)
+	assert.Equal(t, sol.ClusterDevnet, cfg.Cluster)
+	assert.Equal(t, "confirmed", cfg.Commitment.Read)
+	assert.Equal(t, sol.FeeTierFast, cfg.PriorityFee.Tier)
+
+	// The environment overrides the file
+	t.Setenv(config.EnvRPCURL, "https://other.example.com")
+	t.Setenv(config.EnvPriorityFeePrice, "5000")
+	cfg, err = config.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "https://other.example.com", cfg.RPC.URL)
+	assert.Equal(t, config.PriorityFeeConfig{MicroLamports: 5000}, cfg.PriorityFee)
+	t.Setenv(config.EnvRPCURL, "")
+	t.Setenv(config.EnvPriorityFeePrice, "")
+
+	cfg, err = config.Load(writeConfig(t, "solroute.json", `{"slippageBps": 75}`))
+	require.NoError(t, err)
+	assert.EqualValues(t, 75, cfg.SlippageBps)
+
+	for name, content := range map[string]string{
+		"unknown key":      "slipageBps: 30",
+		"unknown protocol": "protocols: [orca_whirpool]",
+		"bad url":          "rpc: {url: rpc.example.com}",
+		"bad commitment":   "commitment: {execute: recent}",
+		"bad cluster":      "cluster: localnet",
+		"slippage":         "slippageBps: 10000",
+		"two fee prices":   "priorityFee: {tier: fast, microLamports: 1000}",
+	} {
+		_, err := config.Load(writeConfig(t, "solroute.yaml", content))
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/gtdvccc/SolRouteTmp/pkg/protocol"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCPMMTradeFeeFromAmmConfig(t *testing.T) {
+	mint0, mint1 := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	mock := sol.NewMockRPC()
+	// A 1% config, far from the AMM v4 fee of 0.25%
+	config := solana.NewWallet().PublicKey()
+	configData := make([]byte, 8+4+4*8+2*32+16*8)
+	binary.LittleEndian.PutUint64(configData[12:], 10_000)
+	mock.SetAccount(config, raydium.RAYDIUM_CPMM_PROGRAM_ID, configData)
+
+	var layout raydium.CPMMPool
+	newPool := func(ammConfig solana.PublicKey) solana.PublicKey {
+		id, vault0, vault1 := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+		data := make([]byte, layout.Span())
+		for field, key := range map[string]solana.PublicKey{
+			"AmmConfig": ammConfig, "Token0Vault": vault0, "Token1Vault": vault1, "Token0Mint": mint0, "Token1Mint": mint1,
+		} {
+			copy(data[layout.Offset(field):], key.Bytes())
+		}
+		mock.SetAccount(id, raydium.RAYDIUM_CPMM_PROGRAM_ID, data)
+		mock.SetTokenAccount(vault0, mint0, id, 1_000_000_000)
+		mock.SetTokenAccount(vault1, mint1, id, 2_000_000_000)
+		return id
+	}
+	id := newPool(config)
+	newPool(solana.NewWallet().PublicKey()) // its config doesn't exist
+	cpmm := protocol.NewRaydiumCpmm(&sol.Client{RpcClient: mock})
+	ctx := context.Background()
+
+	pools, err := cpmm.FetchPoolsByPair(ctx, mint0.String(), mint1.String())
+	require.NoError(t, err)
+	require.Len(t, pools, 1, "pools without a readable config are left out")
+	pool := pools[0].(*raydium.CPMMPool)
+	assert.Equal(t, id, pool.PoolId)
+	assert.Equal(t, uint64(10_000), pool.TradeFeeRate)
+
+	// The 10000 fee is rounded up and taken from the input before the constant product
+	out, err := pool.Quote(ctx, mock, mint0.String(), math.NewInt(1_000_000))
+	require.NoError(t, err)
+	assert.Equal(t, "1978041", out.String())
+
+	// Exact-out grosses up by the same config fee and inverts the exact-in quote
+	in, err := pool.QuoteExactOut(ctx, mock, mint1.String(), out)
+	require.NoError(t, err)
+	assert.Equal(t, "1000000", in.String())
+	short, err := pool.Quote(ctx, mock, mint0.String(), in.SubRaw(1))
+	require.NoError(t, err)
+	assert.True(t, short.LT(out))
+
+	fetched, err := cpmm.FetchPoolByID(ctx, id.String())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10_000), fetched.(*raydium.CPMMPool).TradeFeeRate)
+	binary.LittleEndian.PutUint64(configData[12:], 1_000_000)
+	mock.SetAccount(config, raydium.RAYDIUM_CPMM_PROGRAM_ID, configData)
+	_, err = cpmm.FetchPoolByID(ctx, id.String())
+	assert.ErrorContains(t, err, "invalid trade fee rate")
+}
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stalledProtocol never answers until its context is cancelled
+type stalledProtocol struct {
+	staticProtocol
+}
+
+func (p stalledProtocol) FetchPoolsByPair(ctx context.Context, baseMint, quoteMint string) ([]pkg.Pool, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestDiscoveryBudget(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	pool := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 1e9}
+	fast := staticProtocol{pool}
+	slow := stalledProtocol{}
+	ctx := context.Background()
+
+	for _, cached := range []bool{false, true} {
+		r := router.NewSimpleRouter(fast, slow)
+		if cached {
+			r.SetPoolCache(router.NewPoolCache(time.Minute, fast, slow))
+		}
+		r.SetDiscoveryBudget(50 * time.Millisecond)
+
+		start := time.Now()
+		discovery, err := r.DiscoverPools(ctx, mintA.String(), mintB.String())
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), 5*time.Second)
+		assert.True(t, discovery.Partial())
+		assert.Equal(t, []pkg.Protocol{slow}, discovery.TimedOut)
+		assert.Equal(t, []pkg.Pool{pool}, discovery.Pools)
+
+		if cached {
+			// The partial result wasn't cached, so the slow protocol is asked again
+			discovery, err = r.DiscoverPools(ctx, mintA.String(), mintB.String())
+			require.NoError(t, err)
+			assert.True(t, discovery.Partial())
+		}
+	}
+}
+
+// flakyProtocol fails until healed
+type flakyProtocol struct {
+	staticProtocol
+	healed bool
+	calls  atomic.Int32
+}
+
+func (p *flakyProtocol) FetchPoolsByPair(ctx context.Context, baseMint, quoteMint string) ([]pkg.Pool, error) {
+	p.calls.Add(1)
+	if !p.healed {
+		return nil, errors.New("rpc unavailable")
+	}
+	return p.staticProtocol, nil
+}
+
+func TestProtocolTimeoutAndCircuitBreaker(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	pool := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 1e9}
+	fast := staticProtocol{pool}
+	slow := stalledProtocol{}
+	flaky := &flakyProtocol{}
+	clk := clock.NewFake(time.Unix(1_700_000_000, 0))
+	breaker := router.NewCircuitBreaker(2, time.Minute)
+	breaker.SetClock(clk)
+	ctx := context.Background()
+
+	r := router.NewSimpleRouter(fast, slow, flaky)
+	r.SetProtocolTimeout(50 * time.Millisecond)
+	r.SetCircuitBreaker(breaker)
+
+	discovery, err := r.DiscoverPools(ctx, mintA.String(), mintB.String())
+	require.NoError(t, err)
+	assert.Equal(t, []pkg.Pool{pool}, discovery.Pools)
+	assert.False(t, discovery.Partial(), "a protocol timeout isn't the discovery budget")
+	require.Len(t, discovery.Failed, 2)
+	assert.Equal(t, pkg.Protocol(slow), discovery.Failed[0].Protocol)
+	assert.ErrorIs(t, discovery.Failed[0].Err, context.DeadlineExceeded)
+	assert.Equal(t, pkg.Protocol(flaky), discovery.Failed[1].Protocol)
+	assert.ErrorContains(t, discovery.Err(), "rpc unavailable")
+	assert.Empty(t, discovery.Skipped)
+
+	// The second failure in a row opens both circuits
+	_, err = r.DiscoverPools(ctx, mintA.String(), mintB.String())
+	require.NoError(t, err)
+	assert.Len(t, breaker.Open(), 2)
+
+	start := time.Now()
+	discovery, err = r.DiscoverPools(ctx, mintA.String(), mintB.String())
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 50*time.Millisecond, "open circuits aren't waited on")
+	assert.Equal(t, []pkg.Protocol{slow, flaky}, discovery.Skipped)
+	assert.Empty(t, discovery.Failed)
+	assert.NoError(t, discovery.Err())
+	assert.Equal(t, int32(2), flaky.calls.Load())
+
+	// After the cooldown a recovered protocol closes its circuit
+	flaky.healed = true
+	flaky.staticProtocol = staticProtocol{pool}
+	clk.Advance(time.Minute)
+	discovery, err = r.DiscoverPools(ctx, mintA.String(), mintB.String())
+	require.NoError(t, err)
+	assert.Empty(t, discovery.Skipped)
+	require.Len(t, discovery.Failed, 1)
+	assert.Equal(t, pkg.Protocol(slow), discovery.Failed[0].Protocol)
+	assert.Equal(t, []pkg.Protocol{slow}, breaker.Open())
+}
+
+func TestQueryAllPoolsStream(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	pool := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 1e9}
+	fast := staticProtocol{pool}
+	slow := stalledProtocol{}
+
+	// The fast protocol's pool arrives while the slow one is still scanning
+	ctx, cancel := context.WithCancel(context.Background())
+	r := router.NewSimpleRouter(fast, slow)
+	pools := r.QueryAllPoolsStream(ctx, mintA.String(), mintB.String())
+	select {
+	case got := <-pools:
+		assert.Equal(t, pkg.Pool(pool), got)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no pool streamed before the slow protocol answered")
+	}
+	cancel()
+	for range pools {
+	}
+
+	// Once the stream closes the router knows the streamed pools
+	r = router.NewSimpleRouter(fast, slow)
+	r.SetDiscoveryBudget(50 * time.Millisecond)
+	var streamed []pkg.Pool
+	for got := range r.QueryAllPoolsStream(context.Background(), mintA.String(), mintB.String()) {
+		streamed = append(streamed, got)
+	}
+	assert.Equal(t, []pkg.Pool{pool}, streamed)
+	best, _, err := r.GetBestPool(context.Background(), sol.NewMockRPC(), mintA.String(), mintB.String(), math.NewInt(1_000))
+	require.NoError(t, err)
+	assert.Equal(t, pool.GetID(), best.GetID())
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/meteora"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDlmmDynamicFee(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	pool := &meteora.MeteoraDlmmPool{PoolId: solana.NewWallet().PublicKey(), TimeSource: clock.NewFake(now)}
+	data := make([]byte, pool.Span())
+	put16 := func(field string, v uint16) { binary.LittleEndian.PutUint16(data[pool.Offset(field):], v) }
+	put32 := func(field string, v uint32) { binary.LittleEndian.PutUint32(data[pool.Offset(field):], v) }
+	put16("Parameters.BaseFactor", 10_000)
+	put16("Parameters.FilterPeriod", 30)
+	put16("Parameters.DecayPeriod", 600)
+	put16("Parameters.ReductionFactor", 5_000)
+	put32("Parameters.VariableFeeControl", 40_000)
+	put32("Parameters.MaxVolatilityAccumulator", 350_000)
+	put16("BinStep", 10)
+	put32("ActiveId", 5)
+	// The last swap, 10 seconds ago, moved the price 10 bins from a reference of 5 bins' volatility
+	put32("VParameters.VolatilityAccumulator", 150_000)
+	put32("VParameters.VolatilityReference", 50_000)
+	put32("VParameters.IndexReference", 15)
+	binary.LittleEndian.PutUint64(data[pool.Offset("VParameters.LastUpdateTimestamp"):], uint64(now.Unix()-10))
+	binary.LittleEndian.PutUint64(data[pool.Offset("BinArrayBitmap")+8*8:], 1)
+	mintX, mintY := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	copy(data[pool.Offset("TokenXMint"):], mintX.Bytes())
+	copy(data[pool.Offset("TokenYMint"):], mintY.Bytes())
+	require.NoError(t, pool.Decode(data))
+	binArray, _ := meteora.DeriveBinArrayPDA(pool.PoolId, 0)
+	// Bin 5 holds Y at its stored price of 1, a Q64.64 one
+	bins := make([]byte, 56+70*144)
+	binary.LittleEndian.PutUint64(bins[56+5*144+8:], 1e12)
+	binary.LittleEndian.PutUint64(bins[56+5*144+24:], 1)
+	parsed, err := meteora.ParseBinArray(bins)
+	require.NoError(t, err)
+	pool.BinArrays = map[string]meteora.BinArray{binArray.String(): parsed}
+	mock := sol.NewMockRPC()
+	mock.SetAccount(pool.PoolId, pool.GetProgramID(), data)
+
+	// Within the filter period the reference holds: 50000 + 10 bins * 10000 gives a variable fee
+	// of 40000 * (150000 * 10)^2 / 1e11 = 900000 on top of the 1000000 base fee
+	rate, err := pool.FeeRate()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1_900_000), rate.Int64())
+	assert.EqualValues(t, 19, pool.FeeRateBps())
+
+	ctx := context.Background()
+	for range 2 {
+		_, fees, err := pool.QuoteWithFees(ctx, mock, mintX.String(), math.NewInt(1_000_000))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1_900), fees.LpFee.Add(fees.ProtocolFee).Int64())
+	}
+
+	// Swaps since the pool was discovered are read back before quoting
+	put32("VParameters.VolatilityAccumulator", 0)
+	put32("VParameters.VolatilityReference", 0)
+	put32("VParameters.IndexReference", 5)
+	mock.SetAccount(pool.PoolId, pool.GetProgramID(), data)
+	_, fees, err := pool.QuoteWithFees(ctx, mock, mintX.String(), math.NewInt(1_000_000))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1_000), fees.LpFee.Add(fees.ProtocolFee).Int64())
+}
+
+func TestDlmmFeeSplit(t *testing.T) {
+	pool := &meteora.MeteoraDlmmPool{PoolId: solana.NewWallet().PublicKey(), TimeSource: clock.NewFake(time.Unix(1_700_000_000, 0))}
+	data := make([]byte, pool.Span())
+	binary.LittleEndian.PutUint16(data[pool.Offset("Parameters.BaseFactor"):], 10_000)
+	binary.LittleEndian.PutUint16(data[pool.Offset("BinStep"):], 10)
+	binary.LittleEndian.PutUint32(data[pool.Offset("ActiveId"):], 5)
+	binary.LittleEndian.PutUint32(data[pool.Offset("VParameters.IndexReference"):], 5)
+	binary.LittleEndian.PutUint64(data[pool.Offset("BinArrayBitmap")+8*8:], 1)
+	mintX, mintY := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	copy(data[pool.Offset("TokenXMint"):], mintX.Bytes())
+	copy(data[pool.Offset("TokenYMint"):], mintY.Bytes())
+	binArray, _ := meteora.DeriveBinArrayPDA(pool.PoolId, 0)
+	// Bin 5 holds 600 of Y and bin 4 plenty, both at a stored price of 1: swapping 1000000 X
+	// pays a fee of 1 in bin 5 and 1000 in bin 4
+	bins := make([]byte, 56+70*144)
+	for bin, y := range map[int]uint64{5: 600, 4: 1e12} {
+		binary.LittleEndian.PutUint64(bins[56+bin*144+8:], y)
+		binary.LittleEndian.PutUint64(bins[56+bin*144+24:], 1)
+	}
+	mock := sol.NewMockRPC()
+
+	// Like the program, each bin's protocol fee is rounded down on its own, so the LP share
+	// keeps every bin's remainder: at 99.99% the protocol gets 0 + 999, not 1000 of 1001
+	for share, want := range map[uint16]struct{ lp, protocol int64 }{
+		0:      {lp: 1001},
+		1_234:  {lp: 878, protocol: 123},
+		5_000:  {lp: 501, protocol: 500},
+		9_999:  {lp: 2, protocol: 999},
+		10_000: {protocol: 1001},
+	} {
+		binary.LittleEndian.PutUint16(data[pool.Offset("Parameters.ProtocolShare"):], share)
+		require.NoError(t, pool.Decode(data))
+		parsed, err := meteora.ParseBinArray(bins)
+		require.NoError(t, err)
+		pool.BinArrays = map[string]meteora.BinArray{binArray.String(): parsed}
+		mock.SetAccount(pool.PoolId, pool.GetProgramID(), data)
+
+		_, fees, err := pool.QuoteWithFees(context.Background(), mock, mintX.String(), math.NewInt(1_000_000))
+		require.NoError(t, err, share)
+		assert.Equal(t, mintX.String(), fees.Mint, share)
+		assert.Equal(t, want.lp, fees.LpFee.Int64(), share)
+		assert.Equal(t, want.protocol, fees.ProtocolFee.Int64(), share)
+		assert.Equal(t, int64(1001), fees.LpFee.Add(fees.ProtocolFee).Int64(), "the parts add up to the fee")
+
+		protocolFee, err := pool.ComputeProtocolFee(1000)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(share)/10, protocolFee, "fee * share / 10000, rounded down")
+	}
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/meteora"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dlmmBinArrayData returns bin array account data holding amountY in the bins at the given
+// offsets, each priced at a Q64.64 one
+func dlmmBinArrayData(index int64, amountY map[int]uint64) []byte {
+	data := make([]byte, 56+70*144)
+	binary.LittleEndian.PutUint64(data[8:], uint64(index))
+	for offset, amount := range amountY {
+		binary.LittleEndian.PutUint64(data[56+offset*144+8:], amount)
+		binary.LittleEndian.PutUint64(data[56+offset*144+24:], 1)
+	}
+	return data
+}
+
+func TestDlmmBinArrayTraversal(t *testing.T) {
+	pool := &meteora.MeteoraDlmmPool{PoolId: solana.NewWallet().PublicKey()}
+	data := make([]byte, pool.Span())
+	binary.LittleEndian.PutUint32(data[pool.Offset("ActiveId"):], 5)
+	// Only bin array 0 is in the pair's bitmap; -513 and -514 are in its extension
+	binary.LittleEndian.PutUint64(data[pool.Offset("BinArrayBitmap")+8*8:], 1)
+	mintX, mintY := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	copy(data[pool.Offset("TokenXMint"):], mintX.Bytes())
+	copy(data[pool.Offset("TokenYMint"):], mintY.Bytes())
+	require.NoError(t, pool.Decode(data))
+
+	first, _ := meteora.DeriveBinArrayPDA(pool.PoolId, 0)
+	parsed, err := meteora.ParseBinArray(dlmmBinArrayData(0, map[int]uint64{5: 1_000}))
+	require.NoError(t, err)
+	pool.BinArrays = map[string]meteora.BinArray{first.String(): parsed}
+
+	extensionKey, _ := meteora.DeriveBinArrayBitmapExtension(pool.PoolId)
+	extension := make([]byte, 8+32+2*12*64)
+	binary.LittleEndian.PutUint64(extension[8+32+12*64:], 0b11)
+	far, _ := meteora.DeriveBinArrayPDA(pool.PoolId, -513)
+	farther, _ := meteora.DeriveBinArrayPDA(pool.PoolId, -514)
+
+	mock := sol.NewMockRPC()
+	mock.SetAccount(pool.PoolId, pool.GetProgramID(), data)
+	mock.SetAccount(extensionKey, pool.GetProgramID(), extension)
+	mock.SetAccount(far, pool.GetProgramID(), dlmmBinArrayData(-513, map[int]uint64{69: 1_000}))
+	mock.SetAccount(farther, pool.GetProgramID(), dlmmBinArrayData(-514, map[int]uint64{69: 1_000}))
+
+	// The quote drains bin 5, then crosses the empty arrays the extension skips
+	ctx := context.Background()
+	out, err := pool.Quote(ctx, mock, mintX.String(), math.NewInt(1_500))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1_500), out.Int64())
+	assert.Contains(t, pool.BinArrays, farther.String(), "arrays past the one needed are read in the same request")
+
+	_, err = pool.Quote(ctx, mock, mintX.String(), math.NewInt(5_000))
+	assert.Error(t, err)
+
+	// The swap passes the crossed arrays in order, and the next one
+	instructions, err := pool.BuildSwapInstructions(ctx, mock, solana.NewWallet().PublicKey(), mintX.String(), math.NewInt(1_500), math.NewInt(1))
+	require.NoError(t, err)
+	accounts := instructions[0].Accounts()
+	assert.Equal(t, extensionKey, accounts[1].PublicKey)
+	require.Len(t, accounts, 19)
+	assert.Equal(t, []solana.PublicKey{first, far, farther}, []solana.PublicKey{accounts[16].PublicKey, accounts[17].PublicKey, accounts[18].PublicKey})
+}
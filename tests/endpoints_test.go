@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/gtdvccc/SolRouteTmp/pkg/protocol"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// callSpy records the RPC methods called through it
+type callSpy struct {
+	*sol.MockRPC
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func (s *callSpy) record(method string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, method)
+}
+
+func (s *callSpy) take() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calls := s.calls
+	s.calls = nil
+	return calls
+}
+
+func (s *callSpy) GetAccountInfoWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetAccountInfoOpts) (*rpc.GetAccountInfoResult, error) {
+	s.record("getAccountInfo")
+	return s.MockRPC.GetAccountInfoWithOpts(ctx, account, opts)
+}
+
+func (s *callSpy) GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error) {
+	s.record("getMultipleAccounts")
+	return s.MockRPC.GetMultipleAccountsWithOpts(ctx, accounts, opts)
+}
+
+func (s *callSpy) GetProgramAccountsWithOpts(ctx context.Context, program solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (rpc.GetProgramAccountsResult, error) {
+	s.record("getProgramAccounts")
+	return s.MockRPC.GetProgramAccountsWithOpts(ctx, program, opts)
+}
+
+func (s *callSpy) GetTokenAccountBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetTokenAccountBalanceResult, error) {
+	s.record("getTokenAccountBalance")
+	return s.MockRPC.GetTokenAccountBalance(ctx, account, commitment)
+}
+
+func TestDiscoveryAndQuoteClientsAreSeparate(t *testing.T) {
+	mint0, mint1 := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	// Only the discovery endpoint has the pool and its config, and only the quote endpoint its
+	// vaults, so a read sent to the wrong one fails
+	discovery, quote := &callSpy{MockRPC: sol.NewMockRPC()}, &callSpy{MockRPC: sol.NewMockRPC()}
+	config := solana.NewWallet().PublicKey()
+	configData := make([]byte, 8+4+4*8+2*32+16*8)
+	binary.LittleEndian.PutUint64(configData[12:], 2_500)
+	discovery.SetAccount(config, raydium.RAYDIUM_CPMM_PROGRAM_ID, configData)
+	var layout raydium.CPMMPool
+	id, vault0, vault1 := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	data := make([]byte, layout.Span())
+	for field, key := range map[string]solana.PublicKey{
+		"AmmConfig": config, "Token0Vault": vault0, "Token1Vault": vault1, "Token0Mint": mint0, "Token1Mint": mint1,
+	} {
+		copy(data[layout.Offset(field):], key.Bytes())
+	}
+	discovery.SetAccount(id, raydium.RAYDIUM_CPMM_PROGRAM_ID, data)
+	quote.SetTokenAccount(vault0, mint0, id, 1_000_000_000)
+	quote.SetTokenAccount(vault1, mint1, id, 2_000_000_000)
+
+	endpoints := &sol.Endpoints{Discovery: &sol.Client{RpcClient: discovery}, Quote: &sol.Client{RpcClient: quote}}
+	r := router.NewSimpleRouter(protocol.NewRaydiumCpmm(endpoints.Discovery))
+	r.SetQuoteClient(endpoints.Quote.RpcClient)
+	r.SetMintDecimals(mint0.String(), 6)
+	r.SetMintDecimals(mint1.String(), 6)
+	ctx := context.Background()
+
+	pools, err := r.QueryAllPools(ctx, mint0.String(), mint1.String())
+	require.NoError(t, err)
+	require.Len(t, pools, 1)
+	assert.Contains(t, discovery.take(), "getProgramAccounts")
+	assert.Empty(t, quote.take(), "discovery doesn't touch the quote endpoint")
+
+	best, out, err := r.GetBestPool(ctx, nil, mint0.String(), mint1.String(), math.NewInt(1_000_000))
+	require.NoError(t, err)
+	assert.Equal(t, id.String(), best.GetID())
+	assert.True(t, out.IsPositive())
+	_, err = r.QuoteRoute(ctx, nil, mint0.String(), mint1.String(), math.NewInt(1_000_000))
+	require.NoError(t, err)
+	assert.NotEmpty(t, quote.take())
+	assert.Empty(t, discovery.take(), "quotes don't touch the discovery endpoint")
+}
@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorClassification(t *testing.T) {
+	assert.True(t, solerrors.IsRateLimited(fmt.Errorf("get account: %w", jsonrpc.NewHTTPError(http.StatusTooManyRequests, nil))))
+	assert.False(t, solerrors.IsRateLimited(jsonrpc.NewHTTPError(http.StatusBadGateway, nil)))
+	assert.True(t, solerrors.IsRetryable(router.ErrRateLimited))
+	assert.True(t, solerrors.IsAccountNotFound(fmt.Errorf("get account: %w", rpc.ErrNotFound)))
+
+	marked := solerrors.Mark(errors.New("protocol disabled"), solerrors.ErrPoolDisabled)
+	assert.Equal(t, "protocol disabled", marked.Error())
+	assert.ErrorIs(t, fmt.Errorf("pool x: %w", marked), solerrors.ErrPoolDisabled)
+	assert.ErrorIs(t, fmt.Errorf("pool x: %w", marked), marked)
+}
+
+func TestGetBestPoolErrorTaxonomy(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	pool := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 1e9}
+	r := router.NewSimpleRouter(staticProtocol{pool})
+	r.SetQuoteClient(rpc.New("http://127.0.0.1:0"))
+	ctx := context.Background()
+	_, err := r.QueryAllPools(ctx, mintA.String(), mintB.String())
+	require.NoError(t, err)
+
+	r.SetProtocolEnabled(pool.ProtocolName(), false)
+	_, _, err = r.GetBestPool(ctx, nil, mintA.String(), mintB.String(), math.NewInt(1000))
+	assert.ErrorIs(t, err, solerrors.ErrNoRoute)
+	assert.ErrorIs(t, err, solerrors.ErrPoolDisabled)
+	assert.ErrorIs(t, err, router.ErrProtocolDisabled)
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The example DEX exercises the plugin interfaces without an RPC node
+func TestExampleDexVenue(t *testing.T) {
+	account := exampledex.Pool{
+		MintA:    solana.NewWallet().PublicKey(),
+		MintB:    solana.NewWallet().PublicKey(),
+		VaultA:   solana.NewWallet().PublicKey(),
+		VaultB:   solana.NewWallet().PublicKey(),
+		ReserveA: 1_000_000_000,
+		ReserveB: 50_000_000_000,
+		FeeBps:   30,
+	}
+	id := solana.NewWallet().PublicKey()
+
+	venue := exampledex.Venue{}
+	pool, err := venue.DecodePool(id, account.Encode())
+	require.NoError(t, err)
+	assert.Equal(t, id.String(), pool.GetID())
+	assert.Equal(t, exampledex.ProgramID, pool.GetProgramID())
+	assert.Len(t, venue.PairFilters(account.MintA, account.MintB), 2)
+
+	_, err = venue.DecodePool(id, account.Encode()[1:])
+	assert.Error(t, err)
+
+	ctx := context.Background()
+	out, err := pool.Quote(ctx, nil, account.MintA.String(), math.NewInt(1_000_000))
+	require.NoError(t, err)
+	// 997000 * 50e9 / (1e9 + 997000)
+	assert.Equal(t, "49800349", out.String())
+
+	in, err := pool.QuoteExactOut(ctx, nil, account.MintB.String(), out)
+	require.NoError(t, err)
+	assert.True(t, in.LTE(math.NewInt(1_000_000)), "exact-out input %s", in)
+	back, err := pool.Quote(ctx, nil, account.MintA.String(), in)
+	require.NoError(t, err)
+	assert.True(t, back.GTE(out), "exact-out input %s only buys %s", in, back)
+
+	user := solana.NewWallet().PublicKey()
+	insts, err := pool.BuildSwapInstructions(ctx, nil, user, account.MintB.String(), math.NewInt(1000), math.NewInt(10))
+	require.NoError(t, err)
+	require.Len(t, insts, 1)
+	assert.Equal(t, exampledex.ProgramID, insts[0].ProgramID())
+}
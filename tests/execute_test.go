@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedSimRPC answers simulations with the scripted results in order, then succeeds
+type scriptedSimRPC struct {
+	*sol.MockRPC
+	results []rpc.SimulateTransactionResult
+	// onSimulate, when set, runs before each simulation
+	onSimulate func()
+}
+
+func (r *scriptedSimRPC) SimulateTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts *rpc.SimulateTransactionOpts) (*rpc.SimulateTransactionResponse, error) {
+	if r.onSimulate != nil {
+		r.onSimulate()
+	}
+	resp, err := r.MockRPC.SimulateTransactionWithOpts(ctx, tx, opts)
+	if err != nil || len(r.results) == 0 {
+		return resp, err
+	}
+	result := r.results[0]
+	r.results = r.results[1:]
+	resp.Value = &result
+	return resp, nil
+}
+
+func TestExecuteSwapRetriesSlippageFailures(t *testing.T) {
+	pool := &exampledex.Pool{
+		ID:       solana.NewWallet().PublicKey(),
+		MintA:    solana.NewWallet().PublicKey(),
+		MintB:    solana.NewWallet().PublicKey(),
+		ReserveA: 1e9,
+		ReserveB: 1e9,
+	}
+	mock := &scriptedSimRPC{MockRPC: sol.NewMockRPC(), results: []rpc.SimulateTransactionResult{{
+		Err:  map[string]any{"InstructionError": []any{0, map[string]any{"Custom": 6000}}},
+		Logs: []string{"Program log: Error: exceeds desired slippage limit"},
+	}}}
+	client := &sol.Client{RpcClient: mock}
+	payer := solana.NewWallet().PrivateKey
+	ctx := context.Background()
+
+	result, err := client.ExecuteSwap(ctx, pool, sol.LocalSigners(payer), pool.MintA.String(), math.NewInt(1000), sol.ExecuteOptions{Slippage: pkg.SlippageBps(100)})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Attempts)
+	assert.Len(t, mock.Simulated(), 2)
+	require.Len(t, mock.Sent(), 1)
+	assert.Equal(t, mock.Sent()[0].Signatures[0], result.Signature)
+	assert.Equal(t, result.ExpectedOut.MulRaw(9900).QuoRaw(10000), result.MinOut)
+
+	// Missing funds aren't retried and nothing is sent
+	mock.results = []rpc.SimulateTransactionResult{{
+		Err:  map[string]any{"InstructionError": []any{0, map[string]any{"Custom": 1}}},
+		Logs: []string{"Program log: Error: insufficient funds"},
+	}}
+	_, err = client.ExecuteSwap(ctx, pool, sol.LocalSigners(payer), pool.MintA.String(), math.NewInt(1000), sol.ExecuteOptions{})
+	require.ErrorIs(t, err, solerrors.ErrInsufficientFunds)
+	var simErr *sol.SimulationError
+	require.ErrorAs(t, err, &simErr)
+	assert.Equal(t, exampledex.ProgramID, simErr.Program)
+	assert.Equal(t, uint64(1), *simErr.Code)
+	assert.Len(t, mock.Sent(), 1)
+}
+
+func TestExecuteSwapDeadline(t *testing.T) {
+	pool := &exampledex.Pool{
+		ID:       solana.NewWallet().PublicKey(),
+		MintA:    solana.NewWallet().PublicKey(),
+		MintB:    solana.NewWallet().PublicKey(),
+		ReserveA: 1e9,
+		ReserveB: 1e9,
+	}
+	clk := clock.NewFake(time.Unix(1_700_000_000, 0))
+	mock := &scriptedSimRPC{MockRPC: sol.NewMockRPC()}
+	client := &sol.Client{RpcClient: mock, TimeSource: clk}
+	signers := sol.LocalSigners(solana.NewWallet().PrivateKey)
+	ctx := context.Background()
+	opts := sol.ExecuteOptions{Slippage: pkg.SlippageBps(100), Deadline: clk.Now().Add(time.Second)}
+
+	// A failed attempt isn't rebuilt once the deadline passes
+	mock.results = []rpc.SimulateTransactionResult{{
+		Err:  map[string]any{"InstructionError": []any{0, map[string]any{"Custom": 6000}}},
+		Logs: []string{"Program log: Error: exceeds desired slippage limit"},
+	}}
+	mock.onSimulate = func() { clk.Advance(2 * time.Second) }
+	_, err := client.ExecuteSwap(ctx, pool, signers, pool.MintA.String(), math.NewInt(1000), opts)
+	require.ErrorIs(t, err, solerrors.ErrDeadlineExceeded)
+	assert.Len(t, mock.Simulated(), 1)
+	assert.Empty(t, mock.Sent())
+
+	// Nor is a successful simulation sent
+	opts.Deadline = clk.Now().Add(time.Second)
+	_, err = client.ExecuteSwap(ctx, pool, signers, pool.MintA.String(), math.NewInt(1000), opts)
+	require.ErrorIs(t, err, solerrors.ErrDeadlineExceeded)
+	assert.Len(t, mock.Simulated(), 2)
+	assert.Empty(t, mock.Sent())
+
+	mock.onSimulate = nil
+	opts.Deadline = clk.Now().Add(time.Second)
+	_, err = client.ExecuteSwap(ctx, pool, signers, pool.MintA.String(), math.NewInt(1000), opts)
+	require.NoError(t, err)
+	assert.Len(t, mock.Sent(), 1)
+}
+
+func TestParseSimulationError(t *testing.T) {
+	// Whirlpool's invalid tick array sequence, reported by the node as decoded JSON
+	whirlpool := solana.MustPublicKeyFromBase58("whirLbMiicVdio4qvUfM5KAg6Ct8VwpYzGff3uctyCc")
+	tx, err := solana.NewTransaction(
+		[]solana.Instruction{solana.NewInstruction(whirlpool, solana.AccountMetaSlice{}, nil)},
+		solana.Hash{},
+		solana.TransactionPayer(solana.NewWallet().PublicKey()),
+	)
+	require.NoError(t, err)
+	simErr := sol.ParseSimulationError(tx, &rpc.SimulateTransactionResult{
+		Err: map[string]any{"InstructionError": []any{float64(0), map[string]any{"Custom": float64(6038)}}},
+	})
+	require.NotNil(t, simErr)
+	assert.Equal(t, sol.SimulationFailureTickArray, simErr.Failure)
+	assert.ErrorIs(t, simErr, solerrors.ErrTickArrayMissing)
+	assert.True(t, simErr.Failure.Retryable())
+
+	simErr = sol.ParseSimulationError(nil, &rpc.SimulateTransactionResult{Err: "BlockhashNotFound"})
+	assert.Equal(t, sol.SimulationFailureBlockhash, simErr.Failure)
+	assert.Nil(t, sol.ParseSimulationError(nil, &rpc.SimulateTransactionResult{}))
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tokenTransfer(amount uint64) []byte {
+	data := make([]byte, 9)
+	data[0] = 3
+	binary.LittleEndian.PutUint64(data[1:], amount)
+	return data
+}
+
+func tokenBalance(index uint16, owner, mint solana.PublicKey, amount string, decimals uint8) rpc.TokenBalance {
+	return rpc.TokenBalance{AccountIndex: index, Owner: &owner, Mint: mint, UiTokenAmount: &rpc.UiTokenAmount{Amount: amount, Decimals: decimals}}
+}
+
+func TestExecutionReport(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	usdc := solana.NewWallet().PublicKey()
+	poolAuthority := solana.NewWallet().PublicKey()
+	// The WSOL account is created and closed around the swap, so only the transfers show it
+	const (
+		ownerIndex = iota
+		wsolAccount
+		ownerUSDC
+		vaultSOL
+		vaultUSDC
+		authority
+		tokenProgram
+		ammProgram
+	)
+	tx := &solana.Transaction{
+		Signatures: []solana.Signature{{1}},
+		Message: solana.Message{
+			Header: solana.MessageHeader{NumRequiredSignatures: 1, NumReadonlyUnsignedAccounts: 3},
+			AccountKeys: solana.PublicKeySlice{
+				owner, solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(),
+				solana.NewWallet().PublicKey(), poolAuthority, solana.TokenProgramID, solana.NewWallet().PublicKey(),
+			},
+			Instructions: []solana.CompiledInstruction{
+				{ProgramIDIndex: ammProgram, Accounts: []uint16{ownerIndex, wsolAccount, ownerUSDC, vaultSOL, vaultUSDC, authority, tokenProgram}, Data: []byte{9}},
+			},
+		},
+	}
+	units := uint64(42_000)
+	meta := rpc.TransactionMeta{
+		Fee: 15_000,
+		InnerInstructions: []rpc.InnerInstruction{{Index: 0, Instructions: []solana.CompiledInstruction{
+			{ProgramIDIndex: tokenProgram, Accounts: []uint16{wsolAccount, vaultSOL, ownerIndex}, Data: tokenTransfer(1_000_000_000)},
+			{ProgramIDIndex: tokenProgram, Accounts: []uint16{vaultUSDC, ownerUSDC, authority}, Data: tokenTransfer(150_000_000)},
+		}}},
+		PreTokenBalances: []rpc.TokenBalance{
+			tokenBalance(ownerUSDC, owner, usdc, "10000000", 6),
+			tokenBalance(vaultSOL, poolAuthority, solana.SolMint, "100000000000", 9),
+			tokenBalance(vaultUSDC, poolAuthority, usdc, "15000000000", 6),
+		},
+		PostTokenBalances: []rpc.TokenBalance{
+			tokenBalance(ownerUSDC, owner, usdc, "160000000", 6),
+			tokenBalance(vaultSOL, poolAuthority, solana.SolMint, "101000000000", 9),
+			tokenBalance(vaultUSDC, poolAuthority, usdc, "14850000000", 6),
+		},
+		ComputeUnitsConsumed: &units,
+	}
+	mock := sol.NewMockRPC()
+	mock.SetSlot(777)
+	require.NoError(t, mock.SetTransaction(tx.Signatures[0], tx, meta))
+	client := &sol.Client{RpcClient: mock}
+
+	report, err := client.ExecutionReport(context.Background(), tx.Signatures[0], owner, solana.SolMint.String(), usdc.String(), math.NewInt(151_500_000))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(777), report.Slot)
+	assert.Equal(t, math.NewInt(1_000_000_000), report.AmountIn)
+	assert.Equal(t, math.NewInt(150_000_000), report.AmountOut)
+	assert.InDelta(t, 150.0, report.Price, 1e-9)
+	assert.Equal(t, uint64(15_000), report.Fee)
+	assert.Equal(t, units, report.UnitsConsumed)
+	assert.Equal(t, int64(99), report.SlippageBps)
+
+	// A better fill than quoted has negative slippage
+	report, err = sol.ParseExecutionReport(tx, &meta, owner, solana.SolMint.String(), usdc.String(), math.NewInt(148_500_000))
+	require.NoError(t, err)
+	assert.Equal(t, int64(-101), report.SlippageBps)
+
+	meta.Err = map[string]any{"InstructionError": []any{0, map[string]any{"Custom": 30}}}
+	_, err = sol.ParseExecutionReport(tx, &meta, owner, solana.SolMint.String(), usdc.String(), math.Int{})
+	assert.ErrorContains(t, err, "failed")
+
+	_, err = client.ExecutionReport(context.Background(), solana.Signature{2}, owner, solana.SolMint.String(), usdc.String(), math.Int{})
+	assert.ErrorIs(t, err, rpc.ErrNotFound)
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/meteora"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/meteora/dammv2"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/orca"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/phoenix"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/pump"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/stretchr/testify/assert"
+)
+
+// accountStub is a JSON-RPC transport that serves account reads from memory
+type accountStub map[solana.PublicKey][]byte
+
+func (s accountStub) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	result, ok := out.(**rpc.GetMultipleAccountsResult)
+	if method != "getMultipleAccounts" || !ok {
+		return fmt.Errorf("stub doesn't serve %s", method)
+	}
+	*result = &rpc.GetMultipleAccountsResult{}
+	for _, key := range params[0].([]solana.PublicKey) {
+		var account *rpc.Account
+		if data, ok := s[key]; ok {
+			account = &rpc.Account{Data: rpc.DataBytesOrJSONFromBytes(data)}
+		}
+		(*result).Value = append((*result).Value, account)
+	}
+	return nil
+}
+
+func (s accountStub) CallWithCallback(ctx context.Context, method string, params []interface{}, callback func(*http.Request, *http.Response) error) error {
+	return fmt.Errorf("stub doesn't serve %s", method)
+}
+
+func (s accountStub) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	return nil, fmt.Errorf("stub doesn't serve batches")
+}
+
+// Quotes through a faulty transport fail with errors instead of panicking
+func TestFaultInjectorQuote(t *testing.T) {
+	data := dammV2PoolData()
+	pool, err := dammv2.NewPool(solana.NewWallet().PublicKey(), data)
+	require.NoError(t, err)
+	clockData := make([]byte, 40)
+	binary.LittleEndian.PutUint64(clockData, 1000)
+	stub := accountStub{pool.PoolId: data, solana.SysVarClockPubkey: clockData}
+	ctx := context.Background()
+	in := math.NewInt(1_000_000)
+
+	healthy := sol.NewFaultInjector(stub, sol.FaultConfig{})
+	out, err := pool.Quote(ctx, healthy.Client(), pool.TokenAMint.String(), in)
+	require.NoError(t, err)
+	assert.Equal(t, "996502", out.String())
+
+	failing := sol.NewFaultInjector(stub, sol.FaultConfig{ErrorRate: 1})
+	_, err = pool.Quote(ctx, failing.Client(), pool.TokenAMint.String(), in)
+	assert.ErrorIs(t, err, sol.ErrInjectedFault)
+	assert.Equal(t, 1, failing.Stats().Errors)
+
+	for _, keep := range []int{0, 8, 100, 483} {
+		truncating := sol.NewFaultInjector(stub, sol.FaultConfig{TruncateRate: 1, TruncateTo: keep})
+		assert.NotPanics(t, func() {
+			_, err = pool.Quote(ctx, truncating.Client(), pool.TokenAMint.String(), in)
+		})
+		assert.Error(t, err, "data truncated to %d bytes", keep)
+		assert.Equal(t, 1, truncating.Stats().Truncation)
+	}
+
+	// Latency spikes are waited out through the sleeper, and only hit the listed methods
+	fake := clock.NewFake(time.Unix(0, 0))
+	slow := sol.NewFaultInjector(stub, sol.FaultConfig{LatencyRate: 1, Latency: 2 * time.Second, Methods: []string{"getMultipleAccounts"}})
+	slow.SetSleeper(fake)
+	_, err = pool.Quote(ctx, slow.Client(), pool.TokenAMint.String(), in)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{2 * time.Second}, fake.Sleeps())
+
+	// Rates are drawn per call
+	flaky := sol.NewFaultInjector(stub, sol.FaultConfig{ErrorRate: 0.5})
+	flaky.SetRand(rand.New(rand.NewPCG(3, 4)))
+	for range 100 {
+		_, _ = pool.Quote(ctx, flaky.Client(), pool.TokenAMint.String(), in)
+	}
+	stats := flaky.Stats()
+	assert.Equal(t, 100, stats.Calls)
+	assert.InDelta(t, 50, stats.Errors, 20)
+}
+
+// Truncated account data must fail to decode, never panic
+func TestDecodersRejectTruncatedData(t *testing.T) {
+	decoders := map[string]func([]byte) error{
+		"raydium amm":        func(d []byte) error { return new(raydium.AMMPool).Decode(d) },
+		"raydium market":     func(d []byte) error { return new(raydium.MarketStateLayoutV3).Decode(d) },
+		"raydium cpmm":       func(d []byte) error { return new(raydium.CPMMPool).Decode(d) },
+		"raydium clmm":       func(d []byte) error { return new(raydium.CLMMPool).Decode(d) },
+		"raydium tick array": func(d []byte) error { return new(raydium.TickArray).Decode(d) },
+		"raydium launchlab":  func(d []byte) error { return new(raydium.LaunchLabPool).Decode(d) },
+		"meteora dlmm":       func(d []byte) error { return new(meteora.MeteoraDlmmPool).Decode(d) },
+		"meteora damm":       func(d []byte) error { return new(meteora.MeteoraDammPool).Decode(d) },
+		"meteora vault":      func(d []byte) error { return new(meteora.DynamicVault).Decode(d) },
+		"meteora damm v2":    func(d []byte) error { return new(dammv2.Pool).Decode(d) },
+		"orca whirlpool":     func(d []byte) error { return new(orca.WhirlpoolPool).Decode(d) },
+		"orca tick array":    func(d []byte) error { return new(orca.WhirlpoolTickArray).Decode(d) },
+		"phoenix market":     func(d []byte) error { return new(phoenix.Market).Decode(d) },
+		"pump amm":           func(d []byte) error { return new(pump.PumpAMMPool).Decode(d) },
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]byte, 12_000)
+	for i := range data {
+		data[i] = byte(rng.UintN(256))
+	}
+	for name, decode := range decoders {
+		for n := 0; n < 1200; n += 7 {
+			assert.NotPanics(t, func() { _ = decode(data[:n]) }, "%s with %d bytes", name, n)
+		}
+	}
+}
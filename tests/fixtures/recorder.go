@@ -0,0 +1,145 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+)
+
+// Recorder is an RPC keeping a copy of every account read through it, so a fixture holds
+// exactly what fetching and quoting a pool needed. Data slices aren't recorded, since they
+// hold part of an account.
+type Recorder struct {
+	pkg.RPC
+
+	mu       sync.Mutex
+	accounts map[solana.PublicKey]Account
+	slot     uint64
+}
+
+// NewRecorder creates a recorder reading through client
+func NewRecorder(client pkg.RPC) *Recorder {
+	return &Recorder{RPC: client, accounts: make(map[solana.PublicKey]Account)}
+}
+
+func (r *Recorder) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	return r.GetAccountInfoWithOpts(ctx, account, nil)
+}
+
+func (r *Recorder) GetAccountInfoWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetAccountInfoOpts) (*rpc.GetAccountInfoResult, error) {
+	result, err := r.RPC.GetAccountInfoWithOpts(ctx, account, opts)
+	if err == nil && (opts == nil || opts.DataSlice == nil) {
+		r.record(result.Context.Slot, account, result.Value)
+	}
+	return result, err
+}
+
+func (r *Recorder) GetMultipleAccounts(ctx context.Context, accounts ...solana.PublicKey) (*rpc.GetMultipleAccountsResult, error) {
+	return r.GetMultipleAccountsWithOpts(ctx, accounts, nil)
+}
+
+func (r *Recorder) GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error) {
+	result, err := r.RPC.GetMultipleAccountsWithOpts(ctx, accounts, opts)
+	if err == nil && (opts == nil || opts.DataSlice == nil) {
+		for i, value := range result.Value {
+			if i < len(accounts) {
+				r.record(result.Context.Slot, accounts[i], value)
+			}
+		}
+	}
+	return result, err
+}
+
+func (r *Recorder) GetProgramAccountsWithOpts(ctx context.Context, program solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (rpc.GetProgramAccountsResult, error) {
+	result, err := r.RPC.GetProgramAccountsWithOpts(ctx, program, opts)
+	if err == nil && (opts == nil || opts.DataSlice == nil) {
+		for _, keyed := range result {
+			r.record(0, keyed.Pubkey, keyed.Account)
+		}
+	}
+	return result, err
+}
+
+func (r *Recorder) record(slot uint64, address solana.PublicKey, account *rpc.Account) {
+	if account == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.slot = max(r.slot, slot)
+	r.accounts[address] = Account{
+		Pubkey:     address,
+		Owner:      account.Owner,
+		Lamports:   account.Lamports,
+		Executable: account.Executable,
+		Data:       append([]byte(nil), account.Data.GetBinary()...),
+	}
+}
+
+// Accounts returns the accounts read so far, ordered by address
+func (r *Recorder) Accounts() []Account {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	accounts := make([]Account, 0, len(r.accounts))
+	for _, account := range r.accounts {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Pubkey.String() < accounts[j].Pubkey.String() })
+	return accounts
+}
+
+// Slot returns the latest slot the accounts were read at
+func (r *Recorder) Slot() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.slot
+}
+
+// Record fetches a pool through the default protocol named name, quotes each swap and
+// returns a fixture of the accounts read, with the named pool fields decoded. Swaps without
+// an outcome, AmountOut for exact-in and AmountIn for exact-out, get the quote's; swaps taken
+// from a transaction keep theirs. The state read must be the state the transaction saw for
+// the fixture to replay.
+func Record(ctx context.Context, client pkg.RPC, name pkg.ProtocolName, poolID string, fields []string, swaps []Swap) (*Fixture, error) {
+	recorder := NewRecorder(client)
+	pool, err := fetchPool(ctx, recorder, name, poolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pool %s: %w", poolID, err)
+	}
+	decoded, err := Fields(pool, fields...)
+	if err != nil {
+		return nil, err
+	}
+	recorded := make([]Swap, len(swaps))
+	for i, swap := range swaps {
+		quoted, err := Quote(ctx, pool, recorder, swap)
+		if err != nil {
+			return nil, fmt.Errorf("failed to quote swap %d: %w", i, err)
+		}
+		switch {
+		case swap.ExactOut && swap.AmountIn.IsNil():
+			swap.AmountIn = quoted
+		case !swap.ExactOut && swap.AmountOut.IsNil():
+			swap.AmountOut = quoted
+		}
+		recorded[i] = swap
+	}
+	id, err := solana.PublicKeyFromBase58(poolID)
+	if err != nil {
+		return nil, err
+	}
+	return &Fixture{
+		Name:     fmt.Sprintf("%s %s", name, poolID),
+		Protocol: name,
+		Pool:     id,
+		Slot:     recorder.Slot(),
+		Accounts: recorder.Accounts(),
+		Decoded:  decoded,
+		Swaps:    recorded,
+	}, nil
+}
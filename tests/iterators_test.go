@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/meteora"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/orca"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"lukechampine.com/uint128"
+)
+
+func TestCLMMInitializedTicks(t *testing.T) {
+	upper := raydium.TickArray{StartTickIndex: 0, Ticks: make([]raydium.TickState, raydium.TICK_ARRAY_SIZE)}
+	lower := raydium.TickArray{StartTickIndex: -600, Ticks: make([]raydium.TickState, raydium.TICK_ARRAY_SIZE)}
+	for _, tick := range []struct {
+		array  *raydium.TickArray
+		offset int
+	}{{&upper, 0}, {&upper, 1}, {&upper, 3}, {&lower, 59}} {
+		tick.array.Ticks[tick.offset].Tick = tick.array.StartTickIndex + int32(tick.offset)*10
+		tick.array.Ticks[tick.offset].LiquidityGross = uint128.From64(1)
+	}
+	pool := &raydium.CLMMPool{
+		TickSpacing:    10,
+		TickCurrent:    15,
+		TickArrayCache: map[string]raydium.TickArray{"0": upper, "-600": lower},
+	}
+	// Start indexes 0 and -600 sit either side of bit 512 of the default bitmap
+	pool.TickArrayBitmap[8] = 1
+	pool.TickArrayBitmap[7] = 1 << 63
+
+	assert.Equal(t, []int32{10, 0, -10}, collectTicks(pool.InitializedTicks(true)))
+	assert.Equal(t, []int32{30}, collectTicks(pool.InitializedTicks(false)))
+
+	next, found := pool.NextInitializedTickArray(15, true)
+	assert.True(t, found)
+	assert.Equal(t, int64(-600), next)
+	_, found = pool.NextInitializedTickArray(15, false)
+	assert.False(t, found)
+
+	first, err := upper.FirstInitializedTick(true)
+	require.NoError(t, err)
+	assert.Equal(t, int32(30), first.Tick)
+	first, err = lower.FirstInitializedTick(false)
+	require.NoError(t, err)
+	assert.Equal(t, int32(-10), first.Tick)
+}
+
+func TestWhirlpoolInitializedTicks(t *testing.T) {
+	upper := orca.WhirlpoolTickArray{StartTickIndex: 0, Ticks: make([]orca.WhirlpoolTickState, orca.TICK_ARRAY_SIZE)}
+	lower := orca.WhirlpoolTickArray{StartTickIndex: -orca.TICK_ARRAY_SIZE, Ticks: make([]orca.WhirlpoolTickState, orca.TICK_ARRAY_SIZE)}
+	upper.Ticks[0].Initialized = true
+	upper.Ticks[10].Initialized = true
+	upper.Ticks[30].Initialized = true
+	lower.Ticks[orca.TICK_ARRAY_SIZE-10].Initialized = true
+	pool := &orca.WhirlpoolPool{
+		TickSpacing:      1,
+		TickCurrentIndex: 15,
+		TickArrayCache:   map[string]orca.WhirlpoolTickArray{"0": upper, "-88": lower},
+	}
+
+	assert.Equal(t, []int32{10, 0, -10}, collectTicks(pool.InitializedTicks(true)))
+	assert.Equal(t, []int32{30}, collectTicks(pool.InitializedTicks(false)))
+
+	next, found, err := upper.NextInitializedTick(15, 1, true)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, int64(10), next)
+	next, found, err = upper.NextInitializedTick(15, 1, false)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, int64(30), next)
+	_, found, err = upper.NextInitializedTick(31, 1, false)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestDlmmBinsWithLiquidity(t *testing.T) {
+	data := make([]byte, 904)
+	binary.LittleEndian.PutUint32(data[76:], 5) // active_id
+	// Bin arrays 0 and -1 sit either side of bit 512 of the default bitmap
+	binary.LittleEndian.PutUint64(data[584+8*8:], 1)
+	binary.LittleEndian.PutUint64(data[584+7*8:], 1<<63)
+	pool := &meteora.MeteoraDlmmPool{}
+	require.NoError(t, pool.Decode(data))
+	require.Equal(t, int32(5), pool.ActiveID())
+
+	upper := dlmmBinArray(t, 0, map[int][2]uint64{3: {0, 100}, 5: {10, 20}, 8: {50, 0}})
+	lower := dlmmBinArray(t, -1, map[int][2]uint64{68: {0, 70}})
+	pool.BinArrays = map[string]meteora.BinArray{"upper": upper, "lower": lower}
+
+	ids := make([]int32, 0)
+	for id, bin := range pool.BinsWithLiquidity(true) {
+		ids = append(ids, id)
+		if id == 5 {
+			assert.Equal(t, meteora.BinLiquidity{AmountX: 10, AmountY: 20}, bin)
+		}
+	}
+	assert.Equal(t, []int32{5, 3, -2}, ids)
+	ids = ids[:0]
+	for id := range pool.BinsWithLiquidity(false) {
+		ids = append(ids, id)
+	}
+	assert.Equal(t, []int32{5, 8}, ids)
+
+	index, found, err := pool.NextBinArrayIndexWithLiquidity(true, 0)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, int32(0), index)
+	index, found, err = pool.NextBinArrayIndexWithLiquidity(true, -1)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, int32(-1), index)
+	_, found, err = pool.NextBinArrayIndexWithLiquidity(true, -2)
+	require.NoError(t, err)
+	assert.False(t, found)
+	_, found, err = pool.NextBinArrayIndexWithLiquidity(false, 1)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+// dlmmBinArray encodes a bin array account with the given X and Y amounts by bin offset
+func dlmmBinArray(t *testing.T, index int64, amounts map[int][2]uint64) meteora.BinArray {
+	const binSize = 144
+	data := make([]byte, 56+70*binSize)
+	binary.LittleEndian.PutUint64(data[8:], uint64(index))
+	for offset, amount := range amounts {
+		binary.LittleEndian.PutUint64(data[56+offset*binSize:], amount[0])
+		binary.LittleEndian.PutUint64(data[56+offset*binSize+8:], amount[1])
+	}
+	binArray, err := meteora.ParseBinArray(data)
+	require.NoError(t, err)
+	return binArray
+}
+
+func collectTicks[T any](ticks func(func(int32, T) bool)) []int32 {
+	indexes := make([]int32, 0)
+	for index := range ticks {
+		indexes = append(indexes, index)
+	}
+	return indexes
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"cosmossdk.io/math"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A fresh constant-product curve: 1.07e15 virtual tokens against 30 virtual SOL, 0.25% fee
+func TestLaunchLabQuote(t *testing.T) {
+	state := raydium.LaunchLabPoolState{
+		TotalSellA: 793_100_000_000_000,
+		VirtualA:   1_073_025_605_596_382,
+		VirtualB:   30_000_000_000,
+		MintA:      solana.NewWallet().PublicKey(),
+		MintB:      solana.SolMint,
+	}
+	var buf bytes.Buffer
+	require.NoError(t, bin.NewBinEncoder(&buf).Encode(state))
+	data := append(append([]byte{}, raydium.LaunchLabPoolDiscriminator...), buf.Bytes()...)
+
+	pool := &raydium.LaunchLabPool{TradeFeeRate: 2500}
+	require.NoError(t, pool.Decode(data))
+	assert.Equal(t, state.MintA.Bytes(), data[pool.Offset("MintA"):pool.Offset("MintA")+32])
+	assert.Equal(t, state.MintB.Bytes(), data[pool.Offset("MintB"):pool.Offset("MintB")+32])
+
+	// Buying with 1 SOL spends 0.9975 SOL on the curve
+	out, err := pool.QuoteOffline(solana.SolMint.String(), math.NewInt(1_000_000_000))
+	require.NoError(t, err)
+	assert.Equal(t, "34529979565525", out.String())
+
+	in, err := pool.QuoteExactOutOffline(state.MintA.String(), out)
+	require.NoError(t, err)
+	assert.True(t, in.LTE(math.NewInt(1_000_000_000)), "exact-out input %s", in)
+	short, err := pool.QuoteOffline(solana.SolMint.String(), in.SubRaw(1))
+	require.NoError(t, err)
+	assert.True(t, short.LT(out))
+
+	// Selling back after the buy returns a little less, fee taken from the SOL
+	pool.RealA, pool.RealB = out.Uint64(), 997_499_999
+	back, err := pool.QuoteOffline(state.MintA.String(), out)
+	require.NoError(t, err)
+	assert.Equal(t, "995006249", back.String())
+	tokensIn, err := pool.QuoteExactOutOffline(solana.SolMint.String(), back)
+	require.NoError(t, err)
+	assert.True(t, tokensIn.LTE(out))
+
+	// Buys stop at the curve's remaining supply; graduated curves don't quote
+	pool.RealA = state.TotalSellA - 10
+	capped, err := pool.QuoteOffline(solana.SolMint.String(), math.NewInt(1_000_000_000))
+	require.NoError(t, err)
+	assert.Equal(t, "10", capped.String())
+	pool.Status = 2
+	_, err = pool.QuoteOffline(solana.SolMint.String(), math.NewInt(1_000_000_000))
+	assert.Error(t, err)
+	assert.NotNil(t, pool.Deprecation())
+}
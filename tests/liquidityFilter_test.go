@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiquidityFilterDropsDustPools(t *testing.T) {
+	base, quote := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	mock := sol.NewMockRPC()
+	cpmm := func(quoteBalance uint64, openTime time.Time) *raydium.CPMMPool {
+		pool := &raydium.CPMMPool{
+			PoolId:      solana.NewWallet().PublicKey(),
+			Token0Mint:  base,
+			Token1Mint:  quote,
+			Token0Vault: solana.NewWallet().PublicKey(),
+			Token1Vault: solana.NewWallet().PublicKey(),
+			OpenTime:    uint64(openTime.Unix()),
+		}
+		mock.SetTokenAccount(pool.Token0Vault, base, solana.NewWallet().PublicKey(), 1_000_000)
+		mock.SetTokenAccount(pool.Token1Vault, quote, solana.NewWallet().PublicKey(), quoteBalance)
+		return pool
+	}
+	liquid := cpmm(1_000_000, time.Now().Add(-24*time.Hour))
+	dust := cpmm(10, time.Now().Add(-24*time.Hour))
+	fresh := cpmm(1_000_000, time.Now())
+	cheap := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: base, MintB: quote, FeeBps: 30}
+	pricey := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: base, MintB: quote, FeeBps: 500}
+
+	r := router.NewSimpleRouter(staticProtocol{liquid, dust, fresh, cheap, pricey})
+	r.SetQuoteClient(mock)
+	r.SetLiquidityFilter(router.LiquidityFilter{
+		MinQuoteLiquidity: math.NewInt(1000),
+		MaxFeeBps:         100,
+		MinAge:            time.Hour,
+	})
+	pools, err := r.QueryAllPools(context.Background(), base.String(), quote.String())
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(pools))
+	for _, pool := range pools {
+		ids = append(ids, pool.GetID())
+	}
+	assert.ElementsMatch(t, []string{liquid.GetID(), cheap.GetID()}, ids)
+}
@@ -0,0 +1,94 @@
+//go:build localvalidator
+
+package tests
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/protocol"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gtdvccc/SolRouteTmp/tests/fixtures"
+	"github.com/gtdvccc/SolRouteTmp/tests/validator"
+	"github.com/gtdvccc/SolRouteTmp/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLocalValidatorSwaps swaps WSOL for USDC through a pool of each protocol on a local
+// validator holding the pool's accounts and program cloned from SOLANA_RPC_URL, so the
+// instructions run against the real program before they ever reach mainnet
+func TestLocalValidatorSwaps(t *testing.T) {
+	if !validator.Installed() {
+		t.Skip(validator.Binary + " not found on PATH")
+	}
+	utils.LoadEnv()
+	source := os.Getenv("SOLANA_RPC_URL")
+	if source == "" {
+		source = rpc.MainNetBeta_RPC
+	}
+	amountIn := math.NewInt(defaultAmountIn)
+
+	for _, tc := range []struct {
+		name        string
+		newProtocol func(*sol.Client) pkg.Protocol
+	}{
+		{"raydium_clmm", func(c *sol.Client) pkg.Protocol { return protocol.NewRaydiumClmm(c) }},
+		{"raydium_cpmm", func(c *sol.Client) pkg.Protocol { return protocol.NewRaydiumCpmm(c) }},
+		{"orca_whirlpool", func(c *sol.Client) pkg.Protocol { return protocol.NewOrcaWhirlpool(c) }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			defer cancel()
+			payer := solana.NewWallet().PrivateKey
+			swapOpts := sol.SwapOptions{WrapSol: true, CreateOutputAccount: true}
+
+			// Record everything quoting and building the swap reads on the source cluster
+			recorder := fixtures.NewRecorder(rpc.New(source))
+			recording := &sol.Client{RpcClient: recorder}
+			pools, err := tc.newProtocol(&sol.Client{RpcClient: rpc.New(source)}).FetchPoolsByPair(ctx, sol.WSOL.String(), usdcTokenAddr)
+			require.NoError(t, err)
+			var poolID string
+			for _, pool := range pools {
+				if out, err := pool.Quote(ctx, recorder, sol.WSOL.String(), amountIn); err == nil && out.IsPositive() {
+					poolID = pool.GetID()
+					instructions, err := recording.BuildSwapInstructions(ctx, pool, payer.PublicKey(), sol.WSOL.String(), amountIn, math.OneInt(), swapOpts)
+					require.NoError(t, err)
+					_, err = recorder.GetMultipleAccounts(ctx, validator.InstructionAccounts(instructions)...)
+					require.NoError(t, err)
+					break
+				}
+			}
+			if poolID == "" {
+				t.Skipf("no %s WSOL/USDC pool quotes on %s", tc.name, source)
+			}
+
+			clone, programs := validator.Clones(recorder.Accounts())
+			v, err := validator.Start(ctx, validator.Options{SourceURL: source, Clone: clone, ClonePrograms: programs})
+			require.NoError(t, err)
+			defer v.Close()
+			require.NoError(t, v.Fund(ctx, payer.PublicKey(), 10*solana.LAMPORTS_PER_SOL))
+
+			local, err := sol.NewClient(ctx, v.RPCURL, "")
+			require.NoError(t, err)
+			pool, err := tc.newProtocol(local).FetchPoolByID(ctx, poolID)
+			require.NoError(t, err)
+			result, err := local.ExecuteSwap(ctx, pool, sol.LocalSigners(payer), sol.WSOL.String(), amountIn, sol.ExecuteOptions{
+				SwapOptions: swapOpts,
+				Slippage:    pkg.SlippageConfig{Bps: slippageBps},
+			})
+			require.NoError(t, err, "swap through %s", poolID)
+			require.NoError(t, v.WaitForSignature(ctx, result.Signature))
+
+			received, err := local.GetUserTokenBalance(ctx, payer.PublicKey(), solana.MustPublicKeyFromBase58(usdcTokenAddr))
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, received, result.MinOut.Uint64(), "pool %s delivered less than its minimum", poolID)
+			t.Logf("%s: swapped %s lamports for %d USDC units, quoted %s", poolID, amountIn, received, result.ExpectedOut)
+		})
+	}
+}
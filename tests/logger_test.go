@@ -0,0 +1,43 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouterLogsFailedQuotes(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	pool := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 1e9}
+	var buf bytes.Buffer
+	r := router.NewSimpleRouter(staticProtocol{pool})
+	r.SetLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})))
+	r.SetQuoteClient(rpc.New("http://127.0.0.1:0"))
+	ctx := context.Background()
+	_, err := r.QueryAllPools(ctx, mintA.String(), mintB.String())
+	require.NoError(t, err)
+
+	// The example pool rejects empty inputs
+	_, _, err = r.GetBestPool(ctx, nil, mintA.String(), mintB.String(), math.ZeroInt())
+	require.Error(t, err)
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "quote failed", line["msg"])
+	assert.Equal(t, pool.GetID(), line[pkg.LogKeyPool])
+	assert.Equal(t, mintA.String(), line[pkg.LogKeyInputMint])
+	assert.Equal(t, mintB.String(), line[pkg.LogKeyOutputMint])
+	assert.Contains(t, line[pkg.LogKeyError], "input amount must be positive")
+	assert.Contains(t, line, pkg.LogKeyLatency)
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/meteora/dammv2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// putU128 writes a little-endian u128
+func putU128(data []byte, offset int, v *big.Int) {
+	be := v.FillBytes(make([]byte, 16))
+	for i := range 16 {
+		data[offset+i] = be[15-i]
+	}
+}
+
+// dammV2PoolData encodes a pool at price 1 with 1e9 of each token in virtual reserves,
+// activated at slot 1000
+func dammV2PoolData() []byte {
+	q64 := new(big.Int).Lsh(big.NewInt(1), 64)
+	data := make([]byte, dammv2.PoolAccountSize)
+	copy(data, dammv2.PoolDiscriminator[:])
+	binary.LittleEndian.PutUint64(data[8:], 2_500_000) // 0.25% cliff fee
+	binary.LittleEndian.PutUint16(data[22:], 10)       // number_of_period
+	binary.LittleEndian.PutUint64(data[24:], 100)      // period_frequency
+	binary.LittleEndian.PutUint64(data[32:], 100_000)  // reduction_factor
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	copy(data[dammv2.TokenAMintOffset:], mintA.Bytes())
+	copy(data[dammv2.TokenBMintOffset:], mintB.Bytes())
+	putU128(data, 360, new(big.Int).Mul(big.NewInt(1_000_000_000), q64))
+	putU128(data, 424, big.NewInt(4295048016))
+	putU128(data, 440, new(big.Int).Lsh(big.NewInt(1), 100))
+	putU128(data, 456, q64)
+	binary.LittleEndian.PutUint64(data[472:], 1000) // activation slot
+	return data
+}
+
+func newDammV2Pool(t *testing.T) *dammv2.Pool {
+	data := dammV2PoolData()
+	pool, err := dammv2.NewPool(solana.NewWallet().PublicKey(), data)
+	require.NoError(t, err)
+	require.Equal(t, data[dammv2.TokenAMintOffset:dammv2.TokenAMintOffset+32], pool.TokenAMint.Bytes())
+	pool.Slot = 1000
+	return pool
+}
+
+func TestMeteoraDammV2Quote(t *testing.T) {
+	pool := newDammV2Pool(t)
+	in := math.NewInt(1_000_000)
+
+	// 1e9*1e6/(1e9+1e6) = 999000, less a 0.25% fee rounded up
+	out, err := pool.QuoteOffline(pool.TokenAMint.String(), in)
+	require.NoError(t, err)
+	assert.Equal(t, "996502", out.String())
+	back, err := pool.QuoteOffline(pool.TokenBMint.String(), in)
+	require.NoError(t, err)
+	assert.Equal(t, "996502", back.String())
+
+	needed, err := pool.QuoteExactOutOffline(pool.TokenBMint.String(), out)
+	require.NoError(t, err)
+	assert.True(t, needed.LTE(in), "exact-out input %s", needed)
+	short, err := pool.QuoteOffline(pool.TokenAMint.String(), needed.SubRaw(1))
+	require.NoError(t, err)
+	assert.True(t, short.LT(out))
+
+	// The linear schedule takes 100_000 off the fee every 100 slots, for 10 periods
+	pool.Slot = 1250
+	fee, err := pool.TradeFeeNumerator()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2_300_000), fee)
+	pool.Slot = 1_000_000
+	fee, err = pool.TradeFeeNumerator()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1_500_000), fee)
+
+	pool.Slot = 999
+	_, err = pool.QuoteOffline(pool.TokenAMint.String(), in)
+	assert.Error(t, err, "pool isn't active yet")
+}
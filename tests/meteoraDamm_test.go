@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/meteora"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Quotes price against what the pool's vault LP tokens redeem for, less locked profit
+func TestMeteoraDammQuote(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	pool := &meteora.MeteoraDammPool{
+		PoolId:                      solana.NewWallet().PublicKey(),
+		TokenAMint:                  solana.NewWallet().PublicKey(),
+		TokenBMint:                  solana.NewWallet().PublicKey(),
+		Enabled:                     true,
+		TradeFeeNumerator:           25,
+		TradeFeeDenominator:         10000,
+		ProtocolTradeFeeNumerator:   20,
+		ProtocolTradeFeeDenominator: 100,
+		// Half of the 1e8 locked profit has been released after 500s at 0.1% per second
+		VaultA: meteora.DynamicVault{
+			TotalAmount:             1_000_000_000,
+			LastUpdatedLockedProfit: 100_000_000,
+			LastReport:              uint64(now.Unix()) - 500,
+			LockedProfitDegradation: 1_000_000_000,
+		},
+		VaultB:         meteora.DynamicVault{TotalAmount: 2_000_000_000},
+		PoolVaultALp:   500_000_000,
+		PoolVaultBLp:   1_000_000_000,
+		VaultALpSupply: 1_000_000_000,
+		VaultBLpSupply: 1_000_000_000,
+		TimeSource:     clock.NewFake(now),
+	}
+	assert.Equal(t, "950000000", pool.VaultA.UnlockedAmount(now.Unix()).String())
+
+	// Reserves are 475e6 A against 2e9 B
+	in := math.NewInt(1_000_000)
+	out, err := pool.QuoteOffline(pool.TokenAMint.String(), in)
+	require.NoError(t, err)
+	noFee := math.NewInt(2_000_000_000).Mul(in).Quo(math.NewInt(475_000_000).Add(in))
+	assert.True(t, out.IsPositive() && out.LT(noFee), "quote %s, fee-free %s", out, noFee)
+
+	needed, err := pool.QuoteExactOutOffline(pool.TokenBMint.String(), out)
+	require.NoError(t, err)
+	assert.True(t, needed.LTE(in), "exact-out input %s", needed)
+	back, err := pool.QuoteOffline(pool.TokenAMint.String(), needed)
+	require.NoError(t, err)
+	assert.True(t, back.GTE(out))
+	short, err := pool.QuoteOffline(pool.TokenAMint.String(), needed.SubRaw(1))
+	require.NoError(t, err)
+	assert.True(t, short.LT(out))
+
+	_, err = pool.QuoteExactOutOffline(pool.TokenBMint.String(), math.NewInt(2_000_000_000))
+	assert.Error(t, err)
+
+	pool.Enabled = false
+	_, err = pool.QuoteOffline(pool.TokenAMint.String(), in)
+	assert.Error(t, err)
+}
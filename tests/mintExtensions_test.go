@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// token2022Mint lays out a Token-2022 mint account with a transfer fee and a transfer hook
+func token2022Mint(olderBps, newerBps uint16, newerEpoch, maxFee uint64, hook solana.PublicKey) []byte {
+	data := make([]byte, 166)
+	data[165] = 1 // mint account type
+
+	fee := make([]byte, 108)
+	binary.LittleEndian.PutUint64(fee[80:88], maxFee)
+	binary.LittleEndian.PutUint16(fee[88:90], olderBps)
+	binary.LittleEndian.PutUint64(fee[90:98], newerEpoch)
+	binary.LittleEndian.PutUint64(fee[98:106], maxFee)
+	binary.LittleEndian.PutUint16(fee[106:108], newerBps)
+	data = binary.LittleEndian.AppendUint16(data, 1)
+	data = binary.LittleEndian.AppendUint16(data, 108)
+	data = append(data, fee...)
+
+	data = binary.LittleEndian.AppendUint16(data, 14)
+	data = binary.LittleEndian.AppendUint16(data, 64)
+	data = append(data, make([]byte, 32)...)
+	return append(data, hook.Bytes()...)
+}
+
+func TestParseMintExtensions(t *testing.T) {
+	mint, hook := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	ext, err := sol.ParseMintExtensions(mint, solana.Token2022ProgramID, token2022Mint(50, 100, 700, 5000, hook))
+	require.NoError(t, err)
+	require.NotNil(t, ext.TransferFee)
+	assert.Equal(t, hook, ext.TransferHook)
+	assert.True(t, ext.Affects())
+
+	// The newer fee applies from its epoch on
+	assert.Equal(t, uint16(50), ext.TransferFee.At(699).BasisPoints)
+	fee := ext.TransferFee.At(700)
+	assert.Equal(t, uint16(100), fee.BasisPoints)
+	assert.Equal(t, "1", fee.Fee(math.NewInt(1)).String(), "fees round up")
+	assert.Equal(t, "5000", fee.Fee(math.NewInt(10_000_000)).String(), "fees are capped")
+	amount := math.NewInt(123_457)
+	gross := amount.Add(fee.InverseFee(amount))
+	assert.Equal(t, amount.String(), gross.Sub(fee.Fee(gross)).String(), "the inverse fee nets the amount")
+
+	// Mints of the SPL Token program carry no extensions
+	ext, err = sol.ParseMintExtensions(mint, solana.TokenProgramID, make([]byte, 82))
+	require.NoError(t, err)
+	assert.False(t, ext.Affects())
+}
+
+func TestWrapPoolsTransferFee(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	pool := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 1e9}
+	client := rpc.New("http://127.0.0.1:0")
+	ctx := context.Background()
+
+	inspector := sol.NewMintInspector(client)
+	feeA, err := sol.ParseMintExtensions(mintA, solana.Token2022ProgramID, token2022Mint(100, 100, 0, 1e9, solana.PublicKey{}))
+	require.NoError(t, err)
+	inspector.SetMintExtensions(feeA)
+	inspector.SetMintExtensions(&sol.MintExtensions{Mint: mintB, Program: solana.TokenProgramID})
+
+	wrapped, err := inspector.WrapPools(ctx, []pkg.Pool{pool})
+	require.NoError(t, err)
+	require.Len(t, wrapped, 1)
+	assert.NotEqual(t, pkg.Pool(pool), wrapped[0])
+	assert.Equal(t, pool.GetID(), wrapped[0].GetID())
+
+	// The vault receives 1% less than the user sends
+	out, err := wrapped[0].Quote(ctx, client, mintA.String(), math.NewInt(1_000_000))
+	require.NoError(t, err)
+	assert.Equal(t, "989020", out.String())
+
+	in, err := wrapped[0].QuoteExactOut(ctx, client, mintB.String(), out)
+	require.NoError(t, err)
+	check, err := wrapped[0].Quote(ctx, client, mintA.String(), in)
+	require.NoError(t, err)
+	assert.True(t, check.GTE(out), "exact-out input %s only yields %s", in, check)
+}
+
+func TestCPMMToken2022TransferFee(t *testing.T) {
+	mock := sol.NewMockRPC()
+	pool := &raydium.CPMMPool{
+		PoolId:        solana.NewWallet().PublicKey(),
+		Token0Mint:    solana.NewWallet().PublicKey(),
+		Token1Mint:    solana.NewWallet().PublicKey(),
+		Token0Vault:   solana.NewWallet().PublicKey(),
+		Token1Vault:   solana.NewWallet().PublicKey(),
+		Token0Program: solana.Token2022ProgramID,
+		Token1Program: solana.TokenProgramID,
+	}
+	mock.SetTokenAccount(pool.Token0Vault, pool.Token0Mint, pool.PoolId, 1e9)
+	mock.SetTokenAccount(pool.Token1Vault, pool.Token1Mint, pool.PoolId, 1e9)
+	mock.SetAccount(pool.Token0Mint, solana.Token2022ProgramID, token2022Mint(100, 100, 0, 1e9, solana.PublicKey{}))
+	mock.SetAccount(pool.Token1Mint, solana.TokenProgramID, make([]byte, 82))
+	mock.SetAccount(solana.SysVarClockPubkey, solana.SystemProgramID, make([]byte, sol.ClockAccountDataSize))
+	ctx := context.Background()
+
+	// The vault receives 1% less than the user sends, as with the generic wrapper
+	out, err := pool.Quote(ctx, mock, pool.Token0Mint.String(), math.NewInt(1_000_000))
+	require.NoError(t, err)
+	plain := &raydium.CPMMPool{PoolId: pool.PoolId, Token0Mint: pool.Token0Mint, Token1Mint: pool.Token1Mint, Token0Vault: pool.Token0Vault, Token1Vault: pool.Token1Vault}
+	want, err := plain.Quote(ctx, mock, pool.Token0Mint.String(), math.NewInt(990_000))
+	require.NoError(t, err)
+	assert.Equal(t, want.String(), out.String())
+
+	// The output's fee is withheld on its way back to the user
+	back, err := pool.Quote(ctx, mock, pool.Token1Mint.String(), math.NewInt(1_000_000))
+	require.NoError(t, err)
+	gross, err := plain.Quote(ctx, mock, pool.Token1Mint.String(), math.NewInt(1_000_000))
+	require.NoError(t, err)
+	assert.True(t, back.LT(gross))
+
+	in, err := pool.QuoteExactOut(ctx, mock, pool.Token1Mint.String(), out)
+	require.NoError(t, err)
+	check, err := pool.Quote(ctx, mock, pool.Token0Mint.String(), in)
+	require.NoError(t, err)
+	assert.True(t, check.GTE(out), "exact-out input %s only yields %s", in, check)
+
+	// Pools applying the fees themselves aren't wrapped a second time
+	wrapped, err := sol.NewMintInspector(mock).WrapPools(ctx, []pkg.Pool{pool})
+	require.NoError(t, err)
+	assert.Equal(t, pkg.Pool(pool), wrapped[0])
+
+	instructions, err := pool.BuildSwapInstructions(ctx, mock, solana.NewWallet().PublicKey(), pool.Token0Mint.String(), math.NewInt(1_000_000), out)
+	require.NoError(t, err)
+	accounts := instructions[0].Accounts()
+	assert.Equal(t, solana.Token2022ProgramID, accounts[8].PublicKey)
+	assert.Equal(t, solana.TokenProgramID, accounts[9].PublicKey)
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg/protocol"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteAgainstMockRPC(t *testing.T) {
+	mock := sol.NewMockRPC()
+	mintA, mintB, mintC := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	shallow := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e6, ReserveB: 1e6}
+	deep := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintB, MintB: mintA, ReserveA: 1e9, ReserveB: 1e9}
+	other := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintC, ReserveA: 1e9, ReserveB: 1e9}
+	for _, pool := range []*exampledex.Pool{shallow, deep, other} {
+		mock.SetAccount(pool.ID, exampledex.ProgramID, pool.Encode())
+	}
+
+	client := &sol.Client{RpcClient: mock}
+	r := router.NewSimpleRouter(protocol.NewPluginProtocol(client, exampledex.Venue{}))
+	r.SetQuoteClient(mock)
+	ctx := context.Background()
+	pools, err := r.QueryAllPools(ctx, mintA.String(), mintB.String())
+	require.NoError(t, err)
+	require.Len(t, pools, 2)
+
+	best, out, err := r.GetBestPool(ctx, nil, mintA.String(), mintB.String(), math.NewInt(100000))
+	require.NoError(t, err)
+	assert.Equal(t, deep.GetID(), best.GetID())
+	assert.True(t, out.IsPositive())
+}
+
+func TestMockRPCRecordsTransactions(t *testing.T) {
+	mock := sol.NewMockRPC()
+	payer := solana.NewWallet().PrivateKey
+	client := &sol.Client{RpcClient: mock}
+	ctx := context.Background()
+
+	recent, err := mock.GetLatestBlockhash(ctx, "")
+	require.NoError(t, err)
+	transfer := system.NewTransferInstruction(1, payer.PublicKey(), solana.NewWallet().PublicKey()).Build()
+	sig, err := client.SendTx(ctx, recent.Value.Blockhash, []solana.PrivateKey{payer}, []solana.Instruction{transfer}, false)
+	require.NoError(t, err)
+
+	sent := mock.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, sig, sent[0].Signatures[0])
+	assert.Empty(t, mock.Simulated())
+}
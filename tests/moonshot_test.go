@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/moonshot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A fresh constant-product curve with the default 1% fee
+func TestMoonshotQuote(t *testing.T) {
+	mint := solana.NewWallet().PublicKey()
+	data := make([]byte, 82)
+	copy(data, moonshot.CurveAccountDiscriminator[:])
+	binary.LittleEndian.PutUint64(data[8:], 1_000_000_000_000_000_000)  // total supply
+	binary.LittleEndian.PutUint64(data[16:], 1_000_000_000_000_000_000) // curve amount: nothing sold yet
+	copy(data[24:], mint.Bytes())
+	data[56] = 9
+	data[58] = moonshot.CurveConstantProductV1
+	curve, err := moonshot.CurveAddress(mint)
+	require.NoError(t, err)
+	pool, err := moonshot.NewCurvePool(curve, data)
+	require.NoError(t, err)
+	pool.FeeBps = 100
+
+	// Without sales the curve starts at 1.073e18 virtual tokens against 30 SOL; a 1 SOL buy
+	// puts 0.99 SOL into it
+	wsol := solana.WrappedSol.String()
+	out, err := pool.QuoteOffline(wsol, math.NewInt(1_000_000_000))
+	require.NoError(t, err)
+	assert.Equal(t, "34277831558567279", out.String())
+
+	in, err := pool.QuoteExactOutOffline(mint.String(), out)
+	require.NoError(t, err)
+	assert.True(t, in.LTE(math.NewInt(1_000_000_000)), "exact-out input %s", in)
+	short, err := pool.QuoteOffline(wsol, in.SubRaw(1))
+	require.NoError(t, err)
+	assert.True(t, short.LT(out))
+
+	// After the buy, selling the tokens back returns the 0.99 SOL less another 1%
+	pool.CurveAmount -= out.Uint64()
+	back, err := pool.QuoteOffline(mint.String(), out)
+	require.NoError(t, err)
+	assert.InDelta(t, 980_100_000, back.Int64(), 2)
+	tokensIn, err := pool.QuoteExactOutOffline(wsol, back)
+	require.NoError(t, err)
+	assert.True(t, tokensIn.LTE(out))
+
+	_, err = pool.QuoteOffline(solana.NewWallet().PublicKey().String(), out)
+	assert.Error(t, err)
+}
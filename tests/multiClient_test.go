@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// throttledRPC answers every slot and account read with HTTP 429 while throttled
+type throttledRPC struct {
+	*sol.MockRPC
+	throttled bool
+	calls     int
+}
+
+func (r *throttledRPC) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	r.calls++
+	if r.throttled {
+		return nil, &jsonrpc.HTTPError{Code: http.StatusTooManyRequests}
+	}
+	return r.MockRPC.GetAccountInfo(ctx, account)
+}
+
+func (r *throttledRPC) GetSlot(ctx context.Context, commitment rpc.CommitmentType) (uint64, error) {
+	if r.throttled {
+		return 0, &jsonrpc.HTTPError{Code: http.StatusTooManyRequests}
+	}
+	return r.MockRPC.GetSlot(ctx, commitment)
+}
+
+func TestMultiClientFailover(t *testing.T) {
+	account, owner := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	primary := &throttledRPC{MockRPC: sol.NewMockRPC(), throttled: true}
+	backup := &throttledRPC{MockRPC: sol.NewMockRPC()}
+	for _, endpoint := range []*throttledRPC{primary, backup} {
+		endpoint.SetAccount(account, owner, []byte{1, 2, 3})
+	}
+	fake := clock.NewFake(time.Unix(0, 0))
+	m := sol.NewMultiClient()
+	m.SetClock(fake)
+	m.AddEndpoint("primary", primary)
+	m.AddEndpoint("backup", backup)
+	ctx := context.Background()
+
+	// Whichever endpoint round-robin starts with, the read lands on the backup
+	for range 2 {
+		resp, err := m.GetAccountInfo(ctx, account)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{1, 2, 3}, resp.Value.Data.GetBinary())
+	}
+	assert.Equal(t, 1, primary.calls, "throttled endpoint should cool down after failing")
+	status := m.Status()
+	assert.False(t, status[0].Healthy)
+	assert.True(t, status[1].Healthy)
+
+	// Missing accounts aren't the endpoint's fault
+	_, err := m.GetAccountInfo(ctx, solana.NewWallet().PublicKey())
+	require.ErrorIs(t, err, rpc.ErrNotFound)
+	assert.True(t, m.Status()[1].Healthy)
+
+	// A health check brings the endpoint back once it answers
+	primary.throttled = false
+	m.CheckHealth(ctx)
+	assert.True(t, m.Status()[0].Healthy)
+}
@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/phoenix"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type phoenixTestOrder struct {
+	price, lots, lastValidSlot uint64
+	free                       bool
+}
+
+// phoenixOrderTree lays out a sokoban tree whose allocator handed out one node per order;
+// free orders are chained onto the free list
+func phoenixOrderTree(capacity int, orders []phoenixTestOrder) []byte {
+	data := make([]byte, 32+64*capacity)
+	binary.LittleEndian.PutUint32(data[24:], uint32(len(orders)+1))
+	freeHead := uint32(0)
+	for i, order := range orders {
+		node := data[32+64*i:]
+		if order.free {
+			binary.LittleEndian.PutUint32(node, freeHead)
+			freeHead = uint32(i + 1)
+			continue
+		}
+		binary.LittleEndian.PutUint64(node[16:], order.price)
+		binary.LittleEndian.PutUint64(node[24:], uint64(i))
+		binary.LittleEndian.PutUint64(node[40:], order.lots)
+		binary.LittleEndian.PutUint64(node[48:], order.lastValidSlot)
+	}
+	binary.LittleEndian.PutUint32(data[28:], freeHead)
+	return data
+}
+
+// A SOL/USDC-like market: 0.001 SOL base lots, 1 micro-USDC quote lots and a tick of
+// 0.001 USDC, so one base lot at 150000 ticks costs 150000 quote lots
+func TestPhoenixMarketQuote(t *testing.T) {
+	baseMint, quoteMint := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	baseVault, quoteVault := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+
+	header := make([]byte, phoenix.MarketHeaderSize)
+	binary.LittleEndian.PutUint64(header[8:], phoenix.MarketStatusActive)
+	binary.LittleEndian.PutUint64(header[16:], 4) // bids
+	binary.LittleEndian.PutUint64(header[24:], 4) // asks
+	binary.LittleEndian.PutUint32(header[40:], 9)
+	copy(header[phoenix.BaseMintOffset:], baseMint.Bytes())
+	copy(header[80:], baseVault.Bytes())
+	binary.LittleEndian.PutUint64(header[112:], 1_000_000)
+	binary.LittleEndian.PutUint32(header[120:], 6)
+	copy(header[phoenix.QuoteMintOffset:], quoteMint.Bytes())
+	copy(header[160:], quoteVault.Bytes())
+	binary.LittleEndian.PutUint64(header[192:], 1)
+
+	data := append(header, make([]byte, 256)...)
+	for _, v := range []uint64{1000, 1000, 0, 5, 0, 0} { // lots per unit, tick size, seq, taker fee bps
+		data = binary.LittleEndian.AppendUint64(data, v)
+	}
+	data = append(data, phoenixOrderTree(4, []phoenixTestOrder{
+		{price: 149_000, lots: 1000},
+		{free: true},
+		{price: 150_000, lots: 500},
+		{price: 151_000, lots: 1000, lastValidSlot: 5},
+	})...)
+	data = append(data, phoenixOrderTree(4, []phoenixTestOrder{{price: 151_000, lots: 2000}})...)
+
+	id := solana.NewWallet().PublicKey()
+	pool, err := phoenix.NewMarketPool(id, data)
+	require.NoError(t, err)
+	pool.Slot = 10
+	require.Len(t, pool.Bids, 3)
+	assert.Equal(t, uint64(151_000), pool.Bids[0].PriceInTicks)
+	assert.Equal(t, uint64(150_000), pool.Bids[1].PriceInTicks)
+	require.Len(t, pool.Asks, 1)
+	assert.Nil(t, pool.Deprecation())
+	assert.Equal(t, baseVault, pool.TokenVaults()[0].Address)
+
+	// The expired bid is skipped: 500 lots at 150000 and 500 at 149000, less 5 bps
+	out, err := pool.QuoteOffline(baseMint.String(), math.NewInt(1_000_000_000))
+	require.NoError(t, err)
+	assert.Equal(t, "149425250", out.String())
+
+	// 10 USDC less the reserved fee buys 66 lots at 151000
+	out, err = pool.QuoteOffline(quoteMint.String(), math.NewInt(10_000_000))
+	require.NoError(t, err)
+	assert.Equal(t, "66000000", out.String())
+
+	in, err := pool.QuoteExactOutOffline(quoteMint.String(), math.NewInt(149_425_250))
+	require.NoError(t, err)
+	assert.Equal(t, "1000000000", in.String())
+	in, err = pool.QuoteExactOutOffline(baseMint.String(), math.NewInt(66_000_000))
+	require.NoError(t, err)
+	back, err := pool.QuoteOffline(quoteMint.String(), in)
+	require.NoError(t, err)
+	assert.Equal(t, "66000000", back.String())
+
+	_, err = pool.QuoteExactOutOffline(baseMint.String(), math.NewInt(3_000_000_000))
+	assert.Error(t, err)
+
+	user := solana.NewWallet().PublicKey()
+	insts, err := pool.BuildSwapInstructions(context.Background(), nil, user, baseMint.String(), math.NewInt(1_000_000_000), out)
+	require.NoError(t, err)
+	require.Len(t, insts, 1)
+	assert.Equal(t, phoenix.PHOENIX_PROGRAM_ID, insts[0].ProgramID())
+	assert.Len(t, insts[0].Accounts(), 9)
+	raw, err := insts[0].Data()
+	require.NoError(t, err)
+	// swap, IOC, ask, no price, then 1000 base lots
+	assert.Equal(t, []byte{0, 2, 1, 0}, raw[:4])
+	assert.Equal(t, uint64(1000), binary.LittleEndian.Uint64(raw[4:]))
+	assert.Len(t, raw, 4+32+1+1+16+1+2)
+}
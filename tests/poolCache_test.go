@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolCacheInvalidation(t *testing.T) {
+	protocol := &refetchingProtocol{mintA: solana.NewWallet().PublicKey(), mintB: solana.NewWallet().PublicKey()}
+	other := &refetchingProtocol{mintA: solana.NewWallet().PublicKey(), mintB: solana.NewWallet().PublicKey()}
+	mintA, mintB := protocol.mintA.String(), protocol.mintB.String()
+	cache := router.NewPoolCache(time.Hour, protocol)
+	ctx := context.Background()
+
+	first, err := cache.Get(ctx, mintA, mintB)
+	require.NoError(t, err)
+	cached, err := cache.Get(ctx, mintA, mintB)
+	require.NoError(t, err)
+	assert.Equal(t, first, cached)
+	assert.Equal(t, int32(1), protocol.scans.Load())
+
+	// Invalidating either mint order drops the pair
+	cache.Invalidate(mintB, mintA)
+	assert.Empty(t, cache.Pools())
+	refetched, err := cache.Get(ctx, mintA, mintB)
+	require.NoError(t, err)
+	assert.NotEqual(t, first[0].GetID(), refetched[0].GetID())
+	assert.Equal(t, int32(2), protocol.scans.Load())
+
+	// Invalidating another pair keeps this one
+	cache.Invalidate(other.mintA.String(), other.mintB.String())
+	assert.Len(t, cache.Pools(), 1)
+
+	cache.InvalidateAll()
+	assert.Empty(t, cache.Pools())
+	_, err = cache.Get(ctx, mintA, mintB)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), protocol.scans.Load())
+}
+
+func TestPoolCacheStartRefresh(t *testing.T) {
+	protocol := &refetchingProtocol{mintA: solana.NewWallet().PublicKey(), mintB: solana.NewWallet().PublicKey()}
+	mintA, mintB := protocol.mintA.String(), protocol.mintB.String()
+	cache := router.NewPoolCache(time.Hour, protocol)
+	cache.SetLogger(pkg.DiscardLogger)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Nothing is cached, so refreshing scans nothing
+	cache.StartRefresh(ctx, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	assert.Zero(t, protocol.scans.Load())
+
+	// The first background scan stops the refresh
+	protocol.onScan = func() {
+		if protocol.scans.Load() == 2 {
+			cancel()
+		}
+	}
+	first, err := cache.Get(context.Background(), mintA, mintB)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		pools := cache.Pools()
+		return len(pools) == 1 && pools[0].GetID() != first[0].GetID()
+	}, time.Second, time.Millisecond, "cached pairs are scanned again in the background")
+
+	// Refreshed pools are served without a scan of their own
+	refreshed := cache.Pools()
+	scans := protocol.scans.Load()
+	pools, err := cache.Get(context.Background(), mintA, mintB)
+	require.NoError(t, err)
+	assert.Equal(t, refreshed, pools)
+	assert.Equal(t, scans, protocol.scans.Load())
+
+	// A failing refresh keeps the cached pools
+	protocol.failing.Store(true)
+	refreshCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	cache.StartRefresh(refreshCtx, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, refreshed, cache.Pools())
+}
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scanningProtocol is a named staticProtocol counting its pair scans
+type scanningProtocol struct {
+	staticProtocol
+	scans int
+}
+
+func (p *scanningProtocol) Name() pkg.ProtocolName {
+	return "example_dex"
+}
+
+func (p *scanningProtocol) FetchPoolsByPair(ctx context.Context, baseMint, quoteMint string) ([]pkg.Pool, error) {
+	p.scans++
+	return p.staticProtocol.FetchPoolsByPair(ctx, baseMint, quoteMint)
+}
+
+func TestPoolRegistrySkipsScansAfterWarmStart(t *testing.T) {
+	pool := &exampledex.Pool{
+		ID:       solana.NewWallet().PublicKey(),
+		MintA:    solana.NewWallet().PublicKey(),
+		MintB:    solana.NewWallet().PublicKey(),
+		ReserveA: 1_000_000,
+		ReserveB: 1_000_000,
+		FeeBps:   30,
+	}
+	base, quote := pool.MintA.String(), pool.MintB.String()
+	path := filepath.Join(t.TempDir(), "pools.json")
+	ctx := context.Background()
+
+	cold := &scanningProtocol{staticProtocol: staticProtocol{pool}}
+	registry := router.NewPoolRegistry(path)
+	require.NoError(t, registry.Load(), "a missing snapshot is an empty registry")
+	r := router.NewSimpleRouter(cold)
+	r.SetPoolRegistry(registry)
+	_, err := r.QueryAllPools(ctx, base, quote)
+	require.NoError(t, err)
+	assert.Equal(t, 1, cold.scans)
+	require.NoError(t, registry.Save())
+
+	warm := &scanningProtocol{staticProtocol: staticProtocol{pool}}
+	restored := router.NewPoolRegistry(path)
+	require.NoError(t, restored.Load())
+	registered, ok := restored.Lookup("example_dex", quote, base)
+	require.True(t, ok, "pairs are registered in either mint order")
+	require.Len(t, registered, 1)
+	assert.Equal(t, pool.ID.String(), registered[0].ID)
+
+	r = router.NewSimpleRouter(warm)
+	r.SetPoolRegistry(restored)
+	pools, err := r.QueryAllPools(ctx, base, quote)
+	require.NoError(t, err)
+	require.Len(t, pools, 1)
+	assert.Equal(t, pool.ID.String(), pools[0].GetID())
+	assert.Zero(t, warm.scans, "registered pools are fetched by ID")
+
+	restored.Forget(base, quote)
+	assert.Zero(t, restored.Len())
+}
@@ -0,0 +1,40 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolScorerWeighsReliability(t *testing.T) {
+	base, quote := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	flaky := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: base, MintB: quote, ReserveA: 1_000_000, ReserveB: 1_010_000, FeeBps: 30}
+	steady := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: base, MintB: quote, ReserveA: 1_000_000, ReserveB: 1_000_000, FeeBps: 30}
+	r := router.NewSimpleRouter(staticProtocol{flaky, steady})
+	r.SetQuoteClient(rpc.New("http://127.0.0.1:0"))
+	ctx := context.Background()
+	_, err := r.QueryAllPools(ctx, base.String(), quote.String())
+	require.NoError(t, err)
+
+	best, _, err := r.GetBestPool(ctx, nil, base.String(), quote.String(), math.NewInt(1000))
+	require.NoError(t, err)
+	assert.Equal(t, flaky.GetID(), best.GetID(), "without a scorer the largest output wins")
+
+	for range 5 {
+		r.RecordSwapResult(flaky.GetID(), errors.New("slippage exceeded"))
+	}
+	assert.Greater(t, r.PoolFailureRate(flaky.GetID()), 0.3)
+	r.SetPoolScorer(router.WeightedScorer{FailureWeight: 1})
+	best, out, err := r.GetBestPool(ctx, nil, base.String(), quote.String(), math.NewInt(1000))
+	require.NoError(t, err)
+	assert.Equal(t, steady.GetID(), best.GetID())
+	assert.True(t, out.IsPositive())
+}
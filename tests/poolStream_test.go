@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chanStream hands out a test-fed channel, standing in for a geyser subscription
+type chanStream struct {
+	updates  chan sol.AccountUpdate
+	programs []solana.PublicKey
+}
+
+func (s *chanStream) Subscribe(ctx context.Context, programs []solana.PublicKey) (<-chan sol.AccountUpdate, error) {
+	s.programs = programs
+	return s.updates, nil
+}
+
+func TestPoolCacheAppliesStreamedUpdates(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	pool := &raydium.CPMMPool{PoolId: solana.NewWallet().PublicKey(), Token0Mint: mintA, Token1Mint: mintB}
+	cache := router.NewPoolCache(time.Minute, staticProtocol{pool})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err := cache.Get(ctx, mintA.String(), mintB.String())
+	require.NoError(t, err)
+
+	stream := &chanStream{updates: make(chan sol.AccountUpdate)}
+	require.NoError(t, cache.Stream(ctx, stream))
+	close(stream.updates)
+	assert.Equal(t, []solana.PublicKey{raydium.RAYDIUM_CPMM_PROGRAM_ID}, stream.programs)
+
+	vault := solana.NewWallet().PublicKey()
+	data := make([]byte, pool.Span())
+	copy(data[8+32*2:], vault[:])
+	copy(data[8+32*5:], mintA[:])
+	copy(data[8+32*6:], mintB[:])
+	stale := make([]byte, pool.Span())
+	copy(stale[8+32*5:], mintA[:])
+	copy(stale[8+32*6:], mintB[:])
+	assert.True(t, cache.ApplyUpdate(sol.AccountUpdate{Pubkey: pool.PoolId, Slot: 10, Data: data}))
+	assert.True(t, pool.Token0Vault.IsZero(), "pools already handed out aren't written")
+	pools, err := cache.Get(ctx, mintA.String(), mintB.String())
+	require.NoError(t, err)
+	require.Len(t, pools, 1)
+	updated := pools[0].(*raydium.CPMMPool)
+	assert.NotSame(t, pool, updated)
+	assert.Equal(t, vault, updated.Token0Vault)
+	assert.Equal(t, pool.PoolId.String(), updated.GetID())
+	assert.False(t, cache.ApplyUpdate(sol.AccountUpdate{Pubkey: pool.PoolId, Slot: 9, Data: stale}), "older updates are dropped")
+	pools, err = cache.Get(ctx, mintA.String(), mintB.String())
+	require.NoError(t, err)
+	assert.Same(t, updated, pools[0])
+	assert.False(t, cache.ApplyUpdate(sol.AccountUpdate{Pubkey: vault, Slot: 11, Data: data}), "only pool accounts are decoded")
+}
+
+// Run with -race: streamed updates replace pools that concurrent quotes are refreshing
+func TestPoolCacheStreamsWhileQuoting(t *testing.T) {
+	pool, mock := clmmPoolWithRange(t)
+	data := make([]byte, pool.Span())
+	copy(data[pool.Offset("TokenMint0"):], pool.TokenMint0[:])
+	copy(data[pool.Offset("TokenMint1"):], pool.TokenMint1[:])
+	binary.LittleEndian.PutUint16(data[pool.Offset("TickSpacing"):], pool.TickSpacing)
+	pool.Liquidity.PutBytes(data[pool.Offset("Liquidity"):])
+	pool.SqrtPriceX64.PutBytes(data[pool.Offset("SqrtPriceX64"):])
+	binary.LittleEndian.PutUint32(data[pool.Offset("TickCurrent"):], uint32(pool.TickCurrent))
+	binary.LittleEndian.PutUint64(data[pool.Offset("TickArrayBitmap")+8*8:], pool.TickArrayBitmap[8])
+
+	cache := router.NewPoolCache(time.Minute, staticProtocol{pool})
+	r := router.NewSimpleRouter()
+	r.SetPoolCache(cache)
+	r.SetQuoteClient(mock)
+	ctx := context.Background()
+	mint0, mint1 := pool.TokenMint0.String(), pool.TokenMint1.String()
+	_, err := r.QueryAllPools(ctx, mint0, mint1)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				cache.ApplyUpdate(sol.AccountUpdate{Pubkey: pool.PoolId, Slot: uint64(10*j + i), Data: data})
+				_, err := r.QueryAllPools(ctx, mint0, mint1)
+				if !assert.NoError(t, err) {
+					return
+				}
+				_, out, err := r.GetBestPool(ctx, nil, mint0, mint1, math.NewInt(1_000_000))
+				if assert.NoError(t, err) {
+					assert.True(t, out.IsPositive())
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.True(t, cache.ApplyUpdate(sol.AccountUpdate{Pubkey: pool.PoolId, Slot: 100, Data: data}))
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/phoenix"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listedProtocol discovers a fixed set of pools and re-fetches them from another set by ID,
+// so tests can change what validation sees on-chain
+type listedProtocol struct {
+	listed  []pkg.Pool
+	fetched map[string]pkg.Pool
+}
+
+func (p *listedProtocol) FetchPoolsByPair(ctx context.Context, baseMint, quoteMint string) ([]pkg.Pool, error) {
+	return p.listed, nil
+}
+
+func (p *listedProtocol) FetchPoolByID(ctx context.Context, poolID string) (pkg.Pool, error) {
+	pool, ok := p.fetched[poolID]
+	if !ok {
+		return nil, fmt.Errorf("failed to decode pool %s", poolID)
+	}
+	return pool, nil
+}
+
+func TestPoolCacheValidateRejectsInvalidPools(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	mock := sol.NewMockRPC()
+	protocol := &listedProtocol{fetched: make(map[string]pkg.Pool)}
+	dexPool := func(reserveB uint64) *exampledex.Pool {
+		return &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: reserveB}
+	}
+	add := func(listed, fetched pkg.Pool, owner solana.PublicKey) string {
+		protocol.listed = append(protocol.listed, listed)
+		if fetched != nil {
+			protocol.fetched[listed.GetID()] = fetched
+		}
+		if !owner.IsZero() {
+			mock.SetAccount(solana.MustPublicKeyFromBase58(listed.GetID()), owner, make([]byte, 8))
+		}
+		return listed.GetID()
+	}
+	market := func(status uint64, quoteVaultMint solana.PublicKey) *phoenix.MarketPool {
+		id := solana.NewWallet().PublicKey()
+		pool := &phoenix.MarketPool{PoolId: id, Market: phoenix.Market{Status: status, BaseMint: mintA, QuoteMint: mintB}}
+		for _, vault := range []struct {
+			address *solana.PublicKey
+			mint    solana.PublicKey
+			holds   solana.PublicKey
+		}{{&pool.BaseVault, mintA, mintA}, {&pool.QuoteVault, mintB, quoteVaultMint}} {
+			address, _, err := solana.FindProgramAddress([][]byte{[]byte("vault"), id.Bytes(), vault.mint.Bytes()}, phoenix.PHOENIX_PROGRAM_ID)
+			require.NoError(t, err)
+			*vault.address = address
+			mock.SetTokenAccount(address, vault.holds, address, 1)
+		}
+		return pool
+	}
+
+	healthy := dexPool(1e9)
+	fresh := *healthy
+	healthyID := add(healthy, &fresh, exampledex.ProgramID)
+	missingID := add(dexPool(1e9), nil, solana.PublicKey{})
+	otherProgram := solana.NewWallet().PublicKey()
+	reownedID := add(dexPool(1e9), nil, otherProgram)
+	undecodableID := add(dexPool(1e9), nil, exampledex.ProgramID)
+	reminted := dexPool(1e9)
+	remintedFresh := *reminted
+	remintedFresh.MintB = solana.NewWallet().PublicKey()
+	remintedID := add(reminted, &remintedFresh, exampledex.ProgramID)
+	spoofed := market(phoenix.MarketStatusActive, solana.NewWallet().PublicKey())
+	spoofedID := add(spoofed, spoofed, phoenix.PHOENIX_PROGRAM_ID)
+	closed := market(phoenix.MarketStatusClosed, mintB)
+	closedID := add(closed, closed, phoenix.PHOENIX_PROGRAM_ID)
+	drained := dexPool(0)
+	drainedID := add(drained, drained, exampledex.ProgramID)
+
+	cache := router.NewPoolCache(0, protocol)
+	ctx := context.Background()
+	_, err := cache.Get(ctx, mintA.String(), mintB.String())
+	require.NoError(t, err)
+	report, err := cache.Validate(ctx, mock, router.ValidateOptions{ProbeAmount: math.NewInt(1_000_000)})
+	require.NoError(t, err)
+	assert.Equal(t, 8, report.Checked)
+
+	issues := make(map[string]router.PoolIssue)
+	for _, issue := range report.Issues {
+		issues[issue.PoolID] = issue
+	}
+	assert.NotContains(t, issues, healthyID)
+	for id, want := range map[string]struct {
+		severity router.IssueSeverity
+		check    string
+		detail   string
+	}{
+		missingID:     {router.SeverityBroken, "account", "pool account not found"},
+		reownedID:     {router.SeverityBroken, "account", "owned by " + otherProgram.String()},
+		undecodableID: {router.SeverityBroken, "decode", "failed to decode pool"},
+		remintedID:    {router.SeverityBroken, "tokens", "mints changed"},
+		spoofedID:     {router.SeverityBroken, "vaults", "holds mint"},
+		closedID:      {router.SeveritySuspicious, "deprecated", "does not allow taker orders"},
+		drainedID:     {router.SeveritySuspicious, "quote", "returned 0"},
+	} {
+		issue, ok := issues[id]
+		require.True(t, ok, "%s has no issue", want.check)
+		assert.Equal(t, want.severity, issue.Severity, want.check)
+		assert.Equal(t, want.check, issue.Check)
+		assert.Contains(t, issue.Detail, want.detail, want.check)
+	}
+	assert.Len(t, report.Broken(), 5)
+	assert.Contains(t, report.String(), "checked 8 pools, 7 issues (5 broken)")
+
+	// Pools that re-fetched replace their cached copy
+	for _, pool := range cache.Pools() {
+		if pool.GetID() == healthyID {
+			assert.Same(t, &fresh, pool)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingRPC counts getMultipleAccounts requests
+type countingRPC struct {
+	*sol.MockRPC
+	batches atomic.Int32
+}
+
+func (r *countingRPC) GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error) {
+	r.batches.Add(1)
+	return r.MockRPC.GetMultipleAccountsWithOpts(ctx, accounts, opts)
+}
+
+func TestGetBestPoolPrefetchesQuoteAccounts(t *testing.T) {
+	mint0, mint1 := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	mock := &countingRPC{MockRPC: sol.NewMockRPC()}
+	var pools staticProtocol
+	for _, reserve := range []uint64{1e6, 1e9, 1e8} {
+		pool := &raydium.CPMMPool{
+			PoolId:      solana.NewWallet().PublicKey(),
+			Token0Mint:  mint0,
+			Token1Mint:  mint1,
+			Token0Vault: solana.NewWallet().PublicKey(),
+			Token1Vault: solana.NewWallet().PublicKey(),
+		}
+		mock.SetTokenAccount(pool.Token0Vault, mint0, pool.PoolId, reserve)
+		mock.SetTokenAccount(pool.Token1Vault, mint1, pool.PoolId, reserve)
+		pools = append(pools, pool)
+	}
+
+	r := router.NewSimpleRouter(pools)
+	r.SetQuoteClient(mock)
+	ctx := context.Background()
+	_, err := r.QueryAllPools(ctx, mint0.String(), mint1.String())
+	require.NoError(t, err)
+
+	best, out, err := r.GetBestPool(ctx, nil, mint0.String(), mint1.String(), math.NewInt(1e6))
+	require.NoError(t, err)
+	assert.Equal(t, pools[1].GetID(), best.GetID())
+	assert.True(t, out.IsPositive())
+	assert.Equal(t, int32(1), mock.batches.Load(), "every vault should come from one batched read")
+
+	// Without prefetching each pool reads its own vaults
+	mock.batches.Store(0)
+	r.SetPrefetch(false)
+	_, _, err = r.GetBestPool(ctx, nil, mint0.String(), mint1.String(), math.NewInt(1e6))
+	require.NoError(t, err)
+	assert.Equal(t, int32(len(pools)), mock.batches.Load())
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"math/big"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gtdvccc/SolRouteTmp/pkg/price"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pythPriceUpdate encodes a fully verified PriceUpdateV2 account
+func pythPriceUpdate(price int64, conf uint64, exponent int32, publishTime time.Time) []byte {
+	data := make([]byte, 8+32+1+32)
+	data[8+32] = 1
+	data = binary.LittleEndian.AppendUint64(data, uint64(price))
+	data = binary.LittleEndian.AppendUint64(data, conf)
+	data = binary.LittleEndian.AppendUint32(data, uint32(exponent))
+	data = binary.LittleEndian.AppendUint64(data, uint64(publishTime.Unix()))
+	return append(data, make([]byte, 8*4)...)
+}
+
+// switchboardPullFeed encodes a PullFeed account whose current result is value, scaled by 10^18
+func switchboardPullFeed(value *big.Int, updated time.Time) []byte {
+	data := make([]byte, 3208)
+	binary.LittleEndian.PutUint64(data[2216:], uint64(updated.Unix()))
+	be := value.FillBytes(make([]byte, 16))
+	for i := range be {
+		data[2264+i] = be[15-i]
+	}
+	return data
+}
+
+func TestPriceGuard(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	fair := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 2e9}
+	manipulated := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 3e9}
+
+	// A is worth $2 on Pyth and B $1 on Switchboard
+	now := time.Unix(1_700_000_000, 0)
+	clk := clock.NewFake(now)
+	feedA, feedB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	mock := sol.NewMockRPC()
+	mock.SetAccount(feedA, solana.NewWallet().PublicKey(), pythPriceUpdate(200_000_000, 100_000, -8, now))
+	mock.SetAccount(feedB, solana.NewWallet().PublicKey(), switchboardPullFeed(new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil), now))
+	oracle := price.NewFeedOracle(mock)
+	oracle.SetClock(clk)
+	oracle.SetFeed(mintA.String(), price.Feed{Account: feedA, Kind: price.FeedPyth})
+	oracle.SetFeed(mintB.String(), price.Feed{Account: feedB, Kind: price.FeedSwitchboard})
+
+	reference, err := oracle.Price(context.Background(), mintA.String(), mintB.String())
+	require.NoError(t, err)
+	assert.InDelta(t, 2, reference.Price, 1e-12)
+	assert.InDelta(t, 0.001, reference.Confidence, 1e-12)
+
+	r := router.NewSimpleRouter(staticProtocol{fair, manipulated})
+	r.SetQuoteClient(rpc.New("http://127.0.0.1:0"))
+	r.SetMintDecimals(mintA.String(), 6)
+	r.SetMintDecimals(mintB.String(), 6)
+	ctx := context.Background()
+	_, err = r.QueryAllPools(ctx, mintA.String(), mintB.String())
+	require.NoError(t, err)
+	quote := func() (string, error) {
+		best, _, err := r.GetBestPool(ctx, nil, mintA.String(), mintB.String(), math.NewInt(1_000_000))
+		if err != nil {
+			return "", err
+		}
+		return best.GetID(), nil
+	}
+
+	best, err := quote()
+	require.NoError(t, err)
+	assert.Equal(t, manipulated.GetID(), best, "the skewed pool pays the most")
+
+	r.SetPriceGuard(router.NewPriceGuard(oracle, router.DefaultMaxOracleDeviationBps))
+	best, err = quote()
+	require.NoError(t, err)
+	assert.Equal(t, fair.GetID(), best)
+
+	r.SetPriceGuard(router.NewPriceGuard(oracle, 1))
+	_, err = quote()
+	assert.ErrorIs(t, err, router.ErrOracleDeviation)
+
+	clk.Advance(2 * price.DefaultMaxOracleAge)
+	_, err = quote()
+	assert.ErrorIs(t, err, price.ErrStalePrice)
+}
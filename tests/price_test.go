@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+
+	cosmath "cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/pump"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/gtdvccc/SolRouteTmp/pkg/price"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"lukechampine.com/uint128"
+)
+
+var (
+	_ pkg.SpotPricePool = (*raydium.AMMPool)(nil)
+	_ pkg.SpotPricePool = (*raydium.CPMMPool)(nil)
+	_ pkg.SpotPricePool = (*raydium.CLMMPool)(nil)
+	_ pkg.SpotPricePool = (*pump.PumpAMMPool)(nil)
+)
+
+func TestPriceAggregate(t *testing.T) {
+	base, quote := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	mock := sol.NewMockRPC()
+	pumpPool := func(baseReserve, quoteReserve uint64) *pump.PumpAMMPool {
+		pool := &pump.PumpAMMPool{
+			PoolId:                solana.NewWallet().PublicKey(),
+			BaseMint:              base,
+			QuoteMint:             quote,
+			PoolBaseTokenAccount:  solana.NewWallet().PublicKey(),
+			PoolQuoteTokenAccount: solana.NewWallet().PublicKey(),
+		}
+		mock.SetTokenAccount(pool.PoolBaseTokenAccount, base, pool.PoolId, baseReserve)
+		mock.SetTokenAccount(pool.PoolQuoteTokenAccount, quote, pool.PoolId, quoteReserve)
+		return pool
+	}
+	deep := pumpPool(1_000_000_000_000, 150_000_000_000_000)
+	shallow := pumpPool(1_000_000, 300_000_000)
+	// A CLMM pool holding the pair the other way round, at 152 quote per base
+	sqrtPrice, _ := new(big.Float).Mul(big.NewFloat(math.Sqrt(1.0/152)), new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), 64))).Int(nil)
+	clmm := &raydium.CLMMPool{
+		PoolId:       solana.NewWallet().PublicKey(),
+		TokenMint0:   quote,
+		TokenMint1:   base,
+		Liquidity:    uint128.From64(1_000_000_000_000),
+		SqrtPriceX64: uint128.FromBig(sqrtPrice),
+	}
+
+	market, err := price.Aggregate(context.Background(), mock, []pkg.Pool{shallow, clmm, deep}, base.String(), quote.String())
+	require.NoError(t, err)
+	require.Len(t, market.Mids, 3)
+	assert.Empty(t, market.Failed)
+	assert.InDelta(t, 150, market.Mids[0].Price, 1e-9)
+	assert.InDelta(t, 152, market.Mids[1].Price, 1e-6)
+	assert.Equal(t, clmm, market.Mids[1].Pool)
+	// The deep pool outweighs the others, so the shallow one can't drag the price up
+	assert.InDelta(t, 150, market.Price, 1e-9)
+
+	outliers := market.Outliers(500)
+	require.Len(t, outliers, 1)
+	assert.Equal(t, shallow, outliers[0].Pool)
+
+	// Selling 1 base for 148 quote is within 2%; 100 quote is not
+	assert.NoError(t, market.CheckQuote(base.String(), cosmath.NewInt(1_000_000_000), cosmath.NewInt(148_000_000_000), 200))
+	err = market.CheckQuote(base.String(), cosmath.NewInt(1_000_000_000), cosmath.NewInt(100_000_000_000), 200)
+	assert.True(t, errors.Is(err, price.ErrOffMarket))
+	// Buying prices the quote's input per output
+	assert.NoError(t, market.CheckQuote(quote.String(), cosmath.NewInt(151_000_000_000), cosmath.NewInt(1_000_000_000), 200))
+
+	_, err = price.Aggregate(context.Background(), mock, []pkg.Pool{deep}, base.String(), solana.NewWallet().PublicKey().String())
+	assert.True(t, errors.Is(err, price.ErrNoPrice))
+}
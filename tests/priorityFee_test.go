@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func staticFees(fees ...uint64) sol.PriorityFeeSourceFunc {
+	return func(ctx context.Context, accounts []solana.PublicKey) ([]uint64, error) {
+		return fees, nil
+	}
+}
+
+func TestPriorityFeeLevels(t *testing.T) {
+	oracle := sol.NewPriorityFeeOracle(&sol.Client{RpcClient: sol.NewMockRPC()})
+	ctx := context.Background()
+
+	for name, tc := range map[string]struct {
+		fees []uint64
+		want sol.PriorityFeeLevels
+	}{
+		"empty":         {want: sol.PriorityFeeLevels{}},
+		"single sample": {fees: []uint64{7}, want: sol.PriorityFeeLevels{P50: 7, P75: 7, P95: 7}},
+		"all zero":      {fees: []uint64{0, 0, 0, 0}, want: sol.PriorityFeeLevels{}},
+		// Nearest rank of ten samples: the 5th, 8th and 10th, whatever order they arrive in
+		"ten samples": {fees: []uint64{100, 30, 70, 10, 90, 50, 20, 80, 60, 40}, want: sol.PriorityFeeLevels{P50: 50, P75: 80, P95: 100}},
+		"two samples": {fees: []uint64{5, 1}, want: sol.PriorityFeeLevels{P50: 1, P75: 5, P95: 5}},
+	} {
+		oracle.SetSource(staticFees(tc.fees...))
+		levels, err := oracle.Levels(ctx, nil)
+		require.NoError(t, err, name)
+		assert.Equal(t, tc.want, levels, name)
+	}
+
+	oracle.SetSource(sol.PriorityFeeSourceFunc(func(ctx context.Context, accounts []solana.PublicKey) ([]uint64, error) {
+		return nil, fmt.Errorf("fee api down")
+	}))
+	_, err := oracle.Levels(ctx, nil)
+	assert.ErrorContains(t, err, "fee api down")
+}
+
+func TestPriorityFeeTiers(t *testing.T) {
+	levels := sol.PriorityFeeLevels{P50: 1, P75: 2, P95: 3}
+	for tier, want := range map[sol.FeeTier]uint64{sol.FeeTierEconomy: 1, sol.FeeTierNormal: 2, sol.FeeTierFast: 3} {
+		price, err := levels.ForTier(tier)
+		require.NoError(t, err, tier)
+		assert.Equal(t, want, price, tier)
+	}
+	_, err := levels.ForTier("turbo")
+	assert.ErrorContains(t, err, "unknown fee tier: turbo")
+
+	oracle := sol.NewPriorityFeeOracle(&sol.Client{RpcClient: sol.NewMockRPC()})
+	oracle.SetSource(staticFees(10, 20, 30, 40))
+	price, err := oracle.Strategy(sol.FeeTierNormal).ComputeUnitPrice(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(30), price)
+	_, err = oracle.Strategy("turbo").ComputeUnitPrice(context.Background(), nil)
+	assert.ErrorContains(t, err, "unknown fee tier")
+}
+
+func TestSendTxWithFeeTierReplacesPrice(t *testing.T) {
+	payer := solana.NewWallet().PrivateKey
+	recipient := solana.NewWallet().PublicKey()
+	mock := sol.NewMockRPC()
+	client := &sol.Client{RpcClient: mock}
+	client.PriorityFees = sol.NewPriorityFeeOracle(client)
+	var looked []solana.PublicKey
+	client.PriorityFees.SetSource(sol.PriorityFeeSourceFunc(func(ctx context.Context, accounts []solana.PublicKey) ([]uint64, error) {
+		looked = accounts
+		return []uint64{100, 200, 300, 400}, nil
+	}))
+	insts := []solana.Instruction{
+		computebudget.NewSetComputeUnitLimitInstruction(100_000).Build(),
+		computebudget.NewSetComputeUnitPriceInstruction(1).Build(),
+		system.NewTransferInstruction(1, payer.PublicKey(), recipient).Build(),
+	}
+
+	_, err := client.SendTxWithFeeTier(context.Background(), solana.Hash{1}, []solana.PrivateKey{payer}, insts, sol.FeeTierFast, true)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []solana.PublicKey{payer.PublicKey(), recipient}, looked, "fees are looked up for the writable accounts")
+
+	simulated := mock.Simulated()
+	require.Len(t, simulated, 1)
+	tx := simulated[0]
+	var prices []uint64
+	for _, inst := range tx.Message.Instructions {
+		programID, err := tx.ResolveProgramIDIndex(inst.ProgramIDIndex)
+		require.NoError(t, err)
+		if programID.Equals(solana.ComputeBudget) && inst.Data[0] == computebudget.Instruction_SetComputeUnitPrice {
+			prices = append(prices, binary.LittleEndian.Uint64(inst.Data[1:]))
+		}
+	}
+	assert.Equal(t, []uint64{400}, prices, "the caller's price is replaced, not doubled")
+	assert.Len(t, tx.Message.Instructions, 3)
+
+	_, err = client.SendTxWithFeeTier(context.Background(), solana.Hash{1}, []solana.PrivateKey{payer}, insts, "turbo", true)
+	assert.ErrorContains(t, err, "unknown fee tier")
+	assert.Len(t, mock.Simulated(), 1)
+}
@@ -0,0 +1,38 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/protocol"
+	"github.com/gtdvccc/SolRouteTmp/pkg/protocolregistry"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocolRegistry(t *testing.T) {
+	client := &sol.Client{RpcClient: sol.NewMockRPC()}
+
+	all, err := protocolregistry.Build(client)
+	require.NoError(t, err)
+	assert.Len(t, all, len(protocol.Defaults(client)))
+
+	protocols, err := protocolregistry.Build(client, protocolregistry.ParseNames(" orca_whirlpool, raydium_clmm,,orca_whirlpool")...)
+	require.NoError(t, err)
+	require.Len(t, protocols, 2)
+	assert.Equal(t, pkg.ProtocolNameOrcaWhirlpool, protocols[0].(pkg.NamedProtocol).Name())
+	assert.Equal(t, pkg.ProtocolNameRaydiumClmm, protocols[1].(pkg.NamedProtocol).Name())
+
+	_, err = protocolregistry.Build(client, "orca_whirpool")
+	assert.ErrorIs(t, err, protocolregistry.ErrUnknownProtocol)
+
+	// Out-of-tree protocols register without touching the built-ins
+	registry := protocolregistry.New()
+	require.NoError(t, registry.Register("my_dex", func(*sol.Client) pkg.Protocol { return staticProtocol{} }))
+	assert.ErrorIs(t, registry.Register("my_dex", func(*sol.Client) pkg.Protocol { return nil }), protocolregistry.ErrDuplicateProtocol)
+	protocols, err = registry.Build(client, "my_dex")
+	require.NoError(t, err)
+	assert.Equal(t, []pkg.Protocol{staticProtocol{}}, protocols)
+	assert.NotContains(t, protocolregistry.Names(), pkg.ProtocolName("my_dex"))
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/pump"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pumpGlobalConfig encodes a global config with the given fees and recipients
+func pumpGlobalConfig(lpFeeBps, protocolFeeBps, creatorFeeBps uint64, recipients ...solana.PublicKey) []byte {
+	data := make([]byte, 8+32+8+8+1+8*32+8)
+	binary.LittleEndian.PutUint64(data[40:], lpFeeBps)
+	binary.LittleEndian.PutUint64(data[48:], protocolFeeBps)
+	for i, recipient := range recipients {
+		copy(data[57+i*32:], recipient.Bytes())
+	}
+	binary.LittleEndian.PutUint64(data[57+8*32:], creatorFeeBps)
+	return data
+}
+
+func TestPumpGlobalConfig(t *testing.T) {
+	mock := sol.NewMockRPC()
+	recipient := solana.NewWallet().PublicKey()
+	mock.SetAccount(pump.PumpGlobalConfig, pump.PumpSwapProgramID, pumpGlobalConfig(20, 5, 5, recipient))
+	configs := pump.NewGlobalConfigCache()
+	clk := clock.NewFake(time.Unix(1_700_000_000, 0))
+	configs.SetClock(clk)
+
+	quoteMint := solana.NewWallet().PublicKey()
+	pool := &pump.PumpAMMPool{
+		PoolId:      solana.NewWallet().PublicKey(),
+		BaseMint:    solana.NewWallet().PublicKey(),
+		QuoteMint:   quoteMint,
+		CoinCreator: solana.NewWallet().PublicKey(),
+		Configs:     configs,
+	}
+	// Quotes use the default fee until the config is read
+	assert.EqualValues(t, 25, pool.FeeRateBps())
+
+	ctx := context.Background()
+	user := solana.NewWallet().PublicKey()
+	build := func() []*solana.AccountMeta {
+		instrs, err := pool.BuildSwapInstructions(ctx, mock, user, pool.QuoteMint.String(), math.NewInt(1e6), math.NewInt(1))
+		require.NoError(t, err)
+		require.Len(t, instrs, 1)
+		return instrs[0].Accounts()
+	}
+	accounts := build()
+	recipientATA, _, err := solana.FindAssociatedTokenAddress(recipient, quoteMint)
+	require.NoError(t, err)
+	assert.Equal(t, recipient, accounts[9].PublicKey)
+	assert.Equal(t, recipientATA, accounts[10].PublicKey)
+	creatorATA, err := pump.CoinCreatorVaultATA(pool.CoinCreator, quoteMint)
+	require.NoError(t, err)
+	assert.Equal(t, creatorATA, accounts[17].PublicKey)
+	assert.EqualValues(t, 30, pool.FeeRateBps())
+
+	// Rotated recipients are picked up once the cached config ages out
+	rotated := solana.NewWallet().PublicKey()
+	mock.SetAccount(pump.PumpGlobalConfig, pump.PumpSwapProgramID, pumpGlobalConfig(20, 5, 5, rotated))
+	assert.Equal(t, recipient, build()[9].PublicKey)
+	clk.Advance(pump.DefaultGlobalConfigMaxAge)
+	assert.Equal(t, rotated, build()[9].PublicKey)
+
+	// A config with no recipients can't be swapped against
+	mock.SetAccount(pump.PumpGlobalConfig, pump.PumpSwapProgramID, pumpGlobalConfig(20, 5, 5))
+	configs.Invalidate()
+	_, err = pool.BuildSwapInstructions(ctx, mock, user, pool.QuoteMint.String(), math.NewInt(1e6), math.NewInt(1))
+	assert.ErrorContains(t, err, "no protocol fee recipient")
+}
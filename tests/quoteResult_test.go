@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteResult(t *testing.T) {
+	pool := &exampledex.Pool{
+		ID:       solana.NewWallet().PublicKey(),
+		MintA:    solana.NewWallet().PublicKey(),
+		MintB:    solana.NewWallet().PublicKey(),
+		ReserveA: 1_000_000_000,
+		ReserveB: 50_000_000_000,
+		FeeBps:   30,
+	}
+	r := router.NewSimpleRouter(staticProtocol{pool})
+	r.SetQuoteClient(sol.NewMockRPC())
+	r.SetMintDecimals(pool.MintA.String(), 9)
+	r.SetMintDecimals(pool.MintB.String(), 6)
+	ctx := context.Background()
+	_, err := r.QueryAllPools(ctx, pool.MintA.String(), pool.MintB.String())
+	require.NoError(t, err)
+
+	result, err := r.Quote(ctx, nil, pool.MintA.String(), pool.MintB.String(), math.NewInt(10_000_000), pkg.SlippageBps(50))
+	require.NoError(t, err)
+	assert.True(t, result.ExpectedOut.IsPositive())
+	assert.Equal(t, result.ExpectedOut.MulRaw(9950).QuoRaw(10000), result.MinOut)
+	// Selling 1% of the input reserve moves the price by about 1%
+	require.True(t, result.PriceImpactKnown)
+	assert.InDelta(t, 99, result.PriceImpactBps, 2)
+	assert.Equal(t, uint8(6), result.Units.Output.Decimals)
+	require.Len(t, result.Hops, 1)
+	assert.Equal(t, router.RouteHop{
+		PoolID:     pool.GetID(),
+		Protocol:   pool.ProtocolName(),
+		Venue:      router.VenueName(pool.ProtocolName()),
+		ProgramID:  exampledex.ProgramID,
+		InputMint:  pool.MintA.String(),
+		OutputMint: pool.MintB.String(),
+	}, result.Hops[0])
+
+	_, err = r.Quote(ctx, nil, pool.MintA.String(), pool.MintB.String(), math.NewInt(10_000_000), pkg.SlippageBps(10001))
+	assert.Error(t, err)
+
+	// The same quote fails a tighter price impact ceiling
+	_, err = r.Quote(ctx, nil, pool.MintA.String(), pool.MintB.String(), math.NewInt(10_000_000), pkg.SlippageConfig{Bps: 50, MaxImpactBps: 50})
+	assert.ErrorIs(t, err, solerrors.ErrPriceImpactTooHigh)
+}
+
+func TestSlippageConfig(t *testing.T) {
+	expected := math.NewInt(1_000_000)
+
+	minOut, tolerance, err := pkg.SlippageBps(100).MinAmountOut(expected, 300, true)
+	require.NoError(t, err)
+	assert.Equal(t, math.NewInt(990_000), minOut)
+	assert.Equal(t, uint64(100), tolerance)
+
+	// Half the impact is added to the base tolerance, up to the cap
+	dynamic := pkg.SlippageConfig{Bps: 50, ImpactFactorBps: 5000, MaxBps: 200}
+	minOut, tolerance, err = dynamic.MinAmountOut(expected, 100, true)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), tolerance)
+	assert.Equal(t, math.NewInt(990_000), minOut)
+	_, tolerance, err = dynamic.MinAmountOut(expected, 1000, true)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(200), tolerance)
+
+	absolute := pkg.SlippageConfig{MinOut: math.NewInt(950_000)}
+	minOut, tolerance, err = absolute.MinAmountOut(expected, 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, math.NewInt(950_000), minOut)
+	assert.Equal(t, uint64(500), tolerance)
+	_, _, err = absolute.MinAmountOut(math.NewInt(900_000), 0, false)
+	assert.Error(t, err)
+
+	ceiling := pkg.SlippageConfig{Bps: 100, MaxImpactBps: 200}
+	_, _, err = ceiling.MinAmountOut(expected, 201, true)
+	assert.ErrorIs(t, err, solerrors.ErrPriceImpactTooHigh)
+	_, _, err = ceiling.MinAmountOut(expected, 0, false)
+	assert.ErrorIs(t, err, solerrors.ErrPriceImpactTooHigh)
+}
+
+func TestRefreshQuote(t *testing.T) {
+	pool := &exampledex.Pool{
+		ID:       solana.NewWallet().PublicKey(),
+		MintA:    solana.NewWallet().PublicKey(),
+		MintB:    solana.NewWallet().PublicKey(),
+		ReserveA: 1_000_000_000,
+		ReserveB: 1_000_000_000,
+		FeeBps:   30,
+	}
+	mock := sol.NewMockRPC()
+	mock.SetSlot(1234)
+	r := router.NewSimpleRouter(staticProtocol{pool})
+	r.SetQuoteClient(mock)
+	r.SetSlotPinning(rpc.CommitmentConfirmed)
+	r.SetMintDecimals(pool.MintA.String(), 9)
+	r.SetMintDecimals(pool.MintB.String(), 9)
+	ctx := context.Background()
+	_, err := r.QueryAllPools(ctx, pool.MintA.String(), pool.MintB.String())
+	require.NoError(t, err)
+
+	result, err := r.Quote(ctx, nil, pool.MintA.String(), pool.MintB.String(), math.NewInt(1_000_000), pkg.SlippageBps(100))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1234), result.Route.Slot)
+	assert.False(t, result.Route.QuotedAt.IsZero())
+
+	refresh, err := r.RefreshQuote(ctx, nil, result)
+	require.NoError(t, err)
+	assert.True(t, refresh.Satisfiable)
+	assert.Equal(t, result.ExpectedOut, refresh.Route.AmountOut)
+	assert.GreaterOrEqual(t, refresh.Age, time.Duration(0))
+
+	// Once the price moves past the tolerance the minimum output can't be met
+	mock.SetSlot(1240)
+	pool.ReserveB = 900_000_000
+	refresh, err = r.RefreshQuote(ctx, nil, result)
+	require.NoError(t, err)
+	assert.False(t, refresh.Satisfiable)
+	assert.True(t, refresh.Route.AmountOut.LT(result.MinOut))
+	assert.Equal(t, uint64(1240), refresh.Route.Slot)
+	assert.Equal(t, refresh.Route.AmountOut, refresh.Route.Units.Output.Raw)
+
+	r.SetProtocolEnabled(pool.ProtocolName(), false)
+	_, err = r.RefreshQuote(ctx, nil, result)
+	assert.Error(t, err, "paused pools fail the refresh")
+}
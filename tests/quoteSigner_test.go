@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A signed quote verifies for the executor until its TTL passes, and only for the quoted route
+func TestQuoteSignerTTL(t *testing.T) {
+	pool := &exampledex.Pool{
+		ID:       solana.NewWallet().PublicKey(),
+		MintA:    solana.NewWallet().PublicKey(),
+		MintB:    solana.NewWallet().PublicKey(),
+		ReserveA: 1_000_000_000,
+		ReserveB: 50_000_000_000,
+		FeeBps:   30,
+	}
+	now := time.Unix(1_700_000_000, 0)
+	signer := router.NewQuoteSigner(solana.NewWallet().PrivateKey, 5*time.Second)
+	signer.SetTimeSource(clock.NewFake(now))
+
+	in := math.NewInt(1_000_000)
+	quote, err := signer.Sign(router.NewQuoteSnapshot(pool, pool.MintA.String(), in, math.NewInt(49_000_000), 123))
+	require.NoError(t, err)
+	assert.Equal(t, now.Unix()+5, quote.Snapshot.ExpiresAt)
+	assert.Equal(t, pool.MintB.String(), quote.Snapshot.OutputMint)
+
+	require.NoError(t, quote.VerifyAt(signer.PublicKey(), now.Add(5*time.Second)))
+	assert.ErrorIs(t, quote.VerifyAt(signer.PublicKey(), now.Add(6*time.Second)), router.ErrQuoteExpired)
+	assert.Error(t, quote.VerifyAt(solana.NewWallet().PublicKey(), now), "untrusted signer")
+
+	// Extending the expiry breaks the signature
+	tampered := quote
+	tampered.Snapshot.ExpiresAt += 60
+	assert.Error(t, tampered.VerifyAt(signer.PublicKey(), now))
+
+	// The executor checks the route, and the minimum out it swaps with, on its own clock
+	verifier := router.NewQuoteVerifier(signer.PublicKey())
+	assert.ErrorIs(t, verifier.Verify(quote), router.ErrQuoteExpired, "the wall clock is long past the fake one")
+	clk := clock.NewFake(now)
+	verifier.SetTimeSource(clk)
+	require.NoError(t, verifier.VerifyRoute(quote, pool, pool.MintA.String(), in, math.NewInt(49_000_000)))
+	assert.ErrorContains(t, verifier.VerifyRoute(quote, pool, pool.MintB.String(), in, math.NewInt(49_000_000)), "input mint")
+	assert.ErrorContains(t, verifier.VerifyRoute(quote, pool, pool.MintA.String(), in.AddRaw(1), math.NewInt(49_000_000)), "amount in")
+	assert.ErrorContains(t, verifier.VerifyRoute(quote, pool, pool.MintA.String(), in, math.NewInt(48_999_999)), "quoted minimum out")
+	clk.Advance(6 * time.Second)
+	assert.ErrorIs(t, verifier.VerifyRoute(quote, pool, pool.MintA.String(), in, math.NewInt(49_000_000)), router.ErrQuoteExpired)
+
+	// A signer with slippage commits to less than the quoted output, and the commitment is signed
+	signer.SetSlippage(pkg.SlippageBps(100))
+	quote, err = signer.Sign(router.NewQuoteSnapshot(pool, pool.MintA.String(), in, math.NewInt(49_000_000), 123))
+	require.NoError(t, err)
+	assert.Equal(t, "48510000", quote.Snapshot.MinOut.String())
+	verifier.SetTimeSource(clock.NewFake(now))
+	require.NoError(t, verifier.VerifyRoute(quote, pool, pool.MintA.String(), in, math.NewInt(48_510_000)))
+	assert.ErrorContains(t, verifier.VerifyRoute(quote, pool, pool.MintA.String(), in, math.NewInt(49_000_001)), "exceeds the quoted")
+	tampered = quote
+	tampered.Snapshot.MinOut = math.NewInt(1)
+	assert.Error(t, verifier.Verify(tampered))
+}
+
+func TestSignedQuotePinsItsSlot(t *testing.T) {
+	pool := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: solana.NewWallet().PublicKey(), MintB: solana.NewWallet().PublicKey(), ReserveA: 1e9, ReserveB: 1e9}
+	mock := sol.NewMockRPC()
+	mock.SetSlot(321)
+	r := router.NewSimpleRouter(staticProtocol{pool})
+	r.SetQuoteClient(mock)
+	ctx := context.Background()
+	_, err := r.QueryAllPools(ctx, pool.MintA.String(), pool.MintB.String())
+	require.NoError(t, err)
+
+	signer := router.NewQuoteSigner(solana.NewWallet().PrivateKey, time.Minute)
+	quote, best, err := r.SignedQuote(ctx, nil, signer, pool.MintA.String(), pool.MintB.String(), math.NewInt(1_000))
+	require.NoError(t, err)
+	assert.Equal(t, pool.GetID(), best.GetID())
+	assert.Equal(t, uint64(321), quote.Snapshot.Slot, "quotes are pinned even with slot pinning off")
+	require.NoError(t, router.NewQuoteVerifier(signer.PublicKey()).Verify(quote))
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiterPacesBursts(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	limiter := sol.NewRateLimiter(10, 2)
+	limiter.SetClock(fake, fake)
+	for range 5 {
+		require.NoError(t, limiter.Wait(context.Background()))
+	}
+	// The burst passes at once, then one request per 100ms
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond}, fake.Sleeps())
+}
+
+// gatedRPC holds account reads until released and counts them
+type gatedRPC struct {
+	*sol.MockRPC
+	started chan struct{}
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (r *gatedRPC) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	if r.calls.Add(1) == 1 {
+		close(r.started)
+	}
+	<-r.release
+	return r.MockRPC.GetAccountInfo(ctx, account)
+}
+
+func TestLimitedRPCCoalescesAccountReads(t *testing.T) {
+	account := solana.NewWallet().PublicKey()
+	inner := &gatedRPC{MockRPC: sol.NewMockRPC(), started: make(chan struct{}), release: make(chan struct{})}
+	inner.SetAccount(account, solana.SystemProgramID, []byte{7})
+	limited := sol.NewLimitedRPC(inner, nil)
+	ctx := context.Background()
+
+	const callers = 8
+	results := make([]*rpc.GetAccountInfoResult, callers)
+	var wg sync.WaitGroup
+	read := func(i int) {
+		defer wg.Done()
+		resp, err := limited.GetAccountInfo(ctx, account)
+		assert.NoError(t, err)
+		results[i] = resp
+	}
+	wg.Add(callers)
+	go read(0)
+	<-inner.started
+	for i := 1; i < callers; i++ {
+		go read(i)
+	}
+	// Let the other callers join the read in flight
+	time.Sleep(50 * time.Millisecond)
+	close(inner.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), inner.calls.Load())
+	for _, resp := range results {
+		require.NotNil(t, resp)
+		assert.Equal(t, []byte{7}, resp.Value.Data.GetBinary())
+	}
+
+	// Reads after the first completes go to the node again
+	_, err := limited.GetAccountInfo(ctx, account)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), inner.calls.Load())
+}
@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRelayedTx(t *testing.T) {
+	relayer, user := solana.NewWallet().PrivateKey, solana.NewWallet().PrivateKey
+	usdc, usdt, other := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	policy := sol.RelayerPolicy{Relayer: relayer.PublicKey(), MinFees: map[solana.PublicKey]uint64{usdc: 1000, usdt: 1000}}
+	ata := func(owner, mint solana.PublicKey) solana.PublicKey {
+		address, _, err := solana.FindAssociatedTokenAddress(owner, mint)
+		require.NoError(t, err)
+		return address
+	}
+	transfer := func(amount uint64, mint, to solana.PublicKey) solana.Instruction {
+		return token.NewTransferInstruction(amount, ata(user.PublicKey(), mint), ata(to, mint), user.PublicKey(), nil).Build()
+	}
+	transferChecked := func(amount uint64, mint, to solana.PublicKey) solana.Instruction {
+		return token.NewTransferCheckedInstruction(amount, 6, ata(user.PublicKey(), mint), mint, ata(to, mint), user.PublicKey(), nil).Build()
+	}
+	swap := system.NewTransferInstruction(1, user.PublicKey(), solana.NewWallet().PublicKey()).Build()
+
+	for name, tc := range map[string]struct {
+		insts []solana.Instruction
+		err   string
+	}{
+		"transfer":                  {insts: []solana.Instruction{transfer(1000, usdc, relayer.PublicKey()), swap}},
+		"transfer checked":          {insts: []solana.Instruction{transferChecked(1000, usdt, relayer.PublicKey()), swap}},
+		"fee below minimum":         {insts: []solana.Instruction{transfer(999, usdc, relayer.PublicKey()), swap}, err: "below minimum"},
+		"checked fee below minimum": {insts: []solana.Instruction{transferChecked(999, usdc, relayer.PublicKey())}, err: "below minimum"},
+		"fee to another account":    {insts: []solana.Instruction{transfer(1000, usdc, solana.NewWallet().PublicKey()), swap}, err: "not paid to a relayer account"},
+		"fee in an unaccepted mint": {insts: []solana.Instruction{transfer(1000, other, relayer.PublicKey())}, err: "not paid to a relayer account"},
+		"checked unaccepted mint":   {insts: []solana.Instruction{transferChecked(1000, other, relayer.PublicKey())}, err: "not accepted"},
+		"relayer in later instruction": {
+			insts: []solana.Instruction{transfer(1000, usdc, relayer.PublicKey()), system.NewTransferInstruction(1, relayer.PublicKey(), user.PublicKey()).Build()},
+			err:   "instruction 1 references the relayer account",
+		},
+		"first instruction not token": {insts: []solana.Instruction{swap, transfer(1000, usdc, relayer.PublicKey())}, err: "not a token transfer"},
+		"checked mint of another fee account": {
+			insts: []solana.Instruction{token.NewTransferCheckedInstruction(1000, 6, ata(user.PublicKey(), usdc), usdc, ata(relayer.PublicKey(), usdt), user.PublicKey(), nil).Build()},
+			err:   "not paid to a relayer account",
+		},
+	} {
+		tx, err := solana.NewTransaction(tc.insts, solana.Hash{1}, solana.TransactionPayer(relayer.PublicKey()))
+		require.NoError(t, err, name)
+		_, err = tx.PartialSign(func(key solana.PublicKey) *solana.PrivateKey {
+			if key.Equals(user.PublicKey()) {
+				return &user
+			}
+			return nil
+		})
+		require.NoError(t, err, name)
+		err = sol.ValidateRelayedTx(tx, policy)
+		if tc.err == "" {
+			assert.NoError(t, err, name)
+		} else {
+			assert.ErrorContains(t, err, tc.err, name)
+		}
+	}
+}
+
+func TestValidateRelayedTxPriorityFeeCap(t *testing.T) {
+	relayer, user := solana.NewWallet().PrivateKey, solana.NewWallet().PrivateKey
+	mint := solana.NewWallet().PublicKey()
+	fee := sol.RelayerFee{Relayer: relayer.PublicKey(), Mint: mint, Amount: 1000}
+	policy := sol.RelayerPolicy{Relayer: relayer.PublicKey(), MinFees: map[solana.PublicKey]uint64{mint: 1000}, MaxPriorityFee: 10_000}
+	swap := system.NewTransferInstruction(1, user.PublicKey(), solana.NewWallet().PublicKey()).Build()
+	price := func(microLamports uint64) solana.Instruction {
+		return computebudget.NewSetComputeUnitPriceInstruction(microLamports).Build()
+	}
+	limit := func(units uint32) solana.Instruction {
+		return computebudget.NewSetComputeUnitLimitInstruction(units).Build()
+	}
+
+	for name, tc := range map[string]struct {
+		insts []solana.Instruction
+		err   string
+	}{
+		"no priority fee":    {insts: []solana.Instruction{swap}},
+		"at the cap":         {insts: []solana.Instruction{limit(100_000), price(100_000), swap}},
+		"rounded up":         {insts: []solana.Instruction{limit(100_000), price(100_001), swap}, err: "priority fee 10001 lamports above maximum 10000"},
+		"huge price":         {insts: []solana.Instruction{limit(100_000), price(1 << 62), swap}, err: "above maximum"},
+		"huge limit":         {insts: []solana.Instruction{limit(1_400_000), price(100_000), swap}, err: "priority fee 140000 lamports"},
+		"limit over the max": {insts: []solana.Instruction{limit(1 << 31), price(10_000), swap}, err: "priority fee 14000 lamports"},
+		// Without a limit each other instruction, the fee transfer included, gets 200k units
+		"default limit":      {insts: []solana.Instruction{price(25_000), swap}},
+		"default limit over": {insts: []solana.Instruction{price(25_001), swap}, err: "priority fee 10001 lamports"},
+		"malformed price":    {insts: []solana.Instruction{solana.NewInstruction(solana.ComputeBudget, nil, []byte{computebudget.Instruction_SetComputeUnitPrice, 1}), swap}, err: "invalid compute budget instruction 1"},
+	} {
+		tx, err := sol.BuildRelayedTx(solana.Hash{1}, user, fee, tc.insts)
+		require.NoError(t, err, name)
+		err = sol.ValidateRelayedTx(tx, policy)
+		if tc.err == "" {
+			assert.NoError(t, err, name)
+		} else {
+			assert.ErrorContains(t, err, tc.err, name)
+		}
+	}
+
+	// Without a cap no priority fee is accepted
+	policy.MaxPriorityFee = 0
+	tx, err := sol.BuildRelayedTx(solana.Hash{1}, user, fee, []solana.Instruction{limit(200_000), price(1), swap})
+	require.NoError(t, err)
+	assert.ErrorContains(t, sol.ValidateRelayedTx(tx, policy), "priority fee 1 lamports above maximum 0")
+	tx, err = sol.BuildRelayedTx(solana.Hash{1}, user, fee, []solana.Instruction{limit(1_400_000), swap})
+	require.NoError(t, err)
+	assert.NoError(t, sol.ValidateRelayedTx(tx, policy))
+}
+
+func TestSendRelayedTxSimulatesBeforeSigning(t *testing.T) {
+	relayer, user := solana.NewWallet().PrivateKey, solana.NewWallet().PrivateKey
+	mint := solana.NewWallet().PublicKey()
+	policy := sol.RelayerPolicy{Relayer: relayer.PublicKey(), MinFees: map[solana.PublicKey]uint64{mint: 1000}}
+	fee := sol.RelayerFee{Relayer: relayer.PublicKey(), Mint: mint, Amount: 1000}
+	mock := sol.NewMockRPC()
+	client := &sol.Client{RpcClient: mock}
+	ctx := context.Background()
+
+	tx, err := sol.BuildRelayedTx(solana.Hash{1}, user, fee, nil)
+	require.NoError(t, err)
+	mock.SetSimulation(rpc.SimulateTransactionResult{Err: map[string]any{"InstructionError": []any{0, "InvalidAccountData"}}})
+	_, err = client.SendRelayedTx(ctx, tx, relayer, policy, false)
+	assert.ErrorContains(t, err, "rejected relayed transaction")
+	require.Len(t, mock.Simulated(), 1)
+	assert.Empty(t, mock.Sent())
+	assert.Equal(t, solana.Signature{}, tx.Signatures[0], "failing transactions aren't signed")
+
+	mock.SetSimulation(rpc.SimulateTransactionResult{})
+	_, err = client.SendRelayedTx(ctx, tx, relayer, policy, true)
+	require.NoError(t, err)
+	assert.Empty(t, mock.Sent())
+	assert.Equal(t, solana.Signature{}, tx.Signatures[0])
+
+	sig, err := client.SendRelayedTx(ctx, tx, relayer, policy, false)
+	require.NoError(t, err)
+	require.Len(t, mock.Sent(), 1)
+	assert.Equal(t, tx.Signatures[0], sig)
+	assert.NotEqual(t, solana.Signature{}, sig)
+}
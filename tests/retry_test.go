@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gtdvccc/SolRouteTmp/pkg/retry"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedRand always draws the same value
+type fixedRand float64
+
+func (r fixedRand) Float64() float64 {
+	return float64(r)
+}
+
+var errUnavailable = &jsonrpc.HTTPError{Code: http.StatusServiceUnavailable}
+
+func TestRetryPolicyBacksOff(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	policy := retry.Policy{
+		MaxAttempts: 5,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    500 * time.Millisecond,
+		Jitter:      0.5,
+		Sleeper:     fake,
+		Rand:        fixedRand(0.5),
+	}
+	calls := 0
+	err := policy.Run(context.Background(), func(context.Context) error {
+		calls++
+		return errUnavailable
+	})
+	assert.ErrorIs(t, err, errUnavailable)
+	assert.Equal(t, 5, calls)
+	// Doubling from 100ms up to the 500ms cap, each shortened by a quarter of jitter
+	assert.Equal(t, []time.Duration{75 * time.Millisecond, 150 * time.Millisecond, 300 * time.Millisecond, 375 * time.Millisecond}, fake.Sleeps())
+
+	// Errors that aren't transient are returned at once
+	calls = 0
+	failed := errors.New("invalid account data")
+	err = policy.Run(context.Background(), func(context.Context) error {
+		calls++
+		return failed
+	})
+	assert.Equal(t, failed, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryPolicyStopsWhenContextDone(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	policy := retry.DefaultPolicy()
+	policy.Sleeper = fake
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := policy.Run(ctx, func(context.Context) error {
+		calls++
+		cancel()
+		return errUnavailable
+	})
+	assert.ErrorIs(t, err, errUnavailable)
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, fake.Sleeps())
+
+	err = policy.Run(ctx, func(context.Context) error {
+		t.Fatal("called with a done context")
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRetryBudget(t *testing.T) {
+	budget := retry.NewBudget(0.5, 1)
+	policy := retry.Policy{MaxAttempts: 3, Budget: budget, Sleeper: clock.NewFake(time.Unix(0, 0))}
+	calls := 0
+	failing := func(context.Context) error {
+		calls++
+		return errUnavailable
+	}
+
+	// The one retry held is spent, then the budget stops the third attempt
+	err := policy.Run(context.Background(), failing)
+	assert.ErrorIs(t, err, retry.ErrBudgetExhausted)
+	assert.ErrorIs(t, err, errUnavailable)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 0, budget.Remaining())
+
+	// Two more requests earn another retry
+	require.NoError(t, policy.Run(context.Background(), func(context.Context) error { return nil }))
+	calls = 0
+	assert.ErrorIs(t, policy.Run(context.Background(), failing), retry.ErrBudgetExhausted)
+	assert.Equal(t, 2, calls)
+}
+
+// flakyRPC fails account reads with a retryable error until failures runs out
+type flakyRPC struct {
+	*sol.MockRPC
+	failures atomic.Int32
+	calls    atomic.Int32
+}
+
+func (r *flakyRPC) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	r.calls.Add(1)
+	if r.failures.Add(-1) >= 0 {
+		return nil, &jsonrpc.HTTPError{Code: http.StatusTooManyRequests}
+	}
+	return r.MockRPC.GetAccountInfo(ctx, account)
+}
+
+func TestClientRetryPolicy(t *testing.T) {
+	account := solana.NewWallet().PublicKey()
+	flaky := &flakyRPC{MockRPC: sol.NewMockRPC()}
+	flaky.SetAccount(account, solana.SystemProgramID, []byte{1})
+	fake := clock.NewFake(time.Unix(0, 0))
+	client := &sol.Client{RpcClient: flaky, Sleeper: fake, Rand: fixedRand(0)}
+	ctx := context.Background()
+
+	client.SetRetryPolicy(retry.DefaultPolicy())
+	client.SetRateLimit(0, 0)
+	_, ok := client.RpcClient.(*sol.RetryRPC)
+	require.True(t, ok, "retries must stay outermost so each one is rate limited")
+	policy, ok := client.RetryPolicy()
+	require.True(t, ok)
+	assert.Equal(t, 3, policy.MaxAttempts)
+
+	flaky.failures.Store(2)
+	result, err := client.RpcClient.GetAccountInfo(ctx, account)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1}, result.Value.Data.GetBinary())
+	assert.Equal(t, int32(3), flaky.calls.Load())
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}, fake.Sleeps())
+
+	// Out of attempts, the last failure is returned
+	flaky.failures.Store(3)
+	_, err = client.RpcClient.GetAccountInfo(ctx, account)
+	var httpErr *jsonrpc.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusTooManyRequests, httpErr.Code)
+}
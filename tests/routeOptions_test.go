@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteOptionsConstrainPools(t *testing.T) {
+	base, quote := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	deep := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: base, MintB: quote, ReserveA: 1_000_000, ReserveB: 2_000_000, FeeBps: 30}
+	shallow := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: base, MintB: quote, ReserveA: 1_000_000, ReserveB: 1_000_000, FeeBps: 30}
+	r := router.NewSimpleRouter(staticProtocol{deep, shallow})
+	r.SetQuoteClient(rpc.New("http://127.0.0.1:0"))
+	ctx := context.Background()
+	_, err := r.QueryAllPools(ctx, base.String(), quote.String())
+	require.NoError(t, err)
+	amount := math.NewInt(1000)
+
+	best, _, err := r.GetBestPoolWithOptions(ctx, nil, base.String(), quote.String(), amount, router.RouteOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, deep.GetID(), best.GetID())
+
+	best, _, err = r.GetBestPoolWithOptions(ctx, nil, base.String(), quote.String(), amount, router.RouteOptions{ExcludePools: []string{deep.GetID()}})
+	require.NoError(t, err)
+	assert.Equal(t, shallow.GetID(), best.GetID())
+
+	best, _, err = r.GetBestPoolWithOptions(ctx, nil, base.String(), quote.String(), amount, router.RouteOptions{Pools: []string{shallow.GetID()}})
+	require.NoError(t, err)
+	assert.Equal(t, shallow.GetID(), best.GetID())
+
+	_, _, err = r.GetBestPoolWithOptions(ctx, nil, base.String(), quote.String(), amount, router.RouteOptions{Programs: []solana.PublicKey{solana.TokenProgramID}})
+	assert.True(t, errors.Is(err, solerrors.ErrNoRoute), "no pool runs on an allowed program")
+
+	// The options apply to one request only
+	best, _, err = r.GetBestPool(ctx, nil, base.String(), quote.String(), amount)
+	require.NoError(t, err)
+	assert.Equal(t, deep.GetID(), best.GetID())
+}
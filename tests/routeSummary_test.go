@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeRoute(t *testing.T) {
+	clmm := &raydium.CLMMPool{PoolId: solana.NewWallet().PublicKey()}
+	custom := &exampledex.Pool{ID: solana.NewWallet().PublicKey()}
+	// 1.5 SOL for 225 USDC against a spot price of 226
+	quote := router.RouteQuote{
+		AmountIn:      math.NewInt(1_500_000_000),
+		AmountOut:     math.NewInt(225_000_000),
+		SpotAmountOut: math.NewInt(226_000_000),
+		Pools:         []pkg.Pool{clmm, custom},
+	}
+	opts := router.SummaryOptions{
+		InputDecimals:    9,
+		OutputDecimals:   6,
+		SlippageBps:      50,
+		PlatformFeeBps:   20,
+		Signatures:       2,
+		ComputeUnitLimit: 200_000,
+		ComputeUnitPrice: 1_000,
+	}
+	summary, err := router.SummarizeRoute(quote, opts)
+	require.NoError(t, err)
+	assert.Equal(t, router.RouteSummary{
+		AmountIn:       "1.5",
+		AmountOut:      "224.55", // less the 0.45 platform fee
+		ExecutionPrice: "150",
+		MinReceived:    "223.42725",
+		PlatformFee:    "0.45",
+		NetworkFee:     "0.0000102", // two signatures plus 200 lamports of priority fee
+		PriceImpactPct: "0.44",
+		Route:          []string{"Raydium CLMM (" + clmm.GetID() + ")", "example_dex (" + custom.GetID() + ")"},
+	}, summary)
+
+	// Priority fees round up to the lamport; a quote better than spot has no impact and
+	// one without a spot price leaves it empty
+	opts.ComputeUnitLimit, opts.ComputeUnitPrice = 1, 1
+	quote.SpotAmountOut = math.NewInt(224_000_000)
+	summary, err = router.SummarizeRoute(quote, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "0.000010001", summary.NetworkFee)
+	assert.Equal(t, "0", summary.PriceImpactPct)
+	quote.SpotAmountOut = math.ZeroInt()
+	summary, err = router.SummarizeRoute(quote, opts)
+	require.NoError(t, err)
+	assert.Empty(t, summary.PriceImpactPct)
+
+	// Decimals come from the quote's units and must agree with the options
+	quote.Units = router.QuoteUnits{Input: router.AmountUnits{Mint: "SOL", Decimals: 9}, Output: router.AmountUnits{Mint: "USDC", Decimals: 6}}
+	summary, err = router.SummarizeRoute(quote, router.SummaryOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "225", summary.AmountOut)
+	assert.Equal(t, "0.000005", summary.NetworkFee)
+	_, err = router.SummarizeRoute(quote, router.SummaryOptions{OutputDecimals: 9})
+	assert.ErrorContains(t, err, "9 decimals given for USDC, which has 6")
+
+	_, err = router.SummarizeRoute(quote, router.SummaryOptions{SlippageBps: 10001})
+	assert.ErrorContains(t, err, "must not exceed 10000")
+	_, err = router.SummarizeRoute(router.RouteQuote{AmountIn: math.ZeroInt(), AmountOut: math.NewInt(1)}, opts)
+	assert.ErrorContains(t, err, "amounts are not set")
+}
+
+func TestFormatAmount(t *testing.T) {
+	european := router.NumberFormat{DecimalSeparator: ",", GroupSeparator: "."}
+	for _, tc := range []struct {
+		amount   int64
+		decimals uint8
+		format   router.NumberFormat
+		want     string
+	}{
+		{1_234_567_891_000, 6, router.NumberFormat{}, "1234567.891"},
+		{1_234_567_891_000, 6, router.NumberFormat{GroupSeparator: ","}, "1,234,567.891"},
+		{1_234_567_891_000, 6, european, "1.234.567,891"},
+		{123_456_000_000, 6, router.NumberFormat{GroupSeparator: ","}, "123,456"},
+		{999, 0, router.NumberFormat{GroupSeparator: ","}, "999"},
+		{1_999_999_999, 9, router.NumberFormat{MaxFractionDigits: 2}, "2"},
+		{1_234_999, 6, router.NumberFormat{MaxFractionDigits: 2}, "1.23"},
+		{1, 9, router.NumberFormat{}, "0"}, // below the default six fraction digits
+		{1, 9, router.NumberFormat{MaxFractionDigits: 9}, "0.000000001"},
+		{0, 6, european, "0"},
+	} {
+		assert.Equal(t, tc.want, router.FormatAmount(math.NewInt(tc.amount), tc.decimals, tc.format), "%d with %d decimals", tc.amount, tc.decimals)
+	}
+	assert.Empty(t, router.FormatAmount(math.Int{}, 6, router.NumberFormat{}))
+}
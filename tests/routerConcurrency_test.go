@@ -0,0 +1,223 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	solerrors "github.com/gtdvccc/SolRouteTmp/pkg/errors"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pairProtocol returns only the pools trading the requested pair
+type pairProtocol struct {
+	staticProtocol
+}
+
+func (p pairProtocol) FetchPoolsByPair(ctx context.Context, baseMint, quoteMint string) ([]pkg.Pool, error) {
+	var pools []pkg.Pool
+	for _, pool := range p.staticProtocol {
+		a, b := pool.GetTokens()
+		if (a == baseMint && b == quoteMint) || (a == quoteMint && b == baseMint) {
+			pools = append(pools, pool)
+		}
+	}
+	return pools, nil
+}
+
+func TestRouterConcurrentPairs(t *testing.T) {
+	mintA, mintB, mintC := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	poolAB := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 2e9}
+	poolAC := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintC, ReserveA: 1e9, ReserveB: 3e9}
+	r := router.NewSimpleRouter(pairProtocol{staticProtocol{poolAB, poolAC}})
+	r.SetQuoteClient(sol.NewMockRPC())
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokenOut, want := mintB, poolAB
+			if i%2 == 1 {
+				tokenOut, want = mintC, poolAC
+			}
+			_, err := r.QueryAllPools(ctx, mintA.String(), tokenOut.String())
+			if !assert.NoError(t, err) {
+				return
+			}
+			best, _, err := r.GetBestPool(ctx, nil, mintA.String(), tokenOut.String(), math.NewInt(1_000))
+			if assert.NoError(t, err) {
+				assert.Equal(t, want.GetID(), best.GetID(), "a quote only sees its own pair's pools")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Rediscovering a pair replaces its pools rather than adding to them
+	require.Len(t, r.Pools(mintA.String(), mintB.String()), 1)
+	assert.Equal(t, []pkg.Pool{poolAC}, r.Pools(mintC.String(), mintA.String()))
+}
+
+// Run with -race: quotes refresh the reserves and tick arrays of pools shared between them
+func TestRouterConcurrentQuotesSharePools(t *testing.T) {
+	clmm, mock := clmmPoolWithRange(t)
+	amm := &raydium.AMMPool{
+		PoolId:     solana.NewWallet().PublicKey(),
+		Status:     6,
+		LpReserve:  1,
+		BaseMint:   clmm.TokenMint0,
+		QuoteMint:  clmm.TokenMint1,
+		BaseVault:  solana.NewWallet().PublicKey(),
+		QuoteVault: solana.NewWallet().PublicKey(),
+	}
+	mock.SetTokenAccount(amm.BaseVault, amm.BaseMint, amm.PoolId, 1_000_000_000)
+	mock.SetTokenAccount(amm.QuoteVault, amm.QuoteMint, amm.PoolId, 1_000_000_000)
+	r := router.NewSimpleRouter(staticProtocol{clmm, amm})
+	r.SetQuoteClient(mock)
+	ctx := context.Background()
+	mint0, mint1 := clmm.TokenMint0.String(), clmm.TokenMint1.String()
+	r.SetMintDecimals(mint0, 6)
+	r.SetMintDecimals(mint1, 6)
+	_, err := r.QueryAllPools(ctx, mint0, mint1)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokenIn, tokenOut := mint0, mint1
+			if i%2 == 1 {
+				tokenIn, tokenOut = mint1, mint0
+			}
+			_, out, err := r.GetBestPool(ctx, nil, tokenIn, tokenOut, math.NewInt(int64(1_000+i)))
+			if assert.NoError(t, err) {
+				assert.True(t, out.IsPositive())
+			}
+			_, err = r.QuoteRoute(ctx, nil, tokenIn, tokenOut, math.NewInt(1_000_000))
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// stubQuotePool quotes like its exampledex pool after delay, or fails with err, and counts the
+// quotes in flight across the pools sharing inFlight
+type stubQuotePool struct {
+	*exampledex.Pool
+	delay    time.Duration
+	err      error
+	inFlight *quoteGauge
+}
+
+type quoteGauge struct {
+	mu       sync.Mutex
+	current  int
+	max      int
+	finished int
+}
+
+func (p *stubQuotePool) Quote(ctx context.Context, solClient pkg.RPC, inputMint string, inputAmount math.Int) (math.Int, error) {
+	if p.inFlight != nil {
+		p.inFlight.mu.Lock()
+		p.inFlight.current++
+		p.inFlight.max = max(p.inFlight.max, p.inFlight.current)
+		p.inFlight.mu.Unlock()
+		defer func() {
+			p.inFlight.mu.Lock()
+			p.inFlight.current--
+			p.inFlight.finished++
+			p.inFlight.mu.Unlock()
+		}()
+	}
+	select {
+	case <-time.After(p.delay):
+	case <-ctx.Done():
+		return math.ZeroInt(), ctx.Err()
+	}
+	if p.err != nil {
+		return math.ZeroInt(), p.err
+	}
+	return p.Pool.Quote(ctx, solClient, inputMint, inputAmount)
+}
+
+func TestRouterQuoteConcurrencyAndTimeout(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	stub := func(reserveB uint64, delay time.Duration, err error, gauge *quoteGauge) *stubQuotePool {
+		return &stubQuotePool{
+			Pool:     &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: reserveB},
+			delay:    delay,
+			err:      err,
+			inFlight: gauge,
+		}
+	}
+	bestPool := func(r *router.SimpleRouter) (pkg.Pool, error) {
+		_, err := r.QueryAllPools(context.Background(), mintA.String(), mintB.String())
+		require.NoError(t, err)
+		best, _, err := r.GetBestPool(context.Background(), sol.NewMockRPC(), mintA.String(), mintB.String(), math.NewInt(1_000))
+		return best, err
+	}
+
+	t.Run("in-flight quotes stay within the limit", func(t *testing.T) {
+		gauge := &quoteGauge{}
+		var pools staticProtocol
+		for i := range 8 {
+			pools = append(pools, stub(uint64(i+1)*1e9, 20*time.Millisecond, nil, gauge))
+		}
+		r := router.NewSimpleRouter(pools)
+		r.SetQuoteConcurrency(3)
+		r.SetQuoteTimeout(0)
+		best, err := bestPool(r)
+		require.NoError(t, err)
+		assert.Equal(t, pools[7].GetID(), best.GetID())
+		assert.Equal(t, 8, gauge.finished)
+		assert.LessOrEqual(t, gauge.max, 3)
+		assert.Equal(t, 3, gauge.max, "the limit is reached with more pools than slots")
+
+		gauge.max = 0
+		r.SetQuoteConcurrency(0)
+		_, err = bestPool(r)
+		require.NoError(t, err)
+		assert.Equal(t, 1, gauge.max, "values below 1 quote sequentially")
+	})
+
+	t.Run("slow pools time out", func(t *testing.T) {
+		slow, fast := stub(5e9, time.Minute, nil, nil), stub(2e9, 0, nil, nil)
+		r := router.NewSimpleRouter(staticProtocol{slow, fast})
+		r.SetQuoteTimeout(20 * time.Millisecond)
+		start := time.Now()
+		best, err := bestPool(r)
+		require.NoError(t, err)
+		assert.Equal(t, fast.GetID(), best.GetID(), "the better but slow pool is skipped")
+		assert.Less(t, time.Since(start), 10*time.Second)
+
+		r = router.NewSimpleRouter(staticProtocol{slow})
+		r.SetQuoteTimeout(20 * time.Millisecond)
+		_, err = bestPool(r)
+		assert.ErrorIs(t, err, solerrors.ErrNoRoute)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorContains(t, err, slow.GetID())
+	})
+
+	t.Run("failures are joined under ErrNoRoute", func(t *testing.T) {
+		errStale, errClosed := errors.New("stale reserves"), errors.New("pool closed")
+		first, second := stub(1e9, 0, errStale, nil), stub(1e9, 0, errClosed, nil)
+		_, err := bestPool(router.NewSimpleRouter(staticProtocol{first, second}))
+		assert.ErrorIs(t, err, solerrors.ErrNoRoute)
+		assert.ErrorIs(t, err, errStale)
+		assert.ErrorIs(t, err, errClosed)
+		assert.ErrorContains(t, err, "pool "+first.GetID())
+		assert.ErrorContains(t, err, "pool "+second.GetID())
+	})
+}
@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/saber"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A balanced 1M/1M pool with a 0.04% trade fee, halfway through ramping A from 50 to 150
+func TestSaberQuote(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	data := make([]byte, saber.SwapInfoSize)
+	data[0] = 1 // initialized
+	binary.LittleEndian.PutUint64(data[3:], 50)
+	binary.LittleEndian.PutUint64(data[11:], 150)
+	binary.LittleEndian.PutUint64(data[19:], 1000)
+	binary.LittleEndian.PutUint64(data[27:], 2000)
+	copy(data[saber.TokenAMintOffset:], mintA.Bytes())
+	copy(data[saber.TokenBMintOffset:], mintB.Bytes())
+	binary.LittleEndian.PutUint64(data[363:], 4)
+	binary.LittleEndian.PutUint64(data[371:], 10_000)
+	pool, err := saber.NewPool(solana.NewWallet().PublicKey(), data)
+	require.NoError(t, err)
+	pool.ReserveA, pool.ReserveB = 1_000_000_000_000, 1_000_000_000_000
+	pool.DecimalsA, pool.DecimalsB = 6, 6
+	pool.Now = 1500
+
+	out, err := pool.QuoteOffline(mintA.String(), math.NewInt(1_000_000_000))
+	require.NoError(t, err)
+	assert.Equal(t, "999590103", out.String())
+
+	in, err := pool.QuoteExactOutOffline(mintB.String(), out)
+	require.NoError(t, err)
+	assert.True(t, in.LTE(math.NewInt(1_000_000_000)))
+	short, err := pool.QuoteOffline(mintA.String(), in.SubRaw(1))
+	require.NoError(t, err)
+	assert.True(t, short.LT(out))
+
+	// Buying the scarce token of an imbalanced pool costs more
+	pool.ReserveB = 400_000_000_000
+	out, err = pool.QuoteOffline(mintA.String(), math.NewInt(1_000_000_000))
+	require.NoError(t, err)
+	assert.Equal(t, "987014717", out.String())
+
+	pool.DecimalsB = 8
+	_, err = pool.QuoteOffline(mintA.String(), math.NewInt(1_000_000_000))
+	assert.Error(t, err)
+}
@@ -0,0 +1,266 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/server"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	pool := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 1e9}
+	mock := sol.NewMockRPC()
+	blockhash := solana.Hash{7}
+	mock.SetBlockhash(blockhash)
+	for _, mint := range []solana.PublicKey{mintA, mintB} {
+		mock.SetAccount(mint, solana.TokenProgramID, make([]byte, 82))
+	}
+	client := &sol.Client{RpcClient: mock, Blockhashes: sol.NewBlockhashCache(mock)}
+	r := router.NewSimpleRouter(staticProtocol{pool})
+	r.SetQuoteClient(mock)
+	r.SetLogger(pkg.DiscardLogger)
+	srv := server.New(r, client)
+	srv.SetLogger(pkg.DiscardLogger)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	get := func(path string, query url.Values, out any) int {
+		resp, err := http.Get(ts.URL + path + "?" + query.Encode())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		return resp.StatusCode
+	}
+
+	var quote server.QuoteResponse
+	status := get("/quote", url.Values{"inputMint": {mintA.String()}, "outputMint": {mintB.String()}, "amount": {"1000000"}, "slippageBps": {"100"}}, &quote)
+	require.Equal(t, http.StatusOK, status)
+	expected, err := pool.Quote(t.Context(), mock, mintA.String(), math.NewInt(1_000_000))
+	require.NoError(t, err)
+	assert.Equal(t, expected.String(), quote.OutAmount)
+	assert.Equal(t, uint64(100), quote.SlippageBps)
+	require.Len(t, quote.Route, 1)
+	assert.Equal(t, pool.GetID(), quote.Route[0].PoolID)
+
+	var failure map[string]string
+	status = get("/quote", url.Values{"inputMint": {mintA.String()}, "outputMint": {mintB.String()}, "amount": {"lots"}}, &failure)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, failure["error"], "invalid amount")
+	status = get("/quote", url.Values{"inputMint": {solana.NewWallet().PublicKey().String()}, "outputMint": {solana.NewWallet().PublicKey().String()}, "amount": {"1000"}}, &failure)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	var pools server.PoolsResponse
+	status = get("/pools", url.Values{"inputMint": {mintB.String()}, "outputMint": {mintA.String()}}, &pools)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, pools.Pools, 1)
+	assert.Equal(t, server.Pool{
+		ID:        pool.GetID(),
+		Protocol:  string(pool.ProtocolName()),
+		Venue:     router.VenueName(pool.ProtocolName()),
+		ProgramID: pool.GetProgramID().String(),
+		BaseMint:  mintA.String(),
+		QuoteMint: mintB.String(),
+	}, pools.Pools[0])
+
+	// The swap comes back unsigned, paid for by the user, for the user's wallet to sign
+	user := solana.NewWallet().PublicKey()
+	body, err := json.Marshal(server.SwapRequest{InputMint: mintA.String(), OutputMint: mintB.String(), Amount: "1000000", UserPublicKey: user.String(), PriorityFee: 1000})
+	require.NoError(t, err)
+	resp, err := http.Post(ts.URL+"/swap", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var swap server.SwapResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&swap))
+	assert.Equal(t, uint64(server.DefaultSlippageBps), swap.Quote.SlippageBps)
+
+	data, err := base64.StdEncoding.DecodeString(swap.Transaction)
+	require.NoError(t, err)
+	tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(data))
+	require.NoError(t, err)
+	assert.Equal(t, blockhash, tx.Message.RecentBlockhash)
+	assert.Equal(t, user, tx.Message.AccountKeys[0])
+	assert.Equal(t, []solana.Signature{{}}, tx.Signatures)
+	assert.Len(t, tx.Message.Instructions, 2, "compute unit price and swap")
+}
+
+// Run with -race: concurrent requests quote and build swaps through the same pools
+func TestServerConcurrentRequests(t *testing.T) {
+	pool, mock := clmmPoolWithRange(t)
+	for _, mint := range []solana.PublicKey{pool.TokenMint0, pool.TokenMint1} {
+		mock.SetAccount(mint, solana.TokenProgramID, make([]byte, 82))
+	}
+	r := router.NewSimpleRouter(staticProtocol{pool})
+	r.SetQuoteClient(mock)
+	r.SetLogger(pkg.DiscardLogger)
+	srv := server.New(r, &sol.Client{RpcClient: mock})
+	srv.SetLogger(pkg.DiscardLogger)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	query := url.Values{"inputMint": {pool.TokenMint0.String()}, "outputMint": {pool.TokenMint1.String()}}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			query := maps.Clone(query)
+			query.Set("amount", strconv.Itoa(1_000_000+i))
+			resp, err := http.Get(ts.URL + "/quote?" + query.Encode())
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestServerTenants(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	cpmm := pluginPool{&exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 2e9}, pkg.ProtocolNameRaydiumCpmm}
+	dlmm := pluginPool{&exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 1e9}, pkg.ProtocolNameMeteoraDlmm}
+	mock := sol.NewMockRPC()
+	mock.SetBlockhash(solana.Hash{7})
+	for _, mint := range []solana.PublicKey{mintA, mintB} {
+		mock.SetAccount(mint, solana.TokenProgramID, make([]byte, 82))
+	}
+	r := router.NewSimpleRouter(staticProtocol{cpmm, dlmm})
+	r.SetQuoteClient(mock)
+	r.SetLogger(pkg.DiscardLogger)
+	srv := server.New(r, &sol.Client{RpcClient: mock, Blockhashes: sol.NewBlockhashCache(mock)})
+	srv.SetLogger(pkg.DiscardLogger)
+	srv.SetDefaultSlippageBps(75)
+
+	fake := clock.NewFake(time.Unix(1_700_000_000, 0))
+	tenants := router.NewTenantRegistry()
+	tenants.SetTimeSource(fake)
+	feePayer := solana.NewWallet().PublicKey()
+	require.NoError(t, tenants.Add("key-a", router.Tenant{
+		ID: "a", Protocols: []pkg.ProtocolName{pkg.ProtocolNameMeteoraDlmm}, DefaultSlippageBps: 30, FeePayer: feePayer, RequestsPerSecond: 1, Burst: 3,
+	}))
+	require.NoError(t, tenants.Add("key-b", router.Tenant{ID: "b"}))
+	srv.SetTenants(tenants)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	do := func(method, path, apiKey string, body any, out any) int {
+		var reader *bytes.Reader
+		if body != nil {
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+			reader = bytes.NewReader(data)
+		} else {
+			reader = bytes.NewReader(nil)
+		}
+		req, err := http.NewRequest(method, ts.URL+path, reader)
+		require.NoError(t, err)
+		if apiKey != "" {
+			req.Header.Set(server.APIKeyHeader, apiKey)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		return resp.StatusCode
+	}
+	quotePath := "/quote?" + url.Values{"inputMint": {mintA.String()}, "outputMint": {mintB.String()}, "amount": {"1000000"}}.Encode()
+
+	// Every endpoint but /health needs a registered key
+	var failure map[string]string
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, quotePath, "", nil, &failure))
+	assert.Contains(t, failure["error"], server.APIKeyHeader)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, quotePath, "nope", nil, &failure))
+	var health map[string]string
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/health", "", nil, &health))
+
+	// Tenant a routes through its protocols only, with its slippage
+	var quote server.QuoteResponse
+	require.Equal(t, http.StatusOK, do(http.MethodGet, quotePath, "key-a", nil, &quote))
+	assert.Equal(t, dlmm.GetID(), quote.Route[0].PoolID)
+	assert.Equal(t, uint64(30), quote.SlippageBps)
+	var pools server.PoolsResponse
+	poolsPath := "/pools?" + url.Values{"inputMint": {mintA.String()}, "outputMint": {mintB.String()}}.Encode()
+	require.Equal(t, http.StatusOK, do(http.MethodGet, poolsPath, "key-a", nil, &pools))
+	require.Len(t, pools.Pools, 1)
+	assert.Equal(t, dlmm.GetID(), pools.Pools[0].ID)
+
+	// Its fee payer pays for the swap and signs it along with the user
+	user := solana.NewWallet().PublicKey()
+	var swap server.SwapResponse
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/swap", "key-a", server.SwapRequest{
+		InputMint: mintA.String(), OutputMint: mintB.String(), Amount: "1000000", UserPublicKey: user.String(),
+	}, &swap))
+	data, err := base64.StdEncoding.DecodeString(swap.Transaction)
+	require.NoError(t, err)
+	tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(data))
+	require.NoError(t, err)
+	assert.Equal(t, solana.PublicKeySlice{feePayer, user}, tx.Message.Signers())
+	assert.Len(t, tx.Signatures, 2)
+
+	// The fourth request in the same instant is over its budget
+	assert.Equal(t, http.StatusTooManyRequests, do(http.MethodGet, quotePath, "key-a", nil, &failure))
+	fake.Advance(time.Second)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, quotePath, "key-a", nil, &quote))
+
+	// Tenant b routes through every protocol with the server's slippage
+	require.Equal(t, http.StatusOK, do(http.MethodGet, quotePath, "key-b", nil, &quote))
+	assert.Equal(t, cpmm.GetID(), quote.Route[0].PoolID)
+	assert.Equal(t, uint64(75), quote.SlippageBps)
+}
+
+func TestLoadTenants(t *testing.T) {
+	feePayer := solana.NewWallet().PublicKey()
+	write := func(content string) string {
+		path := filepath.Join(t.TempDir(), "tenants.json")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	tenants, err := server.LoadTenants(write(`[
+		{"apiKey": "key-a", "id": "a", "protocols": ["meteora_dlmm"], "slippageBps": 30, "feePayer": "` + feePayer.String() + `", "requestsPerSecond": 5},
+		{"apiKey": "key-b", "id": "b"}
+	]`))
+	require.NoError(t, err)
+	tenant, err := tenants.Authorize("key-a")
+	require.NoError(t, err)
+	assert.Equal(t, router.Tenant{
+		ID: "a", Protocols: []pkg.ProtocolName{pkg.ProtocolNameMeteoraDlmm}, DefaultSlippageBps: 30, FeePayer: feePayer, RequestsPerSecond: 5, Burst: 5,
+	}, tenant)
+	_, err = tenants.Authorize("key-b")
+	assert.NoError(t, err)
+
+	for content, want := range map[string]string{
+		`[{"apiKey": "k", "protocols": ["nope"]}]`: `tenants[0]: unknown protocol "nope"`,
+		`[{"apiKey": "k", "feePayer": "nope"}]`:    "tenants[0]: invalid fee payer",
+		`[{"apiKey": "k"}, {"apiKey": "k"}]`:       "tenants[1]: api key already registered",
+		`[{"apiKey": ""}]`:                         "tenants[0]: api key must not be empty",
+		`{"apiKey": "k"}`:                          "failed to parse tenants",
+	} {
+		_, err := server.LoadTenants(write(content))
+		assert.ErrorContains(t, err, want, content)
+	}
+}
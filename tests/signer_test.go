@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLedger answers Solana app APDUs with a local key, collecting chunked messages
+type fakeLedger struct {
+	key     solana.PrivateKey
+	payload []byte
+	apdus   int
+}
+
+func (l *fakeLedger) Exchange(apdu []byte) ([]byte, error) {
+	l.apdus++
+	ok := []byte{0x90, 0x00}
+	switch apdu[1] {
+	case 0x05:
+		return append(l.key.PublicKey().Bytes(), ok...), nil
+	case 0x06:
+		if apdu[3]&0x01 == 0 {
+			l.payload = nil
+		}
+		l.payload = append(l.payload, apdu[5:]...)
+		if apdu[3]&0x02 != 0 {
+			return ok, nil
+		}
+		// Skip the signer count and the derivation path
+		message := l.payload[2+4*int(l.payload[1]):]
+		sig, err := l.key.Sign(message)
+		if err != nil {
+			return nil, err
+		}
+		return append(sig[:], ok...), nil
+	}
+	return []byte{0x6d, 0x00}, nil
+}
+
+func TestLedgerSignerChunksLongMessages(t *testing.T) {
+	ledger := &fakeLedger{key: solana.NewWallet().PrivateKey}
+	signer, err := sol.NewLedgerSigner(ledger, sol.LedgerDerivationPath(0))
+	require.NoError(t, err)
+	assert.Equal(t, ledger.key.PublicKey(), signer.PublicKey())
+
+	message := make([]byte, 600)
+	sig, err := signer.Sign(message)
+	require.NoError(t, err)
+	assert.True(t, sig.Verify(ledger.key.PublicKey(), message))
+	assert.Equal(t, 4, ledger.apdus, "one pubkey read and three chunks")
+}
+
+func TestRemoteSignerSendsTransactions(t *testing.T) {
+	key := solana.NewWallet().PrivateKey
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req struct{ PublicKey, Message string }
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		message, err := base64.StdEncoding.DecodeString(req.Message)
+		require.NoError(t, err)
+		sig, err := key.Sign(message)
+		require.NoError(t, err)
+		json.NewEncoder(w).Encode(map[string]string{"signature": sig.String()})
+	}))
+	defer server.Close()
+
+	signer := sol.NewRemoteSigner(server.URL, key.PublicKey())
+	_, err := signer.Sign([]byte("hello"))
+	assert.Error(t, err, "the service rejects requests without the token")
+
+	signer.SetHeader("Authorization", "Bearer token")
+	mock := sol.NewMockRPC()
+	client := &sol.Client{RpcClient: mock}
+	transfer := system.NewTransferInstruction(1, key.PublicKey(), solana.NewWallet().PublicKey()).Build()
+	sig, err := client.SendTxWithSigners(context.Background(), solana.Hash{}, []sol.Signer{signer}, []solana.Instruction{transfer}, false)
+	require.NoError(t, err)
+	require.Len(t, mock.Sent(), 1)
+	assert.Equal(t, sig, mock.Sent()[0].Signatures[0])
+	require.NoError(t, mock.Sent()[0].VerifySignatures())
+
+	// A signer for the wrong key is caught before anything is sent
+	other := sol.NewRemoteSigner(server.URL, solana.NewWallet().PublicKey())
+	other.SetHeader("Authorization", "Bearer token")
+	_, err = other.Sign([]byte("hello"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticProtocol serves a fixed set of pools without an RPC node
+type staticProtocol []pkg.Pool
+
+func (p staticProtocol) FetchPoolsByPair(ctx context.Context, baseMint, quoteMint string) ([]pkg.Pool, error) {
+	return p, nil
+}
+
+func (p staticProtocol) FetchPoolByID(ctx context.Context, poolID string) (pkg.Pool, error) {
+	for _, pool := range p {
+		if pool.GetID() == poolID {
+			return pool, nil
+		}
+	}
+	return nil, fmt.Errorf("pool %s not found", poolID)
+}
+
+func TestMaxSizeForImpact(t *testing.T) {
+	pool := &exampledex.Pool{
+		ID:       solana.NewWallet().PublicKey(),
+		MintA:    solana.NewWallet().PublicKey(),
+		MintB:    solana.NewWallet().PublicKey(),
+		ReserveA: 1_000_000_000,
+		ReserveB: 50_000_000_000,
+		FeeBps:   30,
+	}
+	r := router.NewSimpleRouter(staticProtocol{pool})
+	r.SetQuoteClient(rpc.New("http://127.0.0.1:0"))
+	r.SetMintDecimals(pool.MintA.String(), 9)
+	r.SetMintDecimals(pool.MintB.String(), 6)
+	ctx := context.Background()
+	_, err := r.QueryAllPools(ctx, pool.MintA.String(), pool.MintB.String())
+	require.NoError(t, err)
+
+	// On x*y=k the impact of x against the spot price is x / (reserve + x), so a 1% cap
+	// allows about reserve / 99, a little more for the fee and the probe's own impact
+	quote, err := r.MaxSizeForImpact(ctx, nil, pool.MintA.String(), pool.MintB.String(), router.SizeOptions{
+		MaxImpactBps: 100,
+		MaxAmount:    math.NewInt(1_000_000_000),
+		Tolerance:    math.NewInt(1000),
+	})
+	require.NoError(t, err)
+	assert.InDelta(t, 1_000_000_000/99, quote.AmountIn.Int64(), 200_000)
+	assert.Equal(t, pool.GetID(), quote.Pools[0].GetID())
+	assert.True(t, quote.AmountOut.MulRaw(10000).GTE(quote.SpotAmountOut.MulRaw(9900)))
+	// Two tolerances more breaks the cap at the same spot price
+	larger := quote.AmountIn.AddRaw(2000)
+	largerOut, err := pool.Quote(ctx, nil, pool.MintA.String(), larger)
+	require.NoError(t, err)
+	largerSpot := quote.SpotAmountOut.Mul(larger).Quo(quote.AmountIn)
+	assert.True(t, largerOut.MulRaw(10000).LT(largerSpot.MulRaw(9900)))
+
+	// A cap the whole amount fits under returns the whole amount
+	quote, err = r.MaxSizeForImpact(ctx, nil, pool.MintA.String(), pool.MintB.String(), router.SizeOptions{
+		MaxImpactBps: 100,
+		MaxAmount:    math.NewInt(1_000_000),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "1000000", quote.AmountIn.String())
+	assert.Equal(t, router.NewAmountUnits(pool.MintA.String(), 9, quote.AmountIn), quote.Units.Input)
+	assert.Equal(t, "0.001", quote.Units.Input.Normalized)
+	assert.Equal(t, uint8(6), quote.Units.Output.Decimals)
+
+	// Summaries take the decimals from the quote and reject contradicting ones
+	summary, err := router.SummarizeRoute(quote, router.SummaryOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "0.001", summary.AmountIn)
+	_, err = router.SummarizeRoute(quote, router.SummaryOptions{InputDecimals: 6})
+	assert.Error(t, err)
+}
+
+func TestNormalizeAmount(t *testing.T) {
+	assert.Equal(t, "1.5", router.NormalizeAmount(math.NewInt(1_500_000), 6))
+	assert.Equal(t, "0.000000001", router.NormalizeAmount(math.NewInt(1), 9))
+	assert.Equal(t, "42", router.NormalizeAmount(math.NewInt(42), 0))
+	assert.Equal(t, "-0.25", router.NormalizeAmount(math.NewInt(-250), 3))
+}
+
+func TestGetBestPoolAmountTooSmall(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	pool := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 1e6}
+	r := router.NewSimpleRouter(staticProtocol{pool})
+	r.SetQuoteClient(rpc.New("http://127.0.0.1:0"))
+	ctx := context.Background()
+	_, err := r.QueryAllPools(ctx, mintA.String(), mintB.String())
+	require.NoError(t, err)
+
+	// 1000 units of A are worth exactly one of B before the price moves, so 1001 is the
+	// smallest input that floors to a non-zero output
+	_, _, err = r.GetBestPool(ctx, nil, mintA.String(), mintB.String(), math.NewInt(1))
+	require.ErrorIs(t, err, router.ErrAmountTooSmall)
+	var tooSmall *router.AmountTooSmallError
+	require.ErrorAs(t, err, &tooSmall)
+	assert.Equal(t, "1001", tooSmall.MinAmountIn.String())
+	assert.Equal(t, pool, tooSmall.Pool)
+	assert.Equal(t, "1/1001", tooSmall.Price.String())
+
+	_, out, err := r.GetBestPool(ctx, nil, mintA.String(), mintB.String(), tooSmall.MinAmountIn)
+	require.NoError(t, err)
+	assert.Equal(t, "1", out.String())
+}
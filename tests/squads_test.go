@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSquadsProposal(t *testing.T) {
+	// Multisig 4wBqpZ... is the key with bytes 1..32. The addresses below are pinned from the
+	// seeds of the Squads v4 SDK's getVaultPda, getTransactionPda and getProposalPda, and the
+	// discriminators are those of the v4 IDL.
+	var key [32]byte
+	for i := range key {
+		key[i] = byte(i + 1)
+	}
+	multisig := solana.PublicKeyFromBytes(key[:])
+	require.Equal(t, "4wBqpZM9xaSheZzJSMawUKKwhdpChKbZ5eu5ky4Vigw", multisig.String())
+	vault := solana.MustPublicKeyFromBase58("5ZefKkidpPHEAxj2HMwAytUschSSVzeEP7qy7tWuprxg")
+	transaction := solana.MustPublicKeyFromBase58("733g4VJvwAr6HqnfkA2CNTYCSpXd5f8jNWubiYatJEAP")
+	proposalPDA := solana.MustPublicKeyFromBase58("GKzKVHV2sr5ZsWcaELDStGXZVarQeigbAgQys4C1X1ms")
+	vaultTransactionCreate := []byte{48, 250, 78, 168, 208, 226, 218, 211}
+	proposalCreate := []byte{220, 60, 73, 224, 30, 108, 79, 159}
+
+	derived, err := sol.DeriveSquadsVaultPDA(multisig, 1)
+	require.NoError(t, err)
+	assert.Equal(t, vault, derived)
+	derived, err = sol.DeriveSquadsVaultPDA(multisig, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "5SWjfFsAkX465g7akT9KyKAgvPk5QtCYFuwfepWuvRAR", derived.String())
+
+	// transaction_index sits after discriminator, create_key, config_authority, threshold
+	// and time_lock; the proposal takes the next index
+	data := make([]byte, 8+32+32+2+4+8+8)
+	binary.LittleEndian.PutUint16(data[72:], 2)
+	binary.LittleEndian.PutUint64(data[78:], 7)
+	binary.LittleEndian.PutUint64(data[86:], 99) // stale_transaction_index
+	mock := sol.NewMockRPC()
+	mock.SetAccount(multisig, sol.SquadsV4ProgramID, data)
+	client := &sol.Client{RpcClient: mock}
+
+	creator, recipient := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	swap := system.NewTransferInstruction(1000, vault, recipient).Build()
+	insts, err := client.BuildSquadsProposal(context.Background(), sol.SquadsProposal{
+		Multisig: multisig, VaultIndex: 1, Creator: creator, Memo: "swap",
+	}, []solana.Instruction{swap})
+	require.NoError(t, err)
+	require.Len(t, insts, 2)
+
+	create := insts[0]
+	assert.Equal(t, sol.SquadsV4ProgramID, create.ProgramID())
+	assert.Equal(t, solana.AccountMetaSlice{
+		solana.NewAccountMeta(multisig, true, false),
+		solana.NewAccountMeta(transaction, true, false),
+		solana.NewAccountMeta(creator, false, true),
+		solana.NewAccountMeta(creator, true, true),
+		solana.NewAccountMeta(solana.SystemProgramID, false, false),
+	}, solana.AccountMetaSlice(create.Accounts()))
+
+	// VaultTransactionCreateArgs: vault_index, ephemeral_signers, transaction_message, memo
+	message := []byte{1, 1, 1, 3}
+	message = append(message, vault.Bytes()...)
+	message = append(message, recipient.Bytes()...)
+	message = append(message, solana.SystemProgramID.Bytes()...)
+	swapData, err := swap.Data()
+	require.NoError(t, err)
+	message = append(message, 1, 2, 2, 0, 1, byte(len(swapData)), 0)
+	message = append(message, swapData...)
+	message = append(message, 0)
+	args := append([]byte{}, vaultTransactionCreate...)
+	args = append(args, 1, 0)
+	args = binary.LittleEndian.AppendUint32(args, uint32(len(message)))
+	args = append(args, message...)
+	args = append(args, 1)
+	args = binary.LittleEndian.AppendUint32(args, 4)
+	args = append(args, "swap"...)
+	createData, err := create.Data()
+	require.NoError(t, err)
+	assert.Equal(t, args, createData)
+
+	propose := insts[1]
+	assert.Equal(t, solana.AccountMetaSlice{
+		solana.NewAccountMeta(multisig, false, false),
+		solana.NewAccountMeta(proposalPDA, true, false),
+		solana.NewAccountMeta(creator, false, true),
+		solana.NewAccountMeta(creator, true, true),
+		solana.NewAccountMeta(solana.SystemProgramID, false, false),
+	}, solana.AccountMetaSlice(propose.Accounts()))
+	proposeData, err := propose.Data()
+	require.NoError(t, err)
+	assert.Equal(t, append(append([]byte{}, proposalCreate...), 8, 0, 0, 0, 0, 0, 0, 0, 0), proposeData)
+
+	// A separate rent payer pays for both accounts; a truncated multisig is rejected
+	rentPayer := solana.NewWallet().PublicKey()
+	insts, err = client.BuildSquadsProposal(context.Background(), sol.SquadsProposal{
+		Multisig: multisig, VaultIndex: 1, Creator: creator, RentPayer: rentPayer,
+	}, []solana.Instruction{swap})
+	require.NoError(t, err)
+	for _, inst := range insts {
+		assert.Equal(t, solana.NewAccountMeta(rentPayer, true, true), inst.Accounts()[3])
+	}
+	createData, err = insts[0].Data()
+	require.NoError(t, err)
+	assert.Equal(t, byte(0), createData[len(createData)-1], "no memo")
+	mock.SetAccount(multisig, sol.SquadsV4ProgramID, data[:85])
+	_, err = client.BuildSquadsProposal(context.Background(), sol.SquadsProposal{Multisig: multisig, Creator: creator}, []solana.Instruction{swap})
+	assert.ErrorContains(t, err, "invalid multisig account data length")
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/stakepool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stakePoolData encodes a pool holding 1100 SOL against 1000 pool tokens, with a 0.01% SOL
+// deposit fee, a 0.1% SOL withdrawal fee and optional fields set ahead of them
+func stakePoolData(mint solana.PublicKey) []byte {
+	data := make([]byte, 130)
+	data[0] = stakepool.AccountTypeStakePool
+	u64 := func(v uint64) { data = binary.LittleEndian.AppendUint64(data, v) }
+	fee := func(denominator, numerator uint64) { u64(denominator); u64(numerator) }
+	for _, key := range []solana.PublicKey{solana.NewWallet().PublicKey(), mint, solana.NewWallet().PublicKey(), solana.TokenProgramID} {
+		data = append(data, key.Bytes()...)
+	}
+	u64(1_100_000_000_000) // total lamports
+	u64(1_000_000_000_000) // pool token supply
+	u64(700)               // last update epoch
+	data = append(data, make([]byte, 48)...)
+	fee(100, 5)            // epoch fee
+	data = append(data, 0) // no next epoch fee
+	data = append(data, 1) // preferred deposit validator
+	data = append(data, solana.NewWallet().PublicKey().Bytes()...)
+	data = append(data, 0) // no preferred withdraw validator
+	fee(0, 0)              // stake deposit fee
+	fee(1000, 1)           // stake withdrawal fee
+	data = append(data, 1) // next stake withdrawal fee
+	fee(1000, 2)
+	data = append(data, 0) // stake referral fee
+	data = append(data, 0) // no sol deposit authority
+	fee(10_000, 1)         // sol deposit fee
+	data = append(data, 0) // sol referral fee
+	data = append(data, 0) // no sol withdraw authority
+	fee(1000, 1)           // sol withdrawal fee
+	data = append(data, 0) // no next sol withdrawal fee
+	return append(data, make([]byte, 16)...)
+}
+
+func TestStakePoolQuote(t *testing.T) {
+	mint := solana.NewWallet().PublicKey()
+	pool, err := stakepool.NewPool(solana.NewWallet().PublicKey(), stakepool.SplProgramID, stakePoolData(mint))
+	require.NoError(t, err)
+	assert.Equal(t, stakepool.Fee{Denominator: 10_000, Numerator: 1}, pool.SolDepositFee)
+	assert.Equal(t, stakepool.Fee{Denominator: 1000, Numerator: 1}, pool.SolWithdrawalFee)
+	pool.CurrentEpoch = 700
+	pool.ReserveAvailable = 2_000_000_000
+
+	// 1 SOL mints 909090909 pool tokens at 1.1 SOL each, less a rounded-up 90910 fee
+	wsol := solana.WrappedSol.String()
+	out, err := pool.QuoteOffline(wsol, math.NewInt(1_000_000_000))
+	require.NoError(t, err)
+	assert.Equal(t, "908999999", out.String())
+	in, err := pool.QuoteExactOutOffline(mint.String(), out)
+	require.NoError(t, err)
+	assert.True(t, in.LTE(math.NewInt(1_000_000_000)))
+	short, err := pool.QuoteOffline(wsol, in.SubRaw(1))
+	require.NoError(t, err)
+	assert.True(t, short.LT(out))
+
+	// Burning 1 pool token burns 0.999 after the fee, paying 1.0989 SOL
+	lamports, err := pool.QuoteOffline(mint.String(), math.NewInt(1_000_000_000))
+	require.NoError(t, err)
+	assert.Equal(t, "1098900000", lamports.String())
+	tokens, err := pool.QuoteExactOutOffline(wsol, lamports)
+	require.NoError(t, err)
+	assert.True(t, tokens.LTE(math.NewInt(1_000_000_000)))
+
+	// Withdrawals are capped by the reserve, and pools behind on the epoch are not tradable
+	_, err = pool.QuoteOffline(mint.String(), math.NewInt(2_000_000_000))
+	assert.ErrorContains(t, err, "insufficient liquidity")
+	pool.CurrentEpoch = 701
+	_, err = pool.QuoteOffline(wsol, math.NewInt(1_000_000_000))
+	assert.Error(t, err)
+}
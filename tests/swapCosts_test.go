@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/price"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedOracle prices every pair at the same price
+type fixedOracle float64
+
+func (o fixedOracle) Price(ctx context.Context, baseMint, quoteMint string) (price.OraclePrice, error) {
+	return price.OraclePrice{Price: float64(o)}, nil
+}
+
+func TestSwapCosts(t *testing.T) {
+	mint := solana.NewWallet().PublicKey()
+	cheap := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mint, MintB: solana.WrappedSol, ReserveA: 1e12, ReserveB: 1e12}
+	richer := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mint, MintB: solana.WrappedSol, ReserveA: 1e12, ReserveB: 1.001e12}
+	r := router.NewSimpleRouter(staticProtocol{cheap, richer})
+	r.SetQuoteClient(rpc.New("http://127.0.0.1:0"))
+	ctx := context.Background()
+	_, err := r.QueryAllPools(ctx, mint.String(), solana.WrappedSol.String())
+	require.NoError(t, err)
+	bestPool := func(opts router.RouteOptions) (string, error) {
+		best, _, err := r.GetBestPoolWithOptions(ctx, nil, mint.String(), solana.WrappedSol.String(), math.NewInt(1e7), opts)
+		if err != nil {
+			return "", err
+		}
+		return best.GetID(), nil
+	}
+
+	best, err := bestPool(router.RouteOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, richer.GetID(), best)
+
+	// Fixed costs shift every candidate alike, while a venue's own costs outweigh its edge
+	costs := &router.SwapCosts{RentLamports: 2_039_280, TipLamports: 10_000, PoolLamports: func(pool pkg.Pool) uint64 {
+		if pool == richer {
+			return 20_000
+		}
+		return 0
+	}}
+	best, err = bestPool(router.RouteOptions{Costs: costs})
+	require.NoError(t, err)
+	assert.Equal(t, cheap.GetID(), best)
+
+	// Output that doesn't cover the costs isn't worth swapping for
+	_, err = bestPool(router.RouteOptions{Costs: &router.SwapCosts{RentLamports: 2e7}})
+	assert.ErrorIs(t, err, router.ErrCostsExceedOutput)
+
+	// Other outputs price the costs through the oracle: 10000 lamports at 150 per SOL is
+	// 1500 base units of a 6 decimal token
+	usdc := solana.NewWallet().PublicKey()
+	pool := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mint, MintB: usdc, ReserveA: 1e12, ReserveB: 1e12}
+	r = router.NewSimpleRouter(staticProtocol{pool})
+	r.SetQuoteClient(rpc.New("http://127.0.0.1:0"))
+	r.SetMintDecimals(usdc.String(), 6)
+	var costOut math.Int
+	r.SetPoolScorer(router.PoolScorerFunc(func(c router.PoolCandidate) float64 {
+		costOut = c.CostOut
+		return 0
+	}))
+	r.SetSwapCosts(&router.SwapCosts{PriorityFeeLamports: 10_000})
+	_, err = r.QueryAllPools(ctx, mint.String(), usdc.String())
+	require.NoError(t, err)
+	_, _, err = r.GetBestPool(ctx, nil, mint.String(), usdc.String(), math.NewInt(1e7))
+	assert.ErrorContains(t, err, "no oracle")
+	r.SetSwapCosts(&router.SwapCosts{PriorityFeeLamports: 10_000, Oracle: fixedOracle(150)})
+	_, _, err = r.GetBestPool(ctx, nil, mint.String(), usdc.String(), math.NewInt(1e7))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1500), costOut.Int64())
+}
+
+func TestEstimateSwapRent(t *testing.T) {
+	mint, user := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	mock := sol.NewMockRPC()
+	mock.SetAccount(mint, solana.TokenProgramID, make([]byte, 82))
+	client := &sol.Client{RpcClient: mock}
+	ctx := context.Background()
+
+	rent, err := client.EstimateSwapRent(ctx, user, mint.String(), sol.SwapOptions{CreateOutputAccount: true})
+	require.NoError(t, err)
+	expected, err := mock.GetMinimumBalanceForRentExemption(ctx, sol.TokenAccountSize, rpc.CommitmentConfirmed)
+	require.NoError(t, err)
+	assert.Equal(t, expected, rent)
+
+	// Unwrapped SOL output needs no account that outlives the swap
+	rent, err = client.EstimateSwapRent(ctx, user, sol.NativeSOL.String(), sol.SwapOptions{CreateOutputAccount: true})
+	require.NoError(t, err)
+	assert.Zero(t, rent)
+
+	account, err := sol.AssociatedTokenAddress(user, mint, solana.TokenProgramID)
+	require.NoError(t, err)
+	mock.SetTokenAccount(account, mint, user, 0)
+	rent, err = client.EstimateSwapRent(ctx, user, mint.String(), sol.SwapOptions{CreateOutputAccount: true})
+	require.NoError(t, err)
+	assert.Zero(t, rent)
+}
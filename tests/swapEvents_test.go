@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/pump"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/gtdvccc/SolRouteTmp/pkg/protocol"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gtdvccc/SolRouteTmp/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func appendU64(data []byte, values ...uint64) []byte {
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint64(data, v)
+	}
+	return data
+}
+
+func TestDecodeSwapEvents(t *testing.T) {
+	user := solana.NewWallet().PublicKey()
+	ammPool := solana.NewWallet().PublicKey()
+	clmmPool := solana.NewWallet().PublicKey()
+	pumpPool := solana.NewWallet().PublicKey()
+	aggregator := solana.NewWallet().PublicKey()
+	const (
+		userIndex = iota
+		ammPoolIndex
+		clmmPoolIndex
+		pumpPoolIndex
+		ammProgram
+		clmmProgram
+		pumpProgram
+		aggregatorProgram
+	)
+	tx := &solana.Transaction{
+		Message: solana.Message{
+			AccountKeys: solana.PublicKeySlice{
+				user, ammPool, clmmPool, pumpPool,
+				raydium.RAYDIUM_AMM_PROGRAM_ID, raydium.ClmmProgramID(), pump.PumpSwapProgramID, aggregator,
+			},
+			Instructions: []solana.CompiledInstruction{
+				{ProgramIDIndex: ammProgram, Accounts: []uint16{0, ammPoolIndex, userIndex}, Data: []byte{9}},
+				{ProgramIDIndex: aggregatorProgram, Accounts: []uint16{userIndex, clmmPoolIndex}, Data: []byte{1}},
+				{ProgramIDIndex: pumpProgram, Accounts: []uint16{pumpPoolIndex, userIndex}, Data: []byte{2}},
+			},
+		},
+	}
+
+	// AMM v4 SwapBaseIn of 1000 coin for at least 1400 pc, which returned 1500
+	rayLog := appendU64([]byte{3}, 1000, 1400, 2, 5000, 1_000_000, 1_500_000, 1500)
+	// CLMM swap of 2000 of token 1 for 30 of token 0, invoked by the aggregator
+	clmm := append([]byte{}, utils.GetDiscriminator("event", "SwapEvent")...)
+	clmm = append(clmm, clmmPool[:]...)
+	clmm = append(clmm, user[:]...)
+	clmm = append(clmm, make([]byte, 64)...)
+	clmm = appendU64(clmm, 30, 0, 2000, 0)
+	clmm = append(clmm, 0)
+	clmm = append(clmm, make([]byte, 36)...)
+	// PumpSwap buy of 500 base for 7000 quote, emitted through a self-invocation
+	buy := append([]byte{}, sol.AnchorEventTag...)
+	buy = append(buy, utils.GetDiscriminator("event", "BuyEvent")...)
+	buy = appendU64(buy, 0, 500, 8000, 0, 0, 0, 0, 6900, 25, 17, 5, 3, 6917, 7000)
+	buy = append(buy, pumpPool[:]...)
+	buy = append(buy, user[:]...)
+
+	meta := &rpc.TransactionMeta{
+		InnerInstructions: []rpc.InnerInstruction{
+			{Index: 1, Instructions: []solana.CompiledInstruction{
+				{ProgramIDIndex: clmmProgram, Accounts: []uint16{userIndex, clmmPoolIndex}, Data: []byte{3}},
+			}},
+			{Index: 2, Instructions: []solana.CompiledInstruction{
+				{ProgramIDIndex: pumpProgram, Accounts: []uint16{pumpPoolIndex}, Data: buy},
+			}},
+		},
+		LogMessages: []string{
+			"Program " + raydium.RAYDIUM_AMM_PROGRAM_ID.String() + " invoke [1]",
+			"Program log: ray_log: " + base64.StdEncoding.EncodeToString(rayLog),
+			"Program " + raydium.RAYDIUM_AMM_PROGRAM_ID.String() + " success",
+			"Program " + aggregator.String() + " invoke [1]",
+			"Program log: Program data: not an event",
+			"Program " + raydium.ClmmProgramID().String() + " invoke [2]",
+			"Program data: " + base64.StdEncoding.EncodeToString(clmm),
+			"Program " + raydium.ClmmProgramID().String() + " success",
+			"Program " + aggregator.String() + " success",
+			"Program " + pump.PumpSwapProgramID.String() + " invoke [1]",
+			"Program " + pump.PumpSwapProgramID.String() + " invoke [2]",
+			"Program " + pump.PumpSwapProgramID.String() + " success",
+			"Program " + pump.PumpSwapProgramID.String() + " success",
+		},
+	}
+
+	swaps, err := protocol.DecodeSwapEvents(solana.Signature{7}, 99, tx, meta)
+	require.NoError(t, err)
+	require.Len(t, swaps, 3)
+
+	amm := swaps[0]
+	assert.Equal(t, pkg.ProtocolNameRaydiumAmm, amm.Protocol)
+	assert.Equal(t, ammPool, amm.Pool)
+	assert.Equal(t, user, amm.User)
+	assert.True(t, amm.ZeroForOne)
+	assert.Equal(t, uint64(1000), amm.AmountIn)
+	assert.Equal(t, uint64(1500), amm.AmountOut)
+	assert.Equal(t, solana.Signature{7}, amm.Signature)
+	assert.Equal(t, uint64(99), amm.Slot)
+
+	assert.Equal(t, pkg.ProtocolNameRaydiumClmm, swaps[1].Protocol)
+	assert.Equal(t, clmmPool, swaps[1].Pool)
+	assert.False(t, swaps[1].ZeroForOne)
+	assert.Equal(t, uint64(2000), swaps[1].AmountIn)
+	assert.Equal(t, uint64(30), swaps[1].AmountOut)
+	assert.InDelta(t, 2000.0/30, swaps[1].Price(0, 0), 1e-9)
+
+	assert.Equal(t, pkg.ProtocolNamePumpAmm, swaps[2].Protocol)
+	assert.Equal(t, pumpPool, swaps[2].Pool)
+	assert.False(t, swaps[2].ZeroForOne)
+	assert.Equal(t, uint64(7000), swaps[2].AmountIn)
+	assert.Equal(t, uint64(500), swaps[2].AmountOut)
+	assert.Equal(t, uint64(20), swaps[2].Fee)
+
+	meta.Err = "custom program error"
+	swaps, err = protocol.DecodeSwapEvents(solana.Signature{7}, 99, tx, meta)
+	require.NoError(t, err)
+	assert.Empty(t, swaps)
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// instructionKinds names each instruction of a swap, to check what surrounds it
+func instructionKinds(t *testing.T, pool *exampledex.Pool, insts []solana.Instruction) []string {
+	kinds := make([]string, len(insts))
+	for i, inst := range insts {
+		data, err := inst.Data()
+		require.NoError(t, err)
+		switch {
+		case inst.ProgramID().Equals(solana.SPLAssociatedTokenAccountProgramID):
+			kinds[i] = "create-ata"
+		case inst.ProgramID().Equals(solana.SystemProgramID):
+			kinds[i] = "transfer"
+		case inst.ProgramID().Equals(solana.TokenProgramID) && data[0] == token.Instruction_SyncNative:
+			kinds[i] = "sync"
+		case inst.ProgramID().Equals(solana.TokenProgramID) && data[0] == token.Instruction_CloseAccount:
+			kinds[i] = "close"
+		case inst.ProgramID().Equals(pool.GetProgramID()):
+			kinds[i] = "swap"
+		default:
+			kinds[i] = inst.ProgramID().String()
+		}
+	}
+	return kinds
+}
+
+func TestSwapOptionsWrapSol(t *testing.T) {
+	user := solana.NewWallet().PublicKey()
+	usdc := solana.NewWallet().PublicKey()
+	wsolAccount, _, err := solana.FindAssociatedTokenAddress(user, sol.WSOL)
+	require.NoError(t, err)
+	pool := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: sol.WSOL, MintB: usdc, ReserveA: 1e12, ReserveB: 1e12}
+	mock := sol.NewMockRPC()
+	mock.SetAccountInfo(user, &rpc.Account{Lamports: 10_000_000_000, Owner: solana.SystemProgramID})
+	mock.SetAccount(usdc, solana.TokenProgramID, make([]byte, 82))
+	client := &sol.Client{RpcClient: mock}
+	ctx := context.Background()
+	amount, minOut := math.NewInt(1_000_000), math.NewInt(1)
+	build := func(inputMint string, opts sol.SwapOptions) []string {
+		insts, err := client.BuildSwapInstructions(ctx, pool, user, inputMint, amount, minOut, opts)
+		require.NoError(t, err)
+		return instructionKinds(t, pool, insts)
+	}
+	wrap := sol.SwapOptions{WrapSol: true}
+
+	// Spending SOL: the WSOL account is created and funded before the swap and closed after
+	assert.Equal(t, []string{"create-ata", "transfer", "sync", "swap", "close"}, build(sol.WSOL.String(), wrap))
+	insts, err := client.BuildSwapInstructions(ctx, pool, user, sol.WSOL.String(), amount, minOut, wrap)
+	require.NoError(t, err)
+	for _, i := range []int{1, 2, 4} {
+		assert.Contains(t, insts[i].Accounts(), solana.Meta(wsolAccount).WRITE(), "the transfer, sync and close act on the user's WSOL account")
+	}
+	assert.Equal(t, []string{"create-ata", "transfer", "sync", "swap", "close"}, build(sol.NativeSOL.String(), sol.SwapOptions{}),
+		"native SOL is always wrapped")
+	mock.SetTokenAccount(wsolAccount, sol.WSOL, user, 400_000)
+	assert.Equal(t, []string{"transfer", "sync", "swap", "close"}, build(sol.WSOL.String(), wrap), "an existing account is topped up")
+	mock.SetTokenAccount(wsolAccount, sol.WSOL, user, 1_000_000)
+	assert.Equal(t, []string{"swap", "close"}, build(sol.WSOL.String(), wrap), "a funded account only needs closing")
+
+	// Receiving SOL: the output account is prepared before the swap and unwrapped after
+	mock.SetAccountInfo(wsolAccount, nil)
+	assert.Equal(t, []string{"create-ata", "swap", "close"}, build(usdc.String(), wrap))
+	assert.Equal(t, []string{"create-ata", "swap", "close"}, build(usdc.String(), sol.SwapOptions{WrapSol: true, CreateOutputAccount: true}),
+		"the WSOL output account is created once")
+	mock.SetTokenAccount(wsolAccount, sol.WSOL, user, 0)
+	assert.Equal(t, []string{"swap", "close"}, build(usdc.String(), wrap))
+
+	// Without wrapping the swap moves WSOL the user already holds
+	assert.Equal(t, []string{"swap"}, build(sol.WSOL.String(), sol.SwapOptions{}))
+	assert.Equal(t, []string{"swap"}, build(usdc.String(), sol.SwapOptions{}))
+	mock.SetAccountInfo(wsolAccount, nil)
+	mock.SetAccount(sol.WSOL, solana.TokenProgramID, make([]byte, 82))
+	assert.Equal(t, []string{"create-ata", "swap"}, build(usdc.String(), sol.SwapOptions{CreateOutputAccount: true}))
+}
+
+func TestWrapWsolSwapUnwrapOptions(t *testing.T) {
+	user := solana.NewWallet().PublicKey()
+	wsolAccount, _, err := solana.FindAssociatedTokenAddress(user, sol.WSOL)
+	require.NoError(t, err)
+	pool := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: sol.WSOL, MintB: solana.NewWallet().PublicKey()}
+	swap := solana.NewInstruction(pool.GetProgramID(), nil, []byte{0})
+	mock := sol.NewMockRPC()
+	mock.SetAccountInfo(user, &rpc.Account{Lamports: 10_000_000_000, Owner: solana.SystemProgramID})
+	mock.SetTokenAccount(wsolAccount, sol.WSOL, user, 0)
+	client := &sol.Client{RpcClient: mock}
+	ctx := context.Background()
+	wrap := func(inputMint string, opts sol.WsolOptions) []string {
+		insts, err := client.WrapWsolSwap(ctx, user, inputMint, 1_000_000, []solana.Instruction{swap}, opts)
+		require.NoError(t, err)
+		return instructionKinds(t, pool, insts)
+	}
+	output := pool.MintB.String()
+
+	// Without Unwind the funded WSOL account stays open
+	assert.Equal(t, []string{"transfer", "sync", "swap"}, wrap(sol.WSOL.String(), sol.WsolOptions{}))
+	assert.Equal(t, []string{"transfer", "sync", "swap", "close"}, wrap(sol.WSOL.String(), sol.WsolOptions{Unwind: true}))
+
+	// A WSOL output is unwrapped per the mode, falling back to the client's default
+	toWsol := func(mode sol.UnwrapMode) sol.WsolOptions {
+		return sol.WsolOptions{OutputMint: sol.WSOL.String(), UnwrapOutput: mode}
+	}
+	assert.Equal(t, []string{"swap", "close"}, wrap(output, toWsol(sol.UnwrapAlways)))
+	assert.Equal(t, []string{"swap"}, wrap(output, toWsol(sol.UnwrapNever)))
+	assert.Equal(t, []string{"swap"}, wrap(output, toWsol(sol.UnwrapDefault)))
+	client.UnwrapWsolOutput = true
+	assert.Equal(t, []string{"swap", "close"}, wrap(output, toWsol(sol.UnwrapDefault)))
+	assert.Equal(t, []string{"swap"}, wrap(output, toWsol(sol.UnwrapNever)))
+
+	// Other mints pass through
+	assert.Equal(t, []string{"swap"}, wrap(output, sol.WsolOptions{Unwind: true, OutputMint: solana.NewWallet().PublicKey().String()}))
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwapRequestValidate(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey().String(), solana.NewWallet().PublicKey().String()
+	req := pkg.NewSwapRequest(mintA, mintB, math.NewInt(1000)).WithSlippageBps(50)
+	require.NoError(t, req.Validate())
+
+	// The With methods leave the request they are called on unchanged
+	later := req.WithPriorityFee(1000).WithTimeout(time.Minute)
+	assert.Zero(t, req.PriorityFee)
+	assert.True(t, req.Deadline.IsZero())
+	assert.Equal(t, uint64(1000), later.PriorityFee)
+
+	for name, bad := range map[string]pkg.SwapRequest{
+		"invalid mint":  pkg.NewSwapRequest("not-a-mint", mintB, math.NewInt(1000)),
+		"same mints":    pkg.NewSwapRequest(mintA, mintA, math.NewInt(1000)),
+		"no amount":     pkg.NewSwapRequest(mintA, mintB, math.Int{}),
+		"zero amount":   pkg.NewSwapRequest(mintA, mintB, math.ZeroInt()),
+		"slippage":      req.WithSlippageBps(pkg.MaxBps + 1),
+		"past deadline": req.WithDeadline(time.Now().Add(-time.Second)),
+	} {
+		assert.Error(t, bad.Validate(), name)
+	}
+}
+
+func TestSwapRequestQuoteAndBuild(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	pool := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 1e9, FeeBps: 30}
+	r := router.NewSimpleRouter(staticProtocol{pool})
+	r.SetQuoteClient(sol.NewMockRPC())
+	r.SetMintDecimals(mintA.String(), 9)
+	r.SetMintDecimals(mintB.String(), 6)
+	ctx := context.Background()
+	_, err := r.QueryAllPools(ctx, mintA.String(), mintB.String())
+	require.NoError(t, err)
+
+	payer := solana.NewWallet().PublicKey()
+	req := pkg.NewSwapRequest(mintA.String(), mintB.String(), math.NewInt(1_000_000)).
+		WithSlippageBps(100).
+		WithFeePayer(payer).
+		WithPriorityFee(5000)
+	quote, err := r.QuoteSwap(ctx, nil, req)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), quote.SlippageBps)
+
+	_, err = r.QuoteSwap(ctx, nil, req.WithDeadline(time.Now().Add(-time.Second)))
+	assert.Error(t, err, "expired requests aren't quoted")
+
+	client := &sol.Client{RpcClient: sol.NewMockRPC()}
+	instructions, err := client.BuildSwapRequest(ctx, pool, req, quote.ExpectedOut, sol.SwapOptions{})
+	require.NoError(t, err)
+	require.Len(t, instructions, 2)
+	assert.Equal(t, solana.ComputeBudget, instructions[0].ProgramID(), "the priority fee is set first")
+	assert.Equal(t, pool.GetProgramID(), instructions[1].ProgramID())
+
+	_, err = client.BuildSwapRequest(ctx, pool, req.WithFeePayer(solana.PublicKey{}), quote.ExpectedOut, sol.SwapOptions{})
+	assert.Error(t, err)
+	other := pkg.NewSwapRequest(mintA.String(), solana.NewWallet().PublicKey().String(), math.NewInt(1_000_000)).WithFeePayer(payer)
+	_, err = client.BuildSwapRequest(ctx, pool, other, quote.ExpectedOut, sol.SwapOptions{})
+	assert.Error(t, err, "the pool must trade the requested output")
+
+	// Requests without a clock of their own are checked against the router's and client's
+	fake := clock.NewFake(time.Unix(1_700_000_000, 0))
+	pending := req.WithDeadline(fake.Now().Add(time.Minute))
+	_, err = r.QuoteSwap(ctx, nil, pending)
+	assert.Error(t, err, "the deadline passed on the wall clock")
+	r.SetClock(fake)
+	client.TimeSource = fake
+	_, err = r.QuoteSwap(ctx, nil, pending)
+	require.NoError(t, err)
+	_, err = client.BuildSwapRequest(ctx, pool, pending, quote.ExpectedOut, sol.SwapOptions{})
+	require.NoError(t, err)
+	fake.Advance(time.Minute + time.Second)
+	_, err = r.QuoteSwap(ctx, nil, pending)
+	assert.ErrorContains(t, err, "has passed")
+	_, err = client.BuildSwapRequest(ctx, pool, pending, quote.ExpectedOut, sol.SwapOptions{})
+	assert.ErrorContains(t, err, "has passed")
+
+	timed := req.WithClock(fake).WithTimeout(time.Minute)
+	assert.Equal(t, fake.Now().Add(time.Minute), timed.Deadline)
+	require.NoError(t, timed.Validate())
+	fake.Advance(time.Hour)
+	assert.Error(t, timed.Validate())
+}
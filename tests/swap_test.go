@@ -3,17 +3,12 @@ package tests
 import (
 	"context"
 	"os"
-	"strings"
 	"testing"
 
 	"cosmossdk.io/math"
 	"github.com/gagliardetto/solana-go"
-	ata "github.com/gagliardetto/solana-go/programs/associated-token-account"
 	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
-	"github.com/gagliardetto/solana-go/programs/token"
 	"github.com/gagliardetto/solana-go/rpc"
-	"github.com/gtdvccc/SolRouteTmp/pkg/pool/orca"
-	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
 	"github.com/gtdvccc/SolRouteTmp/pkg/protocol"
 	"github.com/gtdvccc/SolRouteTmp/pkg/router"
 	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
@@ -83,27 +78,10 @@ func setupTestSuite(t *testing.T) *TestSuite {
 	}
 
 	// Enforce RPC and WS belong to the same cluster
-	detectCluster := func(u string) string {
-		u = strings.ToLower(u)
-		if strings.Contains(u, "devnet") {
-			return "devnet"
-		}
-		if strings.Contains(u, "testnet") {
-			return "testnet"
-		}
-		return "mainnet"
-	}
-
-	rpcCluster := detectCluster(rpcUrl)
-	wsCluster := detectCluster(wsRpcUrl)
+	rpcCluster := sol.DetectCluster(rpcUrl)
+	wsCluster := sol.DetectCluster(wsRpcUrl)
 	require.Equal(t, rpcCluster, wsCluster, "RPC URL and WS URL clusters must match (got %s vs %s)", rpcCluster, wsCluster)
 
-	// If devnet, override program IDs for Raydium CLMM and Orca Whirlpool
-	if rpcCluster == "devnet" {
-		raydium.RAYDIUM_CLMM_PROGRAM_ID = raydium.RAYDIUM_CLMM_DEVNET_PROGRAM_ID
-		orca.ORCA_WHIRLPOOL_PROGRAM_ID = orca.ORCA_WHIRLPOOL_DEVNET_PROGRAM_ID
-	}
-
 	isSimulate := true // Default to true unless explicitly "false"
 	if isSimulate {
 		t.Log("Running in SIMULATION mode. No transactions will be sent.")
@@ -114,9 +92,12 @@ func setupTestSuite(t *testing.T) *TestSuite {
 	solClient, err := sol.NewClient(ctx, rpcUrl, wsRpcUrl)
 	require.NoError(t, err, "Failed to create solana client")
 
-	// Initialize router with Orca Whirlpool protocol (same as main.go)
+	require.Equal(t, rpcCluster, solClient.Cluster)
+
+	// Initialize router with Orca Whirlpool and Raydium CLMM protocols, which pick their
+	// program IDs for the client's cluster
 	testRouter := router.NewSimpleRouter(
-		// protocol.NewOrcaWhirlpool(solClient),
+		protocol.NewOrcaWhirlpool(solClient),
 		protocol.NewRaydiumClmm(solClient),
 	)
 
@@ -128,7 +109,7 @@ func setupTestSuite(t *testing.T) *TestSuite {
 		simulate:   isSimulate,
 		rpcURL:     rpcUrl,
 		wsURL:      wsRpcUrl,
-		cluster:    rpcCluster,
+		cluster:    string(rpcCluster),
 	}
 }
 
@@ -450,19 +431,10 @@ func TestUSDCToSOLSwap(t *testing.T) {
 	require.NoError(t, err, "Failed to build swap instructions")
 	require.NotEmpty(t, instructions, "Should generate at least one instruction")
 
-	// Ensure WSOL ATA exists for receiving, create if missing
-	wsolATA, _, err := solana.FindAssociatedTokenAddress(ts.privateKey.PublicKey(), sol.WSOL)
-	require.NoError(t, err, "failed to derive WSOL ATA")
-	acctInfo, err := ts.solClient.RpcClient.GetAccountInfo(ts.ctx, wsolATA)
-	if err != nil || acctInfo.Value == nil || acctInfo.Value.Owner.IsZero() {
-		createATAIx, err := ata.NewCreateInstruction(
-			ts.privateKey.PublicKey(),
-			ts.privateKey.PublicKey(),
-			sol.WSOL,
-		).ValidateAndBuild()
-		require.NoError(t, err, "failed to build create WSOL ATA instruction")
-		instructions = append([]solana.Instruction{createATAIx}, instructions...)
-	}
+	// Create the WSOL ATA if missing and unwrap the output to native SOL after the swap
+	instructions, err = ts.solClient.WrapWsolSwap(ts.ctx, ts.privateKey.PublicKey(), usdcTokenAddr, amountInUSDC.Uint64(), instructions,
+		sol.WsolOptions{OutputMint: sol.WSOL.String(), UnwrapOutput: sol.UnwrapAlways})
+	require.NoError(t, err, "failed to prepare WSOL output")
 
 	// Prepend compute budget instructions
 	cuPriceIx, err := computebudget.NewSetComputeUnitPriceInstruction(1000).ValidateAndBuild()
@@ -471,16 +443,6 @@ func TestUSDCToSOLSwap(t *testing.T) {
 	require.NoError(t, err, "failed to build CU limit instruction")
 	instructions = append([]solana.Instruction{cuPriceIx, cuLimitIx}, instructions...)
 
-	// Append close WSOL ATA to unwrap to native SOL after swap
-	closeIx, err := token.NewCloseAccountInstruction(
-		wsolATA,
-		ts.privateKey.PublicKey(),
-		ts.privateKey.PublicKey(),
-		[]solana.PublicKey{},
-	).ValidateAndBuild()
-	require.NoError(t, err, "failed to build close WSOL ATA instruction")
-	instructions = append(instructions, closeIx)
-
 	t.Logf("Successfully generated %d swap instructions for USDC->SOL", len(instructions))
 
 	if ts.simulate {
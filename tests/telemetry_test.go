@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/retry"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/gtdvccc/SolRouteTmp/pkg/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// telemetrySpy records span names and errors, and metric names with their attributes
+type telemetrySpy struct {
+	mu      sync.Mutex
+	spans   []string
+	errs    map[string]error
+	metrics []recordedMetric
+}
+
+type recordedMetric struct {
+	name  string
+	attrs map[string]any
+}
+
+type spySpan struct {
+	spy  *telemetrySpy
+	name string
+}
+
+func newTelemetrySpy() *telemetrySpy {
+	return &telemetrySpy{errs: make(map[string]error)}
+}
+
+func (s *telemetrySpy) Start(ctx context.Context, name string, attrs ...telemetry.Attr) (context.Context, telemetry.Span) {
+	return ctx, &spySpan{spy: s, name: name}
+}
+
+func (s *spySpan) SetAttributes(...telemetry.Attr) {}
+
+func (s *spySpan) RecordError(err error) {
+	s.spy.mu.Lock()
+	defer s.spy.mu.Unlock()
+	s.spy.errs[s.name] = err
+}
+
+func (s *spySpan) End() {
+	s.spy.mu.Lock()
+	defer s.spy.mu.Unlock()
+	s.spy.spans = append(s.spy.spans, s.name)
+}
+
+func (s *telemetrySpy) Add(ctx context.Context, name string, n int64, attrs ...telemetry.Attr) {
+	s.record(name, attrs)
+}
+
+func (s *telemetrySpy) Record(ctx context.Context, name string, value float64, attrs ...telemetry.Attr) {
+	s.record(name, attrs)
+}
+
+func (s *telemetrySpy) record(name string, attrs []telemetry.Attr) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	metric := recordedMetric{name: name, attrs: make(map[string]any)}
+	for _, attr := range attrs {
+		metric.attrs[attr.Key] = attr.Value
+	}
+	s.metrics = append(s.metrics, metric)
+}
+
+// metric returns the attributes of each recording of name
+func (s *telemetrySpy) metric(name string) []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []map[string]any
+	for _, metric := range s.metrics {
+		if metric.name == name {
+			out = append(out, metric.attrs)
+		}
+	}
+	return out
+}
+
+func TestRouterTelemetry(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	pool := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 1e9}
+	spy := newTelemetrySpy()
+	r := router.NewSimpleRouter(staticProtocol{pool})
+	r.SetTelemetry(&telemetry.Telemetry{Tracer: spy, Meter: spy})
+	r.SetQuoteClient(rpc.New("http://127.0.0.1:0"))
+	ctx := context.Background()
+
+	_, err := r.QueryAllPools(ctx, mintA.String(), mintB.String())
+	require.NoError(t, err)
+	_, _, err = r.GetBestPool(ctx, nil, mintA.String(), mintB.String(), math.NewInt(1000))
+	require.NoError(t, err)
+	_, _, err = r.GetBestPool(ctx, nil, mintA.String(), mintB.String(), math.ZeroInt())
+	require.Error(t, err)
+
+	assert.Equal(t, []string{telemetry.SpanDiscover, telemetry.SpanRoute, telemetry.SpanRoute}, spy.spans)
+	assert.ErrorIs(t, spy.errs[telemetry.SpanRoute], err)
+	protocol := string(pool.ProtocolName())
+	assert.Equal(t, []map[string]any{{pkg.LogKeyProtocol: protocol}}, spy.metric(telemetry.MetricDiscoveredPools))
+	assert.Equal(t, []map[string]any{
+		{pkg.LogKeyProtocol: protocol, telemetry.AttrOutcome: telemetry.OutcomeOK},
+		{pkg.LogKeyProtocol: protocol, telemetry.AttrOutcome: telemetry.OutcomeError},
+	}, spy.metric(telemetry.MetricQuotes))
+	assert.Equal(t, []map[string]any{
+		{telemetry.AttrOutcome: telemetry.OutcomeOK},
+		{telemetry.AttrOutcome: telemetry.OutcomeError},
+	}, spy.metric(telemetry.MetricRouteDuration))
+}
+
+// rejectingSender fails every send
+type rejectingSender struct {
+	*sol.MockRPC
+}
+
+var errRejected = errors.New("transaction rejected")
+
+func (r rejectingSender) SendTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts rpc.TransactionOpts) (solana.Signature, error) {
+	return solana.Signature{}, errRejected
+}
+
+func TestClientTelemetry(t *testing.T) {
+	spy := newTelemetrySpy()
+	client := &sol.Client{RpcClient: rejectingSender{sol.NewMockRPC()}}
+	client.SetRetryPolicy(retry.Policy{})
+	client.SetTelemetry(&telemetry.Telemetry{Tracer: spy, Meter: spy})
+	ctx := context.Background()
+
+	_, err := client.RpcClient.GetSlot(ctx, rpc.CommitmentProcessed)
+	require.NoError(t, err)
+	payer := solana.NewWallet().PrivateKey
+	transfer := system.NewTransferInstruction(1, payer.PublicKey(), solana.NewWallet().PublicKey()).Build()
+	_, err = client.SendTx(ctx, solana.Hash{1}, []solana.PrivateKey{payer}, []solana.Instruction{transfer}, true)
+	require.NoError(t, err)
+	_, err = client.SendTx(ctx, solana.Hash{1}, []solana.PrivateKey{payer}, []solana.Instruction{transfer}, false)
+	require.ErrorIs(t, err, errRejected)
+
+	assert.Equal(t, []string{telemetry.SpanSend, telemetry.SpanSend}, spy.spans)
+	assert.ErrorIs(t, spy.errs[telemetry.SpanSend], errRejected)
+	assert.Equal(t, []map[string]any{{telemetry.AttrStage: "send"}}, spy.metric(telemetry.MetricSendFailures))
+	assert.Equal(t, []map[string]any{
+		{telemetry.AttrMethod: "getSlot", telemetry.AttrOutcome: telemetry.OutcomeOK},
+		{telemetry.AttrMethod: "simulateTransaction", telemetry.AttrOutcome: telemetry.OutcomeOK},
+		{telemetry.AttrMethod: "sendTransaction", telemetry.AttrOutcome: telemetry.OutcomeError},
+	}, spy.metric(telemetry.MetricRPCLatency))
+
+	// Turning telemetry off removes the metered transport beneath the retry policy
+	client.SetTelemetry(nil)
+	_, err = client.RpcClient.GetSlot(ctx, rpc.CommitmentProcessed)
+	require.NoError(t, err)
+	assert.Len(t, spy.metric(telemetry.MetricRPCLatency), 3)
+	_, ok := client.RpcClient.(*sol.RetryRPC)
+	assert.True(t, ok)
+}
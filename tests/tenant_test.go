@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantRegistry(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1_700_000_000, 0))
+	registry := router.NewTenantRegistry()
+	registry.SetTimeSource(fake)
+	require.NoError(t, registry.Add("key-a", router.Tenant{ID: "a", DefaultSlippageBps: 50, RequestsPerSecond: 2}))
+	require.NoError(t, registry.Add("key-b", router.Tenant{ID: "b"}))
+	assert.Error(t, registry.Add("key-a", router.Tenant{ID: "c"}), "duplicate key")
+
+	_, err := registry.Authorize("nope")
+	assert.ErrorIs(t, err, router.ErrUnknownAPIKey)
+
+	// A budget of 2 requests per second, bursting to 2
+	for range 2 {
+		tenant, err := registry.Authorize("key-a")
+		require.NoError(t, err)
+		assert.Equal(t, uint64(50), tenant.DefaultSlippageBps)
+	}
+	_, err = registry.Authorize("key-a")
+	assert.ErrorIs(t, err, router.ErrRateLimited)
+	fake.Advance(500 * time.Millisecond)
+	_, err = registry.Authorize("key-a")
+	assert.NoError(t, err)
+
+	// Unlimited tenants aren't throttled
+	for range 10 {
+		_, err := registry.Authorize("key-b")
+		require.NoError(t, err)
+	}
+
+	registry.Remove("key-b")
+	_, err = registry.Authorize("key-b")
+	assert.ErrorIs(t, err, router.ErrUnknownAPIKey)
+}
+
+// pluginPool is an exampledex pool reporting a different protocol
+type pluginPool struct {
+	*exampledex.Pool
+	name pkg.ProtocolName
+}
+
+func (p pluginPool) ProtocolName() pkg.ProtocolName {
+	return p.name
+}
+
+func TestRouterForTenant(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	newPool := func(name pkg.ProtocolName) pkg.Pool {
+		return pluginPool{&exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 1e9}, name}
+	}
+	cpmm, dlmm := newPool(pkg.ProtocolNameRaydiumCpmm), newPool(pkg.ProtocolNameMeteoraDlmm)
+
+	r := router.NewSimpleRouter(staticProtocol{cpmm, dlmm})
+	ctx := context.Background()
+	scoped := r.ForTenant(router.Tenant{Protocols: []pkg.ProtocolName{pkg.ProtocolNameMeteoraDlmm}})
+	pools, err := scoped.QueryAllPools(ctx, mintA.String(), mintB.String())
+	require.NoError(t, err)
+	assert.Equal(t, []pkg.Pool{dlmm}, pools)
+
+	all, err := r.QueryAllPools(ctx, mintA.String(), mintB.String())
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+	// Scoping a known pool set keeps only the allowed pools, and scopes narrow when nested
+	best, _, err := r.ForTenant(router.Tenant{Protocols: []pkg.ProtocolName{pkg.ProtocolNameRaydiumCpmm}}).
+		GetBestPool(ctx, rpc.New("http://127.0.0.1:0"), mintA.String(), mintB.String(), math.NewInt(1000))
+	require.NoError(t, err)
+	assert.Equal(t, cpmm, best)
+	_, _, err = scoped.ForTenant(router.Tenant{Protocols: []pkg.ProtocolName{pkg.ProtocolNameRaydiumCpmm}}).
+		GetBestPool(ctx, rpc.New("http://127.0.0.1:0"), mintA.String(), mintB.String(), math.NewInt(1000))
+	assert.Error(t, err)
+}
+
+func TestSetProtocolEnabled(t *testing.T) {
+	mintA, mintB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	cpmm := pluginPool{&exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 2e9}, pkg.ProtocolNameRaydiumCpmm}
+	dlmm := pluginPool{&exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: mintA, MintB: mintB, ReserveA: 1e9, ReserveB: 1e9}, pkg.ProtocolNameMeteoraDlmm}
+
+	r := router.NewSimpleRouter(staticProtocol{cpmm, dlmm})
+	r.SetQuoteClient(rpc.New("http://127.0.0.1:0"))
+	ctx := context.Background()
+	_, err := r.QueryAllPools(ctx, mintA.String(), mintB.String())
+	require.NoError(t, err)
+	scoped := r.ForTenant(router.Tenant{})
+
+	best, _, err := r.GetBestPool(ctx, nil, mintA.String(), mintB.String(), math.NewInt(1000))
+	require.NoError(t, err)
+	assert.Equal(t, cpmm, best)
+
+	// Pausing applies to derived routers without rediscovery
+	r.SetProtocolEnabled(pkg.ProtocolNameRaydiumCpmm, false)
+	assert.False(t, scoped.ProtocolEnabled(pkg.ProtocolNameRaydiumCpmm))
+	best, _, err = scoped.GetBestPool(ctx, nil, mintA.String(), mintB.String(), math.NewInt(1000))
+	require.NoError(t, err)
+	assert.Equal(t, dlmm, best)
+
+	r.SetProtocolEnabled(pkg.ProtocolNameMeteoraDlmm, false)
+	assert.Equal(t, []pkg.ProtocolName{pkg.ProtocolNameMeteoraDlmm, pkg.ProtocolNameRaydiumCpmm}, r.DisabledProtocols())
+	_, _, err = r.GetBestPool(ctx, nil, mintA.String(), mintB.String(), math.NewInt(1000))
+	assert.ErrorIs(t, err, router.ErrProtocolDisabled)
+
+	r.SetProtocolEnabled(pkg.ProtocolNameRaydiumCpmm, true)
+	best, _, err = r.GetBestPool(ctx, nil, mintA.String(), mintB.String(), math.NewInt(1000))
+	require.NoError(t, err)
+	assert.Equal(t, cpmm, best)
+}
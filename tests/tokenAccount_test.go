@@ -0,0 +1,30 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAssociatedTokenAccountIdempotent(t *testing.T) {
+	user, mint := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	want, _, err := solana.FindAssociatedTokenAddress(user, mint)
+	require.NoError(t, err)
+	got, err := sol.AssociatedTokenAddress(user, mint, solana.TokenProgramID)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	inst, err := sol.CreateAssociatedTokenAccountIdempotent(user, user, mint, solana.Token2022ProgramID)
+	require.NoError(t, err)
+	data, err := inst.Data()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1}, data)
+	token2022Account, err := sol.AssociatedTokenAddress(user, mint, solana.Token2022ProgramID)
+	require.NoError(t, err)
+	assert.NotEqual(t, got, token2022Account)
+	assert.Equal(t, token2022Account, inst.Accounts()[1].PublicKey)
+	assert.Equal(t, solana.Token2022ProgramID, inst.Accounts()[5].PublicKey)
+}
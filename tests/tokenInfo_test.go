@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/examples/exampledex"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setMint stores a mint with decimals in mock, and Metaplex metadata when symbol is set
+func setMint(t *testing.T, mock *sol.MockRPC, mint solana.PublicKey, decimals uint8, name, symbol string) {
+	data := make([]byte, 82)
+	data[44] = decimals
+	mock.SetAccount(mint, solana.TokenProgramID, data)
+	if symbol == "" {
+		return
+	}
+	metadata := append([]byte{4}, make([]byte, 32)...)
+	metadata = append(metadata, mint[:]...)
+	for _, field := range []struct {
+		value string
+		size  int
+	}{{name, 32}, {symbol, 10}, {"https://example.com", 200}} {
+		padded := make([]byte, field.size)
+		copy(padded, field.value)
+		metadata = binary.LittleEndian.AppendUint32(metadata, uint32(field.size))
+		metadata = append(metadata, padded...)
+	}
+	address, _, err := solana.FindTokenMetadataAddress(mint)
+	require.NoError(t, err)
+	mock.SetAccount(address, solana.TokenMetadataProgramID, metadata)
+}
+
+func TestTokenResolver(t *testing.T) {
+	usdc, unnamed := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	mock := sol.NewMockRPC()
+	setMint(t, mock, usdc, 6, "USD Coin", "USDC")
+	setMint(t, mock, unnamed, 0, "", "")
+	resolver := sol.NewTokenResolver(nil)
+	ctx := context.Background()
+
+	tokens, err := resolver.Resolve(ctx, mock, usdc, unnamed, solana.WrappedSol)
+	require.NoError(t, err)
+	assert.Equal(t, sol.TokenInfo{Mint: usdc, Decimals: 6, Symbol: "USDC", Name: "USD Coin"}, tokens[0])
+	assert.Equal(t, "1.5 USDC", tokens[0].Format(math.NewInt(1_500_000)))
+	assert.Equal(t, "0.000001", tokens[0].Normalize(math.NewInt(1)))
+	assert.Equal(t, "42 "+unnamed.String(), tokens[1].Format(math.NewInt(42)))
+	assert.Equal(t, uint8(9), tokens[2].Decimals)
+
+	// Resolved tokens are cached, so no client is needed the second time
+	cached, err := resolver.Resolve(ctx, nil, usdc, unnamed)
+	require.NoError(t, err)
+	assert.Equal(t, tokens[:2], cached)
+	_, err = resolver.Resolve(ctx, mock, solana.NewWallet().PublicKey())
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestQuoteResultTokenUnits(t *testing.T) {
+	pool := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: solana.WrappedSol, MintB: solana.NewWallet().PublicKey(), ReserveA: 1e12, ReserveB: 1e11}
+	mock := sol.NewMockRPC()
+	setMint(t, mock, pool.MintB, 6, "USD Coin", "USDC")
+	r := router.NewSimpleRouter(staticProtocol{pool})
+	r.SetQuoteClient(mock)
+	ctx := context.Background()
+	_, err := r.QueryAllPools(ctx, pool.MintA.String(), pool.MintB.String())
+	require.NoError(t, err)
+
+	result, err := r.Quote(ctx, nil, pool.MintA.String(), pool.MintB.String(), math.NewInt(1e9), pkg.SlippageBps(100))
+	require.NoError(t, err)
+	assert.Equal(t, "1 SOL", result.Units.Input.String())
+	assert.Equal(t, "USDC", result.Units.Output.Symbol)
+	assert.Equal(t, router.NormalizeAmount(result.MinOut, 6)+" USDC", result.MinOutUnits().String())
+
+	token, err := r.TokenInfo(ctx, nil, pool.MintB.String())
+	require.NoError(t, err)
+	assert.Equal(t, "USD Coin", token.Name)
+}
+
+func TestParseAmount(t *testing.T) {
+	for _, tc := range []struct {
+		amount string
+		want   string
+	}{
+		{"1.5", "1500000"},
+		{"0.000001", "1"},
+		{"42", "42000000"},
+		{"007.10", "7100000"},
+		{"123456789012345678901234567890", "123456789012345678901234567890000000"},
+	} {
+		raw, err := sol.ParseAmount(tc.amount, 6)
+		require.NoError(t, err, tc.amount)
+		assert.Equal(t, tc.want, raw.String(), tc.amount)
+	}
+	for _, amount := range []string{"", ".5", "1.", "-1", "+1", "1e6", "1,000", " 1", "1.0000001", "0x10", "1.2.3"} {
+		_, err := sol.ParseAmount(amount, 6)
+		assert.ErrorIs(t, err, sol.ErrInvalidAmount, amount)
+	}
+
+	usdc := sol.TokenInfo{Mint: solana.NewWallet().PublicKey(), Decimals: 6, Symbol: "USDC"}
+	for _, amount := range []string{"2.5", "2.5 USDC", "2.5 usdc", "2.5 " + usdc.Mint.String()} {
+		raw, err := usdc.ParseAmount(amount)
+		require.NoError(t, err, amount)
+		assert.Equal(t, int64(2_500_000), raw.Int64(), amount)
+	}
+	_, err := usdc.ParseAmount("2.5 SOL")
+	assert.ErrorIs(t, err, sol.ErrInvalidAmount)
+}
+
+func TestQuoteDecimal(t *testing.T) {
+	pool := &exampledex.Pool{ID: solana.NewWallet().PublicKey(), MintA: solana.WrappedSol, MintB: solana.NewWallet().PublicKey(), ReserveA: 1e12, ReserveB: 1e11}
+	mock := sol.NewMockRPC()
+	setMint(t, mock, pool.MintB, 6, "USD Coin", "USDC")
+	r := router.NewSimpleRouter(staticProtocol{pool})
+	r.SetQuoteClient(mock)
+	ctx := context.Background()
+	_, err := r.QueryAllPools(ctx, pool.MintA.String(), pool.MintB.String())
+	require.NoError(t, err)
+
+	result, err := r.QuoteDecimal(ctx, nil, pool.MintA.String(), pool.MintB.String(), "1.5 SOL", pkg.SlippageBps(100))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1_500_000_000), result.AmountIn.Int64())
+	expected, err := pool.Quote(ctx, mock, pool.MintA.String(), result.AmountIn)
+	require.NoError(t, err)
+	assert.Equal(t, router.NormalizeAmount(expected, 6), result.Units.Output.Normalized)
+
+	out, err := r.QuotePoolDecimal(ctx, nil, pool, pool.MintB.String(), "150")
+	require.NoError(t, err)
+	assert.Equal(t, "SOL", out.Symbol)
+	assert.True(t, out.Raw.IsPositive())
+
+	_, err = r.QuoteDecimal(ctx, nil, pool.MintA.String(), pool.MintB.String(), "1.0000000001", pkg.SlippageBps(100))
+	assert.ErrorIs(t, err, sol.ErrInvalidAmount)
+	_, err = r.QuotePoolDecimal(ctx, nil, pool, solana.NewWallet().PublicKey().String(), "1")
+	assert.ErrorContains(t, err, "not in pool")
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gtdvccc/SolRouteTmp/pkg"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clock"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/twap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedQuoter quotes each slice at the next of its prices, in output per input
+type scriptedQuoter struct {
+	prices []float64
+	calls  int
+}
+
+func (q *scriptedQuoter) QuoteRoute(ctx context.Context, solClient pkg.RPC, tokenIn, tokenOut string, amountIn math.Int) (router.RouteQuote, error) {
+	price := q.prices[min(q.calls, len(q.prices)-1)]
+	q.calls++
+	if price == 0 {
+		return router.RouteQuote{}, errors.New("no route")
+	}
+	out := math.LegacyNewDecFromInt(amountIn).Mul(math.LegacyNewDecWithPrec(int64(price*1000), 3)).TruncateInt()
+	return router.RouteQuote{InputMint: tokenIn, OutputMint: tokenOut, AmountIn: amountIn, AmountOut: out}, nil
+}
+
+func fillAsQuoted(ctx context.Context, quote router.RouteQuote) (twap.Fill, error) {
+	return twap.Fill{AmountIn: quote.AmountIn, AmountOut: quote.AmountOut}, nil
+}
+
+func TestTWAPScheduler(t *testing.T) {
+	ctx := context.Background()
+	newScheduler := func(prices ...float64) (*twap.Scheduler, *clock.Fake) {
+		clk := clock.NewFake(time.Unix(1_700_000_000, 0))
+		scheduler := twap.NewScheduler(&scriptedQuoter{prices: prices}, fillAsQuoted)
+		scheduler.SetClock(clk)
+		scheduler.SetSleeper(clk)
+		return scheduler, clk
+	}
+	order := twap.Order{InputMint: "in", OutputMint: "out", AmountIn: math.NewInt(1000), Slices: 3, Interval: time.Minute}
+
+	// Slices of 333, 333 and the remaining 334, one a minute; a failed quote is retried
+	scheduler, clk := newScheduler(2, 0, 2, 1.99)
+	report, err := scheduler.Execute(ctx, order)
+	require.NoError(t, err)
+	assert.Equal(t, twap.StopCompleted, report.Stop)
+	require.Len(t, report.Slices, 4)
+	assert.Equal(t, math.NewInt(333), report.Slices[0].AmountIn)
+	assert.Error(t, report.Slices[1].Err)
+	assert.Equal(t, math.NewInt(334), report.Slices[3].AmountIn)
+	assert.Equal(t, math.NewInt(1000), report.FilledIn)
+	assert.Equal(t, math.NewInt(666+666+664), report.FilledOut)
+	assert.True(t, report.Remaining.IsZero())
+	assert.InDelta(t, 2, report.ArrivalPrice, 1e-9)
+	assert.InDelta(t, 1.996, report.AveragePrice, 1e-9)
+	assert.Equal(t, []time.Duration{time.Minute, time.Minute, time.Minute}, clk.Sleeps())
+
+	// The price falling 10% past the arrival price stops the order before the slice executes
+	order.MaxSlippageBps = 500
+	scheduler, _ = newScheduler(2, 1.8)
+	report, err = scheduler.Execute(ctx, order)
+	require.NoError(t, err)
+	assert.Equal(t, twap.StopSlippage, report.Stop)
+	assert.Equal(t, math.NewInt(333), report.FilledIn)
+	assert.Equal(t, math.NewInt(667), report.Remaining)
+
+	// Below the limit price slices wait, until the order runs out of time
+	order.MaxSlippageBps = 0
+	order.LimitPrice = 1.9
+	order.MaxDuration = 4 * time.Minute
+	scheduler, _ = newScheduler(2, 1.5)
+	report, err = scheduler.Execute(ctx, order)
+	require.NoError(t, err)
+	assert.Equal(t, twap.StopDeadline, report.Stop)
+	require.Len(t, report.Slices, 4)
+	assert.False(t, report.Slices[0].Deferred)
+	assert.True(t, report.Slices[3].Deferred)
+	assert.Equal(t, math.NewInt(333), report.FilledIn)
+
+	// Failing slices stop the order
+	order.LimitPrice, order.MaxDuration = 0, 0
+	scheduler, _ = newScheduler(0)
+	report, err = scheduler.Execute(ctx, order)
+	require.NoError(t, err)
+	assert.Equal(t, twap.StopFailures, report.Stop)
+	assert.Len(t, report.Slices, twap.DefaultMaxFailures)
+}
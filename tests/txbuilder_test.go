@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/meteora"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/meteora/dammv2"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/raydium"
+	"github.com/gtdvccc/SolRouteTmp/pkg/txbuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxbuilderProgramAddresses(t *testing.T) {
+	// The mainnet authorities every swap of these programs passes
+	for name, tc := range map[string]struct {
+		derive func() (solana.PublicKey, error)
+		want   string
+	}{
+		"raydium amm authority": {func() (solana.PublicKey, error) {
+			pda, _, err := txbuilder.RaydiumAmmAuthority(raydium.RAYDIUM_AMM_PROGRAM_ID)
+			return pda, err
+		}, "5Q544fKrFoe6tsEbD7S8EmxGTJYAKtTVhAW5Q5pge4j1"},
+		"raydium cpmm authority": {func() (solana.PublicKey, error) {
+			pda, _, err := txbuilder.RaydiumCpmmAuthority(raydium.RAYDIUM_CPMM_PROGRAM_ID)
+			return pda, err
+		}, "GpMZbSM2GgvTKHJirzeGfMFoaZ8UR2X7F4v8vHTvxFbL"},
+		"raydium launchlab authority": {func() (solana.PublicKey, error) {
+			pda, _, err := txbuilder.RaydiumLaunchLabAuthority(raydium.RAYDIUM_LAUNCHLAB_PROGRAM_ID)
+			return pda, err
+		}, "WLHv2UAZm6z4KyaaELi5pjdbJh6RESMva1Rnn8pJVVh"},
+		"dlmm event authority": {func() (solana.PublicKey, error) {
+			return txbuilder.EventAuthority(meteora.MeteoraProgramID)
+		}, "D1ZN9Wj1fRSUQfCjhvnu1hqDMT7hzjzBBpi12nVniYD6"},
+		"damm v2 pool authority": {func() (solana.PublicKey, error) {
+			return txbuilder.DammV2PoolAuthority(dammv2.ProgramID)
+		}, "HLnpSz9h2S4hiLQ43rnSD9XkcUThA7B8hQMKmDaiTLcC"},
+	} {
+		pda, err := tc.derive()
+		require.NoError(t, err, name)
+		assert.Equal(t, tc.want, pda.String(), name)
+	}
+	assert.Equal(t, "HLnpSz9h2S4hiLQ43rnSD9XkcUThA7B8hQMKmDaiTLcC", dammv2.PoolAuthority.String())
+	assert.Equal(t, "D1ZN9Wj1fRSUQfCjhvnu1hqDMT7hzjzBBpi12nVniYD6", meteora.DeriveEventAuthorityPDA().String())
+
+	// SPL Token accounts match solana-go's derivation, Token-2022 ones differ
+	owner, mint := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	want, _, err := solana.FindAssociatedTokenAddress(owner, mint)
+	require.NoError(t, err)
+	got, err := txbuilder.AssociatedTokenAddress(owner, mint, solana.TokenProgramID)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	got, err = txbuilder.AssociatedTokenAddress(owner, mint, solana.Token2022ProgramID)
+	require.NoError(t, err)
+	assert.NotEqual(t, want, got)
+}
+
+func TestTxbuilderDependsOnSolanaGoOnly(t *testing.T) {
+	out, err := exec.Command("go", "list", "-deps", "github.com/gtdvccc/SolRouteTmp/pkg/txbuilder").Output()
+	require.NoError(t, err)
+	for _, dep := range strings.Fields(string(out)) {
+		assert.False(t, strings.HasPrefix(dep, "github.com/gtdvccc/SolRouteTmp/") && dep != "github.com/gtdvccc/SolRouteTmp/pkg/txbuilder", dep)
+	}
+}
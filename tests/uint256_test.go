@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gtdvccc/SolRouteTmp/pkg/clmmmath"
+	"github.com/gtdvccc/SolRouteTmp/pkg/uint256"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// randomUint256 returns a value of random bit length, with runs of zero and all-ones limbs
+// often enough to reach the carries and the division's corrections
+func randomUint256(rng *rand.Rand) *uint256.Int {
+	var z uint256.Int
+	for i := range z {
+		switch rng.Intn(4) {
+		case 0:
+		case 1:
+			z[i] = ^uint64(0)
+		default:
+			z[i] = rng.Uint64()
+		}
+	}
+	return z.Rsh(&z, uint(rng.Intn(256)))
+}
+
+func TestUint256MatchesBig(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	mod := new(big.Int).Lsh(big.NewInt(1), 256)
+	wrap := func(b *big.Int) *big.Int { return b.Mod(b, mod) }
+
+	for i := 0; i < 20_000; i++ {
+		x, y, d := randomUint256(rng), randomUint256(rng), randomUint256(rng)
+		bx, by, bd := x.ToBig(), y.ToBig(), d.ToBig()
+
+		sum, overflow := new(uint256.Int).AddOverflow(x, y)
+		exact := new(big.Int).Add(bx, by)
+		assert.Equal(t, wrap(new(big.Int).Set(exact)).String(), sum.String())
+		assert.Equal(t, exact.BitLen() > 256, overflow)
+
+		diff, underflow := new(uint256.Int).SubOverflow(x, y)
+		assert.Equal(t, wrap(new(big.Int).Sub(bx, by)).String(), diff.String())
+		assert.Equal(t, bx.Cmp(by) < 0, underflow)
+		assert.Equal(t, bx.Cmp(by), x.Cmp(y))
+
+		product, overflow := new(uint256.Int).MulOverflow(x, y)
+		exact = new(big.Int).Mul(bx, by)
+		assert.Equal(t, wrap(new(big.Int).Set(exact)).String(), product.String())
+		assert.Equal(t, exact.BitLen() > 256, overflow)
+
+		n := uint(rng.Intn(300))
+		assert.Equal(t, wrap(new(big.Int).Lsh(bx, n)).String(), new(uint256.Int).Lsh(x, n).String())
+		assert.Equal(t, new(big.Int).Rsh(bx, n).String(), new(uint256.Int).Rsh(x, n).String())
+
+		if d.IsZero() {
+			continue
+		}
+		var rem uint256.Int
+		quot, _ := new(uint256.Int).DivMod(x, d, &rem)
+		bq, br := new(big.Int).QuoRem(bx, bd, new(big.Int))
+		require.Equal(t, bq.String(), quot.String(), "%s / %s", x, d)
+		require.Equal(t, br.String(), rem.String(), "%s %% %s", x, d)
+
+		mulDiv, overflow := new(uint256.Int).MulDivOverflow(x, y, d)
+		bq, br = new(big.Int).QuoRem(new(big.Int).Mul(bx, by), bd, new(big.Int))
+		require.Equal(t, bq.BitLen() > 256, overflow, "%s * %s / %s", x, y, d)
+		if !overflow {
+			require.Equal(t, bq.String(), mulDiv.String(), "%s * %s / %s", x, y, d)
+			ceil, overflow := new(uint256.Int).MulDivCeilOverflow(x, y, d)
+			if br.Sign() != 0 {
+				bq.Add(bq, big.NewInt(1))
+			}
+			assert.Equal(t, bq.BitLen() > 256, overflow)
+			if !overflow {
+				assert.Equal(t, bq.String(), ceil.String())
+			}
+		}
+	}
+}
+
+func TestUint256FromBig(t *testing.T) {
+	max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	z, overflow := uint256.FromBig(max)
+	assert.False(t, overflow)
+	assert.Equal(t, max.String(), z.String())
+	assert.Equal(t, 256, z.BitLen())
+
+	_, overflow = uint256.FromBig(new(big.Int).Add(max, big.NewInt(1)))
+	assert.True(t, overflow)
+	_, overflow = uint256.FromBig(big.NewInt(-1))
+	assert.True(t, overflow)
+
+	_, overflow = new(uint256.Int).MulDivOverflow(uint256.NewInt(1), uint256.NewInt(1), new(uint256.Int))
+	assert.True(t, overflow, "division by zero")
+}
+
+// referenceSwapStep is the swap step on the big.Int helpers, as the pools computed it before
+// the 256-bit math
+func referenceSwapStep(current, target, liquidity, amount *big.Int, exactIn bool, feeRate int64, zeroForOne bool) (next, in, out, fee *big.Int) {
+	afterFee := big.NewInt(clmmmath.FeeRateDenominator - feeRate)
+	amountA := func(a, b *big.Int, up bool) *big.Int {
+		return clmmmath.GetTokenAmountAFromLiquidity(a, b, liquidity, up)
+	}
+	amountB := func(a, b *big.Int, up bool) *big.Int {
+		return clmmmath.GetTokenAmountBFromLiquidity(a, b, liquidity, up)
+	}
+	in, out = new(big.Int), new(big.Int)
+	if exactIn {
+		remaining := new(big.Int).Mul(amount, afterFee)
+		remaining.Quo(remaining, big.NewInt(clmmmath.FeeRateDenominator))
+		if zeroForOne {
+			in = amountA(target, current, true)
+		} else {
+			in = amountB(current, target, true)
+		}
+		next = target
+		if remaining.Cmp(in) < 0 {
+			next = clmmmath.GetNextSqrtPriceX64FromInput(current, liquidity, remaining, zeroForOne)
+		}
+	} else {
+		if zeroForOne {
+			out = amountB(target, current, false)
+		} else {
+			out = amountA(current, target, false)
+		}
+		next = target
+		if amount.Cmp(out) < 0 {
+			next = clmmmath.GetNextSqrtPriceX64FromOutput(current, liquidity, amount, zeroForOne)
+		}
+	}
+	reached := next.Cmp(target) == 0
+	if zeroForOne {
+		if !(reached && exactIn) {
+			in = amountA(next, current, true)
+		}
+		if !(reached && !exactIn) {
+			out = amountB(next, current, false)
+		}
+	} else {
+		if !(reached && exactIn) {
+			in = amountB(current, next, true)
+		}
+		if !(reached && !exactIn) {
+			out = amountA(current, next, false)
+		}
+	}
+	if !exactIn && out.Cmp(amount) > 0 {
+		out = amount
+	}
+	if exactIn && !reached {
+		fee = new(big.Int).Sub(amount, in)
+	} else {
+		fee = clmmmath.MulDivCeil(math.NewIntFromBigInt(in), math.NewInt(feeRate), math.NewIntFromBigInt(afterFee)).BigInt()
+	}
+	return next, in, out, fee
+}
+
+func TestComputeSwapStepMatchesBig(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 5_000; i++ {
+		tickA, tickB := rng.Int63n(2*200_000)-200_000, rng.Int63n(2*200_000)-200_000
+		zeroForOne := rng.Intn(2) == 0
+		// Swapping zero for one moves the price down
+		if zeroForOne == (tickB > tickA) {
+			tickA, tickB = tickB, tickA
+		}
+		current, err := clmmmath.SqrtPriceX64FromTick(tickA)
+		require.NoError(t, err)
+		target, err := clmmmath.SqrtPriceX64FromTick(tickB)
+		require.NoError(t, err)
+		liquidity := new(big.Int).Rand(rng, new(big.Int).Lsh(big.NewInt(1), uint(1+rng.Intn(100))))
+		liquidity.Add(liquidity, big.NewInt(1))
+		amount := new(big.Int).SetUint64(rng.Uint64()>>(1+rng.Intn(63)) + 1)
+		exactIn := rng.Intn(2) == 0
+		feeRate := rng.Int63n(100_000)
+
+		signed := math.NewIntFromBigInt(amount)
+		if !exactIn {
+			signed = signed.Neg()
+		}
+		next, in, out, fee, err := clmmmath.ComputeSwapStepInt(current, target, math.NewIntFromBigInt(liquidity), signed, uint32(feeRate), zeroForOne)
+
+		var wantNext, wantIn, wantOut, wantFee *big.Int
+		panicked := func() (panicked bool) {
+			defer func() { panicked = recover() != nil }()
+			wantNext, wantIn, wantOut, wantFee = referenceSwapStep(current.BigInt(), target.BigInt(), liquidity, amount, exactIn, feeRate, zeroForOne)
+			return false
+		}()
+		if panicked {
+			// The big.Int helpers panic where the step fails, removing more output than the
+			// range holds
+			assert.Error(t, err)
+			continue
+		}
+		require.NoError(t, err)
+		msg := []any{"step %d: ticks %d to %d, liquidity %s, amount %s, exact in %t", i, tickA, tickB, liquidity, amount, exactIn}
+		require.Equal(t, wantNext.String(), next.String(), msg...)
+		require.Equal(t, wantIn.String(), in.String(), msg...)
+		require.Equal(t, wantOut.String(), out.String(), msg...)
+		require.Equal(t, wantFee.String(), fee.String(), msg...)
+	}
+}
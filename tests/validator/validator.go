@@ -0,0 +1,299 @@
+// Package validator runs solana-test-validator with accounts cloned from a live cluster, so
+// swaps can be built and executed end to end against real program code without spending
+// anything. Tests using it are built with the localvalidator tag:
+//
+//	go test -tags localvalidator ./tests -run LocalValidator
+package validator
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/tests/fixtures"
+)
+
+// Binary is the validator executable looked up on PATH
+const Binary = "solana-test-validator"
+
+// DefaultStartTimeout bounds how long Start waits for the validator to report healthy
+const DefaultStartTimeout = 2 * time.Minute
+
+var sysvarOwner = solana.MustPublicKeyFromBase58("Sysvar1111111111111111111111111111111111111")
+
+// bundled are programs and accounts the validator's genesis already holds
+var bundled = map[solana.PublicKey]bool{
+	solana.SystemProgramID:                    true,
+	solana.ComputeBudget:                      true,
+	solana.TokenProgramID:                     true,
+	solana.Token2022ProgramID:                 true,
+	solana.SPLAssociatedTokenAccountProgramID: true,
+	solana.MemoProgramID:                      true,
+	solana.WrappedSol:                         true,
+}
+
+// Options configures a validator
+type Options struct {
+	// SourceURL is the cluster accounts and programs are cloned from, mainnet when empty
+	SourceURL string
+	// Clone are accounts copied from SourceURL when the validator starts
+	Clone []solana.PublicKey
+	// ClonePrograms are upgradeable programs copied from SourceURL with their program data
+	ClonePrograms []solana.PublicKey
+	// Accounts are loaded as given, e.g. from a fixture, without reading SourceURL
+	Accounts []fixtures.Account
+	// StartTimeout is DefaultStartTimeout when zero
+	StartTimeout time.Duration
+}
+
+// Validator is a running solana-test-validator on a fresh ledger
+type Validator struct {
+	RPCURL string
+	WSURL  string
+	// Ledger is the ledger directory, holding validator.log until Close
+	Ledger string
+
+	cmd     *exec.Cmd
+	client  *rpc.Client
+	exited  chan struct{}
+	waitErr error
+}
+
+// Installed reports whether solana-test-validator is on PATH
+func Installed() bool {
+	_, err := exec.LookPath(Binary)
+	return err == nil
+}
+
+// Start launches a validator on free local ports and waits until it is healthy. Close it
+// to stop the process and remove its ledger.
+func Start(ctx context.Context, opts Options) (*Validator, error) {
+	ledger, err := os.MkdirTemp("", "solroute-validator-")
+	if err != nil {
+		return nil, err
+	}
+	rpcPort, err := freePort()
+	if err != nil {
+		os.RemoveAll(ledger)
+		return nil, err
+	}
+	faucetPort, err := freePort()
+	if err != nil {
+		os.RemoveAll(ledger)
+		return nil, err
+	}
+	source := opts.SourceURL
+	if source == "" {
+		source = rpc.MainNetBeta_RPC
+	}
+
+	args := []string{
+		"--reset", "--quiet",
+		"--ledger", ledger,
+		"--rpc-port", strconv.Itoa(rpcPort),
+		"--faucet-port", strconv.Itoa(faucetPort),
+	}
+	if len(opts.Clone) > 0 || len(opts.ClonePrograms) > 0 {
+		args = append(args, "--url", source)
+	}
+	for _, address := range opts.Clone {
+		args = append(args, "--clone", address.String())
+	}
+	for _, program := range opts.ClonePrograms {
+		args = append(args, "--clone-upgradeable-program", program.String())
+	}
+	for i, account := range opts.Accounts {
+		path := filepath.Join(ledger, fmt.Sprintf("account-%d.json", i))
+		if err := writeAccount(path, account); err != nil {
+			os.RemoveAll(ledger)
+			return nil, err
+		}
+		args = append(args, "--account", account.Pubkey.String(), path)
+	}
+
+	v := &Validator{
+		RPCURL: fmt.Sprintf("http://127.0.0.1:%d", rpcPort),
+		WSURL:  fmt.Sprintf("ws://127.0.0.1:%d", rpcPort+1),
+		Ledger: ledger,
+		cmd:    exec.Command(Binary, args...),
+		exited: make(chan struct{}),
+	}
+	v.client = rpc.New(v.RPCURL)
+	if err := v.cmd.Start(); err != nil {
+		os.RemoveAll(ledger)
+		return nil, fmt.Errorf("failed to start %s: %w", Binary, err)
+	}
+	go func() {
+		v.waitErr = v.cmd.Wait()
+		close(v.exited)
+	}()
+
+	timeout := opts.StartTimeout
+	if timeout == 0 {
+		timeout = DefaultStartTimeout
+	}
+	if err := v.waitHealthy(ctx, timeout); err != nil {
+		v.Close()
+		return nil, err
+	}
+	return v, nil
+}
+
+// Client returns an RPC client for the validator
+func (v *Validator) Client() *rpc.Client {
+	return v.client
+}
+
+// Close stops the validator and removes its ledger
+func (v *Validator) Close() error {
+	defer os.RemoveAll(v.Ledger)
+	select {
+	case <-v.exited:
+		return nil
+	default:
+	}
+	if err := v.cmd.Process.Signal(os.Interrupt); err != nil {
+		return v.cmd.Process.Kill()
+	}
+	select {
+	case <-v.exited:
+	case <-time.After(10 * time.Second):
+		return v.cmd.Process.Kill()
+	}
+	return nil
+}
+
+// Fund airdrops lamports to account and waits for the airdrop to confirm
+func (v *Validator) Fund(ctx context.Context, account solana.PublicKey, lamports uint64) error {
+	sig, err := v.client.RequestAirdrop(ctx, account, lamports, rpc.CommitmentConfirmed)
+	if err != nil {
+		return fmt.Errorf("failed to airdrop to %s: %w", account, err)
+	}
+	return v.WaitForSignature(ctx, sig)
+}
+
+// WaitForSignature waits until the transaction is confirmed, returning its error if it failed
+func (v *Validator) WaitForSignature(ctx context.Context, sig solana.Signature) error {
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		result, err := v.client.GetSignatureStatuses(ctx, false, sig)
+		if err == nil && len(result.Value) == 1 && result.Value[0] != nil {
+			status := result.Value[0]
+			if status.Err != nil {
+				return fmt.Errorf("transaction %s failed: %v", sig, status.Err)
+			}
+			if status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed || status.ConfirmationStatus == rpc.ConfirmationStatusFinalized {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (v *Validator) waitHealthy(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if health, err := v.client.GetHealth(ctx); err == nil && health == rpc.HealthOk {
+			return nil
+		}
+		select {
+		case <-v.exited:
+			return fmt.Errorf("%s exited before becoming healthy (%v): %s", Binary, v.waitErr, v.logTail())
+		case <-ctx.Done():
+			return fmt.Errorf("%s not healthy after %s: %w", Binary, timeout, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// logTail returns the end of the validator's log, which Close removes with the ledger
+func (v *Validator) logTail() string {
+	data, err := os.ReadFile(filepath.Join(v.Ledger, "validator.log"))
+	if err != nil {
+		return err.Error()
+	}
+	return string(data[max(0, len(data)-2048):])
+}
+
+// InstructionAccounts returns the programs and accounts instructions reference, other than
+// their signers, ordered by address
+func InstructionAccounts(instructions []solana.Instruction) []solana.PublicKey {
+	seen := make(map[solana.PublicKey]bool)
+	for _, instruction := range instructions {
+		seen[instruction.ProgramID()] = true
+		for _, meta := range instruction.Accounts() {
+			if !meta.IsSigner {
+				seen[meta.PublicKey] = true
+			}
+		}
+	}
+	accounts := make([]solana.PublicKey, 0, len(seen))
+	for account := range seen {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].String() < accounts[j].String() })
+	return accounts
+}
+
+// Clones splits accounts read from the source cluster into the accounts and the upgradeable
+// programs to clone. Sysvars, program data, programs of other loaders and what the validator's
+// genesis already holds are left out.
+func Clones(accounts []fixtures.Account) (clone, programs []solana.PublicKey) {
+	for _, account := range accounts {
+		switch {
+		case bundled[account.Pubkey], account.Owner.Equals(sysvarOwner):
+		case account.Executable && account.Owner.Equals(solana.BPFLoaderUpgradeableProgramID):
+			programs = append(programs, account.Pubkey)
+		case account.Executable, account.Owner.Equals(solana.BPFLoaderUpgradeableProgramID):
+		default:
+			clone = append(clone, account.Pubkey)
+		}
+	}
+	return clone, programs
+}
+
+// writeAccount writes account in the JSON format --account loads, which is solana account's
+func writeAccount(path string, account fixtures.Account) error {
+	data, err := json.Marshal(map[string]any{
+		"pubkey": account.Pubkey.String(),
+		"account": map[string]any{
+			"lamports":   account.Lamports,
+			"data":       []string{base64.StdEncoding.EncodeToString(account.Data), "base64"},
+			"owner":      account.Owner.String(),
+			"executable": account.Executable,
+			"rentEpoch":  0,
+			"space":      len(account.Data),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
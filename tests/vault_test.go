@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/phoenix"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyPoolVaults(t *testing.T) {
+	market := solana.NewWallet().PublicKey()
+	baseMint, quoteMint := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	vault := func(mint solana.PublicKey) solana.PublicKey {
+		address, _, err := solana.FindProgramAddress([][]byte{[]byte("vault"), market.Bytes(), mint.Bytes()}, phoenix.PHOENIX_PROGRAM_ID)
+		require.NoError(t, err)
+		return address
+	}
+	baseVault, quoteVault := vault(baseMint), vault(quoteMint)
+	pool := &phoenix.MarketPool{PoolId: market, Market: phoenix.Market{
+		BaseMint: baseMint, BaseVault: baseVault, QuoteMint: quoteMint, QuoteVault: quoteVault,
+	}}
+	ctx := context.Background()
+
+	// Phoenix vaults are their own authority
+	newMock := func() *sol.MockRPC {
+		mock := sol.NewMockRPC()
+		mock.SetAccount(market, phoenix.PHOENIX_PROGRAM_ID, make([]byte, 8))
+		mock.SetTokenAccount(baseVault, baseMint, baseVault, 1)
+		mock.SetTokenAccount(quoteVault, quoteMint, quoteVault, 1)
+		return mock
+	}
+	require.NoError(t, sol.VerifyPoolVaults(ctx, newMock(), pool))
+
+	for name, tc := range map[string]struct {
+		setup func(mock *sol.MockRPC)
+		err   string
+	}{
+		"pool of another program": {
+			setup: func(mock *sol.MockRPC) { mock.SetAccount(market, solana.NewWallet().PublicKey(), make([]byte, 8)) },
+			err:   "pool " + market.String() + " owned by",
+		},
+		"missing pool": {
+			setup: func(mock *sol.MockRPC) { mock.SetAccountInfo(market, nil) },
+			err:   "not found",
+		},
+		"wrong mint": {
+			setup: func(mock *sol.MockRPC) { mock.SetTokenAccount(quoteVault, baseMint, quoteVault, 1) },
+			err:   "holds mint " + baseMint.String(),
+		},
+		"vault owned by another program": {
+			setup: func(mock *sol.MockRPC) {
+				mock.SetAccount(baseVault, solana.SystemProgramID, make([]byte, sol.TokenAccountSize))
+			},
+			err: "not a token program",
+		},
+		"wrong authority": {
+			setup: func(mock *sol.MockRPC) { mock.SetTokenAccount(baseVault, baseMint, market, 1) },
+			err:   "controlled by " + market.String(),
+		},
+	} {
+		mock := newMock()
+		tc.setup(mock)
+		assert.ErrorContains(t, sol.VerifyPoolVaults(ctx, mock, pool), tc.err, name)
+	}
+}
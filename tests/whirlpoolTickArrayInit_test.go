@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gtdvccc/SolRouteTmp/pkg/pool/orca"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"lukechampine.com/uint128"
+)
+
+func TestWhirlpoolTickArrayInit(t *testing.T) {
+	pool := &orca.WhirlpoolPool{
+		PoolId:      solana.NewWallet().PublicKey(),
+		TickSpacing: 64,
+		FeeRate:     3000,
+		Liquidity:   uint128.From64(1_000_000_000_000),
+		// Tick 0
+		SqrtPrice:  uint128.New(0, 1),
+		TokenMintA: solana.NewWallet().PublicKey(),
+		TokenMintB: solana.NewWallet().PublicKey(),
+	}
+	ctx := context.Background()
+	mock := sol.NewMockRPC()
+	// Of the arrays an a->b swap is given, only the second one exists
+	second, err := orca.DeriveWhirlpoolTickArrayPDA(pool.GetProgramID(), pool.PoolId, -5632)
+	require.NoError(t, err)
+	data := make([]byte, 8+4+orca.TICK_ARRAY_SIZE*113+32)
+	copy(data, []byte{69, 97, 189, 190, 110, 7, 66, 187})
+	startIndex := int32(-5632)
+	binary.LittleEndian.PutUint32(data[8:], uint32(startIndex))
+	mock.SetAccount(second, orca.WhirlpoolProgramID(), data)
+
+	_, err = pool.Quote(ctx, mock, pool.TokenMintA.String(), math.NewInt(1_000_000))
+	assert.Error(t, err)
+
+	pool.InitializeTickArrays = true
+	out, err := pool.Quote(ctx, mock, pool.TokenMintA.String(), math.NewInt(1_000_000))
+	require.NoError(t, err)
+	assert.True(t, out.IsPositive())
+
+	user := solana.NewWallet().PublicKey()
+	instructions, err := pool.BuildSwapInstructions(ctx, mock, user, pool.TokenMintA.String(), math.NewInt(1_000_000), math.NewInt(1))
+	require.NoError(t, err)
+	// Two ATAs, the two missing arrays, then the swap
+	require.Len(t, instructions, 5)
+	for i, startIndex := range []int32{0, -11264} {
+		instruction := instructions[2+i]
+		tickArray, err := orca.DeriveWhirlpoolTickArrayPDA(pool.GetProgramID(), pool.PoolId, int64(startIndex))
+		require.NoError(t, err)
+		accounts := instruction.Accounts()
+		assert.Equal(t, []solana.PublicKey{pool.PoolId, user, tickArray, solana.SystemProgramID},
+			[]solana.PublicKey{accounts[0].PublicKey, accounts[1].PublicKey, accounts[2].PublicKey, accounts[3].PublicKey})
+		assert.True(t, accounts[1].IsSigner)
+		data, err := instruction.Data()
+		require.NoError(t, err)
+		assert.Equal(t, orca.InitializeTickArrayDiscriminator, data[:8])
+		assert.Equal(t, startIndex, int32(binary.LittleEndian.Uint32(data[8:])))
+	}
+}
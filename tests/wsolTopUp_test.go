@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWsolTopUpShortfall(t *testing.T) {
+	user := solana.NewWallet().PublicKey()
+	wsolAccount, _, err := solana.FindAssociatedTokenAddress(user, sol.WSOL)
+	require.NoError(t, err)
+	mock := sol.NewMockRPC()
+	mock.SetAccountInfo(user, &rpc.Account{Lamports: 10_000_000, Owner: solana.SystemProgramID})
+	client := &sol.Client{RpcClient: mock}
+	ctx := context.Background()
+	// transferred returns the lamports wrapped by a top-up, checking it ends with transfer and sync
+	transferred := func(insts []solana.Instruction) uint64 {
+		require.GreaterOrEqual(t, len(insts), 2)
+		transfer, sync := insts[len(insts)-2], insts[len(insts)-1]
+		assert.Equal(t, solana.SystemProgramID, transfer.ProgramID())
+		assert.Equal(t, wsolAccount, transfer.Accounts()[1].PublicKey)
+		assert.Equal(t, solana.TokenProgramID, sync.ProgramID())
+		data, err := transfer.Data()
+		require.NoError(t, err)
+		return binary.LittleEndian.Uint64(data[4:])
+	}
+
+	for _, tc := range []struct {
+		name    string
+		balance uint64
+		want    uint64 // lamports transferred, zero for no top-up
+	}{
+		{"short", 400_000, 600_000},
+		{"exact", 1_000_000, 0},
+		{"over", 1_500_000, 0},
+		{"empty", 0, 1_000_000},
+	} {
+		mock.SetTokenAccount(wsolAccount, sol.WSOL, user, tc.balance)
+		insts, err := client.WsolTopUpInstructions(ctx, user, 1_000_000)
+		require.NoError(t, err, tc.name)
+		if tc.want == 0 {
+			assert.Empty(t, insts, tc.name)
+			continue
+		}
+		assert.Len(t, insts, 2, tc.name)
+		assert.Equal(t, tc.want, transferred(insts), tc.name)
+	}
+
+	// A missing account is created and the whole amount wrapped, which with its rent must
+	// fit in the user's SOL
+	mock.SetAccountInfo(wsolAccount, nil)
+	insts, err := client.WsolTopUpInstructions(ctx, user, 1_000_000)
+	require.NoError(t, err)
+	require.Len(t, insts, 3)
+	assert.Equal(t, solana.SPLAssociatedTokenAccountProgramID, insts[0].ProgramID())
+	assert.Equal(t, uint64(1_000_000), transferred(insts))
+	_, err = client.WsolTopUpInstructions(ctx, user, 10_000_000-2_039_280+1)
+	assert.ErrorContains(t, err, "insufficient SOL to wrap: need 10000001 lamports, have 10000000")
+
+	// Only the shortfall counts against the user's SOL when the account exists
+	mock.SetTokenAccount(wsolAccount, sol.WSOL, user, 5_000_000)
+	insts, err = client.WsolTopUpInstructions(ctx, user, 15_000_000)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10_000_000), transferred(insts))
+	_, err = client.WsolTopUpInstructions(ctx, user, 15_000_001)
+	assert.ErrorContains(t, err, "need 10000001 lamports")
+
+	// Swaps spending WSOL get the top-up ahead and the unwind after
+	swap := solana.NewInstruction(solana.NewWallet().PublicKey(), nil, []byte{1})
+	wrapped, err := client.WrapWsolSwap(ctx, user, sol.WSOL.String(), 6_000_000, []solana.Instruction{swap}, sol.WsolOptions{Unwind: true})
+	require.NoError(t, err)
+	require.Len(t, wrapped, 4)
+	assert.Equal(t, uint64(1_000_000), transferred(wrapped[:2]))
+	assert.Equal(t, swap, wrapped[2])
+	assert.Equal(t, solana.TokenProgramID, wrapped[3].ProgramID())
+	wrapped, err = client.WrapWsolSwap(ctx, user, sol.WSOL.String(), 5_000_000, []solana.Instruction{swap}, sol.WsolOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []solana.Instruction{swap}, wrapped)
+}
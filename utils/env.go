@@ -1,3 +1,5 @@
+// Package utils holds small helpers used by the examples and pool packages: .env loading and
+// Anchor instruction discriminators
 package utils
 
 import (
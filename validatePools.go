@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"cosmossdk.io/math"
+	"github.com/gtdvccc/SolRouteTmp/pkg/config"
+	"github.com/gtdvccc/SolRouteTmp/pkg/router"
+	"github.com/gtdvccc/SolRouteTmp/pkg/sol"
+)
+
+// runValidatePools implements `validate-pools [-probe amount] [baseMint/quoteMint ...]`.
+// It discovers the pools of each pair, validates them and prints the report. The exit
+// code is 1 when a pool is broken, so it can run from cron after DEX program upgrades.
+func runValidatePools(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("validate-pools", flag.ExitOnError)
+	probe := fs.Int64("probe", 0, "raw base-mint amount to test-quote on every pool, 0 to skip")
+	fs.Parse(args)
+
+	pairs := fs.Args()
+	if len(pairs) == 0 {
+		pairs = []string{sol.WSOL.String() + "/" + usdcTokenAddr}
+	}
+
+	cfg, err := config.Load("")
+	if err != nil {
+		log.Printf("Invalid config: %v", err)
+		return 2
+	}
+	endpoints, err := cfg.NewEndpoints(ctx)
+	if err != nil {
+		log.Printf("Failed to create solana client: %v", err)
+		return 2
+	}
+	defer endpoints.Close()
+
+	protocols, err := cfg.NewProtocols(endpoints.Discovery)
+	if err != nil {
+		log.Printf("Failed to create protocols: %v", err)
+		return 2
+	}
+	cache := router.NewPoolCache(0, protocols...)
+	for _, pair := range pairs {
+		baseMint, quoteMint, ok := strings.Cut(pair, "/")
+		if !ok {
+			log.Printf("Invalid pair %q, expected baseMint/quoteMint", pair)
+			return 2
+		}
+		if _, err := cache.Get(ctx, baseMint, quoteMint); err != nil {
+			log.Printf("Failed to discover pools for %s: %v", pair, err)
+			return 2
+		}
+	}
+
+	report, err := cache.Validate(ctx, endpoints.Quote.RpcClient, router.ValidateOptions{ProbeAmount: math.NewInt(*probe)})
+	if err != nil {
+		log.Printf("Failed to validate pools: %v", err)
+		return 2
+	}
+	fmt.Fprint(os.Stdout, report.String())
+	if len(report.Broken()) > 0 {
+		return 1
+	}
+	return 0
+}